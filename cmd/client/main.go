@@ -56,8 +56,12 @@ func run(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
+	keyGenerator, err := signers.New(cfg.Value().KeyAlgorithm)
+	if err != nil {
+		return err
+	}
 	clientKeys := keys.NewKeys(keys.Options{
-		Generator:    signers.NewRsaSigner(),
+		Generator:    keyGenerator,
 		SavePath:     cfg.Value().PathKeys,
 		Organization: "Go32_client",
 		Country:      "RU",
@@ -86,7 +90,7 @@ func run(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
-	manager, err := controller.NewManager(cfg, cryptService, log)
+	manager, err := controller.NewManager(cfg, cryptService, service.NewVault(), log)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os/signal"
+	"syscall"
+
+	"github.com/northmule/gophkeeper/internal/server/config"
+	"github.com/northmule/gophkeeper/internal/server/logger"
+	"github.com/northmule/gophkeeper/internal/server/repository"
+	"github.com/northmule/gophkeeper/internal/server/services/kek"
+	"github.com/northmule/gophkeeper/internal/server/storage"
+)
+
+// Перешифрование ключей клиентов активным KEK.
+// Порядок смены ключа:
+//  1. kek_rewrap -generate /path/kek_k2.key
+//  2. в .server.env добавить ключ в KEK_KEYS (прежний оставить) и указать KEK_ACTIVE_KID=k2, перезапустить сервер
+//  3. kek_rewrap
+//  4. после успешного перешифрования убрать прежний ключ из KEK_KEYS
+func main() {
+	generate := flag.String("generate", "", "создать файл с новым ключом по указанному пути и завершить работу")
+	flag.Parse()
+
+	if *generate != "" {
+		if err := kek.GenerateKeyFile(*generate); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("A new key encryption key has been written to %s\n", *generate)
+		return
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	if err := run(ctx); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func run(ctx context.Context) error {
+	cfg := config.NewConfig()
+	err := cfg.Init()
+	if err != nil {
+		return err
+	}
+	appLog, err := logger.NewLogger(cfg.Value().LogLevel)
+	if err != nil {
+		return err
+	}
+
+	keyProvider, err := kek.NewLocalFileProvider(cfg)
+	if err != nil {
+		return err
+	}
+
+	store, err := storage.NewPostgres(cfg.Value().Dsn)
+	if err != nil {
+		return err
+	}
+	err = store.Ping(ctx)
+	if err != nil {
+		return err
+	}
+	users, err := repository.NewUserRepository(store.DB)
+	if err != nil {
+		return err
+	}
+	devices, err := repository.NewDeviceRepository(store.DB)
+	if err != nil {
+		return err
+	}
+
+	appLog.Infof("Rewrapping client keys with the key encryption key %q", keyProvider.ActiveKeyID())
+	result, err := kek.Rewrap(ctx, users, keyProvider, func(userUUID string, err error) {
+		appLog.Errorf("The key of user %s cannot be unwrapped: %s", userUUID, err)
+	})
+	appLog.Infof("Total: %d, rewrapped: %d, skipped: %d, failed: %d", result.Total, result.Rewrapped, result.Skipped, result.Failed)
+	if err != nil {
+		return err
+	}
+	failed := result.Failed
+
+	appLog.Infof("Rewrapping device client keys with the key encryption key %q", keyProvider.ActiveKeyID())
+	result, err = kek.RewrapDevices(ctx, devices, keyProvider, func(deviceUUID string, err error) {
+		appLog.Errorf("The key of device %s cannot be unwrapped: %s", deviceUUID, err)
+	})
+	appLog.Infof("Total: %d, rewrapped: %d, skipped: %d, failed: %d", result.Total, result.Rewrapped, result.Skipped, result.Failed)
+	if err != nil {
+		return err
+	}
+	failed += result.Failed
+	if failed > 0 {
+		return fmt.Errorf("%d keys were not rewrapped", failed)
+	}
+	return nil
+}
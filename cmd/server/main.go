@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"crypto/rand"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"log"
@@ -23,6 +24,10 @@ import (
 	"github.com/northmule/gophkeeper/internal/server/repository"
 	service "github.com/northmule/gophkeeper/internal/server/services"
 	"github.com/northmule/gophkeeper/internal/server/services/access"
+	"github.com/northmule/gophkeeper/internal/server/services/ca"
+	"github.com/northmule/gophkeeper/internal/server/services/events"
+	"github.com/northmule/gophkeeper/internal/server/services/kek"
+	"github.com/northmule/gophkeeper/internal/server/services/rotation"
 	"github.com/northmule/gophkeeper/internal/server/storage"
 )
 
@@ -78,8 +83,12 @@ func run(ctx context.Context) error {
 	}
 
 	log.Info("Preparing server keys")
+	keyGenerator, err := signers.New(cfg.Value().KeyAlgorithm)
+	if err != nil {
+		return err
+	}
 	serverKeys := keys.NewKeys(keys.Options{
-		Generator:    signers.NewRsaSigner(),
+		Generator:    keyGenerator,
 		SavePath:     cfg.Value().PathKeys,
 		Organization: "Go32_Server",
 		Country:      "RU",
@@ -106,46 +115,54 @@ func run(ctx context.Context) error {
 	}
 
 	log.Info("Preparing the server for launch")
-	accessService := access.NewAccess(cfg)
-	cryptService, err := service.NewCrypt(cfg)
-	if err != nil {
-		return err
-	}
-
-	log.Info("Initializing the Repository Manager")
-	userRepository, err := repository.NewUserRepository(store.DB)
+	accessService, err := access.NewAccess(cfg)
 	if err != nil {
 		return err
 	}
-	cardDataRepository, err := repository.NewCardDataRepository(store.DB)
+	cryptService, err := service.NewCrypt(cfg)
 	if err != nil {
 		return err
 	}
-	ownerRepository, err := repository.NewOwnerRepository(store.DB)
+	keyProvider, err := kek.NewLocalFileProvider(cfg)
 	if err != nil {
 		return err
 	}
-	metaDataRepository, err := repository.NewMetaDataRepository(store.DB)
-	if err != nil {
-		return err
+	log.Infof("Client keys are encrypted with the key encryption key %q", keyProvider.ActiveKeyID())
+
+	var certIssuer handlers.CertificateIssuer
+	var authority *ca.Authority
+	if cfg.Value().MTLSEnable {
+		if !cfg.Value().TLSEnable {
+			return errors.New("MTLS_ENABLE requires TLS_ENABLE")
+		}
+		authority, err = ca.NewAuthority(cfg)
+		if err != nil {
+			return err
+		}
+		certIssuer = authority
+		log.Info("Client certificate authentication is enabled")
 	}
-	textDataRepository, err := repository.NewTextDataRepository(store.DB)
+
+	log.Info("Initializing the Repository Manager")
+	repositoryManager, err := repository.NewManager(store.DB)
 	if err != nil {
 		return err
 	}
-	fileDataRepository, err := repository.NewFileDataRepository(store.DB)
+
+	log.Info("Resuming unfinished client key rotations")
+	keyRotator := rotation.NewRotator(ctx, repositoryManager.KeyRotation(), keyProvider, log)
+	err = keyRotator.Resume()
 	if err != nil {
 		return err
 	}
+	defer keyRotator.Wait()
+
+	log.Info("Listening to data events")
+	eventsHub := events.NewHub()
+	go events.NewListener(store, eventsHub, log).Run(ctx)
 
 	log.Info("Initializing the Routes")
-	routes := handlers.NewAppRoutes(store.DB, storage.NewSession(), log, cfg, accessService, cryptService).
-		SetFileDataRepository(fileDataRepository).
-		SetCardDataRepository(cardDataRepository).
-		SetMetaDataRepository(metaDataRepository).
-		SetOwnerRepository(ownerRepository).
-		SetTextDataRepository(textDataRepository).
-		SetUserRepository(userRepository)
+	routes := handlers.NewAppRoutes(repositoryManager, store.DB, storage.NewSession(), log, cfg, accessService, cryptService, keyProvider, certIssuer, keyRotator, eventsHub)
 
 	httpServer := http.Server{
 		Addr:    cfg.Value().Address,
@@ -166,8 +183,29 @@ func run(ctx context.Context) error {
 		}
 	}()
 
-	log.Infof("Running server on - %s", cfg.Value().Address)
-	err = httpServer.ListenAndServe()
+	if cfg.Value().TLSEnable {
+		certFile, keyFile := cfg.Value().TLSCertFile, cfg.Value().TLSKeyFile
+		if certFile == "" || keyFile == "" {
+			certFile, keyFile = serverKeys.CertPath(), serverKeys.PrivateKeyPath()
+		}
+		var fingerprint string
+		fingerprint, err = keys.CertificateFileFingerprint(certFile)
+		if err != nil {
+			return err
+		}
+		httpServer.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+		if authority != nil {
+			// сертификат не обязателен: без него работает вход по паролю
+			httpServer.TLSConfig.ClientAuth = tls.VerifyClientCertIfGiven
+			httpServer.TLSConfig.ClientCAs = authority.Pool()
+		}
+		log.Infof("Running server on - https://%s, certificate %s, SHA-256 fingerprint %s", cfg.Value().Address, certFile, fingerprint)
+		err = httpServer.ListenAndServeTLS(certFile, keyFile)
+	} else {
+		log.Warn("TLS is disabled, tokens and data are transmitted in plain text")
+		log.Infof("Running server on - %s", cfg.Value().Address)
+		err = httpServer.ListenAndServe()
+	}
 	if err != nil && !errors.Is(err, http.ErrServerClosed) {
 		return err
 	}
@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE public.credential_data (
+      id int8 GENERATED ALWAYS AS IDENTITY NOT NULL,
+      "uuid" uuid NOT NULL,
+      value jsonb NOT NULL,
+      object_type varchar(50) NOT NULL,
+      "name" varchar(300) NOT NULL,
+      CONSTRAINT credential_data_pk PRIMARY KEY (id),
+      CONSTRAINT credential_data_uuid_unique UNIQUE (uuid)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS credential_data;
+-- +goose StatementEnd
@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE public.users ADD COLUMN master_key_salt varchar(100) DEFAULT '' NOT NULL;
+ALTER TABLE public.users ADD COLUMN master_key_check text DEFAULT '' NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE public.users DROP COLUMN master_key_salt;
+ALTER TABLE public.users DROP COLUMN master_key_check;
+-- +goose StatementEnd
@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE public.sessions (
+      id int8 GENERATED ALWAYS AS IDENTITY NOT NULL,
+      "uuid" uuid NOT NULL,
+      user_uuid uuid NOT NULL,
+      refresh_token_hash varchar(64) NOT NULL,
+      previous_refresh_token_hash varchar(64) DEFAULT '' NOT NULL,
+      created_at timestamp DEFAULT now() NOT NULL,
+      expires_at timestamp NOT NULL,
+      revoked_at timestamp NULL,
+      CONSTRAINT sessions_pk PRIMARY KEY (id),
+      CONSTRAINT sessions_uuid_unique UNIQUE (uuid)
+);
+CREATE INDEX sessions_user_uuid_idx ON public.sessions (user_uuid);
+CREATE UNIQUE INDEX sessions_refresh_token_hash_idx ON public.sessions (refresh_token_hash);
+CREATE INDEX sessions_previous_refresh_token_hash_idx ON public.sessions (previous_refresh_token_hash);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS sessions;
+-- +goose StatementEnd
@@ -0,0 +1,27 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE public.user_totp (
+      id int8 GENERATED ALWAYS AS IDENTITY NOT NULL,
+      user_uuid uuid NOT NULL,
+      secret varchar(64) NOT NULL,
+      enabled bool DEFAULT false NOT NULL,
+      last_step int8 DEFAULT 0 NOT NULL,
+      created_at timestamp DEFAULT now() NOT NULL,
+      CONSTRAINT user_totp_pk PRIMARY KEY (id),
+      CONSTRAINT user_totp_user_uuid_unique UNIQUE (user_uuid)
+);
+CREATE TABLE public.totp_backup_codes (
+      id int8 GENERATED ALWAYS AS IDENTITY NOT NULL,
+      user_uuid uuid NOT NULL,
+      code_hash varchar(64) NOT NULL,
+      used_at timestamp NULL,
+      CONSTRAINT totp_backup_codes_pk PRIMARY KEY (id)
+);
+CREATE INDEX totp_backup_codes_user_uuid_idx ON public.totp_backup_codes (user_uuid, code_hash);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS totp_backup_codes;
+DROP TABLE IF EXISTS user_totp;
+-- +goose StatementEnd
@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE public.client_certificates (
+      id int8 GENERATED ALWAYS AS IDENTITY NOT NULL,
+      user_uuid uuid NOT NULL,
+      fingerprint varchar(64) NOT NULL,
+      public_key_hash varchar(64) NOT NULL,
+      serial_number varchar(64) NOT NULL,
+      created_at timestamp DEFAULT now() NOT NULL,
+      expires_at timestamp NOT NULL,
+      revoked_at timestamp NULL,
+      CONSTRAINT client_certificates_pk PRIMARY KEY (id),
+      CONSTRAINT client_certificates_fingerprint_unique UNIQUE (fingerprint)
+);
+CREATE INDEX client_certificates_user_uuid_idx ON public.client_certificates (user_uuid, public_key_hash);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS client_certificates;
+-- +goose StatementEnd
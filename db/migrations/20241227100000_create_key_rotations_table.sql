@@ -0,0 +1,27 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE public.key_rotations (
+      id int8 GENERATED ALWAYS AS IDENTITY NOT NULL,
+      uuid uuid NOT NULL,
+      user_uuid uuid NOT NULL,
+      new_client_key text NOT NULL,
+      status varchar(16) NOT NULL,
+      stage varchar(64) DEFAULT '' NOT NULL,
+      "cursor" varchar(255) DEFAULT '' NOT NULL,
+      processed int8 DEFAULT 0 NOT NULL,
+      error text DEFAULT '' NOT NULL,
+      created_at timestamp DEFAULT now() NOT NULL,
+      updated_at timestamp DEFAULT now() NOT NULL,
+      finished_at timestamp NULL,
+      CONSTRAINT key_rotations_pk PRIMARY KEY (id),
+      CONSTRAINT key_rotations_uuid_unique UNIQUE (uuid)
+);
+CREATE INDEX key_rotations_user_uuid_idx ON public.key_rotations (user_uuid, id);
+-- не больше одной незавершённой смены ключа у пользователя
+CREATE UNIQUE INDEX key_rotations_user_uuid_running_unique ON public.key_rotations (user_uuid) WHERE status = 'running';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS key_rotations;
+-- +goose StatementEnd
@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE public.recovery_kits (
+      id int8 GENERATED ALWAYS AS IDENTITY NOT NULL,
+      user_uuid uuid NOT NULL,
+      vault_key text NOT NULL,
+      client_key text NOT NULL,
+      created_at timestamp DEFAULT now() NOT NULL,
+      updated_at timestamp DEFAULT now() NOT NULL,
+      CONSTRAINT recovery_kits_pk PRIMARY KEY (id),
+      CONSTRAINT recovery_kits_user_uuid_unique UNIQUE (user_uuid)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS recovery_kits;
+-- +goose StatementEnd
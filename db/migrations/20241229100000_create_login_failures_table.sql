@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE public.login_failures (
+      id int8 GENERATED ALWAYS AS IDENTITY NOT NULL,
+      login varchar(50) NOT NULL,
+      failures int4 DEFAULT 0 NOT NULL,
+      failed_at timestamp DEFAULT now() NOT NULL,
+      locked_until timestamp NULL,
+      CONSTRAINT login_failures_pk PRIMARY KEY (id),
+      CONSTRAINT login_failures_login_unique UNIQUE (login)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS login_failures;
+-- +goose StatementEnd
@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE public.audit_events (
+      id int8 GENERATED ALWAYS AS IDENTITY NOT NULL,
+      user_uuid uuid NOT NULL,
+      action varchar(50) NOT NULL,
+      data_uuid varchar(36) DEFAULT '' NOT NULL,
+      ip varchar(64) DEFAULT '' NOT NULL,
+      details varchar(255) DEFAULT '' NOT NULL,
+      prev_hash varchar(64) NOT NULL,
+      hash varchar(64) NOT NULL,
+      created_at timestamp NOT NULL,
+      CONSTRAINT audit_events_pk PRIMARY KEY (id),
+      CONSTRAINT audit_events_chain_unique UNIQUE (user_uuid, prev_hash)
+);
+CREATE INDEX audit_events_user_uuid_idx ON public.audit_events (user_uuid, id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS audit_events;
+-- +goose StatementEnd
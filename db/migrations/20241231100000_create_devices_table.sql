@@ -0,0 +1,28 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE public.devices (
+      id int8 GENERATED ALWAYS AS IDENTITY NOT NULL,
+      "uuid" uuid NOT NULL,
+      user_uuid uuid NOT NULL,
+      "name" varchar(100) NOT NULL,
+      public_key text DEFAULT '' NOT NULL,
+      vault_key text DEFAULT '' NOT NULL,
+      created_at timestamp DEFAULT now() NOT NULL,
+      last_seen_at timestamp DEFAULT now() NOT NULL,
+      revoked_at timestamp NULL,
+      CONSTRAINT devices_pk PRIMARY KEY (id),
+      CONSTRAINT devices_uuid_unique UNIQUE (uuid)
+);
+CREATE INDEX devices_user_uuid_idx ON public.devices (user_uuid);
+ALTER TABLE public.sessions ADD device_uuid uuid NULL;
+CREATE INDEX sessions_device_uuid_idx ON public.sessions (device_uuid);
+ALTER TABLE public.client_certificates ADD device_uuid uuid NULL;
+CREATE INDEX client_certificates_device_uuid_idx ON public.client_certificates (device_uuid);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE public.client_certificates DROP COLUMN IF EXISTS device_uuid;
+ALTER TABLE public.sessions DROP COLUMN IF EXISTS device_uuid;
+DROP TABLE IF EXISTS devices;
+-- +goose StatementEnd
@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE public.idempotency_keys (
+      id int8 GENERATED ALWAYS AS IDENTITY NOT NULL,
+      user_uuid uuid NOT NULL,
+      idempotency_key varchar(100) NOT NULL,
+      request_hash varchar(64) NOT NULL,
+      status_code int4 NULL,
+      response text NULL,
+      created_at timestamp DEFAULT now() NOT NULL,
+      CONSTRAINT idempotency_keys_pk PRIMARY KEY (id),
+      CONSTRAINT idempotency_keys_user_key_unique UNIQUE (user_uuid, idempotency_key)
+);
+CREATE INDEX idempotency_keys_created_at_idx ON public.idempotency_keys (created_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS idempotency_keys;
+-- +goose StatementEnd
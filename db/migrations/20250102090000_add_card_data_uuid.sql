@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+-- uuid связывает данные карты с владельцем (owner.data_uuid). На базах, где колонка уже добавлена вручную,
+-- она остаётся, строкам без uuid выдаётся новый: владелец на них не ссылается
+ALTER TABLE public.card_data ADD COLUMN IF NOT EXISTS "uuid" uuid NULL;
+UPDATE public.card_data SET "uuid" = gen_random_uuid() WHERE "uuid" IS NULL;
+ALTER TABLE public.card_data ALTER COLUMN "uuid" SET NOT NULL;
+ALTER TABLE public.card_data ADD CONSTRAINT card_data_uuid_unique UNIQUE ("uuid");
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE card_data DROP CONSTRAINT IF EXISTS card_data_uuid_unique;
+ALTER TABLE card_data DROP COLUMN IF EXISTS "uuid";
+-- +goose StatementEnd
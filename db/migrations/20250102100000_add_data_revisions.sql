@@ -0,0 +1,85 @@
+-- +goose Up
+-- +goose StatementBegin
+-- общая последовательность ревизий данных всех типов: курсор синхронизации клиента
+CREATE SEQUENCE public.data_revision_seq;
+
+ALTER TABLE public.card_data
+    ADD COLUMN created_at timestamptz DEFAULT now() NOT NULL,
+    ADD COLUMN updated_at timestamptz DEFAULT now() NOT NULL,
+    ADD COLUMN revision int8 DEFAULT nextval('data_revision_seq') NOT NULL;
+ALTER TABLE public.text_data
+    ADD COLUMN created_at timestamptz DEFAULT now() NOT NULL,
+    ADD COLUMN updated_at timestamptz DEFAULT now() NOT NULL,
+    ADD COLUMN revision int8 DEFAULT nextval('data_revision_seq') NOT NULL;
+ALTER TABLE public.credential_data
+    ADD COLUMN created_at timestamptz DEFAULT now() NOT NULL,
+    ADD COLUMN updated_at timestamptz DEFAULT now() NOT NULL,
+    ADD COLUMN revision int8 DEFAULT nextval('data_revision_seq') NOT NULL;
+ALTER TABLE public.file_data
+    ADD COLUMN created_at timestamptz DEFAULT now() NOT NULL,
+    ADD COLUMN updated_at timestamptz DEFAULT now() NOT NULL,
+    ADD COLUMN revision int8 DEFAULT nextval('data_revision_seq') NOT NULL;
+
+CREATE INDEX card_data_revision_idx ON public.card_data (revision);
+CREATE INDEX text_data_revision_idx ON public.text_data (revision);
+CREATE INDEX credential_data_revision_idx ON public.credential_data (revision);
+CREATE INDEX file_data_revision_idx ON public.file_data (revision);
+
+-- удалённые данные для синхронизации клиентов
+CREATE TABLE public.deleted_items (
+    id int8 GENERATED ALWAYS AS IDENTITY NOT NULL,
+    user_uuid uuid NOT NULL,
+    data_type varchar(50) NOT NULL,
+    data_uuid uuid NOT NULL,
+    revision int8 DEFAULT nextval('data_revision_seq') NOT NULL,
+    deleted_at timestamptz DEFAULT now() NOT NULL,
+    CONSTRAINT deleted_items_pk PRIMARY KEY (id)
+);
+CREATE INDEX deleted_items_user_uuid_revision_idx ON public.deleted_items (user_uuid, revision);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+-- любое изменение строки данных получает новую ревизию
+CREATE FUNCTION public.data_revision_touch() RETURNS trigger AS $$
+BEGIN
+    NEW.revision := nextval('data_revision_seq');
+    NEW.updated_at := now();
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+-- удаление владельца данных оставляет запись об удалении
+CREATE FUNCTION public.owner_deleted() RETURNS trigger AS $$
+BEGIN
+    INSERT INTO deleted_items (user_uuid, data_type, data_uuid) VALUES (OLD.user_uuid, OLD.data_type, OLD.data_uuid);
+    RETURN OLD;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER card_data_revision BEFORE UPDATE ON public.card_data FOR EACH ROW EXECUTE FUNCTION data_revision_touch();
+CREATE TRIGGER text_data_revision BEFORE UPDATE ON public.text_data FOR EACH ROW EXECUTE FUNCTION data_revision_touch();
+CREATE TRIGGER credential_data_revision BEFORE UPDATE ON public.credential_data FOR EACH ROW EXECUTE FUNCTION data_revision_touch();
+CREATE TRIGGER file_data_revision BEFORE UPDATE ON public.file_data FOR EACH ROW EXECUTE FUNCTION data_revision_touch();
+CREATE TRIGGER owner_deleted AFTER DELETE ON public."owner" FOR EACH ROW EXECUTE FUNCTION owner_deleted();
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TRIGGER IF EXISTS owner_deleted ON "owner";
+DROP TRIGGER IF EXISTS file_data_revision ON file_data;
+DROP TRIGGER IF EXISTS credential_data_revision ON credential_data;
+DROP TRIGGER IF EXISTS text_data_revision ON text_data;
+DROP TRIGGER IF EXISTS card_data_revision ON card_data;
+DROP FUNCTION IF EXISTS owner_deleted();
+DROP FUNCTION IF EXISTS data_revision_touch();
+DROP TABLE IF EXISTS deleted_items;
+ALTER TABLE file_data DROP COLUMN revision, DROP COLUMN updated_at, DROP COLUMN created_at;
+ALTER TABLE credential_data DROP COLUMN revision, DROP COLUMN updated_at, DROP COLUMN created_at;
+ALTER TABLE text_data DROP COLUMN revision, DROP COLUMN updated_at, DROP COLUMN created_at;
+ALTER TABLE card_data DROP COLUMN revision, DROP COLUMN updated_at, DROP COLUMN created_at;
+DROP SEQUENCE IF EXISTS data_revision_seq;
+-- +goose StatementEnd
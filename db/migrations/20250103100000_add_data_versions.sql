@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+-- версия данных для оптимистичной блокировки: растёт только при сохранении пользователем
+ALTER TABLE public.card_data ADD COLUMN "version" int8 DEFAULT 1 NOT NULL;
+ALTER TABLE public.text_data ADD COLUMN "version" int8 DEFAULT 1 NOT NULL;
+ALTER TABLE public.credential_data ADD COLUMN "version" int8 DEFAULT 1 NOT NULL;
+ALTER TABLE public.file_data ADD COLUMN "version" int8 DEFAULT 1 NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE file_data DROP COLUMN "version";
+ALTER TABLE credential_data DROP COLUMN "version";
+ALTER TABLE text_data DROP COLUMN "version";
+ALTER TABLE card_data DROP COLUMN "version";
+-- +goose StatementEnd
@@ -0,0 +1,60 @@
+-- +goose Up
+-- +goose StatementBegin
+-- события изменения данных для подключённых клиентов всех экземпляров сервера (LISTEN data_events).
+-- Уведомления доставляются после фиксации транзакции
+CREATE FUNCTION public.owner_event_notify() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        PERFORM pg_notify('data_events', json_build_object('type', 'item_created', 'user_uuid', NEW.user_uuid, 'data_type', NEW.data_type, 'data_uuid', NEW.data_uuid)::text);
+        RETURN NEW;
+    END IF;
+    PERFORM pg_notify('data_events', json_build_object('type', 'item_deleted', 'user_uuid', OLD.user_uuid, 'data_type', OLD.data_type, 'data_uuid', OLD.data_uuid)::text);
+    RETURN OLD;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE FUNCTION public.data_event_notify() RETURNS trigger AS $$
+BEGIN
+    PERFORM pg_notify('data_events', json_build_object('type', 'item_updated', 'user_uuid', o.user_uuid, 'data_type', o.data_type, 'data_uuid', o.data_uuid, 'revision', NEW.revision)::text)
+    FROM "owner" o
+    WHERE o.data_uuid = NEW.uuid;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+-- смена ключа клиента завершена: клиенты переходят на новый ключ
+CREATE FUNCTION public.key_rotation_event_notify() RETURNS trigger AS $$
+BEGIN
+    IF NEW.status = 'finished' AND OLD.status <> 'finished' THEN
+        PERFORM pg_notify('data_events', json_build_object('type', 'key_rotated', 'user_uuid', NEW.user_uuid)::text);
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER owner_event AFTER INSERT OR DELETE ON public."owner" FOR EACH ROW EXECUTE FUNCTION owner_event_notify();
+CREATE TRIGGER card_data_event AFTER UPDATE ON public.card_data FOR EACH ROW EXECUTE FUNCTION data_event_notify();
+CREATE TRIGGER text_data_event AFTER UPDATE ON public.text_data FOR EACH ROW EXECUTE FUNCTION data_event_notify();
+CREATE TRIGGER credential_data_event AFTER UPDATE ON public.credential_data FOR EACH ROW EXECUTE FUNCTION data_event_notify();
+CREATE TRIGGER file_data_event AFTER UPDATE ON public.file_data FOR EACH ROW EXECUTE FUNCTION data_event_notify();
+CREATE TRIGGER key_rotation_event AFTER UPDATE ON public.key_rotations FOR EACH ROW EXECUTE FUNCTION key_rotation_event_notify();
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TRIGGER IF EXISTS key_rotation_event ON key_rotations;
+DROP TRIGGER IF EXISTS file_data_event ON file_data;
+DROP TRIGGER IF EXISTS credential_data_event ON credential_data;
+DROP TRIGGER IF EXISTS text_data_event ON text_data;
+DROP TRIGGER IF EXISTS card_data_event ON card_data;
+DROP TRIGGER IF EXISTS owner_event ON "owner";
+DROP FUNCTION IF EXISTS key_rotation_event_notify();
+DROP FUNCTION IF EXISTS data_event_notify();
+DROP FUNCTION IF EXISTS owner_event_notify();
+-- +goose StatementEnd
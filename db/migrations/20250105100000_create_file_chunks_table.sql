@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+-- число частей, на которые клиент разбил содержимое файла (0 - файл загружен одним запросом до частей)
+ALTER TABLE public.file_data ADD COLUMN chunks int4 DEFAULT 0 NOT NULL;
+-- принятые части файла, повтор части заменяет запись
+CREATE TABLE public.file_chunks (
+      id int8 GENERATED ALWAYS AS IDENTITY NOT NULL,
+      file_uuid uuid NOT NULL,
+      part int4 NOT NULL,
+      "size" int8 NOT NULL,
+      checksum varchar(64) NOT NULL,
+      created_at timestamp DEFAULT now() NOT NULL,
+      CONSTRAINT file_chunks_pk PRIMARY KEY (id),
+      CONSTRAINT file_chunks_file_part_unique UNIQUE (file_uuid, part)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS file_chunks;
+ALTER TABLE file_data DROP COLUMN chunks;
+-- +goose StatementEnd
@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE public.login_challenges (
+      id int8 GENERATED ALWAYS AS IDENTITY NOT NULL,
+      token_hash varchar(64) NOT NULL,
+      user_uuid uuid NOT NULL,
+      attempts int4 DEFAULT 0 NOT NULL,
+      created_at timestamp DEFAULT now() NOT NULL,
+      expires_at timestamp NOT NULL,
+      CONSTRAINT login_challenges_pk PRIMARY KEY (id),
+      CONSTRAINT login_challenges_token_hash_unique UNIQUE (token_hash)
+);
+CREATE INDEX login_challenges_expires_at_idx ON public.login_challenges (expires_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS login_challenges;
+-- +goose StatementEnd
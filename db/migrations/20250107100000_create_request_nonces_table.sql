@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE public.request_nonces (
+      id int8 GENERATED ALWAYS AS IDENTITY NOT NULL,
+      user_uuid uuid NOT NULL,
+      nonce varchar(64) NOT NULL,
+      expires_at timestamp NOT NULL,
+      CONSTRAINT request_nonces_pk PRIMARY KEY (id),
+      CONSTRAINT request_nonces_user_nonce_unique UNIQUE (user_uuid, nonce)
+);
+CREATE INDEX request_nonces_user_expires_at_idx ON public.request_nonces (user_uuid, expires_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS request_nonces;
+-- +goose StatementEnd
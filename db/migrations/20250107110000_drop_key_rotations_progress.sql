@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE public.key_rotations DROP COLUMN stage, DROP COLUMN "cursor", DROP COLUMN processed;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE public.key_rotations ADD COLUMN stage varchar(64) DEFAULT '' NOT NULL,
+      ADD COLUMN "cursor" varchar(255) DEFAULT '' NOT NULL,
+      ADD COLUMN processed int8 DEFAULT 0 NOT NULL;
+-- +goose StatementEnd
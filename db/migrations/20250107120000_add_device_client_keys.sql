@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+-- ключ шифрования клиента у каждого устройства, users.private_client_key остаётся для сессий без устройства
+ALTER TABLE public.devices ADD client_key text DEFAULT '' NOT NULL;
+ALTER TABLE public.key_rotations ADD device_uuid uuid NULL;
+DROP INDEX IF EXISTS key_rotations_user_uuid_running_unique;
+-- не больше одной незавершённой смены ключа у устройства
+CREATE UNIQUE INDEX key_rotations_device_running_unique ON public.key_rotations (user_uuid, device_uuid) WHERE status = 'running';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS key_rotations_device_running_unique;
+DELETE FROM public.key_rotations WHERE status = 'running' AND device_uuid IS NOT NULL;
+CREATE UNIQUE INDEX key_rotations_user_uuid_running_unique ON public.key_rotations (user_uuid) WHERE status = 'running';
+ALTER TABLE public.key_rotations DROP COLUMN IF EXISTS device_uuid;
+ALTER TABLE public.devices DROP COLUMN IF EXISTS client_key;
+-- +goose StatementEnd
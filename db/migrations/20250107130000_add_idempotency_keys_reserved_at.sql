@@ -0,0 +1,9 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE public.idempotency_keys ADD COLUMN reserved_at timestamp DEFAULT now() NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE public.idempotency_keys DROP COLUMN reserved_at;
+-- +goose StatementEnd
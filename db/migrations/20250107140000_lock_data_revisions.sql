@@ -0,0 +1,64 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Ревизия выдаётся под разделяемой advisory-блокировкой, которая держится до конца транзакции.
+-- ChangesSince берёт эту блокировку монопольно и ждёт транзакции, уже получившие ревизию: иначе клиент мог бы
+-- сдвинуть курсор за ревизию транзакции, зафиксированной позже транзакции с большей ревизией.
+-- Значение по умолчанию колонки revision вычисляется до триггера, поэтому ревизия вставки тоже выдаётся в триггере
+CREATE OR REPLACE FUNCTION public.data_revision_touch() RETURNS trigger AS $$
+BEGIN
+    PERFORM pg_advisory_xact_lock_shared(hashtext('data_revision_seq'));
+    NEW.revision := nextval('data_revision_seq');
+    NEW.updated_at := now();
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE FUNCTION public.deleted_item_revision() RETURNS trigger AS $$
+BEGIN
+    PERFORM pg_advisory_xact_lock_shared(hashtext('data_revision_seq'));
+    NEW.revision := nextval('data_revision_seq');
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+DROP TRIGGER IF EXISTS card_data_revision ON card_data;
+DROP TRIGGER IF EXISTS text_data_revision ON text_data;
+DROP TRIGGER IF EXISTS credential_data_revision ON credential_data;
+DROP TRIGGER IF EXISTS file_data_revision ON file_data;
+CREATE TRIGGER card_data_revision BEFORE INSERT OR UPDATE ON public.card_data FOR EACH ROW EXECUTE FUNCTION data_revision_touch();
+CREATE TRIGGER text_data_revision BEFORE INSERT OR UPDATE ON public.text_data FOR EACH ROW EXECUTE FUNCTION data_revision_touch();
+CREATE TRIGGER credential_data_revision BEFORE INSERT OR UPDATE ON public.credential_data FOR EACH ROW EXECUTE FUNCTION data_revision_touch();
+CREATE TRIGGER file_data_revision BEFORE INSERT OR UPDATE ON public.file_data FOR EACH ROW EXECUTE FUNCTION data_revision_touch();
+CREATE TRIGGER deleted_item_revision BEFORE INSERT ON public.deleted_items FOR EACH ROW EXECUTE FUNCTION deleted_item_revision();
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TRIGGER IF EXISTS deleted_item_revision ON deleted_items;
+DROP TRIGGER IF EXISTS file_data_revision ON file_data;
+DROP TRIGGER IF EXISTS credential_data_revision ON credential_data;
+DROP TRIGGER IF EXISTS text_data_revision ON text_data;
+DROP TRIGGER IF EXISTS card_data_revision ON card_data;
+DROP FUNCTION IF EXISTS deleted_item_revision();
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION public.data_revision_touch() RETURNS trigger AS $$
+BEGIN
+    NEW.revision := nextval('data_revision_seq');
+    NEW.updated_at := now();
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER card_data_revision BEFORE UPDATE ON public.card_data FOR EACH ROW EXECUTE FUNCTION data_revision_touch();
+CREATE TRIGGER text_data_revision BEFORE UPDATE ON public.text_data FOR EACH ROW EXECUTE FUNCTION data_revision_touch();
+CREATE TRIGGER credential_data_revision BEFORE UPDATE ON public.credential_data FOR EACH ROW EXECUTE FUNCTION data_revision_touch();
+CREATE TRIGGER file_data_revision BEFORE UPDATE ON public.file_data FOR EACH ROW EXECUTE FUNCTION data_revision_touch();
+-- +goose StatementEnd
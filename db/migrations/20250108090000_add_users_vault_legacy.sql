@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE public.users ADD COLUMN vault_legacy boolean DEFAULT false NOT NULL;
+-- данные, сохранённые до шифрования мастер-ключом, клиент перешифрует при первой разблокировке
+UPDATE public.users SET vault_legacy = true WHERE EXISTS (SELECT 1 FROM public."owner" o WHERE o.user_uuid = users.uuid);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE public.users DROP COLUMN vault_legacy;
+-- +goose StatementEnd
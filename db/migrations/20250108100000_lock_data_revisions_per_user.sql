@@ -0,0 +1,105 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Блокировка ревизий берётся по пользователю-владельцу: ChangesSince ждёт только транзакции своего пользователя.
+-- Строка данных без владельца (вставка до записи в owner) получает ревизию без блокировки,
+-- поэтому вставка владельца выдаёт данным новую ревизию уже под блокировкой
+CREATE OR REPLACE FUNCTION public.data_revision_touch() RETURNS trigger AS $$
+DECLARE
+    owner_uuid uuid;
+BEGIN
+    SELECT o.user_uuid INTO owner_uuid FROM "owner" o WHERE o.data_uuid = NEW.uuid LIMIT 1;
+    IF owner_uuid IS NOT NULL THEN
+        PERFORM pg_advisory_xact_lock_shared(hashtext('data_revision:' || owner_uuid::text));
+    END IF;
+    NEW.revision := nextval('data_revision_seq');
+    NEW.updated_at := now();
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION public.deleted_item_revision() RETURNS trigger AS $$
+BEGIN
+    PERFORM pg_advisory_xact_lock_shared(hashtext('data_revision:' || NEW.user_uuid::text));
+    NEW.revision := nextval('data_revision_seq');
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE FUNCTION public.owner_data_revision() RETURNS trigger AS $$
+BEGIN
+    IF NEW.data_type = 'card_type' THEN
+        UPDATE card_data SET revision = revision WHERE uuid = NEW.data_uuid;
+    ELSIF NEW.data_type = 'text_type' THEN
+        UPDATE text_data SET revision = revision WHERE uuid = NEW.data_uuid;
+    ELSIF NEW.data_type = 'credential_type' THEN
+        UPDATE credential_data SET revision = revision WHERE uuid = NEW.data_uuid;
+    ELSIF NEW.data_type = 'binary_type' THEN
+        UPDATE file_data SET revision = revision WHERE uuid = NEW.data_uuid;
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+-- повторная выдача ревизии при вставке владельца не является изменением данных для клиентов
+CREATE OR REPLACE FUNCTION public.data_event_notify() RETURNS trigger AS $$
+BEGIN
+    IF pg_trigger_depth() > 1 THEN
+        RETURN NEW;
+    END IF;
+    PERFORM pg_notify('data_events', json_build_object('type', 'item_updated', 'user_uuid', o.user_uuid, 'data_type', o.data_type, 'data_uuid', o.data_uuid, 'revision', NEW.revision)::text)
+    FROM "owner" o
+    WHERE o.data_uuid = NEW.uuid;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE INDEX owner_data_uuid_idx ON public."owner" (data_uuid);
+CREATE TRIGGER owner_data_revision AFTER INSERT ON public."owner" FOR EACH ROW EXECUTE FUNCTION owner_data_revision();
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TRIGGER IF EXISTS owner_data_revision ON "owner";
+DROP INDEX IF EXISTS owner_data_uuid_idx;
+DROP FUNCTION IF EXISTS owner_data_revision();
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION public.data_event_notify() RETURNS trigger AS $$
+BEGIN
+    PERFORM pg_notify('data_events', json_build_object('type', 'item_updated', 'user_uuid', o.user_uuid, 'data_type', o.data_type, 'data_uuid', o.data_uuid, 'revision', NEW.revision)::text)
+    FROM "owner" o
+    WHERE o.data_uuid = NEW.uuid;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION public.deleted_item_revision() RETURNS trigger AS $$
+BEGIN
+    PERFORM pg_advisory_xact_lock_shared(hashtext('data_revision_seq'));
+    NEW.revision := nextval('data_revision_seq');
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION public.data_revision_touch() RETURNS trigger AS $$
+BEGIN
+    PERFORM pg_advisory_xact_lock_shared(hashtext('data_revision_seq'));
+    NEW.revision := nextval('data_revision_seq');
+    NEW.updated_at := now();
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
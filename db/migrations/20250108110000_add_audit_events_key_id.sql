@@ -0,0 +1,10 @@
+-- +goose Up
+-- +goose StatementBegin
+-- kid KEK, от которого получен ключ HMAC записи. Пусто у записей, сделанных до подписи
+ALTER TABLE public.audit_events ADD COLUMN key_id varchar(50) DEFAULT '' NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE public.audit_events DROP COLUMN IF EXISTS key_id;
+-- +goose StatementEnd
@@ -21,6 +21,17 @@ type ServerConfig struct {
 	PathPublicKeyServer string `json:"PathPublicKeyServer"`
 	// Перезаписывать клиентские ключи при старте клиента
 	OverwriteKeys bool `json:"OverwriteKeys"`
+	// Алгоритм создаваемых ключей клиента: rsa, ecdsa, ed25519
+	KeyAlgorithm string `mapstructure:"KeyAlgorithm"`
+	// Отпечаток SHA-256 сертификата сервера (hex, допускаются двоеточия). Для https соединение
+	// устанавливается только с сервером, предъявившим этот сертификат
+	ServerCertFingerprint string `mapstructure:"ServerCertFingerprint"`
+	// Путь к сертификату сервера (cert.pem), альтернатива ServerCertFingerprint
+	ServerCertPath string `mapstructure:"ServerCertPath"`
+	// Число частей ключа хранилища в комплекте восстановления (схема Шамира), 0 - без частей
+	RecoveryShares int `mapstructure:"RecoveryShares"`
+	// Сколько частей достаточно для восстановления
+	RecoveryThreshold int `mapstructure:"RecoveryThreshold"`
 }
 
 // ErrorCfg ошибка конфигурации
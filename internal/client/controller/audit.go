@@ -0,0 +1,148 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/northmule/gophkeeper/internal/client/config"
+	"github.com/northmule/gophkeeper/internal/client/logger"
+	"github.com/northmule/gophkeeper/internal/common/model_data"
+	"golang.org/x/net/context"
+)
+
+// auditHeadFileName число записей и хэш последней записи журнала при прошлой проверке
+const auditHeadFileName = "audit_head"
+
+// ErrAuditTruncated в журнале на сервере меньше записей, чем было при прошлой проверке
+var ErrAuditTruncated = errors.New("из журнала удалены записи")
+
+// AuditFilter отбор записей журнала, пустые значения не ограничивают выборку
+type AuditFilter struct {
+	Action   string
+	DataUUID string
+	Offset   int
+	Limit    int
+}
+
+// Audit контроллер журнала действий
+type Audit struct {
+	logger *logger.Logger
+	cfg    *config.Config
+	client *http.Client
+}
+
+// NewAudit конструктор
+func NewAudit(cfg *config.Config, logger *logger.Logger) *Audit {
+	return &Audit{
+		logger: logger,
+		cfg:    cfg,
+		client: newHTTPClient(cfg),
+	}
+}
+
+// List страница журнала, новые записи первыми
+func (c *Audit) List(token string, filter AuditFilter) (*model_data.AuditListResponse, error) {
+	query := url.Values{}
+	if filter.Action != "" {
+		query.Set("action", filter.Action)
+	}
+	if filter.DataUUID != "" {
+		query.Set("data_uuid", filter.DataUUID)
+	}
+	if filter.Offset > 0 {
+		query.Set("offset", strconv.Itoa(filter.Offset))
+	}
+	if filter.Limit > 0 {
+		query.Set("limit", strconv.Itoa(filter.Limit))
+	}
+	requestURL := fmt.Sprintf("%s/api/v1/audit", c.cfg.Value().ServerAddress)
+	if len(query) > 0 {
+		requestURL += "?" + query.Encode()
+	}
+
+	responseData := new(model_data.AuditListResponse)
+	err := c.get(token, requestURL, responseData)
+	if err != nil {
+		return nil, err
+	}
+	return responseData, nil
+}
+
+// Verify проверка цепочки журнала на сервере. Число записей и хэш последней запоминаются рядом с ключами,
+// так замечается удаление записей с конца журнала, которое сама цепочка не выдаёт
+func (c *Audit) Verify(token string) (*model_data.AuditVerifyResponse, error) {
+	requestURL := fmt.Sprintf("%s/api/v1/audit/verify", c.cfg.Value().ServerAddress)
+	responseData := new(model_data.AuditVerifyResponse)
+	err := c.get(token, requestURL, responseData)
+	if err != nil {
+		return nil, err
+	}
+	if !responseData.Valid {
+		return responseData, nil
+	}
+
+	headPath := path.Join(c.cfg.Value().PathKeys, auditHeadFileName)
+	checked, lastHash := c.readHead(headPath)
+	if responseData.Checked < checked || (responseData.Checked == checked && responseData.LastHash != lastHash) {
+		c.logger.Errorf("audit log is shorter than at the previous check: %d < %d", responseData.Checked, checked)
+		return responseData, ErrAuditTruncated
+	}
+	head := fmt.Sprintf("%d:%s", responseData.Checked, responseData.LastHash)
+	err = os.WriteFile(headPath, []byte(head), 0600)
+	if err != nil {
+		c.logger.Error(err)
+	}
+	return responseData, nil
+}
+
+// readHead результат прошлой проверки, нули если проверок не было
+func (c *Audit) readHead(headPath string) (int64, string) {
+	raw, err := os.ReadFile(headPath)
+	if err != nil {
+		return 0, ""
+	}
+	checkedRaw, lastHash, _ := strings.Cut(strings.TrimSpace(string(raw)), ":")
+	checked, err := strconv.ParseInt(checkedRaw, 10, 64)
+	if err != nil {
+		return 0, ""
+	}
+	return checked, lastHash
+}
+
+func (c *Audit) get(token string, requestURL string, responseData any) error {
+	requestPrepare, err := http.NewRequestWithContext(context.Background(), http.MethodGet, requestURL, nil)
+	if err != nil {
+		return err
+	}
+	requestPrepare.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+	response, err := c.client.Do(requestPrepare)
+	if err != nil {
+		c.logger.Error(err)
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		if response.StatusCode == http.StatusUnauthorized {
+			return fmt.Errorf("вы не авторизованы")
+		}
+		if response.StatusCode == http.StatusBadRequest {
+			return fmt.Errorf("не верные параметры запроса")
+		}
+		return fmt.Errorf("не известная ошибка")
+	}
+
+	err = json.NewDecoder(response.Body).Decode(responseData)
+	if err != nil {
+		c.logger.Error(err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/northmule/gophkeeper/internal/client/logger"
+	"github.com/northmule/gophkeeper/internal/common/model_data"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newAuditTestClient(t *testing.T, serverURL string) *Audit {
+	log, _ := logger.NewLogger("info")
+	cfg := makeMockConfig(serverURL)
+	cfg.Value().PathKeys = t.TempDir()
+	return NewAudit(cfg, log)
+}
+
+func TestAudit_List(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/audit", r.URL.Path)
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		assert.Equal(t, "item_read", r.URL.Query().Get("action"))
+		assert.Equal(t, "20", r.URL.Query().Get("offset"))
+		assert.Equal(t, "10", r.URL.Query().Get("limit"))
+		assert.Empty(t, r.URL.Query().Get("data_uuid"))
+		_ = json.NewEncoder(w).Encode(model_data.AuditListResponse{
+			Events: []model_data.AuditEventResponse{{ID: 3, Action: "item_read", DataUUID: "uuid"}},
+			Offset: 20,
+			Limit:  10,
+		})
+	}))
+	defer server.Close()
+
+	list, err := newAuditTestClient(t, server.URL).List("token", AuditFilter{Action: "item_read", Offset: 20, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, list.Events, 1)
+	assert.Equal(t, int64(3), list.Events[0].ID)
+	assert.Equal(t, 20, list.Offset)
+}
+
+func TestAudit_List_Unauthorized(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	_, err := newAuditTestClient(t, server.URL).List("token", AuditFilter{})
+	assert.EqualError(t, err, "вы не авторизованы")
+}
+
+func TestAudit_Verify(t *testing.T) {
+	verify := model_data.AuditVerifyResponse{Valid: true, Checked: 2, LastHash: "bb"}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/audit/verify", r.URL.Path)
+		_ = json.NewEncoder(w).Encode(verify)
+	}))
+	defer server.Close()
+	client := newAuditTestClient(t, server.URL)
+
+	result, err := client.Verify("token")
+	require.NoError(t, err)
+	assert.True(t, result.Valid)
+
+	// новые записи после прошлой проверки
+	verify = model_data.AuditVerifyResponse{Valid: true, Checked: 3, LastHash: "cc"}
+	_, err = client.Verify("token")
+	require.NoError(t, err)
+
+	// последняя запись удалена
+	verify = model_data.AuditVerifyResponse{Valid: true, Checked: 2, LastHash: "bb"}
+	_, err = client.Verify("token")
+	assert.ErrorIs(t, err, ErrAuditTruncated)
+
+	// записи заменены тем же числом новых
+	verify = model_data.AuditVerifyResponse{Valid: true, Checked: 3, LastHash: "dd"}
+	_, err = client.Verify("token")
+	assert.ErrorIs(t, err, ErrAuditTruncated)
+}
+
+func TestAudit_Verify_Broken(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(model_data.AuditVerifyResponse{Valid: false, Checked: 5, BrokenID: 4})
+	}))
+	defer server.Close()
+
+	result, err := newAuditTestClient(t, server.URL).Verify("token")
+	require.NoError(t, err)
+	assert.False(t, result.Valid)
+	assert.Equal(t, int64(4), result.BrokenID)
+}
@@ -3,12 +3,15 @@ package controller
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"strings"
 
 	"github.com/northmule/gophkeeper/internal/client/config"
 	"github.com/northmule/gophkeeper/internal/client/logger"
+	"github.com/northmule/gophkeeper/internal/common/model_data"
 	"golang.org/x/net/context"
 )
 
@@ -16,6 +19,7 @@ import (
 type Authentication struct {
 	logger *logger.Logger
 	cfg    *config.Config
+	client *http.Client
 }
 
 // NewAuthentication конструктор
@@ -23,6 +27,7 @@ func NewAuthentication(cfg *config.Config, logger *logger.Logger) *Authenticatio
 	return &Authentication{
 		logger: logger,
 		cfg:    cfg,
+		client: newHTTPClient(cfg),
 	}
 }
 
@@ -33,7 +38,11 @@ type authenticationRequest struct {
 
 // AuthenticationResponse ответ
 type AuthenticationResponse struct {
-	Value string
+	Value        string // токен доступа
+	RefreshToken string // токен для получения новой пары токенов
+	ExpiresIn    int64  // время жизни токена доступа в секундах
+	MFAToken     string // не пустой, если для входа нужен код второго фактора (SendCode)
+	DeviceUUID   string // устройство, на котором открыта сессия
 }
 
 // Send отправка запроса к серверу
@@ -57,18 +66,32 @@ func (c *Authentication) Send(login string, password string) (*AuthenticationRes
 		c.logger.Error(err)
 		return nil, err
 	}
-	client := &http.Client{}
-	response, err := client.Do(requestPrepare)
+	setDeviceHeaders(c.cfg, requestPrepare)
+	response, err := c.client.Do(requestPrepare)
 	if err != nil {
 		c.logger.Error(err)
 		return nil, err
 	}
 	defer response.Body.Close()
 
+	// Пароль верный, но нужен код второго фактора
+	if response.StatusCode == http.StatusAccepted {
+		challenge := new(model_data.LoginChallengeResponse)
+		err = json.NewDecoder(response.Body).Decode(challenge)
+		if err != nil {
+			c.logger.Error(err)
+			return nil, err
+		}
+		return &AuthenticationResponse{MFAToken: challenge.MFAToken}, nil
+	}
+
 	if response.StatusCode != http.StatusOK {
 		if response.StatusCode == http.StatusUnauthorized {
 			return nil, fmt.Errorf("не верная пара логин/пароль")
 		}
+		if response.StatusCode == http.StatusTooManyRequests {
+			return nil, tooManyRequestsError(response)
+		}
 
 		return nil, fmt.Errorf("не известная ошибка")
 	}
@@ -78,8 +101,172 @@ func (c *Authentication) Send(login string, password string) (*AuthenticationRes
 	token = strings.Replace(token, "Bearer ", "", 1)
 	token = strings.Trim(token, " ")
 
+	return c.readTokens(response, token)
+}
+
+// SendCode второй шаг входа: код из приложения-аутентификатора или резервный код
+func (c *Authentication) SendCode(mfaToken string, code string) (*AuthenticationResponse, error) {
+	requestURL := fmt.Sprintf("%s/api/v1/login/totp", c.cfg.Value().ServerAddress)
+	ctx := context.Background()
+
+	requestBody, err := json.Marshal(model_data.TOTPLoginRequest{MFAToken: mfaToken, Code: code})
+	if err != nil {
+		c.logger.Error(err)
+		return nil, err
+	}
+	requestPrepare, err := http.NewRequestWithContext(ctx, http.MethodPost, requestURL, bytes.NewBuffer(requestBody))
+	if err != nil {
+		c.logger.Error(err)
+		return nil, err
+	}
+	requestPrepare.Header.Set("Content-Type", "application/json")
+	setDeviceHeaders(c.cfg, requestPrepare)
+	response, err := c.client.Do(requestPrepare)
+	if err != nil {
+		c.logger.Error(err)
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		if response.StatusCode == http.StatusUnauthorized {
+			return nil, fmt.Errorf("не верный код или время на ввод кода истекло")
+		}
+		if response.StatusCode == http.StatusTooManyRequests {
+			return nil, tooManyRequestsError(response)
+		}
+		if response.StatusCode == http.StatusBadRequest {
+			return nil, fmt.Errorf("ошибка в запросе")
+		}
+		return nil, fmt.Errorf("не известная ошибка")
+	}
+
+	return c.readTokens(response, "")
+}
+
+// SendCertificate вход по сертификату клиента (mTLS), выданному сервером при отправке публичного ключа
+func (c *Authentication) SendCertificate() (*AuthenticationResponse, error) {
+	requestURL := fmt.Sprintf("%s/api/v1/login/certificate", c.cfg.Value().ServerAddress)
+	ctx := context.Background()
+
+	requestPrepare, err := http.NewRequestWithContext(ctx, http.MethodPost, requestURL, nil)
+	if err != nil {
+		c.logger.Error(err)
+		return nil, err
+	}
+	setDeviceHeaders(c.cfg, requestPrepare)
+	response, err := c.client.Do(requestPrepare)
+	if err != nil {
+		c.logger.Error(err)
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		if response.StatusCode == http.StatusUnauthorized {
+			return nil, fmt.Errorf("сертификат клиента не принят сервером")
+		}
+		return nil, fmt.Errorf("не известная ошибка")
+	}
+
+	return c.readTokens(response, "")
+}
+
+// Refresh обмен refresh токена на новую пару токенов
+func (c *Authentication) Refresh(refreshToken string) (*AuthenticationResponse, error) {
+	requestURL := fmt.Sprintf("%s/api/v1/token/refresh", c.cfg.Value().ServerAddress)
+	ctx := context.Background()
+
+	requestBody, err := json.Marshal(model_data.RefreshTokenRequest{RefreshToken: refreshToken})
+	if err != nil {
+		c.logger.Error(err)
+		return nil, err
+	}
+	requestPrepare, err := http.NewRequestWithContext(ctx, http.MethodPost, requestURL, bytes.NewBuffer(requestBody))
+	if err != nil {
+		c.logger.Error(err)
+		return nil, err
+	}
+	requestPrepare.Header.Set("Content-Type", "application/json")
+	response, err := c.client.Do(requestPrepare)
+	if err != nil {
+		c.logger.Error(err)
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		if response.StatusCode == http.StatusUnauthorized {
+			return nil, fmt.Errorf("сессия закрыта, авторизуйтесь заново")
+		}
+		if response.StatusCode == http.StatusBadRequest {
+			return nil, fmt.Errorf("ошибка в запросе")
+		}
+		return nil, fmt.Errorf("не известная ошибка")
+	}
+
+	return c.readTokens(response, "")
+}
+
+// Logout закрытие текущей сессии на сервере
+func (c *Authentication) Logout(token string) error {
+	requestURL := fmt.Sprintf("%s/api/v1/logout", c.cfg.Value().ServerAddress)
+	ctx := context.Background()
+
+	requestPrepare, err := http.NewRequestWithContext(ctx, http.MethodPost, requestURL, nil)
+	if err != nil {
+		c.logger.Error(err)
+		return err
+	}
+	requestPrepare.Header.Set("Authorization", "Bearer "+token)
+	response, err := c.client.Do(requestPrepare)
+	if err != nil {
+		c.logger.Error(err)
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		if response.StatusCode == http.StatusUnauthorized {
+			return fmt.Errorf("вы не авторизованы")
+		}
+		return fmt.Errorf("не известная ошибка")
+	}
+	return nil
+}
+
+// readTokens токены из тела ответа, токен доступа из заголовка имеет приоритет
+func (c *Authentication) readTokens(response *http.Response, token string) (*AuthenticationResponse, error) {
+	tokens := new(model_data.TokenResponse)
+	err := json.NewDecoder(response.Body).Decode(tokens)
+	if err != nil && !errors.Is(err, io.EOF) {
+		c.logger.Error(err)
+		return nil, err
+	}
+
 	responseData := new(AuthenticationResponse)
 	responseData.Value = token
+	if responseData.Value == "" {
+		responseData.Value = tokens.AccessToken
+	}
+	responseData.RefreshToken = tokens.RefreshToken
+	responseData.ExpiresIn = tokens.ExpiresIn
+	responseData.DeviceUUID = tokens.DeviceUUID
+
+	err = saveDeviceUUID(c.cfg, tokens.DeviceUUID)
+	if err != nil {
+		c.logger.Error(err)
+		return nil, err
+	}
 
 	return responseData, nil
 }
+
+// tooManyRequestsError ошибка 429: сервер ограничил попытки входа, Retry-After - через сколько секунд можно повторить
+func tooManyRequestsError(response *http.Response) error {
+	retryAfter := response.Header.Get("Retry-After")
+	if retryAfter == "" {
+		return fmt.Errorf("слишком много попыток, повторите позже")
+	}
+	return fmt.Errorf("слишком много попыток, повторите через %s сек.", retryAfter)
+}
@@ -8,6 +8,7 @@ import (
 	"testing"
 
 	"github.com/northmule/gophkeeper/internal/client/logger"
+	"github.com/northmule/gophkeeper/internal/common/model_data"
 )
 
 func TestAuthenticationSend(t *testing.T) {
@@ -39,6 +40,12 @@ func TestAuthenticationSend(t *testing.T) {
 			return
 		}
 
+		if requestData.Login == "lockeduser" {
+			w.Header().Set("Retry-After", "60")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+
 		w.WriteHeader(http.StatusInternalServerError)
 	}))
 	defer server.Close()
@@ -66,6 +73,11 @@ func TestAuthenticationSend(t *testing.T) {
 		t.Errorf("Send should have failed with unauthorized credentials: %v", err)
 	}
 
+	_, err = authController.Send("lockeduser", "validpass")
+	if err == nil || !strings.Contains(err.Error(), "повторите через 60 сек.") {
+		t.Errorf("Send should have failed with too many requests: %v", err)
+	}
+
 	_, err = authController.Send("unknownuser", "unknownpass")
 	if err == nil || !strings.Contains(err.Error(), "не известная ошибка") {
 		t.Errorf("Send should have failed with unknown error: %v", err)
@@ -82,3 +94,114 @@ func TestAuthenticationSend(t *testing.T) {
 	}
 
 }
+
+func TestAuthenticationRefresh(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/token/refresh" {
+			t.Errorf("Expected path /api/v1/token/refresh, got %s", r.URL.Path)
+			return
+		}
+		var requestData model_data.RefreshTokenRequest
+		if err := json.NewDecoder(r.Body).Decode(&requestData); err != nil {
+			t.Errorf("Failed to decode request body: %v", err)
+			return
+		}
+		if requestData.RefreshToken != "valid-refresh" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(model_data.TokenResponse{AccessToken: "new-access", RefreshToken: "new-refresh", ExpiresIn: 900})
+	}))
+	defer server.Close()
+
+	log, _ := logger.NewLogger("info")
+	authController := NewAuthentication(makeMockConfig(server.URL), log)
+
+	response, err := authController.Refresh("valid-refresh")
+	if err != nil {
+		t.Fatalf("Refresh failed: %v", err)
+	}
+	if response.Value != "new-access" || response.RefreshToken != "new-refresh" || response.ExpiresIn != 900 {
+		t.Errorf("Unexpected response: %+v", response)
+	}
+
+	_, err = authController.Refresh("reused-refresh")
+	if err == nil || !strings.Contains(err.Error(), "сессия закрыта") {
+		t.Errorf("Refresh should have failed with closed session: %v", err)
+	}
+}
+
+func TestAuthenticationLogout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/logout" {
+			t.Errorf("Expected path /api/v1/logout, got %s", r.URL.Path)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer validtoken" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	log, _ := logger.NewLogger("info")
+	authController := NewAuthentication(makeMockConfig(server.URL), log)
+
+	if err := authController.Logout("validtoken"); err != nil {
+		t.Errorf("Logout failed: %v", err)
+	}
+	if err := authController.Logout("expired"); err == nil {
+		t.Errorf("Logout should have failed with invalid token")
+	}
+}
+
+func TestAuthenticationSecondFactor(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/login":
+			w.WriteHeader(http.StatusAccepted)
+			_ = json.NewEncoder(w).Encode(model_data.LoginChallengeResponse{MFARequired: true, MFAToken: "mfa-token", ExpiresIn: 300})
+		case "/api/v1/login/totp":
+			var requestData model_data.TOTPLoginRequest
+			if err := json.NewDecoder(r.Body).Decode(&requestData); err != nil {
+				t.Errorf("Failed to decode request body: %v", err)
+				return
+			}
+			if requestData.MFAToken != "mfa-token" || requestData.Code != "123456" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Header().Set("Authorization", "Bearer access-token")
+			_ = json.NewEncoder(w).Encode(model_data.TokenResponse{AccessToken: "access-token", RefreshToken: "refresh-token", ExpiresIn: 900})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	log, _ := logger.NewLogger("info")
+	authController := NewAuthentication(makeMockConfig(server.URL), log)
+
+	response, err := authController.Send("login", "password")
+	if err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	if response.MFAToken != "mfa-token" || response.Value != "" {
+		t.Errorf("Unexpected response: %+v", response)
+	}
+
+	_, err = authController.SendCode("mfa-token", "000000")
+	if err == nil || !strings.Contains(err.Error(), "не верный код") {
+		t.Errorf("SendCode should have failed with wrong code: %v", err)
+	}
+
+	response, err = authController.SendCode("mfa-token", "123456")
+	if err != nil {
+		t.Fatalf("SendCode failed: %v", err)
+	}
+	if response.Value != "access-token" || response.RefreshToken != "refresh-token" {
+		t.Errorf("Unexpected response: %+v", response)
+	}
+}
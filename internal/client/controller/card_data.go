@@ -6,26 +6,35 @@ import (
 	"fmt"
 	"net/http"
 
+	"github.com/google/uuid"
 	"github.com/northmule/gophkeeper/internal/client/config"
 	"github.com/northmule/gophkeeper/internal/client/logger"
 	"github.com/northmule/gophkeeper/internal/client/service"
+	"github.com/northmule/gophkeeper/internal/client/storage"
+	"github.com/northmule/gophkeeper/internal/common/data_type"
 	"github.com/northmule/gophkeeper/internal/common/model_data"
 	"golang.org/x/net/context"
 )
 
 // CardData контроллер
 type CardData struct {
-	logger *logger.Logger
-	cfg    *config.Config
-	crypt  service.Cryptographer
+	logger  *logger.Logger
+	cfg     *config.Config
+	client  *http.Client
+	crypt   service.Cryptographer
+	vault   service.VaultCryptographer
+	offline *storage.OfflineVault
 }
 
 // NewCardData конструктор
-func NewCardData(cfg *config.Config, crypt service.Cryptographer, logger *logger.Logger) *CardData {
+func NewCardData(cfg *config.Config, crypt service.Cryptographer, vault service.VaultCryptographer, offline *storage.OfflineVault, logger *logger.Logger) *CardData {
 	return &CardData{
-		logger: logger,
-		cfg:    cfg,
-		crypt:  crypt,
+		logger:  logger,
+		cfg:     cfg,
+		client:  newHTTPClient(cfg),
+		crypt:   crypt,
+		vault:   vault,
+		offline: offline,
 	}
 }
 
@@ -34,18 +43,35 @@ type CardDataResponse struct {
 	Value string
 }
 
-// Send отправка запроса к серверу
+// Send отправка запроса к серверу. Если сервер недоступен, запрос ставится в очередь изменений (ErrQueued)
 func (c *CardData) Send(token string, requestData *model_data.CardDataRequest) (*CardDataResponse, error) {
+	idempotencyKey := uuid.NewString()
+	responseData, err := c.send(token, requestData, idempotencyKey)
+	if isUnreachable(err) {
+		entry := storage.OutboxEntry{Key: idempotencyKey, Type: data_type.CardType, Name: requestData.Name}
+		return nil, enqueue(c.offline, entry, requestData, err)
+	}
+	return responseData, err
+}
+
+// send отправка запроса с ключом идемпотентности: повтор с тем же ключом сервер не выполняет
+func (c *CardData) send(token string, requestData *model_data.CardDataRequest, idempotencyKey string) (*CardDataResponse, error) {
 	requestURL := fmt.Sprintf("%s/api/v1/save_card_data", c.cfg.Value().ServerAddress)
 	ctx := context.Background()
 
-	requestBody, err := json.Marshal(requestData)
+	// Значения шифруются мастер-ключом, сервер получает только шифротекст
+	sealedData, err := sealCardData(c.vault, requestData)
+	if err != nil {
+		c.logger.Error(err)
+		return nil, err
+	}
+	requestBody, err := json.Marshal(sealedData)
 	if err != nil {
 		return nil, err
 	}
 
 	// Шифруем
-	requestBody, err = c.crypt.EncryptAES(requestBody)
+	requestBody, err = sealRequestBody(c.crypt, http.MethodPost, requestURL, requestBody)
 	if err != nil {
 		c.logger.Error(err)
 		return nil, err
@@ -58,8 +84,8 @@ func (c *CardData) Send(token string, requestData *model_data.CardDataRequest) (
 		return nil, err
 	}
 	requestPrepare.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
-	client := &http.Client{}
-	response, err := client.Do(requestPrepare)
+	requestPrepare.Header.Add(data_type.IdempotencyKeyHeader, idempotencyKey)
+	response, err := c.client.Do(requestPrepare)
 	if err != nil {
 		c.logger.Error(err)
 		return nil, err
@@ -70,6 +96,9 @@ func (c *CardData) Send(token string, requestData *model_data.CardDataRequest) (
 		if response.StatusCode == http.StatusUnauthorized {
 			return nil, fmt.Errorf("вы не авторизованы")
 		}
+		if err = envelopeError(response); err != nil {
+			return nil, err
+		}
 
 		if response.StatusCode == http.StatusBadRequest {
 			return nil, fmt.Errorf("ошибка в запросе")
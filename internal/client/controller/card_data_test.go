@@ -10,17 +10,18 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/northmule/gophkeeper/internal/client/logger"
+	"github.com/northmule/gophkeeper/internal/client/service"
 	"github.com/northmule/gophkeeper/internal/common/model_data"
 	"github.com/northmule/gophkeeper/internal/common/util"
 )
 
 // CryptMock мокк
 type CryptMock struct {
-	pubKey     *rsa.PublicKey
-	privateKey *rsa.PrivateKey
-	aesKey     []byte
+	pubKey *rsa.PublicKey
+	aesKey []byte
 }
 
 // NewCryptMock мокк
@@ -30,20 +31,15 @@ func NewCryptMock(t *testing.T) *CryptMock {
 		t.Fatalf("Failed to generate RSA key pair: %v", err)
 	}
 	return &CryptMock{
-		pubKey:     &key.PublicKey,
-		privateKey: key,
-		aesKey:     make([]byte, 32),
+		pubKey: &key.PublicKey,
+		aesKey: make([]byte, 32),
 	}
 }
 
-// EncryptRSA мокк
-func (c *CryptMock) EncryptRSA(data []byte) ([]byte, error) {
-	return util.DataEncryptRSA(data, c.pubKey)
-}
-
-// DecryptRSA мокк
-func (c *CryptMock) DecryptRSA(data []byte) ([]byte, error) {
-	return util.DataDecryptRSA(data, c.privateKey)
+// EncryptKey мокк
+func (c *CryptMock) EncryptKey(data []byte) (string, []byte, error) {
+	encrypted, err := util.DataEncryptRSA(data, c.pubKey)
+	return util.KeyExchangeRSA, encrypted, err
 }
 
 // EncryptAES мокк
@@ -56,8 +52,34 @@ func (c *CryptMock) DecryptAES(data []byte) ([]byte, error) {
 	return util.DataDecryptAES(data, c.aesKey)
 }
 
+// ReloadEncryptionKey мокк
+func (c *CryptMock) ReloadEncryptionKey() error {
+	return nil
+}
+
+// SealRequest мокк
+func (c *CryptMock) SealRequest(method string, path string, data []byte) ([]byte, error) {
+	return util.SealRequest(data, c.aesKey, method, path, time.Now())
+}
+
+// OpenRequest расшифровка конверта запроса на стороне тестового сервера
+func (c *CryptMock) OpenRequest(r *http.Request, data []byte) ([]byte, error) {
+	_, body, err := util.OpenRequest(data, c.aesKey, r.Method, r.URL.Path)
+	return body, err
+}
+
+// newVaultMock хранилище мастер-ключа, разблокированное тестовым паролем
+func newVaultMock(t *testing.T) *service.Vault {
+	vault := service.NewVault()
+	if _, _, err := vault.Setup("test master password"); err != nil {
+		t.Fatalf("Failed to setup vault: %v", err)
+	}
+	return vault
+}
+
 func TestCardDataSend(t *testing.T) {
 	cryptService := NewCryptMock(t)
+	vault := newVaultMock(t)
 
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
@@ -76,7 +98,7 @@ func TestCardDataSend(t *testing.T) {
 			return
 		}
 		bodyBytes, _ := io.ReadAll(r.Body)
-		rawBody, _ := cryptService.DecryptAES(bodyBytes)
+		rawBody, _ := cryptService.OpenRequest(r, bodyBytes)
 		buf := bytes.NewBuffer(rawBody)
 
 		var requestData model_data.CardDataRequest
@@ -85,6 +107,13 @@ func TestCardDataSend(t *testing.T) {
 			return
 		}
 
+		// сервер получает только шифротекст
+		if requestData.CardNumber == "1234567890123456" {
+			t.Errorf("Expected encrypted card number")
+			return
+		}
+		_ = openFields(vault, &requestData.CardNumber, &requestData.FullNameHolder)
+
 		if requestData.CardNumber == "1234567890123456" && requestData.FullNameHolder == "John Doe" {
 			w.WriteHeader(http.StatusOK)
 			return
@@ -107,7 +136,7 @@ func TestCardDataSend(t *testing.T) {
 
 	mockConfig := makeMockConfig(server.URL)
 
-	cardDataController := NewCardData(mockConfig, cryptService, log)
+	cardDataController := NewCardData(mockConfig, cryptService, vault, newTestOfflineVault(t), log)
 
 	t.Run("ok", func(t *testing.T) {
 		requestData := &model_data.CardDataRequest{
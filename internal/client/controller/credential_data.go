@@ -0,0 +1,114 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/northmule/gophkeeper/internal/client/config"
+	"github.com/northmule/gophkeeper/internal/client/logger"
+	"github.com/northmule/gophkeeper/internal/client/service"
+	"github.com/northmule/gophkeeper/internal/client/storage"
+	"github.com/northmule/gophkeeper/internal/common/data_type"
+	"github.com/northmule/gophkeeper/internal/common/model_data"
+	"golang.org/x/net/context"
+)
+
+// CredentialData контроллер
+type CredentialData struct {
+	logger  *logger.Logger
+	cfg     *config.Config
+	client  *http.Client
+	crypt   service.Cryptographer
+	vault   service.VaultCryptographer
+	offline *storage.OfflineVault
+}
+
+// NewCredentialData конструктор
+func NewCredentialData(cfg *config.Config, crypt service.Cryptographer, vault service.VaultCryptographer, offline *storage.OfflineVault, logger *logger.Logger) *CredentialData {
+	return &CredentialData{
+		logger:  logger,
+		cfg:     cfg,
+		client:  newHTTPClient(cfg),
+		crypt:   crypt,
+		vault:   vault,
+		offline: offline,
+	}
+}
+
+// CredentialDataResponse ответ
+type CredentialDataResponse struct {
+	Value string
+}
+
+// Send отправка запроса к серверу. Если сервер недоступен, запрос ставится в очередь изменений (ErrQueued)
+func (c *CredentialData) Send(token string, requestData *model_data.CredentialDataRequest) (*CredentialDataResponse, error) {
+	idempotencyKey := uuid.NewString()
+	responseData, err := c.send(token, requestData, idempotencyKey)
+	if isUnreachable(err) {
+		entry := storage.OutboxEntry{Key: idempotencyKey, Type: data_type.CredentialType, Name: requestData.Name}
+		return nil, enqueue(c.offline, entry, requestData, err)
+	}
+	return responseData, err
+}
+
+// send отправка запроса с ключом идемпотентности: повтор с тем же ключом сервер не выполняет
+func (c *CredentialData) send(token string, requestData *model_data.CredentialDataRequest, idempotencyKey string) (*CredentialDataResponse, error) {
+	requestURL := fmt.Sprintf("%s/api/v1/save_credential_data", c.cfg.Value().ServerAddress)
+	ctx := context.Background()
+
+	// Значения шифруются мастер-ключом, сервер получает только шифротекст
+	sealedData, err := sealCredentialData(c.vault, requestData)
+	if err != nil {
+		c.logger.Error(err)
+		return nil, err
+	}
+	requestBody, err := json.Marshal(sealedData)
+	if err != nil {
+		return nil, err
+	}
+
+	// Шифруем
+	requestBody, err = sealRequestBody(c.crypt, http.MethodPost, requestURL, requestBody)
+	if err != nil {
+		c.logger.Error(err)
+		return nil, err
+	}
+
+	buf := bytes.NewBuffer(requestBody)
+	requestPrepare, err := http.NewRequestWithContext(ctx, http.MethodPost, requestURL, buf)
+	if err != nil {
+		c.logger.Error(err)
+		return nil, err
+	}
+	requestPrepare.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+	requestPrepare.Header.Add(data_type.IdempotencyKeyHeader, idempotencyKey)
+	response, err := c.client.Do(requestPrepare)
+	if err != nil {
+		c.logger.Error(err)
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		if response.StatusCode == http.StatusUnauthorized {
+			return nil, fmt.Errorf("вы не авторизованы")
+		}
+		if err = envelopeError(response); err != nil {
+			return nil, err
+		}
+
+		if response.StatusCode == http.StatusBadRequest {
+			return nil, fmt.Errorf("ошибка в запросе")
+		}
+
+		return nil, fmt.Errorf("не известная ошибка")
+	}
+
+	responseData := new(CredentialDataResponse)
+	responseData.Value = "ok"
+
+	return responseData, nil
+}
@@ -0,0 +1,120 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/northmule/gophkeeper/internal/client/logger"
+	"github.com/northmule/gophkeeper/internal/common/model_data"
+)
+
+func TestCredentialDataSend(t *testing.T) {
+	cryptService := NewCryptMock(t)
+	vault := newVaultMock(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Errorf("Expected POST request, got %s", r.Method)
+			return
+		}
+
+		if r.URL.Path != "/api/v1/save_credential_data" {
+			t.Errorf("Expected path /api/v1/save_credential_data, got %s", r.URL.Path)
+			return
+		}
+
+		authHeader := r.Header.Get("Authorization")
+		if authHeader != "Bearer validtoken" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		bodyBytes, _ := io.ReadAll(r.Body)
+		rawBody, _ := cryptService.OpenRequest(r, bodyBytes)
+		buf := bytes.NewBuffer(rawBody)
+
+		var requestData model_data.CredentialDataRequest
+		if err := json.NewDecoder(buf).Decode(&requestData); err != nil {
+			t.Errorf("Failed to decode request body: %v", err)
+			return
+		}
+
+		// сервер получает только шифротекст
+		if requestData.Password == "secret" {
+			t.Errorf("Expected encrypted password")
+			return
+		}
+		_ = openFields(vault, &requestData.Username, &requestData.Password)
+
+		if requestData.Username == "john" && requestData.Password == "secret" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		if requestData.Username == "badrequest" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+
+	defer server.Close()
+
+	log, err := logger.NewLogger("info")
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+
+	mockConfig := makeMockConfig(server.URL)
+
+	credentialDataController := NewCredentialData(mockConfig, cryptService, vault, newTestOfflineVault(t), log)
+
+	t.Run("ok", func(t *testing.T) {
+		requestData := &model_data.CredentialDataRequest{
+			Name:     "Mail",
+			Username: "john",
+			Password: "secret",
+			URLs:     []string{"https://mail.example.com"},
+		}
+
+		response, err := credentialDataController.Send("validtoken", requestData)
+		if err != nil {
+			t.Errorf("Send failed with valid credentials: %v", err)
+		}
+
+		if response.Value != "ok" {
+			t.Errorf("Expected response 'ok', got '%s'", response.Value)
+		}
+	})
+
+	t.Run("badrequest", func(t *testing.T) {
+		requestData := &model_data.CredentialDataRequest{
+			Username: "badrequest",
+		}
+		_, err := credentialDataController.Send("validtoken", requestData)
+		if err == nil || !strings.Contains(err.Error(), "ошибка в запросе") {
+			t.Errorf("Send should have failed with bad request error: %v", err)
+		}
+	})
+
+	t.Run("no_validtoken", func(t *testing.T) {
+		requestData := &model_data.CredentialDataRequest{}
+		_, err := credentialDataController.Send("no_validtoken", requestData)
+		if err == nil || !strings.Contains(err.Error(), "вы не авторизованы") {
+			t.Errorf("Send should have failed with unauthorized error: %v", err)
+		}
+	})
+
+	t.Run("unknown_error", func(t *testing.T) {
+		requestData := &model_data.CredentialDataRequest{Username: "other"}
+		_, err := credentialDataController.Send("validtoken", requestData)
+		if err == nil || !strings.Contains(err.Error(), "не известная ошибка") {
+			t.Errorf("Send should have failed with unknown error: %v", err)
+		}
+	})
+}
@@ -0,0 +1,223 @@
+package controller
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/northmule/gophkeeper/internal/client/config"
+	"github.com/northmule/gophkeeper/internal/client/logger"
+	"github.com/northmule/gophkeeper/internal/client/service"
+	"github.com/northmule/gophkeeper/internal/common/data_type"
+	"github.com/northmule/gophkeeper/internal/common/keys"
+	"github.com/northmule/gophkeeper/internal/common/model_data"
+	"github.com/northmule/gophkeeper/internal/common/util"
+	"golang.org/x/net/context"
+)
+
+// Клиент хранит UUID устройства, выданный сервером при первом входе, и передаёт его при следующих входах.
+// Ключ хранилища шифруется локальным ключом устройства и отправляется на сервер вместе с публичным ключом,
+// поэтому на этом устройстве хранилище открывается без мастер-пароля. Отзыв устройства удаляет этот ключ на сервере.
+
+// deviceAdditionalData дополнительные данные AEAD ключа хранилища устройства
+var deviceAdditionalData = []byte("gophkeeper device vault key")
+
+// ErrDeviceVaultKeyNotFound на сервере нет ключа хранилища этого устройства
+var ErrDeviceVaultKeyNotFound = errors.New("ключ хранилища устройства не найден")
+
+// Devices контроллер устройств пользователя
+type Devices struct {
+	logger *logger.Logger
+	cfg    *config.Config
+	client *http.Client
+	vault  service.Vaulter
+}
+
+// NewDevices конструктор
+func NewDevices(cfg *config.Config, vault service.Vaulter, logger *logger.Logger) *Devices {
+	return &Devices{
+		logger: logger,
+		cfg:    cfg,
+		client: newHTTPClient(cfg),
+		vault:  vault,
+	}
+}
+
+// List устройства пользователя, в том числе отозванные
+func (c *Devices) List(token string) (*model_data.DeviceListResponse, error) {
+	response, err := c.do(token, http.MethodGet, "/api/v1/devices", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, deviceStatusError(response.StatusCode)
+	}
+	responseData := new(model_data.DeviceListResponse)
+	err = json.NewDecoder(response.Body).Decode(responseData)
+	if err != nil {
+		c.logger.Error(err)
+		return nil, err
+	}
+	return responseData, nil
+}
+
+// Rename новое имя устройства
+func (c *Devices) Rename(token string, deviceUUID string, name string) error {
+	requestBody, err := json.Marshal(model_data.DeviceRenameRequest{Name: name})
+	if err != nil {
+		return err
+	}
+	response, err := c.do(token, http.MethodPost, "/api/v1/devices/"+url.PathEscape(deviceUUID)+"/rename", requestBody)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return deviceStatusError(response.StatusCode)
+	}
+	return nil
+}
+
+// Revoke отзыв устройства, его сессии закрываются сразу
+func (c *Devices) Revoke(token string, deviceUUID string) error {
+	response, err := c.do(token, http.MethodPost, "/api/v1/devices/"+url.PathEscape(deviceUUID)+"/revoke", nil)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return deviceStatusError(response.StatusCode)
+	}
+	c.logger.Infof("Device %s has been revoked", deviceUUID)
+	return nil
+}
+
+// Unlock разблокировка хранилища ключом, сохранённым на сервере для этого устройства
+func (c *Devices) Unlock(token string) error {
+	deviceKey, err := os.ReadFile(path.Join(c.cfg.Value().PathKeys, keys.DeviceKeyFileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return ErrDeviceVaultKeyNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	response, err := c.do(token, http.MethodGet, "/api/v1/devices/current", nil)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode == http.StatusNotFound {
+		return ErrDeviceVaultKeyNotFound
+	}
+	if response.StatusCode != http.StatusOK {
+		return deviceStatusError(response.StatusCode)
+	}
+	device := new(model_data.DeviceResponse)
+	err = json.NewDecoder(response.Body).Decode(device)
+	if err != nil {
+		c.logger.Error(err)
+		return err
+	}
+	if device.VaultKey == "" {
+		return ErrDeviceVaultKeyNotFound
+	}
+	sealed, err := base64.StdEncoding.DecodeString(device.VaultKey)
+	if err != nil {
+		return err
+	}
+	vaultKey, err := util.OpenEnvelope(sealed, deviceKey, deviceAdditionalData)
+	if err != nil {
+		return err
+	}
+	return c.vault.UnlockWithKey(vaultKey)
+}
+
+// do запрос к api устройств
+func (c *Devices) do(token string, method string, uri string, body []byte) (*http.Response, error) {
+	requestURL := c.cfg.Value().ServerAddress + uri
+	requestPrepare, err := http.NewRequestWithContext(context.Background(), method, requestURL, bytes.NewBuffer(body))
+	if err != nil {
+		return nil, err
+	}
+	requestPrepare.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+	if body != nil {
+		requestPrepare.Header.Add("Content-Type", "application/json")
+	}
+	response, err := c.client.Do(requestPrepare)
+	if err != nil {
+		c.logger.Error(err)
+		return nil, err
+	}
+	return response, nil
+}
+
+// deviceStatusError ошибка по коду ответа api устройств
+func deviceStatusError(statusCode int) error {
+	switch statusCode {
+	case http.StatusUnauthorized:
+		return fmt.Errorf("вы не авторизованы")
+	case http.StatusNotFound:
+		return fmt.Errorf("устройство не найдено или уже отозвано")
+	case http.StatusBadRequest:
+		return fmt.Errorf("ошибка в запросе")
+	}
+	return fmt.Errorf("не известная ошибка")
+}
+
+// setDeviceHeaders UUID и имя устройства в запросе входа
+func setDeviceHeaders(cfg *config.Config, request *http.Request) {
+	deviceUUID, err := os.ReadFile(path.Join(cfg.Value().PathKeys, keys.DeviceFileName))
+	if err == nil {
+		request.Header.Set(data_type.DeviceUUIDHeader, strings.TrimSpace(string(deviceUUID)))
+	}
+	if name, err := os.Hostname(); err == nil {
+		request.Header.Set(data_type.DeviceNameHeader, url.QueryEscape(name))
+	}
+}
+
+// saveDeviceUUID сохраняет UUID устройства из ответа на вход
+func saveDeviceUUID(cfg *config.Config, deviceUUID string) error {
+	if deviceUUID == "" {
+		return nil
+	}
+	return os.WriteFile(path.Join(cfg.Value().PathKeys, keys.DeviceFileName), []byte(deviceUUID), 0600)
+}
+
+// sealDeviceVaultKey ключ хранилища, зашифрованный ключом устройства. Ключ устройства создаётся при первом вызове
+func sealDeviceVaultKey(cfg *config.Config, vault service.Vaulter) (string, error) {
+	vaultKey, err := vault.Key()
+	if err != nil {
+		return "", err
+	}
+	keyPath := path.Join(cfg.Value().PathKeys, keys.DeviceKeyFileName)
+	deviceKey, err := os.ReadFile(keyPath)
+	if errors.Is(err, os.ErrNotExist) {
+		deviceKey = make([]byte, util.MasterKeyLen)
+		if _, err = rand.Read(deviceKey); err != nil {
+			return "", err
+		}
+		err = os.WriteFile(keyPath, deviceKey, 0600)
+	}
+	if err != nil {
+		return "", err
+	}
+	sealed, err := util.SealEnvelope(vaultKey, deviceKey, util.CipherAES256GCM, "device", deviceAdditionalData)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
@@ -0,0 +1,135 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/northmule/gophkeeper/internal/client/config"
+	"github.com/northmule/gophkeeper/internal/client/logger"
+	"github.com/northmule/gophkeeper/internal/client/service"
+	"github.com/northmule/gophkeeper/internal/common/data_type"
+	"github.com/northmule/gophkeeper/internal/common/keys"
+	"github.com/northmule/gophkeeper/internal/common/model_data"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newDevicesTestConfig(t *testing.T, serverURL string) *config.Config {
+	cfg := makeMockConfig(serverURL)
+	cfg.Value().PathKeys = t.TempDir()
+	return cfg
+}
+
+func TestDevices_List(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodGet, r.Method)
+		assert.Equal(t, "/api/v1/devices", r.URL.Path)
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		_ = json.NewEncoder(w).Encode(model_data.DeviceListResponse{Devices: []model_data.DeviceResponse{
+			{UUID: "device-1", Name: "laptop", Current: true},
+			{UUID: "device-2", Name: "phone", RevokedAt: 100},
+		}})
+	}))
+	defer server.Close()
+	log, _ := logger.NewLogger("info")
+
+	list, err := NewDevices(newDevicesTestConfig(t, server.URL), service.NewVault(), log).List("token")
+	require.NoError(t, err)
+	require.Len(t, list.Devices, 2)
+	assert.True(t, list.Devices[0].Current)
+	assert.Equal(t, int64(100), list.Devices[1].RevokedAt)
+}
+
+func TestDevices_RenameAndRevoke(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		switch r.URL.Path {
+		case "/api/v1/devices/device-1/rename":
+			request := new(model_data.DeviceRenameRequest)
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(request))
+			assert.Equal(t, "work laptop", request.Name)
+		case "/api/v1/devices/device-1/revoke":
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	log, _ := logger.NewLogger("info")
+	devices := NewDevices(newDevicesTestConfig(t, server.URL), service.NewVault(), log)
+
+	assert.NoError(t, devices.Rename("token", "device-1", "work laptop"))
+	assert.NoError(t, devices.Revoke("token", "device-1"))
+	assert.EqualError(t, devices.Revoke("token", "device-2"), "устройство не найдено или уже отозвано")
+}
+
+func TestDevices_Unlock(t *testing.T) {
+	var sealedVaultKey string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/devices/current", r.URL.Path)
+		if sealedVaultKey == "" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(model_data.DeviceResponse{UUID: "device-1", Current: true, VaultKey: sealedVaultKey})
+	}))
+	defer server.Close()
+	log, _ := logger.NewLogger("info")
+	cfg := newDevicesTestConfig(t, server.URL)
+
+	vault := service.NewVault()
+	_, _, err := vault.Setup("master password")
+	require.NoError(t, err)
+	vaultKey, err := vault.Key()
+	require.NoError(t, err)
+
+	// ключа устройства ещё нет
+	locked := service.NewVault()
+	devices := NewDevices(cfg, locked, log)
+	assert.ErrorIs(t, devices.Unlock("token"), ErrDeviceVaultKeyNotFound)
+
+	sealedVaultKey, err = sealDeviceVaultKey(cfg, vault)
+	require.NoError(t, err)
+	_, err = os.Stat(path.Join(cfg.Value().PathKeys, keys.DeviceKeyFileName))
+	require.NoError(t, err)
+
+	require.NoError(t, devices.Unlock("token"))
+	unlockedKey, err := locked.Key()
+	require.NoError(t, err)
+	assert.Equal(t, vaultKey, unlockedKey)
+
+	// устройство отозвано, ключа хранилища на сервере больше нет
+	sealedVaultKey = ""
+	locked.Lock()
+	assert.ErrorIs(t, devices.Unlock("token"), ErrDeviceVaultKeyNotFound)
+	assert.False(t, locked.IsUnlocked())
+}
+
+func TestAuthentication_DeviceHeaders(t *testing.T) {
+	var deviceUUID, deviceName string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		deviceUUID = r.Header.Get(data_type.DeviceUUIDHeader)
+		deviceName, _ = url.QueryUnescape(r.Header.Get(data_type.DeviceNameHeader))
+		_ = json.NewEncoder(w).Encode(model_data.TokenResponse{AccessToken: "token", DeviceUUID: "device-1"})
+	}))
+	defer server.Close()
+	log, _ := logger.NewLogger("info")
+	cfg := newDevicesTestConfig(t, server.URL)
+	authentication := NewAuthentication(cfg, log)
+
+	response, err := authentication.Send("login", "password")
+	require.NoError(t, err)
+	assert.Equal(t, "device-1", response.DeviceUUID)
+	assert.Empty(t, deviceUUID)
+	hostname, _ := os.Hostname()
+	assert.Equal(t, hostname, deviceName)
+
+	// при следующем входе передаётся сохранённое устройство
+	_, err = authentication.SendCertificate()
+	require.NoError(t, err)
+	assert.Equal(t, "device-1", deviceUUID)
+}
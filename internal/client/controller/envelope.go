@@ -0,0 +1,86 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/northmule/gophkeeper/internal/client/service"
+	"github.com/northmule/gophkeeper/internal/common/data_type"
+)
+
+var (
+	// ErrRequestExpired сервер отклонил запрос по времени
+	ErrRequestExpired = errors.New("запрос отклонён: часы клиента и сервера расходятся")
+	// ErrRequestReplayed сервер отклонил запрос как повторный
+	ErrRequestReplayed = errors.New("запрос отклонён сервером как повторный")
+	// ErrIdempotencyKeyReused ключ идемпотентности уже использован сервером для другого запроса
+	ErrIdempotencyKeyReused = errors.New("запрос отклонён: ключ идемпотентности использован для другого запроса")
+	// ErrIdempotencyInProgress запрос с тем же ключом идемпотентности ещё выполняется сервером
+	ErrIdempotencyInProgress = errors.New("запрос с тем же ключом ещё выполняется, повторите позже")
+	// ErrVersionRequired изменение данных отправлено без версии
+	ErrVersionRequired = errors.New("запрос отклонён: не указана версия изменяемых данных")
+	// ErrChunkChecksum сервер получил часть файла с другой контрольной суммой
+	ErrChunkChecksum = errors.New("часть файла повреждена при передаче")
+	// ErrFileAssembly сервер не собрал файл: повреждённые части нужно отправить заново
+	ErrFileAssembly = errors.New("файл не собран сервером, части отправляются заново")
+)
+
+// VersionConflictError данные изменены на сервере после чтения, Version - текущая версия на сервере
+type VersionConflictError struct {
+	Version int64
+}
+
+func (e *VersionConflictError) Error() string {
+	return fmt.Sprintf("данные изменены на сервере (версия %d), откройте их заново", e.Version)
+}
+
+// sealRequestBody шифрует тело запроса в конверт, привязанный к методу и пути запроса
+func sealRequestBody(crypt service.Cryptographer, method string, requestURL string, body []byte) ([]byte, error) {
+	parsedURL, err := url.Parse(requestURL)
+	if err != nil {
+		return nil, err
+	}
+	return crypt.SealRequest(method, parsedURL.Path, body)
+}
+
+// envelopeError ошибка проверки конверта, ключа идемпотентности или версии данных по коду в ответе сервера.
+// Тело ответа остаётся доступным для чтения
+func envelopeError(response *http.Response) error {
+	if response.StatusCode != http.StatusBadRequest && response.StatusCode != http.StatusConflict &&
+		response.StatusCode != http.StatusUnprocessableEntity && response.StatusCode != http.StatusPreconditionRequired {
+		return nil
+	}
+	bodyRaw, _ := io.ReadAll(response.Body)
+	response.Body = io.NopCloser(bytes.NewReader(bodyRaw))
+	errResponse := struct {
+		Code    int64 `json:"code"`
+		Version int64 `json:"version"`
+	}{}
+	if json.Unmarshal(bodyRaw, &errResponse) != nil {
+		return nil
+	}
+	switch errResponse.Code {
+	case data_type.AppCodeRequestExpired:
+		return ErrRequestExpired
+	case data_type.AppCodeRequestReplayed:
+		return ErrRequestReplayed
+	case data_type.AppCodeIdempotencyKeyReused:
+		return ErrIdempotencyKeyReused
+	case data_type.AppCodeIdempotencyInProgress:
+		return ErrIdempotencyInProgress
+	case data_type.AppCodeVersionConflict:
+		return &VersionConflictError{Version: errResponse.Version}
+	case data_type.AppCodeVersionRequired:
+		return ErrVersionRequired
+	case data_type.AppCodeChunkChecksum:
+		return ErrChunkChecksum
+	case data_type.AppCodeFileAssembly:
+		return ErrFileAssembly
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package controller
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/northmule/gophkeeper/internal/client/logger"
+	"github.com/northmule/gophkeeper/internal/common/model_data"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSealRequestBody(t *testing.T) {
+	cryptService := NewCryptMock(t)
+	sealed, err := sealRequestBody(cryptService, http.MethodPost, "https://localhost:9097/api/v1/save_card_data", []byte("body"))
+	require.NoError(t, err)
+
+	request := httptest.NewRequest(http.MethodPost, "/api/v1/save_card_data", nil)
+	body, err := cryptService.OpenRequest(request, sealed)
+	assert.NoError(t, err)
+	assert.Equal(t, "body", string(body))
+
+	request = httptest.NewRequest(http.MethodPost, "/api/v1/save_text_data", nil)
+	_, err = cryptService.OpenRequest(request, sealed)
+	assert.Error(t, err)
+}
+
+func TestEnvelopeError(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		body     string
+		expected error
+	}{
+		{"replayed", http.StatusConflict, `{"status":"Request replayed","code":1002}`, ErrRequestReplayed},
+		{"expired", http.StatusBadRequest, `{"status":"Request expired","code":1001}`, ErrRequestExpired},
+		{"version required", http.StatusPreconditionRequired, `{"status":"Version required","code":1006}`, ErrVersionRequired},
+		{"bad request", http.StatusBadRequest, `{"status":"Bad request"}`, nil},
+		{"not json", http.StatusBadRequest, `ошибка в запросе`, nil},
+		{"ok", http.StatusOK, `{"code":1002}`, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			log, err := logger.NewLogger("info")
+			require.NoError(t, err)
+			_, err = NewCardData(makeMockConfig(server.URL), NewCryptMock(t), newVaultMock(t), newTestOfflineVault(t), log).Send("token", &model_data.CardDataRequest{})
+			if tt.expected != nil {
+				assert.ErrorIs(t, err, tt.expected)
+				return
+			}
+			assert.NotErrorIs(t, err, ErrRequestReplayed)
+			assert.NotErrorIs(t, err, ErrRequestExpired)
+		})
+	}
+}
+
+func TestEnvelopeError_VersionConflict(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusConflict)
+		w.Write([]byte(`{"status":"Version conflict","code":1005,"version":4}`))
+	}))
+	defer server.Close()
+
+	log, err := logger.NewLogger("info")
+	require.NoError(t, err)
+	_, err = NewCardData(makeMockConfig(server.URL), NewCryptMock(t), newVaultMock(t), newTestOfflineVault(t), log).Send("token", &model_data.CardDataRequest{})
+	var conflict *VersionConflictError
+	require.ErrorAs(t, err, &conflict)
+	assert.Equal(t, int64(4), conflict.Version)
+}
@@ -0,0 +1,171 @@
+package controller
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/northmule/gophkeeper/internal/client/config"
+	"github.com/northmule/gophkeeper/internal/client/logger"
+	"github.com/northmule/gophkeeper/internal/client/service"
+	"github.com/northmule/gophkeeper/internal/common/model_data"
+	"github.com/northmule/gophkeeper/internal/common/models"
+)
+
+// EventReconnected поток событий восстановлен после обрыва: события за это время не получены, данные нужно обновить
+const EventReconnected = "reconnected"
+
+const (
+	eventsRetryMin = time.Second
+	eventsRetryMax = 30 * time.Second
+)
+
+// KeyRotationChecker переход на новый ключ клиента после завершения смены
+type KeyRotationChecker interface {
+	KeyRotationStatus(token string) (*model_data.KeyRotationResponse, error)
+}
+
+// Events подписка на события изменения данных с других устройств (/api/v1/events)
+type Events struct {
+	logger   *logger.Logger
+	cfg      *config.Config
+	client   *http.Client
+	crypt    service.Cryptographer
+	keys     KeyRotationChecker
+	retryMin time.Duration
+
+	cancel context.CancelFunc
+	mx     sync.Mutex
+}
+
+// NewEvents конструктор
+func NewEvents(cfg *config.Config, crypt service.Cryptographer, keys KeyRotationChecker, logger *logger.Logger) *Events {
+	return &Events{
+		logger:   logger,
+		cfg:      cfg,
+		client:   newHTTPClient(cfg),
+		crypt:    crypt,
+		keys:     keys,
+		retryMin: eventsRetryMin,
+	}
+}
+
+// Start получение событий в фоне до Stop, после обрыва соединения подключается заново.
+// token возвращает текущий токен доступа, handle вызывается из фоновой горутины
+func (c *Events) Start(token func() string, handle func(event model_data.EventResponse)) {
+	c.Stop()
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	ctx, cancel := context.WithCancel(context.Background())
+	c.cancel = cancel
+	go c.run(ctx, token, handle)
+}
+
+// Stop остановка получения событий. Не ждёт завершения горутины: handle может ждать интерфейс,
+// из которого вызван Stop
+func (c *Events) Stop() {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	if c.cancel == nil {
+		return
+	}
+	c.cancel()
+	c.cancel = nil
+}
+
+func (c *Events) run(ctx context.Context, token func() string, handle func(event model_data.EventResponse)) {
+	delay := c.retryMin
+	connected := false
+	for {
+		reconnected := connected
+		ok, err := c.listen(ctx, token(), func() {
+			if reconnected {
+				handle(model_data.EventResponse{Type: EventReconnected})
+			}
+		}, handle)
+		if ctx.Err() != nil {
+			return
+		}
+		if ok {
+			connected = true
+			delay = c.retryMin
+		}
+		if err != nil {
+			c.logger.Info(err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, eventsRetryMax)
+	}
+}
+
+// listen чтение потока событий до его закрытия. Вернёт true, если сервер принял подключение
+func (c *Events) listen(ctx context.Context, token string, opened func(), handle func(event model_data.EventResponse)) (bool, error) {
+	requestURL := fmt.Sprintf("%s/api/v1/events", c.cfg.Value().ServerAddress)
+	requestPrepare, err := http.NewRequestWithContext(ctx, http.MethodGet, requestURL, nil)
+	if err != nil {
+		return false, err
+	}
+	requestPrepare.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+	response, err := c.client.Do(requestPrepare)
+	if err != nil {
+		return false, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		if response.StatusCode == http.StatusUnauthorized {
+			return false, fmt.Errorf("вы не авторизованы")
+		}
+		return false, fmt.Errorf("не известная ошибка: %d", response.StatusCode)
+	}
+	opened()
+
+	scanner := bufio.NewScanner(response.Body)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok { // пинги и разделители событий
+			continue
+		}
+		event, err := c.decode(data)
+		if err != nil {
+			c.logger.Error(err)
+			continue
+		}
+		if event.Type == models.EventKeyRotated {
+			// новый ключ нужен до переподключения: следующие события шифруются им
+			_, err = c.keys.KeyRotationStatus(token)
+			if err != nil {
+				c.logger.Error(err)
+			}
+		}
+		handle(*event)
+	}
+	return true, scanner.Err()
+}
+
+// decode расшифровка данных события
+func (c *Events) decode(data string) (*model_data.EventResponse, error) {
+	encrypted, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return nil, err
+	}
+	body, err := c.crypt.DecryptAES(encrypted)
+	if err != nil {
+		return nil, err
+	}
+	event := new(model_data.EventResponse)
+	err = json.Unmarshal(body, event)
+	if err != nil {
+		return nil, err
+	}
+	return event, nil
+}
@@ -0,0 +1,73 @@
+package controller
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/northmule/gophkeeper/internal/client/logger"
+	"github.com/northmule/gophkeeper/internal/common/model_data"
+	"github.com/northmule/gophkeeper/internal/common/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type keyRotationCheckerMock struct {
+	calls atomic.Int32
+}
+
+func (m *keyRotationCheckerMock) KeyRotationStatus(token string) (*model_data.KeyRotationResponse, error) {
+	m.calls.Add(1)
+	return &model_data.KeyRotationResponse{Status: models.KeyRotationFinished}, nil
+}
+
+func TestEvents_Start(t *testing.T) {
+	cryptService := NewCryptMock(t)
+	var connections atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/events" || r.Header.Get("Authorization") != "Bearer validtoken" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		if connections.Add(1) > 1 {
+			// второе подключение ждёт остановки клиента
+			w.(http.Flusher).Flush()
+			<-r.Context().Done()
+			return
+		}
+		for _, event := range []model_data.EventResponse{
+			{Type: models.EventItemUpdated, DataType: "card", DataUUID: "card-uuid", Revision: 3},
+			{Type: models.EventKeyRotated},
+		} {
+			body, _ := json.Marshal(event)
+			encrypted, _ := cryptService.EncryptAES(body)
+			fmt.Fprintf(w, ": ping\n\ndata: %s\n\n", base64.StdEncoding.EncodeToString(encrypted))
+		}
+		fmt.Fprint(w, "data: not base64\n\n")
+	}))
+	defer server.Close()
+
+	log, err := logger.NewLogger("info")
+	require.NoError(t, err)
+	keys := new(keyRotationCheckerMock)
+	controller := NewEvents(makeMockConfig(server.URL), cryptService, keys, log)
+	controller.retryMin = time.Millisecond
+
+	received := make(chan model_data.EventResponse, 10)
+	controller.Start(func() string { return "validtoken" }, func(event model_data.EventResponse) {
+		received <- event
+	})
+	defer controller.Stop()
+
+	assert.Equal(t, model_data.EventResponse{Type: models.EventItemUpdated, DataType: "card", DataUUID: "card-uuid", Revision: 3}, <-received)
+	assert.Equal(t, models.EventKeyRotated, (<-received).Type)
+	assert.Equal(t, int32(1), keys.calls.Load())
+	// поток закрыт сервером: после переподключения данные обновляются
+	assert.Equal(t, EventReconnected, (<-received).Type)
+}
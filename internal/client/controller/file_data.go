@@ -3,54 +3,122 @@ package controller
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"mime/multipart"
 	"net/http"
 	"os"
 	"path"
+	"path/filepath"
 
+	"github.com/google/uuid"
 	"github.com/northmule/gophkeeper/internal/client/config"
 	"github.com/northmule/gophkeeper/internal/client/logger"
 	"github.com/northmule/gophkeeper/internal/client/service"
+	"github.com/northmule/gophkeeper/internal/client/storage"
 	"github.com/northmule/gophkeeper/internal/common/data_type"
 	"github.com/northmule/gophkeeper/internal/common/model_data"
+	"github.com/northmule/gophkeeper/internal/common/util"
 	"golang.org/x/net/context"
 )
 
 // FileData контроллер
 type FileData struct {
-	logger *logger.Logger
-	cfg    *config.Config
-	crypt  service.Cryptographer
+	logger  *logger.Logger
+	cfg     *config.Config
+	client  *http.Client
+	crypt   service.Cryptographer
+	vault   service.VaultCryptographer
+	offline *storage.OfflineVault
 }
 
 // NewFileData конструктор
-func NewFileData(cfg *config.Config, crypt service.Cryptographer, logger *logger.Logger) *FileData {
+func NewFileData(cfg *config.Config, crypt service.Cryptographer, vault service.VaultCryptographer, offline *storage.OfflineVault, logger *logger.Logger) *FileData {
 	return &FileData{
-		logger: logger,
-		cfg:    cfg,
-		crypt:  crypt,
+		logger:  logger,
+		cfg:     cfg,
+		client:  newHTTPClient(cfg),
+		crypt:   crypt,
+		vault:   vault,
+		offline: offline,
 	}
 }
 
 // FileDataResponse ответ
 type FileDataResponse struct {
-	// Адрес без хоста для загрузки данных файла
+	// Адрес без хоста для загрузки частей файла: UploadPath/{номер части}
 	UploadPath string `json:"upload_path"`
+	// Адрес без хоста состояния загрузки (принятые части)
+	StatusPath string `json:"status_path"`
 }
 
+// uploadRounds сколько раз сверяться с состоянием загрузки и дозагружать недостающие части
+const uploadRounds = 3
+
 // Send отправка запроса к серверу. Предзагрузка основной информации о файле. В ответе будет адрес куда отправлять сам файл
 func (c *FileData) Send(token string, requestData *model_data.FileDataInitRequest) (*FileDataResponse, error) {
+	return c.send(token, requestData, uuid.NewString())
+}
+
+// SendFile предзагрузка информации о файле и отправка файла. Если сервер недоступен,
+// отправка ставится в очередь изменений (ErrQueued), файл будет прочитан при повторе
+func (c *FileData) SendFile(token string, requestData *model_data.FileDataInitRequest, filePath string) error {
+	idempotencyKey := uuid.NewString()
+	err := c.sendFile(token, requestData, filePath, idempotencyKey)
+	if isUnreachable(err) {
+		entry := storage.OutboxEntry{Key: idempotencyKey, Type: data_type.BinaryType, Name: requestData.Name, FilePath: filePath}
+		return enqueue(c.offline, entry, requestData, err)
+	}
+	return err
+}
+
+// sendFile предзагрузка с ключом идемпотентности и отправка файла частями. Повтор предзагрузки с тем же ключом
+// вернёт прежний адрес загрузки, и отправка продолжится с недостающих частей
+func (c *FileData) sendFile(token string, requestData *model_data.FileDataInitRequest, filePath string, idempotencyKey string) error {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	fileInfo, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	requestData.Chunks = fileChunks(fileInfo.Size())
+	response, err := c.send(token, requestData, idempotencyKey)
+	if err != nil {
+		return err
+	}
+	return c.UploadFile(token, response, file)
+}
+
+// fileChunks число частей файла размера size, пустой файл - одна пустая часть
+func fileChunks(size int64) int {
+	chunks := int((size + data_type.FileChunkSize - 1) / data_type.FileChunkSize)
+	if chunks == 0 {
+		return 1
+	}
+	return chunks
+}
+
+// send предзагрузка информации о файле с ключом идемпотентности
+func (c *FileData) send(token string, requestData *model_data.FileDataInitRequest, idempotencyKey string) (*FileDataResponse, error) {
 	requestURL := fmt.Sprintf("%s/api/v1/file_data/init", c.cfg.Value().ServerAddress)
 	ctx := context.Background()
 
-	requestBody, err := json.Marshal(requestData)
+	// Значения шифруются мастер-ключом, сервер получает только шифротекст
+	sealedData, err := sealFileDataInit(c.vault, requestData)
+	if err != nil {
+		c.logger.Error(err)
+		return nil, err
+	}
+	requestBody, err := json.Marshal(sealedData)
 	if err != nil {
 		return nil, err
 	}
 	// Шифруем
-	requestBody, err = c.crypt.EncryptAES(requestBody)
+	requestBody, err = sealRequestBody(c.crypt, http.MethodPost, requestURL, requestBody)
 	if err != nil {
 		c.logger.Error(err)
 		return nil, err
@@ -61,8 +129,8 @@ func (c *FileData) Send(token string, requestData *model_data.FileDataInitReques
 		return nil, err
 	}
 	requestPrepare.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
-	client := &http.Client{}
-	response, err := client.Do(requestPrepare)
+	requestPrepare.Header.Add(data_type.IdempotencyKeyHeader, idempotencyKey)
+	response, err := c.client.Do(requestPrepare)
 	if err != nil {
 		return nil, err
 	}
@@ -76,6 +144,9 @@ func (c *FileData) Send(token string, requestData *model_data.FileDataInitReques
 		if response.StatusCode == http.StatusUnauthorized {
 			return nil, fmt.Errorf("вы не авторизованы")
 		}
+		if err = envelopeError(response); err != nil {
+			return nil, err
+		}
 
 		if response.StatusCode == http.StatusBadRequest {
 			return nil, fmt.Errorf("ошибка в запросе")
@@ -93,57 +164,140 @@ func (c *FileData) Send(token string, requestData *model_data.FileDataInitReques
 	return responseData, nil
 }
 
-// UploadFile отправка файла на сервер
-func (c *FileData) UploadFile(token string, url string, file *os.File) error {
-	requestURL := fmt.Sprintf("%s/api/v1%s", c.cfg.Value().ServerAddress, url)
-	body := &bytes.Buffer{}
-	writer := multipart.NewWriter(body)
-	part, err := writer.CreateFormFile(data_type.FileField, file.Name())
+// UploadFile отправка файла на сервер частями. Части, уже принятые сервером, не отправляются повторно.
+// Если сервер не собрал файл, недостающие части отправляются по новому состоянию загрузки
+func (c *FileData) UploadFile(token string, upload *FileDataResponse, file *os.File) error {
+	status, err := c.uploadStatus(token, upload.StatusPath)
 	if err != nil {
 		return err
 	}
-	_, err = io.Copy(part, file)
+	for round := 0; round < uploadRounds && !status.Uploaded; round++ {
+		received := make(map[int]bool, len(status.Received))
+		for _, chunk := range status.Received {
+			received[chunk.Part] = true
+		}
+		chunks := status.Chunks
+		for part := 0; part < chunks && !status.Uploaded; part++ {
+			if received[part] {
+				continue
+			}
+			status, err = c.uploadChunk(token, upload.UploadPath, part, file)
+			if errors.Is(err, ErrFileAssembly) {
+				c.logger.Info(err)
+				status, err = c.uploadStatus(token, upload.StatusPath)
+				if err != nil {
+					return err
+				}
+				break
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+	if !status.Uploaded {
+		return fmt.Errorf("файл загружен не полностью, повторите отправку")
+	}
+	return nil
+}
 
-	err = writer.Close()
+// uploadStatus состояние загрузки файла на сервере
+func (c *FileData) uploadStatus(token string, url string) (*model_data.FileDataStatusResponse, error) {
+	requestURL := fmt.Sprintf("%s/api/v1%s", c.cfg.Value().ServerAddress, url)
+	requestPrepare, err := http.NewRequestWithContext(context.Background(), http.MethodGet, requestURL, nil)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	requestPrepare.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+	response, err := c.client.Do(requestPrepare)
+	if err != nil {
+		return nil, err
 	}
+	defer response.Body.Close()
+	return c.readStatus(response)
+}
 
-	var buf []byte
-	// Шифруем файл
-	buf, err = c.crypt.EncryptAES(body.Bytes())
+// uploadChunk отправка части part файла. Часть шифруется мастер-ключом отдельно от остальных,
+// сервер сверяет контрольную сумму шифротекста
+func (c *FileData) uploadChunk(token string, url string, part int, file *os.File) (*model_data.FileDataStatusResponse, error) {
+	requestURL := fmt.Sprintf("%s/api/v1%s/%d", c.cfg.Value().ServerAddress, url, part)
+	content := make([]byte, data_type.FileChunkSize)
+	n, err := file.ReadAt(content, int64(part)*data_type.FileChunkSize)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	// Содержимое части шифруется мастер-ключом
+	content, err = c.vault.Encrypt(content[:n])
 	if err != nil {
 		c.logger.Error(err)
-		return err
+		return nil, err
 	}
-	bodyCrypt := &bytes.Buffer{}
-	_, err = bodyCrypt.Write(buf)
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	err = writer.WriteField(data_type.ChecksumField, util.ChunkChecksum(content))
+	if err != nil {
+		return nil, err
+	}
+	formFile, err := writer.CreateFormFile(data_type.FileField, path.Base(file.Name()))
+	if err != nil {
+		return nil, err
+	}
+	_, err = formFile.Write(content)
+	if err != nil {
+		return nil, err
+	}
+	err = writer.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	// Шифруем запрос
+	buf, err := sealRequestBody(c.crypt, http.MethodPost, requestURL, body.Bytes())
 	if err != nil {
 		c.logger.Error(err)
-		return err
+		return nil, err
 	}
-	ctx := context.Background()
-	requestPrepare, err := http.NewRequestWithContext(ctx, http.MethodPost, requestURL, bodyCrypt)
+	requestPrepare, err := http.NewRequestWithContext(context.Background(), http.MethodPost, requestURL, bytes.NewReader(buf))
 	if err != nil {
-		return err
+		return nil, err
 	}
 	requestPrepare.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
 	requestPrepare.Header.Add("Content-Type", writer.FormDataContentType())
-	client := &http.Client{}
-	response, err := client.Do(requestPrepare)
+	response, err := c.client.Do(requestPrepare)
 	if err != nil {
-		return err
-	}
-	if response.StatusCode == http.StatusUnauthorized {
-		return fmt.Errorf("вы не авторизованы")
+		return nil, err
 	}
 	defer response.Body.Close()
+	return c.readStatus(response)
+}
 
-	return nil
+// readStatus разбор ответа с состоянием загрузки
+func (c *FileData) readStatus(response *http.Response) (*model_data.FileDataStatusResponse, error) {
+	if response.StatusCode == http.StatusUnauthorized {
+		return nil, fmt.Errorf("вы не авторизованы")
+	}
+	if err := envelopeError(response); err != nil {
+		return nil, err
+	}
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("не известная ошибка")
+	}
+	status := new(model_data.FileDataStatusResponse)
+	err := json.NewDecoder(response.Body).Decode(status)
+	if err != nil {
+		return nil, err
+	}
+	return status, nil
 }
 
-// DownLoadFile загрузка файла
+// DownLoadFile загрузка файла. Имя файла приходит с сервера, из него берётся только имя без папок
 func (c *FileData) DownLoadFile(token string, fileName string, dataUUID string) error {
+	return c.download(token, dataUUID, filepath.Join(c.cfg.Value().FilePath, filepath.Base(fileName)))
+}
+
+// download загрузка файла с расшифровкой частей в filePath
+func (c *FileData) download(token string, dataUUID string, filePath string) error {
 	requestURL := fmt.Sprintf("%s/api/v1/file_data/get/%s/%s", c.cfg.Value().ServerAddress, dataUUID, "0")
 	ctx := context.Background()
 
@@ -152,8 +306,7 @@ func (c *FileData) DownLoadFile(token string, fileName string, dataUUID string)
 		return err
 	}
 	requestPrepare.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
-	client := &http.Client{}
-	response, err := client.Do(requestPrepare)
+	response, err := c.client.Do(requestPrepare)
 	if err != nil {
 		return err
 	}
@@ -174,10 +327,6 @@ func (c *FileData) DownLoadFile(token string, fileName string, dataUUID string)
 		return fmt.Errorf(string(bodyRaw), response.StatusCode)
 	}
 
-	f, err := os.OpenFile(path.Join(c.cfg.Value().FilePath, fileName), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
-	if err != nil {
-		return err
-	}
 	bodyRaw, err := io.ReadAll(response.Body)
 	if err != nil {
 		c.logger.Error(err)
@@ -189,10 +338,32 @@ func (c *FileData) DownLoadFile(token string, fileName string, dataUUID string)
 		c.logger.Error(err)
 		return err
 	}
-	_, err = f.Write(bodyRaw)
+	f, err := os.OpenFile(filePath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
 	if err != nil {
 		return err
 	}
+	defer f.Close()
+	// Файл состоит из частей, каждая расшифровывается мастер-ключом отдельно
+	reader := bytes.NewReader(bodyRaw)
+	for {
+		chunk, err := util.ReadChunkFrame(reader, len(bodyRaw))
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			c.logger.Error(err)
+			return err
+		}
+		chunk, err = c.vault.Decrypt(chunk)
+		if err != nil {
+			c.logger.Error(err)
+			return err
+		}
+		_, err = f.Write(chunk)
+		if err != nil {
+			return err
+		}
+	}
 
 	return nil
 }
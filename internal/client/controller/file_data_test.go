@@ -7,12 +7,19 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path"
+	"strconv"
 	"strings"
+	"sync"
 	"testing"
 
 	"github.com/northmule/gophkeeper/internal/client/config"
 	"github.com/northmule/gophkeeper/internal/client/logger"
+	"github.com/northmule/gophkeeper/internal/common/data_type"
 	"github.com/northmule/gophkeeper/internal/common/model_data"
+	"github.com/northmule/gophkeeper/internal/common/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func makeMockConfig(server string) *config.Config {
@@ -54,7 +61,7 @@ func TestFileDataSend(t *testing.T) {
 			return
 		}
 		bodyBytes, _ := io.ReadAll(r.Body)
-		rawBody, _ := cryptService.DecryptAES(bodyBytes)
+		rawBody, _ := cryptService.OpenRequest(r, bodyBytes)
 		buf := bytes.NewBuffer(rawBody)
 
 		var requestData model_data.FileDataInitRequest
@@ -85,7 +92,7 @@ func TestFileDataSend(t *testing.T) {
 	}
 
 	mockConfig := makeMockConfig(server.URL)
-	controller := NewFileData(mockConfig, cryptService, log)
+	controller := NewFileData(mockConfig, cryptService, newVaultMock(t), newTestOfflineVault(t), log)
 
 	t.Run("ok", func(t *testing.T) {
 		requestData := &model_data.FileDataInitRequest{
@@ -126,31 +133,69 @@ func TestFileDataSend(t *testing.T) {
 
 func TestFileDataUploadFile(t *testing.T) {
 	cryptService := NewCryptMock(t)
+	vault := newVaultMock(t)
+
+	content := bytes.Repeat([]byte("0123456789"), data_type.FileChunkSize/10+10) // две части
+	var (
+		mx       sync.Mutex
+		received = map[int][]byte{}
+		uploaded []int
+		// brokenChunk часть, повреждённая на диске сервера: сборка файла не удастся один раз
+		brokenChunk = -1
+	)
+	status := func() model_data.FileDataStatusResponse {
+		response := model_data.FileDataStatusResponse{Chunks: 2, Uploaded: len(received) == 2}
+		for part := 0; part < 2; part++ {
+			if _, ok := received[part]; ok {
+				response.Received = append(response.Received, model_data.FileChunkReceipt{Part: part})
+			}
+		}
+		return response
+	}
 
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if r.Method != http.MethodPost {
-			t.Errorf("Expected POST request, got %s", r.Method)
+		mx.Lock()
+		defer mx.Unlock()
+		if r.Header.Get("Authorization") != "Bearer validtoken" {
+			w.WriteHeader(http.StatusUnauthorized)
 			return
 		}
-
-		if !strings.HasPrefix(r.URL.Path, "/api/v1") {
-			t.Errorf("Expected path starting with /api/v1, got %s", r.URL.Path)
+		if r.URL.Path == "/api/v1/file_data/status/file-uuid" {
+			_ = json.NewEncoder(w).Encode(status())
 			return
 		}
-
-		authHeader := r.Header.Get("Authorization")
-		if authHeader != "Bearer validtoken" {
-			w.WriteHeader(http.StatusUnauthorized)
+		part, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/api/v1/file_data/load/file-uuid/"))
+		if err != nil {
+			t.Errorf("Unexpected path %s", r.URL.Path)
 			return
 		}
-
-		contentType := r.Header.Get("Content-Type")
-		if !strings.HasPrefix(contentType, "multipart/form-data") {
-			t.Errorf("Expected Content-Type starting with multipart/form-data, got %s", contentType)
+		bodyBytes, _ := io.ReadAll(r.Body)
+		rawBody, err := cryptService.OpenRequest(r, bodyBytes)
+		require.NoError(t, err)
+		r.Body = io.NopCloser(bytes.NewReader(rawBody))
+		require.NoError(t, r.ParseMultipartForm(data_type.FileChunkMaxSize))
+		formFile, _, err := r.FormFile(data_type.FileField)
+		require.NoError(t, err)
+		chunk, _ := io.ReadAll(formFile)
+		if util.ChunkChecksum(chunk) != r.FormValue(data_type.ChecksumField) {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			_, _ = w.Write([]byte(`{"code":1007}`))
 			return
 		}
-
-		w.WriteHeader(http.StatusOK)
+		chunk, err = vault.Decrypt(chunk)
+		require.NoError(t, err)
+		received[part] = chunk
+		uploaded = append(uploaded, part)
+		if len(received) == 2 && brokenChunk >= 0 {
+			// повреждённая часть удаляется, приём последней части откатывается
+			delete(received, brokenChunk)
+			delete(received, part)
+			brokenChunk = -1
+			w.WriteHeader(http.StatusConflict)
+			_, _ = w.Write([]byte(`{"code":1008}`))
+			return
+		}
+		_ = json.NewEncoder(w).Encode(status())
 	}))
 
 	defer server.Close()
@@ -161,30 +206,41 @@ func TestFileDataUploadFile(t *testing.T) {
 	}
 
 	mockConfig := makeMockConfig(server.URL)
-	controller := NewFileData(mockConfig, cryptService, log)
+	controller := NewFileData(mockConfig, cryptService, vault, newTestOfflineVault(t), log)
 
-	tempFile, err := os.CreateTemp("", "testfile")
-	if err != nil {
-		t.Fatalf("Failed to create temporary file: %v", err)
-	}
-	defer os.Remove(tempFile.Name())
+	filePath := path.Join(t.TempDir(), "testfile")
+	require.NoError(t, os.WriteFile(filePath, content, 0600))
+	upload := &FileDataResponse{UploadPath: "/file_data/load/file-uuid", StatusPath: "/file_data/status/file-uuid"}
 
-	_, err = tempFile.WriteString("Hello")
-	if err != nil {
-		t.Fatalf("Failed to write to temporary file: %v", err)
-	}
-	tempFile.Close()
+	t.Run("resume", func(t *testing.T) {
+		received[0] = content[:data_type.FileChunkSize] // первая часть принята до обрыва связи
+		file, _ := os.Open(filePath)
+		defer file.Close()
+		err = controller.UploadFile("validtoken", upload, file)
+		require.NoError(t, err)
 
-	t.Run("ok", func(t *testing.T) {
-		err = controller.UploadFile("validtoken", "/upload", tempFile)
-		if err != nil {
-			t.Errorf("Send failed: %v", err)
-		}
+		assert.Equal(t, []int{1}, uploaded)
+		assert.Equal(t, content, append(bytes.Clone(received[0]), received[1]...))
+	})
 
+	t.Run("broken_chunk", func(t *testing.T) {
+		received = map[int][]byte{0: content[:data_type.FileChunkSize]}
+		uploaded = nil
+		brokenChunk = 0
+		file, _ := os.Open(filePath)
+		defer file.Close()
+		err = controller.UploadFile("validtoken", upload, file)
+		require.NoError(t, err)
+
+		// после неудачной сборки части отправлены заново по состоянию загрузки
+		assert.Equal(t, []int{1, 0, 1}, uploaded)
+		assert.Equal(t, content, append(bytes.Clone(received[0]), received[1]...))
 	})
 
 	t.Run("no_validtoken", func(t *testing.T) {
-		err = controller.UploadFile("no_validtoken", "/upload", tempFile)
+		file, _ := os.Open(filePath)
+		defer file.Close()
+		err = controller.UploadFile("no_validtoken", upload, file)
 		if err == nil {
 			t.Errorf("Send failed: %v", err)
 		}
@@ -192,8 +248,15 @@ func TestFileDataUploadFile(t *testing.T) {
 
 }
 
+func TestFileChunks(t *testing.T) {
+	assert.Equal(t, 1, fileChunks(0))
+	assert.Equal(t, 1, fileChunks(data_type.FileChunkSize))
+	assert.Equal(t, 2, fileChunks(data_type.FileChunkSize+1))
+}
+
 func TestFileDataDownLoadFile(t *testing.T) {
 	cryptService := NewCryptMock(t)
+	vault := newVaultMock(t)
 
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
@@ -224,13 +287,18 @@ func TestFileDataDownLoadFile(t *testing.T) {
 		}
 
 		if strings.Contains(r.URL.Path, "valid_file_1123") {
-			rawBody, _ := cryptService.EncryptAES([]byte("file_data"))
+			framed := new(bytes.Buffer)
+			for _, chunk := range []string{"file_", "data"} {
+				sealed, _ := vault.Encrypt([]byte(chunk))
+				_ = util.WriteChunkFrame(framed, sealed)
+			}
+			rawBody, _ := cryptService.EncryptAES(framed.Bytes())
 			_, _ = w.Write(rawBody)
 			return
 		}
 
 		bodyBytes, _ := io.ReadAll(r.Body)
-		rawBody, _ := cryptService.DecryptAES(bodyBytes)
+		rawBody, _ := cryptService.OpenRequest(r, bodyBytes)
 		buf := bytes.NewBuffer(rawBody)
 
 		var requestData model_data.FileDataInitRequest
@@ -261,7 +329,7 @@ func TestFileDataDownLoadFile(t *testing.T) {
 	}
 
 	mockConfig := makeMockConfig(server.URL)
-	controller := NewFileData(mockConfig, cryptService, log)
+	controller := NewFileData(mockConfig, cryptService, vault, newTestOfflineVault(t), log)
 
 	tempFile, err := os.CreateTemp("", "testfile")
 	if err != nil {
@@ -280,6 +348,18 @@ func TestFileDataDownLoadFile(t *testing.T) {
 		if err != nil {
 			t.Errorf("Send failed: %v", err)
 		}
+		content, _ := os.ReadFile("file_name")
+		assert.Equal(t, "file_data", string(content))
+		os.Remove("file_name")
+	})
+
+	t.Run("server_file_name", func(t *testing.T) {
+		// имя файла приходит с сервера, папки из него не используются
+		err = controller.DownLoadFile("validtoken", "../file_name", "valid_file_1123")
+		assert.NoError(t, err)
+		assert.NoFileExists(t, "../file_name")
+		content, _ := os.ReadFile("file_name")
+		assert.Equal(t, "file_data", string(content))
 		os.Remove("file_name")
 	})
 
@@ -5,81 +5,159 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"time"
 
 	"github.com/northmule/gophkeeper/internal/client/config"
 	"github.com/northmule/gophkeeper/internal/client/logger"
 	"github.com/northmule/gophkeeper/internal/client/service"
+	"github.com/northmule/gophkeeper/internal/client/storage"
 	"github.com/northmule/gophkeeper/internal/common/model_data"
 	"golang.org/x/net/context"
 )
 
 // GridData контроллер
 type GridData struct {
-	logger *logger.Logger
-	cfg    *config.Config
-	crypt  service.Cryptographer
+	logger  *logger.Logger
+	cfg     *config.Config
+	client  *http.Client
+	crypt   service.Cryptographer
+	offline *storage.OfflineVault
 }
 
 // NewGridData конструктор
-func NewGridData(cfg *config.Config, crypt service.Cryptographer, logger *logger.Logger) *GridData {
+func NewGridData(cfg *config.Config, crypt service.Cryptographer, offline *storage.OfflineVault, logger *logger.Logger) *GridData {
 	return &GridData{
-		logger: logger,
-		cfg:    cfg,
-		crypt:  crypt,
+		logger:  logger,
+		cfg:     cfg,
+		client:  newHTTPClient(cfg),
+		crypt:   crypt,
+		offline: offline,
 	}
 }
 
 // GridDataResponse ответ
 type GridDataResponse struct {
 	model_data.ListDataItemsResponse
+	// Offline сервер недоступен, список из локальной копии
+	Offline bool `json:"-"`
+	// SyncedAt время получения списка локальной копии с сервера
+	SyncedAt time.Time `json:"-"`
 }
 
-// Send отправка запроса к серверу
+// gridSyncLimit изменений в одном ответе синхронизации
+const gridSyncLimit = 200
+
+// Send отправка запроса к серверу. При открытой копии хранилища запрашиваются только изменения
+// после сохранённого курсора, список берётся из копии
 func (c *GridData) Send(token string) (*GridDataResponse, error) {
-	requestURL := fmt.Sprintf("%s/api/v1/items_list?offset=0&limit=200", c.cfg.Value().ServerAddress) //todo
+	if c.offline.IsOpen() {
+		return c.sync(token)
+	}
+	responseData := new(GridDataResponse)
+	err := c.get(token, "/api/v1/items_list?offset=0&limit=200", responseData) //todo
+	if err != nil {
+		return nil, err
+	}
+	return responseData, nil
+}
+
+// sync получение изменений списка постранично до последнего курсора
+func (c *GridData) sync(token string) (*GridDataResponse, error) {
+	cursor, err := c.offline.Cursor()
+	if err != nil {
+		return nil, err
+	}
+	// с пустого курсора сервер вернёт весь список, прежний список копии заменяется
+	full := cursor == ""
+	for {
+		changes := new(model_data.SyncResponse)
+		err = c.get(token, fmt.Sprintf("/api/v1/sync?cursor=%s&limit=%d", url.QueryEscape(cursor), gridSyncLimit), changes)
+		if err != nil {
+			return c.fromOffline(err)
+		}
+		deleted := make([]string, 0, len(changes.Deleted))
+		for _, item := range changes.Deleted {
+			deleted = append(deleted, item.UUID)
+		}
+		err = c.offline.ApplySync(changes.Changed, deleted, changes.Cursor, full)
+		if err != nil {
+			c.logger.Error(err)
+			return nil, err
+		}
+		full = false
+		cursor = changes.Cursor
+		if !changes.HasMore {
+			break
+		}
+	}
+
+	items, syncedAt, err := c.offline.List()
+	if err != nil {
+		return nil, err
+	}
+	responseData := new(GridDataResponse)
+	responseData.Items = items
+	responseData.SyncedAt = syncedAt
+	return responseData, nil
+}
+
+// get запрос к серверу и расшифровка ответа в responseData
+func (c *GridData) get(token string, path string, responseData any) error {
+	requestURL := fmt.Sprintf("%s%s", c.cfg.Value().ServerAddress, path)
 	ctx := context.Background()
 
 	requestPrepare, err := http.NewRequestWithContext(ctx, http.MethodGet, requestURL, nil)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	requestPrepare.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
-	client := &http.Client{}
-	response, err := client.Do(requestPrepare)
+	response, err := c.client.Do(requestPrepare)
 	if err != nil {
-		return nil, err
+		c.logger.Error(err)
+		return err
 	}
 	defer response.Body.Close()
 
 	if response.StatusCode != http.StatusOK {
 		if response.StatusCode == http.StatusUnauthorized {
-			return nil, fmt.Errorf("вы не авторизованы")
+			return fmt.Errorf("вы не авторизованы")
 		}
 
 		if response.StatusCode == http.StatusBadRequest {
-			return nil, fmt.Errorf("ошибка в запросе")
+			return fmt.Errorf("ошибка в запросе")
 		}
-		return nil, fmt.Errorf("не известная ошибка")
+		return fmt.Errorf("не известная ошибка")
 	}
 
 	bodyRaw, err := io.ReadAll(response.Body)
 	if err != nil {
 		c.logger.Error(err)
-		return nil, err
+		return err
 	}
 	// Расшифровка тела
 	bodyRaw, err = c.crypt.DecryptAES(bodyRaw)
 	if err != nil {
 		c.logger.Error(err)
-		return nil, err
+		return err
 	}
 
-	responseData := new(GridDataResponse)
 	err = json.Unmarshal(bodyRaw, responseData)
 	if err != nil {
 		c.logger.Error(err)
-		return nil, err
+		return err
 	}
+	return nil
+}
 
-	return responseData, nil
+// fromOffline список из локальной копии, если сервер недоступен
+func (c *GridData) fromOffline(requestErr error) (*GridDataResponse, error) {
+	if !isUnreachable(requestErr) || !c.offline.IsOpen() {
+		return nil, requestErr
+	}
+	items, syncedAt, err := c.offline.List()
+	if err != nil {
+		return nil, requestErr
+	}
+	return offlineGridData(items, syncedAt), nil
 }
@@ -1,12 +1,17 @@
 package controller
 
 import (
+	"bytes"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
 
 	"github.com/northmule/gophkeeper/internal/client/logger"
+	"github.com/northmule/gophkeeper/internal/common/model_data"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestGridDataSend(t *testing.T) {
@@ -43,7 +48,7 @@ func TestGridDataSend(t *testing.T) {
 	}
 
 	mockConfig := makeMockConfig(server.URL)
-	controller := NewGridData(mockConfig, cryptService, log)
+	controller := NewGridData(mockConfig, cryptService, newTestOfflineVault(t), log)
 
 	t.Run("ok", func(t *testing.T) {
 		_, err := controller.Send("validtoken")
@@ -60,3 +65,53 @@ func TestGridDataSend(t *testing.T) {
 		}
 	})
 }
+
+func TestGridDataSync(t *testing.T) {
+	cryptService := NewCryptMock(t)
+	var cursors []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/sync" {
+			t.Errorf("Expected path /api/v1/sync, got %s", r.URL.Path)
+			return
+		}
+		cursor := r.URL.Query().Get("cursor")
+		cursors = append(cursors, cursor)
+		response := model_data.SyncResponse{}
+		switch cursor {
+		case "":
+			response.Changed = []model_data.ItemDataResponse{{Name: "note", UUID: "text-uuid", Revision: 1}}
+			response.Cursor = "1"
+			response.HasMore = true
+		case "1":
+			response.Changed = []model_data.ItemDataResponse{{Name: "card", UUID: "card-uuid", Revision: 2}}
+			response.Cursor = "2"
+		default:
+			response.Deleted = []model_data.DeletedItemResponse{{UUID: "text-uuid", Revision: 3}}
+			response.Cursor = "3"
+		}
+		body, _ := json.Marshal(response)
+		rawBody, _ := cryptService.EncryptAES(body)
+		_, _ = w.Write(rawBody)
+	}))
+	defer server.Close()
+
+	log, err := logger.NewLogger("info")
+	require.NoError(t, err)
+	store := newTestOfflineVault(t)
+	require.NoError(t, store.Open(bytes.Repeat([]byte{1}, 32)))
+	controller := NewGridData(makeMockConfig(server.URL), cryptService, store, log)
+
+	// первая синхронизация получает весь список постранично
+	grid, err := controller.Send("validtoken")
+	require.NoError(t, err)
+	require.Len(t, grid.Items, 2)
+	assert.Equal(t, "2", grid.Items[1].Number)
+	assert.Equal(t, []string{"", "1"}, cursors)
+
+	// следующая только изменения после курсора
+	grid, err = controller.Send("validtoken")
+	require.NoError(t, err)
+	require.Len(t, grid.Items, 1)
+	assert.Equal(t, "card-uuid", grid.Items[0].UUID)
+	assert.Equal(t, []string{"", "1", "2"}, cursors)
+}
@@ -10,23 +10,30 @@ import (
 	"github.com/northmule/gophkeeper/internal/client/config"
 	"github.com/northmule/gophkeeper/internal/client/logger"
 	"github.com/northmule/gophkeeper/internal/client/service"
+	"github.com/northmule/gophkeeper/internal/client/storage"
 	"github.com/northmule/gophkeeper/internal/common/model_data"
 	"golang.org/x/net/context"
 )
 
 // ItemData контроллер запроса данных по uuid
 type ItemData struct {
-	logger *logger.Logger
-	cfg    *config.Config
-	crypt  service.Cryptographer
+	logger  *logger.Logger
+	cfg     *config.Config
+	client  *http.Client
+	crypt   service.Cryptographer
+	vault   service.VaultCryptographer
+	offline *storage.OfflineVault
 }
 
 // NewItemData конструктор
-func NewItemData(cfg *config.Config, crypt service.Cryptographer, logger *logger.Logger) *ItemData {
+func NewItemData(cfg *config.Config, crypt service.Cryptographer, vault service.VaultCryptographer, offline *storage.OfflineVault, logger *logger.Logger) *ItemData {
 	return &ItemData{
-		logger: logger,
-		cfg:    cfg,
-		crypt:  crypt,
+		logger:  logger,
+		cfg:     cfg,
+		client:  newHTTPClient(cfg),
+		crypt:   crypt,
+		vault:   vault,
+		offline: offline,
 	}
 }
 
@@ -42,9 +49,15 @@ func (c *ItemData) Send(token string, dataUUID string) (*model_data.DataByUUIDRe
 		return nil, err
 	}
 	requestPrepare.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
-	client := &http.Client{}
-	response, err := client.Do(requestPrepare)
+	response, err := c.client.Do(requestPrepare)
 	if err != nil {
+		c.logger.Error(err)
+		// сервер недоступен, данные из локальной копии
+		if isUnreachable(err) && c.offline.IsOpen() {
+			if item, offlineErr := c.offline.Item(dataUUID); offlineErr == nil {
+				return item, nil
+			}
+		}
 		return nil, err
 	}
 	defer response.Body.Close()
@@ -78,6 +91,18 @@ func (c *ItemData) Send(token string, dataUUID string) (*model_data.DataByUUIDRe
 		c.logger.Error(err)
 		return nil, err
 	}
+	// Расшифровка значений мастер-ключом
+	err = openDataByUUID(c.vault, responseData)
+	if err != nil {
+		c.logger.Error(err)
+		return nil, err
+	}
+	if c.offline.IsOpen() {
+		err = c.offline.SaveItem(dataUUID, *responseData)
+		if err != nil {
+			c.logger.Error(err)
+		}
+	}
 
 	return responseData, nil
 }
@@ -1,12 +1,14 @@
 package controller
 
 import (
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
 
 	"github.com/northmule/gophkeeper/internal/client/logger"
+	"github.com/northmule/gophkeeper/internal/common/model_data"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -37,13 +39,49 @@ func TestItemData_Send_Success(t *testing.T) {
 	}
 
 	mockConfig := makeMockConfig(server.URL)
-	controller := NewItemData(mockConfig, cryptService, log)
+	controller := NewItemData(mockConfig, cryptService, newVaultMock(t), newTestOfflineVault(t), log)
 
 	response, err := controller.Send("token", "dataUUID")
 	assert.NoError(t, err)
 	assert.NotNil(t, response)
 }
 
+func TestItemData_Send_DecryptsValues(t *testing.T) {
+	cryptService := NewCryptMock(t)
+	vault := newVaultMock(t)
+
+	sealed, _ := sealCredentialData(vault, &model_data.CredentialDataRequest{
+		Name:     "Mail",
+		Username: "john",
+		Password: "secret",
+		URLs:     []string{"https://mail.example.com"},
+		Meta:     map[string]string{"note": "value"},
+	})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := json.Marshal(model_data.DataByUUIDResponse{IsCredential: true, CredentialData: *sealed})
+		encryptedData, _ := cryptService.EncryptAES(body)
+		w.WriteHeader(http.StatusOK)
+		w.Write(encryptedData)
+	}))
+	defer server.Close()
+
+	log, err := logger.NewLogger("info")
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+
+	mockConfig := makeMockConfig(server.URL)
+	controller := NewItemData(mockConfig, cryptService, vault, newTestOfflineVault(t), log)
+
+	response, err := controller.Send("token", "dataUUID")
+	assert.NoError(t, err)
+	assert.Equal(t, "Mail", response.CredentialData.Name)
+	assert.Equal(t, "john", response.CredentialData.Username)
+	assert.Equal(t, "secret", response.CredentialData.Password)
+	assert.Equal(t, []string{"https://mail.example.com"}, response.CredentialData.URLs)
+	assert.Equal(t, "value", response.CredentialData.Meta["note"])
+}
+
 func TestItemData_Send_Unauthorized(t *testing.T) {
 	cryptService := NewCryptMock(t)
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -57,7 +95,7 @@ func TestItemData_Send_Unauthorized(t *testing.T) {
 	}
 
 	mockConfig := makeMockConfig(server.URL)
-	controller := NewItemData(mockConfig, cryptService, log)
+	controller := NewItemData(mockConfig, cryptService, newVaultMock(t), newTestOfflineVault(t), log)
 	_, err = controller.Send("invalid_token", "dataUUID")
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "вы не авторизованы")
@@ -76,7 +114,7 @@ func TestItemData_Send_BadRequest(t *testing.T) {
 	}
 
 	mockConfig := makeMockConfig(server.URL)
-	controller := NewItemData(mockConfig, cryptService, log)
+	controller := NewItemData(mockConfig, cryptService, newVaultMock(t), newTestOfflineVault(t), log)
 
 	_, err = controller.Send("token", "invalid_dataUUID")
 	assert.Error(t, err)
@@ -96,7 +134,7 @@ func TestItemData_Send_UnknownError(t *testing.T) {
 	}
 
 	mockConfig := makeMockConfig(server.URL)
-	controller := NewItemData(mockConfig, cryptService, log)
+	controller := NewItemData(mockConfig, cryptService, newVaultMock(t), newTestOfflineVault(t), log)
 
 	_, err = controller.Send("token", "dataUUID")
 	assert.Error(t, err)
@@ -2,39 +2,56 @@ package controller
 
 import (
 	"bytes"
+	"crypto/ecdh"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"mime/multipart"
 	"net/http"
 	"os"
 	"path"
+	"strings"
 
 	"github.com/northmule/gophkeeper/internal/client/config"
 	"github.com/northmule/gophkeeper/internal/client/logger"
 	"github.com/northmule/gophkeeper/internal/client/service"
 	"github.com/northmule/gophkeeper/internal/common/data_type"
 	"github.com/northmule/gophkeeper/internal/common/keys"
+	"github.com/northmule/gophkeeper/internal/common/model_data"
+	"github.com/northmule/gophkeeper/internal/common/models"
 	"github.com/northmule/gophkeeper/internal/common/util"
 	"golang.org/x/net/context"
 )
 
+// ErrClientKeyMismatch сервер хранит другой ключ шифрования клиента (PathKeys утерян или заменён),
+// ключ восстанавливается из комплекта восстановления
+var ErrClientKeyMismatch = errors.New("ключ шифрования не совпадает с сохранённым на сервере, требуется восстановление")
+
 // KeysData контроллер обмена ключами с сервером
 type KeysData struct {
 	logger *logger.Logger
 	cfg    *config.Config
+	client *http.Client
 	crypt  service.Cryptographer
+	vault  service.Vaulter
 }
 
 // NewKeysData конструктор
-func NewKeysData(cfg *config.Config, crypt service.Cryptographer, logger *logger.Logger) *KeysData {
+func NewKeysData(cfg *config.Config, crypt service.Cryptographer, vault service.Vaulter, logger *logger.Logger) *KeysData {
 	return &KeysData{
 		logger: logger,
 		cfg:    cfg,
+		client: newHTTPClient(cfg),
 		crypt:  crypt,
+		vault:  vault,
 	}
 }
 
-// UploadClientPublicKey отправить публичный ключ на сервер
+// UploadClientPublicKey отправить публичный ключ устройства на сервер. Если хранилище разблокировано,
+// вместе с ключом отправляется ключ хранилища, зашифрованный ключом устройства
 func (c *KeysData) UploadClientPublicKey(token string) error {
 	requestURL := fmt.Sprintf("%s/api/v1/save_public_key", c.cfg.Value().ServerAddress)
 	body := &bytes.Buffer{}
@@ -48,6 +65,20 @@ func (c *KeysData) UploadClientPublicKey(token string) error {
 		return err
 	}
 	_, err = io.Copy(part, key)
+	_ = key.Close()
+	if err != nil {
+		return err
+	}
+	if c.vault.IsUnlocked() {
+		vaultKey, err := sealDeviceVaultKey(c.cfg, c.vault)
+		if err != nil {
+			return err
+		}
+		err = writer.WriteField(data_type.VaultKeyField, vaultKey)
+		if err != nil {
+			return err
+		}
+	}
 
 	err = writer.Close()
 	if err != nil {
@@ -60,8 +91,7 @@ func (c *KeysData) UploadClientPublicKey(token string) error {
 	}
 	requestPrepare.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
 	requestPrepare.Header.Add("Content-Type", writer.FormDataContentType())
-	client := &http.Client{}
-	response, err := client.Do(requestPrepare)
+	response, err := c.client.Do(requestPrepare)
 	if err != nil {
 		return err
 	}
@@ -74,6 +104,31 @@ func (c *KeysData) UploadClientPublicKey(token string) error {
 	}
 	defer response.Body.Close()
 
+	return c.saveClientCertificate(response.Body)
+}
+
+// saveClientCertificate сохраняет сертификат клиента, если сервер выдал его в ответ на публичный ключ (mTLS)
+func (c *KeysData) saveClientCertificate(body io.Reader) error {
+	certificate := new(model_data.ClientCertificateResponse)
+	err := json.NewDecoder(body).Decode(certificate)
+	if errors.Is(err, io.EOF) || certificate.Certificate == "" {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	fingerprint, err := keys.CertificateFingerprint([]byte(certificate.Certificate))
+	if err != nil {
+		return err
+	}
+	if fingerprint != certificate.Fingerprint {
+		return fmt.Errorf("сертификат клиента повреждён")
+	}
+	err = os.WriteFile(path.Join(c.cfg.Value().PathKeys, keys.ClientCertificateFileName), []byte(certificate.Certificate), 0644)
+	if err != nil {
+		return err
+	}
+	c.logger.Infof("Client certificate %s has been saved", fingerprint)
 	return nil
 }
 
@@ -87,8 +142,8 @@ func (c *KeysData) DownloadPublicServerKey(token string) error {
 		return err
 	}
 	requestPrepare.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
-	client := &http.Client{}
-	response, err := client.Do(requestPrepare)
+	requestPrepare.Header.Add(data_type.KeyExchangeHeader, strings.Join([]string{util.KeyExchangeX25519, util.KeyExchangeRSA}, ", "))
+	response, err := c.client.Do(requestPrepare)
 	if err != nil {
 		return err
 	}
@@ -113,61 +168,247 @@ func (c *KeysData) DownloadPublicServerKey(token string) error {
 	if err != nil {
 		return err
 	}
+	defer f.Close()
 	_, err = io.Copy(f, response.Body)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	return c.saveExchangePublicKey(response)
 }
 
-// UploadClientPrivateKey отправить приватный ключ ключ на сервер
-func (c *KeysData) UploadClientPrivateKey(token string) error {
-	requestURL := fmt.Sprintf("%s/api/v1/save_client_private_key", c.cfg.Value().ServerAddress)
-	body := &bytes.Buffer{}
-	writer := multipart.NewWriter(body)
-	part, err := writer.CreateFormFile(data_type.FileField, "private_client_key")
+// saveExchangePublicKey сохраняет ключ обмена X25519, если сервер выбрал согласование ключа. Иначе ключ шифруется RSA ключом сервера
+func (c *KeysData) saveExchangePublicKey(response *http.Response) error {
+	exchangeKeyPath := path.Join(c.cfg.Value().PathPublicKeyServer, keys.ExchangePublicKeyFileName)
+	if response.Header.Get(data_type.KeyExchangeHeader) != util.KeyExchangeX25519 {
+		err := os.Remove(exchangeKeyPath)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+	rawKey, err := base64.StdEncoding.DecodeString(response.Header.Get(data_type.KeyExchangePublicKeyHeader))
 	if err != nil {
 		return err
 	}
-	f, err := os.OpenFile(path.Join(c.cfg.Value().PathKeys, keys.PrivateKeyFileNameForEncryption), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	exchangeKey, err := ecdh.X25519().NewPublicKey(rawKey)
 	if err != nil {
 		return err
 	}
-	defer f.Close()
-	privateKey := []byte(util.CreateHashForKey("super_secret_key")) //todo
-	_, err = f.Write(privateKey)
+	keyPEM, err := util.ExchangePublicKeyToPEM(exchangeKey)
 	if err != nil {
 		return err
 	}
+	return os.WriteFile(exchangeKeyPath, keyPEM, 0644)
+}
+
+// UploadClientPrivateKey отправить приватный ключ ключ на сервер
+func (c *KeysData) UploadClientPrivateKey(token string) error {
+	// незавершённая до выхода смена ключа: новый ключ применяется, если сервер её завершил
+	if c.hasNextPrivateKey() {
+		_, err := c.KeyRotationStatus(token)
+		if err != nil {
+			return err
+		}
+	}
+
+	keyPath := path.Join(c.cfg.Value().PathKeys, keys.PrivateKeyFileNameForEncryption)
+	privateKey, err := os.ReadFile(keyPath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	created := len(privateKey) == 0
+	if created {
+		// ключ создаётся на устройстве случайным и не повторяется у других клиентов
+		privateKey, err = newClientEncryptionKey()
+		if err != nil {
+			return err
+		}
+		err = os.WriteFile(keyPath, privateKey, 0600)
+		if err != nil {
+			return err
+		}
+	}
 
-	// шифруем публичным серверным ключом
-	privateKeyCrypt, err := c.crypt.EncryptRSA(privateKey)
+	response, err := c.sendClientPrivateKey(token, "save_client_private_key", privateKey)
 	if err != nil {
 		return err
 	}
+	defer response.Body.Close()
+
+	if response.StatusCode == http.StatusConflict {
+		if created {
+			// новый ключ не подошёл, его место занимает восстановленный
+			_ = os.Remove(keyPath)
+		}
+		return ErrClientKeyMismatch
+	}
+	if response.StatusCode != http.StatusOK {
+		bodyRaw, _ := io.ReadAll(response.Body)
+		return fmt.Errorf("сервер не принял ключ: %s", bodyRaw)
+	}
+
+	return nil
+}
+
+// RotateClientPrivateKey смена ключа шифрования клиента. Новый ключ хранится рядом с прежним
+// и заменяет его, когда сервер завершит смену (KeyRotationStatus)
+func (c *KeysData) RotateClientPrivateKey(token string) (*model_data.KeyRotationResponse, error) {
+	if c.hasNextPrivateKey() {
+		rotation, err := c.KeyRotationStatus(token)
+		if err != nil {
+			return nil, err
+		}
+		if c.hasNextPrivateKey() {
+			return rotation, fmt.Errorf("смена ключа уже выполняется")
+		}
+	}
+
+	privateKey, err := newClientEncryptionKey()
+	if err != nil {
+		return nil, err
+	}
+	nextKeyPath := path.Join(c.cfg.Value().PathKeys, keys.NextPrivateKeyFileNameForEncryption)
+	err = os.WriteFile(nextKeyPath, privateKey, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := c.sendClientPrivateKey(token, "rotate_client_private_key", privateKey)
+	if err != nil {
+		// ответ не получен: ключ оставляем, состояние смены уточняется у сервера
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusAccepted {
+		_ = os.Remove(nextKeyPath)
+		if response.StatusCode == http.StatusConflict {
+			return nil, fmt.Errorf("смена ключа уже выполняется")
+		}
+		bodyRaw, _ := io.ReadAll(response.Body)
+		return nil, fmt.Errorf("сервер не принял ключ: %s", bodyRaw)
+	}
+
+	rotation := new(model_data.KeyRotationResponse)
+	err = json.NewDecoder(response.Body).Decode(rotation)
+	if err != nil {
+		return nil, err
+	}
+	c.logger.Infof("The key rotation %s has been started", rotation.UUID)
+	return rotation, nil
+}
+
+// KeyRotationStatus состояние последней смены ключа. После завершения смены новый ключ заменяет прежний.
+// Возвращает nil, если ключ не менялся
+func (c *KeysData) KeyRotationStatus(token string) (*model_data.KeyRotationResponse, error) {
+	requestURL := fmt.Sprintf("%s/api/v1/key_rotation", c.cfg.Value().ServerAddress)
+	requestPrepare, err := http.NewRequestWithContext(context.Background(), http.MethodGet, requestURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	requestPrepare.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+	response, err := c.client.Do(requestPrepare)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	nextKeyPath := path.Join(c.cfg.Value().PathKeys, keys.NextPrivateKeyFileNameForEncryption)
+	if response.StatusCode == http.StatusNotFound {
+		// сервер не принял новый ключ
+		err = os.Remove(nextKeyPath)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+		return nil, nil
+	}
+	if response.StatusCode != http.StatusOK {
+		if response.StatusCode == http.StatusUnauthorized {
+			return nil, fmt.Errorf("вы не авторизованы")
+		}
+		bodyRaw, _ := io.ReadAll(response.Body)
+		return nil, fmt.Errorf("не известная ошибка: %s", bodyRaw)
+	}
+
+	rotation := new(model_data.KeyRotationResponse)
+	err = json.NewDecoder(response.Body).Decode(rotation)
+	if err != nil {
+		return nil, err
+	}
+	if !c.hasNextPrivateKey() {
+		return rotation, nil
+	}
+	switch rotation.Status {
+	case models.KeyRotationFinished:
+		err = os.Rename(nextKeyPath, path.Join(c.cfg.Value().PathKeys, keys.PrivateKeyFileNameForEncryption))
+		if err != nil {
+			return nil, err
+		}
+		err = c.crypt.ReloadEncryptionKey()
+		if err != nil {
+			return nil, err
+		}
+		c.logger.Infof("The key rotation %s has been finished, the new key is in use", rotation.UUID)
+	case models.KeyRotationFailed:
+		err = os.Remove(nextKeyPath)
+		if err != nil {
+			return nil, err
+		}
+		c.logger.Infof("The key rotation %s has failed: %s", rotation.UUID, rotation.Error)
+	}
+	return rotation, nil
+}
+
+// hasNextPrivateKey есть новый ключ незавершённой смены
+func (c *KeysData) hasNextPrivateKey() bool {
+	_, err := os.Stat(path.Join(c.cfg.Value().PathKeys, keys.NextPrivateKeyFileNameForEncryption))
+	return err == nil
+}
+
+// newClientEncryptionKey случайный ключ шифрования данных между клиентом и сервером (AES-256, 32 случайных байта)
+func newClientEncryptionKey() ([]byte, error) {
+	key := make([]byte, 32)
+	_, err := rand.Read(key)
+	if err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// sendClientPrivateKey отправка ключа клиента, зашифрованного по схеме, согласованной с сервером
+func (c *KeysData) sendClientPrivateKey(token string, action string, privateKey []byte) (*http.Response, error) {
+	requestURL := fmt.Sprintf("%s/api/v1/%s", c.cfg.Value().ServerAddress, action)
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, err := writer.CreateFormFile(data_type.FileField, "private_client_key")
+	if err != nil {
+		return nil, err
+	}
+
+	scheme, privateKeyCrypt, err := c.crypt.EncryptKey(privateKey)
+	if err != nil {
+		return nil, err
+	}
 	_, err = part.Write(privateKeyCrypt)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	err = writer.WriteField(data_type.KeyExchangeField, scheme)
+	if err != nil {
+		return nil, err
 	}
 
 	err = writer.Close()
 	if err != nil {
-		return err
+		return nil, err
 	}
 	ctx := context.Background()
 	requestPrepare, err := http.NewRequestWithContext(ctx, http.MethodPost, requestURL, body)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	requestPrepare.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
 	requestPrepare.Header.Add("Content-Type", writer.FormDataContentType())
-	client := &http.Client{}
-	response, err := client.Do(requestPrepare)
-	if err != nil {
-		return err
-	}
-	defer response.Body.Close()
-
-	return nil
+	return c.client.Do(requestPrepare)
 }
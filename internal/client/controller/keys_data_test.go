@@ -2,6 +2,7 @@ package controller
 
 import (
 	"bytes"
+	"encoding/base64"
 	"io"
 	"net/http"
 	"net/http/httptest"
@@ -11,8 +12,10 @@ import (
 	"testing"
 
 	"github.com/northmule/gophkeeper/internal/client/logger"
+	"github.com/northmule/gophkeeper/internal/client/service"
 	"github.com/northmule/gophkeeper/internal/common/data_type"
 	"github.com/northmule/gophkeeper/internal/common/keys"
+	"github.com/northmule/gophkeeper/internal/common/util"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -58,12 +61,38 @@ func TestKeysData_UploadClientPublicKey_Success(t *testing.T) {
 	}
 
 	mockConfig := makeMockConfig(server.URL)
-	controller := NewKeysData(mockConfig, cryptService, log)
+	controller := NewKeysData(mockConfig, cryptService, service.NewVault(), log)
 
 	err = controller.UploadClientPublicKey("token")
 	assert.NoError(t, err)
 }
 
+func TestKeysData_UploadClientPublicKey_DeviceVaultKey(t *testing.T) {
+	var vaultKey string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		vaultKey = r.FormValue(data_type.VaultKeyField)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	log, _ := logger.NewLogger("info")
+	mockConfig := makeMockConfig(server.URL)
+	mockConfig.Value().PathKeys = t.TempDir()
+	err := os.WriteFile(path.Join(mockConfig.Value().PathKeys, keys.PublicKeyFileName), []byte("public_key_data"), 0644)
+	assert.NoError(t, err)
+
+	vault := service.NewVault()
+	controller := NewKeysData(mockConfig, NewCryptMock(t), vault, log)
+	// хранилище заблокировано, ключ хранилища не отправляется
+	assert.NoError(t, controller.UploadClientPublicKey("token"))
+	assert.Empty(t, vaultKey)
+
+	_, _, err = vault.Setup("master password")
+	assert.NoError(t, err)
+	assert.NoError(t, controller.UploadClientPublicKey("token"))
+	assert.NotEmpty(t, vaultKey)
+	assert.FileExists(t, path.Join(mockConfig.Value().PathKeys, keys.DeviceKeyFileName))
+}
+
 func TestKeysData_UploadClientPublicKey_Unauthorized(t *testing.T) {
 
 	tempFile, err := os.Create(path.Join("", keys.PublicKeyFileName))
@@ -89,7 +118,7 @@ func TestKeysData_UploadClientPublicKey_Unauthorized(t *testing.T) {
 	}
 	cryptService := NewCryptMock(t)
 	mockConfig := makeMockConfig(server.URL)
-	controller := NewKeysData(mockConfig, cryptService, log)
+	controller := NewKeysData(mockConfig, cryptService, service.NewVault(), log)
 
 	err = controller.UploadClientPublicKey("invalid_token")
 	assert.Error(t, err)
@@ -103,7 +132,7 @@ func TestKeysData_UploadClientPublicKey_FileOpenError(t *testing.T) {
 	}
 	cryptService := NewCryptMock(t)
 	mockConfig := makeMockConfig("http://uuu.loc")
-	controller := NewKeysData(mockConfig, cryptService, log)
+	controller := NewKeysData(mockConfig, cryptService, service.NewVault(), log)
 
 	err = controller.UploadClientPublicKey("token")
 	assert.Error(t, err)
@@ -122,7 +151,7 @@ func TestKeysData_UploadClientPublicKey_MultipartFormError(t *testing.T) {
 	}
 	cryptService := NewCryptMock(t)
 	mockConfig := makeMockConfig(server.URL)
-	controller := NewKeysData(mockConfig, cryptService, log)
+	controller := NewKeysData(mockConfig, cryptService, service.NewVault(), log)
 
 	tempFile, err := os.Create(path.Join("", keys.PublicKeyFileName))
 	if err != nil {
@@ -162,7 +191,7 @@ func TestKeysData_DownloadPublicServerKey_Success(t *testing.T) {
 	}
 	cryptService := NewCryptMock(t)
 	mockConfig := makeMockConfig(server.URL)
-	controller := NewKeysData(mockConfig, cryptService, log)
+	controller := NewKeysData(mockConfig, cryptService, service.NewVault(), log)
 
 	err = controller.DownloadPublicServerKey("token")
 	assert.NoError(t, err)
@@ -172,6 +201,42 @@ func TestKeysData_DownloadPublicServerKey_Success(t *testing.T) {
 	assert.Equal(t, "server_public_key_data", string(data))
 }
 
+func TestKeysData_DownloadPublicServerKey_X25519(t *testing.T) {
+	exchangeKey, err := util.GenerateExchangeKey()
+	assert.NoError(t, err)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Contains(t, r.Header.Get(data_type.KeyExchangeHeader), util.KeyExchangeX25519)
+		w.Header().Set(data_type.KeyExchangeHeader, util.KeyExchangeX25519)
+		w.Header().Set(data_type.KeyExchangePublicKeyHeader, base64.StdEncoding.EncodeToString(exchangeKey.PublicKey().Bytes()))
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("server_public_key_data"))
+	}))
+	defer server.Close()
+	defer os.Remove(keys.ExchangePublicKeyFileName)
+
+	log, err := logger.NewLogger("info")
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	controller := NewKeysData(makeMockConfig(server.URL), NewCryptMock(t), service.NewVault(), log)
+
+	err = controller.DownloadPublicServerKey("token")
+	assert.NoError(t, err)
+
+	saved, err := util.FillExchangePublicKeyFromFile(keys.ExchangePublicKeyFileName)
+	assert.NoError(t, err)
+	assert.True(t, exchangeKey.PublicKey().Equal(saved))
+
+	// сервер без согласования: старый ключ обмена удаляется
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("server_public_key_data"))
+	})
+	err = controller.DownloadPublicServerKey("token")
+	assert.NoError(t, err)
+	assert.NoFileExists(t, keys.ExchangePublicKeyFileName)
+}
+
 func TestKeysData_DownloadPublicServerKey_Unauthorized(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
@@ -184,7 +249,7 @@ func TestKeysData_DownloadPublicServerKey_Unauthorized(t *testing.T) {
 	}
 	cryptService := NewCryptMock(t)
 	mockConfig := makeMockConfig(server.URL)
-	controller := NewKeysData(mockConfig, cryptService, log)
+	controller := NewKeysData(mockConfig, cryptService, service.NewVault(), log)
 
 	err = controller.DownloadPublicServerKey("invalid_token")
 	assert.Error(t, err)
@@ -203,7 +268,7 @@ func TestKeysData_DownloadPublicServerKey_BadRequest(t *testing.T) {
 	}
 	cryptService := NewCryptMock(t)
 	mockConfig := makeMockConfig(server.URL)
-	controller := NewKeysData(mockConfig, cryptService, log)
+	controller := NewKeysData(mockConfig, cryptService, service.NewVault(), log)
 
 	err = controller.DownloadPublicServerKey("token")
 	assert.Error(t, err)
@@ -222,7 +287,7 @@ func TestKeysData_DownloadPublicServerKey_UnknownError(t *testing.T) {
 	}
 	cryptService := NewCryptMock(t)
 	mockConfig := makeMockConfig(server.URL)
-	controller := NewKeysData(mockConfig, cryptService, log)
+	controller := NewKeysData(mockConfig, cryptService, service.NewVault(), log)
 
 	err = controller.DownloadPublicServerKey("token")
 	assert.Error(t, err)
@@ -249,6 +314,7 @@ func TestUploadClientPrivateKey_Success(t *testing.T) {
 		_, err = io.Copy(buf, file)
 		assert.NoError(t, err)
 		assert.NotEmpty(t, buf.String())
+		assert.Equal(t, util.KeyExchangeRSA, r.FormValue(data_type.KeyExchangeField))
 
 		w.WriteHeader(http.StatusOK)
 	}))
@@ -256,7 +322,7 @@ func TestUploadClientPrivateKey_Success(t *testing.T) {
 
 	cryptService := NewCryptMock(t)
 	mockConfig := makeMockConfig(testServer.URL)
-	controller := NewKeysData(mockConfig, cryptService, log)
+	controller := NewKeysData(mockConfig, cryptService, service.NewVault(), log)
 
 	err = controller.UploadClientPrivateKey("test_token")
 	assert.NoError(t, err)
@@ -269,8 +335,195 @@ func TestUploadClientPrivateKey_EmptyToken(t *testing.T) {
 	}
 	cryptService := NewCryptMock(t)
 	mockConfig := makeMockConfig("")
-	controller := NewKeysData(mockConfig, cryptService, log)
+	controller := NewKeysData(mockConfig, cryptService, service.NewVault(), log)
 
 	err = controller.UploadClientPrivateKey("")
 	assert.Error(t, err)
 }
+
+func TestUploadClientPrivateKey_Rejected(t *testing.T) {
+	log, err := logger.NewLogger("info")
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+	}))
+	defer testServer.Close()
+
+	controller := NewKeysData(makeMockConfig(testServer.URL), NewCryptMock(t), service.NewVault(), log)
+
+	err = controller.UploadClientPrivateKey("test_token")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Bad Request")
+}
+
+func TestUploadClientPrivateKey_KeepsExistingKey(t *testing.T) {
+	log, _ := logger.NewLogger("info")
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer testServer.Close()
+
+	mockConfig := makeMockConfig(testServer.URL)
+	mockConfig.Value().PathKeys = t.TempDir()
+	keyPath := filepath.Join(mockConfig.Value().PathKeys, keys.PrivateKeyFileNameForEncryption)
+	assert.NoError(t, os.WriteFile(keyPath, []byte("rotated key"), 0600))
+
+	controller := NewKeysData(mockConfig, NewCryptMock(t), service.NewVault(), log)
+	assert.NoError(t, controller.UploadClientPrivateKey("test_token"))
+
+	// ключ после смены не перезаписывается при входе
+	key, err := os.ReadFile(keyPath)
+	assert.NoError(t, err)
+	assert.Equal(t, "rotated key", string(key))
+}
+
+func TestUploadClientPrivateKey_GeneratesRandomKey(t *testing.T) {
+	log, _ := logger.NewLogger("info")
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer testServer.Close()
+
+	// у каждого клиента свой ключ
+	createdKeys := make([]string, 0, 2)
+	for range 2 {
+		mockConfig := makeMockConfig(testServer.URL)
+		mockConfig.Value().PathKeys = t.TempDir()
+		controller := NewKeysData(mockConfig, NewCryptMock(t), service.NewVault(), log)
+		assert.NoError(t, controller.UploadClientPrivateKey("test_token"))
+
+		keyPath := filepath.Join(mockConfig.Value().PathKeys, keys.PrivateKeyFileNameForEncryption)
+		info, err := os.Stat(keyPath)
+		assert.NoError(t, err)
+		assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+		key, err := os.ReadFile(keyPath)
+		assert.NoError(t, err)
+		assert.Len(t, key, 32)
+		createdKeys = append(createdKeys, string(key))
+	}
+	assert.NotEqual(t, createdKeys[0], createdKeys[1])
+}
+
+func TestRotateClientPrivateKey(t *testing.T) {
+	log, _ := logger.NewLogger("info")
+
+	tests := []struct {
+		name       string
+		statusCode int
+		wantErr    string
+		keepsKey   bool
+	}{
+		{"accepted", http.StatusAccepted, "", true},
+		{"already running", http.StatusConflict, "смена ключа уже выполняется", false},
+		{"rejected", http.StatusBadRequest, "сервер не принял ключ", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "/api/v1/rotate_client_private_key", r.URL.Path)
+				assert.Equal(t, "Bearer test_token", r.Header.Get("Authorization"))
+				assert.NoError(t, r.ParseMultipartForm(32<<20))
+				assert.Equal(t, util.KeyExchangeRSA, r.FormValue(data_type.KeyExchangeField))
+				w.WriteHeader(tt.statusCode)
+				_, _ = w.Write([]byte(`{"uuid":"rotation-uuid","status":"running"}`))
+			}))
+			defer testServer.Close()
+
+			mockConfig := makeMockConfig(testServer.URL)
+			mockConfig.Value().PathKeys = t.TempDir()
+			controller := NewKeysData(mockConfig, NewCryptMock(t), service.NewVault(), log)
+
+			rotation, err := controller.RotateClientPrivateKey("test_token")
+			nextKeyPath := filepath.Join(mockConfig.Value().PathKeys, keys.NextPrivateKeyFileNameForEncryption)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				assert.NoFileExists(t, nextKeyPath)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, "rotation-uuid", rotation.UUID)
+			info, err := os.Stat(nextKeyPath)
+			assert.NoError(t, err)
+			assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+		})
+	}
+}
+
+func TestRotateClientPrivateKey_Pending(t *testing.T) {
+	log, _ := logger.NewLogger("info")
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/key_rotation", r.URL.Path)
+		_, _ = w.Write([]byte(`{"uuid":"rotation-uuid","status":"running"}`))
+	}))
+	defer testServer.Close()
+
+	mockConfig := makeMockConfig(testServer.URL)
+	mockConfig.Value().PathKeys = t.TempDir()
+	nextKeyPath := filepath.Join(mockConfig.Value().PathKeys, keys.NextPrivateKeyFileNameForEncryption)
+	assert.NoError(t, os.WriteFile(nextKeyPath, []byte("next key"), 0600))
+	controller := NewKeysData(mockConfig, NewCryptMock(t), service.NewVault(), log)
+
+	rotation, err := controller.RotateClientPrivateKey("test_token")
+	assert.ErrorContains(t, err, "смена ключа уже выполняется")
+	assert.Equal(t, "running", rotation.Status)
+	key, _ := os.ReadFile(nextKeyPath)
+	assert.Equal(t, "next key", string(key))
+}
+
+func TestKeyRotationStatus(t *testing.T) {
+	log, _ := logger.NewLogger("info")
+
+	tests := []struct {
+		name       string
+		statusCode int
+		body       string
+		wantKey    string
+		wantNext   bool
+		reload     bool
+	}{
+		{"running", http.StatusOK, `{"status":"running"}`, "old key", true, false},
+		{"finished", http.StatusOK, `{"status":"finished"}`, "next key", false, true},
+		{"failed", http.StatusOK, `{"status":"failed","error":"broken"}`, "old key", false, false},
+		{"not found", http.StatusNotFound, ``, "old key", false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, http.MethodGet, r.Method)
+				assert.Equal(t, "/api/v1/key_rotation", r.URL.Path)
+				w.WriteHeader(tt.statusCode)
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer testServer.Close()
+
+			mockConfig := makeMockConfig(testServer.URL)
+			mockConfig.Value().PathKeys = t.TempDir()
+			keyPath := filepath.Join(mockConfig.Value().PathKeys, keys.PrivateKeyFileNameForEncryption)
+			nextKeyPath := filepath.Join(mockConfig.Value().PathKeys, keys.NextPrivateKeyFileNameForEncryption)
+			assert.NoError(t, os.WriteFile(keyPath, []byte("old key"), 0600))
+			assert.NoError(t, os.WriteFile(nextKeyPath, []byte("next key"), 0600))
+
+			cryptService := new(MockCryptographer)
+			cryptService.On("ReloadEncryptionKey").Return(nil)
+			controller := NewKeysData(mockConfig, cryptService, service.NewVault(), log)
+
+			_, err := controller.KeyRotationStatus("test_token")
+			assert.NoError(t, err)
+			key, _ := os.ReadFile(keyPath)
+			assert.Equal(t, tt.wantKey, string(key))
+			if tt.wantNext {
+				assert.FileExists(t, nextKeyPath)
+			} else {
+				assert.NoFileExists(t, nextKeyPath)
+			}
+			if tt.reload {
+				cryptService.AssertCalled(t, "ReloadEncryptionKey")
+			} else {
+				cryptService.AssertNotCalled(t, "ReloadEncryptionKey")
+			}
+		})
+	}
+}
@@ -2,10 +2,13 @@ package controller
 
 import (
 	"os"
+	"path"
 
 	"github.com/northmule/gophkeeper/internal/client/config"
 	"github.com/northmule/gophkeeper/internal/client/logger"
 	"github.com/northmule/gophkeeper/internal/client/service"
+	"github.com/northmule/gophkeeper/internal/client/storage"
+	"github.com/northmule/gophkeeper/internal/common/keys"
 	"github.com/northmule/gophkeeper/internal/common/model_data"
 )
 
@@ -14,30 +17,55 @@ type Manager struct {
 	logger *logger.Logger
 
 	// Контроллеры
+	audit          *Audit
 	authentication *Authentication
 	cardData       *CardData
+	credentialData *CredentialData
+	devices        *Devices
+	events         *Events
 	textData       *TextData
 	fileData       *FileData
 	gridData       *GridData
 	itemData       *ItemData
 	keysData       *KeysData
+	masterKey      *MasterKey
+	offline        *Offline
+	outbox         *Outbox
+	recovery       *Recovery
 	registration   *Registration
 
 	cfg *config.Config
 }
 
 // NewManager конструктор
-func NewManager(cfg *config.Config, cryptService service.Cryptographer, logger *logger.Logger) (*Manager, error) {
+func NewManager(cfg *config.Config, cryptService service.Cryptographer, vault service.Vaulter, logger *logger.Logger) (*Manager, error) {
+	offlineVault := storage.NewOfflineVault(path.Join(cfg.Value().PathKeys, keys.OfflineVaultFileName))
+	cardData := NewCardData(cfg, cryptService, vault, offlineVault, logger)
+	textData := NewTextData(cfg, cryptService, vault, offlineVault, logger)
+	credentialData := NewCredentialData(cfg, cryptService, vault, offlineVault, logger)
+	fileData := NewFileData(cfg, cryptService, vault, offlineVault, logger)
+	keysData := NewKeysData(cfg, cryptService, vault, logger)
+	gridData := NewGridData(cfg, cryptService, offlineVault, logger)
+	itemData := NewItemData(cfg, cryptService, vault, offlineVault, logger)
+	migration := NewVaultMigration(gridData, itemData, cardData, textData, credentialData, fileData, logger)
 
 	return &Manager{
 		logger:         logger,
+		audit:          NewAudit(cfg, logger),
 		authentication: NewAuthentication(cfg, logger),
-		cardData:       NewCardData(cfg, cryptService, logger),
-		textData:       NewTextData(cfg, cryptService, logger),
-		fileData:       NewFileData(cfg, cryptService, logger),
-		gridData:       NewGridData(cfg, cryptService, logger),
-		itemData:       NewItemData(cfg, cryptService, logger),
-		keysData:       NewKeysData(cfg, cryptService, logger),
+		cardData:       cardData,
+		credentialData: credentialData,
+		devices:        NewDevices(cfg, vault, logger),
+		events:         NewEvents(cfg, cryptService, keysData, logger),
+		textData:       textData,
+		fileData:       fileData,
+		gridData:       gridData,
+		itemData:       itemData,
+		keysData:       keysData,
+		masterKey:      NewMasterKey(cfg, vault, offlineVault, migration, logger),
+		offline:        NewOffline(vault, offlineVault, logger),
+		outbox:         NewOutbox(offlineVault, cardData, textData, credentialData, fileData, logger),
+		recovery:       NewRecovery(cfg, cryptService, vault, logger),
 		registration:   NewRegistration(cfg, logger),
 	}, nil
 }
@@ -55,7 +83,8 @@ type GridDataController interface {
 // FileDataController контроллер
 type FileDataController interface {
 	Send(token string, requestData *model_data.FileDataInitRequest) (*FileDataResponse, error)
-	UploadFile(token string, url string, file *os.File) error
+	SendFile(token string, requestData *model_data.FileDataInitRequest, filePath string) error
+	UploadFile(token string, upload *FileDataResponse, file *os.File) error
 	DownLoadFile(token string, fileName string, dataUUID string) error
 }
 
@@ -72,6 +101,10 @@ type TextDataController interface {
 // AuthenticationDataController контроллер
 type AuthenticationDataController interface {
 	Send(login string, password string) (*AuthenticationResponse, error)
+	SendCode(mfaToken string, code string) (*AuthenticationResponse, error)
+	SendCertificate() (*AuthenticationResponse, error)
+	Refresh(refreshToken string) (*AuthenticationResponse, error)
+	Logout(token string) error
 }
 
 // KeyDataController контроллер
@@ -79,12 +112,76 @@ type KeyDataController interface {
 	UploadClientPublicKey(token string) error
 	DownloadPublicServerKey(token string) error
 	UploadClientPrivateKey(token string) error
+	RotateClientPrivateKey(token string) (*model_data.KeyRotationResponse, error)
+	KeyRotationStatus(token string) (*model_data.KeyRotationResponse, error)
 }
 
+// MasterKeyController контроллер
+type MasterKeyController interface {
+	Unlock(token string, masterPassword string) error
+	Migrate(token string) error
+	Lock()
+}
+
+// OfflineController контроллер
+type OfflineController interface {
+	Open() error
+	Unlock(masterPassword string) error
+	Lock()
+	List() (*GridDataResponse, error)
+	Item(dataUUID string) (*model_data.DataByUUIDResponse, error)
+}
+
+// OutboxController контроллер
+type OutboxController interface {
+	List() ([]storage.OutboxEntry, error)
+	Replay(token string) (int, error)
+	Discard(key string) error
+}
+
+// RecoveryController контроллер
+type RecoveryController interface {
+	CreateKit(token string) (*RecoveryKit, error)
+	EnsureKit(token string) (*RecoveryKit, error)
+	RestoreClientKey(token string) error
+	Restore(token string, secret string) error
+}
+
+// AuditController контроллер
+type AuditController interface {
+	List(token string, filter AuditFilter) (*model_data.AuditListResponse, error)
+	Verify(token string) (*model_data.AuditVerifyResponse, error)
+}
+
+// DevicesController контроллер
+type DevicesController interface {
+	List(token string) (*model_data.DeviceListResponse, error)
+	Rename(token string, deviceUUID string, name string) error
+	Revoke(token string, deviceUUID string) error
+	Unlock(token string) error
+}
+
+// EventsController контроллер
+type EventsController interface {
+	Start(token func() string, handle func(event model_data.EventResponse))
+	Stop()
+}
+
+// CardDataController контроллер
 type CardDataController interface {
 	Send(token string, requestData *model_data.CardDataRequest) (*CardDataResponse, error)
 }
 
+// CredentialDataController контроллер
+type CredentialDataController interface {
+	Send(token string, requestData *model_data.CredentialDataRequest) (*CredentialDataResponse, error)
+}
+
+// Audit контроллер
+func (manager *Manager) Audit() AuditController {
+	return manager.audit
+}
+
 // Authentication контроллер
 func (manager *Manager) Authentication() AuthenticationDataController {
 	return manager.authentication
@@ -95,6 +192,21 @@ func (manager *Manager) CardData() CardDataController {
 	return manager.cardData
 }
 
+// CredentialData контроллер
+func (manager *Manager) CredentialData() CredentialDataController {
+	return manager.credentialData
+}
+
+// Devices контроллер
+func (manager *Manager) Devices() DevicesController {
+	return manager.devices
+}
+
+// Events контроллер
+func (manager *Manager) Events() EventsController {
+	return manager.events
+}
+
 // TextData контроллер
 func (manager *Manager) TextData() TextDataController {
 	return manager.textData
@@ -120,6 +232,26 @@ func (manager *Manager) KeysData() KeyDataController {
 	return manager.keysData
 }
 
+// MasterKey контроллер
+func (manager *Manager) MasterKey() MasterKeyController {
+	return manager.masterKey
+}
+
+// Offline контроллер
+func (manager *Manager) Offline() OfflineController {
+	return manager.offline
+}
+
+// Outbox контроллер
+func (manager *Manager) Outbox() OutboxController {
+	return manager.outbox
+}
+
+// Recovery контроллер
+func (manager *Manager) Recovery() RecoveryController {
+	return manager.recovery
+}
+
 // Registration контроллер
 func (manager *Manager) Registration() RegistrationController {
 	return manager.registration
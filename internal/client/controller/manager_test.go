@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/northmule/gophkeeper/internal/client/logger"
+	"github.com/northmule/gophkeeper/internal/client/service"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -14,16 +15,20 @@ func TestNewManager_Success(t *testing.T) {
 		t.Errorf(err.Error())
 	}
 	cryptService := NewCryptMock(t)
-	manager, err := NewManager(mockConfig, cryptService, log)
+	manager, err := NewManager(mockConfig, cryptService, service.NewVault(), log)
 	assert.NoError(t, err)
 	assert.NotNil(t, manager)
+	assert.NotNil(t, manager.audit)
 	assert.NotNil(t, manager.authentication)
 	assert.NotNil(t, manager.cardData)
+	assert.NotNil(t, manager.credentialData)
+	assert.NotNil(t, manager.devices)
 	assert.NotNil(t, manager.textData)
 	assert.NotNil(t, manager.fileData)
 	assert.NotNil(t, manager.gridData)
 	assert.NotNil(t, manager.itemData)
 	assert.NotNil(t, manager.keysData)
+	assert.NotNil(t, manager.masterKey)
 	assert.NotNil(t, manager.registration)
 }
 
@@ -34,13 +39,27 @@ func TestManager_Authentication(t *testing.T) {
 		t.Errorf(err.Error())
 	}
 	cryptService := NewCryptMock(t)
-	manager, err := NewManager(mockConfig, cryptService, log)
+	manager, err := NewManager(mockConfig, cryptService, service.NewVault(), log)
 	assert.NoError(t, err)
 
 	auth := manager.Authentication()
 	assert.NotNil(t, auth)
 }
 
+func TestManager_Devices(t *testing.T) {
+	mockConfig := makeMockConfig("")
+	log, err := logger.NewLogger("info")
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	cryptService := NewCryptMock(t)
+	manager, err := NewManager(mockConfig, cryptService, service.NewVault(), log)
+	assert.NoError(t, err)
+
+	devices := manager.Devices()
+	assert.NotNil(t, devices)
+}
+
 func TestManager_CardData(t *testing.T) {
 	mockConfig := makeMockConfig("")
 	log, err := logger.NewLogger("info")
@@ -48,13 +67,27 @@ func TestManager_CardData(t *testing.T) {
 		t.Errorf(err.Error())
 	}
 	cryptService := NewCryptMock(t)
-	manager, err := NewManager(mockConfig, cryptService, log)
+	manager, err := NewManager(mockConfig, cryptService, service.NewVault(), log)
 	assert.NoError(t, err)
 
 	cardData := manager.CardData()
 	assert.NotNil(t, cardData)
 }
 
+func TestManager_CredentialData(t *testing.T) {
+	mockConfig := makeMockConfig("")
+	log, err := logger.NewLogger("info")
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	cryptService := NewCryptMock(t)
+	manager, err := NewManager(mockConfig, cryptService, service.NewVault(), log)
+	assert.NoError(t, err)
+
+	data := manager.CredentialData()
+	assert.NotNil(t, data)
+}
+
 func TestManager_TextData(t *testing.T) {
 	mockConfig := makeMockConfig("")
 	log, err := logger.NewLogger("info")
@@ -62,7 +95,7 @@ func TestManager_TextData(t *testing.T) {
 		t.Errorf(err.Error())
 	}
 	cryptService := NewCryptMock(t)
-	manager, err := NewManager(mockConfig, cryptService, log)
+	manager, err := NewManager(mockConfig, cryptService, service.NewVault(), log)
 	assert.NoError(t, err)
 
 	textData := manager.TextData()
@@ -76,7 +109,7 @@ func TestManager_FileData(t *testing.T) {
 		t.Errorf(err.Error())
 	}
 	cryptService := NewCryptMock(t)
-	manager, err := NewManager(mockConfig, cryptService, log)
+	manager, err := NewManager(mockConfig, cryptService, service.NewVault(), log)
 	assert.NoError(t, err)
 
 	data := manager.FileData()
@@ -89,7 +122,7 @@ func TestManager_ItemData(t *testing.T) {
 		t.Errorf(err.Error())
 	}
 	cryptService := NewCryptMock(t)
-	manager, err := NewManager(mockConfig, cryptService, log)
+	manager, err := NewManager(mockConfig, cryptService, service.NewVault(), log)
 	assert.NoError(t, err)
 
 	data := manager.ItemData()
@@ -103,7 +136,7 @@ func TestManager_KeysData(t *testing.T) {
 		t.Errorf(err.Error())
 	}
 	cryptService := NewCryptMock(t)
-	manager, err := NewManager(mockConfig, cryptService, log)
+	manager, err := NewManager(mockConfig, cryptService, service.NewVault(), log)
 	assert.NoError(t, err)
 
 	data := manager.KeysData()
@@ -116,9 +149,23 @@ func TestManager_Registration(t *testing.T) {
 		t.Errorf(err.Error())
 	}
 	cryptService := NewCryptMock(t)
-	manager, err := NewManager(mockConfig, cryptService, log)
+	manager, err := NewManager(mockConfig, cryptService, service.NewVault(), log)
 	assert.NoError(t, err)
 
 	data := manager.Registration()
 	assert.NotNil(t, data)
 }
+
+func TestManager_MasterKey(t *testing.T) {
+	mockConfig := makeMockConfig("")
+	log, err := logger.NewLogger("info")
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	cryptService := NewCryptMock(t)
+	manager, err := NewManager(mockConfig, cryptService, service.NewVault(), log)
+	assert.NoError(t, err)
+
+	data := manager.MasterKey()
+	assert.NotNil(t, data)
+}
@@ -0,0 +1,207 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/northmule/gophkeeper/internal/client/config"
+	"github.com/northmule/gophkeeper/internal/client/logger"
+	"github.com/northmule/gophkeeper/internal/client/service"
+	"github.com/northmule/gophkeeper/internal/client/storage"
+	"github.com/northmule/gophkeeper/internal/common/model_data"
+	"golang.org/x/net/context"
+)
+
+// MasterKey контроллер мастер-ключа (разблокировка хранилища мастер-паролем)
+type MasterKey struct {
+	logger    *logger.Logger
+	cfg       *config.Config
+	client    *http.Client
+	vault     service.Vaulter
+	offline   *storage.OfflineVault
+	migration *VaultMigration
+}
+
+// NewMasterKey конструктор
+func NewMasterKey(cfg *config.Config, vault service.Vaulter, offline *storage.OfflineVault, migration *VaultMigration, logger *logger.Logger) *MasterKey {
+	return &MasterKey{
+		logger:    logger,
+		cfg:       cfg,
+		client:    newHTTPClient(cfg),
+		vault:     vault,
+		offline:   offline,
+		migration: migration,
+	}
+}
+
+// Unlock получение мастер-ключа по мастер-паролю, при первом вводе параметры ключа сохраняются на сервере
+func (c *MasterKey) Unlock(token string, masterPassword string) error {
+	params, err := c.params(token)
+	if err != nil {
+		return err
+	}
+
+	if params.Salt != "" {
+		err = c.vault.Unlock(masterPassword, params.Salt, params.Check)
+		if err != nil {
+			return err
+		}
+		c.vault.SetLegacy(params.Legacy)
+		// параметры сохраняются в локальной копии для разблокировки без сервера
+		c.offline.SetParams(params.Salt, params.Check)
+		return nil
+	}
+
+	salt, check, err := c.vault.Setup(masterPassword)
+	if err != nil {
+		c.logger.Error(err)
+		return err
+	}
+	err = c.save(token, &model_data.MasterKeyRequest{Salt: salt, Check: check})
+	if err != nil {
+		c.vault.Lock()
+		return err
+	}
+	c.offline.SetParams(salt, check)
+
+	return nil
+}
+
+// Migrate перешифрование данных, сохранённых до шифрования мастер-ключом (после разблокировки хранилища).
+// До завершения открытые значения принимаются, после ошибки перешифрование повторится при следующей разблокировке
+func (c *MasterKey) Migrate(token string) error {
+	params, err := c.params(token)
+	if err != nil {
+		return err
+	}
+	if !params.Legacy {
+		return nil
+	}
+	c.vault.SetLegacy(true)
+
+	err = c.migration.Run(token)
+	if err != nil {
+		return err
+	}
+	err = c.migrated(token)
+	if err != nil {
+		return err
+	}
+	c.vault.SetLegacy(false)
+
+	return nil
+}
+
+// Lock сброс мастер-ключа и закрытие локальной копии (при выходе)
+func (c *MasterKey) Lock() {
+	c.offline.Close()
+	c.vault.Lock()
+}
+
+// params запрос параметров мастер-ключа
+func (c *MasterKey) params(token string) (*model_data.MasterKeyResponse, error) {
+	requestURL := fmt.Sprintf("%s/api/v1/master_key", c.cfg.Value().ServerAddress)
+	ctx := context.Background()
+
+	requestPrepare, err := http.NewRequestWithContext(ctx, http.MethodGet, requestURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	requestPrepare.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+	response, err := c.client.Do(requestPrepare)
+	if err != nil {
+		c.logger.Error(err)
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		if response.StatusCode == http.StatusUnauthorized {
+			return nil, fmt.Errorf("вы не авторизованы")
+		}
+		return nil, fmt.Errorf("не известная ошибка")
+	}
+
+	bodyRaw, err := io.ReadAll(response.Body)
+	if err != nil {
+		c.logger.Error(err)
+		return nil, err
+	}
+	responseData := new(model_data.MasterKeyResponse)
+	err = json.Unmarshal(bodyRaw, responseData)
+	if err != nil {
+		c.logger.Error(err)
+		return nil, err
+	}
+
+	return responseData, nil
+}
+
+// save сохранение параметров мастер-ключа на сервере
+func (c *MasterKey) save(token string, requestData *model_data.MasterKeyRequest) error {
+	requestURL := fmt.Sprintf("%s/api/v1/save_master_key", c.cfg.Value().ServerAddress)
+	ctx := context.Background()
+
+	requestBody, err := json.Marshal(requestData)
+	if err != nil {
+		return err
+	}
+
+	requestPrepare, err := http.NewRequestWithContext(ctx, http.MethodPost, requestURL, bytes.NewBuffer(requestBody))
+	if err != nil {
+		return err
+	}
+	requestPrepare.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+	requestPrepare.Header.Add("Content-Type", "application/json")
+	response, err := c.client.Do(requestPrepare)
+	if err != nil {
+		c.logger.Error(err)
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		if response.StatusCode == http.StatusUnauthorized {
+			return fmt.Errorf("вы не авторизованы")
+		}
+		if response.StatusCode == http.StatusBadRequest {
+			return fmt.Errorf("ошибка в запросе")
+		}
+		if response.StatusCode == http.StatusConflict {
+			return fmt.Errorf("мастер-пароль уже задан")
+		}
+		return fmt.Errorf("не известная ошибка")
+	}
+
+	return nil
+}
+
+// migrated снятие на сервере признака данных без шифрования мастер-ключом
+func (c *MasterKey) migrated(token string) error {
+	requestURL := fmt.Sprintf("%s/api/v1/master_key/migrated", c.cfg.Value().ServerAddress)
+	ctx := context.Background()
+
+	requestPrepare, err := http.NewRequestWithContext(ctx, http.MethodPost, requestURL, nil)
+	if err != nil {
+		return err
+	}
+	requestPrepare.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+	response, err := c.client.Do(requestPrepare)
+	if err != nil {
+		c.logger.Error(err)
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		if response.StatusCode == http.StatusUnauthorized {
+			return fmt.Errorf("вы не авторизованы")
+		}
+		return fmt.Errorf("не известная ошибка")
+	}
+
+	return nil
+}
@@ -0,0 +1,83 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/northmule/gophkeeper/internal/client/logger"
+	"github.com/northmule/gophkeeper/internal/client/service"
+	"github.com/northmule/gophkeeper/internal/common/model_data"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// masterKeyServer сервер, хранящий параметры мастер-ключа одного пользователя
+func masterKeyServer(t *testing.T, params *model_data.MasterKeyResponse) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer validtoken" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/api/v1/master_key":
+			_ = json.NewEncoder(w).Encode(params)
+		case "/api/v1/save_master_key":
+			if params.Salt != "" {
+				w.WriteHeader(http.StatusConflict)
+				return
+			}
+			request := new(model_data.MasterKeyRequest)
+			if err := json.NewDecoder(r.Body).Decode(request); err != nil {
+				t.Errorf("Failed to decode request body: %v", err)
+				return
+			}
+			params.Salt = request.Salt
+			params.Check = request.Check
+			w.WriteHeader(http.StatusOK)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestMasterKey_Unlock(t *testing.T) {
+	log, err := logger.NewLogger("info")
+	require.NoError(t, err)
+
+	params := new(model_data.MasterKeyResponse)
+	server := masterKeyServer(t, params)
+	defer server.Close()
+	mockConfig := makeMockConfig(server.URL)
+
+	t.Run("first_setup", func(t *testing.T) {
+		vault := service.NewVault()
+		err := NewMasterKey(mockConfig, vault, newTestOfflineVault(t), nil, log).Unlock("validtoken", "master password")
+		require.NoError(t, err)
+		assert.True(t, vault.IsUnlocked())
+		assert.NotEmpty(t, params.Salt)
+		assert.NotEmpty(t, params.Check)
+	})
+
+	t.Run("unlock", func(t *testing.T) {
+		vault := service.NewVault()
+		err := NewMasterKey(mockConfig, vault, newTestOfflineVault(t), nil, log).Unlock("validtoken", "master password")
+		require.NoError(t, err)
+		assert.True(t, vault.IsUnlocked())
+	})
+
+	t.Run("wrong_password", func(t *testing.T) {
+		vault := service.NewVault()
+		err := NewMasterKey(mockConfig, vault, newTestOfflineVault(t), nil, log).Unlock("validtoken", "other password")
+		assert.ErrorIs(t, err, service.ErrWrongMasterPassword)
+		assert.False(t, vault.IsUnlocked())
+	})
+
+	t.Run("no_validtoken", func(t *testing.T) {
+		vault := service.NewVault()
+		err := NewMasterKey(mockConfig, vault, newTestOfflineVault(t), nil, log).Unlock("no_validtoken", "master password")
+		assert.EqualError(t, err, "вы не авторизованы")
+		assert.False(t, vault.IsUnlocked())
+	})
+}
@@ -0,0 +1,128 @@
+package controller
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/northmule/gophkeeper/internal/client/logger"
+	"github.com/northmule/gophkeeper/internal/client/service"
+	"github.com/northmule/gophkeeper/internal/common/data_type"
+	"github.com/northmule/gophkeeper/internal/common/keys"
+	"github.com/northmule/gophkeeper/internal/common/model_data"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testAuthority CA сервера для выпуска сертификатов клиентов
+type testAuthority struct {
+	certificate *x509.Certificate
+	key         *ecdsa.PrivateKey
+}
+
+func newTestAuthority(t *testing.T) *testAuthority {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Minute),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, key.Public(), key)
+	require.NoError(t, err)
+	certificate, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return &testAuthority{certificate: certificate, key: key}
+}
+
+func (a *testAuthority) issue(t *testing.T, publicKeyPEM []byte) []byte {
+	block, _ := pem.Decode(publicKeyPEM)
+	require.NotNil(t, block)
+	publicKey, err := x509.ParsePKIXPublicKey(block.Bytes)
+	require.NoError(t, err)
+	template := x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "user-uuid"},
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &template, a.certificate, publicKey, a.key)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func TestMutualTLS(t *testing.T) {
+	authority := newTestAuthority(t)
+
+	// ключи клиента, как их создаёт keys.InitSelfSigned
+	pathKeys := t.TempDir()
+	clientKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	privateKeyBytes, err := x509.MarshalPKCS8PrivateKey(clientKey)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path.Join(pathKeys, keys.PrivateKeyFileName), pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateKeyBytes}), 0600))
+	publicKeyBytes, err := x509.MarshalPKIXPublicKey(clientKey.Public())
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path.Join(pathKeys, keys.PublicKeyFileName), pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicKeyBytes}), 0644))
+
+	pool := x509.NewCertPool()
+	pool.AddCert(authority.certificate)
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/save_public_key":
+			_ = r.ParseMultipartForm(4096)
+			file, _, err := r.FormFile(data_type.FileField)
+			require.NoError(t, err)
+			publicKeyPEM := make([]byte, 4096)
+			n, _ := file.Read(publicKeyPEM)
+			certificatePEM := authority.issue(t, publicKeyPEM[:n])
+			fingerprint, err := keys.CertificateFingerprint(certificatePEM)
+			require.NoError(t, err)
+			_ = json.NewEncoder(w).Encode(model_data.ClientCertificateResponse{Certificate: string(certificatePEM), Fingerprint: fingerprint})
+		case "/api/v1/login/certificate":
+			if len(r.TLS.VerifiedChains) == 0 || r.TLS.PeerCertificates[0].Subject.CommonName != "user-uuid" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			_ = json.NewEncoder(w).Encode(model_data.TokenResponse{AccessToken: "access-token", RefreshToken: "refresh-token", ExpiresIn: 900})
+		}
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.VerifyClientCertIfGiven, ClientCAs: pool}
+	server.StartTLS()
+	defer server.Close()
+
+	log, err := logger.NewLogger("info")
+	require.NoError(t, err)
+	cfg := makeMockConfig(server.URL)
+	cfg.Value().PathKeys = pathKeys
+	cfg.Value().ServerCertFingerprint = keys.Fingerprint(server.Certificate().Raw)
+
+	_, err = NewAuthentication(cfg, log).SendCertificate()
+	assert.Error(t, err, "without a client certificate")
+
+	require.NoError(t, NewKeysData(cfg, NewCryptMock(t), service.NewVault(), log).UploadClientPublicKey("token"))
+	assert.FileExists(t, path.Join(pathKeys, keys.ClientCertificateFileName))
+
+	response, err := NewAuthentication(cfg, log).SendCertificate()
+	require.NoError(t, err)
+	assert.Equal(t, "access-token", response.Value)
+	assert.Equal(t, "refresh-token", response.RefreshToken)
+}
@@ -0,0 +1,93 @@
+package controller
+
+import (
+	"errors"
+	"net/url"
+	"time"
+
+	"github.com/northmule/gophkeeper/internal/client/logger"
+	"github.com/northmule/gophkeeper/internal/client/service"
+	"github.com/northmule/gophkeeper/internal/client/storage"
+	"github.com/northmule/gophkeeper/internal/common/model_data"
+)
+
+// Offline контроллер локальной копии хранилища. Копия открывается после разблокировки хранилища и пополняется
+// списком и данными, полученными с сервера. Без сервера хранилище разблокируется мастер-паролем по параметрам
+// из копии и доступно только для просмотра.
+type Offline struct {
+	logger *logger.Logger
+	vault  service.Vaulter
+	store  *storage.OfflineVault
+}
+
+// NewOffline конструктор
+func NewOffline(vault service.Vaulter, store *storage.OfflineVault, logger *logger.Logger) *Offline {
+	return &Offline{
+		logger: logger,
+		vault:  vault,
+		store:  store,
+	}
+}
+
+// Open открывает копию ключом разблокированного хранилища
+func (c *Offline) Open() error {
+	key, err := c.vault.Key()
+	if err != nil {
+		return err
+	}
+	return c.store.Open(key)
+}
+
+// Unlock разблокировка хранилища мастер-паролем без сервера
+func (c *Offline) Unlock(masterPassword string) error {
+	salt, check, err := c.store.Params()
+	if err != nil {
+		return err
+	}
+	err = c.vault.Unlock(masterPassword, salt, check)
+	if err != nil {
+		return err
+	}
+	err = c.Open()
+	if err != nil {
+		c.vault.Lock()
+		c.logger.Error(err)
+		return err
+	}
+	return nil
+}
+
+// Lock сброс мастер-ключа и закрытие копии
+func (c *Offline) Lock() {
+	c.store.Close()
+	c.vault.Lock()
+}
+
+// List список данных из копии
+func (c *Offline) List() (*GridDataResponse, error) {
+	items, syncedAt, err := c.store.List()
+	if err != nil {
+		return nil, err
+	}
+	return offlineGridData(items, syncedAt), nil
+}
+
+// Item данные из копии
+func (c *Offline) Item(dataUUID string) (*model_data.DataByUUIDResponse, error) {
+	return c.store.Item(dataUUID)
+}
+
+// offlineGridData ответ со списком из копии
+func offlineGridData(items []model_data.ItemDataResponse, syncedAt time.Time) *GridDataResponse {
+	responseData := new(GridDataResponse)
+	responseData.Items = items
+	responseData.Offline = true
+	responseData.SyncedAt = syncedAt
+	return responseData
+}
+
+// isUnreachable сервер не ответил: ошибка соединения, а не ответ с кодом ошибки
+func isUnreachable(err error) bool {
+	var urlErr *url.Error
+	return errors.As(err, &urlErr)
+}
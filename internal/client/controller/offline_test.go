@@ -0,0 +1,106 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"testing"
+
+	"github.com/northmule/gophkeeper/internal/client/logger"
+	"github.com/northmule/gophkeeper/internal/client/service"
+	"github.com/northmule/gophkeeper/internal/client/storage"
+	"github.com/northmule/gophkeeper/internal/common/keys"
+	"github.com/northmule/gophkeeper/internal/common/model_data"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestOfflineVault(t *testing.T) *storage.OfflineVault {
+	return storage.NewOfflineVault(path.Join(t.TempDir(), keys.OfflineVaultFileName))
+}
+
+func TestOffline_BrowseWithoutServer(t *testing.T) {
+	log, err := logger.NewLogger("info")
+	require.NoError(t, err)
+	cryptService := NewCryptMock(t)
+	store := newTestOfflineVault(t)
+	vault := service.NewVault()
+
+	params := new(model_data.MasterKeyResponse)
+	keyServer := masterKeyServer(t, params)
+	defer keyServer.Close()
+	masterKey := NewMasterKey(makeMockConfig(keyServer.URL), vault, store, nil, log)
+	require.NoError(t, masterKey.Unlock("validtoken", "master password"))
+
+	sealed, err := sealTextData(vault, &model_data.TextDataRequest{Name: "note", Value: "secret text"})
+	require.NoError(t, err)
+	dataServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body []byte
+		switch r.URL.Path {
+		case "/api/v1/sync":
+			body, _ = json.Marshal(model_data.SyncResponse{Changed: []model_data.ItemDataResponse{
+				{Name: "note", UUID: "text-uuid", Revision: 1},
+				{Name: "card", UUID: "card-uuid", Revision: 2},
+			}, Cursor: "2"})
+		default:
+			body, _ = json.Marshal(model_data.DataByUUIDResponse{IsText: true, TextData: *sealed})
+		}
+		encrypted, _ := cryptService.EncryptAES(body)
+		_, _ = w.Write(encrypted)
+	}))
+	dataConfig := makeMockConfig(dataServer.URL)
+	gridData := NewGridData(dataConfig, cryptService, store, log)
+	itemData := NewItemData(dataConfig, cryptService, vault, store, log)
+
+	offline := NewOffline(vault, store, log)
+	require.NoError(t, offline.Open())
+	grid, err := gridData.Send("validtoken")
+	require.NoError(t, err)
+	assert.False(t, grid.Offline)
+	_, err = itemData.Send("validtoken", "text-uuid")
+	require.NoError(t, err)
+
+	// сервер недоступен: список и открытые ранее данные из копии
+	dataServer.Close()
+	grid, err = gridData.Send("validtoken")
+	require.NoError(t, err)
+	assert.True(t, grid.Offline)
+	assert.Len(t, grid.Items, 2)
+	item, err := itemData.Send("validtoken", "text-uuid")
+	require.NoError(t, err)
+	assert.Equal(t, "secret text", item.TextData.Value)
+	_, err = itemData.Send("validtoken", "card-uuid")
+	assert.Error(t, err)
+
+	// после выхода хранилище открывается мастер-паролем без сервера
+	masterKey.Lock()
+	assert.False(t, vault.IsUnlocked())
+	_, err = offline.List()
+	assert.ErrorIs(t, err, storage.ErrOfflineVaultClosed)
+
+	assert.ErrorIs(t, offline.Unlock("other password"), service.ErrWrongMasterPassword)
+	require.NoError(t, offline.Unlock("master password"))
+	grid, err = offline.List()
+	require.NoError(t, err)
+	assert.True(t, grid.Offline)
+	assert.Len(t, grid.Items, 2)
+	item, err = offline.Item("text-uuid")
+	require.NoError(t, err)
+	assert.Equal(t, "secret text", item.TextData.Value)
+	_, err = offline.Item("card-uuid")
+	assert.ErrorIs(t, err, storage.ErrOfflineItemNotFound)
+
+	offline.Lock()
+	assert.False(t, vault.IsUnlocked())
+}
+
+func TestOffline_UnlockWithoutCopy(t *testing.T) {
+	log, err := logger.NewLogger("info")
+	require.NoError(t, err)
+	vault := service.NewVault()
+
+	err = NewOffline(vault, newTestOfflineVault(t), log).Unlock("master password")
+	assert.ErrorIs(t, err, storage.ErrOfflineVaultNotFound)
+	assert.False(t, vault.IsUnlocked())
+}
@@ -0,0 +1,134 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/northmule/gophkeeper/internal/client/logger"
+	"github.com/northmule/gophkeeper/internal/client/storage"
+	"github.com/northmule/gophkeeper/internal/common/data_type"
+	"github.com/northmule/gophkeeper/internal/common/model_data"
+)
+
+// ErrQueued сервер недоступен, операция сохранена в очередь изменений
+var ErrQueued = errors.New("нет связи с сервером, изменения сохранены и будут отправлены при подключении")
+
+// Outbox контроллер очереди изменений. Сохранения, не отправленные из-за недоступности сервера, хранятся
+// в локальной копии хранилища и повторяются по порядку с тем же ключом идемпотентности,
+// поэтому повтор принятого сервером запроса не создаёт данные повторно.
+type Outbox struct {
+	logger         *logger.Logger
+	store          *storage.OfflineVault
+	cardData       *CardData
+	textData       *TextData
+	credentialData *CredentialData
+	fileData       *FileData
+}
+
+// NewOutbox конструктор
+func NewOutbox(store *storage.OfflineVault, cardData *CardData, textData *TextData, credentialData *CredentialData, fileData *FileData, logger *logger.Logger) *Outbox {
+	return &Outbox{
+		logger:         logger,
+		store:          store,
+		cardData:       cardData,
+		textData:       textData,
+		credentialData: credentialData,
+		fileData:       fileData,
+	}
+}
+
+// List операции в очереди
+func (c *Outbox) List() ([]storage.OutboxEntry, error) {
+	return c.store.Outbox()
+}
+
+// Replay отправка операций по порядку. Отправленные удаляются из очереди, у отклонённых сервером
+// запоминается ошибка, они повторятся при следующей отправке. Если сервер снова недоступен, отправка прерывается.
+// Вернёт число отправленных операций
+func (c *Outbox) Replay(token string) (int, error) {
+	entries, err := c.store.Outbox()
+	if errors.Is(err, storage.ErrOfflineVaultClosed) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	sent := 0
+	for _, entry := range entries {
+		err = c.send(token, entry)
+		if isUnreachable(err) {
+			return sent, err
+		}
+		if err != nil {
+			c.logger.Infof("Queued operation %s failed: %s", entry.Key, err)
+			err = c.store.SetOutboxError(entry.Key, err.Error())
+			if err != nil {
+				return sent, err
+			}
+			continue
+		}
+		err = c.store.Dequeue(entry.Key)
+		if err != nil {
+			return sent, err
+		}
+		sent++
+	}
+	return sent, nil
+}
+
+// Discard удаление операции из очереди без отправки
+func (c *Outbox) Discard(key string) error {
+	return c.store.Dequeue(key)
+}
+
+// send отправка операции её контроллером
+func (c *Outbox) send(token string, entry storage.OutboxEntry) error {
+	var err error
+	switch entry.Type {
+	case data_type.CardType:
+		requestData := new(model_data.CardDataRequest)
+		if err = json.Unmarshal(entry.Request, requestData); err != nil {
+			return err
+		}
+		_, err = c.cardData.send(token, requestData, entry.Key)
+	case data_type.TextType:
+		requestData := new(model_data.TextDataRequest)
+		if err = json.Unmarshal(entry.Request, requestData); err != nil {
+			return err
+		}
+		_, err = c.textData.send(token, requestData, entry.Key)
+	case data_type.CredentialType:
+		requestData := new(model_data.CredentialDataRequest)
+		if err = json.Unmarshal(entry.Request, requestData); err != nil {
+			return err
+		}
+		_, err = c.credentialData.send(token, requestData, entry.Key)
+	case data_type.BinaryType:
+		requestData := new(model_data.FileDataInitRequest)
+		if err = json.Unmarshal(entry.Request, requestData); err != nil {
+			return err
+		}
+		err = c.fileData.sendFile(token, requestData, entry.FilePath, entry.Key)
+	default:
+		err = fmt.Errorf("неизвестный тип операции %s", entry.Type)
+	}
+	return err
+}
+
+// enqueue ставит не отправленную операцию в очередь изменений и вернёт ErrQueued.
+// Если очередь недоступна (копия хранилища не открыта), вернёт исходную ошибку отправки
+func enqueue(store *storage.OfflineVault, entry storage.OutboxEntry, requestData any, sendErr error) error {
+	request, err := json.Marshal(requestData)
+	if err != nil {
+		return errors.Join(sendErr, err)
+	}
+	entry.Request = request
+	entry.CreatedAt = time.Now().Unix()
+	err = store.Enqueue(entry)
+	if err != nil {
+		return sendErr
+	}
+	return ErrQueued
+}
@@ -0,0 +1,121 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"sync"
+	"testing"
+
+	"github.com/northmule/gophkeeper/internal/client/logger"
+	"github.com/northmule/gophkeeper/internal/common/data_type"
+	"github.com/northmule/gophkeeper/internal/common/model_data"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOutbox_QueueAndReplay(t *testing.T) {
+	log, err := logger.NewLogger("info")
+	require.NoError(t, err)
+	cryptService := NewCryptMock(t)
+	vault := newVaultMock(t)
+	store := newTestOfflineVault(t)
+	require.NoError(t, store.Open(bytes.Repeat([]byte{1}, 32)))
+
+	// сервер недоступен
+	down := httptest.NewServer(http.NotFoundHandler())
+	mockConfig := makeMockConfig(down.URL)
+	down.Close()
+
+	textData := NewTextData(mockConfig, cryptService, vault, store, log)
+	credentialData := NewCredentialData(mockConfig, cryptService, vault, store, log)
+	fileData := NewFileData(mockConfig, cryptService, vault, store, log)
+	outbox := NewOutbox(store, NewCardData(mockConfig, cryptService, vault, store, log), textData, credentialData, fileData, log)
+
+	_, err = textData.Send("validtoken", &model_data.TextDataRequest{Name: "note", Value: "secret text"})
+	assert.ErrorIs(t, err, ErrQueued)
+	filePath := path.Join(t.TempDir(), "file.txt")
+	require.NoError(t, os.WriteFile(filePath, []byte("file content"), 0600))
+	err = fileData.SendFile("validtoken", &model_data.FileDataInitRequest{Name: "file", FileName: "file.txt"}, filePath)
+	assert.ErrorIs(t, err, ErrQueued)
+	_, err = credentialData.Send("validtoken", &model_data.CredentialDataRequest{Name: "mail"})
+	assert.ErrorIs(t, err, ErrQueued)
+
+	entries, err := outbox.List()
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	assert.Equal(t, data_type.TextType, entries[0].Type)
+	assert.Equal(t, data_type.BinaryType, entries[1].Type)
+	assert.Equal(t, filePath, entries[1].FilePath)
+
+	// сервер всё ещё недоступен: очередь не меняется
+	sent, err := outbox.Replay("validtoken")
+	assert.True(t, isUnreachable(err))
+	assert.Equal(t, 0, sent)
+
+	var (
+		mx   sync.Mutex
+		keys = make(map[string]string)
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mx.Lock()
+		keys[r.URL.Path] = r.Header.Get(data_type.IdempotencyKeyHeader)
+		mx.Unlock()
+		switch r.URL.Path {
+		case "/api/v1/save_text_data":
+			w.WriteHeader(http.StatusOK)
+		case "/api/v1/file_data/init":
+			body, _ := json.Marshal(FileDataResponse{UploadPath: "/file_data/load/file-uuid", StatusPath: "/file_data/status/file-uuid"})
+			_, _ = w.Write(body)
+		case "/api/v1/file_data/status/file-uuid":
+			body, _ := json.Marshal(model_data.FileDataStatusResponse{Chunks: 1})
+			_, _ = w.Write(body)
+		case "/api/v1/file_data/load/file-uuid/0":
+			body, _ := json.Marshal(model_data.FileDataStatusResponse{Chunks: 1, Received: []model_data.FileChunkReceipt{{Part: 0}}, Uploaded: true})
+			_, _ = w.Write(body)
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+	mockConfig.Value().ServerAddress = server.URL
+
+	sent, err = outbox.Replay("validtoken")
+	require.NoError(t, err)
+	assert.Equal(t, 2, sent)
+	// повтор идёт с ключом, выданным при постановке в очередь
+	assert.Equal(t, entries[0].Key, keys["/api/v1/save_text_data"])
+	assert.Equal(t, entries[1].Key, keys["/api/v1/file_data/init"])
+
+	// отклонённая сервером операция остаётся в очереди с ошибкой
+	remaining, err := outbox.List()
+	require.NoError(t, err)
+	require.Len(t, remaining, 1)
+	assert.Equal(t, entries[2].Key, remaining[0].Key)
+	assert.Equal(t, "ошибка в запросе", remaining[0].Error)
+
+	require.NoError(t, outbox.Discard(remaining[0].Key))
+	remaining, err = outbox.List()
+	require.NoError(t, err)
+	assert.Empty(t, remaining)
+}
+
+func TestOutbox_ClosedStore(t *testing.T) {
+	log, err := logger.NewLogger("info")
+	require.NoError(t, err)
+	down := httptest.NewServer(http.NotFoundHandler())
+	mockConfig := makeMockConfig(down.URL)
+	down.Close()
+
+	// без открытой копии хранилища очереди нет, возвращается ошибка соединения
+	_, err = NewCardData(mockConfig, NewCryptMock(t), newVaultMock(t), newTestOfflineVault(t), log).Send("validtoken", &model_data.CardDataRequest{Name: "card"})
+	assert.True(t, isUnreachable(err))
+	assert.NotErrorIs(t, err, ErrQueued)
+
+	sent, err := NewOutbox(newTestOfflineVault(t), nil, nil, nil, nil, log).Replay("validtoken")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, sent)
+}
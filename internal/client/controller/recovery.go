@@ -0,0 +1,292 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/northmule/gophkeeper/internal/client/config"
+	"github.com/northmule/gophkeeper/internal/client/logger"
+	"github.com/northmule/gophkeeper/internal/client/service"
+	"github.com/northmule/gophkeeper/internal/common/keys"
+	"github.com/northmule/gophkeeper/internal/common/model_data"
+	"github.com/northmule/gophkeeper/internal/common/util"
+	"golang.org/x/net/context"
+)
+
+// Комплект восстановления: ключ хранилища (мастер-ключ) шифруется ключом восстановления, который печатается
+// пользователю, и по желанию делится на части по схеме Шамира. Ключ шифрования клиента шифруется ключом хранилища.
+// Оба значения хранятся на сервере, поэтому после потери PathKeys ключ клиента восстанавливается мастер-паролем,
+// ключом восстановления или достаточным числом частей.
+
+// recoveryAdditionalData дополнительные данные AEAD ключа хранилища в комплекте
+var recoveryAdditionalData = []byte("gophkeeper recovery vault key")
+
+var (
+	// ErrRecoveryKitNotFound комплект восстановления не создавался
+	ErrRecoveryKitNotFound = errors.New("комплект восстановления не найден")
+	// ErrRecoverySecret ключ восстановления или части ключа не подходят к комплекту
+	ErrRecoverySecret = errors.New("ключ восстановления не подходит")
+)
+
+// RecoveryKit печатный комплект восстановления, показывается пользователю один раз
+type RecoveryKit struct {
+	Key       string
+	Shares    []string
+	Threshold int
+}
+
+// Recovery контроллер комплекта восстановления
+type Recovery struct {
+	logger *logger.Logger
+	cfg    *config.Config
+	client *http.Client
+	crypt  service.Cryptographer
+	vault  service.Vaulter
+}
+
+// NewRecovery конструктор
+func NewRecovery(cfg *config.Config, crypt service.Cryptographer, vault service.Vaulter, logger *logger.Logger) *Recovery {
+	return &Recovery{
+		logger: logger,
+		cfg:    cfg,
+		client: newHTTPClient(cfg),
+		crypt:  crypt,
+		vault:  vault,
+	}
+}
+
+// CreateKit новый комплект восстановления, прежний перестаёт действовать. Хранилище должно быть разблокировано
+func (c *Recovery) CreateKit(token string) (*RecoveryKit, error) {
+	vaultKey, err := c.vault.Key()
+	if err != nil {
+		return nil, err
+	}
+	clientKey, err := c.sealClientKey()
+	if err != nil {
+		return nil, err
+	}
+	recoveryKey, printable, err := util.NewRecoveryKey()
+	if err != nil {
+		return nil, err
+	}
+	sealedVaultKey, err := util.SealEnvelope(vaultKey, recoveryKey, util.CipherAES256GCM, "recovery", recoveryAdditionalData)
+	if err != nil {
+		return nil, err
+	}
+
+	kit := &RecoveryKit{Key: printable}
+	if c.cfg.Value().RecoveryShares > 0 {
+		kit.Threshold = c.cfg.Value().RecoveryThreshold
+		kit.Shares, err = util.NewRecoveryShares(vaultKey, c.cfg.Value().RecoveryShares, kit.Threshold)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = c.save(token, &model_data.RecoveryKitRequest{
+		VaultKey:  base64.StdEncoding.EncodeToString(sealedVaultKey),
+		ClientKey: clientKey,
+	})
+	if err != nil {
+		return nil, err
+	}
+	c.logger.Info("The recovery kit has been created")
+	return kit, nil
+}
+
+// EnsureKit создаёт комплект, если его нет (вернёт его для показа пользователю), и обновляет в нём
+// ключ шифрования клиента после смены ключа. Хранилище должно быть разблокировано
+func (c *Recovery) EnsureKit(token string) (*RecoveryKit, error) {
+	stored, err := c.kit(token)
+	if errors.Is(err, ErrRecoveryKitNotFound) {
+		return c.CreateKit(token)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := os.ReadFile(path.Join(c.cfg.Value().PathKeys, keys.PrivateKeyFileNameForEncryption))
+	if err != nil {
+		return nil, err
+	}
+	clientKey, err := c.openClientKey(stored)
+	if err == nil && bytes.Equal(clientKey, current) {
+		return nil, nil
+	}
+	sealed, err := c.sealClientKey()
+	if err != nil {
+		return nil, err
+	}
+	c.logger.Info("The client key in the recovery kit has been updated")
+	return nil, c.save(token, &model_data.RecoveryKitRequest{ClientKey: sealed})
+}
+
+// RestoreClientKey восстановление ключа шифрования клиента из комплекта. Хранилище должно быть разблокировано
+// (мастер-паролем или через Restore)
+func (c *Recovery) RestoreClientKey(token string) error {
+	stored, err := c.kit(token)
+	if err != nil {
+		return err
+	}
+	return c.restoreClientKey(stored)
+}
+
+// Restore восстановление доступа ключом восстановления или частями ключа хранилища (по одной в строке):
+// хранилище разблокируется без мастер-пароля, ключ шифрования клиента восстанавливается
+func (c *Recovery) Restore(token string, secret string) error {
+	stored, err := c.kit(token)
+	if err != nil {
+		return err
+	}
+
+	var vaultKey []byte
+	if util.IsRecoveryKey(secret) {
+		recoveryKey, err := util.ParseRecoveryKey(secret)
+		if err != nil {
+			return err
+		}
+		sealedVaultKey, err := base64.StdEncoding.DecodeString(stored.VaultKey)
+		if err != nil {
+			return err
+		}
+		vaultKey, err = util.OpenEnvelope(sealedVaultKey, recoveryKey, recoveryAdditionalData)
+		if err != nil {
+			return ErrRecoverySecret
+		}
+	} else {
+		shares := make([]string, 0)
+		for _, line := range strings.Split(secret, "\n") {
+			if strings.TrimSpace(line) != "" {
+				shares = append(shares, line)
+			}
+		}
+		vaultKey, err = util.CombineRecoveryShares(shares)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = c.vault.UnlockWithKey(vaultKey)
+	if err != nil {
+		return err
+	}
+	err = c.restoreClientKey(stored)
+	if err != nil {
+		// ключ не подошёл (например, части от разных комплектов)
+		c.vault.Lock()
+		return err
+	}
+	return nil
+}
+
+// restoreClientKey запись ключа шифрования клиента из комплекта
+func (c *Recovery) restoreClientKey(stored *model_data.RecoveryKitResponse) error {
+	clientKey, err := c.openClientKey(stored)
+	if err != nil {
+		return ErrRecoverySecret
+	}
+	err = os.WriteFile(path.Join(c.cfg.Value().PathKeys, keys.PrivateKeyFileNameForEncryption), clientKey, 0600)
+	if err != nil {
+		return err
+	}
+	err = c.crypt.ReloadEncryptionKey()
+	if err != nil {
+		return err
+	}
+	c.logger.Info("The client key has been restored from the recovery kit")
+	return nil
+}
+
+// sealClientKey ключ шифрования клиента, зашифрованный ключом хранилища
+func (c *Recovery) sealClientKey() (string, error) {
+	clientKey, err := os.ReadFile(path.Join(c.cfg.Value().PathKeys, keys.PrivateKeyFileNameForEncryption))
+	if err != nil {
+		return "", err
+	}
+	sealed, err := c.vault.Encrypt(clientKey)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// openClientKey расшифровка ключа шифрования клиента из комплекта
+func (c *Recovery) openClientKey(stored *model_data.RecoveryKitResponse) ([]byte, error) {
+	sealed, err := base64.StdEncoding.DecodeString(stored.ClientKey)
+	if err != nil {
+		return nil, err
+	}
+	return c.vault.Decrypt(sealed)
+}
+
+// kit запрос комплекта восстановления
+func (c *Recovery) kit(token string) (*model_data.RecoveryKitResponse, error) {
+	requestURL := fmt.Sprintf("%s/api/v1/recovery_kit", c.cfg.Value().ServerAddress)
+	requestPrepare, err := http.NewRequestWithContext(context.Background(), http.MethodGet, requestURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	requestPrepare.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+	response, err := c.client.Do(requestPrepare)
+	if err != nil {
+		c.logger.Error(err)
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		if response.StatusCode == http.StatusNotFound {
+			return nil, ErrRecoveryKitNotFound
+		}
+		if response.StatusCode == http.StatusUnauthorized {
+			return nil, fmt.Errorf("вы не авторизованы")
+		}
+		return nil, fmt.Errorf("не известная ошибка")
+	}
+
+	responseData := new(model_data.RecoveryKitResponse)
+	err = json.NewDecoder(response.Body).Decode(responseData)
+	if err != nil {
+		c.logger.Error(err)
+		return nil, err
+	}
+	return responseData, nil
+}
+
+// save сохранение комплекта восстановления на сервере
+func (c *Recovery) save(token string, requestData *model_data.RecoveryKitRequest) error {
+	requestURL := fmt.Sprintf("%s/api/v1/save_recovery_kit", c.cfg.Value().ServerAddress)
+	requestBody, err := json.Marshal(requestData)
+	if err != nil {
+		return err
+	}
+	requestPrepare, err := http.NewRequestWithContext(context.Background(), http.MethodPost, requestURL, bytes.NewBuffer(requestBody))
+	if err != nil {
+		return err
+	}
+	requestPrepare.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+	requestPrepare.Header.Add("Content-Type", "application/json")
+	response, err := c.client.Do(requestPrepare)
+	if err != nil {
+		c.logger.Error(err)
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		if response.StatusCode == http.StatusUnauthorized {
+			return fmt.Errorf("вы не авторизованы")
+		}
+		bodyRaw, _ := io.ReadAll(response.Body)
+		return fmt.Errorf("сервер не принял комплект восстановления: %s", bodyRaw)
+	}
+	return nil
+}
@@ -0,0 +1,208 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/northmule/gophkeeper/internal/client/config"
+	"github.com/northmule/gophkeeper/internal/client/logger"
+	"github.com/northmule/gophkeeper/internal/client/service"
+	"github.com/northmule/gophkeeper/internal/common/keys"
+	"github.com/northmule/gophkeeper/internal/common/model_data"
+	"github.com/northmule/gophkeeper/internal/common/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recoveryKitServer сервер, хранящий комплект восстановления в памяти
+type recoveryKitServer struct {
+	mx  sync.Mutex
+	kit *model_data.RecoveryKitResponse
+}
+
+func (s *recoveryKitServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	switch r.URL.Path {
+	case "/api/v1/recovery_kit":
+		if s.kit == nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(s.kit)
+	case "/api/v1/save_recovery_kit":
+		request := new(model_data.RecoveryKitRequest)
+		_ = json.NewDecoder(r.Body).Decode(request)
+		if s.kit == nil {
+			s.kit = new(model_data.RecoveryKitResponse)
+		}
+		if request.VaultKey != "" {
+			s.kit.VaultKey = request.VaultKey
+		}
+		s.kit.ClientKey = request.ClientKey
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func newRecoveryTestClient(t *testing.T, serverURL string, vault service.Vaulter) (*Recovery, *config.Config, *MockCryptographer) {
+	log, _ := logger.NewLogger("info")
+	cfg := makeMockConfig(serverURL)
+	cfg.Value().PathKeys = t.TempDir()
+	cfg.Value().RecoveryShares = 5
+	cfg.Value().RecoveryThreshold = 3
+	crypt := new(MockCryptographer)
+	crypt.On("ReloadEncryptionKey").Return(nil)
+	return NewRecovery(cfg, crypt, vault, log), cfg, crypt
+}
+
+func readClientKey(t *testing.T, cfg *config.Config) string {
+	key, err := os.ReadFile(filepath.Join(cfg.Value().PathKeys, keys.PrivateKeyFileNameForEncryption))
+	require.NoError(t, err)
+	return string(key)
+}
+
+func writeClientKey(t *testing.T, cfg *config.Config, key string) {
+	require.NoError(t, os.WriteFile(filepath.Join(cfg.Value().PathKeys, keys.PrivateKeyFileNameForEncryption), []byte(key), 0600))
+}
+
+func TestRecovery_CreateKitAndRestore(t *testing.T) {
+	server := new(recoveryKitServer)
+	testServer := httptest.NewServer(server)
+	defer testServer.Close()
+
+	vault := service.NewVault()
+	_, _, err := vault.Setup("master password")
+	require.NoError(t, err)
+	recovery, cfg, _ := newRecoveryTestClient(t, testServer.URL, vault)
+	writeClientKey(t, cfg, "client key")
+
+	kit, err := recovery.CreateKit("token")
+	require.NoError(t, err)
+	assert.True(t, util.IsRecoveryKey(kit.Key))
+	assert.Len(t, kit.Shares, 5)
+	assert.Equal(t, 3, kit.Threshold)
+	// сервер не получает открытых ключей
+	assert.NotContains(t, server.kit.ClientKey, "client key")
+
+	tests := []struct {
+		name   string
+		secret string
+	}{
+		{"recovery key", strings.ToLower(kit.Key)},
+		{"shares", kit.Shares[4] + "\n\n" + kit.Shares[1] + "\n" + kit.Shares[2] + "\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// новый клиент без PathKeys и мастер-пароля
+			otherVault := service.NewVault()
+			other, otherCfg, crypt := newRecoveryTestClient(t, testServer.URL, otherVault)
+
+			require.NoError(t, other.Restore("token", tt.secret))
+			assert.Equal(t, "client key", readClientKey(t, otherCfg))
+			assert.True(t, otherVault.IsUnlocked())
+			crypt.AssertCalled(t, "ReloadEncryptionKey")
+		})
+	}
+
+	t.Run("not enough shares", func(t *testing.T) {
+		other, _, _ := newRecoveryTestClient(t, testServer.URL, service.NewVault())
+		err := other.Restore("token", kit.Shares[0]+"\n"+kit.Shares[1])
+		assert.ErrorIs(t, err, util.ErrRecoverySharesCount)
+	})
+
+	t.Run("other kit", func(t *testing.T) {
+		_, printable, err := util.NewRecoveryKey()
+		require.NoError(t, err)
+		otherVault := service.NewVault()
+		other, _, _ := newRecoveryTestClient(t, testServer.URL, otherVault)
+		assert.ErrorIs(t, other.Restore("token", printable), ErrRecoverySecret)
+		assert.False(t, otherVault.IsUnlocked())
+	})
+
+	t.Run("master password", func(t *testing.T) {
+		key, err := vault.Key()
+		require.NoError(t, err)
+		otherVault := service.NewVault()
+		require.NoError(t, otherVault.UnlockWithKey(key))
+		other, otherCfg, _ := newRecoveryTestClient(t, testServer.URL, otherVault)
+
+		require.NoError(t, other.RestoreClientKey("token"))
+		assert.Equal(t, "client key", readClientKey(t, otherCfg))
+	})
+}
+
+func TestRecovery_EnsureKit(t *testing.T) {
+	server := new(recoveryKitServer)
+	testServer := httptest.NewServer(server)
+	defer testServer.Close()
+
+	vault := service.NewVault()
+	_, _, err := vault.Setup("master password")
+	require.NoError(t, err)
+	recovery, cfg, _ := newRecoveryTestClient(t, testServer.URL, vault)
+	writeClientKey(t, cfg, "client key")
+
+	// первый вход: комплект создаётся и показывается
+	kit, err := recovery.EnsureKit("token")
+	require.NoError(t, err)
+	require.NotNil(t, kit)
+	vaultKey := server.kit.VaultKey
+
+	// комплект актуален
+	kit, err = recovery.EnsureKit("token")
+	require.NoError(t, err)
+	assert.Nil(t, kit)
+
+	// после смены ключа клиента обновляется только ключ клиента
+	writeClientKey(t, cfg, "rotated key")
+	kit, err = recovery.EnsureKit("token")
+	require.NoError(t, err)
+	assert.Nil(t, kit)
+	assert.Equal(t, vaultKey, server.kit.VaultKey)
+
+	writeClientKey(t, cfg, "lost")
+	require.NoError(t, recovery.RestoreClientKey("token"))
+	assert.Equal(t, "rotated key", readClientKey(t, cfg))
+}
+
+func TestRecovery_Errors(t *testing.T) {
+	testServer := httptest.NewServer(new(recoveryKitServer))
+	defer testServer.Close()
+
+	recovery, _, _ := newRecoveryTestClient(t, testServer.URL, service.NewVault())
+	_, err := recovery.CreateKit("token")
+	assert.ErrorIs(t, err, service.ErrVaultLocked)
+	assert.ErrorIs(t, recovery.RestoreClientKey("token"), ErrRecoveryKitNotFound)
+	assert.ErrorIs(t, recovery.Restore("token", "GKRK-AAAA"), ErrRecoveryKitNotFound)
+
+	unauthorized := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer unauthorized.Close()
+	recovery, _, _ = newRecoveryTestClient(t, unauthorized.URL, service.NewVault())
+	assert.EqualError(t, recovery.RestoreClientKey("token"), "вы не авторизованы")
+}
+
+func TestUploadClientPrivateKey_Mismatch(t *testing.T) {
+	log, _ := logger.NewLogger("info")
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusConflict)
+	}))
+	defer testServer.Close()
+
+	mockConfig := makeMockConfig(testServer.URL)
+	mockConfig.Value().PathKeys = t.TempDir()
+	controller := NewKeysData(mockConfig, NewCryptMock(t), service.NewVault(), log)
+
+	err := controller.UploadClientPrivateKey("test_token")
+	assert.ErrorIs(t, err, ErrClientKeyMismatch)
+	// созданный ключ не подошёл и удаляется
+	assert.NoFileExists(t, filepath.Join(mockConfig.Value().PathKeys, keys.PrivateKeyFileNameForEncryption))
+}
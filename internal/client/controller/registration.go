@@ -15,6 +15,7 @@ import (
 type Registration struct {
 	logger *logger.Logger
 	cfg    *config.Config
+	client *http.Client
 }
 
 // NewRegistration конструктор
@@ -22,6 +23,7 @@ func NewRegistration(cfg *config.Config, logger *logger.Logger) *Registration {
 	return &Registration{
 		logger: logger,
 		cfg:    cfg,
+		client: newHTTPClient(cfg),
 	}
 }
 
@@ -58,8 +60,7 @@ func (c *Registration) Send(login string, password string, email string) (*Regis
 		c.logger.Error(err)
 		return nil, err
 	}
-	client := &http.Client{}
-	response, err := client.Do(requestPrepare)
+	response, err := c.client.Do(requestPrepare)
 	if err != nil {
 		c.logger.Error(err)
 		return nil, err
@@ -67,6 +68,15 @@ func (c *Registration) Send(login string, password string, email string) (*Regis
 	defer response.Body.Close()
 
 	if response.StatusCode != http.StatusOK {
+		if response.StatusCode == http.StatusTooManyRequests {
+			return nil, tooManyRequestsError(response)
+		}
+		if response.StatusCode == http.StatusConflict {
+			return nil, fmt.Errorf("логин уже занят")
+		}
+		if response.StatusCode == http.StatusBadRequest {
+			return nil, fmt.Errorf("проверьте данные: логин от 2 символов, пароль от 8 символов, корректный email")
+		}
 		return nil, fmt.Errorf("не известная ошибка")
 	}
 
@@ -85,3 +85,22 @@ func TestRegistration_Send_NonOKResponse(t *testing.T) {
 	assert.Error(t, err)
 	assert.Nil(t, response)
 }
+
+func TestRegistration_Send_TooManyRequests(t *testing.T) {
+	log, err := logger.NewLogger("info")
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "30")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer testServer.Close()
+	mockConfig := makeMockConfig(testServer.URL)
+	controller := NewRegistration(mockConfig, log)
+
+	response, err := controller.Send("testlogin", "testpassword", "testemail")
+	assert.EqualError(t, err, "слишком много попыток, повторите через 30 сек.")
+	assert.Nil(t, response)
+}
@@ -6,26 +6,35 @@ import (
 	"fmt"
 	"net/http"
 
+	"github.com/google/uuid"
 	"github.com/northmule/gophkeeper/internal/client/config"
 	"github.com/northmule/gophkeeper/internal/client/logger"
 	"github.com/northmule/gophkeeper/internal/client/service"
+	"github.com/northmule/gophkeeper/internal/client/storage"
+	"github.com/northmule/gophkeeper/internal/common/data_type"
 	"github.com/northmule/gophkeeper/internal/common/model_data"
 	"golang.org/x/net/context"
 )
 
 // TextData контроллер
 type TextData struct {
-	logger *logger.Logger
-	cfg    *config.Config
-	crypt  service.Cryptographer
+	logger  *logger.Logger
+	cfg     *config.Config
+	client  *http.Client
+	crypt   service.Cryptographer
+	vault   service.VaultCryptographer
+	offline *storage.OfflineVault
 }
 
 // NewTextData конструктор
-func NewTextData(cfg *config.Config, crypt service.Cryptographer, logger *logger.Logger) *TextData {
+func NewTextData(cfg *config.Config, crypt service.Cryptographer, vault service.VaultCryptographer, offline *storage.OfflineVault, logger *logger.Logger) *TextData {
 	return &TextData{
-		cfg:    cfg,
-		crypt:  crypt,
-		logger: logger,
+		cfg:     cfg,
+		client:  newHTTPClient(cfg),
+		crypt:   crypt,
+		vault:   vault,
+		offline: offline,
+		logger:  logger,
 	}
 }
 
@@ -34,19 +43,36 @@ type TextDataResponse struct {
 	Value string
 }
 
-// Send отправка запроса к серверу
+// Send отправка запроса к серверу. Если сервер недоступен, запрос ставится в очередь изменений (ErrQueued)
 func (c *TextData) Send(token string, requestData *model_data.TextDataRequest) (*TextDataResponse, error) {
+	idempotencyKey := uuid.NewString()
+	responseData, err := c.send(token, requestData, idempotencyKey)
+	if isUnreachable(err) {
+		entry := storage.OutboxEntry{Key: idempotencyKey, Type: data_type.TextType, Name: requestData.Name}
+		return nil, enqueue(c.offline, entry, requestData, err)
+	}
+	return responseData, err
+}
+
+// send отправка запроса с ключом идемпотентности: повтор с тем же ключом сервер не выполняет
+func (c *TextData) send(token string, requestData *model_data.TextDataRequest, idempotencyKey string) (*TextDataResponse, error) {
 	requestURL := fmt.Sprintf("%s/api/v1/save_text_data", c.cfg.Value().ServerAddress)
 	ctx := context.Background()
 
-	requestBody, err := json.Marshal(requestData)
+	// Значения шифруются мастер-ключом, сервер получает только шифротекст
+	sealedData, err := sealTextData(c.vault, requestData)
+	if err != nil {
+		c.logger.Error(err)
+		return nil, err
+	}
+	requestBody, err := json.Marshal(sealedData)
 	if err != nil {
 		c.logger.Error(err)
 		return nil, err
 	}
 
 	// Шифруем
-	requestBody, err = c.crypt.EncryptAES(requestBody)
+	requestBody, err = sealRequestBody(c.crypt, http.MethodPost, requestURL, requestBody)
 	if err != nil {
 		c.logger.Error(err)
 		return nil, err
@@ -59,8 +85,8 @@ func (c *TextData) Send(token string, requestData *model_data.TextDataRequest) (
 		return nil, err
 	}
 	requestPrepare.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
-	client := &http.Client{}
-	response, err := client.Do(requestPrepare)
+	requestPrepare.Header.Add(data_type.IdempotencyKeyHeader, idempotencyKey)
+	response, err := c.client.Do(requestPrepare)
 	if err != nil {
 		c.logger.Error(err)
 		return nil, err
@@ -71,6 +97,9 @@ func (c *TextData) Send(token string, requestData *model_data.TextDataRequest) (
 		if response.StatusCode == http.StatusUnauthorized {
 			return nil, fmt.Errorf("вы не авторизованы")
 		}
+		if err = envelopeError(response); err != nil {
+			return nil, err
+		}
 
 		if response.StatusCode == http.StatusBadRequest {
 			return nil, fmt.Errorf("ошибка в запросе")
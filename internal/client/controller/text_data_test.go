@@ -34,14 +34,24 @@ func (m *MockCryptographer) DecryptAES(data []byte) ([]byte, error) {
 	return args.Get(0).([]byte), args.Error(1)
 }
 
-// EncryptRSA Шифрование исходящих данных серверных публичным ключом
-func (m *MockCryptographer) EncryptRSA(data []byte) ([]byte, error) {
-	return nil, nil
+// ReloadEncryptionKey мок
+func (m *MockCryptographer) ReloadEncryptionKey() error {
+	args := m.Called()
+	return args.Error(0)
 }
 
-// DecryptRSA Расшифровка входящих сообещний приватным ключом клиента
-func (m *MockCryptographer) DecryptRSA(data []byte) ([]byte, error) {
-	return nil, nil
+// SealRequest мок
+func (m *MockCryptographer) SealRequest(method string, path string, data []byte) ([]byte, error) {
+	args := m.Called(method, path, data)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]byte), args.Error(1)
+}
+
+// EncryptKey Шифрование ключа для передачи серверу
+func (m *MockCryptographer) EncryptKey(data []byte) (string, []byte, error) {
+	return "", nil, nil
 }
 
 func TestNewTextData_Success(t *testing.T) {
@@ -52,7 +62,7 @@ func TestNewTextData_Success(t *testing.T) {
 	}
 	mockCrypt := new(MockCryptographer)
 
-	textData := NewTextData(mockConfig, mockCrypt, log)
+	textData := NewTextData(mockConfig, mockCrypt, newVaultMock(t), newTestOfflineVault(t), log)
 	assert.NotNil(t, textData)
 	assert.Equal(t, mockConfig, textData.cfg)
 	assert.Equal(t, mockCrypt, textData.crypt)
@@ -67,7 +77,7 @@ func TestTextData_Send_Success(t *testing.T) {
 	}
 	mockCrypt := new(MockCryptographer)
 
-	mockCrypt.On("EncryptAES", mock.Anything).Return([]byte("encrypted_data"), nil)
+	mockCrypt.On("SealRequest", http.MethodPost, "/api/v1/save_text_data", mock.Anything).Return([]byte("encrypted_data"), nil)
 
 	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		assert.Equal(t, "/api/v1/save_text_data", r.URL.Path)
@@ -83,7 +93,7 @@ func TestTextData_Send_Success(t *testing.T) {
 	}))
 	defer testServer.Close()
 	mockConfig := makeMockConfig(testServer.URL)
-	textData := NewTextData(mockConfig, mockCrypt, log)
+	textData := NewTextData(mockConfig, mockCrypt, newVaultMock(t), newTestOfflineVault(t), log)
 	requestData := &model_data.TextDataRequest{
 		Value: "test_text",
 	}
@@ -101,9 +111,9 @@ func TestTextData_Send_HTTPRequestError(t *testing.T) {
 		t.Errorf(err.Error())
 	}
 	mockCrypt := new(MockCryptographer)
-	mockCrypt.On("EncryptAES", mock.Anything).Return([]byte("encrypted_data"), nil)
+	mockCrypt.On("SealRequest", http.MethodPost, "/api/v1/save_text_data", mock.Anything).Return([]byte("encrypted_data"), nil)
 
-	textData := NewTextData(mockConfig, mockCrypt, log)
+	textData := NewTextData(mockConfig, mockCrypt, newVaultMock(t), newTestOfflineVault(t), log)
 	requestData := &model_data.TextDataRequest{
 		Value: "test_text",
 	}
@@ -120,9 +130,9 @@ func TestTextData_Send_EncryptionError(t *testing.T) {
 	}
 	mockCrypt := new(MockCryptographer)
 
-	mockCrypt.On("EncryptAES", mock.Anything).Return(nil, errors.New("encryption error"))
+	mockCrypt.On("SealRequest", http.MethodPost, "/api/v1/save_text_data", mock.Anything).Return(nil, errors.New("encryption error"))
 
-	textData := NewTextData(mockConfig, mockCrypt, log)
+	textData := NewTextData(mockConfig, mockCrypt, newVaultMock(t), newTestOfflineVault(t), log)
 	requestData := &model_data.TextDataRequest{
 		Value: "test_text",
 	}
@@ -0,0 +1,110 @@
+package controller
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"sync"
+
+	"github.com/northmule/gophkeeper/internal/client/config"
+	"github.com/northmule/gophkeeper/internal/common/keys"
+)
+
+// ErrCertificateMismatch сервер предъявил сертификат, не совпадающий с закреплённым
+var ErrCertificateMismatch = errors.New("server certificate does not match the pinned fingerprint")
+
+// PinnedTransport транспорт с закреплённым сертификатом сервера и сертификатом клиента для mTLS.
+// Если в конфигурации задан ServerCertFingerprint или ServerCertPath, сертификат сервера проверяется
+// по отпечатку (самоподписанный сертификат сервера не проходит проверку цепочки),
+// иначе используется стандартная проверка по системным корневым сертификатам
+type PinnedTransport struct {
+	cfg *config.Config
+
+	once      sync.Once
+	transport http.RoundTripper
+	err       error
+}
+
+// NewPinnedTransport конструктор
+func NewPinnedTransport(cfg *config.Config) *PinnedTransport {
+	return &PinnedTransport{cfg: cfg}
+}
+
+// RoundTrip выполнение запроса
+func (t *PinnedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.once.Do(func() {
+		t.transport, t.err = t.build()
+	})
+	if t.err != nil {
+		return nil, t.err
+	}
+	return t.transport.RoundTrip(req)
+}
+
+func (t *PinnedTransport) build() (http.RoundTripper, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	pins, err := t.pins()
+	if err != nil {
+		return nil, err
+	}
+	transport.TLSClientConfig = &tls.Config{
+		MinVersion:           tls.VersionTLS12,
+		GetClientCertificate: t.clientCertificate,
+	}
+	if len(pins) == 0 {
+		return transport, nil
+	}
+	// цепочка и имя не проверяются, сертификат сверяется с отпечатком в VerifyConnection
+	transport.TLSClientConfig.InsecureSkipVerify = true
+	transport.TLSClientConfig.VerifyConnection = func(state tls.ConnectionState) error {
+		if len(state.PeerCertificates) == 0 {
+			return ErrCertificateMismatch
+		}
+		if _, ok := pins[keys.Fingerprint(state.PeerCertificates[0].Raw)]; !ok {
+			return ErrCertificateMismatch
+		}
+		return nil
+	}
+	return transport, nil
+}
+
+// clientCertificate сертификат клиента для сервера с mTLS. Файл появляется после отправки
+// публичного ключа на сервер, поэтому читается при каждом рукопожатии. Без сертификата отправляется пустой ответ
+func (t *PinnedTransport) clientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	certPath := path.Join(t.cfg.Value().PathKeys, keys.ClientCertificateFileName)
+	if _, err := os.Stat(certPath); err != nil {
+		return &tls.Certificate{}, nil
+	}
+	certificate, err := tls.LoadX509KeyPair(certPath, path.Join(t.cfg.Value().PathKeys, keys.PrivateKeyFileName))
+	if err != nil {
+		return nil, fmt.Errorf("client certificate: %w", err)
+	}
+	return &certificate, nil
+}
+
+// pins отпечатки из конфигурации
+func (t *PinnedTransport) pins() (map[string]struct{}, error) {
+	pins := make(map[string]struct{})
+	if value := keys.NormalizeFingerprint(t.cfg.Value().ServerCertFingerprint); value != "" {
+		if len(value) != 64 {
+			return nil, fmt.Errorf("invalid ServerCertFingerprint, expected SHA-256 in hex")
+		}
+		pins[value] = struct{}{}
+	}
+	if t.cfg.Value().ServerCertPath != "" {
+		value, err := keys.CertificateFileFingerprint(t.cfg.Value().ServerCertPath)
+		if err != nil {
+			return nil, fmt.Errorf("server certificate: %w", err)
+		}
+		pins[value] = struct{}{}
+	}
+	return pins, nil
+}
+
+// newHTTPClient http клиент контроллеров
+func newHTTPClient(cfg *config.Config) *http.Client {
+	return &http.Client{Transport: NewPinnedTransport(cfg)}
+}
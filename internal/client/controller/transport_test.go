@@ -0,0 +1,66 @@
+package controller
+
+import (
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/northmule/gophkeeper/internal/common/keys"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPinnedTransport(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	fingerprint := keys.Fingerprint(server.Certificate().Raw)
+	certPath := filepath.Join(t.TempDir(), keys.CertificateFileName)
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	require.NoError(t, os.WriteFile(certPath, certPEM, 0644))
+
+	get := func(fingerprint string, certPath string) error {
+		cfg := makeMockConfig(server.URL)
+		cfg.Value().ServerCertFingerprint = fingerprint
+		cfg.Value().ServerCertPath = certPath
+		response, err := newHTTPClient(cfg).Get(server.URL)
+		if err != nil {
+			return err
+		}
+		return response.Body.Close()
+	}
+
+	t.Run("pinned fingerprint", func(t *testing.T) {
+		assert.NoError(t, get(fingerprint, ""))
+	})
+	t.Run("pinned fingerprint with colons", func(t *testing.T) {
+		var parts []string
+		for i := 0; i < len(fingerprint); i += 2 {
+			parts = append(parts, strings.ToUpper(fingerprint[i:i+2]))
+		}
+		assert.NoError(t, get(strings.Join(parts, ":"), ""))
+	})
+	t.Run("pinned certificate file", func(t *testing.T) {
+		assert.NoError(t, get("", certPath))
+	})
+	t.Run("another certificate", func(t *testing.T) {
+		err := get(strings.Repeat("0", 64), "")
+		assert.True(t, errors.Is(err, ErrCertificateMismatch), err)
+	})
+	t.Run("not pinned self-signed", func(t *testing.T) {
+		assert.Error(t, get("", ""))
+	})
+	t.Run("invalid fingerprint", func(t *testing.T) {
+		assert.Error(t, get("abc", ""))
+	})
+	t.Run("missing certificate file", func(t *testing.T) {
+		assert.Error(t, get("", filepath.Join(t.TempDir(), "none.pem")))
+	})
+}
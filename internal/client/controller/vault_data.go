@@ -0,0 +1,163 @@
+package controller
+
+import (
+	"github.com/northmule/gophkeeper/internal/client/service"
+	"github.com/northmule/gophkeeper/internal/common/model_data"
+)
+
+// Шифрование значений данных мастер-ключом перед отправкой и расшифровка после получения.
+// Название данных остаётся открытым, оно нужно серверу для списка.
+
+// sealFields шифрует значения по указателям
+func sealFields(vault service.VaultCryptographer, fields ...*string) error {
+	var err error
+	for _, field := range fields {
+		*field, err = vault.EncryptString(*field)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// openFields расшифровывает значения по указателям
+func openFields(vault service.VaultCryptographer, fields ...*string) error {
+	var err error
+	for _, field := range fields {
+		*field, err = vault.DecryptString(*field)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sealMeta копия мета данных с зашифрованными значениями
+func sealMeta(vault service.VaultCryptographer, meta map[string]string) (map[string]string, error) {
+	if meta == nil {
+		return nil, nil
+	}
+	sealed := make(map[string]string, len(meta))
+	for name, value := range meta {
+		v, err := vault.EncryptString(value)
+		if err != nil {
+			return nil, err
+		}
+		sealed[name] = v
+	}
+	return sealed, nil
+}
+
+// openMeta расшифровка значений мета данных
+func openMeta(vault service.VaultCryptographer, meta map[string]string) error {
+	for name, value := range meta {
+		v, err := vault.DecryptString(value)
+		if err != nil {
+			return err
+		}
+		meta[name] = v
+	}
+	return nil
+}
+
+// sealCardData копия данных карты с зашифрованными значениями
+func sealCardData(vault service.VaultCryptographer, data *model_data.CardDataRequest) (*model_data.CardDataRequest, error) {
+	var err error
+	sealed := *data
+	err = sealFields(vault, &sealed.CardNumber, &sealed.ValidityPeriod, &sealed.SecurityCode, &sealed.FullNameHolder,
+		&sealed.NameBank, &sealed.PhoneHolder, &sealed.CurrentAccountNumber)
+	if err != nil {
+		return nil, err
+	}
+	sealed.Meta, err = sealMeta(vault, data.Meta)
+	if err != nil {
+		return nil, err
+	}
+	return &sealed, nil
+}
+
+// sealTextData копия текстовых данных с зашифрованными значениями
+func sealTextData(vault service.VaultCryptographer, data *model_data.TextDataRequest) (*model_data.TextDataRequest, error) {
+	var err error
+	sealed := *data
+	err = sealFields(vault, &sealed.Value)
+	if err != nil {
+		return nil, err
+	}
+	sealed.Meta, err = sealMeta(vault, data.Meta)
+	if err != nil {
+		return nil, err
+	}
+	return &sealed, nil
+}
+
+// sealCredentialData копия пары логин/пароль с зашифрованными значениями
+func sealCredentialData(vault service.VaultCryptographer, data *model_data.CredentialDataRequest) (*model_data.CredentialDataRequest, error) {
+	var err error
+	sealed := *data
+	err = sealFields(vault, &sealed.Username, &sealed.Password, &sealed.Notes)
+	if err != nil {
+		return nil, err
+	}
+	sealed.URLs = make([]string, len(data.URLs))
+	copy(sealed.URLs, data.URLs)
+	for i := range sealed.URLs {
+		err = sealFields(vault, &sealed.URLs[i])
+		if err != nil {
+			return nil, err
+		}
+	}
+	sealed.Meta, err = sealMeta(vault, data.Meta)
+	if err != nil {
+		return nil, err
+	}
+	return &sealed, nil
+}
+
+// sealFileDataInit копия данных файла с зашифрованными мета данными
+func sealFileDataInit(vault service.VaultCryptographer, data *model_data.FileDataInitRequest) (*model_data.FileDataInitRequest, error) {
+	var err error
+	sealed := *data
+	sealed.Meta, err = sealMeta(vault, data.Meta)
+	if err != nil {
+		return nil, err
+	}
+	return &sealed, nil
+}
+
+// openDataByUUID расшифровка полученных данных
+func openDataByUUID(vault service.VaultCryptographer, data *model_data.DataByUUIDResponse) error {
+	var err error
+	if data.IsCard {
+		err = openFields(vault, &data.CardData.CardNumber, &data.CardData.ValidityPeriod, &data.CardData.SecurityCode,
+			&data.CardData.FullNameHolder, &data.CardData.NameBank, &data.CardData.PhoneHolder, &data.CardData.CurrentAccountNumber)
+		if err != nil {
+			return err
+		}
+		return openMeta(vault, data.CardData.Meta)
+	}
+	if data.IsText {
+		err = openFields(vault, &data.TextData.Value)
+		if err != nil {
+			return err
+		}
+		return openMeta(vault, data.TextData.Meta)
+	}
+	if data.IsCredential {
+		err = openFields(vault, &data.CredentialData.Username, &data.CredentialData.Password, &data.CredentialData.Notes)
+		if err != nil {
+			return err
+		}
+		for i := range data.CredentialData.URLs {
+			err = openFields(vault, &data.CredentialData.URLs[i])
+			if err != nil {
+				return err
+			}
+		}
+		return openMeta(vault, data.CredentialData.Meta)
+	}
+	if data.IsFile {
+		return openMeta(vault, data.FileData.Meta)
+	}
+	return nil
+}
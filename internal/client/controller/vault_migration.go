@@ -0,0 +1,116 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+
+	"github.com/google/uuid"
+	"github.com/northmule/gophkeeper/internal/client/logger"
+	"github.com/northmule/gophkeeper/internal/common/model_data"
+)
+
+// Данные, сохранённые до шифрования мастер-ключом, хранятся на сервере открытыми. Пока они есть
+// (признак legacy в параметрах мастер-ключа), хранилище принимает открытые значения. После разблокировки
+// данные один раз сохраняются заново (значения шифруются при отправке), и сервер снимает признак.
+
+// vaultMigrationPage данных в одном запросе списка
+const vaultMigrationPage = 200
+
+// VaultMigration перешифрование данных, сохранённых до шифрования мастер-ключом
+type VaultMigration struct {
+	logger         *logger.Logger
+	gridData       *GridData
+	itemData       *ItemData
+	cardData       *CardData
+	textData       *TextData
+	credentialData *CredentialData
+	fileData       *FileData
+}
+
+// NewVaultMigration конструктор
+func NewVaultMigration(gridData *GridData, itemData *ItemData, cardData *CardData, textData *TextData, credentialData *CredentialData, fileData *FileData, logger *logger.Logger) *VaultMigration {
+	return &VaultMigration{
+		logger:         logger,
+		gridData:       gridData,
+		itemData:       itemData,
+		cardData:       cardData,
+		textData:       textData,
+		credentialData: credentialData,
+		fileData:       fileData,
+	}
+}
+
+// Run сохраняет заново все данные пользователя. Хранилище должно принимать открытые значения (SetLegacy)
+func (c *VaultMigration) Run(token string) error {
+	// список собирается до сохранения: сохранённые данные меняют порядок списка
+	var dataUUIDs []string
+	for offset := 0; ; offset += vaultMigrationPage {
+		list := new(model_data.ListDataItemsResponse)
+		err := c.gridData.get(token, fmt.Sprintf("/api/v1/items_list?offset=%d&limit=%d", offset, vaultMigrationPage), list)
+		if err != nil {
+			return err
+		}
+		for _, item := range list.Items {
+			dataUUIDs = append(dataUUIDs, item.UUID)
+		}
+		if len(list.Items) < vaultMigrationPage {
+			break
+		}
+	}
+
+	for _, dataUUID := range dataUUIDs {
+		err := c.reseal(token, dataUUID)
+		if err != nil {
+			c.logger.Errorf("vault migration: %s: %s", dataUUID, err)
+			return err
+		}
+	}
+	return nil
+}
+
+// reseal сохранение данных с прежней версией, значения шифруются мастер-ключом при отправке
+func (c *VaultMigration) reseal(token string, dataUUID string) error {
+	data, err := c.itemData.Send(token, dataUUID)
+	if err != nil {
+		return err
+	}
+	switch {
+	case data.IsCard:
+		_, err = c.cardData.send(token, &data.CardData, uuid.NewString())
+	case data.IsText:
+		_, err = c.textData.send(token, &data.TextData, uuid.NewString())
+	case data.IsCredential:
+		_, err = c.credentialData.send(token, &data.CredentialData, uuid.NewString())
+	case data.IsFile:
+		err = c.resealFile(token, &data.FileData)
+	}
+	var conflict *VersionConflictError
+	if errors.As(err, &conflict) {
+		// данные изменены после чтения и уже сохранены зашифрованными
+		return nil
+	}
+	return err
+}
+
+// resealFile файл, загруженный до частей, загружается заново (части шифруются мастер-ключом),
+// у файла из частей сохраняются заново только мета данные
+func (c *VaultMigration) resealFile(token string, data *model_data.FileDataInitRequest) error {
+	if data.Chunks > 0 {
+		data.Chunks = 0
+		_, err := c.fileData.send(token, data, uuid.NewString())
+		return err
+	}
+	dir, err := os.MkdirTemp("", "gophkeeper")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	filePath := path.Join(dir, data.UUID)
+	err = c.fileData.download(token, data.UUID, filePath)
+	if err != nil {
+		return err
+	}
+	return c.fileData.sendFile(token, data, filePath, uuid.NewString())
+}
@@ -0,0 +1,133 @@
+package controller
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/northmule/gophkeeper/internal/client/logger"
+	"github.com/northmule/gophkeeper/internal/common/data_type"
+	"github.com/northmule/gophkeeper/internal/common/model_data"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// legacyServer сервер с текстовыми данными, сохранёнными до шифрования мастер-ключом
+func legacyServer(t *testing.T, crypt *CryptMock, saved *[]model_data.TextDataRequest, migrated *bool, saveStatus int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body []byte
+		switch r.URL.Path {
+		case "/api/v1/master_key":
+			_ = json.NewEncoder(w).Encode(model_data.MasterKeyResponse{Salt: "c2FsdA==", Check: "check", Legacy: !*migrated})
+			return
+		case "/api/v1/items_list":
+			body, _ = json.Marshal(model_data.ListDataItemsResponse{Items: []model_data.ItemDataResponse{{UUID: "text-uuid"}}})
+		case "/api/v1/item_get/text-uuid":
+			body, _ = json.Marshal(model_data.DataByUUIDResponse{IsText: true, TextData: model_data.TextDataRequest{
+				UUID: "text-uuid", Version: 1, Name: "note", Value: "plain value", Meta: map[string]string{"note": "plain note"},
+			}})
+		case "/api/v1/save_text_data":
+			raw, _ := io.ReadAll(r.Body)
+			opened, err := crypt.OpenRequest(r, raw)
+			require.NoError(t, err)
+			request := model_data.TextDataRequest{}
+			require.NoError(t, json.Unmarshal(opened, &request))
+			*saved = append(*saved, request)
+			w.WriteHeader(saveStatus)
+			if saveStatus == http.StatusConflict {
+				_ = json.NewEncoder(w).Encode(map[string]int64{"code": data_type.AppCodeVersionConflict, "version": 2})
+			}
+			return
+		case "/api/v1/master_key/migrated":
+			*migrated = true
+			return
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		encrypted, _ := crypt.EncryptAES(body)
+		_, _ = w.Write(encrypted)
+	}))
+}
+
+func newTestVaultMigration(t *testing.T, serverURL string, crypt *CryptMock) (*MasterKey, *VaultMigration) {
+	log, err := logger.NewLogger("info")
+	require.NoError(t, err)
+	cfg := makeMockConfig(serverURL)
+	vault := newVaultMock(t)
+	offline := newTestOfflineVault(t)
+	migration := NewVaultMigration(
+		NewGridData(cfg, crypt, offline, log),
+		NewItemData(cfg, crypt, vault, offline, log),
+		NewCardData(cfg, crypt, vault, offline, log),
+		NewTextData(cfg, crypt, vault, offline, log),
+		NewCredentialData(cfg, crypt, vault, offline, log),
+		NewFileData(cfg, crypt, vault, offline, log),
+		log,
+	)
+	return NewMasterKey(cfg, vault, offline, migration, log), migration
+}
+
+func TestMasterKey_Migrate(t *testing.T) {
+	t.Run("reseal", func(t *testing.T) {
+		crypt := NewCryptMock(t)
+		var saved []model_data.TextDataRequest
+		migrated := false
+		server := legacyServer(t, crypt, &saved, &migrated, http.StatusOK)
+		defer server.Close()
+		masterKey, _ := newTestVaultMigration(t, server.URL, crypt)
+
+		require.NoError(t, masterKey.Migrate("token"))
+		assert.True(t, migrated)
+		require.Len(t, saved, 1)
+		// данные сохранены с прежней версией, значения зашифрованы мастер-ключом
+		assert.Equal(t, "text-uuid", saved[0].UUID)
+		assert.Equal(t, int64(1), saved[0].Version)
+		assert.Equal(t, "note", saved[0].Name)
+		value, err := masterKey.vault.DecryptString(saved[0].Value)
+		require.NoError(t, err)
+		assert.Equal(t, "plain value", value)
+		note, err := masterKey.vault.DecryptString(saved[0].Meta["note"])
+		require.NoError(t, err)
+		assert.Equal(t, "plain note", note)
+
+		// после перешифрования открытые значения не принимаются
+		_, err = masterKey.vault.DecryptString("plain value")
+		assert.Error(t, err)
+
+		// признак снят, повторно данные не сохраняются
+		require.NoError(t, masterKey.Migrate("token"))
+		assert.Len(t, saved, 1)
+	})
+
+	t.Run("conflict", func(t *testing.T) {
+		crypt := NewCryptMock(t)
+		var saved []model_data.TextDataRequest
+		migrated := false
+		server := legacyServer(t, crypt, &saved, &migrated, http.StatusConflict)
+		defer server.Close()
+		masterKey, _ := newTestVaultMigration(t, server.URL, crypt)
+
+		// данные изменены после чтения и уже сохранены зашифрованными
+		require.NoError(t, masterKey.Migrate("token"))
+		assert.True(t, migrated)
+	})
+
+	t.Run("save_error", func(t *testing.T) {
+		crypt := NewCryptMock(t)
+		var saved []model_data.TextDataRequest
+		migrated := false
+		server := legacyServer(t, crypt, &saved, &migrated, http.StatusInternalServerError)
+		defer server.Close()
+		masterKey, _ := newTestVaultMigration(t, server.URL, crypt)
+
+		assert.Error(t, masterKey.Migrate("token"))
+		assert.False(t, migrated)
+		// до перешифрования открытые значения читаются
+		value, err := masterKey.vault.DecryptString("plain value")
+		require.NoError(t, err)
+		assert.Equal(t, "plain value", value)
+	})
+}
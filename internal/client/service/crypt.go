@@ -1,48 +1,59 @@
 package service
 
 import (
+	"crypto"
 	"crypto/rsa"
+	"errors"
 	"os"
 	"path"
+	"sync"
+	"time"
 
 	"github.com/northmule/gophkeeper/internal/client/config"
 	"github.com/northmule/gophkeeper/internal/common/keys"
 	"github.com/northmule/gophkeeper/internal/common/util"
 )
 
+// ErrUnsupportedKeyExchange сервер не предложил подходящую схему обмена ключами
+var ErrUnsupportedKeyExchange = errors.New("сервер не поддерживает обмен ключами с этим клиентом")
+
 // Crypt сервис шифрования дешифрования данных
 type Crypt struct {
-	// Серверный публичный ключ, для шифрования исходящиз сообщений
-	serverPublicKey *rsa.PublicKey
-	// Приватный ключ этого клиента, для расшифровки сообщений от сервера
-	clientPrivateKey *rsa.PrivateKey
+	// Серверный публичный ключ (RSA, ECDSA или Ed25519)
+	serverPublicKey crypto.PublicKey
+	// Приватный ключ этого клиента (RSA, ECDSA или Ed25519)
+	clientPrivateKey crypto.Signer
 	// Ключ для шифрования и дешифрования данных между клиентом и сервером (ключ хранится и на клиенте и на сервере)
 	privateKeyForEncryption []byte
+	// ключ шифрования заменяется после смены ключа, в том числе из потока событий
+	keyMx sync.RWMutex
 
 	cfg *config.Config
 }
 
 // Cryptographer общий интерфейс шифрования для клиента
 type Cryptographer interface {
-	// EncryptRSA Шифрование исходящих данных серверных публичным ключом
-	EncryptRSA(data []byte) ([]byte, error)
-	// DecryptRSA Расшифровка входящих сообещний приватным ключом клиента
-	DecryptRSA(data []byte) ([]byte, error)
+	// EncryptKey Шифрование ключа для передачи серверу, возвращает использованную схему обмена
+	EncryptKey(data []byte) (string, []byte, error)
 	// EncryptAES Шифрование исходящих данных
 	EncryptAES(data []byte) ([]byte, error)
+	// SealRequest Шифрование тела запроса в конверт с временем, nonce, методом и путём запроса (защита от повтора)
+	SealRequest(method string, path string, data []byte) ([]byte, error)
 	// DecryptAES Расшифровка входящих сообещний
 	DecryptAES(data []byte) ([]byte, error)
+	// ReloadEncryptionKey Перечитать ключ шифрования данных (после смены ключа)
+	ReloadEncryptionKey() error
 }
 
 // NewCrypt конструктор
 func NewCrypt(cfg *config.Config) (*Crypt, error) {
 	instance := new(Crypt)
 	var err error
-	instance.serverPublicKey, err = util.FillPublicRsaKeyFromFile(path.Join(cfg.Value().PathPublicKeyServer, keys.PublicKeyFileName))
+	instance.serverPublicKey, err = util.FillPublicKeyFromFile(path.Join(cfg.Value().PathPublicKeyServer, keys.PublicKeyFileName))
 	if err != nil {
 		return nil, err
 	}
-	instance.clientPrivateKey, err = util.FillPrivateRsaKeyFromFile(path.Join(cfg.Value().PathKeys, keys.PrivateKeyFileName))
+	instance.clientPrivateKey, err = util.FillPrivateKeyFromFile(path.Join(cfg.Value().PathKeys, keys.PrivateKeyFileName))
 	if err != nil {
 		return nil, err
 	}
@@ -54,22 +65,58 @@ func NewCrypt(cfg *config.Config) (*Crypt, error) {
 	return instance, nil
 }
 
-// EncryptRSA Шифрование исходящих данных серверных публичным ключом
-func (crypt *Crypt) EncryptRSA(data []byte) ([]byte, error) {
-	return util.DataEncryptRSA(data, crypt.serverPublicKey)
-}
-
-// DecryptRSA Расшифровка входящих сообещний приватным ключом клиента
-func (crypt *Crypt) DecryptRSA(data []byte) ([]byte, error) {
-	return util.DataDecryptRSA(data, crypt.clientPrivateKey)
+// EncryptKey Шифрование ключа для передачи серверу. X25519, если при обмене сервер прислал ключ обмена, иначе RSA-OAEP
+func (crypt *Crypt) EncryptKey(data []byte) (string, []byte, error) {
+	exchangeKey, err := util.FillExchangePublicKeyFromFile(path.Join(crypt.cfg.Value().PathPublicKeyServer, keys.ExchangePublicKeyFileName))
+	if err == nil {
+		encrypted, err := util.DataEncryptX25519(data, exchangeKey)
+		return util.KeyExchangeX25519, encrypted, err
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return "", nil, err
+	}
+	// ключ сервера обновляется при каждом обмене ключами
+	crypt.serverPublicKey, err = util.FillPublicKeyFromFile(path.Join(crypt.cfg.Value().PathPublicKeyServer, keys.PublicKeyFileName))
+	if err != nil {
+		return "", nil, err
+	}
+	rsaKey, ok := crypt.serverPublicKey.(*rsa.PublicKey)
+	if !ok {
+		return "", nil, ErrUnsupportedKeyExchange
+	}
+	encrypted, err := util.DataEncryptRSA(data, rsaKey)
+	return util.KeyExchangeRSA, encrypted, err
 }
 
 // EncryptAES Шифрование исходящих данных
 func (crypt *Crypt) EncryptAES(data []byte) ([]byte, error) {
-	return util.DataEncryptAES(data, crypt.privateKeyForEncryption)
+	return util.DataEncryptAES(data, crypt.encryptionKey())
+}
+
+// SealRequest Шифрование тела запроса в конверт с временем, nonce, методом и путём запроса
+func (crypt *Crypt) SealRequest(method string, path string, data []byte) ([]byte, error) {
+	return util.SealRequest(data, crypt.encryptionKey(), method, path, time.Now())
 }
 
 // DecryptAES Расшифровка входящих сообещний
 func (crypt *Crypt) DecryptAES(data []byte) ([]byte, error) {
-	return util.DataDecryptAES(data, crypt.privateKeyForEncryption)
+	return util.DataDecryptAES(data, crypt.encryptionKey())
+}
+
+// ReloadEncryptionKey Перечитать ключ шифрования данных из файла (после смены ключа)
+func (crypt *Crypt) ReloadEncryptionKey() error {
+	key, err := os.ReadFile(path.Join(crypt.cfg.Value().PathKeys, keys.PrivateKeyFileNameForEncryption))
+	if err != nil {
+		return err
+	}
+	crypt.keyMx.Lock()
+	defer crypt.keyMx.Unlock()
+	crypt.privateKeyForEncryption = key
+	return nil
+}
+
+func (crypt *Crypt) encryptionKey() []byte {
+	crypt.keyMx.RLock()
+	defer crypt.keyMx.RUnlock()
+	return crypt.privateKeyForEncryption
 }
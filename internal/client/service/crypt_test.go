@@ -10,10 +10,12 @@ import (
 	"path"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/northmule/gophkeeper/internal/client/config"
 	"github.com/northmule/gophkeeper/internal/common/keys"
 	"github.com/northmule/gophkeeper/internal/common/keys/signers"
+	"github.com/northmule/gophkeeper/internal/common/util"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -131,7 +133,7 @@ func TestNewCrypt_EncryptionKeyFileError(t *testing.T) {
 	os.RemoveAll(mockCfg.Value().PathKeys)
 }
 
-func TestCrypt_EncryptRSA(t *testing.T) {
+func TestCrypt_EncryptKey(t *testing.T) {
 	mockCfg, _ := config.NewConfig()
 	mockCfg.Value().PathPublicKeyServer = path.Join("testpath")
 	mockCfg.Value().PathKeys = path.Join("testpath")
@@ -151,21 +153,38 @@ func TestCrypt_EncryptRSA(t *testing.T) {
 
 	defer os.RemoveAll("testpath")
 	crypt, _ := NewCrypt(mockCfg)
-	v, e := crypt.EncryptRSA([]byte("text"))
+
+	// без ключа обмена ключ шифруется RSA ключом сервера
+	scheme, v, e := crypt.EncryptKey([]byte("text"))
 	assert.NoError(t, e)
-	assert.NotEmpty(t, v)
+	assert.Equal(t, util.KeyExchangeRSA, scheme)
+	privateKey, _ := util.FillPrivateRsaKeyFromFile(filepath.Join("testpath", keys.PrivateKeyFileName))
+	decrypted, e := util.DataDecryptRSA(v, privateKey)
+	assert.NoError(t, e)
+	assert.Equal(t, "text", string(decrypted))
+
+	// сервер прислал ключ обмена
+	exchangeKey, _ := util.GenerateExchangeKey()
+	exchangePEM, _ := util.ExchangePublicKeyToPEM(exchangeKey.PublicKey())
+	os.WriteFile(filepath.Join("testpath", keys.ExchangePublicKeyFileName), exchangePEM, 0644)
+
+	scheme, v, e = crypt.EncryptKey([]byte("text"))
+	assert.NoError(t, e)
+	assert.Equal(t, util.KeyExchangeX25519, scheme)
+	decrypted, e = util.DataDecryptX25519(v, exchangeKey)
+	assert.NoError(t, e)
+	assert.Equal(t, "text", string(decrypted))
 }
-func TestCrypt_DecryptRSA(t *testing.T) {
+
+func TestCrypt_EncryptKey_UnsupportedServerKey(t *testing.T) {
 	mockCfg, _ := config.NewConfig()
-	mockCfg.Value().PathPublicKeyServer = path.Join("testpath")
-	mockCfg.Value().PathKeys = path.Join("testpath")
+	mockCfg.Value().PathPublicKeyServer = t.TempDir()
+	mockCfg.Value().PathKeys = mockCfg.Value().PathPublicKeyServer
 
-	os.MkdirAll(mockCfg.Value().PathKeys, 0755)
 	os.WriteFile(filepath.Join(mockCfg.Value().PathKeys, keys.PrivateKeyFileNameForEncryption), []byte("encryption_key"), 0644)
-
 	serialNumber, _ := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
 	kk := keys.NewKeys(keys.Options{
-		Generator:    signers.NewRsaSigner(),
+		Generator:    signers.NewEcdsaSigner(),
 		SavePath:     mockCfg.Value().PathKeys,
 		Organization: "Go32_client",
 		Country:      "RU",
@@ -173,12 +192,10 @@ func TestCrypt_DecryptRSA(t *testing.T) {
 	})
 	_ = kk.InitSelfSigned()
 
-	defer os.RemoveAll("testpath")
-	crypt, _ := NewCrypt(mockCfg)
-	vv, _ := crypt.EncryptRSA([]byte("text"))
-	v, e := crypt.DecryptRSA(vv)
-	assert.NoError(t, e)
-	assert.NotEmpty(t, v)
+	crypt, err := NewCrypt(mockCfg)
+	assert.NoError(t, err)
+	_, _, err = crypt.EncryptKey([]byte("text"))
+	assert.ErrorIs(t, err, ErrUnsupportedKeyExchange)
 }
 
 func TestCrypt_EncryptAES(t *testing.T) {
@@ -233,3 +250,51 @@ func TestCrypt_DecryptAES(t *testing.T) {
 	assert.NoError(t, e)
 	assert.NotEmpty(t, v)
 }
+
+func TestCrypt_ReloadEncryptionKey(t *testing.T) {
+	mockCfg, _ := config.NewConfig()
+	mockCfg.Value().PathPublicKeyServer = t.TempDir()
+	mockCfg.Value().PathKeys = mockCfg.Value().PathPublicKeyServer
+	keyPath := filepath.Join(mockCfg.Value().PathKeys, keys.PrivateKeyFileNameForEncryption)
+	_ = os.WriteFile(keyPath, make([]byte, 32), 0600)
+
+	serialNumber, _ := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	kk := keys.NewKeys(keys.Options{
+		Generator:    signers.NewRsaSigner(),
+		SavePath:     mockCfg.Value().PathKeys,
+		Organization: "Go32_client",
+		Country:      "RU",
+		SerialNumber: serialNumber,
+	})
+	_ = kk.InitSelfSigned()
+
+	crypt, err := NewCrypt(mockCfg)
+	assert.NoError(t, err)
+	old, _ := crypt.EncryptAES([]byte("text"))
+
+	// после смены ключа данные шифруются новым ключом
+	_ = os.WriteFile(keyPath, []byte(util.CreateHashForKey("new key")), 0600)
+	assert.NoError(t, crypt.ReloadEncryptionKey())
+	_, err = crypt.DecryptAES(old)
+	assert.Error(t, err)
+	v, _ := crypt.EncryptAES([]byte("text"))
+	plain, err := crypt.DecryptAES(v)
+	assert.NoError(t, err)
+	assert.Equal(t, "text", string(plain))
+
+	_ = os.Remove(keyPath)
+	assert.Error(t, crypt.ReloadEncryptionKey())
+}
+
+func TestCrypt_SealRequest(t *testing.T) {
+	key := make([]byte, 32)
+	crypt := &Crypt{privateKeyForEncryption: key}
+
+	sealed, err := crypt.SealRequest("POST", "/api/v1/save_card_data", []byte("text"))
+	assert.NoError(t, err)
+
+	envelope, body, err := util.OpenRequest(sealed, key, "POST", "/api/v1/save_card_data")
+	assert.NoError(t, err)
+	assert.Equal(t, "text", string(body))
+	assert.WithinDuration(t, time.Now(), envelope.Timestamp, time.Minute)
+}
@@ -0,0 +1,259 @@
+package service
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"sync"
+
+	"github.com/northmule/gophkeeper/internal/common/util"
+)
+
+// Данные пользователя шифруются на клиенте мастер-ключом, который выводится из мастер-пароля (Argon2id).
+// Сервер хранит только соль и контрольное значение, сам ключ клиент не покидает.
+// Транспортное шифрование (Cryptographer) выполняется поверх как отдельный слой.
+
+const (
+	// vaultPrefix признак значения, зашифрованного мастер-ключом
+	vaultPrefix = "gk1:"
+	// vaultCheckPhrase открытый текст контрольного значения
+	vaultCheckPhrase = "gophkeeper master key check"
+	// vaultNonceSize размер nonce AES-GCM в начале шифротекста
+	vaultNonceSize = 12
+)
+
+var (
+	// ErrVaultLocked мастер-пароль ещё не введён
+	ErrVaultLocked = errors.New("хранилище заблокировано, введите мастер-пароль")
+	// ErrWrongMasterPassword мастер-пароль не прошёл проверку
+	ErrWrongMasterPassword = errors.New("неверный мастер-пароль")
+	// ErrVaultPlaintext значение не зашифровано мастер-ключом. Клиент сохраняет только шифротекст,
+	// открытое значение мог подставить сервер
+	ErrVaultPlaintext = errors.New("данные не зашифрованы мастер-ключом")
+
+	errVaultCiphertextShort = errors.New("ciphertext too short")
+)
+
+// VaultCryptographer шифрование данных пользователя мастер-ключом
+type VaultCryptographer interface {
+	// EncryptString Шифрование строкового значения
+	EncryptString(value string) (string, error)
+	// DecryptString Расшифровка строкового значения
+	DecryptString(value string) (string, error)
+	// Encrypt Шифрование бинарных данных
+	Encrypt(data []byte) ([]byte, error)
+	// Decrypt Расшифровка бинарных данных
+	Decrypt(data []byte) ([]byte, error)
+}
+
+// Vaulter хранилище мастер-ключа
+type Vaulter interface {
+	VaultCryptographer
+	// Setup Первичная настройка мастер-ключа, вернёт соль и контрольное значение для сервера
+	Setup(masterPassword string) (salt string, check string, err error)
+	// Unlock Проверка мастер-пароля и разблокировка хранилища
+	Unlock(masterPassword string, salt string, check string) error
+	// Lock Сброс мастер-ключа
+	Lock()
+	// IsUnlocked Мастер-ключ получен
+	IsUnlocked() bool
+	// UnlockWithKey Разблокировка хранилища ключом из комплекта восстановления
+	UnlockWithKey(key []byte) error
+	// Key Копия мастер-ключа (для комплекта восстановления)
+	Key() ([]byte, error)
+	// SetLegacy Приём открытых значений, сохранённых до шифрования мастер-ключом (до их перешифрования)
+	SetLegacy(legacy bool)
+}
+
+// Vault хранилище мастер-ключа в памяти клиента
+type Vault struct {
+	mx     sync.RWMutex
+	key    []byte
+	legacy bool
+}
+
+// NewVault конструктор
+func NewVault() *Vault {
+	return &Vault{}
+}
+
+// Setup Первичная настройка мастер-ключа, вернёт соль и контрольное значение для сервера
+func (v *Vault) Setup(masterPassword string) (string, string, error) {
+	salt, err := util.NewMasterKeySalt()
+	if err != nil {
+		return "", "", err
+	}
+	key := util.DeriveMasterKey(masterPassword, salt)
+	check, err := util.DataEncryptAES([]byte(vaultCheckPhrase), key)
+	if err != nil {
+		return "", "", err
+	}
+
+	v.mx.Lock()
+	defer v.mx.Unlock()
+	v.key = key
+
+	return base64.StdEncoding.EncodeToString(salt), base64.StdEncoding.EncodeToString(check), nil
+}
+
+// Unlock Проверка мастер-пароля и разблокировка хранилища
+func (v *Vault) Unlock(masterPassword string, salt string, check string) error {
+	rawSalt, err := base64.StdEncoding.DecodeString(salt)
+	if err != nil {
+		return err
+	}
+	rawCheck, err := base64.StdEncoding.DecodeString(check)
+	if err != nil {
+		return err
+	}
+	key := util.DeriveMasterKey(masterPassword, rawSalt)
+	phrase, err := util.DataDecryptAES(rawCheck, key)
+	if err != nil || !bytes.Equal(phrase, []byte(vaultCheckPhrase)) {
+		return ErrWrongMasterPassword
+	}
+
+	v.mx.Lock()
+	defer v.mx.Unlock()
+	v.key = key
+
+	return nil
+}
+
+// Lock Сброс мастер-ключа
+func (v *Vault) Lock() {
+	v.mx.Lock()
+	defer v.mx.Unlock()
+	v.key = nil
+	v.legacy = false
+}
+
+// SetLegacy Приём открытых значений, сохранённых до шифрования мастер-ключом. Включается,
+// пока у пользователя есть такие данные, и выключается после их перешифрования
+func (v *Vault) SetLegacy(legacy bool) {
+	v.mx.Lock()
+	defer v.mx.Unlock()
+	v.legacy = legacy
+}
+
+// UnlockWithKey Разблокировка хранилища ключом из комплекта восстановления (без мастер-пароля)
+func (v *Vault) UnlockWithKey(key []byte) error {
+	if len(key) != util.MasterKeyLen {
+		return ErrWrongMasterPassword
+	}
+
+	v.mx.Lock()
+	defer v.mx.Unlock()
+	v.key = bytes.Clone(key)
+
+	return nil
+}
+
+// Key Копия мастер-ключа (для комплекта восстановления)
+func (v *Vault) Key() ([]byte, error) {
+	key, err := v.currentKey()
+	if err != nil {
+		return nil, err
+	}
+	return bytes.Clone(key), nil
+}
+
+// IsUnlocked Мастер-ключ получен
+func (v *Vault) IsUnlocked() bool {
+	v.mx.RLock()
+	defer v.mx.RUnlock()
+	return v.key != nil
+}
+
+// Encrypt Шифрование бинарных данных
+func (v *Vault) Encrypt(data []byte) ([]byte, error) {
+	key, err := v.currentKey()
+	if err != nil {
+		return nil, err
+	}
+	encrypted, err := util.DataEncryptAES(data, key)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(vaultPrefix), encrypted...), nil
+}
+
+// Decrypt Расшифровка бинарных данных, данные без признака шифрования принимаются только до перешифрования
+func (v *Vault) Decrypt(data []byte) ([]byte, error) {
+	key, err := v.currentKey()
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.HasPrefix(data, []byte(vaultPrefix)) {
+		if v.isLegacy() {
+			return data, nil
+		}
+		return nil, ErrVaultPlaintext
+	}
+	encrypted := data[len(vaultPrefix):]
+	if len(encrypted) < vaultNonceSize {
+		return nil, errVaultCiphertextShort
+	}
+	return util.DataDecryptAES(encrypted, key)
+}
+
+// EncryptString Шифрование строкового значения, пустые значения не шифруются
+func (v *Vault) EncryptString(value string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+	key, err := v.currentKey()
+	if err != nil {
+		return "", err
+	}
+	encrypted, err := util.DataEncryptAES([]byte(value), key)
+	if err != nil {
+		return "", err
+	}
+	return vaultPrefix + base64.StdEncoding.EncodeToString(encrypted), nil
+}
+
+// DecryptString Расшифровка строкового значения. Пустые значения не шифруются,
+// непустые значения без признака шифрования принимаются только до перешифрования
+func (v *Vault) DecryptString(value string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+	key, err := v.currentKey()
+	if err != nil {
+		return "", err
+	}
+	if !strings.HasPrefix(value, vaultPrefix) {
+		if v.isLegacy() {
+			return value, nil
+		}
+		return "", ErrVaultPlaintext
+	}
+	encrypted, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, vaultPrefix))
+	if err != nil {
+		return "", err
+	}
+	if len(encrypted) < vaultNonceSize {
+		return "", errVaultCiphertextShort
+	}
+	raw, err := util.DataDecryptAES(encrypted, key)
+	if err != nil {
+		return "", err
+	}
+	return string(raw), nil
+}
+
+func (v *Vault) isLegacy() bool {
+	v.mx.RLock()
+	defer v.mx.RUnlock()
+	return v.legacy
+}
+
+func (v *Vault) currentKey() ([]byte, error) {
+	v.mx.RLock()
+	defer v.mx.RUnlock()
+	if v.key == nil {
+		return nil, ErrVaultLocked
+	}
+	return v.key, nil
+}
@@ -0,0 +1,142 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVault_SetupAndUnlock(t *testing.T) {
+	vault := NewVault()
+	assert.False(t, vault.IsUnlocked())
+
+	salt, check, err := vault.Setup("master password")
+	require.NoError(t, err)
+	assert.NotEmpty(t, salt)
+	assert.NotEmpty(t, check)
+	assert.True(t, vault.IsUnlocked())
+
+	encrypted, err := vault.EncryptString("secret")
+	require.NoError(t, err)
+
+	other := NewVault()
+	err = other.Unlock("wrong password", salt, check)
+	assert.ErrorIs(t, err, ErrWrongMasterPassword)
+	assert.False(t, other.IsUnlocked())
+
+	err = other.Unlock("master password", salt, check)
+	require.NoError(t, err)
+	decrypted, err := other.DecryptString(encrypted)
+	require.NoError(t, err)
+	assert.Equal(t, "secret", decrypted)
+
+	other.Lock()
+	assert.False(t, other.IsUnlocked())
+	_, err = other.DecryptString(encrypted)
+	assert.ErrorIs(t, err, ErrVaultLocked)
+}
+
+func TestVault_UnlockWithKey(t *testing.T) {
+	vault := NewVault()
+	_, err := vault.Key()
+	assert.ErrorIs(t, err, ErrVaultLocked)
+
+	_, _, err = vault.Setup("master password")
+	require.NoError(t, err)
+	key, err := vault.Key()
+	require.NoError(t, err)
+	encrypted, err := vault.EncryptString("secret")
+	require.NoError(t, err)
+
+	// ключ из комплекта восстановления открывает те же данные без мастер-пароля
+	other := NewVault()
+	require.NoError(t, other.UnlockWithKey(key))
+	plain, err := other.DecryptString(encrypted)
+	require.NoError(t, err)
+	assert.Equal(t, "secret", plain)
+
+	assert.ErrorIs(t, other.UnlockWithKey([]byte("short")), ErrWrongMasterPassword)
+}
+
+func TestVault_EncryptString(t *testing.T) {
+	vault := NewVault()
+	_, err := vault.EncryptString("secret")
+	assert.ErrorIs(t, err, ErrVaultLocked)
+
+	_, _, err = vault.Setup("master password")
+	require.NoError(t, err)
+
+	encrypted, err := vault.EncryptString("secret")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(encrypted, vaultPrefix))
+	assert.NotContains(t, encrypted, "secret")
+
+	empty, err := vault.EncryptString("")
+	require.NoError(t, err)
+	assert.Empty(t, empty)
+
+	// открытое значение мог подставить сервер
+	_, err = vault.DecryptString("plain value")
+	assert.ErrorIs(t, err, ErrVaultPlaintext)
+
+	empty, err = vault.DecryptString("")
+	require.NoError(t, err)
+	assert.Empty(t, empty)
+
+	_, err = vault.DecryptString(vaultPrefix + "AAAA")
+	assert.Error(t, err)
+}
+
+func TestVault_Legacy(t *testing.T) {
+	vault := NewVault()
+	_, _, err := vault.Setup("master-password")
+	require.NoError(t, err)
+
+	// данные, сохранённые до шифрования мастер-ключом, читаются до перешифрования
+	vault.SetLegacy(true)
+	value, err := vault.DecryptString("plain value")
+	require.NoError(t, err)
+	assert.Equal(t, "plain value", value)
+	content, err := vault.Decrypt([]byte("plain content"))
+	require.NoError(t, err)
+	assert.Equal(t, "plain content", string(content))
+
+	sealed, err := vault.EncryptString("secret")
+	require.NoError(t, err)
+	value, err = vault.DecryptString(sealed)
+	require.NoError(t, err)
+	assert.Equal(t, "secret", value)
+
+	vault.SetLegacy(false)
+	_, err = vault.DecryptString("plain value")
+	assert.ErrorIs(t, err, ErrVaultPlaintext)
+
+	// признак сбрасывается вместе с ключом
+	vault.SetLegacy(true)
+	vault.Lock()
+	_, _, err = vault.Setup("master-password")
+	require.NoError(t, err)
+	_, err = vault.Decrypt([]byte("plain content"))
+	assert.ErrorIs(t, err, ErrVaultPlaintext)
+}
+
+func TestVault_EncryptBytes(t *testing.T) {
+	vault := NewVault()
+	_, _, err := vault.Setup("master password")
+	require.NoError(t, err)
+
+	encrypted, err := vault.Encrypt([]byte("file content"))
+	require.NoError(t, err)
+
+	decrypted, err := vault.Decrypt(encrypted)
+	require.NoError(t, err)
+	assert.Equal(t, "file content", string(decrypted))
+
+	_, err = vault.Decrypt([]byte("plain content"))
+	assert.ErrorIs(t, err, ErrVaultPlaintext)
+
+	_, err = vault.Decrypt([]byte(vaultPrefix + "short"))
+	assert.Error(t, err)
+}
@@ -2,13 +2,16 @@ package storage
 
 import (
 	"sync"
+	"time"
 
 	"github.com/northmule/gophkeeper/internal/common/models"
 )
 
 // MemoryStorage хранилище данных на время запуска
 type MemoryStorage struct {
-	token string // текущий токен авторизации
+	token        string    // текущий токен авторизации
+	refreshToken string    // токен для обновления токена авторизации
+	tokenExpire  time.Time // окончание действия токена авторизации
 
 	// данные синхронизации, ключами явлюятся uuid этих данных
 	cardDataList map[string]models.CardData
@@ -16,7 +19,7 @@ type MemoryStorage struct {
 	textDataList map[string]models.TextData
 	fileDataList map[string]models.FileData
 
-	mx sync.RWMutex
+	mx sync.RWMutex // токен читается и потоком событий
 }
 
 // NewMemoryStorage конструктор
@@ -33,17 +36,41 @@ func NewMemoryStorage() *MemoryStorage {
 
 // SetToken добавить токен
 func (s *MemoryStorage) SetToken(token string) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
 	s.token = token
 }
 
 // Token значение токена
 func (s *MemoryStorage) Token() string {
+	s.mx.RLock()
+	defer s.mx.RUnlock()
 	return s.token
 }
 
+// SetRefreshToken добавить refresh токен и время окончания действия токена авторизации
+func (s *MemoryStorage) SetRefreshToken(refreshToken string, tokenExpire time.Time) {
+	s.refreshToken = refreshToken
+	s.tokenExpire = tokenExpire
+}
+
+// RefreshToken значение refresh токена
+func (s *MemoryStorage) RefreshToken() string {
+	return s.refreshToken
+}
+
+// TokenExpire окончание действия токена авторизации
+func (s *MemoryStorage) TokenExpire() time.Time {
+	return s.tokenExpire
+}
+
 // ResetToken сбросить токен
 func (s *MemoryStorage) ResetToken() {
+	s.mx.Lock()
+	defer s.mx.Unlock()
 	s.token = ""
+	s.refreshToken = ""
+	s.tokenExpire = time.Time{}
 }
 
 // AddCardDataList добавляет или заменяет данные
@@ -2,6 +2,7 @@ package storage
 
 import (
 	"testing"
+	"time"
 
 	"github.com/northmule/gophkeeper/internal/common/models"
 	"github.com/stretchr/testify/assert"
@@ -26,8 +27,19 @@ func TestMemoryStorage_SetToken(t *testing.T) {
 func TestMemoryStorage_ResetToken(t *testing.T) {
 	storage := NewMemoryStorage()
 	storage.SetToken("test_token")
+	storage.SetRefreshToken("refresh_token", time.Now())
 	storage.ResetToken()
 	assert.Empty(t, storage.Token())
+	assert.Empty(t, storage.RefreshToken())
+	assert.True(t, storage.TokenExpire().IsZero())
+}
+
+func TestMemoryStorage_SetRefreshToken(t *testing.T) {
+	storage := NewMemoryStorage()
+	expire := time.Now().Add(time.Minute)
+	storage.SetRefreshToken("refresh_token", expire)
+	assert.Equal(t, "refresh_token", storage.RefreshToken())
+	assert.Equal(t, expire, storage.TokenExpire())
 }
 
 func TestMemoryStorage_AddCardDataList_Success(t *testing.T) {
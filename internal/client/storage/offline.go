@@ -0,0 +1,324 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/northmule/gophkeeper/internal/common/model_data"
+	"github.com/northmule/gophkeeper/internal/common/util"
+)
+
+// Копия хранилища на диске: список данных и просмотренные данные в расшифрованном виде. Очередь изменений
+// хранится рядом в своём файле: копию можно получить с сервера заново, очередь - нет.
+// Файлы целиком шифруются мастер-ключом, открыто хранятся только соль и контрольное значение мастер-ключа,
+// по ним хранилище разблокируется мастер-паролем без сервера. Файл, который не расшифровывается ключом,
+// не перезаписывается, а сохраняется под другим именем.
+
+var (
+	// offlineAdditionalData дополнительные данные AEAD копии хранилища
+	offlineAdditionalData = []byte("gophkeeper offline vault")
+	// outboxAdditionalData дополнительные данные AEAD очереди изменений
+	outboxAdditionalData = []byte("gophkeeper offline outbox")
+)
+
+// outboxFileSuffix окончание имени файла очереди изменений рядом с копией хранилища
+const outboxFileSuffix = "_outbox"
+
+var (
+	// ErrOfflineVaultNotFound копия хранилища ещё не создавалась
+	ErrOfflineVaultNotFound = errors.New("локальная копия хранилища не найдена, войдите в клиент при доступном сервере")
+	// ErrOfflineVaultClosed копия хранилища не открыта мастер-ключом
+	ErrOfflineVaultClosed = errors.New("локальная копия хранилища не открыта")
+	// ErrOfflineItemNotFound данные не открывались на этом устройстве
+	ErrOfflineItemNotFound = errors.New("данные не сохранены в локальной копии, откройте их при доступном сервере")
+	// ErrOfflineVaultUnreadable файл копии не расшифрован мастер-ключом (другой ключ или повреждение),
+	// файл сохранён под другим именем, при следующем открытии создаётся новый
+	ErrOfflineVaultUnreadable = errors.New("локальная копия не расшифрована мастер-ключом, прежний файл сохранён")
+)
+
+// offlineFile файл копии хранилища
+type offlineFile struct {
+	Salt  string `json:"salt"`  // соль мастер-ключа
+	Check string `json:"check"` // контрольное значение мастер-ключа
+	Data  []byte `json:"data"`  // offlineData в конверте, зашифрованном мастер-ключом
+}
+
+// offlineData содержимое копии хранилища
+type offlineData struct {
+	Items    []model_data.ItemDataResponse            `json:"items"`
+	Data     map[string]model_data.DataByUUIDResponse `json:"data"`
+	SyncedAt int64                                    `json:"synced_at"`        // unix время получения списка с сервера
+	Outbox   []OutboxEntry                            `json:"outbox,omitempty"` // очередь изменений прежних версий клиента, переносится в файл очереди
+	Cursor   string                                   `json:"cursor"`           // курсор синхронизации списка с сервером
+}
+
+// OfflineVault зашифрованная копия хранилища для просмотра данных без сервера
+type OfflineVault struct {
+	path       string
+	outboxPath string
+	key        []byte
+	salt       string
+	check      string
+	data       offlineData
+	outbox     []OutboxEntry
+
+	mx sync.RWMutex
+}
+
+// NewOfflineVault конструктор
+func NewOfflineVault(path string) *OfflineVault {
+	return &OfflineVault{path: path, outboxPath: path + outboxFileSuffix}
+}
+
+// SetParams параметры мастер-ключа, записываются в файл вместе с данными
+func (s *OfflineVault) SetParams(salt string, check string) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	s.salt = salt
+	s.check = check
+}
+
+// Params параметры мастер-ключа из файла для разблокировки без сервера
+func (s *OfflineVault) Params() (string, string, error) {
+	file, err := s.read()
+	if err != nil {
+		return "", "", err
+	}
+	if file.Salt == "" {
+		return "", "", ErrOfflineVaultNotFound
+	}
+	return file.Salt, file.Check, nil
+}
+
+// Open открывает копию и очередь изменений мастер-ключом. Отсутствующие файлы создаются. Файл, который
+// не расшифровывается ключом, сохраняется под другим именем и не перезаписывается: Open вернёт
+// ErrOfflineVaultUnreadable, следующее открытие создаст новый файл
+func (s *OfflineVault) Open(key []byte) error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	data := offlineData{}
+	file, err := openFile(s.path, key, offlineAdditionalData, &data)
+	var outbox []OutboxEntry
+	_, outboxErr := openFile(s.outboxPath, key, outboxAdditionalData, &outbox)
+	if err != nil || outboxErr != nil {
+		return errors.Join(err, outboxErr)
+	}
+
+	if file != nil && s.salt == "" {
+		s.salt, s.check = file.Salt, file.Check
+	}
+	s.data = data
+	if s.data.Data == nil {
+		s.data.Data = make(map[string]model_data.DataByUUIDResponse)
+	}
+	// очередь из копии прежних версий клиента переносится в файл очереди до перезаписи копии
+	for _, entry := range s.data.Outbox {
+		if !slices.ContainsFunc(outbox, func(current OutboxEntry) bool { return current.Key == entry.Key }) {
+			outbox = append(outbox, entry)
+		}
+	}
+	s.data.Outbox = nil
+	s.outbox = outbox
+	s.key = bytes.Clone(key)
+
+	err = s.writeOutbox()
+	if err == nil {
+		err = s.write()
+	}
+	if err != nil {
+		s.key = nil
+		return err
+	}
+	return nil
+}
+
+// Close сбрасывает ключ и расшифрованные данные, файл остаётся на диске
+func (s *OfflineVault) Close() {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	s.key = nil
+	s.salt = ""
+	s.check = ""
+	s.data = offlineData{}
+	s.outbox = nil
+}
+
+// IsOpen копия открыта мастер-ключом
+func (s *OfflineVault) IsOpen() bool {
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+	return s.key != nil
+}
+
+// Cursor курсор синхронизации списка, пустой - список ещё не получен с сервера
+func (s *OfflineVault) Cursor() (string, error) {
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+	if s.key == nil {
+		return "", ErrOfflineVaultClosed
+	}
+	return s.data.Cursor, nil
+}
+
+// ApplySync применяет изменения списка, полученные с сервера, и запоминает курсор.
+// full - изменения от пустого курсора, прежний список заменяется. Расшифрованные данные изменённых
+// и удалённых элементов удаляются из копии
+func (s *OfflineVault) ApplySync(changed []model_data.ItemDataResponse, deleted []string, cursor string, full bool) error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	if s.key == nil {
+		return ErrOfflineVaultClosed
+	}
+	if full {
+		for dataUUID := range s.data.Data {
+			if !slices.ContainsFunc(changed, func(item model_data.ItemDataResponse) bool { return item.UUID == dataUUID }) {
+				delete(s.data.Data, dataUUID)
+			}
+		}
+		s.data.Items = nil
+	}
+	for _, item := range changed {
+		index := slices.IndexFunc(s.data.Items, func(current model_data.ItemDataResponse) bool { return current.UUID == item.UUID })
+		if index < 0 {
+			s.data.Items = append(s.data.Items, item)
+			continue
+		}
+		if s.data.Items[index].Revision != item.Revision {
+			delete(s.data.Data, item.UUID)
+		}
+		s.data.Items[index] = item
+	}
+	s.data.Items = slices.DeleteFunc(s.data.Items, func(item model_data.ItemDataResponse) bool {
+		return slices.Contains(deleted, item.UUID)
+	})
+	for _, dataUUID := range deleted {
+		delete(s.data.Data, dataUUID)
+	}
+	for i := range s.data.Items {
+		s.data.Items[i].Number = strconv.Itoa(i + 1)
+	}
+	s.data.Cursor = cursor
+	s.data.SyncedAt = time.Now().Unix()
+	return s.write()
+}
+
+// List список данных и время его получения с сервера
+func (s *OfflineVault) List() ([]model_data.ItemDataResponse, time.Time, error) {
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+	if s.key == nil {
+		return nil, time.Time{}, ErrOfflineVaultClosed
+	}
+	return slices.Clone(s.data.Items), time.Unix(s.data.SyncedAt, 0), nil
+}
+
+// SaveItem сохраняет расшифрованные данные
+func (s *OfflineVault) SaveItem(dataUUID string, data model_data.DataByUUIDResponse) error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	if s.key == nil {
+		return ErrOfflineVaultClosed
+	}
+	s.data.Data[dataUUID] = data
+	return s.write()
+}
+
+// Item данные по uuid
+func (s *OfflineVault) Item(dataUUID string) (*model_data.DataByUUIDResponse, error) {
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+	if s.key == nil {
+		return nil, ErrOfflineVaultClosed
+	}
+	data, ok := s.data.Data[dataUUID]
+	if !ok {
+		return nil, ErrOfflineItemNotFound
+	}
+	return &data, nil
+}
+
+// read чтение файла без расшифровки данных
+func (s *OfflineVault) read() (*offlineFile, error) {
+	raw, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrOfflineVaultNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	file := new(offlineFile)
+	err = json.Unmarshal(raw, file)
+	if err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+// write запись копии
+func (s *OfflineVault) write() error {
+	return writeFile(s.path, s.key, offlineAdditionalData, s.salt, s.check, s.data)
+}
+
+// writeOutbox запись очереди изменений
+func (s *OfflineVault) writeOutbox() error {
+	return writeFile(s.outboxPath, s.key, outboxAdditionalData, "", "", s.outbox)
+}
+
+// openFile чтение и расшифровка файла в value, отсутствующий файл оставляет value пустым.
+// Файл, который не расшифровывается ключом, переименовывается (ErrOfflineVaultUnreadable)
+func openFile(filePath string, key []byte, additionalData []byte, value any) (*offlineFile, error) {
+	raw, err := os.ReadFile(filePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	file := new(offlineFile)
+	err = json.Unmarshal(raw, file)
+	if err == nil {
+		raw, err = util.OpenEnvelope(file.Data, key, additionalData)
+	}
+	if err == nil {
+		err = json.Unmarshal(raw, value)
+	}
+	if err != nil {
+		backupPath := fmt.Sprintf("%s.%d.bak", filePath, time.Now().UnixNano())
+		renameErr := os.Rename(filePath, backupPath)
+		if renameErr != nil {
+			return nil, renameErr
+		}
+		return nil, fmt.Errorf("%w: %s", ErrOfflineVaultUnreadable, backupPath)
+	}
+	return file, nil
+}
+
+// writeFile запись файла через временный файл, чтобы прерванная запись не испортила прежний
+func writeFile(filePath string, key []byte, additionalData []byte, salt string, check string, value any) error {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	sealed, err := util.SealEnvelope(raw, key, util.CipherAES256GCM, "vault", additionalData)
+	if err != nil {
+		return err
+	}
+	raw, err = json.Marshal(offlineFile{Salt: salt, Check: check, Data: sealed})
+	if err != nil {
+		return err
+	}
+	tmpPath := filePath + ".tmp"
+	err = os.WriteFile(tmpPath, raw, 0600)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, filePath)
+}
@@ -0,0 +1,161 @@
+package storage
+
+import (
+	"bytes"
+	"os"
+	"path"
+	"path/filepath"
+	"testing"
+
+	"github.com/northmule/gophkeeper/internal/common/model_data"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOfflineVault_SaveAndOpen(t *testing.T) {
+	filePath := path.Join(t.TempDir(), "offline_vault")
+	key := bytes.Repeat([]byte{1}, 32)
+
+	store := NewOfflineVault(filePath)
+	_, _, err := store.Params()
+	assert.ErrorIs(t, err, ErrOfflineVaultNotFound)
+	assert.ErrorIs(t, store.ApplySync(nil, nil, "", true), ErrOfflineVaultClosed)
+
+	store.SetParams("salt", "check")
+	require.NoError(t, store.Open(key))
+	require.NoError(t, store.ApplySync([]model_data.ItemDataResponse{{Name: "note", UUID: "text-uuid"}, {Name: "card", UUID: "card-uuid"}}, nil, "2", true))
+	require.NoError(t, store.SaveItem("text-uuid", model_data.DataByUUIDResponse{IsText: true, TextData: model_data.TextDataRequest{Value: "secret text"}}))
+	require.NoError(t, store.SaveItem("card-uuid", model_data.DataByUUIDResponse{IsCard: true}))
+
+	// данные на диске зашифрованы, параметры мастер-ключа открыты
+	raw, err := os.ReadFile(filePath)
+	require.NoError(t, err)
+	assert.NotContains(t, string(raw), "secret text")
+	assert.NotContains(t, string(raw), "text-uuid")
+	salt, check, err := store.Params()
+	require.NoError(t, err)
+	assert.Equal(t, "salt", salt)
+	assert.Equal(t, "check", check)
+
+	store.Close()
+	_, _, err = store.List()
+	assert.ErrorIs(t, err, ErrOfflineVaultClosed)
+
+	reopened := NewOfflineVault(filePath)
+	require.NoError(t, reopened.Open(key))
+	items, syncedAt, err := reopened.List()
+	require.NoError(t, err)
+	assert.Len(t, items, 2)
+	assert.False(t, syncedAt.IsZero())
+	item, err := reopened.Item("text-uuid")
+	require.NoError(t, err)
+	assert.Equal(t, "secret text", item.TextData.Value)
+
+	// данные, удалённые на сервере, удаляются из копии
+	require.NoError(t, reopened.ApplySync([]model_data.ItemDataResponse{{Name: "note", UUID: "text-uuid"}}, nil, "3", true))
+	_, err = reopened.Item("card-uuid")
+	assert.ErrorIs(t, err, ErrOfflineItemNotFound)
+	// параметры сохранились при записи без SetParams
+	salt, _, err = reopened.Params()
+	require.NoError(t, err)
+	assert.Equal(t, "salt", salt)
+}
+
+func TestOfflineVault_OpenWithOtherKey(t *testing.T) {
+	filePath := path.Join(t.TempDir(), "offline_vault")
+
+	store := NewOfflineVault(filePath)
+	require.NoError(t, store.Open(bytes.Repeat([]byte{1}, 32)))
+	require.NoError(t, store.SaveItem("text-uuid", model_data.DataByUUIDResponse{IsText: true}))
+	require.NoError(t, store.Enqueue(OutboxEntry{Key: "key-1"}))
+	store.Close()
+
+	// копия другого ключа не расшифровывается и не перезаписывается: файлы сохраняются под другим именем
+	err := store.Open(bytes.Repeat([]byte{2}, 32))
+	assert.ErrorIs(t, err, ErrOfflineVaultUnreadable)
+	assert.False(t, store.IsOpen())
+	assert.NoFileExists(t, filePath)
+	backups, _ := filepath.Glob(filePath + ".*.bak")
+	assert.Len(t, backups, 1)
+	backups, _ = filepath.Glob(filePath + outboxFileSuffix + ".*.bak")
+	require.Len(t, backups, 1)
+
+	// прежний файл очереди открывается прежним ключом
+	restored := NewOfflineVault(path.Join(t.TempDir(), "offline_vault"))
+	require.NoError(t, os.Rename(backups[0], restored.outboxPath))
+	require.NoError(t, restored.Open(bytes.Repeat([]byte{1}, 32)))
+	entries, err := restored.Outbox()
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "key-1", entries[0].Key)
+
+	// следующее открытие создаёт новую копию
+	require.NoError(t, store.Open(bytes.Repeat([]byte{2}, 32)))
+	_, err = store.Item("text-uuid")
+	assert.ErrorIs(t, err, ErrOfflineItemNotFound)
+}
+
+func TestOfflineVault_OpenLegacyOutbox(t *testing.T) {
+	filePath := path.Join(t.TempDir(), "offline_vault")
+	key := bytes.Repeat([]byte{1}, 32)
+
+	// очередь прежних версий клиента хранилась в файле копии
+	legacy := offlineData{Outbox: []OutboxEntry{{Key: "key-1", Name: "note"}}}
+	require.NoError(t, writeFile(filePath, key, offlineAdditionalData, "salt", "check", legacy))
+
+	store := NewOfflineVault(filePath)
+	require.NoError(t, store.Open(key))
+	entries, err := store.Outbox()
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "key-1", entries[0].Key)
+	store.Close()
+
+	// очередь перенесена в свой файл и не повторяется при следующем открытии
+	assert.FileExists(t, filePath+outboxFileSuffix)
+	require.NoError(t, store.Open(key))
+	entries, err = store.Outbox()
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
+
+func TestOfflineVault_ApplySync(t *testing.T) {
+	store := NewOfflineVault(path.Join(t.TempDir(), "offline_vault"))
+	_, err := store.Cursor()
+	assert.ErrorIs(t, err, ErrOfflineVaultClosed)
+	require.NoError(t, store.Open(bytes.Repeat([]byte{1}, 32)))
+	cursor, err := store.Cursor()
+	require.NoError(t, err)
+	assert.Empty(t, cursor)
+
+	require.NoError(t, store.ApplySync([]model_data.ItemDataResponse{
+		{Name: "note", UUID: "text-uuid", Revision: 1},
+		{Name: "card", UUID: "card-uuid", Revision: 2},
+		{Name: "mail", UUID: "credential-uuid", Revision: 3},
+	}, nil, "3", true))
+	require.NoError(t, store.SaveItem("text-uuid", model_data.DataByUUIDResponse{IsText: true}))
+	require.NoError(t, store.SaveItem("credential-uuid", model_data.DataByUUIDResponse{IsCredential: true}))
+
+	// изменение после курсора: новое имя, удаление и новые данные
+	require.NoError(t, store.ApplySync([]model_data.ItemDataResponse{
+		{Name: "note 2", UUID: "text-uuid", Revision: 4},
+		{Name: "file", UUID: "file-uuid", Revision: 6},
+	}, []string{"card-uuid"}, "6", false))
+
+	items, _, err := store.List()
+	require.NoError(t, err)
+	require.Len(t, items, 3)
+	assert.Equal(t, "note 2", items[0].Name)
+	assert.Equal(t, "credential-uuid", items[1].UUID)
+	assert.Equal(t, "2", items[1].Number)
+	assert.Equal(t, "file-uuid", items[2].UUID)
+	assert.Equal(t, "3", items[2].Number)
+	// расшифрованные данные изменённого элемента устарели
+	_, err = store.Item("text-uuid")
+	assert.ErrorIs(t, err, ErrOfflineItemNotFound)
+	_, err = store.Item("credential-uuid")
+	assert.NoError(t, err)
+	cursor, err = store.Cursor()
+	require.NoError(t, err)
+	assert.Equal(t, "6", cursor)
+}
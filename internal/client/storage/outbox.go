@@ -0,0 +1,77 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"slices"
+)
+
+// ErrOutboxEntryNotFound операции нет в очереди
+var ErrOutboxEntryNotFound = errors.New("операция не найдена в очереди изменений")
+
+// OutboxEntry операция сохранения, не отправленная из-за недоступности сервера.
+// Запрос хранится до шифрования мастер-ключом, файл очереди зашифрован целиком
+type OutboxEntry struct {
+	Key       string          `json:"key"`                 // ключ идемпотентности, один для всех повторов операции
+	Type      string          `json:"type"`                // тип данных (data_type)
+	Name      string          `json:"name"`                // название данных для вывода
+	Request   json.RawMessage `json:"request"`             // запрос сохранения
+	FilePath  string          `json:"file_path,omitempty"` // путь к отправляемому файлу
+	Error     string          `json:"error,omitempty"`     // ошибка последнего повтора
+	CreatedAt int64           `json:"created_at"`          // unix время постановки в очередь
+}
+
+// Enqueue добавляет операцию в конец очереди
+func (s *OfflineVault) Enqueue(entry OutboxEntry) error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	if s.key == nil {
+		return ErrOfflineVaultClosed
+	}
+	s.outbox = append(s.outbox, entry)
+	return s.writeOutbox()
+}
+
+// Outbox очередь изменений в порядке постановки
+func (s *OfflineVault) Outbox() ([]OutboxEntry, error) {
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+	if s.key == nil {
+		return nil, ErrOfflineVaultClosed
+	}
+	return slices.Clone(s.outbox), nil
+}
+
+// Dequeue удаляет операцию из очереди
+func (s *OfflineVault) Dequeue(key string) error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	if s.key == nil {
+		return ErrOfflineVaultClosed
+	}
+	index := s.outboxIndex(key)
+	if index < 0 {
+		return ErrOutboxEntryNotFound
+	}
+	s.outbox = slices.Delete(s.outbox, index, index+1)
+	return s.writeOutbox()
+}
+
+// SetOutboxError запоминает ошибку повтора операции, пустая строка сбрасывает ошибку
+func (s *OfflineVault) SetOutboxError(key string, message string) error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	if s.key == nil {
+		return ErrOfflineVaultClosed
+	}
+	index := s.outboxIndex(key)
+	if index < 0 {
+		return ErrOutboxEntryNotFound
+	}
+	s.outbox[index].Error = message
+	return s.writeOutbox()
+}
+
+func (s *OfflineVault) outboxIndex(key string) int {
+	return slices.IndexFunc(s.outbox, func(entry OutboxEntry) bool { return entry.Key == key })
+}
@@ -0,0 +1,48 @@
+package storage
+
+import (
+	"bytes"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/northmule/gophkeeper/internal/common/data_type"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOfflineVault_Outbox(t *testing.T) {
+	filePath := path.Join(t.TempDir(), "offline_vault")
+	key := bytes.Repeat([]byte{1}, 32)
+
+	store := NewOfflineVault(filePath)
+	assert.ErrorIs(t, store.Enqueue(OutboxEntry{Key: "key-1"}), ErrOfflineVaultClosed)
+
+	require.NoError(t, store.Open(key))
+	require.NoError(t, store.Enqueue(OutboxEntry{Key: "key-1", Type: data_type.TextType, Name: "note", Request: []byte(`{"value":"secret text"}`)}))
+	require.NoError(t, store.Enqueue(OutboxEntry{Key: "key-2", Type: data_type.CardType, Name: "card"}))
+	require.NoError(t, store.SetOutboxError("key-2", "ошибка в запросе"))
+	assert.ErrorIs(t, store.SetOutboxError("key-3", ""), ErrOutboxEntryNotFound)
+
+	// очередь хранится в своём зашифрованном файле
+	raw, err := os.ReadFile(filePath + outboxFileSuffix)
+	require.NoError(t, err)
+	assert.NotContains(t, string(raw), "secret text")
+
+	// очередь переживает перезапуск клиента
+	store.Close()
+	reopened := NewOfflineVault(filePath)
+	require.NoError(t, reopened.Open(key))
+	entries, err := reopened.Outbox()
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "key-1", entries[0].Key)
+	assert.Equal(t, "ошибка в запросе", entries[1].Error)
+
+	require.NoError(t, reopened.Dequeue("key-1"))
+	assert.ErrorIs(t, reopened.Dequeue("key-1"), ErrOutboxEntryNotFound)
+	entries, err = reopened.Outbox()
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "key-2", entries[0].Key)
+}
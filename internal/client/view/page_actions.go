@@ -30,8 +30,8 @@ func (m *pageAction) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		k := msg.String()
 		if k == "down" || k == "tab" {
 			m.Choice++
-			if m.Choice > 4 {
-				m.Choice = 4
+			if m.Choice > 10 {
+				m.Choice = 10
 			}
 		}
 		if k == "up" {
@@ -49,16 +49,40 @@ func (m *pageAction) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				return newPageTextData(m.mainPage), nil
 			}
 			if m.Choice == 2 {
+				return newPageCredentialData(m.mainPage), nil
+			}
+			if m.Choice == 3 {
 				p := newPageFileData(m.mainPage)
 				return p, p.Init()
 			}
-			if m.Choice == 3 {
+			if m.Choice == 4 {
 				return newPageDataGrid(m.mainPage, m), nil
 			}
+			if m.Choice == 5 {
+				return newPageKeyRotation(m.mainPage, m), nil
+			}
+			if m.Choice == 6 {
+				return newPageAudit(m.mainPage, m), nil
+			}
+			if m.Choice == 7 {
+				return newPageDevices(m.mainPage, m), nil
+			}
+			if m.Choice == 8 {
+				kit, err := m.mainPage.managerController.Recovery().CreateKit(m.mainPage.accessToken())
+				if err != nil {
+					return newPageRecoveryKit(m.mainPage, nil, err.Error()), nil
+				}
+				return newPageRecoveryKit(m.mainPage, kit, ""), nil
+			}
+
+			if m.Choice == 9 {
+				return newPageOutbox(m.mainPage, m), nil
+			}
 
 			// выход
-			if m.Choice == 4 {
-				m.mainPage.storage.ResetToken()
+			if m.Choice == 10 {
+				m.mainPage.logout()
+				m.mainPage.managerController.MasterKey().Lock()
 				return m.mainPage, nil
 			}
 
@@ -78,12 +102,18 @@ func (m *pageAction) View() string {
 		subtleStyle.Render("enter: выбрать")
 
 	choices := fmt.Sprintf(
-		"%s\n%s\n%s\n%s\n\n%s\n",
+		"%s\n%s\n%s\n%s\n%s\n%s\n%s\n%s\n%s\n%s\n\n%s\n",
 		renderCheckbox("Добавить данные банковских карт", c == 0),
 		renderCheckbox("Добавить произвольные текстовые данные", c == 1),
-		renderCheckbox("Добавить бинарные данные", c == 2),
-		renderCheckbox("Показать мои данные", c == 3),
-		renderCheckbox("Выйти", c == 4),
+		renderCheckbox("Добавить логин/пароль", c == 2),
+		renderCheckbox("Добавить бинарные данные", c == 3),
+		renderCheckbox("Показать мои данные", c == 4),
+		renderCheckbox("Сменить ключ шифрования", c == 5),
+		renderCheckbox("Журнал действий", c == 6),
+		renderCheckbox("Устройства", c == 7),
+		renderCheckbox("Новый комплект восстановления", c == 8),
+		renderCheckbox("Очередь изменений", c == 9),
+		renderCheckbox("Выйти", c == 10),
 	)
 
 	s := fmt.Sprintf(tpl, choices)
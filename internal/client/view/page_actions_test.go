@@ -20,7 +20,7 @@ func TestPageAction_Init(t *testing.T) {
 	mockCfg.Value().PathKeys = path.Join("testpath")
 	log, _ := logger.NewLogger("info")
 	cryptService, _ := service.NewCrypt(mockCfg)
-	manager, _ := controller.NewManager(mockCfg, cryptService, log)
+	manager, _ := controller.NewManager(mockCfg, cryptService, service.NewVault(), log)
 	memoryStorage := storage.NewMemoryStorage()
 	mainPage := newPageIndex(manager, memoryStorage, log)
 
@@ -46,7 +46,7 @@ func TestPageAction_Update(t *testing.T) {
 	mockCfg.Value().PathKeys = path.Join("testpath")
 	log, _ := logger.NewLogger("info")
 	cryptService, _ := service.NewCrypt(mockCfg)
-	manager, _ := controller.NewManager(mockCfg, cryptService, log)
+	manager, _ := controller.NewManager(mockCfg, cryptService, service.NewVault(), log)
 	memoryStorage := storage.NewMemoryStorage()
 	mainPage := newPageIndex(manager, memoryStorage, log)
 
@@ -82,6 +82,55 @@ func TestPageAction_Update(t *testing.T) {
 		m, _ := pa.Update(msg)
 		assert.NotNil(t, m)
 	})
+	t.Run("choice 5", func(t *testing.T) {
+		pa := pageAction{Choice: 5, mainPage: mainPage}
+		msg := tea.KeyMsg{Type: tea.KeyEnter}
+		m, _ := pa.Update(msg)
+		assert.IsType(t, &pageKeyRotation{}, m)
+	})
+	t.Run("choice 6", func(t *testing.T) {
+		// сервер недоступен, журнал пуст
+		pa := pageAction{Choice: 6, mainPage: mainPage}
+		msg := tea.KeyMsg{Type: tea.KeyEnter}
+		m, _ := pa.Update(msg)
+		auditPage, ok := m.(*pageAudit)
+		assert.True(t, ok)
+		assert.NotEmpty(t, auditPage.responseMessage)
+	})
+	t.Run("choice 7", func(t *testing.T) {
+		// сервер недоступен, список устройств пуст
+		pa := pageAction{Choice: 7, mainPage: mainPage}
+		msg := tea.KeyMsg{Type: tea.KeyEnter}
+		m, _ := pa.Update(msg)
+		devicesPage, ok := m.(*pageDevices)
+		assert.True(t, ok)
+		assert.NotEmpty(t, devicesPage.responseMessage)
+	})
+	t.Run("choice 8", func(t *testing.T) {
+		// хранилище заблокировано, комплект не создаётся
+		pa := pageAction{Choice: 8, mainPage: mainPage}
+		msg := tea.KeyMsg{Type: tea.KeyEnter}
+		m, _ := pa.Update(msg)
+		kitPage, ok := m.(*pageRecoveryKit)
+		assert.True(t, ok)
+		assert.Nil(t, kitPage.kit)
+		assert.NotEmpty(t, kitPage.responseMessage)
+	})
+	t.Run("choice 9", func(t *testing.T) {
+		// локальная копия хранилища не открыта, очередь недоступна
+		pa := pageAction{Choice: 9, mainPage: mainPage}
+		msg := tea.KeyMsg{Type: tea.KeyEnter}
+		m, _ := pa.Update(msg)
+		outboxPage, ok := m.(*pageOutbox)
+		assert.True(t, ok)
+		assert.NotEmpty(t, outboxPage.responseMessage)
+	})
+	t.Run("choice 10", func(t *testing.T) {
+		pa := pageAction{Choice: 10, mainPage: mainPage}
+		msg := tea.KeyMsg{Type: tea.KeyEnter}
+		m, _ := pa.Update(msg)
+		assert.NotNil(t, m)
+	})
 }
 
 func TestPageAction_View(t *testing.T) {
@@ -90,7 +139,7 @@ func TestPageAction_View(t *testing.T) {
 	mockCfg.Value().PathKeys = path.Join("testpath")
 	log, _ := logger.NewLogger("info")
 	cryptService, _ := service.NewCrypt(mockCfg)
-	manager, _ := controller.NewManager(mockCfg, cryptService, log)
+	manager, _ := controller.NewManager(mockCfg, cryptService, service.NewVault(), log)
 	memoryStorage := storage.NewMemoryStorage()
 	mainPage := newPageIndex(manager, memoryStorage, log)
 
@@ -99,8 +148,11 @@ func TestPageAction_View(t *testing.T) {
 	assert.True(t, strings.Contains(result, "Доступные действия"))
 	assert.True(t, strings.Contains(result, "Добавить данные банковских карт"))
 	assert.True(t, strings.Contains(result, "Добавить произвольные текстовые данные"))
+	assert.True(t, strings.Contains(result, "Добавить логин/пароль"))
 	assert.True(t, strings.Contains(result, "Добавить бинарные данные"))
 	assert.True(t, strings.Contains(result, "Показать мои данные"))
+	assert.True(t, strings.Contains(result, "Сменить ключ шифрования"))
+	assert.True(t, strings.Contains(result, "Новый комплект восстановления"))
 	assert.True(t, strings.Contains(result, "Выйти"))
 	assert.True(t, strings.Contains(result, "вверх/вниз: для переключения • enter: выбрать"))
 
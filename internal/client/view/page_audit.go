@@ -0,0 +1,172 @@
+package view
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/charmbracelet/bubbles/table"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/northmule/gophkeeper/internal/client/controller"
+	"github.com/northmule/gophkeeper/internal/common/model_data"
+	"github.com/northmule/gophkeeper/internal/common/models"
+)
+
+// auditPageSize записей журнала на странице
+const auditPageSize = 20
+
+// auditActions фильтр по действию, переключается по кругу. Пустое значение - все действия
+var auditActions = []string{
+	"",
+	models.AuditLogin,
+	models.AuditKeyExchange,
+	models.AuditItemRead,
+	models.AuditItemSave,
+	models.AuditFileDownload,
+}
+
+// Экран журнала действий пользователя
+type pageAudit struct {
+	mainPage        *pageIndex
+	prevPage        tea.Model
+	table           table.Model
+	action          int
+	offset          int
+	responseMessage string
+}
+
+func newPageAudit(mainPage *pageIndex, prevPage tea.Model) *pageAudit {
+	m := &pageAudit{
+		mainPage: mainPage,
+		prevPage: prevPage,
+	}
+
+	columns := []table.Column{
+		{Title: "Время", Width: 20},
+		{Title: "Действие", Width: 14},
+		{Title: "Уточнение", Width: 26},
+		{Title: "UUID данных", Width: 38},
+		{Title: "IP", Width: 20},
+	}
+	t := table.New(
+		table.WithColumns(columns),
+		table.WithFocused(true),
+		table.WithHeight(10),
+	)
+	s := table.DefaultStyles()
+	s.Header = s.Header.
+		BorderStyle(lipgloss.NormalBorder()).
+		BorderForeground(lipgloss.Color("240")).
+		BorderBottom(true).
+		Bold(false)
+	s.Selected = s.Selected.
+		Foreground(lipgloss.Color("229")).
+		Background(lipgloss.Color("57")).
+		Bold(false)
+	t.SetStyles(s)
+	m.table = t
+
+	m.load()
+	return m
+}
+
+// load загрузка текущей страницы журнала
+func (m *pageAudit) load() {
+	list, err := m.mainPage.managerController.Audit().List(m.mainPage.accessToken(), controller.AuditFilter{
+		Action: auditActions[m.action],
+		Offset: m.offset,
+		Limit:  auditPageSize,
+	})
+	if err != nil {
+		m.responseMessage = err.Error()
+		return
+	}
+	var rows []table.Row
+	for _, event := range list.Events {
+		rows = append(rows, table.Row{
+			time.Unix(event.CreatedAt, 0).Format(time.DateTime),
+			event.Action,
+			event.Details,
+			event.DataUUID,
+			event.IP,
+		})
+	}
+	m.table.SetRows(rows)
+	m.table.SetCursor(0)
+	m.responseMessage = ""
+}
+
+func (m *pageAudit) Init() tea.Cmd { return nil }
+
+func (m *pageAudit) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		switch msg.String() {
+		case "ctrl+c":
+			return m.prevPage, nil
+		case "f":
+			m.action = (m.action + 1) % len(auditActions)
+			m.offset = 0
+			m.load()
+			return m, nil
+		case "right":
+			if len(m.table.Rows()) < auditPageSize {
+				return m, nil
+			}
+			m.offset += auditPageSize
+			m.load()
+			return m, nil
+		case "left":
+			if m.offset == 0 {
+				return m, nil
+			}
+			m.offset = max(m.offset-auditPageSize, 0)
+			m.load()
+			return m, nil
+		case "v":
+			result, err := m.mainPage.managerController.Audit().Verify(m.mainPage.accessToken())
+			m.responseMessage = auditVerifyMessage(result, err)
+			return m, nil
+		}
+	}
+	m.table, cmd = m.table.Update(msg)
+	return m, cmd
+}
+
+// auditVerifyMessage текст результата проверки журнала
+func auditVerifyMessage(result *model_data.AuditVerifyResponse, err error) string {
+	if errors.Is(err, controller.ErrAuditTruncated) {
+		return fmt.Sprintf("журнал нарушен: %s (записей сейчас: %d)", err, result.Checked)
+	}
+	if err != nil {
+		return err.Error()
+	}
+	if !result.Valid {
+		return fmt.Sprintf("журнал нарушен: запись %d не сходится с цепочкой", result.BrokenID)
+	}
+	if result.Unsigned > 0 {
+		return fmt.Sprintf("журнал цел, проверено записей: %d (без подписи сервера: %d)", result.Checked, result.Unsigned)
+	}
+	return fmt.Sprintf("журнал цел, проверено записей: %d", result.Checked)
+}
+
+// View контент страницы
+func (m *pageAudit) View() string {
+	action := auditActions[m.action]
+	if action == "" {
+		action = "все"
+	}
+	title := renderTitle("Журнал действий")
+	tpl := bodyStyle.Render(fmt.Sprintf("Действие: %s, страница: %d\n", action, m.offset/auditPageSize+1))
+	tpl += "%s\n\n"
+	tpl += subtleStyle.Render("вверх/вниз: для переключения") + dotStyle +
+		subtleStyle.Render("влево/вправо: страницы") + dotStyle +
+		subtleStyle.Render("f: фильтр по действию") + dotStyle +
+		subtleStyle.Render("v: проверить журнал") + dotStyle +
+		subtleStyle.Render("ctrl+c: вернуться") + dotStyle +
+		responseTextStyle.Render("\n"+m.responseMessage)
+
+	s := fmt.Sprintf(tpl, baseStyle.Render(m.table.View()))
+	return mainStyle.Render(title + "\n" + s + "\n\n")
+}
@@ -0,0 +1,110 @@
+package view
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/northmule/gophkeeper/internal/client/controller"
+	"github.com/northmule/gophkeeper/internal/client/logger"
+	"github.com/northmule/gophkeeper/internal/client/storage"
+	"github.com/northmule/gophkeeper/internal/common/model_data"
+	"github.com/northmule/gophkeeper/internal/common/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func auditPageEvents(count int) *model_data.AuditListResponse {
+	list := new(model_data.AuditListResponse)
+	for i := 0; i < count; i++ {
+		list.Events = append(list.Events, model_data.AuditEventResponse{ID: int64(i + 1), Action: models.AuditItemRead, IP: "127.0.0.1"})
+	}
+	return list
+}
+
+func TestPageAudit_Update(t *testing.T) {
+	log, _ := logger.NewLogger("info")
+	memoryStorage := storage.NewMemoryStorage()
+
+	t.Run("pages and filter", func(t *testing.T) {
+		mockManagerController := new(MockManagerController)
+		mockAudit := new(MockAuditController)
+		mockManagerController.On("Audit").Return(mockAudit)
+		mockAudit.On("List", mock.Anything, controller.AuditFilter{Limit: auditPageSize}).Return(auditPageEvents(auditPageSize), nil).Once()
+		mockAudit.On("List", mock.Anything, controller.AuditFilter{Offset: auditPageSize, Limit: auditPageSize}).Return(auditPageEvents(3), nil).Once()
+		mockAudit.On("List", mock.Anything, controller.AuditFilter{Limit: auditPageSize}).Return(auditPageEvents(auditPageSize), nil).Once()
+		mockAudit.On("List", mock.Anything, controller.AuditFilter{Action: models.AuditLogin, Limit: auditPageSize}).Return(auditPageEvents(1), nil).Once()
+
+		mainPage := newPageIndex(mockManagerController, memoryStorage, log)
+		page := newPageAudit(mainPage, mainPage)
+		assert.Nil(t, page.Init())
+		assert.Len(t, page.table.Rows(), auditPageSize)
+
+		_, _ = page.Update(tea.KeyMsg{Type: tea.KeyRight})
+		assert.Equal(t, auditPageSize, page.offset)
+		assert.Len(t, page.table.Rows(), 3)
+
+		// последняя страница неполная, дальше не листается
+		_, _ = page.Update(tea.KeyMsg{Type: tea.KeyRight})
+		assert.Equal(t, auditPageSize, page.offset)
+
+		_, _ = page.Update(tea.KeyMsg{Type: tea.KeyLeft})
+		assert.Equal(t, 0, page.offset)
+
+		_, _ = page.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("f")})
+		assert.Equal(t, models.AuditLogin, auditActions[page.action])
+		assert.Len(t, page.table.Rows(), 1)
+		assert.True(t, strings.Contains(page.View(), "Действие: login"))
+		mockAudit.AssertExpectations(t)
+	})
+
+	t.Run("list error", func(t *testing.T) {
+		mockManagerController := new(MockManagerController)
+		mockAudit := new(MockAuditController)
+		mockManagerController.On("Audit").Return(mockAudit)
+		mockAudit.On("List", mock.Anything, mock.Anything).Return(nil, errors.New("вы не авторизованы"))
+
+		mainPage := newPageIndex(mockManagerController, memoryStorage, log)
+		page := newPageAudit(mainPage, mainPage)
+		assert.Equal(t, "вы не авторизованы", page.responseMessage)
+		assert.Empty(t, page.table.Rows())
+	})
+
+	t.Run("verify", func(t *testing.T) {
+		mockManagerController := new(MockManagerController)
+		mockAudit := new(MockAuditController)
+		mockManagerController.On("Audit").Return(mockAudit)
+		mockAudit.On("List", mock.Anything, mock.Anything).Return(auditPageEvents(0), nil)
+		mockAudit.On("Verify", mock.Anything).Return(&model_data.AuditVerifyResponse{Valid: true, Checked: 7}, nil).Once()
+		mockAudit.On("Verify", mock.Anything).Return(&model_data.AuditVerifyResponse{Valid: false, Checked: 7, BrokenID: 5}, nil).Once()
+		mockAudit.On("Verify", mock.Anything).Return(&model_data.AuditVerifyResponse{Valid: true, Checked: 6}, controller.ErrAuditTruncated).Once()
+		mockAudit.On("Verify", mock.Anything).Return(&model_data.AuditVerifyResponse{Valid: true, Checked: 7, Unsigned: 2}, nil).Once()
+
+		mainPage := newPageIndex(mockManagerController, memoryStorage, log)
+		page := newPageAudit(mainPage, mainPage)
+		verify := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("v")}
+
+		_, _ = page.Update(verify)
+		assert.Equal(t, "журнал цел, проверено записей: 7", page.responseMessage)
+		_, _ = page.Update(verify)
+		assert.Contains(t, page.responseMessage, "запись 5 не сходится")
+		_, _ = page.Update(verify)
+		assert.Contains(t, page.responseMessage, controller.ErrAuditTruncated.Error())
+		_, _ = page.Update(verify)
+		assert.Equal(t, "журнал цел, проверено записей: 7 (без подписи сервера: 2)", page.responseMessage)
+	})
+
+	t.Run("back", func(t *testing.T) {
+		mockManagerController := new(MockManagerController)
+		mockAudit := new(MockAuditController)
+		mockManagerController.On("Audit").Return(mockAudit)
+		mockAudit.On("List", mock.Anything, mock.Anything).Return(auditPageEvents(0), nil)
+
+		mainPage := newPageIndex(mockManagerController, memoryStorage, log)
+		prevPage := newPageAction(mainPage)
+		page := newPageAudit(mainPage, prevPage)
+		m, _ := page.Update(tea.KeyMsg{Type: tea.KeyCtrlC})
+		assert.Equal(t, prevPage, m)
+	})
+}
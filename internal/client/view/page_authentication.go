@@ -1,8 +1,11 @@
 package view
 
 import (
+	"errors"
 	"fmt"
 	"time"
+
+	"github.com/northmule/gophkeeper/internal/client/controller"
 )
 import tea "github.com/charmbracelet/bubbletea"
 import "github.com/charmbracelet/bubbles/textinput"
@@ -11,6 +14,8 @@ import "github.com/charmbracelet/bubbles/textinput"
 type pageAuthentication struct {
 	login           textinput.Model
 	password        textinput.Model
+	code            textinput.Model
+	mfaToken        string // не пустой, когда сервер ждёт код второго фактора
 	err             error
 	Choice          int
 	mainPage        *pageIndex
@@ -30,9 +35,15 @@ func newPageAuthentication(main *pageIndex) *pageAuthentication {
 	password.CharLimit = 50
 	password.Width = 20
 
+	code := textinput.New()
+	code.Placeholder = "Код из приложения или резервный код"
+	code.CharLimit = 20
+	code.Width = 36
+
 	m := &pageAuthentication{}
 	m.login = login
 	m.password = password
+	m.code = code
 
 	m.mainPage = main
 
@@ -48,12 +59,16 @@ func (m *pageAuthentication) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	var cmd tea.Cmd
 
+	if m.mfaToken != "" {
+		return m.updateCode(msg)
+	}
+
 	if msg, ok := msg.(tea.KeyMsg); ok {
 		k := msg.String()
 		if k == "down" || k == "tab" {
 			m.Choice++
-			if m.Choice > 3 {
-				m.Choice = 3
+			if m.Choice > 4 {
+				m.Choice = 4
 			}
 		}
 		if k == "up" {
@@ -69,33 +84,30 @@ func (m *pageAuthentication) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					m.responseMessage = err.Error()
 					return m, tea.Batch(cmd, clearErrorAfter(3*time.Second))
 				}
-
-				// Сохранём токен
-				m.mainPage.storage.SetToken(r.Value)
-				// Отправляем публичный ключ клиента на сервер
-				err = m.mainPage.managerController.KeysData().UploadClientPublicKey(m.mainPage.storage.Token())
-				if err != nil {
-					m.responseMessage = err.Error()
-					return m, tea.Batch(cmd, clearErrorAfter(3*time.Second))
-				}
-				// Забираем публичный ключ с сервера
-				err = m.mainPage.managerController.KeysData().DownloadPublicServerKey(m.mainPage.storage.Token())
-				if err != nil {
-					m.responseMessage = err.Error()
-					return m, tea.Batch(cmd, clearErrorAfter(3*time.Second))
+				// Включён второй фактор, запрашиваем код
+				if r.MFAToken != "" {
+					m.mfaToken = r.MFAToken
+					m.Choice = 0
+					m.code.Reset()
+					m.code.Focus()
+					m.responseMessage = "Введите код второго фактора"
+					return m, tea.Batch(textinput.Blink, clearErrorAfter(3*time.Second))
 				}
-				// Отправка приватного ключа (ключ отправляется зашифрованным публичным серверным)
-				err = m.mainPage.managerController.KeysData().UploadClientPrivateKey(m.mainPage.storage.Token())
+
+				return m.completeLogin(r)
+			}
+
+			if m.Choice == 3 {
+				// вход по сертификату клиента, выданному сервером при прошлом входе (mTLS)
+				r, err := m.mainPage.managerController.Authentication().SendCertificate()
 				if err != nil {
 					m.responseMessage = err.Error()
 					return m, tea.Batch(cmd, clearErrorAfter(3*time.Second))
 				}
-				// Авторизация успешна, отображаем следующее меню
-				m.responseMessage = "Вы авторизованы"
-				return newPageAction(m.mainPage), tea.Batch(cmd, clearErrorAfter(3*time.Second))
+				return m.completeLogin(r)
 			}
 
-			if m.Choice == 3 {
+			if m.Choice == 4 {
 				return m.mainPage, nil
 			}
 		}
@@ -115,6 +127,98 @@ func (m *pageAuthentication) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
+// updateCode шаг ввода кода второго фактора
+func (m *pageAuthentication) updateCode(msg tea.Msg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		k := msg.String()
+		if k == "down" || k == "tab" {
+			m.Choice++
+			if m.Choice > 2 {
+				m.Choice = 2
+			}
+		}
+		if k == "up" {
+			m.Choice--
+			if m.Choice < 0 {
+				m.Choice = 0
+			}
+		}
+		if k == "enter" {
+			if m.Choice == 1 {
+				r, err := m.mainPage.managerController.Authentication().SendCode(m.mfaToken, m.code.Value())
+				if err != nil {
+					m.responseMessage = err.Error()
+					return m, tea.Batch(cmd, clearErrorAfter(3*time.Second))
+				}
+				m.mfaToken = ""
+				return m.completeLogin(r)
+			}
+
+			if m.Choice == 2 {
+				// возврат к вводу логина и пароля
+				m.mfaToken = ""
+				m.Choice = 0
+				return m, nil
+			}
+		}
+	}
+
+	if m.Choice == 0 {
+		m.code, cmd = m.code.Update(msg)
+		m.code.Focus()
+		return m, cmd
+	}
+
+	return m, nil
+}
+
+// completeLogin сохранение токенов и обмен ключами после успешного входа
+func (m *pageAuthentication) completeLogin(r *controller.AuthenticationResponse) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+
+	// Сохранём токены
+	m.mainPage.setTokens(r)
+	// Отправляем публичный ключ клиента на сервер
+	err := m.mainPage.managerController.KeysData().UploadClientPublicKey(m.mainPage.accessToken())
+	if err != nil {
+		m.responseMessage = err.Error()
+		return m, tea.Batch(cmd, clearErrorAfter(3*time.Second))
+	}
+	// Забираем публичный ключ с сервера
+	err = m.mainPage.managerController.KeysData().DownloadPublicServerKey(m.mainPage.accessToken())
+	if err != nil {
+		m.responseMessage = err.Error()
+		return m, tea.Batch(cmd, clearErrorAfter(3*time.Second))
+	}
+	// Отправка приватного ключа (ключ отправляется зашифрованным публичным серверным)
+	err = m.mainPage.managerController.KeysData().UploadClientPrivateKey(m.mainPage.accessToken())
+	if errors.Is(err, controller.ErrClientKeyMismatch) {
+		// ключ восстанавливается из комплекта после ввода мастер-пароля
+		p := newPageMasterPassword(m.mainPage)
+		p.restoreClientKey = true
+		p.responseMessage = err.Error()
+		return p, p.Init()
+	}
+	if err != nil {
+		m.responseMessage = err.Error()
+		return m, tea.Batch(cmd, clearErrorAfter(3*time.Second))
+	}
+	// Хранилище открывается ключом, сохранённым на сервере для этого устройства
+	err = m.mainPage.managerController.Devices().Unlock(m.mainPage.accessToken())
+	if err == nil {
+		return newPageMasterPassword(m.mainPage).unlocked()
+	}
+	if !errors.Is(err, controller.ErrDeviceVaultKeyNotFound) {
+		m.mainPage.log.Error(err)
+	}
+	// Авторизация успешна, запрашиваем мастер-пароль
+	m.responseMessage = "Вы авторизованы"
+	p := newPageMasterPassword(m.mainPage)
+	return p, tea.Batch(p.Init(), clearErrorAfter(3*time.Second))
+}
+
 // View внешний вид
 func (m *pageAuthentication) View() string {
 
@@ -127,12 +231,24 @@ func (m *pageAuthentication) View() string {
 		subtleStyle.Render("enter: начать ввод значения") + dotStyle +
 		responseTextStyle.Render("\n"+m.responseMessage) + dotStyle
 
+	if m.mfaToken != "" {
+		choices := fmt.Sprintf(
+			"%s\n%s\n\n%s\n",
+			renderCheckbox(m.code.View(), c == 0),
+			renderCheckbox("Подтвердить", c == 1),
+			renderCheckbox("Вернуться", c == 2),
+		)
+		s := fmt.Sprintf(tpl, choices)
+		return mainStyle.Render(renderTitle("Второй фактор") + "\n" + s + "\n\n")
+	}
+
 	choices := fmt.Sprintf(
-		"%s\n%s\n%s\n\n%s\n",
+		"%s\n%s\n%s\n%s\n\n%s\n",
 		renderCheckbox(m.login.View(), c == 0),
 		renderCheckbox(m.password.View(), c == 1),
 		renderCheckbox("Отправить", c == 2),
-		renderCheckbox("Вернуться", c == 3),
+		renderCheckbox("Войти по сертификату", c == 3),
+		renderCheckbox("Вернуться", c == 4),
 	)
 
 	s := fmt.Sprintf(tpl, choices)
@@ -16,13 +16,20 @@ import (
 	"github.com/stretchr/testify/mock"
 )
 
+// newMockDevicesLocked ключа хранилища для устройства на сервере нет
+func newMockDevicesLocked() *MockDevicesController {
+	mockDevices := new(MockDevicesController)
+	mockDevices.On("Unlock", mock.Anything).Return(controller.ErrDeviceVaultKeyNotFound)
+	return mockDevices
+}
+
 func TestPageAuthentication_Init(t *testing.T) {
 	mockCfg, _ := config.NewConfig()
 	mockCfg.Value().PathPublicKeyServer = path.Join("testpath")
 	mockCfg.Value().PathKeys = path.Join("testpath")
 	log, _ := logger.NewLogger("info")
 	cryptService, _ := service.NewCrypt(mockCfg)
-	manager, _ := controller.NewManager(mockCfg, cryptService, log)
+	manager, _ := controller.NewManager(mockCfg, cryptService, service.NewVault(), log)
 	memoryStorage := storage.NewMemoryStorage()
 	mainPage := newPageIndex(manager, memoryStorage, log)
 
@@ -48,7 +55,7 @@ func TestPageAuthentication_Update(t *testing.T) {
 	mockCfg.Value().PathKeys = path.Join("testpath")
 	log, _ := logger.NewLogger("info")
 	cryptService, _ := service.NewCrypt(mockCfg)
-	manager, _ := controller.NewManager(mockCfg, cryptService, log)
+	manager, _ := controller.NewManager(mockCfg, cryptService, service.NewVault(), log)
 	memoryStorage := storage.NewMemoryStorage()
 	mainPage := newPageIndex(manager, memoryStorage, log)
 
@@ -69,6 +76,9 @@ func TestPageAuthentication_Update(t *testing.T) {
 		mockKeyData := new(MockKeyDataController)
 		mockManagerController.On("Authentication").Return(mockAuthentication)
 		mockManagerController.On("KeysData").Return(mockKeyData)
+		mockManagerController.On("Offline").Return(newMockOfflineOpened())
+		mockManagerController.On("Outbox").Return(newMockOutboxEmpty())
+		mockManagerController.On("Devices").Return(newMockDevicesLocked())
 
 		mockKeyData.On("UploadClientPublicKey", mock.Anything).Return(nil)
 		mockKeyData.On("DownloadPublicServerKey", mock.Anything).Return(nil)
@@ -111,6 +121,9 @@ func TestPageAuthentication_Update(t *testing.T) {
 		mockKeyData := new(MockKeyDataController)
 		mockManagerController.On("Authentication").Return(mockAuthentication)
 		mockManagerController.On("KeysData").Return(mockKeyData)
+		mockManagerController.On("Offline").Return(newMockOfflineOpened())
+		mockManagerController.On("Outbox").Return(newMockOutboxEmpty())
+		mockManagerController.On("Devices").Return(newMockDevicesLocked())
 
 		mockKeyData.On("UploadClientPublicKey", mock.Anything).Return(errors.New("error"))
 		mockKeyData.On("DownloadPublicServerKey", mock.Anything).Return(nil)
@@ -136,6 +149,9 @@ func TestPageAuthentication_Update(t *testing.T) {
 		mockKeyData := new(MockKeyDataController)
 		mockManagerController.On("Authentication").Return(mockAuthentication)
 		mockManagerController.On("KeysData").Return(mockKeyData)
+		mockManagerController.On("Offline").Return(newMockOfflineOpened())
+		mockManagerController.On("Outbox").Return(newMockOutboxEmpty())
+		mockManagerController.On("Devices").Return(newMockDevicesLocked())
 
 		mockKeyData.On("UploadClientPublicKey", mock.Anything).Return(nil)
 		mockKeyData.On("DownloadPublicServerKey", mock.Anything).Return(errors.New("error"))
@@ -161,6 +177,9 @@ func TestPageAuthentication_Update(t *testing.T) {
 		mockKeyData := new(MockKeyDataController)
 		mockManagerController.On("Authentication").Return(mockAuthentication)
 		mockManagerController.On("KeysData").Return(mockKeyData)
+		mockManagerController.On("Offline").Return(newMockOfflineOpened())
+		mockManagerController.On("Outbox").Return(newMockOutboxEmpty())
+		mockManagerController.On("Devices").Return(newMockDevicesLocked())
 
 		mockKeyData.On("UploadClientPublicKey", mock.Anything).Return(nil)
 		mockKeyData.On("DownloadPublicServerKey", mock.Anything).Return(nil)
@@ -178,11 +197,141 @@ func TestPageAuthentication_Update(t *testing.T) {
 		assert.NotNil(t, m)
 	})
 
-	t.Run("choice 3", func(t *testing.T) {
-		pa := pageAuthentication{Choice: 3, mainPage: mainPage}
+	t.Run("client key mismatch", func(t *testing.T) {
+		mockManagerController := new(MockManagerController)
+		mockAuthentication := new(MockAuthenticationDataController)
+		mockKeyData := new(MockKeyDataController)
+		mockManagerController.On("Authentication").Return(mockAuthentication)
+		mockManagerController.On("KeysData").Return(mockKeyData)
+		mockManagerController.On("Offline").Return(newMockOfflineOpened())
+		mockManagerController.On("Outbox").Return(newMockOutboxEmpty())
+		mockManagerController.On("Devices").Return(newMockDevicesLocked())
+		mockKeyData.On("UploadClientPublicKey", mock.Anything).Return(nil)
+		mockKeyData.On("DownloadPublicServerKey", mock.Anything).Return(nil)
+		mockKeyData.On("UploadClientPrivateKey", mock.Anything).Return(controller.ErrClientKeyMismatch)
+		mockAuthentication.On("Send", mock.Anything, mock.Anything).Return(&controller.AuthenticationResponse{Value: "ok"}, nil)
+
+		mainPage := newPageIndex(mockManagerController, storage.NewMemoryStorage(), log)
+		pa := pageAuthentication{Choice: 2, mainPage: mainPage}
+		m, _ := pa.Update(tea.KeyMsg{Type: tea.KeyEnter})
+		// ключ восстанавливается после ввода мастер-пароля
+		masterPasswordPage, ok := m.(*pageMasterPassword)
+		assert.True(t, ok)
+		assert.True(t, masterPasswordPage.restoreClientKey)
+	})
+
+	t.Run("device unlock", func(t *testing.T) {
+		mockManagerController := new(MockManagerController)
+		mockAuthentication := new(MockAuthenticationDataController)
+		mockKeyData := new(MockKeyDataController)
+		mockDevices := new(MockDevicesController)
+		mockRecovery := new(MockRecoveryController)
+		mockManagerController.On("Authentication").Return(mockAuthentication)
+		mockManagerController.On("KeysData").Return(mockKeyData)
+		mockManagerController.On("Offline").Return(newMockOfflineOpened())
+		mockManagerController.On("Outbox").Return(newMockOutboxEmpty())
+		mockManagerController.On("Devices").Return(mockDevices)
+		mockManagerController.On("Recovery").Return(mockRecovery)
+		mockKeyData.On("UploadClientPublicKey", "ok").Return(nil)
+		mockKeyData.On("DownloadPublicServerKey", "ok").Return(nil)
+		mockKeyData.On("UploadClientPrivateKey", "ok").Return(nil)
+		mockDevices.On("Unlock", "ok").Return(nil)
+		mockMasterKey := new(MockMasterKeyController)
+		mockManagerController.On("MasterKey").Return(mockMasterKey)
+		mockMasterKey.On("Migrate", "ok").Return(nil)
+		mockRecovery.On("EnsureKit", "ok").Return(nil, nil)
+		mockAuthentication.On("Send", mock.Anything, mock.Anything).Return(&controller.AuthenticationResponse{Value: "ok"}, nil)
+
+		mainPage := newPageIndex(mockManagerController, storage.NewMemoryStorage(), log)
+		pa := pageAuthentication{Choice: 2, mainPage: mainPage}
+		m, _ := pa.Update(tea.KeyMsg{Type: tea.KeyEnter})
+		// мастер-пароль не запрашивается
+		assert.IsType(t, &pageAction{}, m)
+	})
+
+	t.Run("second factor required", func(t *testing.T) {
+		mockManagerController := new(MockManagerController)
+		mockAuthentication := new(MockAuthenticationDataController)
+		mockKeyData := new(MockKeyDataController)
+		mockManagerController.On("Authentication").Return(mockAuthentication)
+		mockManagerController.On("KeysData").Return(mockKeyData)
+		mockManagerController.On("Offline").Return(newMockOfflineOpened())
+		mockManagerController.On("Outbox").Return(newMockOutboxEmpty())
+		mockManagerController.On("Devices").Return(newMockDevicesLocked())
+		mockKeyData.On("UploadClientPublicKey", "ok").Return(nil)
+		mockKeyData.On("DownloadPublicServerKey", "ok").Return(nil)
+		mockKeyData.On("UploadClientPrivateKey", "ok").Return(nil)
+
+		mockAuthentication.On("Send", mock.Anything, mock.Anything).Return(&controller.AuthenticationResponse{MFAToken: "mfa-token"}, nil)
+		mockAuthentication.On("SendCode", "mfa-token", "wrong").Return(nil, errors.New("не верный код")).Once()
+		mockAuthentication.On("SendCode", "mfa-token", "123456").Return(&controller.AuthenticationResponse{Value: "ok"}, nil)
+
+		mainPage := newPageIndex(mockManagerController, storage.NewMemoryStorage(), log)
+		pa := newPageAuthentication(mainPage)
+		pa.Choice = 2
+		m, _ := pa.Update(tea.KeyMsg{Type: tea.KeyEnter})
+		assert.Equal(t, pa, m)
+		assert.Equal(t, "mfa-token", pa.mfaToken)
+		assert.Equal(t, 0, pa.Choice)
+		assert.Contains(t, pa.View(), "Второй фактор")
+		mockKeyData.AssertNotCalled(t, "UploadClientPublicKey", mock.Anything)
+
+		pa.code.SetValue("wrong")
+		pa.Choice = 1
+		m, _ = pa.Update(tea.KeyMsg{Type: tea.KeyEnter})
+		assert.Equal(t, pa, m)
+		assert.NotEmpty(t, pa.responseMessage)
+
+		pa.code.SetValue("123456")
+		m, _ = pa.Update(tea.KeyMsg{Type: tea.KeyEnter})
+		assert.IsType(t, &pageMasterPassword{}, m)
+		assert.Empty(t, pa.mfaToken)
+		assert.Equal(t, "ok", mainPage.storage.Token())
+	})
+
+	t.Run("second factor back", func(t *testing.T) {
+		pa := newPageAuthentication(mainPage)
+		pa.mfaToken = "mfa-token"
+		pa.Choice = 2
+		m, _ := pa.Update(tea.KeyMsg{Type: tea.KeyEnter})
+		assert.Equal(t, pa, m)
+		assert.Empty(t, pa.mfaToken)
+		assert.Equal(t, 0, pa.Choice)
+	})
+
+	t.Run("choice 3 certificate", func(t *testing.T) {
+		mockManagerController := new(MockManagerController)
+		mockAuthentication := new(MockAuthenticationDataController)
+		mockKeyData := new(MockKeyDataController)
+		mockManagerController.On("Authentication").Return(mockAuthentication)
+		mockManagerController.On("KeysData").Return(mockKeyData)
+		mockManagerController.On("Offline").Return(newMockOfflineOpened())
+		mockManagerController.On("Outbox").Return(newMockOutboxEmpty())
+		mockManagerController.On("Devices").Return(newMockDevicesLocked())
+		mockKeyData.On("UploadClientPublicKey", "ok").Return(nil)
+		mockKeyData.On("DownloadPublicServerKey", "ok").Return(nil)
+		mockKeyData.On("UploadClientPrivateKey", "ok").Return(nil)
+		mockAuthentication.On("SendCertificate").Return(nil, errors.New("сертификат клиента не принят сервером")).Once()
+		mockAuthentication.On("SendCertificate").Return(&controller.AuthenticationResponse{Value: "ok"}, nil)
+
+		mainPage := newPageIndex(mockManagerController, storage.NewMemoryStorage(), log)
+		pa := newPageAuthentication(mainPage)
+		pa.Choice = 3
+		m, _ := pa.Update(tea.KeyMsg{Type: tea.KeyEnter})
+		assert.Equal(t, pa, m)
+		assert.Equal(t, "сертификат клиента не принят сервером", pa.responseMessage)
+
+		m, _ = pa.Update(tea.KeyMsg{Type: tea.KeyEnter})
+		_, ok := m.(*pageMasterPassword)
+		assert.True(t, ok)
+		assert.Equal(t, "ok", mainPage.storage.Token())
+	})
+
+	t.Run("choice 4", func(t *testing.T) {
+		pa := pageAuthentication{Choice: 4, mainPage: mainPage}
 		msg := tea.KeyMsg{Type: tea.KeyEnter}
 		m, _ := pa.Update(msg)
-		assert.NotNil(t, m)
+		assert.Equal(t, mainPage, m)
 	})
 
 	t.Run("choice 10", func(t *testing.T) {
@@ -200,7 +349,7 @@ func TestPageAuthentication_View(t *testing.T) {
 	mockCfg.Value().PathKeys = path.Join("testpath")
 	log, _ := logger.NewLogger("info")
 	cryptService, _ := service.NewCrypt(mockCfg)
-	manager, _ := controller.NewManager(mockCfg, cryptService, log)
+	manager, _ := controller.NewManager(mockCfg, cryptService, service.NewVault(), log)
 	memoryStorage := storage.NewMemoryStorage()
 	mainPage := newPageIndex(manager, memoryStorage, log)
 
@@ -210,6 +359,7 @@ func TestPageAuthentication_View(t *testing.T) {
 	assert.True(t, strings.Contains(result, "вверх/вниз: для переключения"))
 	assert.True(t, strings.Contains(result, "enter: начать ввод значения"))
 	assert.True(t, strings.Contains(result, "Отправить"))
+	assert.True(t, strings.Contains(result, "Войти по сертификату"))
 	assert.True(t, strings.Contains(result, "Вернуться"))
 
 }
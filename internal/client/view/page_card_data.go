@@ -2,6 +2,7 @@ package view
 
 import (
 	"fmt"
+	"reflect"
 
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
@@ -19,6 +20,10 @@ type pageCardData struct {
 
 	// идентификатор редактирования
 	uuid string
+	// версия редактируемых данных, полученная с сервера
+	version int64
+	// данные, загруженные для редактирования
+	loaded *model_data.CardDataRequest
 	// поля
 	name                 textinput.Model
 	cardNumber           textinput.Model
@@ -107,6 +112,7 @@ func newPageCardData(mainPage *pageIndex) *pageCardData {
 // SetEditableData значения для редактирования
 func (m *pageCardData) SetEditableData(data *model_data.CardDataRequest) *pageCardData {
 	m.uuid = data.UUID
+	m.version = data.Version
 	m.name.SetValue(data.Name)
 	m.cardNumber.SetValue(data.CardNumber)
 	m.validityPeriod.SetValue(data.ValidityPeriod)
@@ -123,11 +129,48 @@ func (m *pageCardData) SetEditableData(data *model_data.CardDataRequest) *pageCa
 		m.meta2.SetValue(v)
 	}
 
+	m.loaded = m.request()
 	m.isEditable = true
 
 	return m
 }
 
+// request данные страницы для отправки
+func (m *pageCardData) request() *model_data.CardDataRequest {
+	requestData := new(model_data.CardDataRequest)
+
+	requestData.UUID = m.uuid
+	requestData.Version = m.version
+	requestData.Name = m.name.Value()
+	requestData.CardNumber = m.cardNumber.Value()
+	requestData.ValidityPeriod = m.validityPeriod.Value()
+	requestData.SecurityCode = m.securityCode.Value()
+	requestData.FullNameHolder = m.fullNameHolder.Value()
+	requestData.NameBank = m.nameBank.Value()
+	requestData.PhoneHolder = m.phoneHolder.Value()
+	requestData.CurrentAccountNumber = m.currentAccountNumber.Value()
+	requestData.Meta = make(map[string]string)
+	requestData.Meta[data_type.MetaNameNote] = m.meta1.Value()
+	requestData.Meta[data_type.MetaNameWebSite] = m.meta2.Value()
+
+	return requestData
+}
+
+// dataChanged изменение редактируемых данных на другом устройстве
+func (m *pageCardData) dataChanged(event dataEventMsg) *pageCardData {
+	if !m.isEditable || event.DataUUID != m.uuid {
+		return m
+	}
+	item, message := m.mainPage.reloadChanged(event, !reflect.DeepEqual(m.request(), m.loaded))
+	if item != nil {
+		m.SetEditableData(&item.CardData)
+	}
+	if message != "" {
+		m.responseMessage = message
+	}
+	return m
+}
+
 // SetPageGrid установка значения страницы
 func (m *pageCardData) SetPageGrid(page *pageDataGrid) *pageCardData {
 	m.gridPage = page
@@ -144,6 +187,10 @@ func (m *pageCardData) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	var cmd tea.Cmd
 
+	if event, ok := msg.(dataEventMsg); ok {
+		return m.dataChanged(event), nil
+	}
+
 	if msg, ok := msg.(tea.KeyMsg); ok {
 		k := msg.String()
 		if k == "down" || k == "tab" {
@@ -160,23 +207,13 @@ func (m *pageCardData) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 		if k == "enter" {
 			if m.Choice == 10 {
-				requestData := new(model_data.CardDataRequest)
-
-				requestData.UUID = m.uuid
-				requestData.Name = m.name.Value()
-				requestData.CardNumber = m.cardNumber.Value()
-				requestData.ValidityPeriod = m.validityPeriod.Value()
-				requestData.SecurityCode = m.securityCode.Value()
-				requestData.FullNameHolder = m.fullNameHolder.Value()
-				requestData.NameBank = m.nameBank.Value()
-				requestData.PhoneHolder = m.phoneHolder.Value()
-				requestData.CurrentAccountNumber = m.currentAccountNumber.Value()
-				requestData.Meta = make(map[string]string)
-				requestData.Meta[data_type.MetaNameNote] = m.meta1.Value()
-				requestData.Meta[data_type.MetaNameWebSite] = m.meta2.Value()
-
-				_, err := m.mainPage.managerController.CardData().Send(m.mainPage.storage.Token(), requestData)
+				requestData := m.request()
+
+				_, err := m.mainPage.managerController.CardData().Send(m.mainPage.accessToken(), requestData)
 				if err != nil {
+					if page, ok := openConflictPage(m.mainPage, m, &model_data.DataByUUIDResponse{IsCard: true, CardData: *requestData}, err); ok {
+						return page, nil
+					}
 					m.responseMessage = err.Error()
 					return m, nil
 				}
@@ -24,7 +24,7 @@ func TestPageCardData_Init(t *testing.T) {
 	mockCfg.Value().PathKeys = path.Join("testpath")
 	log, _ := logger.NewLogger("info")
 	cryptService, _ := service.NewCrypt(mockCfg)
-	manager, _ := controller.NewManager(mockCfg, cryptService, log)
+	manager, _ := controller.NewManager(mockCfg, cryptService, service.NewVault(), log)
 	memoryStorage := storage.NewMemoryStorage()
 	mainPage := newPageIndex(manager, memoryStorage, log)
 	page := newPageCardData(mainPage)
@@ -47,7 +47,7 @@ func TestPageCardData_Update(t *testing.T) {
 	mockCfg.Value().PathKeys = path.Join("testpath")
 	log, _ := logger.NewLogger("info")
 	cryptService, _ := service.NewCrypt(mockCfg)
-	manager, _ := controller.NewManager(mockCfg, cryptService, log)
+	manager, _ := controller.NewManager(mockCfg, cryptService, service.NewVault(), log)
 	memoryStorage := storage.NewMemoryStorage()
 	mainPage := newPageIndex(manager, memoryStorage, log)
 	for _, tt := range tests {
@@ -156,7 +156,7 @@ func TestPageCardData_View(t *testing.T) {
 	mockCfg.Value().PathKeys = path.Join("testpath")
 	log, _ := logger.NewLogger("info")
 	cryptService, _ := service.NewCrypt(mockCfg)
-	manager, _ := controller.NewManager(mockCfg, cryptService, log)
+	manager, _ := controller.NewManager(mockCfg, cryptService, service.NewVault(), log)
 	memoryStorage := storage.NewMemoryStorage()
 	mainPage := newPageIndex(manager, memoryStorage, log)
 
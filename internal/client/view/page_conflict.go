@@ -0,0 +1,365 @@
+package view
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/northmule/gophkeeper/internal/client/controller"
+	"github.com/northmule/gophkeeper/internal/common/data_type"
+	"github.com/northmule/gophkeeper/internal/common/model_data"
+)
+
+// ширина колонки значения на странице конфликта
+const conflictColumnWidth = 30
+
+// Разрешение конфликта версий: данные изменены на сервере после открытия.
+// Поля показываются рядом (моё и с сервера), для каждого выбирается одно из значений, многострочные сравниваются построчно
+type pageConflict struct {
+	Choice          int
+	mainPage        *pageIndex
+	prevPage        tea.Model
+	responseMessage string
+
+	// local отправленные пользователем данные, server текущие данные сервера
+	local  *model_data.DataByUUIDResponse
+	server *model_data.DataByUUIDResponse
+	fields []conflictField
+}
+
+// conflictField значение поля в обеих версиях
+type conflictField struct {
+	title  string
+	local  string
+	server string
+	// secret значение не показывается, только признак различия
+	secret bool
+	// useServer выбрано значение с сервера
+	useServer bool
+	// set записывает выбранное значение в отправляемые данные
+	set func(data *model_data.DataByUUIDResponse, value string)
+}
+
+// openConflictPage страница разрешения конфликта, если сервер отклонил сохранение из-за изменения данных.
+// Текущие данные запрашиваются с сервера, при другой ошибке вернёт false
+func openConflictPage(mainPage *pageIndex, prevPage tea.Model, local *model_data.DataByUUIDResponse, sendErr error) (tea.Model, bool) {
+	var conflict *controller.VersionConflictError
+	if !errors.As(sendErr, &conflict) {
+		return nil, false
+	}
+	page := &pageConflict{
+		mainPage: mainPage,
+		prevPage: prevPage,
+		local:    local,
+	}
+	if err := page.load(); err != nil {
+		return nil, false
+	}
+	return page, true
+}
+
+// load получение данных с сервера и сравнение полей
+func (m *pageConflict) load() error {
+	server, err := m.mainPage.managerController.ItemData().Send(m.mainPage.accessToken(), conflictDataUUID(m.local))
+	if err != nil {
+		return err
+	}
+	if server.IsCard != m.local.IsCard || server.IsText != m.local.IsText || server.IsCredential != m.local.IsCredential {
+		return fmt.Errorf("тип данных на сервере изменился")
+	}
+	m.server = server
+	m.fields = conflictFields(m.local, server)
+	return nil
+}
+
+// conflictDataUUID идентификатор данных
+func conflictDataUUID(data *model_data.DataByUUIDResponse) string {
+	switch {
+	case data.IsCard:
+		return data.CardData.UUID
+	case data.IsCredential:
+		return data.CredentialData.UUID
+	}
+	return data.TextData.UUID
+}
+
+// conflictFields поля данных для сравнения
+func conflictFields(local, server *model_data.DataByUUIDResponse) []conflictField {
+	var fields []conflictField
+	add := func(title string, value func(data *model_data.DataByUUIDResponse) *string) {
+		fields = append(fields, conflictField{
+			title:  title,
+			local:  *value(local),
+			server: *value(server),
+			set: func(data *model_data.DataByUUIDResponse, v string) {
+				*value(data) = v
+			},
+		})
+	}
+	addMeta := func(meta func(data *model_data.DataByUUIDResponse) map[string]string) {
+		for _, name := range []string{data_type.MetaNameNote, data_type.MetaNameWebSite} {
+			fields = append(fields, conflictField{
+				title:  data_type.TranslateDataType(name),
+				local:  meta(local)[name],
+				server: meta(server)[name],
+				set: func(data *model_data.DataByUUIDResponse, v string) {
+					meta(data)[name] = v
+				},
+			})
+		}
+	}
+
+	switch {
+	case local.IsCard:
+		add("Название данных", func(d *model_data.DataByUUIDResponse) *string { return &d.CardData.Name })
+		add("Номер карты", func(d *model_data.DataByUUIDResponse) *string { return &d.CardData.CardNumber })
+		add("Срок действия", func(d *model_data.DataByUUIDResponse) *string { return &d.CardData.ValidityPeriod })
+		add("Защитный код", func(d *model_data.DataByUUIDResponse) *string { return &d.CardData.SecurityCode })
+		add("ФИО держателя", func(d *model_data.DataByUUIDResponse) *string { return &d.CardData.FullNameHolder })
+		add("Название банка", func(d *model_data.DataByUUIDResponse) *string { return &d.CardData.NameBank })
+		add("Телефон держателя", func(d *model_data.DataByUUIDResponse) *string { return &d.CardData.PhoneHolder })
+		add("Номер счёта", func(d *model_data.DataByUUIDResponse) *string { return &d.CardData.CurrentAccountNumber })
+		addMeta(func(d *model_data.DataByUUIDResponse) map[string]string { return d.CardData.Meta })
+	case local.IsCredential:
+		add("Название данных", func(d *model_data.DataByUUIDResponse) *string { return &d.CredentialData.Name })
+		add("Логин", func(d *model_data.DataByUUIDResponse) *string { return &d.CredentialData.Username })
+		add("Пароль", func(d *model_data.DataByUUIDResponse) *string { return &d.CredentialData.Password })
+		fields[len(fields)-1].secret = true
+		fields = append(fields, conflictField{
+			title:  "Адреса сайтов",
+			local:  strings.Join(local.CredentialData.URLs, ", "),
+			server: strings.Join(server.CredentialData.URLs, ", "),
+			set: func(data *model_data.DataByUUIDResponse, v string) {
+				data.CredentialData.URLs = splitURLs(v)
+			},
+		})
+		add("Заметки", func(d *model_data.DataByUUIDResponse) *string { return &d.CredentialData.Notes })
+		addMeta(func(d *model_data.DataByUUIDResponse) map[string]string { return d.CredentialData.Meta })
+	case local.IsText:
+		add("Название данных", func(d *model_data.DataByUUIDResponse) *string { return &d.TextData.Name })
+		add("Текст", func(d *model_data.DataByUUIDResponse) *string { return &d.TextData.Value })
+		addMeta(func(d *model_data.DataByUUIDResponse) map[string]string { return d.TextData.Meta })
+	}
+	return fields
+}
+
+// merged данные для отправки: выбранные значения полей поверх текущей версии сервера.
+// Метаданные копируются с сервера: поля, которых нет на странице, сохраняются
+func (m *pageConflict) merged() *model_data.DataByUUIDResponse {
+	data := *m.server
+	data.CardData.Meta = cloneMeta(m.server.CardData.Meta)
+	data.TextData.Meta = cloneMeta(m.server.TextData.Meta)
+	data.CredentialData.Meta = cloneMeta(m.server.CredentialData.Meta)
+	for _, field := range m.fields {
+		if field.useServer {
+			field.set(&data, field.server)
+			continue
+		}
+		field.set(&data, field.local)
+	}
+	return &data
+}
+
+// cloneMeta копия метаданных, изменения копии не затрагивают исходные данные
+func cloneMeta(meta map[string]string) map[string]string {
+	clone := make(map[string]string, len(meta))
+	maps.Copy(clone, meta)
+	return clone
+}
+
+// send повторная отправка объединённых данных с версией сервера
+func (m *pageConflict) send() error {
+	data := m.merged()
+	var err error
+	switch {
+	case data.IsCard:
+		_, err = m.mainPage.managerController.CardData().Send(m.mainPage.accessToken(), &data.CardData)
+	case data.IsCredential:
+		_, err = m.mainPage.managerController.CredentialData().Send(m.mainPage.accessToken(), &data.CredentialData)
+	case data.IsText:
+		_, err = m.mainPage.managerController.TextData().Send(m.mainPage.accessToken(), &data.TextData)
+	}
+	return err
+}
+
+// setAll выбор всех значений одной версии
+func (m *pageConflict) setAll(useServer bool) {
+	for i := range m.fields {
+		m.fields[i].useServer = useServer
+	}
+}
+
+func (m *pageConflict) Init() tea.Cmd { return nil }
+
+func (m *pageConflict) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	msgKey, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, nil
+	}
+	// после полей: все мои, все с сервера, отправить, вернуться
+	last := len(m.fields) + 3
+	switch msgKey.String() {
+	case "ctrl+c":
+		return m.prevPage, nil
+	case "down", "tab":
+		m.Choice++
+		if m.Choice > last {
+			m.Choice = last
+		}
+	case "up":
+		m.Choice--
+		if m.Choice < 0 {
+			m.Choice = 0
+		}
+	case "left":
+		if m.Choice < len(m.fields) {
+			m.fields[m.Choice].useServer = false
+		}
+	case "right":
+		if m.Choice < len(m.fields) {
+			m.fields[m.Choice].useServer = true
+		}
+	case "enter":
+		switch m.Choice - len(m.fields) {
+		case 0:
+			m.setAll(false)
+		case 1:
+			m.setAll(true)
+		case 2:
+			err := m.send()
+			if err == nil {
+				return newPageAction(m.mainPage), nil
+			}
+			var conflict *controller.VersionConflictError
+			if !errors.As(err, &conflict) {
+				m.responseMessage = err.Error()
+				return m, nil
+			}
+			// данные снова изменились, выбор по полям сохраняется
+			choices := make(map[string]bool, len(m.fields))
+			for _, field := range m.fields {
+				choices[field.title] = field.useServer
+			}
+			if err = m.load(); err != nil {
+				m.responseMessage = err.Error()
+				return m, nil
+			}
+			for i := range m.fields {
+				m.fields[i].useServer = choices[m.fields[i].title]
+			}
+			m.responseMessage = "Данные снова изменены на сервере, проверьте значения"
+		case 3:
+			return m.prevPage, nil
+		default:
+			m.fields[m.Choice].useServer = !m.fields[m.Choice].useServer
+		}
+	}
+	return m, nil
+}
+
+// View контент страницы
+func (m *pageConflict) View() string {
+	title := renderTitle("Данные изменены на сервере")
+	c := m.Choice
+
+	// значения вводит пользователь, поэтому без шаблона fmt
+	s := subtleStyle.Render(padColumn("Поле", 20)+"   "+padColumn("Моё", conflictColumnWidth)+"   "+"На сервере") + "\n"
+	var diffs []string
+	for i, field := range m.fields {
+		mark := "  "
+		if field.local != field.server {
+			mark = "≠ "
+		}
+		local, server := field.local, field.server
+		if field.secret {
+			local, server = "******", "******"
+		}
+		multiline := strings.Contains(field.local, "\n") || strings.Contains(field.server, "\n")
+		if multiline {
+			local, server = "(построчно ниже)", "(построчно ниже)"
+			if field.local != field.server {
+				diffs = append(diffs, field.title+":")
+				diffs = append(diffs, diffLines(field.local, field.server)...)
+			}
+		}
+		row := mark + padColumn(field.title, 18) + " " +
+			renderChoice(padColumn(local, conflictColumnWidth), !field.useServer) + " " +
+			renderChoice(padColumn(server, conflictColumnWidth), field.useServer)
+		s += renderCheckbox(row, c == i) + "\n"
+	}
+	if len(diffs) > 0 {
+		s += "\n" + subtleStyle.Render("- только в моей версии, + только на сервере") + "\n" + strings.Join(diffs, "\n") + "\n"
+	}
+	n := len(m.fields)
+	s += "\n" + renderCheckbox("Оставить всё моё", c == n) + "\n" +
+		renderCheckbox("Оставить всё с сервера", c == n+1) + "\n" +
+		renderCheckbox("Отправить", c == n+2) + "\n\n" +
+		renderCheckbox("Вернуться", c == n+3) + "\n\n"
+	s += subtleStyle.Render("вверх/вниз: для переключения") + dotStyle +
+		subtleStyle.Render("влево/вправо или enter: выбрать значение") + dotStyle +
+		subtleStyle.Render("ctrl+c: вернуться") + dotStyle
+	s += responseTextStyle.Render("\n" + m.responseMessage)
+	return mainStyle.Render(title + "\n" + s + "\n\n")
+}
+
+// renderChoice выбранное значение поля
+func renderChoice(value string, chosen bool) string {
+	if chosen {
+		return "(•) " + value
+	}
+	return "( ) " + value
+}
+
+// padColumn значение фиксированной ширины, длинное обрезается
+func padColumn(value string, width int) string {
+	runes := []rune(value)
+	if len(runes) > width {
+		return string(runes[:width-1]) + "…"
+	}
+	return value + strings.Repeat(" ", width-len(runes))
+}
+
+// diffLines построчное сравнение: общие строки с отступом, только в моей версии с "- ", только на сервере с "+ "
+func diffLines(local, server string) []string {
+	a := strings.Split(local, "\n")
+	b := strings.Split(server, "\n")
+	// lcs[i][j] длина общей подпоследовательности a[i:] и b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+				continue
+			}
+			lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+		}
+	}
+	lines := make([]string, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			lines = append(lines, "  "+a[i])
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			lines = append(lines, "- "+a[i])
+			i++
+		default:
+			lines = append(lines, "+ "+b[j])
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		lines = append(lines, "- "+a[i])
+	}
+	for ; j < len(b); j++ {
+		lines = append(lines, "+ "+b[j])
+	}
+	return lines
+}
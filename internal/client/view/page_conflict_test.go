@@ -0,0 +1,136 @@
+package view
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/northmule/gophkeeper/internal/client/controller"
+	"github.com/northmule/gophkeeper/internal/client/logger"
+	"github.com/northmule/gophkeeper/internal/client/storage"
+	"github.com/northmule/gophkeeper/internal/common/data_type"
+	"github.com/northmule/gophkeeper/internal/common/model_data"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffLines(t *testing.T) {
+	lines := diffLines("one\ntwo\nthree", "one\n2\nthree\nfour")
+	assert.Equal(t, []string{"  one", "- two", "+ 2", "  three", "+ four"}, lines)
+	assert.Equal(t, []string{"  same"}, diffLines("same", "same"))
+}
+
+func TestPageConflict_Card(t *testing.T) {
+	log, _ := logger.NewLogger("info")
+	mockManagerController := new(MockManagerController)
+	mockItemData := new(MockItemDataController)
+	mockCardData := new(MockCardDataController)
+	mockManagerController.On("ItemData").Return(mockItemData)
+	mockManagerController.On("CardData").Return(mockCardData)
+	memoryStorage := storage.NewMemoryStorage()
+	memoryStorage.SetToken("token")
+	mainPage := newPageIndex(mockManagerController, memoryStorage, log)
+
+	local := &model_data.DataByUUIDResponse{IsCard: true, CardData: model_data.CardDataRequest{
+		UUID: "card-uuid", Version: 1, Name: "card", CardNumber: "1111", NameBank: "my bank",
+		Meta: map[string]string{data_type.MetaNameNote: "my note"},
+	}}
+	server := &model_data.DataByUUIDResponse{IsCard: true, CardData: model_data.CardDataRequest{
+		UUID: "card-uuid", Version: 2, Name: "card", CardNumber: "2222", NameBank: "bank",
+		Meta: map[string]string{data_type.MetaNameNote: "note"},
+	}}
+	mockItemData.On("Send", "token", "card-uuid").Return(server, nil)
+
+	_, ok := openConflictPage(mainPage, nil, local, errors.New("ошибка в запросе"))
+	assert.False(t, ok)
+
+	editPage := newPageCardData(mainPage)
+	m, ok := openConflictPage(mainPage, editPage, local, &controller.VersionConflictError{Version: 2})
+	require.True(t, ok)
+	page := m.(*pageConflict)
+	assert.True(t, strings.Contains(page.View(), "2222"))
+
+	// номер карты с сервера, банк и заметка свои
+	page.Choice = 1
+	page.Update(tea.KeyMsg{Type: tea.KeyRight})
+	mockCardData.On("Send", "token", mock.MatchedBy(func(data *model_data.CardDataRequest) bool {
+		return data.Version == 2 && data.CardNumber == "2222" && data.NameBank == "my bank" && data.Meta[data_type.MetaNameNote] == "my note"
+	})).Return(&controller.CardDataResponse{}, nil)
+	page.Choice = len(page.fields) + 2
+	m, _ = page.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	assert.IsType(t, &pageAction{}, m)
+	mockCardData.AssertExpectations(t)
+
+	m, _ = page.Update(tea.KeyMsg{Type: tea.KeyCtrlC})
+	assert.Equal(t, editPage, m)
+}
+
+func TestPageConflict_MergedKeepsServerMeta(t *testing.T) {
+	log, _ := logger.NewLogger("info")
+	mainPage := newPageIndex(new(MockManagerController), storage.NewMemoryStorage(), log)
+	local := &model_data.DataByUUIDResponse{IsText: true, TextData: model_data.TextDataRequest{
+		UUID: "text-uuid", Version: 1, Name: "note", Value: "one",
+		Meta: map[string]string{data_type.MetaNameNote: "my note"},
+	}}
+	server := &model_data.DataByUUIDResponse{IsText: true, TextData: model_data.TextDataRequest{
+		UUID: "text-uuid", Version: 2, Name: "note", Value: "two",
+		Meta: map[string]string{data_type.MetaNameNote: "note", "custom": "server value"},
+	}}
+	page := &pageConflict{mainPage: mainPage, local: local, server: server, fields: conflictFields(local, server)}
+
+	// метаданные, которых нет на странице, не теряются при отправке
+	data := page.merged()
+	assert.Equal(t, "my note", data.TextData.Meta[data_type.MetaNameNote])
+	assert.Equal(t, "server value", data.TextData.Meta["custom"])
+	// данные сервера не меняются выбором значений
+	assert.Equal(t, "note", server.TextData.Meta[data_type.MetaNameNote])
+}
+
+func TestPageConflict_TextChangedAgain(t *testing.T) {
+	log, _ := logger.NewLogger("info")
+	mockManagerController := new(MockManagerController)
+	mockItemData := new(MockItemDataController)
+	mockText := new(mockTextData)
+	mockManagerController.On("ItemData").Return(mockItemData)
+	mockManagerController.On("TextData").Return(mockText)
+	memoryStorage := storage.NewMemoryStorage()
+	memoryStorage.SetToken("token")
+	mainPage := newPageIndex(mockManagerController, memoryStorage, log)
+
+	local := &model_data.DataByUUIDResponse{IsText: true, TextData: model_data.TextDataRequest{
+		UUID: "text-uuid", Version: 1, Name: "note", Value: "one\ntwo",
+	}}
+	mockItemData.On("Send", "token", "text-uuid").Return(&model_data.DataByUUIDResponse{IsText: true, TextData: model_data.TextDataRequest{
+		UUID: "text-uuid", Version: 2, Name: "note", Value: "one\n2",
+	}}, nil).Once()
+	mockItemData.On("Send", "token", "text-uuid").Return(&model_data.DataByUUIDResponse{IsText: true, TextData: model_data.TextDataRequest{
+		UUID: "text-uuid", Version: 3, Name: "note", Value: "one\n3",
+	}}, nil)
+
+	m, ok := openConflictPage(mainPage, nil, local, &controller.VersionConflictError{Version: 2})
+	require.True(t, ok)
+	page := m.(*pageConflict)
+	assert.True(t, strings.Contains(page.View(), "- two"))
+	assert.True(t, strings.Contains(page.View(), "+ 2"))
+
+	// все значения с сервера, но сервер успел получить новую версию
+	page.Choice = len(page.fields) + 1
+	page.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	mockText.On("Send", "token", mock.MatchedBy(func(data *model_data.TextDataRequest) bool {
+		return data.Version == 2
+	})).Return(nil, &controller.VersionConflictError{Version: 3}).Once()
+	page.Choice = len(page.fields) + 2
+	m, _ = page.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	assert.Equal(t, page, m)
+	assert.Equal(t, int64(3), page.server.TextData.Version)
+	assert.True(t, page.fields[1].useServer)
+	assert.True(t, strings.Contains(page.View(), "+ 3"))
+
+	mockText.On("Send", "token", mock.MatchedBy(func(data *model_data.TextDataRequest) bool {
+		return data.Version == 3 && data.Value == "one\n3"
+	})).Return(&controller.TextDataResponse{}, nil)
+	m, _ = page.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	assert.IsType(t, &pageAction{}, m)
+}
@@ -0,0 +1,291 @@
+package view
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/northmule/gophkeeper/internal/common/data_type"
+	"github.com/northmule/gophkeeper/internal/common/model_data"
+)
+
+// Ввод/редактирование пар логин/пароль
+type pageCredentialData struct {
+	Choice          int
+	Chosen          bool
+	mainPage        *pageIndex
+	gridPage        *pageDataGrid
+	responseMessage string
+
+	// идентификатор редактирования
+	uuid string
+	// версия редактируемых данных, полученная с сервера
+	version int64
+	// данные, загруженные для редактирования
+	loaded *model_data.CredentialDataRequest
+	// поля
+	name     textinput.Model
+	username textinput.Model
+	password textinput.Model
+	urls     textinput.Model
+	notes    textinput.Model
+	meta1    textinput.Model
+	meta2    textinput.Model
+
+	isEditable bool
+}
+
+func newPageCredentialData(mainPage *pageIndex) *pageCredentialData {
+
+	name := textinput.New()
+	name.Placeholder = "Название данных"
+	name.Focus()
+	name.CharLimit = 100
+	name.Width = 100
+
+	username := textinput.New()
+	username.Placeholder = "Логин"
+	username.CharLimit = 200
+	username.Width = 100
+
+	password := textinput.New()
+	password.Placeholder = "Пароль"
+	password.EchoMode = textinput.EchoPassword
+	password.EchoCharacter = '•'
+	password.CharLimit = 500
+	password.Width = 100
+
+	urls := textinput.New()
+	urls.Placeholder = "Адреса сайтов (через запятую)"
+	urls.CharLimit = 1000
+	urls.Width = 100
+
+	notes := textinput.New()
+	notes.Placeholder = "Заметки"
+	notes.CharLimit = 2000
+	notes.Width = 100
+
+	meta1 := textinput.New()
+	meta1.Placeholder = data_type.TranslateDataType(data_type.MetaNameNote)
+	meta1.CharLimit = 100
+	meta1.Width = 100
+
+	meta2 := textinput.New()
+	meta2.Placeholder = data_type.TranslateDataType(data_type.MetaNameWebSite)
+	meta2.CharLimit = 100
+	meta2.Width = 100
+
+	m := &pageCredentialData{}
+	m.mainPage = mainPage
+
+	m.name = name
+	m.username = username
+	m.password = password
+	m.urls = urls
+	m.notes = notes
+	m.meta1 = meta1
+	m.meta2 = meta2
+
+	return m
+}
+
+// SetEditableData значения для редактирования
+func (m *pageCredentialData) SetEditableData(data *model_data.CredentialDataRequest) *pageCredentialData {
+	m.uuid = data.UUID
+	m.version = data.Version
+	m.name.SetValue(data.Name)
+	m.username.SetValue(data.Username)
+	m.password.SetValue(data.Password)
+	m.urls.SetValue(strings.Join(data.URLs, ", "))
+	m.notes.SetValue(data.Notes)
+
+	if v, ok := data.Meta[data_type.MetaNameNote]; ok {
+		m.meta1.SetValue(v)
+	}
+	if v, ok := data.Meta[data_type.MetaNameWebSite]; ok {
+		m.meta2.SetValue(v)
+	}
+
+	m.loaded = m.request()
+	m.isEditable = true
+
+	return m
+}
+
+// request данные страницы для отправки
+func (m *pageCredentialData) request() *model_data.CredentialDataRequest {
+	requestData := new(model_data.CredentialDataRequest)
+
+	requestData.UUID = m.uuid
+	requestData.Version = m.version
+	requestData.Name = m.name.Value()
+	requestData.Username = m.username.Value()
+	requestData.Password = m.password.Value()
+	requestData.URLs = splitURLs(m.urls.Value())
+	requestData.Notes = m.notes.Value()
+	requestData.Meta = make(map[string]string)
+	requestData.Meta[data_type.MetaNameNote] = m.meta1.Value()
+	requestData.Meta[data_type.MetaNameWebSite] = m.meta2.Value()
+
+	return requestData
+}
+
+// dataChanged изменение редактируемых данных на другом устройстве
+func (m *pageCredentialData) dataChanged(event dataEventMsg) *pageCredentialData {
+	if !m.isEditable || event.DataUUID != m.uuid {
+		return m
+	}
+	item, message := m.mainPage.reloadChanged(event, !reflect.DeepEqual(m.request(), m.loaded))
+	if item != nil {
+		m.SetEditableData(&item.CredentialData)
+	}
+	if message != "" {
+		m.responseMessage = message
+	}
+	return m
+}
+
+// SetPageGrid установка значения страницы
+func (m *pageCredentialData) SetPageGrid(page *pageDataGrid) *pageCredentialData {
+	m.gridPage = page
+
+	return m
+}
+
+func (m *pageCredentialData) Init() tea.Cmd {
+	return textinput.Blink
+}
+
+// Update обновление страницы
+func (m *pageCredentialData) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+
+	var cmd tea.Cmd
+
+	if event, ok := msg.(dataEventMsg); ok {
+		return m.dataChanged(event), nil
+	}
+
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		k := msg.String()
+		if k == "down" || k == "tab" {
+			m.Choice++
+			if m.Choice > 8 {
+				m.Choice = 8
+			}
+		}
+		if k == "up" {
+			m.Choice--
+			if m.Choice < 0 {
+				m.Choice = 0
+			}
+		}
+		if k == "enter" {
+			if m.Choice == 7 {
+				requestData := m.request()
+
+				_, err := m.mainPage.managerController.CredentialData().Send(m.mainPage.accessToken(), requestData)
+				if err != nil {
+					if page, ok := openConflictPage(m.mainPage, m, &model_data.DataByUUIDResponse{IsCredential: true, CredentialData: *requestData}, err); ok {
+						return page, nil
+					}
+					m.responseMessage = err.Error()
+					return m, nil
+				}
+				// Данные отправлены
+				m.responseMessage = "Данные сохранены"
+
+				return newPageAction(m.mainPage), nil
+			}
+
+			if m.Choice == 8 {
+				if m.isEditable {
+					return m.gridPage, nil
+				}
+				return newPageAction(m.mainPage), nil
+			}
+		}
+	}
+
+	if m.Choice == 0 {
+		m.name, cmd = m.name.Update(msg)
+		m.name.Focus()
+		return m, cmd
+	}
+	if m.Choice == 1 {
+		m.username, cmd = m.username.Update(msg)
+		m.username.Focus()
+		return m, cmd
+	}
+	if m.Choice == 2 {
+		m.password, cmd = m.password.Update(msg)
+		m.password.Focus()
+		return m, cmd
+	}
+	if m.Choice == 3 {
+		m.urls, cmd = m.urls.Update(msg)
+		m.urls.Focus()
+		return m, cmd
+	}
+	if m.Choice == 4 {
+		m.notes, cmd = m.notes.Update(msg)
+		m.notes.Focus()
+		return m, cmd
+	}
+	if m.Choice == 5 {
+		m.meta1, cmd = m.meta1.Update(msg)
+		m.meta1.Focus()
+		return m, cmd
+	}
+	if m.Choice == 6 {
+		m.meta2, cmd = m.meta2.Update(msg)
+		m.meta2.Focus()
+		return m, cmd
+	}
+
+	return m, nil
+}
+
+// View внешний вид
+func (m *pageCredentialData) View() string {
+
+	c := m.Choice
+
+	title := renderTitle("Логины и пароли")
+
+	tpl := "%s\n\n"
+	tpl += subtleStyle.Render("вверх/вниз: для переключения") + dotStyle +
+		subtleStyle.Render("enter: начать ввод значения") + dotStyle +
+		responseTextStyle.Render("\n"+m.responseMessage) + dotStyle
+
+	choices := fmt.Sprintf(
+		"%s\n%s\n%s\n%s\n%s\n%s\n%s\n%s\n\n%s\n",
+		renderCheckbox(m.name.View(), c == 0),
+		renderCheckbox(m.username.View(), c == 1),
+		renderCheckbox(m.password.View(), c == 2),
+		renderCheckbox(m.urls.View(), c == 3),
+		renderCheckbox(m.notes.View(), c == 4),
+		renderCheckbox(m.meta1.View(), c == 5),
+		renderCheckbox(m.meta2.View(), c == 6),
+		renderCheckbox("Отправить", c == 7),
+		renderCheckbox("Вернуться", c == 8),
+	)
+
+	s := fmt.Sprintf(tpl, choices)
+	return mainStyle.Render(title + "\n" + s + "\n\n")
+
+}
+
+// splitURLs разбор строки адресов, разделённых запятыми
+func splitURLs(value string) []string {
+	urls := make([]string, 0)
+	for _, v := range strings.Split(value, ",") {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+		urls = append(urls, v)
+	}
+	return urls
+}
@@ -0,0 +1,162 @@
+package view
+
+import (
+	"errors"
+	"path"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/northmule/gophkeeper/internal/client/config"
+	"github.com/northmule/gophkeeper/internal/client/controller"
+	"github.com/northmule/gophkeeper/internal/client/logger"
+	"github.com/northmule/gophkeeper/internal/client/service"
+	"github.com/northmule/gophkeeper/internal/client/storage"
+	"github.com/northmule/gophkeeper/internal/common/data_type"
+	"github.com/northmule/gophkeeper/internal/common/model_data"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestPageCredentialData_Init(t *testing.T) {
+	mockCfg, _ := config.NewConfig()
+	mockCfg.Value().PathPublicKeyServer = path.Join("testpath")
+	mockCfg.Value().PathKeys = path.Join("testpath")
+	log, _ := logger.NewLogger("info")
+	cryptService, _ := service.NewCrypt(mockCfg)
+	manager, _ := controller.NewManager(mockCfg, cryptService, service.NewVault(), log)
+	memoryStorage := storage.NewMemoryStorage()
+	mainPage := newPageIndex(manager, memoryStorage, log)
+	page := newPageCredentialData(mainPage)
+	cmd := page.Init()
+	assert.NotNil(t, cmd)
+}
+
+func TestPageCredentialData_Update(t *testing.T) {
+	tests := []struct {
+		name     string
+		msg      tea.Msg
+		expected pageCredentialData
+	}{
+		{"down key", tea.KeyMsg{Type: tea.KeyDown}, pageCredentialData{Choice: 1}},
+		{"up key", tea.KeyMsg{Type: tea.KeyUp}, pageCredentialData{Choice: 0}},
+		{"invalid key", tea.KeyMsg{Type: tea.KeyCtrlC}, pageCredentialData{Choice: 0}},
+	}
+	mockCfg, _ := config.NewConfig()
+	mockCfg.Value().PathPublicKeyServer = path.Join("testpath")
+	mockCfg.Value().PathKeys = path.Join("testpath")
+	log, _ := logger.NewLogger("info")
+	cryptService, _ := service.NewCrypt(mockCfg)
+	manager, _ := controller.NewManager(mockCfg, cryptService, service.NewVault(), log)
+	memoryStorage := storage.NewMemoryStorage()
+	mainPage := newPageIndex(manager, memoryStorage, log)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page := newPageCredentialData(mainPage)
+			_, _ = page.Update(tt.msg)
+			assert.Equal(t, tt.expected.Choice, page.Choice)
+		})
+	}
+
+	// прочие кейсы
+	t.Run("choice 7", func(t *testing.T) {
+		mockManagerController := new(MockManagerController)
+		mockCredentialData := new(MockCredentialDataController)
+		mockManagerController.On("CredentialData").Return(mockCredentialData)
+
+		mockCredentialData.On("Send", mock.Anything, mock.MatchedBy(func(data *model_data.CredentialDataRequest) bool {
+			return len(data.URLs) == 2 && data.URLs[1] == "https://b.example.com"
+		})).Return(&controller.CredentialDataResponse{Value: "ok"}, nil)
+
+		mainPage := newPageIndex(mockManagerController, memoryStorage, log)
+		pa := newPageCredentialData(mainPage)
+		pa.urls.SetValue("https://a.example.com, https://b.example.com,")
+		pa.Choice = 7
+		msg := tea.KeyMsg{Type: tea.KeyEnter}
+		m, _ := pa.Update(msg)
+		assert.Contains(t, "Данные сохранены", pa.responseMessage)
+		assert.NotNil(t, m)
+		mockCredentialData.AssertExpectations(t)
+	})
+
+	t.Run("choice 7 error", func(t *testing.T) {
+		mockManagerController := new(MockManagerController)
+		mockCredentialData := new(MockCredentialDataController)
+		mockManagerController.On("CredentialData").Return(mockCredentialData)
+
+		mockCredentialData.On("Send", mock.Anything, mock.Anything).Return(nil, errors.New("error"))
+
+		mainPage := newPageIndex(mockManagerController, memoryStorage, log)
+		pa := newPageCredentialData(mainPage)
+		pa.Choice = 7
+		msg := tea.KeyMsg{Type: tea.KeyEnter}
+		m, _ := pa.Update(msg)
+		assert.NotEmpty(t, pa.responseMessage)
+		assert.NotNil(t, m)
+	})
+
+	t.Run("choice 8", func(t *testing.T) {
+		mainPage := newPageIndex(new(MockManagerController), memoryStorage, log)
+		pa := pageCredentialData{Choice: 8, mainPage: mainPage}
+		pa.isEditable = false
+		msg := tea.KeyMsg{Type: tea.KeyEnter}
+		m, _ := pa.Update(msg)
+		assert.NotNil(t, m)
+
+		pa.isEditable = true
+		m, _ = pa.Update(msg)
+		assert.Nil(t, m)
+	})
+
+	t.Run("choice 1-6", func(t *testing.T) {
+		mainPage := newPageIndex(new(MockManagerController), memoryStorage, log)
+		pa := newPageCredentialData(mainPage)
+		msg := tea.KeyMsg{Type: tea.KeyEnter}
+		for num := 1; num <= 6; num++ {
+			pa.Choice = num
+			m, _ := pa.Update(msg)
+			assert.NotNil(t, m)
+		}
+	})
+}
+
+func TestPageCredentialData_View(t *testing.T) {
+	mockCfg, _ := config.NewConfig()
+	mockCfg.Value().PathPublicKeyServer = path.Join("testpath")
+	mockCfg.Value().PathKeys = path.Join("testpath")
+	log, _ := logger.NewLogger("info")
+	cryptService, _ := service.NewCrypt(mockCfg)
+	manager, _ := controller.NewManager(mockCfg, cryptService, service.NewVault(), log)
+	memoryStorage := storage.NewMemoryStorage()
+	mainPage := newPageIndex(manager, memoryStorage, log)
+
+	page := newPageCredentialData(mainPage)
+	page.password.SetValue("secret")
+	result := page.View()
+	assert.True(t, strings.Contains(result, "Логины и пароли"))
+	assert.True(t, strings.Contains(result, "Отправить"))
+	assert.True(t, strings.Contains(result, "Вернуться"))
+	assert.False(t, strings.Contains(result, "secret"))
+}
+
+func TestPageCredentialData_SetEditableData(t *testing.T) {
+	data := &model_data.CredentialDataRequest{
+		Name:     "Mail",
+		UUID:     "123e4567-e89b-12d3-a456-426614174000",
+		Username: "john",
+		Password: "secret",
+		URLs:     []string{"https://a.example.com", "https://b.example.com"},
+		Notes:    "work",
+		Meta: map[string]string{
+			data_type.MetaNameNote:    "value1",
+			data_type.MetaNameWebSite: "value2",
+		},
+	}
+	pa := pageCredentialData{}
+	pa.SetEditableData(data)
+
+	assert.True(t, pa.isEditable)
+	assert.Equal(t, "john", pa.username.Value())
+	assert.Equal(t, "https://a.example.com, https://b.example.com", pa.urls.Value())
+	assert.Equal(t, "value1", pa.meta1.Value())
+}
@@ -2,10 +2,12 @@ package view
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/charmbracelet/bubbles/table"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/northmule/gophkeeper/internal/client/controller"
 )
 
 var baseStyle = lipgloss.NewStyle().
@@ -17,33 +19,68 @@ type pageDataGrid struct {
 	mainPage   *pageIndex
 	actionPage *pageAction
 	table      table.Model
+	// список из локальной копии хранилища, данные только для просмотра
+	offline  bool
+	syncedAt time.Time
 }
 
 func newPageDataGrid(mainPage *pageIndex, actionPage *pageAction) *pageDataGrid {
 	m := &pageDataGrid{}
 	m.mainPage = mainPage
 	m.actionPage = actionPage
+	m.table = newDataGridTable()
 
-	columns := []table.Column{
-		{Title: "№", Width: 4},
-		{Title: "Тип", Width: 30},
-		{Title: "Название", Width: 60},
-		{Title: "UUID", Width: 40},
+	// очередь изменений отправляется до запроса списка, чтобы список их учитывал
+	m.mainPage.replayOutbox()
+	rowsData, err := m.mainPage.managerController.GridData().Send(m.mainPage.accessToken())
+	if err != nil {
+		tea.Println(err)
+		return m
 	}
+	m.setRows(rowsData)
 
-	rowsData, err := m.mainPage.managerController.GridData().Send(m.mainPage.storage.Token())
+	return m
+}
+
+// newPageOfflineDataGrid список из локальной копии хранилища, открытой без сервера
+func newPageOfflineDataGrid(mainPage *pageIndex) *pageDataGrid {
+	m := &pageDataGrid{}
+	m.mainPage = mainPage
+	m.table = newDataGridTable()
+
+	rowsData, err := m.mainPage.managerController.Offline().List()
 	if err != nil {
 		tea.Println(err)
 		return m
 	}
+	m.setRows(rowsData)
+
+	return m
+}
+
+// setRows строки таблицы из ответа
+func (m *pageDataGrid) setRows(rowsData *controller.GridDataResponse) {
 	var rows []table.Row
 	for _, item := range rowsData.Items {
-		rows = append(rows, table.Row{item.Number, item.Type, item.Name, item.UUID})
+		rows = append(rows, table.Row{item.Number, item.Type, item.Name, item.UpdateDate, item.UUID})
+	}
+	m.table.SetRows(rows)
+	m.offline = rowsData.Offline
+	m.syncedAt = rowsData.SyncedAt
+}
+
+// newDataGridTable таблица данных
+func newDataGridTable() table.Model {
+	columns := []table.Column{
+		{Title: "№", Width: 4},
+		{Title: "Тип", Width: 30},
+		{Title: "Название", Width: 40},
+		{Title: "Изменено", Width: 20},
+		{Title: "UUID", Width: 40},
 	}
 
 	t := table.New(
 		table.WithColumns(columns),
-		table.WithRows(rows),
 		table.WithFocused(true),
 		table.WithHeight(7),
 	)
@@ -60,9 +97,7 @@ func newPageDataGrid(mainPage *pageIndex, actionPage *pageAction) *pageDataGrid
 		Bold(false)
 	t.SetStyles(s)
 
-	m.table = t
-
-	return m
+	return t
 }
 
 func (m *pageDataGrid) Init() tea.Cmd { return nil }
@@ -70,14 +105,42 @@ func (m *pageDataGrid) Init() tea.Cmd { return nil }
 func (m *pageDataGrid) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var cmd tea.Cmd
 	switch msg := msg.(type) {
+	case dataEventMsg:
+		if m.actionPage == nil { // хранилище открыто без сервера
+			return m, nil
+		}
+		// список получается заново, с офлайн-копией загружаются только изменения после курсора
+		rowsData, err := m.mainPage.managerController.GridData().Send(m.mainPage.accessToken())
+		if err != nil {
+			m.mainPage.log.Error(err)
+			return m, nil
+		}
+		m.setRows(rowsData)
+		return m, nil
 	case tea.KeyMsg:
 		switch msg.String() {
 		case "ctrl+c":
+			if m.actionPage == nil {
+				// выход из хранилища, открытого без сервера
+				m.mainPage.managerController.Offline().Lock()
+				return m.mainPage, nil
+			}
 			return m.actionPage, nil
 		case "enter":
-			dataUUID := m.table.SelectedRow()[3]
+			if m.table.SelectedRow() == nil {
+				return m, nil
+			}
+			dataUUID := m.table.SelectedRow()[4]
+
+			if m.offline {
+				itemResponse, err := m.mainPage.managerController.Offline().Item(dataUUID)
+				if err != nil {
+					return m, tea.Printf("Произошла ошибка: %s!", err)
+				}
+				return newPageOfflineItem(m, itemResponse), nil
+			}
 
-			itemResponse, err := m.mainPage.managerController.ItemData().Send(m.mainPage.storage.Token(), dataUUID)
+			itemResponse, err := m.mainPage.managerController.ItemData().Send(m.mainPage.accessToken(), dataUUID)
 			if err != nil {
 				return m, tea.Batch(
 					tea.Printf("Произошла ошибка: %s!", err),
@@ -91,6 +154,10 @@ func (m *pageDataGrid) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				return newPageTextData(m.mainPage).SetEditableData(&itemResponse.TextData).SetPageGrid(m), nil
 			}
 
+			if itemResponse.IsCredential {
+				return newPageCredentialData(m.mainPage).SetEditableData(&itemResponse.CredentialData).SetPageGrid(m), nil
+			}
+
 			if itemResponse.IsFile {
 				return newPageFileData(m.mainPage).SetEditableData(&itemResponse.FileData).SetPageGrid(m), nil
 			}
@@ -108,6 +175,9 @@ func (m *pageDataGrid) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 func (m pageDataGrid) View() string {
 	title := renderTitle("Все данные")
 	tpl := "%s\n\n"
+	if m.offline {
+		tpl += responseTextStyle.Render("нет связи с сервером, данные на "+m.syncedAt.Format(time.DateTime)+" только для просмотра") + "\n"
+	}
 	tpl += subtleStyle.Render("вверх/вниз: для переключения") + dotStyle +
 		subtleStyle.Render("enter: просмотреть данные") + dotStyle +
 		subtleStyle.Render("ctrl+c: вернуться") + dotStyle
@@ -21,6 +21,11 @@ type MockManagerController struct {
 	mock.Mock
 }
 
+func (m *MockManagerController) Audit() controller.AuditController {
+	args := m.Called()
+	return args.Get(0).(controller.AuditController)
+}
+
 func (m *MockManagerController) Authentication() controller.AuthenticationDataController {
 	args := m.Called()
 	return args.Get(0).(controller.AuthenticationDataController)
@@ -31,6 +36,21 @@ func (m *MockManagerController) CardData() controller.CardDataController {
 	return args.Get(0).(controller.CardDataController)
 }
 
+func (m *MockManagerController) Devices() controller.DevicesController {
+	args := m.Called()
+	return args.Get(0).(controller.DevicesController)
+}
+
+func (m *MockManagerController) CredentialData() controller.CredentialDataController {
+	args := m.Called()
+	return args.Get(0).(controller.CredentialDataController)
+}
+
+func (m *MockManagerController) Events() controller.EventsController {
+	args := m.Called()
+	return args.Get(0).(controller.EventsController)
+}
+
 func (m *MockManagerController) TextData() controller.TextDataController {
 	args := m.Called()
 	return args.Get(0).(*mockTextData)
@@ -56,6 +76,26 @@ func (m *MockManagerController) KeysData() controller.KeyDataController {
 	return args.Get(0).(controller.KeyDataController)
 }
 
+func (m *MockManagerController) MasterKey() controller.MasterKeyController {
+	args := m.Called()
+	return args.Get(0).(controller.MasterKeyController)
+}
+
+func (m *MockManagerController) Offline() controller.OfflineController {
+	args := m.Called()
+	return args.Get(0).(controller.OfflineController)
+}
+
+func (m *MockManagerController) Outbox() controller.OutboxController {
+	args := m.Called()
+	return args.Get(0).(controller.OutboxController)
+}
+
+func (m *MockManagerController) Recovery() controller.RecoveryController {
+	args := m.Called()
+	return args.Get(0).(controller.RecoveryController)
+}
+
 func (m *MockManagerController) Registration() controller.RegistrationController {
 	args := m.Called()
 	return args.Get(0).(*mockRegistration)
@@ -100,6 +140,35 @@ func (m *MockAuthenticationDataController) Send(login string, password string) (
 	return args.Get(0).(*controller.AuthenticationResponse), args.Error(1)
 }
 
+func (m *MockAuthenticationDataController) SendCode(mfaToken string, code string) (*controller.AuthenticationResponse, error) {
+	args := m.Called(mfaToken, code)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*controller.AuthenticationResponse), args.Error(1)
+}
+
+func (m *MockAuthenticationDataController) SendCertificate() (*controller.AuthenticationResponse, error) {
+	args := m.Called()
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*controller.AuthenticationResponse), args.Error(1)
+}
+
+func (m *MockAuthenticationDataController) Refresh(refreshToken string) (*controller.AuthenticationResponse, error) {
+	args := m.Called(refreshToken)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*controller.AuthenticationResponse), args.Error(1)
+}
+
+func (m *MockAuthenticationDataController) Logout(token string) error {
+	args := m.Called(token)
+	return args.Error(0)
+}
+
 // MockKeyDataController mock
 type MockKeyDataController struct {
 	mock.Mock
@@ -120,6 +189,179 @@ func (m *MockKeyDataController) UploadClientPrivateKey(token string) error {
 	return args.Error(0)
 }
 
+func (m *MockKeyDataController) RotateClientPrivateKey(token string) (*model_data.KeyRotationResponse, error) {
+	args := m.Called(token)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model_data.KeyRotationResponse), args.Error(1)
+}
+
+func (m *MockKeyDataController) KeyRotationStatus(token string) (*model_data.KeyRotationResponse, error) {
+	args := m.Called(token)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model_data.KeyRotationResponse), args.Error(1)
+}
+
+// MockMasterKeyController mock
+type MockMasterKeyController struct {
+	mock.Mock
+}
+
+func (m *MockMasterKeyController) Unlock(token string, masterPassword string) error {
+	args := m.Called(token, masterPassword)
+	return args.Error(0)
+}
+
+func (m *MockMasterKeyController) Migrate(token string) error {
+	args := m.Called(token)
+	return args.Error(0)
+}
+
+func (m *MockMasterKeyController) Lock() {
+	m.Called()
+}
+
+// MockOfflineController mock
+type MockOfflineController struct {
+	mock.Mock
+}
+
+func (m *MockOfflineController) Open() error {
+	args := m.Called()
+	return args.Error(0)
+}
+
+func (m *MockOfflineController) Unlock(masterPassword string) error {
+	args := m.Called(masterPassword)
+	return args.Error(0)
+}
+
+func (m *MockOfflineController) Lock() {
+	m.Called()
+}
+
+func (m *MockOfflineController) List() (*controller.GridDataResponse, error) {
+	args := m.Called()
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*controller.GridDataResponse), args.Error(1)
+}
+
+func (m *MockOfflineController) Item(dataUUID string) (*model_data.DataByUUIDResponse, error) {
+	args := m.Called(dataUUID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model_data.DataByUUIDResponse), args.Error(1)
+}
+
+// MockOutboxController mock
+type MockOutboxController struct {
+	mock.Mock
+}
+
+func (m *MockOutboxController) List() ([]storage.OutboxEntry, error) {
+	args := m.Called()
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]storage.OutboxEntry), args.Error(1)
+}
+
+func (m *MockOutboxController) Replay(token string) (int, error) {
+	args := m.Called(token)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockOutboxController) Discard(key string) error {
+	args := m.Called(key)
+	return args.Error(0)
+}
+
+// MockAuditController mock
+type MockAuditController struct {
+	mock.Mock
+}
+
+func (m *MockAuditController) List(token string, filter controller.AuditFilter) (*model_data.AuditListResponse, error) {
+	args := m.Called(token, filter)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model_data.AuditListResponse), args.Error(1)
+}
+
+func (m *MockAuditController) Verify(token string) (*model_data.AuditVerifyResponse, error) {
+	args := m.Called(token)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model_data.AuditVerifyResponse), args.Error(1)
+}
+
+// MockDevicesController mock
+type MockDevicesController struct {
+	mock.Mock
+}
+
+func (m *MockDevicesController) List(token string) (*model_data.DeviceListResponse, error) {
+	args := m.Called(token)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model_data.DeviceListResponse), args.Error(1)
+}
+
+func (m *MockDevicesController) Rename(token string, deviceUUID string, name string) error {
+	args := m.Called(token, deviceUUID, name)
+	return args.Error(0)
+}
+
+func (m *MockDevicesController) Revoke(token string, deviceUUID string) error {
+	args := m.Called(token, deviceUUID)
+	return args.Error(0)
+}
+
+func (m *MockDevicesController) Unlock(token string) error {
+	args := m.Called(token)
+	return args.Error(0)
+}
+
+// MockRecoveryController mock
+type MockRecoveryController struct {
+	mock.Mock
+}
+
+func (m *MockRecoveryController) CreateKit(token string) (*controller.RecoveryKit, error) {
+	args := m.Called(token)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*controller.RecoveryKit), args.Error(1)
+}
+
+func (m *MockRecoveryController) EnsureKit(token string) (*controller.RecoveryKit, error) {
+	args := m.Called(token)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*controller.RecoveryKit), args.Error(1)
+}
+
+func (m *MockRecoveryController) RestoreClientKey(token string) error {
+	args := m.Called(token)
+	return args.Error(0)
+}
+
+func (m *MockRecoveryController) Restore(token string, secret string) error {
+	args := m.Called(token, secret)
+	return args.Error(0)
+}
+
 // MockCardDataController mock
 type MockCardDataController struct {
 	mock.Mock
@@ -133,13 +375,26 @@ func (m *MockCardDataController) Send(token string, requestData *model_data.Card
 	return args.Get(0).(*controller.CardDataResponse), args.Error(1)
 }
 
+// MockCredentialDataController mock
+type MockCredentialDataController struct {
+	mock.Mock
+}
+
+func (m *MockCredentialDataController) Send(token string, requestData *model_data.CredentialDataRequest) (*controller.CredentialDataResponse, error) {
+	args := m.Called(token, requestData)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*controller.CredentialDataResponse), args.Error(1)
+}
+
 func TestPageDataGrid_Init(t *testing.T) {
 	mockCfg, _ := config.NewConfig()
 	mockCfg.Value().PathPublicKeyServer = path.Join("testpath")
 	mockCfg.Value().PathKeys = path.Join("testpath")
 	log, _ := logger.NewLogger("info")
 	cryptService, _ := service.NewCrypt(mockCfg)
-	manager, _ := controller.NewManager(mockCfg, cryptService, log)
+	manager, _ := controller.NewManager(mockCfg, cryptService, service.NewVault(), log)
 	memoryStorage := storage.NewMemoryStorage()
 	mainPage := newPageIndex(manager, memoryStorage, log)
 
@@ -158,6 +413,7 @@ func TestNewPageDataGrid(t *testing.T) {
 
 	mockStorage.SetToken("token")
 	mockManagerController.On("GridData").Return(mockGridDataController)
+	mockManagerController.On("Outbox").Return(newMockOutboxEmpty())
 
 	result := new(controller.GridDataResponse)
 	result.Items = []model_data.ItemDataResponse{
@@ -178,7 +434,7 @@ func TestPageDataGrid_View(t *testing.T) {
 	mockCfg.Value().PathKeys = path.Join("testpath")
 	log, _ := logger.NewLogger("info")
 	cryptService, _ := service.NewCrypt(mockCfg)
-	manager, _ := controller.NewManager(mockCfg, cryptService, log)
+	manager, _ := controller.NewManager(mockCfg, cryptService, service.NewVault(), log)
 	memoryStorage := storage.NewMemoryStorage()
 	mainPage := newPageIndex(manager, memoryStorage, log)
 	pa := newPageAction(mainPage)
@@ -202,6 +458,7 @@ func TestPageDataGrid_Update(t *testing.T) {
 		mockGridData := new(MockGridDataController)
 		mockManagerController.On("ItemData").Return(mockItemData)
 		mockManagerController.On("GridData").Return(mockGridData)
+		mockManagerController.On("Outbox").Return(newMockOutboxEmpty())
 
 		responseData := new(controller.GridDataResponse)
 		responseData.Items = []model_data.ItemDataResponse{
@@ -235,6 +492,7 @@ func TestPageDataGrid_Update(t *testing.T) {
 		mockGridData := new(MockGridDataController)
 		mockManagerController.On("ItemData").Return(mockItemData)
 		mockManagerController.On("GridData").Return(mockGridData)
+		mockManagerController.On("Outbox").Return(newMockOutboxEmpty())
 
 		responseData := new(controller.GridDataResponse)
 		responseData.Items = []model_data.ItemDataResponse{
@@ -262,12 +520,47 @@ func TestPageDataGrid_Update(t *testing.T) {
 
 	})
 
+	t.Run("enter IsCredential", func(t *testing.T) {
+		mockManagerController := new(MockManagerController)
+		mockItemData := new(MockItemDataController)
+		mockGridData := new(MockGridDataController)
+		mockManagerController.On("ItemData").Return(mockItemData)
+		mockManagerController.On("GridData").Return(mockGridData)
+		mockManagerController.On("Outbox").Return(newMockOutboxEmpty())
+
+		responseData := new(controller.GridDataResponse)
+		responseData.Items = []model_data.ItemDataResponse{
+			{
+				Number: "1",
+				Type:   "type1",
+				Name:   "name1",
+				UUID:   "111",
+			},
+			{
+				Number: "2",
+				Type:   "type2",
+				Name:   "name2",
+				UUID:   "22222",
+			},
+		}
+		mockGridData.On("Send", mock.Anything).Return(responseData, nil)
+
+		mainPage := newPageIndex(mockManagerController, memoryStorage, log)
+		actionPage := newPageAction(mainPage)
+		mockItemData.On("Send", mock.Anything, mock.Anything).Return(&model_data.DataByUUIDResponse{IsCredential: true}, nil)
+		pa := newPageDataGrid(mainPage, actionPage)
+		m, _ := pa.Update(msg)
+		assert.NotNil(t, m)
+
+	})
+
 	t.Run("enter IsCard", func(t *testing.T) {
 		mockManagerController := new(MockManagerController)
 		mockItemData := new(MockItemDataController)
 		mockGridData := new(MockGridDataController)
 		mockManagerController.On("ItemData").Return(mockItemData)
 		mockManagerController.On("GridData").Return(mockGridData)
+		mockManagerController.On("Outbox").Return(newMockOutboxEmpty())
 
 		responseData := new(controller.GridDataResponse)
 		responseData.Items = []model_data.ItemDataResponse{
@@ -301,6 +594,7 @@ func TestPageDataGrid_Update(t *testing.T) {
 		mockGridData := new(MockGridDataController)
 		mockManagerController.On("ItemData").Return(mockItemData)
 		mockManagerController.On("GridData").Return(mockGridData)
+		mockManagerController.On("Outbox").Return(newMockOutboxEmpty())
 
 		responseData := new(controller.GridDataResponse)
 		responseData.Items = []model_data.ItemDataResponse{
@@ -328,3 +622,32 @@ func TestPageDataGrid_Update(t *testing.T) {
 
 	})
 }
+
+func TestPageDataGrid_DataEvent(t *testing.T) {
+	log, _ := logger.NewLogger("info")
+	memoryStorage := storage.NewMemoryStorage()
+	memoryStorage.SetToken("token")
+	mockManagerController := new(MockManagerController)
+	mockGridData := new(MockGridDataController)
+	mockManagerController.On("GridData").Return(mockGridData)
+	mockManagerController.On("Outbox").Return(newMockOutboxEmpty())
+
+	first := new(controller.GridDataResponse)
+	first.Items = []model_data.ItemDataResponse{{Number: "1", Type: "Card", Name: "Card1", UUID: "uuid1"}}
+	second := new(controller.GridDataResponse)
+	second.Items = []model_data.ItemDataResponse{
+		{Number: "1", Type: "Card", Name: "Card1", UUID: "uuid1"},
+		{Number: "2", Type: "Text", Name: "Text1", UUID: "uuid2"},
+	}
+	mockGridData.On("Send", "token").Return(first, nil).Once()
+	mockGridData.On("Send", "token").Return(second, nil).Once()
+
+	mainPage := newPageIndex(mockManagerController, memoryStorage, log)
+	page := newPageDataGrid(mainPage, newPageAction(mainPage))
+	assert.Len(t, page.table.Rows(), 1)
+
+	m, _ := page.Update(dataEventMsg{Type: "item_created", DataType: "text", DataUUID: "uuid2"})
+	assert.Equal(t, page, m)
+	assert.Len(t, page.table.Rows(), 2)
+	mockGridData.AssertExpectations(t)
+}
@@ -0,0 +1,194 @@
+package view
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/charmbracelet/bubbles/table"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/northmule/gophkeeper/internal/common/model_data"
+)
+
+// Экран устройств пользователя: переименование и отзыв потерянного устройства
+type pageDevices struct {
+	mainPage        *pageIndex
+	prevPage        tea.Model
+	table           table.Model
+	devices         []model_data.DeviceResponse
+	name            textinput.Model
+	renaming        bool
+	responseMessage string
+}
+
+func newPageDevices(mainPage *pageIndex, prevPage tea.Model) *pageDevices {
+	m := &pageDevices{
+		mainPage: mainPage,
+		prevPage: prevPage,
+	}
+
+	name := textinput.New()
+	name.Placeholder = "Новое имя устройства"
+	name.CharLimit = 100
+	name.Width = 50
+	m.name = name
+
+	columns := []table.Column{
+		{Title: "Имя", Width: 30},
+		{Title: "Последний вход", Width: 20},
+		{Title: "Добавлено", Width: 20},
+		{Title: "Состояние", Width: 20},
+		{Title: "UUID", Width: 38},
+	}
+	t := table.New(
+		table.WithColumns(columns),
+		table.WithFocused(true),
+		table.WithHeight(10),
+	)
+	s := table.DefaultStyles()
+	s.Header = s.Header.
+		BorderStyle(lipgloss.NormalBorder()).
+		BorderForeground(lipgloss.Color("240")).
+		BorderBottom(true).
+		Bold(false)
+	s.Selected = s.Selected.
+		Foreground(lipgloss.Color("229")).
+		Background(lipgloss.Color("57")).
+		Bold(false)
+	t.SetStyles(s)
+	m.table = t
+
+	m.load()
+	return m
+}
+
+// load загрузка списка устройств
+func (m *pageDevices) load() {
+	list, err := m.mainPage.managerController.Devices().List(m.mainPage.accessToken())
+	if err != nil {
+		m.responseMessage = err.Error()
+		return
+	}
+	m.devices = list.Devices
+	var rows []table.Row
+	for _, device := range list.Devices {
+		state := "активно"
+		if device.Current {
+			state = "текущее"
+		}
+		if device.RevokedAt > 0 {
+			state = "отозвано " + time.Unix(device.RevokedAt, 0).Format(time.DateOnly)
+		}
+		rows = append(rows, table.Row{
+			device.Name,
+			time.Unix(device.LastSeenAt, 0).Format(time.DateTime),
+			time.Unix(device.CreatedAt, 0).Format(time.DateTime),
+			state,
+			device.UUID,
+		})
+	}
+	m.table.SetRows(rows)
+}
+
+// selected выбранное в таблице устройство
+func (m *pageDevices) selected() (model_data.DeviceResponse, bool) {
+	cursor := m.table.Cursor()
+	if cursor < 0 || cursor >= len(m.devices) {
+		return model_data.DeviceResponse{}, false
+	}
+	return m.devices[cursor], true
+}
+
+func (m *pageDevices) Init() tea.Cmd { return nil }
+
+func (m *pageDevices) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+	if m.renaming {
+		return m.updateRename(msg)
+	}
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		switch msg.String() {
+		case "ctrl+c":
+			return m.prevPage, nil
+		case "r":
+			device, ok := m.selected()
+			if !ok {
+				return m, nil
+			}
+			m.renaming = true
+			m.name.SetValue(device.Name)
+			m.name.Focus()
+			return m, textinput.Blink
+		case "x":
+			device, ok := m.selected()
+			if !ok || device.RevokedAt > 0 {
+				return m, nil
+			}
+			err := m.mainPage.managerController.Devices().Revoke(m.mainPage.accessToken(), device.UUID)
+			if err != nil {
+				m.responseMessage = err.Error()
+				return m, nil
+			}
+			if device.Current {
+				// сессия этого клиента закрыта на сервере вместе с устройством
+				m.mainPage.storage.ResetToken()
+				m.mainPage.managerController.MasterKey().Lock()
+				return m.mainPage, nil
+			}
+			m.load()
+			m.responseMessage = fmt.Sprintf("устройство %s отозвано", device.Name)
+			return m, nil
+		}
+	}
+	m.table, cmd = m.table.Update(msg)
+	return m, cmd
+}
+
+// updateRename ввод нового имени устройства
+func (m *pageDevices) updateRename(msg tea.Msg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		switch msg.String() {
+		case "ctrl+c", "esc":
+			m.renaming = false
+			m.name.Blur()
+			return m, nil
+		case "enter":
+			device, ok := m.selected()
+			if !ok || m.name.Value() == "" {
+				return m, nil
+			}
+			err := m.mainPage.managerController.Devices().Rename(m.mainPage.accessToken(), device.UUID, m.name.Value())
+			if err != nil {
+				m.responseMessage = err.Error()
+				return m, nil
+			}
+			m.renaming = false
+			m.name.Blur()
+			m.load()
+			return m, nil
+		}
+	}
+	m.name, cmd = m.name.Update(msg)
+	return m, cmd
+}
+
+// View контент страницы
+func (m *pageDevices) View() string {
+	title := renderTitle("Устройства")
+	// имя устройства вводит пользователь, поэтому без шаблона fmt
+	s := baseStyle.Render(m.table.View()) + "\n\n"
+	if m.renaming {
+		s += m.name.View() + "\n\n" +
+			subtleStyle.Render("enter: сохранить") + dotStyle +
+			subtleStyle.Render("esc: отмена") + dotStyle
+	} else {
+		s += subtleStyle.Render("вверх/вниз: для переключения") + dotStyle +
+			subtleStyle.Render("r: переименовать") + dotStyle +
+			subtleStyle.Render("x: отозвать") + dotStyle +
+			subtleStyle.Render("ctrl+c: вернуться") + dotStyle
+	}
+	s += responseTextStyle.Render("\n" + m.responseMessage)
+	return mainStyle.Render(title + "\n" + s + "\n\n")
+}
@@ -0,0 +1,109 @@
+package view
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/northmule/gophkeeper/internal/client/logger"
+	"github.com/northmule/gophkeeper/internal/client/storage"
+	"github.com/northmule/gophkeeper/internal/common/model_data"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func devicesList() *model_data.DeviceListResponse {
+	return &model_data.DeviceListResponse{Devices: []model_data.DeviceResponse{
+		{UUID: "device-1", Name: "laptop", Current: true},
+		{UUID: "device-2", Name: "phone"},
+	}}
+}
+
+func TestPageDevices_Update(t *testing.T) {
+	log, _ := logger.NewLogger("info")
+
+	t.Run("list error", func(t *testing.T) {
+		mockManagerController := new(MockManagerController)
+		mockDevices := new(MockDevicesController)
+		mockManagerController.On("Devices").Return(mockDevices)
+		mockDevices.On("List", mock.Anything).Return(nil, errors.New("вы не авторизованы"))
+
+		mainPage := newPageIndex(mockManagerController, storage.NewMemoryStorage(), log)
+		page := newPageDevices(mainPage, mainPage)
+		assert.Equal(t, "вы не авторизованы", page.responseMessage)
+		assert.Empty(t, page.table.Rows())
+	})
+
+	t.Run("rename", func(t *testing.T) {
+		mockManagerController := new(MockManagerController)
+		mockDevices := new(MockDevicesController)
+		mockManagerController.On("Devices").Return(mockDevices)
+		mockDevices.On("List", mock.Anything).Return(devicesList(), nil)
+		mockDevices.On("Rename", mock.Anything, "device-1", "laptop 100%").Return(nil)
+
+		mainPage := newPageIndex(mockManagerController, storage.NewMemoryStorage(), log)
+		page := newPageDevices(mainPage, mainPage)
+		assert.Len(t, page.table.Rows(), 2)
+		assert.Equal(t, "текущее", page.table.Rows()[0][3])
+
+		_, _ = page.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("r")})
+		assert.True(t, page.renaming)
+		assert.Equal(t, "laptop", page.name.Value())
+		_, _ = page.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(" 100%")})
+		assert.True(t, strings.Contains(page.View(), "laptop 100%"))
+		_, _ = page.Update(tea.KeyMsg{Type: tea.KeyEnter})
+		assert.False(t, page.renaming)
+		mockDevices.AssertExpectations(t)
+	})
+
+	t.Run("revoke", func(t *testing.T) {
+		mockManagerController := new(MockManagerController)
+		mockDevices := new(MockDevicesController)
+		mockManagerController.On("Devices").Return(mockDevices)
+		mockDevices.On("List", mock.Anything).Return(devicesList(), nil)
+		mockDevices.On("Revoke", mock.Anything, "device-2").Return(nil)
+
+		mainPage := newPageIndex(mockManagerController, storage.NewMemoryStorage(), log)
+		page := newPageDevices(mainPage, mainPage)
+		_, _ = page.Update(tea.KeyMsg{Type: tea.KeyDown})
+		m, _ := page.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("x")})
+		assert.Equal(t, page, m)
+		assert.Equal(t, "устройство phone отозвано", page.responseMessage)
+		mockDevices.AssertExpectations(t)
+	})
+
+	t.Run("revoke current", func(t *testing.T) {
+		mockManagerController := new(MockManagerController)
+		mockDevices := new(MockDevicesController)
+		mockMasterKey := new(MockMasterKeyController)
+		mockManagerController.On("Devices").Return(mockDevices)
+		mockManagerController.On("MasterKey").Return(mockMasterKey)
+		mockDevices.On("List", mock.Anything).Return(devicesList(), nil)
+		mockDevices.On("Revoke", mock.Anything, "device-1").Return(nil)
+		mockMasterKey.On("Lock").Return()
+
+		memoryStorage := storage.NewMemoryStorage()
+		memoryStorage.SetToken("token")
+		mainPage := newPageIndex(mockManagerController, memoryStorage, log)
+		page := newPageDevices(mainPage, mainPage)
+		m, _ := page.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("x")})
+		// сессия закрыта вместе с устройством
+		assert.Equal(t, mainPage, m)
+		assert.Empty(t, memoryStorage.Token())
+		mockMasterKey.AssertExpectations(t)
+	})
+
+	t.Run("back", func(t *testing.T) {
+		mockManagerController := new(MockManagerController)
+		mockDevices := new(MockDevicesController)
+		mockManagerController.On("Devices").Return(mockDevices)
+		mockDevices.On("List", mock.Anything).Return(devicesList(), nil)
+
+		mainPage := newPageIndex(mockManagerController, storage.NewMemoryStorage(), log)
+		prevPage := newPageAction(mainPage)
+		page := newPageDevices(mainPage, prevPage)
+		m, _ := page.Update(tea.KeyMsg{Type: tea.KeyCtrlC})
+		assert.Equal(t, prevPage, m)
+	})
+}
@@ -1,6 +1,7 @@
 package view
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"time"
@@ -8,8 +9,10 @@ import (
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/gabriel-vasile/mimetype"
+	"github.com/northmule/gophkeeper/internal/client/controller"
 	"github.com/northmule/gophkeeper/internal/common/data_type"
 	"github.com/northmule/gophkeeper/internal/common/model_data"
+	"github.com/northmule/gophkeeper/internal/common/models"
 )
 
 // Ввод/редактирование данных о файле
@@ -24,6 +27,8 @@ type pageFileData struct {
 
 	// идентификатор редактирования
 	uuid string
+	// версия редактируемых данных, полученная с сервера
+	version int64
 	// поля на основание файла
 	mimeType  string
 	extension string
@@ -76,6 +81,7 @@ func newPageFileData(mainPage *pageIndex) *pageFileData {
 // SetEditableData значения для редактирования
 func (m *pageFileData) SetEditableData(data *model_data.FileDataInitRequest) *pageFileData {
 	m.uuid = data.UUID
+	m.version = data.Version
 	m.name.SetValue(data.Name)
 	m.filePath.SetValue(data.FileName)
 	m.fileName = data.FileName
@@ -106,7 +112,19 @@ func (m *pageFileData) Init() tea.Cmd {
 func (m *pageFileData) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var cmd tea.Cmd
 
-	switch msg.(type) {
+	switch msg := msg.(type) {
+	case dataEventMsg:
+		if !m.isEditable || msg.DataUUID != m.uuid {
+			return m, nil
+		}
+		switch msg.Type {
+		case models.EventItemDeleted:
+			m.responseMessage = "Файл удалён на другом устройстве"
+		case models.EventItemUpdated:
+			// файл не загружается заново: новая версия скачивается по ctrl+d
+			m.responseMessage = "Файл изменён на другом устройстве"
+		}
+		return m, nil
 	case clearErrorMsg:
 		m.err = nil
 		m.responseMessage = ""
@@ -141,6 +159,7 @@ func (m *pageFileData) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				requestData := new(model_data.FileDataInitRequest)
 
 				requestData.UUID = m.uuid
+				requestData.Version = m.version
 				requestData.Name = m.name.Value()
 				// Информация о файле
 				if m.selectedFile == "" {
@@ -162,14 +181,12 @@ func (m *pageFileData) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				requestData.Meta[data_type.MetaNameNote] = m.meta1.Value()
 				requestData.Meta[data_type.MetaNameWebSite] = m.meta2.Value()
 
-				fresponse, err := m.mainPage.managerController.FileData().Send(m.mainPage.storage.Token(), requestData)
-				if err != nil {
+				// Предзагрузка информации о файле и отправка самого файла
+				err := m.mainPage.managerController.FileData().SendFile(m.mainPage.accessToken(), requestData, m.selectedFile)
+				if errors.Is(err, controller.ErrQueued) {
 					m.responseMessage = err.Error()
-					return m, tea.Batch(cmd, clearErrorAfter(3*time.Second))
+					return m, tea.Batch(cmd, clearErrorAfter(3*time.Second), clearFieldAfter(1*time.Second))
 				}
-
-				// Отправка самого файла
-				err = m.mainPage.managerController.FileData().UploadFile(m.mainPage.storage.Token(), fresponse.UploadPath, file)
 				if err != nil {
 					m.responseMessage = err.Error()
 					return m, tea.Batch(cmd, clearErrorAfter(3*time.Second))
@@ -187,7 +204,7 @@ func (m *pageFileData) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 
 		if k == "ctrl+d" && m.uuid != "" { // скачать файл при редактирование в папку указанную в конфиге
-			err := m.mainPage.managerController.FileData().DownLoadFile(m.mainPage.storage.Token(), m.fileName, m.uuid)
+			err := m.mainPage.managerController.FileData().DownLoadFile(m.mainPage.accessToken(), m.fileName, m.uuid)
 			if err != nil {
 				m.responseMessage = err.Error()
 				return m, tea.Batch(cmd, clearErrorAfter(3*time.Second))
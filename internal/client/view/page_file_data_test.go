@@ -26,8 +26,13 @@ func (m *mockFileData) Send(token string, requestData *model_data.FileDataInitRe
 	return args.Get(0).(*controller.FileDataResponse), args.Error(1)
 }
 
-func (m *mockFileData) UploadFile(token, uploadPath string, file *os.File) error {
-	args := m.Called(token, uploadPath, file)
+func (m *mockFileData) SendFile(token string, requestData *model_data.FileDataInitRequest, filePath string) error {
+	args := m.Called(token, requestData, filePath)
+	return args.Error(0)
+}
+
+func (m *mockFileData) UploadFile(token string, upload *controller.FileDataResponse, file *os.File) error {
+	args := m.Called(token, upload, file)
 	return args.Error(0)
 }
 
@@ -128,8 +133,7 @@ func TestUpdate(t *testing.T) {
 	}
 	defer os.Remove(tempFile.Name())
 
-	fileDataCtrl.On("Send", "test-token", mock.Anything).Return(&controller.FileDataResponse{UploadPath: "test-upload-path"}, nil)
-	fileDataCtrl.On("UploadFile", "test-token", "test-upload-path", mock.Anything).Return(nil)
+	fileDataCtrl.On("SendFile", "test-token", mock.Anything, "tmp_file").Return(nil).Once()
 
 	msg = tea.KeyMsg{Type: tea.KeyEnter}
 	_, cmd = page.Update(msg)
@@ -151,9 +155,12 @@ func TestUpdate(t *testing.T) {
 	_, cmd = page.Update(msg)
 	assert.NotNil(t, cmd)
 
+	// сервер недоступен, файл поставлен в очередь изменений
+	fileDataCtrl.On("SendFile", "test-token", mock.Anything, "tmp_file").Return(controller.ErrQueued).Once()
 	page.Choice = 4
 	msg = tea.KeyMsg{Type: tea.KeyEnter}
 	_, cmd = page.Update(msg)
+	assert.Equal(t, controller.ErrQueued.Error(), page.responseMessage)
 	page.selectedFile = ""
 	assert.NotNil(t, cmd)
 }
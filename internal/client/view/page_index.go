@@ -2,9 +2,13 @@ package view
 
 import (
 	"fmt"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/northmule/gophkeeper/internal/client/controller"
 	"github.com/northmule/gophkeeper/internal/client/logger"
+	"github.com/northmule/gophkeeper/internal/common/model_data"
+	"github.com/northmule/gophkeeper/internal/common/models"
 )
 
 // Экран сразу после запуска клиента
@@ -17,6 +21,10 @@ type pageIndex struct {
 	log               *logger.Logger
 
 	storage Storage
+
+	// notify передача сообщения в интерфейс из фоновой горутины (tea.Program.Send)
+	notify        func(msg tea.Msg)
+	eventsStarted bool
 }
 
 func newPageIndex(managerController ManagerController, storage Storage, log *logger.Logger) *pageIndex {
@@ -42,8 +50,8 @@ func (m *pageIndex) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 		if k == "down" {
 			m.Choice++
-			if m.Choice > 2 {
-				m.Choice = 2
+			if m.Choice > 3 {
+				m.Choice = 3
 			}
 		}
 		if k == "up" {
@@ -61,6 +69,9 @@ func (m *pageIndex) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				return newPageRegistration(m), nil
 			}
 			if m.Choice == 2 {
+				return newPageOffline(m), nil
+			}
+			if m.Choice == 3 {
 				return newPageHelp(m), nil
 			}
 
@@ -81,12 +92,107 @@ func (m *pageIndex) View() string {
 		subtleStyle.Render("q, esc: quit")
 
 	choices := fmt.Sprintf(
-		"%s\n%s\n%s\n",
+		"%s\n%s\n%s\n%s\n",
 		renderCheckbox("Авторизация", c == 0),
 		renderCheckbox("Регистрация", c == 1),
-		renderCheckbox("Справка", c == 2),
+		renderCheckbox("Хранилище без сети", c == 2),
+		renderCheckbox("Справка", c == 3),
 	)
 
 	s := fmt.Sprintf(tpl, choices)
 	return mainStyle.Render(title + "\n" + s + "\n\n")
 }
+
+// tokenRefreshBefore за сколько до окончания действия токен авторизации обновляется
+const tokenRefreshBefore = 30 * time.Second
+
+// setTokens сохраняет токены, полученные при входе или обновлении
+func (m *pageIndex) setTokens(r *controller.AuthenticationResponse) {
+	m.storage.SetToken(r.Value)
+	var expire time.Time
+	if r.ExpiresIn > 0 {
+		expire = time.Now().Add(time.Duration(r.ExpiresIn) * time.Second)
+	}
+	m.storage.SetRefreshToken(r.RefreshToken, expire)
+}
+
+// accessToken токен авторизации, истекающий токен обновляется по refresh токену
+func (m *pageIndex) accessToken() string {
+	expire := m.storage.TokenExpire()
+	if m.storage.RefreshToken() == "" || expire.IsZero() || time.Until(expire) > tokenRefreshBefore {
+		return m.storage.Token()
+	}
+	r, err := m.managerController.Authentication().Refresh(m.storage.RefreshToken())
+	if err != nil {
+		m.log.Error(err)
+		return m.storage.Token()
+	}
+	m.setTokens(r)
+	return m.storage.Token()
+}
+
+// logout закрывает сессию на сервере и сбрасывает токены
+func (m *pageIndex) logout() {
+	if m.storage.Token() != "" {
+		err := m.managerController.Authentication().Logout(m.accessToken())
+		if err != nil {
+			m.log.Error(err)
+		}
+	}
+	m.stopEvents()
+	m.storage.ResetToken()
+}
+
+// dataEventMsg событие изменения данных с другого устройства
+type dataEventMsg model_data.EventResponse
+
+// startEvents получение событий изменения данных, события передаются открытой странице
+func (m *pageIndex) startEvents() {
+	if m.notify == nil || m.eventsStarted {
+		return
+	}
+	m.eventsStarted = true
+	m.managerController.Events().Start(m.storage.Token, func(event model_data.EventResponse) {
+		m.notify(dataEventMsg(event))
+	})
+}
+
+// stopEvents остановка получения событий
+func (m *pageIndex) stopEvents() {
+	if !m.eventsStarted {
+		return
+	}
+	m.eventsStarted = false
+	m.managerController.Events().Stop()
+}
+
+// reloadChanged данные, изменённые на другом устройстве, и сообщение для страницы редактирования.
+// Данные загружаются заново, только если на странице нет несохранённых правок
+func (m *pageIndex) reloadChanged(event dataEventMsg, edited bool) (*model_data.DataByUUIDResponse, string) {
+	switch event.Type {
+	case models.EventItemDeleted:
+		return nil, "Данные удалены на другом устройстве"
+	case models.EventItemUpdated:
+		if edited {
+			return nil, "Данные изменены на другом устройстве, при отправке откроется сравнение"
+		}
+		item, err := m.managerController.ItemData().Send(m.accessToken(), event.DataUUID)
+		if err != nil {
+			m.log.Error(err)
+			return nil, "Данные изменены на другом устройстве"
+		}
+		return item, "Данные обновлены с другого устройства"
+	}
+	return nil, ""
+}
+
+// replayOutbox отправка изменений, сохранённых в очередь без связи с сервером
+func (m *pageIndex) replayOutbox() {
+	sent, err := m.managerController.Outbox().Replay(m.accessToken())
+	if err != nil {
+		m.log.Error(err)
+	}
+	if sent > 0 {
+		m.log.Infof("Sent %d queued changes", sent)
+	}
+}
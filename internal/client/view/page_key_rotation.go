@@ -0,0 +1,113 @@
+package view
+
+import (
+	"fmt"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/northmule/gophkeeper/internal/common/model_data"
+	"github.com/northmule/gophkeeper/internal/common/models"
+)
+
+// Экран смены ключа шифрования клиента
+type pageKeyRotation struct {
+	Choice          int
+	responseMessage string
+	mainPage        *pageIndex
+	prevPage        tea.Model
+}
+
+func newPageKeyRotation(mainPage *pageIndex, prevPage tea.Model) *pageKeyRotation {
+	return &pageKeyRotation{
+		mainPage: mainPage,
+		prevPage: prevPage,
+	}
+}
+
+// Init инициализация модели
+func (m *pageKeyRotation) Init() tea.Cmd {
+	return nil
+}
+
+// Update изменение модели
+func (m *pageKeyRotation) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		k := msg.String()
+		if k == "down" || k == "tab" {
+			m.Choice++
+			if m.Choice > 2 {
+				m.Choice = 2
+			}
+		}
+		if k == "up" {
+			m.Choice--
+			if m.Choice < 0 {
+				m.Choice = 0
+			}
+		}
+
+		if k == "enter" {
+			if m.Choice == 0 {
+				rotation, err := m.mainPage.managerController.KeysData().RotateClientPrivateKey(m.mainPage.accessToken())
+				m.responseMessage = keyRotationMessage(rotation, err)
+			}
+			if m.Choice == 1 {
+				rotation, err := m.mainPage.managerController.KeysData().KeyRotationStatus(m.mainPage.accessToken())
+				m.responseMessage = keyRotationMessage(rotation, err)
+				if err == nil && rotation != nil && rotation.Status == models.KeyRotationFinished {
+					// новый ключ попадает в комплект восстановления
+					_, err = m.mainPage.managerController.Recovery().EnsureKit(m.mainPage.accessToken())
+					if err != nil {
+						m.mainPage.log.Error(err)
+					}
+				}
+			}
+			if m.Choice == 2 {
+				return m.prevPage, nil
+			}
+		}
+	}
+
+	return m, nil
+}
+
+// keyRotationMessage текст состояния смены ключа
+func keyRotationMessage(rotation *model_data.KeyRotationResponse, err error) string {
+	if err != nil {
+		return err.Error()
+	}
+	if rotation == nil {
+		return "ключ не менялся"
+	}
+	switch rotation.Status {
+	case models.KeyRotationRunning:
+		return "смена ключа выполняется"
+	case models.KeyRotationFinished:
+		return fmt.Sprintf("ключ заменён %s", time.Unix(rotation.FinishedAt, 0).Format(time.DateTime))
+	case models.KeyRotationFailed:
+		return fmt.Sprintf("смена ключа не удалась, действует прежний ключ: %s", rotation.Error)
+	}
+	return rotation.Status
+}
+
+// View вид модели
+func (m *pageKeyRotation) View() string {
+	c := m.Choice
+
+	title := renderTitle("Смена ключа шифрования")
+	tpl := bodyStyle.Render("Прежний ключ действует, пока сервер не заменит его новым.\n")
+	tpl += "%s\n\n"
+	tpl += subtleStyle.Render("вверх/вниз: для переключения") + dotStyle +
+		subtleStyle.Render("enter: выбрать") + dotStyle +
+		responseTextStyle.Render("\n"+m.responseMessage)
+
+	choices := fmt.Sprintf(
+		"%s\n%s\n\n%s\n",
+		renderCheckbox("Сменить ключ шифрования", c == 0),
+		renderCheckbox("Обновить состояние", c == 1),
+		renderCheckbox("Вернуться", c == 2),
+	)
+
+	s := fmt.Sprintf(tpl, choices)
+	return mainStyle.Render(title + "\n" + s + "\n\n")
+}
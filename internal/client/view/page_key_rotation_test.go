@@ -0,0 +1,104 @@
+package view
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/northmule/gophkeeper/internal/client/logger"
+	"github.com/northmule/gophkeeper/internal/client/storage"
+	"github.com/northmule/gophkeeper/internal/common/model_data"
+	"github.com/northmule/gophkeeper/internal/common/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestPageKeyRotation_Update(t *testing.T) {
+	log, _ := logger.NewLogger("info")
+	memoryStorage := storage.NewMemoryStorage()
+
+	t.Run("navigation", func(t *testing.T) {
+		mainPage := newPageIndex(new(MockManagerController), memoryStorage, log)
+		page := newPageKeyRotation(mainPage, mainPage)
+		assert.Nil(t, page.Init())
+		for i := 0; i < 4; i++ {
+			_, _ = page.Update(tea.KeyMsg{Type: tea.KeyDown})
+		}
+		assert.Equal(t, 2, page.Choice)
+		_, _ = page.Update(tea.KeyMsg{Type: tea.KeyUp})
+		assert.Equal(t, 1, page.Choice)
+	})
+
+	t.Run("rotate", func(t *testing.T) {
+		mockManagerController := new(MockManagerController)
+		mockKeyData := new(MockKeyDataController)
+		mockManagerController.On("KeysData").Return(mockKeyData)
+		mockKeyData.On("RotateClientPrivateKey", mock.Anything).Return(&model_data.KeyRotationResponse{Status: models.KeyRotationRunning}, nil)
+
+		mainPage := newPageIndex(mockManagerController, memoryStorage, log)
+		page := newPageKeyRotation(mainPage, mainPage)
+		m, _ := page.Update(tea.KeyMsg{Type: tea.KeyEnter})
+		assert.Equal(t, page, m)
+		assert.Contains(t, page.responseMessage, "смена ключа выполняется")
+	})
+
+	t.Run("status error", func(t *testing.T) {
+		mockManagerController := new(MockManagerController)
+		mockKeyData := new(MockKeyDataController)
+		mockManagerController.On("KeysData").Return(mockKeyData)
+		mockKeyData.On("KeyRotationStatus", mock.Anything).Return(nil, errors.New("вы не авторизованы"))
+
+		mainPage := newPageIndex(mockManagerController, memoryStorage, log)
+		page := &pageKeyRotation{Choice: 1, mainPage: mainPage}
+		_, _ = page.Update(tea.KeyMsg{Type: tea.KeyEnter})
+		assert.Equal(t, "вы не авторизованы", page.responseMessage)
+	})
+
+	t.Run("status finished", func(t *testing.T) {
+		mockManagerController := new(MockManagerController)
+		mockKeyData := new(MockKeyDataController)
+		mockRecovery := new(MockRecoveryController)
+		mockManagerController.On("KeysData").Return(mockKeyData)
+		mockManagerController.On("Recovery").Return(mockRecovery)
+		mockKeyData.On("KeyRotationStatus", mock.Anything).Return(&model_data.KeyRotationResponse{Status: models.KeyRotationFinished}, nil)
+		mockRecovery.On("EnsureKit", mock.Anything).Return(nil, nil)
+
+		mainPage := newPageIndex(mockManagerController, memoryStorage, log)
+		page := &pageKeyRotation{Choice: 1, mainPage: mainPage}
+		_, _ = page.Update(tea.KeyMsg{Type: tea.KeyEnter})
+		assert.Contains(t, page.responseMessage, "ключ заменён")
+		mockRecovery.AssertExpectations(t)
+	})
+
+	t.Run("back", func(t *testing.T) {
+		mainPage := newPageIndex(new(MockManagerController), memoryStorage, log)
+		prevPage := newPageAction(mainPage)
+		page := &pageKeyRotation{Choice: 2, mainPage: mainPage, prevPage: prevPage}
+		m, _ := page.Update(tea.KeyMsg{Type: tea.KeyEnter})
+		assert.Equal(t, prevPage, m)
+	})
+}
+
+func TestKeyRotationMessage(t *testing.T) {
+	finishedAt := time.Now()
+	assert.Equal(t, "ключ не менялся", keyRotationMessage(nil, nil))
+	assert.Equal(t, "error", keyRotationMessage(nil, errors.New("error")))
+	assert.Contains(t, keyRotationMessage(&model_data.KeyRotationResponse{Status: models.KeyRotationFinished, FinishedAt: finishedAt.Unix()}, nil), "ключ заменён")
+	assert.Contains(t, keyRotationMessage(&model_data.KeyRotationResponse{Status: models.KeyRotationFailed, Error: "broken"}, nil), "broken")
+}
+
+func TestPageKeyRotation_View(t *testing.T) {
+	log, _ := logger.NewLogger("info")
+	mainPage := newPageIndex(new(MockManagerController), storage.NewMemoryStorage(), log)
+	page := newPageKeyRotation(mainPage, mainPage)
+	page.responseMessage = "ключ не менялся"
+
+	result := page.View()
+	assert.True(t, strings.Contains(result, "Смена ключа шифрования"))
+	assert.True(t, strings.Contains(result, "Сменить ключ шифрования"))
+	assert.True(t, strings.Contains(result, "Обновить состояние"))
+	assert.True(t, strings.Contains(result, "Вернуться"))
+	assert.True(t, strings.Contains(result, "ключ не менялся"))
+}
@@ -0,0 +1,167 @@
+package view
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Экран ввода мастер-пароля (после авторизации). Из мастер-пароля на клиенте выводится ключ шифрования данных
+type pageMasterPassword struct {
+	password        textinput.Model
+	Choice          int
+	mainPage        *pageIndex
+	responseMessage string
+	// ключ шифрования клиента не совпал с сервером, после разблокировки он восстанавливается из комплекта
+	restoreClientKey bool
+}
+
+func newPageMasterPassword(mainPage *pageIndex) *pageMasterPassword {
+	password := textinput.New()
+	password.Placeholder = "Введите мастер-пароль"
+	password.EchoMode = textinput.EchoPassword
+	password.EchoCharacter = '•'
+	password.Focus()
+	password.CharLimit = 100
+	password.Width = 50
+
+	m := &pageMasterPassword{}
+	m.password = password
+	m.mainPage = mainPage
+
+	return m
+}
+
+func (m *pageMasterPassword) Init() tea.Cmd {
+	return textinput.Blink
+}
+
+// Update обновление страницы
+func (m *pageMasterPassword) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+
+	var cmd tea.Cmd
+
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		k := msg.String()
+		if k == "down" || k == "tab" {
+			m.Choice++
+			if m.Choice > 3 {
+				m.Choice = 3
+			}
+		}
+		if k == "up" {
+			m.Choice--
+			if m.Choice < 0 {
+				m.Choice = 0
+			}
+		}
+		if k == "enter" {
+			if m.Choice == 1 {
+				if len(m.password.Value()) < 8 {
+					m.responseMessage = "мастер-пароль должен быть не короче 8 символов"
+					return m, tea.Batch(cmd, clearErrorAfter(3*time.Second))
+				}
+				err := m.mainPage.managerController.MasterKey().Unlock(m.mainPage.accessToken(), m.password.Value())
+				if err != nil {
+					m.responseMessage = err.Error()
+					return m, tea.Batch(cmd, clearErrorAfter(3*time.Second))
+				}
+				m.password.SetValue("")
+				return m.unlocked()
+			}
+
+			// вход без мастер-пароля
+			if m.Choice == 2 {
+				p := newPageRecovery(m.mainPage, m)
+				return p, p.Init()
+			}
+
+			// выход
+			if m.Choice == 3 {
+				m.mainPage.logout()
+				return m.mainPage, nil
+			}
+		}
+	}
+
+	if m.Choice == 0 {
+		m.password, cmd = m.password.Update(msg)
+		m.password.Focus()
+		return m, cmd
+	}
+
+	return m, nil
+}
+
+// unlocked восстановление ключа клиента при необходимости и создание комплекта восстановления
+func (m *pageMasterPassword) unlocked() (tea.Model, tea.Cmd) {
+	token := m.mainPage.accessToken()
+	if m.restoreClientKey {
+		err := m.mainPage.managerController.Recovery().RestoreClientKey(token)
+		if err == nil {
+			err = m.mainPage.managerController.KeysData().UploadClientPrivateKey(token)
+		}
+		if err != nil {
+			m.responseMessage = err.Error()
+			return m, clearErrorAfter(3 * time.Second)
+		}
+		m.restoreClientKey = false
+	}
+	// данные, сохранённые до шифрования мастер-ключом, перешифровываются один раз
+	err := m.mainPage.managerController.MasterKey().Migrate(token)
+	if err != nil {
+		m.mainPage.log.Error(err)
+	}
+	// локальная копия хранилища для просмотра данных без сервера
+	err = m.mainPage.managerController.Offline().Open()
+	if err != nil {
+		m.mainPage.log.Error(err)
+	} else {
+		// изменения, сохранённые без сервера в прошлых сессиях
+		m.mainPage.replayOutbox()
+	}
+	// изменения с других устройств обновляют открытые страницы
+	m.mainPage.startEvents()
+	// ключ хранилища, зашифрованный ключом устройства, сохраняется на сервере для следующих входов
+	err = m.mainPage.managerController.KeysData().UploadClientPublicKey(token)
+	if err != nil {
+		m.mainPage.log.Error(err)
+	}
+
+	kit, err := m.mainPage.managerController.Recovery().EnsureKit(token)
+	if err != nil {
+		m.mainPage.log.Error(err)
+		return newPageRecoveryKit(m.mainPage, nil, "комплект восстановления не создан: "+err.Error()), nil
+	}
+	if kit != nil {
+		return newPageRecoveryKit(m.mainPage, kit, ""), nil
+	}
+	return newPageAction(m.mainPage), nil
+}
+
+// View внешний вид
+func (m *pageMasterPassword) View() string {
+
+	c := m.Choice
+
+	title := renderTitle("Мастер-пароль")
+
+	tpl := "%s\n\n"
+	tpl += subtleStyle.Render("вверх/вниз: для переключения") + dotStyle +
+		subtleStyle.Render("enter: начать ввод значения") + dotStyle +
+		subtleStyle.Render("\nпри первом вводе пароль станет мастер-паролем, восстановить его нельзя") + dotStyle +
+		responseTextStyle.Render("\n"+m.responseMessage) + dotStyle
+
+	choices := fmt.Sprintf(
+		"%s\n%s\n%s\n\n%s\n",
+		renderCheckbox(m.password.View(), c == 0),
+		renderCheckbox("Разблокировать", c == 1),
+		renderCheckbox("Восстановить доступ без мастер-пароля", c == 2),
+		renderCheckbox("Выйти", c == 3),
+	)
+
+	s := fmt.Sprintf(tpl, choices)
+	return mainStyle.Render(title + "\n" + s + "\n\n")
+}
@@ -0,0 +1,204 @@
+package view
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/northmule/gophkeeper/internal/client/controller"
+	"github.com/northmule/gophkeeper/internal/client/logger"
+	"github.com/northmule/gophkeeper/internal/client/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestPageMasterPassword_Init(t *testing.T) {
+	log, _ := logger.NewLogger("info")
+	mainPage := newPageIndex(new(MockManagerController), storage.NewMemoryStorage(), log)
+	page := newPageMasterPassword(mainPage)
+	assert.NotNil(t, page.Init())
+}
+
+func TestPageMasterPassword_Update(t *testing.T) {
+	log, _ := logger.NewLogger("info")
+	memoryStorage := storage.NewMemoryStorage()
+	msg := tea.KeyMsg{Type: tea.KeyEnter}
+
+	t.Run("navigation", func(t *testing.T) {
+		mainPage := newPageIndex(new(MockManagerController), memoryStorage, log)
+		page := newPageMasterPassword(mainPage)
+		_, _ = page.Update(tea.KeyMsg{Type: tea.KeyDown})
+		assert.Equal(t, 1, page.Choice)
+		_, _ = page.Update(tea.KeyMsg{Type: tea.KeyUp})
+		assert.Equal(t, 0, page.Choice)
+	})
+
+	t.Run("unlock", func(t *testing.T) {
+		mockManagerController := new(MockManagerController)
+		mockMasterKey := new(MockMasterKeyController)
+		mockRecovery := new(MockRecoveryController)
+		mockKeyData := new(MockKeyDataController)
+		mockManagerController.On("MasterKey").Return(mockMasterKey)
+		mockManagerController.On("Recovery").Return(mockRecovery)
+		mockManagerController.On("KeysData").Return(mockKeyData)
+		mockManagerController.On("Offline").Return(newMockOfflineOpened())
+		mockManagerController.On("Outbox").Return(newMockOutboxEmpty())
+		mockMasterKey.On("Unlock", mock.Anything, "master password").Return(nil)
+		mockMasterKey.On("Migrate", mock.Anything).Return(nil)
+		mockRecovery.On("EnsureKit", mock.Anything).Return(nil, nil)
+		// ключ хранилища отправляется для этого устройства
+		mockKeyData.On("UploadClientPublicKey", mock.Anything).Return(nil)
+
+		mainPage := newPageIndex(mockManagerController, memoryStorage, log)
+		page := newPageMasterPassword(mainPage)
+		page.password.SetValue("master password")
+		page.Choice = 1
+		m, _ := page.Update(msg)
+		_, ok := m.(*pageAction)
+		assert.True(t, ok)
+		assert.Empty(t, page.password.Value())
+		mockMasterKey.AssertExpectations(t)
+		mockKeyData.AssertExpectations(t)
+	})
+
+	t.Run("unlock creates recovery kit", func(t *testing.T) {
+		mockManagerController := new(MockManagerController)
+		mockMasterKey := new(MockMasterKeyController)
+		mockRecovery := new(MockRecoveryController)
+		mockKeyData := new(MockKeyDataController)
+		mockManagerController.On("MasterKey").Return(mockMasterKey)
+		mockManagerController.On("Recovery").Return(mockRecovery)
+		mockManagerController.On("KeysData").Return(mockKeyData)
+		mockManagerController.On("Offline").Return(newMockOfflineOpened())
+		mockManagerController.On("Outbox").Return(newMockOutboxEmpty())
+		mockMasterKey.On("Unlock", mock.Anything, mock.Anything).Return(nil)
+		mockMasterKey.On("Migrate", mock.Anything).Return(nil)
+		mockRecovery.On("EnsureKit", mock.Anything).Return(&controller.RecoveryKit{Key: "GKRK-AAAA"}, nil)
+		// ошибка отправки ключа хранилища не мешает входу
+		mockKeyData.On("UploadClientPublicKey", mock.Anything).Return(errors.New("не известная ошибка"))
+
+		mainPage := newPageIndex(mockManagerController, memoryStorage, log)
+		page := newPageMasterPassword(mainPage)
+		page.password.SetValue("master password")
+		page.Choice = 1
+		m, _ := page.Update(msg)
+		kitPage, ok := m.(*pageRecoveryKit)
+		assert.True(t, ok)
+		assert.Contains(t, kitPage.View(), "GKRK-AAAA")
+	})
+
+	t.Run("unlock restores client key", func(t *testing.T) {
+		mockManagerController := new(MockManagerController)
+		mockMasterKey := new(MockMasterKeyController)
+		mockRecovery := new(MockRecoveryController)
+		mockKeyData := new(MockKeyDataController)
+		mockManagerController.On("MasterKey").Return(mockMasterKey)
+		mockManagerController.On("Recovery").Return(mockRecovery)
+		mockManagerController.On("KeysData").Return(mockKeyData)
+		mockManagerController.On("Offline").Return(newMockOfflineOpened())
+		mockManagerController.On("Outbox").Return(newMockOutboxEmpty())
+		mockMasterKey.On("Unlock", mock.Anything, mock.Anything).Return(nil)
+		mockMasterKey.On("Migrate", mock.Anything).Return(nil)
+		mockRecovery.On("RestoreClientKey", mock.Anything).Return(nil)
+		mockRecovery.On("EnsureKit", mock.Anything).Return(nil, nil)
+		mockKeyData.On("UploadClientPrivateKey", mock.Anything).Return(nil)
+		mockKeyData.On("UploadClientPublicKey", mock.Anything).Return(nil)
+
+		mainPage := newPageIndex(mockManagerController, memoryStorage, log)
+		page := newPageMasterPassword(mainPage)
+		page.restoreClientKey = true
+		page.password.SetValue("master password")
+		page.Choice = 1
+		m, _ := page.Update(msg)
+		_, ok := m.(*pageAction)
+		assert.True(t, ok)
+		assert.False(t, page.restoreClientKey)
+		mockRecovery.AssertExpectations(t)
+		mockKeyData.AssertExpectations(t)
+	})
+
+	t.Run("restore client key error", func(t *testing.T) {
+		mockManagerController := new(MockManagerController)
+		mockMasterKey := new(MockMasterKeyController)
+		mockRecovery := new(MockRecoveryController)
+		mockManagerController.On("MasterKey").Return(mockMasterKey)
+		mockManagerController.On("Recovery").Return(mockRecovery)
+		mockMasterKey.On("Unlock", mock.Anything, mock.Anything).Return(nil)
+		mockMasterKey.On("Migrate", mock.Anything).Return(nil)
+		mockRecovery.On("RestoreClientKey", mock.Anything).Return(controller.ErrRecoveryKitNotFound)
+
+		mainPage := newPageIndex(mockManagerController, memoryStorage, log)
+		page := newPageMasterPassword(mainPage)
+		page.restoreClientKey = true
+		page.password.SetValue("master password")
+		page.Choice = 1
+		m, _ := page.Update(msg)
+		assert.Equal(t, page, m)
+		assert.Equal(t, controller.ErrRecoveryKitNotFound.Error(), page.responseMessage)
+	})
+
+	t.Run("recovery page", func(t *testing.T) {
+		mainPage := newPageIndex(new(MockManagerController), memoryStorage, log)
+		page := newPageMasterPassword(mainPage)
+		page.Choice = 2
+		m, _ := page.Update(msg)
+		recoveryPage, ok := m.(*pageRecovery)
+		assert.True(t, ok)
+		assert.Equal(t, page, recoveryPage.prevPage)
+	})
+
+	t.Run("unlock error", func(t *testing.T) {
+		mockManagerController := new(MockManagerController)
+		mockMasterKey := new(MockMasterKeyController)
+		mockManagerController.On("MasterKey").Return(mockMasterKey)
+		mockMasterKey.On("Unlock", mock.Anything, mock.Anything).Return(errors.New("неверный мастер-пароль"))
+
+		mainPage := newPageIndex(mockManagerController, memoryStorage, log)
+		page := newPageMasterPassword(mainPage)
+		page.password.SetValue("master password")
+		page.Choice = 1
+		m, _ := page.Update(msg)
+		assert.Equal(t, page, m)
+		assert.Equal(t, "неверный мастер-пароль", page.responseMessage)
+	})
+
+	t.Run("short password", func(t *testing.T) {
+		mockManagerController := new(MockManagerController)
+		mainPage := newPageIndex(mockManagerController, memoryStorage, log)
+		page := newPageMasterPassword(mainPage)
+		page.password.SetValue("short")
+		page.Choice = 1
+		m, _ := page.Update(msg)
+		assert.Equal(t, page, m)
+		assert.NotEmpty(t, page.responseMessage)
+		mockManagerController.AssertNotCalled(t, "MasterKey")
+	})
+
+	t.Run("exit", func(t *testing.T) {
+		mockAuthentication := new(MockAuthenticationDataController)
+		mockAuthentication.On("Logout", "token").Return(nil)
+		mockManagerController := new(MockManagerController)
+		mockManagerController.On("Authentication").Return(mockAuthentication)
+		mainPage := newPageIndex(mockManagerController, memoryStorage, log)
+		memoryStorage.SetToken("token")
+		page := newPageMasterPassword(mainPage)
+		page.Choice = 3
+		m, _ := page.Update(msg)
+		assert.Equal(t, mainPage, m)
+		assert.Empty(t, memoryStorage.Token())
+		mockAuthentication.AssertExpectations(t)
+	})
+}
+
+func TestPageMasterPassword_View(t *testing.T) {
+	log, _ := logger.NewLogger("info")
+	mainPage := newPageIndex(new(MockManagerController), storage.NewMemoryStorage(), log)
+	page := newPageMasterPassword(mainPage)
+	page.password.SetValue("secret value")
+	result := page.View()
+	assert.True(t, strings.Contains(result, "Мастер-пароль"))
+	assert.True(t, strings.Contains(result, "Разблокировать"))
+	assert.True(t, strings.Contains(result, "Восстановить доступ без мастер-пароля"))
+	assert.False(t, strings.Contains(result, "secret value"))
+}
@@ -0,0 +1,106 @@
+package view
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Экран разблокировки локальной копии хранилища мастер-паролем, когда сервер недоступен
+type pageOffline struct {
+	password        textinput.Model
+	Choice          int
+	mainPage        *pageIndex
+	responseMessage string
+}
+
+func newPageOffline(mainPage *pageIndex) *pageOffline {
+	password := textinput.New()
+	password.Placeholder = "Введите мастер-пароль"
+	password.EchoMode = textinput.EchoPassword
+	password.EchoCharacter = '•'
+	password.Focus()
+	password.CharLimit = 100
+	password.Width = 50
+
+	m := &pageOffline{}
+	m.password = password
+	m.mainPage = mainPage
+
+	return m
+}
+
+func (m *pageOffline) Init() tea.Cmd {
+	return textinput.Blink
+}
+
+// Update обновление страницы
+func (m *pageOffline) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+
+	var cmd tea.Cmd
+
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		k := msg.String()
+		if k == "down" || k == "tab" {
+			m.Choice++
+			if m.Choice > 2 {
+				m.Choice = 2
+			}
+		}
+		if k == "up" {
+			m.Choice--
+			if m.Choice < 0 {
+				m.Choice = 0
+			}
+		}
+		if k == "enter" {
+			if m.Choice == 1 {
+				err := m.mainPage.managerController.Offline().Unlock(m.password.Value())
+				if err != nil {
+					m.responseMessage = err.Error()
+					return m, tea.Batch(cmd, clearErrorAfter(3*time.Second))
+				}
+				m.password.SetValue("")
+				return newPageOfflineDataGrid(m.mainPage), nil
+			}
+
+			if m.Choice == 2 {
+				return m.mainPage, nil
+			}
+		}
+	}
+
+	if m.Choice == 0 {
+		m.password, cmd = m.password.Update(msg)
+		m.password.Focus()
+		return m, cmd
+	}
+
+	return m, nil
+}
+
+// View внешний вид
+func (m *pageOffline) View() string {
+
+	c := m.Choice
+
+	title := renderTitle("Хранилище без сети")
+
+	tpl := "%s\n\n"
+	tpl += subtleStyle.Render("вверх/вниз: для переключения") + dotStyle +
+		subtleStyle.Render("enter: начать ввод значения") + dotStyle +
+		subtleStyle.Render("\nдоступны данные, открытые на этом устройстве при последнем входе, только для просмотра") + dotStyle +
+		responseTextStyle.Render("\n"+m.responseMessage) + dotStyle
+
+	choices := fmt.Sprintf(
+		"%s\n%s\n\n%s\n",
+		renderCheckbox(m.password.View(), c == 0),
+		renderCheckbox("Открыть", c == 1),
+		renderCheckbox("Вернуться", c == 2),
+	)
+
+	s := fmt.Sprintf(tpl, choices)
+	return mainStyle.Render(title + "\n" + s + "\n\n")
+}
@@ -0,0 +1,93 @@
+package view
+
+import (
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/northmule/gophkeeper/internal/common/data_type"
+	"github.com/northmule/gophkeeper/internal/common/model_data"
+)
+
+// Просмотр данных из локальной копии хранилища, без сервера данные не изменяются
+type pageOfflineItem struct {
+	gridPage *pageDataGrid
+	title    string
+	fields   [][2]string
+}
+
+func newPageOfflineItem(gridPage *pageDataGrid, data *model_data.DataByUUIDResponse) *pageOfflineItem {
+	m := &pageOfflineItem{gridPage: gridPage}
+
+	var meta map[string]string
+	switch {
+	case data.IsCard:
+		m.title = "Данные банковских карт"
+		m.add("Название данных", data.CardData.Name)
+		m.add("Номер карты", data.CardData.CardNumber)
+		m.add("Срок действия", data.CardData.ValidityPeriod)
+		m.add("Защитный код", data.CardData.SecurityCode)
+		m.add("ФИО держателя", data.CardData.FullNameHolder)
+		m.add("Название банка", data.CardData.NameBank)
+		m.add("Телефон держателя", data.CardData.PhoneHolder)
+		m.add("Номер счёта", data.CardData.CurrentAccountNumber)
+		meta = data.CardData.Meta
+	case data.IsText:
+		m.title = "Произвольные текстовые данные"
+		m.add("Название данных", data.TextData.Name)
+		m.add("Текст", data.TextData.Value)
+		meta = data.TextData.Meta
+	case data.IsCredential:
+		m.title = "Логины и пароли"
+		m.add("Название данных", data.CredentialData.Name)
+		m.add("Логин", data.CredentialData.Username)
+		m.add("Пароль", data.CredentialData.Password)
+		m.add("Адреса сайтов", strings.Join(data.CredentialData.URLs, ", "))
+		m.add("Заметки", data.CredentialData.Notes)
+		meta = data.CredentialData.Meta
+	case data.IsFile:
+		// сам файл хранится на сервере и без него недоступен
+		m.title = "Бинарные данные"
+		m.add("Название данных", data.FileData.Name)
+		m.add("Имя файла", data.FileData.FileName)
+		m.add("Размер, байт", strconv.FormatInt(data.FileData.Size, 10))
+		meta = data.FileData.Meta
+	}
+	m.add(data_type.TranslateDataType(data_type.MetaNameNote), meta[data_type.MetaNameNote])
+	m.add(data_type.TranslateDataType(data_type.MetaNameWebSite), meta[data_type.MetaNameWebSite])
+
+	return m
+}
+
+// add поле для вывода, пустые поля не выводятся
+func (m *pageOfflineItem) add(name string, value string) {
+	if value == "" {
+		return
+	}
+	m.fields = append(m.fields, [2]string{name, value})
+}
+
+func (m *pageOfflineItem) Init() tea.Cmd { return nil }
+
+func (m *pageOfflineItem) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		k := msg.String()
+		if k == "ctrl+c" || k == "esc" || k == "enter" {
+			return m.gridPage, nil
+		}
+	}
+	return m, nil
+}
+
+// View контент страницы
+func (m *pageOfflineItem) View() string {
+	title := renderTitle(m.title)
+	// значения вводил пользователь, поэтому без шаблона fmt
+	s := ""
+	for _, field := range m.fields {
+		s += subtleStyle.Render(field[0]+": ") + field[1] + "\n"
+	}
+	s += "\n" + subtleStyle.Render("только для просмотра, нет связи с сервером") + dotStyle +
+		subtleStyle.Render("enter, ctrl+c: вернуться") + dotStyle
+	return mainStyle.Render(title + "\n" + s + "\n\n")
+}
@@ -0,0 +1,126 @@
+package view
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/northmule/gophkeeper/internal/client/controller"
+	"github.com/northmule/gophkeeper/internal/client/logger"
+	"github.com/northmule/gophkeeper/internal/client/service"
+	"github.com/northmule/gophkeeper/internal/client/storage"
+	"github.com/northmule/gophkeeper/internal/common/model_data"
+	"github.com/stretchr/testify/assert"
+)
+
+// newMockOfflineOpened локальная копия открывается после разблокировки хранилища
+func newMockOfflineOpened() *MockOfflineController {
+	mockOffline := new(MockOfflineController)
+	mockOffline.On("Open").Return(nil)
+	return mockOffline
+}
+
+func offlineGridData() *controller.GridDataResponse {
+	responseData := new(controller.GridDataResponse)
+	responseData.Items = []model_data.ItemDataResponse{
+		{Number: "1", Type: "Текст", Name: "note", UUID: "text-uuid"},
+		{Number: "2", Type: "Карта", Name: "card", UUID: "card-uuid"},
+	}
+	responseData.Offline = true
+	responseData.SyncedAt = time.Date(2024, 12, 31, 10, 0, 0, 0, time.Local)
+	return responseData
+}
+
+func TestPageOffline_Update(t *testing.T) {
+	log, _ := logger.NewLogger("info")
+
+	t.Run("wrong password", func(t *testing.T) {
+		mockManagerController := new(MockManagerController)
+		mockOffline := new(MockOfflineController)
+		mockManagerController.On("Offline").Return(mockOffline)
+		mockOffline.On("Unlock", "").Return(service.ErrWrongMasterPassword)
+
+		mainPage := newPageIndex(mockManagerController, storage.NewMemoryStorage(), log)
+		page := newPageOffline(mainPage)
+		page.Choice = 1
+		m, _ := page.Update(tea.KeyMsg{Type: tea.KeyEnter})
+		assert.Equal(t, page, m)
+		assert.Equal(t, service.ErrWrongMasterPassword.Error(), page.responseMessage)
+	})
+
+	t.Run("browse and lock", func(t *testing.T) {
+		mockManagerController := new(MockManagerController)
+		mockOffline := new(MockOfflineController)
+		mockManagerController.On("Offline").Return(mockOffline)
+		mockOffline.On("Unlock", "master password").Return(nil)
+		mockOffline.On("List").Return(offlineGridData(), nil)
+		mockOffline.On("Item", "text-uuid").Return(&model_data.DataByUUIDResponse{
+			IsText:   true,
+			TextData: model_data.TextDataRequest{Name: "note", Value: "secret text"},
+		}, nil)
+		mockOffline.On("Lock").Return()
+
+		mainPage := newPageIndex(mockManagerController, storage.NewMemoryStorage(), log)
+		page := newPageOffline(mainPage)
+		_, _ = page.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("master password")})
+		page.Choice = 1
+		m, _ := page.Update(tea.KeyMsg{Type: tea.KeyEnter})
+		grid, ok := m.(*pageDataGrid)
+		assert.True(t, ok)
+		assert.Len(t, grid.table.Rows(), 2)
+		assert.True(t, strings.Contains(grid.View(), "только для просмотра"))
+
+		m, _ = grid.Update(tea.KeyMsg{Type: tea.KeyEnter})
+		item, ok := m.(*pageOfflineItem)
+		assert.True(t, ok)
+		assert.True(t, strings.Contains(item.View(), "secret text"))
+
+		m, _ = item.Update(tea.KeyMsg{Type: tea.KeyCtrlC})
+		assert.Equal(t, grid, m)
+		m, _ = grid.Update(tea.KeyMsg{Type: tea.KeyCtrlC})
+		assert.Equal(t, mainPage, m)
+		mockOffline.AssertExpectations(t)
+	})
+
+	t.Run("back", func(t *testing.T) {
+		mainPage := newPageIndex(new(MockManagerController), storage.NewMemoryStorage(), log)
+		page := newPageOffline(mainPage)
+		page.Choice = 2
+		m, _ := page.Update(tea.KeyMsg{Type: tea.KeyEnter})
+		assert.Equal(t, mainPage, m)
+		assert.True(t, strings.Contains(page.View(), "Хранилище без сети"))
+	})
+}
+
+func TestPageDataGrid_ServerUnreachable(t *testing.T) {
+	log, _ := logger.NewLogger("info")
+	mockManagerController := new(MockManagerController)
+	mockGridData := new(MockGridDataController)
+	mockOffline := new(MockOfflineController)
+	mockManagerController.On("GridData").Return(mockGridData)
+	mockManagerController.On("Outbox").Return(newMockOutboxEmpty())
+	mockManagerController.On("Offline").Return(mockOffline)
+	mockGridData.On("Send", "token").Return(offlineGridData(), nil)
+	mockOffline.On("Item", "text-uuid").Return(&model_data.DataByUUIDResponse{
+		IsCredential:   true,
+		CredentialData: model_data.CredentialDataRequest{Name: "mail", Username: "john", URLs: []string{"https://mail.example.com"}},
+	}, nil)
+
+	memoryStorage := storage.NewMemoryStorage()
+	memoryStorage.SetToken("token")
+	mainPage := newPageIndex(mockManagerController, memoryStorage, log)
+	actionPage := newPageAction(mainPage)
+	grid := newPageDataGrid(mainPage, actionPage)
+	assert.True(t, grid.offline)
+
+	// данные не редактируются, открывается просмотр
+	m, _ := grid.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	item, ok := m.(*pageOfflineItem)
+	assert.True(t, ok)
+	assert.True(t, strings.Contains(item.View(), "https://mail.example.com"))
+
+	m, _ = grid.Update(tea.KeyMsg{Type: tea.KeyCtrlC})
+	assert.Equal(t, actionPage, m)
+	mockOffline.AssertNotCalled(t, "Lock")
+}
@@ -0,0 +1,134 @@
+package view
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/charmbracelet/bubbles/table"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/northmule/gophkeeper/internal/client/storage"
+	"github.com/northmule/gophkeeper/internal/common/data_type"
+)
+
+// Экран очереди изменений, сохранённых без связи с сервером: отправка и удаление операций
+type pageOutbox struct {
+	mainPage        *pageIndex
+	prevPage        tea.Model
+	table           table.Model
+	entries         []storage.OutboxEntry
+	responseMessage string
+}
+
+func newPageOutbox(mainPage *pageIndex, prevPage tea.Model) *pageOutbox {
+	m := &pageOutbox{
+		mainPage: mainPage,
+		prevPage: prevPage,
+	}
+
+	columns := []table.Column{
+		{Title: "Тип", Width: 20},
+		{Title: "Название", Width: 30},
+		{Title: "Сохранено", Width: 20},
+		{Title: "Ошибка отправки", Width: 50},
+	}
+	t := table.New(
+		table.WithColumns(columns),
+		table.WithFocused(true),
+		table.WithHeight(10),
+	)
+	s := table.DefaultStyles()
+	s.Header = s.Header.
+		BorderStyle(lipgloss.NormalBorder()).
+		BorderForeground(lipgloss.Color("240")).
+		BorderBottom(true).
+		Bold(false)
+	s.Selected = s.Selected.
+		Foreground(lipgloss.Color("229")).
+		Background(lipgloss.Color("57")).
+		Bold(false)
+	t.SetStyles(s)
+	m.table = t
+
+	m.load()
+	return m
+}
+
+// load загрузка очереди
+func (m *pageOutbox) load() {
+	entries, err := m.mainPage.managerController.Outbox().List()
+	if err != nil {
+		m.responseMessage = err.Error()
+		return
+	}
+	m.entries = entries
+	var rows []table.Row
+	for _, entry := range entries {
+		rows = append(rows, table.Row{
+			data_type.TranslateDataType(entry.Type),
+			entry.Name,
+			time.Unix(entry.CreatedAt, 0).Format(time.DateTime),
+			entry.Error,
+		})
+	}
+	m.table.SetRows(rows)
+}
+
+// selected выбранная в таблице операция
+func (m *pageOutbox) selected() (storage.OutboxEntry, bool) {
+	cursor := m.table.Cursor()
+	if cursor < 0 || cursor >= len(m.entries) {
+		return storage.OutboxEntry{}, false
+	}
+	return m.entries[cursor], true
+}
+
+func (m *pageOutbox) Init() tea.Cmd { return nil }
+
+func (m *pageOutbox) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		switch msg.String() {
+		case "ctrl+c":
+			return m.prevPage, nil
+		case "s":
+			sent, err := m.mainPage.managerController.Outbox().Replay(m.mainPage.accessToken())
+			m.load()
+			if err != nil {
+				m.responseMessage = err.Error()
+				return m, nil
+			}
+			m.responseMessage = fmt.Sprintf("отправлено операций: %d, осталось: %d", sent, len(m.entries))
+			return m, nil
+		case "x":
+			entry, ok := m.selected()
+			if !ok {
+				return m, nil
+			}
+			err := m.mainPage.managerController.Outbox().Discard(entry.Key)
+			if err != nil {
+				m.responseMessage = err.Error()
+				return m, nil
+			}
+			m.load()
+			m.responseMessage = fmt.Sprintf("операция %s удалена без отправки", entry.Name)
+			return m, nil
+		}
+	}
+	m.table, cmd = m.table.Update(msg)
+	return m, cmd
+}
+
+// View контент страницы
+func (m *pageOutbox) View() string {
+	title := renderTitle("Очередь изменений")
+	// названия данных вводит пользователь, поэтому без шаблона fmt
+	s := baseStyle.Render(m.table.View()) + "\n\n" +
+		subtleStyle.Render("изменения, сохранённые без связи с сервером, отправляются при входе и открытии списка данных") + "\n" +
+		subtleStyle.Render("вверх/вниз: для переключения") + dotStyle +
+		subtleStyle.Render("s: отправить сейчас") + dotStyle +
+		subtleStyle.Render("x: удалить без отправки") + dotStyle +
+		subtleStyle.Render("ctrl+c: вернуться") + dotStyle
+	s += responseTextStyle.Render("\n" + m.responseMessage)
+	return mainStyle.Render(title + "\n" + s + "\n\n")
+}
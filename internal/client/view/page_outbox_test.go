@@ -0,0 +1,70 @@
+package view
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/northmule/gophkeeper/internal/client/logger"
+	"github.com/northmule/gophkeeper/internal/client/storage"
+	"github.com/northmule/gophkeeper/internal/common/data_type"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// newMockOutboxEmpty очередь изменений пуста, отправлять нечего
+func newMockOutboxEmpty() *MockOutboxController {
+	mockOutbox := new(MockOutboxController)
+	mockOutbox.On("Replay", mock.Anything).Return(0, nil)
+	return mockOutbox
+}
+
+func TestPageOutbox_Update(t *testing.T) {
+	log, _ := logger.NewLogger("info")
+	entries := []storage.OutboxEntry{
+		{Key: "key-1", Type: data_type.TextType, Name: "note", CreatedAt: time.Now().Unix()},
+		{Key: "key-2", Type: data_type.CardType, Name: "card", Error: "ошибка в запросе", CreatedAt: time.Now().Unix()},
+	}
+
+	t.Run("replay", func(t *testing.T) {
+		mockManagerController := new(MockManagerController)
+		mockOutbox := new(MockOutboxController)
+		mockManagerController.On("Outbox").Return(mockOutbox)
+		mockOutbox.On("List").Return(entries, nil).Once()
+		mockOutbox.On("Replay", "token").Return(1, nil)
+		mockOutbox.On("List").Return(entries[1:], nil)
+
+		memoryStorage := storage.NewMemoryStorage()
+		memoryStorage.SetToken("token")
+		mainPage := newPageIndex(mockManagerController, memoryStorage, log)
+		actionPage := newPageAction(mainPage)
+		page := newPageOutbox(mainPage, actionPage)
+		assert.Len(t, page.table.Rows(), 2)
+		assert.True(t, strings.Contains(page.View(), "ошибка в запросе"))
+
+		m, _ := page.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("s")})
+		assert.Equal(t, page, m)
+		assert.Len(t, page.table.Rows(), 1)
+		assert.Equal(t, "отправлено операций: 1, осталось: 1", page.responseMessage)
+
+		m, _ = page.Update(tea.KeyMsg{Type: tea.KeyCtrlC})
+		assert.Equal(t, actionPage, m)
+	})
+
+	t.Run("discard", func(t *testing.T) {
+		mockManagerController := new(MockManagerController)
+		mockOutbox := new(MockOutboxController)
+		mockManagerController.On("Outbox").Return(mockOutbox)
+		mockOutbox.On("List").Return(entries, nil).Once()
+		mockOutbox.On("Discard", "key-1").Return(nil)
+		mockOutbox.On("List").Return(entries[1:], nil)
+
+		mainPage := newPageIndex(mockManagerController, storage.NewMemoryStorage(), log)
+		page := newPageOutbox(mainPage, newPageAction(mainPage))
+		_, _ = page.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("x")})
+		assert.Len(t, page.table.Rows(), 1)
+		assert.True(t, strings.Contains(page.responseMessage, "note"))
+		mockOutbox.AssertExpectations(t)
+	})
+}
@@ -0,0 +1,121 @@
+package view
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/charmbracelet/bubbles/textarea"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Экран восстановления доступа ключом восстановления или частями ключа хранилища (без мастер-пароля)
+type pageRecovery struct {
+	secret          textarea.Model
+	Choice          int
+	mainPage        *pageIndex
+	prevPage        tea.Model
+	responseMessage string
+}
+
+func newPageRecovery(mainPage *pageIndex, prevPage tea.Model) *pageRecovery {
+	secret := textarea.New()
+	secret.Placeholder = "Ключ восстановления или части ключа, по одной в строке"
+	secret.CharLimit = 2000
+	secret.MaxHeight = 20
+	secret.Focus()
+
+	return &pageRecovery{
+		secret:   secret,
+		mainPage: mainPage,
+		prevPage: prevPage,
+	}
+}
+
+func (m *pageRecovery) Init() tea.Cmd {
+	return textarea.Blink
+}
+
+// Update обновление страницы
+func (m *pageRecovery) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+
+	var cmd tea.Cmd
+
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		k := msg.String()
+		if k == "down" || k == "tab" {
+			m.Choice++
+			if m.Choice > 2 {
+				m.Choice = 2
+			}
+		}
+		if k == "up" {
+			m.Choice--
+			if m.Choice < 0 {
+				m.Choice = 0
+			}
+		}
+		if k == "enter" {
+			if m.Choice == 1 {
+				token := m.mainPage.accessToken()
+				err := m.mainPage.managerController.Recovery().Restore(token, m.secret.Value())
+				if err != nil {
+					m.responseMessage = err.Error()
+					return m, tea.Batch(cmd, clearErrorAfter(3*time.Second))
+				}
+				// восстановленный ключ совпадает с сохранённым на сервере
+				err = m.mainPage.managerController.KeysData().UploadClientPrivateKey(token)
+				if err != nil {
+					m.responseMessage = err.Error()
+					return m, tea.Batch(cmd, clearErrorAfter(3*time.Second))
+				}
+				m.secret.SetValue("")
+				err = m.mainPage.managerController.MasterKey().Migrate(token)
+				if err != nil {
+					m.mainPage.log.Error(err)
+				}
+				err = m.mainPage.managerController.Offline().Open()
+				if err != nil {
+					m.mainPage.log.Error(err)
+				} else {
+					m.mainPage.replayOutbox()
+				}
+				return newPageAction(m.mainPage), nil
+			}
+			if m.Choice == 2 {
+				return m.prevPage, nil
+			}
+		}
+	}
+
+	if m.Choice == 0 {
+		m.secret, cmd = m.secret.Update(msg)
+		m.secret.Focus()
+		return m, cmd
+	}
+	m.secret.Blur()
+
+	return m, nil
+}
+
+// View внешний вид
+func (m *pageRecovery) View() string {
+
+	c := m.Choice
+
+	title := renderTitle("Восстановление доступа")
+
+	tpl := "%s\n\n"
+	tpl += subtleStyle.Render("вверх/вниз: для переключения") + dotStyle +
+		subtleStyle.Render("enter: выбрать") + dotStyle +
+		responseTextStyle.Render("\n"+m.responseMessage) + dotStyle
+
+	choices := fmt.Sprintf(
+		"%s\n%s\n\n%s\n",
+		renderCheckbox(m.secret.View(), c == 0),
+		renderCheckbox("Восстановить", c == 1),
+		renderCheckbox("Вернуться", c == 2),
+	)
+
+	s := fmt.Sprintf(tpl, choices)
+	return mainStyle.Render(title + "\n" + s + "\n\n")
+}
@@ -0,0 +1,63 @@
+package view
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/northmule/gophkeeper/internal/client/controller"
+)
+
+// Экран комплекта восстановления. Комплект показывается один раз после создания
+type pageRecoveryKit struct {
+	kit             *controller.RecoveryKit
+	mainPage        *pageIndex
+	responseMessage string
+}
+
+func newPageRecoveryKit(mainPage *pageIndex, kit *controller.RecoveryKit, responseMessage string) *pageRecoveryKit {
+	return &pageRecoveryKit{
+		kit:             kit,
+		mainPage:        mainPage,
+		responseMessage: responseMessage,
+	}
+}
+
+func (m *pageRecoveryKit) Init() tea.Cmd {
+	return nil
+}
+
+// Update обновление страницы
+func (m *pageRecoveryKit) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		if msg.String() == "enter" {
+			return newPageAction(m.mainPage), nil
+		}
+	}
+	return m, nil
+}
+
+// View внешний вид
+func (m *pageRecoveryKit) View() string {
+	title := renderTitle("Комплект восстановления")
+
+	var b strings.Builder
+	if m.kit != nil {
+		b.WriteString(bodyStyle.Render("Запишите и храните отдельно от компьютера. Комплект показывается один раз,\nпрежний комплект больше не действует.\n"))
+		b.WriteString("\nКлюч восстановления:\n")
+		b.WriteString(checkboxStyle.Render(m.kit.Key) + "\n")
+		if len(m.kit.Shares) > 0 {
+			b.WriteString(fmt.Sprintf("\nЧасти ключа хранилища (достаточно любых %d из %d):\n", m.kit.Threshold, len(m.kit.Shares)))
+			for _, share := range m.kit.Shares {
+				b.WriteString(share + "\n")
+			}
+		}
+	}
+
+	tpl := b.String()
+	tpl += "\n" + renderCheckbox("Продолжить", true) + "\n\n"
+	tpl += subtleStyle.Render("enter: продолжить") + dotStyle +
+		responseTextStyle.Render("\n"+m.responseMessage)
+
+	return mainStyle.Render(title + "\n" + tpl + "\n\n")
+}
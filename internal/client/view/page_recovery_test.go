@@ -0,0 +1,133 @@
+package view
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/northmule/gophkeeper/internal/client/controller"
+	"github.com/northmule/gophkeeper/internal/client/logger"
+	"github.com/northmule/gophkeeper/internal/client/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestPageRecovery_Update(t *testing.T) {
+	log, _ := logger.NewLogger("info")
+	memoryStorage := storage.NewMemoryStorage()
+	msg := tea.KeyMsg{Type: tea.KeyEnter}
+
+	t.Run("navigation", func(t *testing.T) {
+		mainPage := newPageIndex(new(MockManagerController), memoryStorage, log)
+		page := newPageRecovery(mainPage, mainPage)
+		assert.NotNil(t, page.Init())
+		for i := 0; i < 4; i++ {
+			_, _ = page.Update(tea.KeyMsg{Type: tea.KeyDown})
+		}
+		assert.Equal(t, 2, page.Choice)
+		_, _ = page.Update(tea.KeyMsg{Type: tea.KeyUp})
+		assert.Equal(t, 1, page.Choice)
+	})
+
+	t.Run("restore", func(t *testing.T) {
+		mockManagerController := new(MockManagerController)
+		mockRecovery := new(MockRecoveryController)
+		mockKeyData := new(MockKeyDataController)
+		mockManagerController.On("Recovery").Return(mockRecovery)
+		mockManagerController.On("KeysData").Return(mockKeyData)
+		mockManagerController.On("Offline").Return(newMockOfflineOpened())
+		mockManagerController.On("Outbox").Return(newMockOutboxEmpty())
+		mockRecovery.On("Restore", mock.Anything, "GKRK-AAAA").Return(nil)
+		mockKeyData.On("UploadClientPrivateKey", mock.Anything).Return(nil)
+		mockMasterKey := new(MockMasterKeyController)
+		mockManagerController.On("MasterKey").Return(mockMasterKey)
+		// ошибка перешифрования не мешает входу, оно повторится при следующей разблокировке
+		mockMasterKey.On("Migrate", mock.Anything).Return(errors.New("не известная ошибка"))
+
+		mainPage := newPageIndex(mockManagerController, memoryStorage, log)
+		page := newPageRecovery(mainPage, mainPage)
+		page.secret.SetValue("GKRK-AAAA")
+		page.Choice = 1
+		m, _ := page.Update(msg)
+		_, ok := m.(*pageAction)
+		assert.True(t, ok)
+		assert.Empty(t, page.secret.Value())
+		mockKeyData.AssertExpectations(t)
+		mockMasterKey.AssertExpectations(t)
+	})
+
+	t.Run("restore error", func(t *testing.T) {
+		mockManagerController := new(MockManagerController)
+		mockRecovery := new(MockRecoveryController)
+		mockManagerController.On("Recovery").Return(mockRecovery)
+		mockRecovery.On("Restore", mock.Anything, mock.Anything).Return(controller.ErrRecoverySecret)
+
+		mainPage := newPageIndex(mockManagerController, memoryStorage, log)
+		page := newPageRecovery(mainPage, mainPage)
+		page.Choice = 1
+		m, _ := page.Update(msg)
+		assert.Equal(t, page, m)
+		assert.Equal(t, controller.ErrRecoverySecret.Error(), page.responseMessage)
+	})
+
+	t.Run("upload error", func(t *testing.T) {
+		mockManagerController := new(MockManagerController)
+		mockRecovery := new(MockRecoveryController)
+		mockKeyData := new(MockKeyDataController)
+		mockManagerController.On("Recovery").Return(mockRecovery)
+		mockManagerController.On("KeysData").Return(mockKeyData)
+		mockManagerController.On("Offline").Return(newMockOfflineOpened())
+		mockManagerController.On("Outbox").Return(newMockOutboxEmpty())
+		mockRecovery.On("Restore", mock.Anything, mock.Anything).Return(nil)
+		mockKeyData.On("UploadClientPrivateKey", mock.Anything).Return(errors.New("сервер не принял ключ"))
+
+		mainPage := newPageIndex(mockManagerController, memoryStorage, log)
+		page := newPageRecovery(mainPage, mainPage)
+		page.Choice = 1
+		m, _ := page.Update(msg)
+		assert.Equal(t, page, m)
+		assert.Equal(t, "сервер не принял ключ", page.responseMessage)
+	})
+
+	t.Run("back", func(t *testing.T) {
+		mainPage := newPageIndex(new(MockManagerController), memoryStorage, log)
+		prevPage := newPageMasterPassword(mainPage)
+		page := newPageRecovery(mainPage, prevPage)
+		page.Choice = 2
+		m, _ := page.Update(msg)
+		assert.Equal(t, prevPage, m)
+	})
+}
+
+func TestPageRecovery_View(t *testing.T) {
+	log, _ := logger.NewLogger("info")
+	mainPage := newPageIndex(new(MockManagerController), storage.NewMemoryStorage(), log)
+	page := newPageRecovery(mainPage, mainPage)
+	result := page.View()
+	assert.True(t, strings.Contains(result, "Восстановление доступа"))
+	assert.True(t, strings.Contains(result, "Восстановить"))
+	assert.True(t, strings.Contains(result, "Вернуться"))
+}
+
+func TestPageRecoveryKit(t *testing.T) {
+	log, _ := logger.NewLogger("info")
+	mainPage := newPageIndex(new(MockManagerController), storage.NewMemoryStorage(), log)
+	kit := &controller.RecoveryKit{Key: "GKRK-AAAA-BBBB", Shares: []string{"GKRS-1111", "GKRS-2222", "GKRS-3333"}, Threshold: 2}
+	page := newPageRecoveryKit(mainPage, kit, "")
+	assert.Nil(t, page.Init())
+
+	result := page.View()
+	assert.True(t, strings.Contains(result, "GKRK-AAAA-BBBB"))
+	assert.True(t, strings.Contains(result, "достаточно любых 2 из 3"))
+	assert.True(t, strings.Contains(result, "GKRS-3333"))
+
+	m, _ := page.Update(tea.KeyMsg{Type: tea.KeyDown})
+	assert.Equal(t, page, m)
+	m, _ = page.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	_, ok := m.(*pageAction)
+	assert.True(t, ok)
+
+	page = newPageRecoveryKit(mainPage, nil, "хранилище заблокировано")
+	assert.True(t, strings.Contains(page.View(), "хранилище заблокировано"))
+}
@@ -86,6 +86,10 @@ func (m *pageRegistration) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					m.responseMessage = "Заполните все поля"
 					return m, tea.Batch(cmd, clearErrorAfter(3*time.Second))
 				}
+				if len(m.password.Value()) < 8 {
+					m.responseMessage = "пароль должен быть не короче 8 символов"
+					return m, tea.Batch(cmd, clearErrorAfter(3*time.Second))
+				}
 				_, err := m.mainPage.managerController.Registration().Send(m.login.Value(), m.password.Value(), m.email.Value())
 				if err != nil {
 					m.responseMessage = err.Error()
@@ -102,9 +102,17 @@ func TestPageRegistration_Update(t *testing.T) {
 	assert.Equal(t, "Заполните все поля", model.(*pageRegistration).responseMessage)
 
 	pg.login.SetValue("testuser")
+	pg.password.SetValue("short")
+	pg.password2.SetValue("short")
+	pg.email.SetValue("test@example.com")
+	msg = tea.KeyMsg{Type: tea.KeyEnter}
+	model, cmd = pg.Update(msg)
+	assert.Equal(t, 4, model.(*pageRegistration).Choice)
+	assert.NotNil(t, cmd)
+	assert.Equal(t, "пароль должен быть не короче 8 символов", model.(*pageRegistration).responseMessage)
+
 	pg.password.SetValue("password1")
 	pg.password2.SetValue("password1")
-	pg.email.SetValue("test@example.com")
 
 	response := new(controller.RegistrationResponse)
 	response.Value = "ok"
@@ -2,6 +2,7 @@ package view
 
 import (
 	"fmt"
+	"reflect"
 
 	"github.com/charmbracelet/bubbles/textarea"
 	"github.com/charmbracelet/bubbles/textinput"
@@ -20,6 +21,10 @@ type pageTextData struct {
 
 	// идентификатор редактирования
 	uuid string
+	// версия редактируемых данных, полученная с сервера
+	version int64
+	// данные, загруженные для редактирования
+	loaded *model_data.TextDataRequest
 	// поля
 	name  textinput.Model
 	text  textarea.Model
@@ -66,6 +71,7 @@ func newPageTextData(mainPage *pageIndex) *pageTextData {
 // SetEditableData значения для редактирования
 func (m *pageTextData) SetEditableData(data *model_data.TextDataRequest) *pageTextData {
 	m.uuid = data.UUID
+	m.version = data.Version
 	m.name.SetValue(data.Name)
 	m.text.SetValue(data.Value)
 
@@ -76,11 +82,42 @@ func (m *pageTextData) SetEditableData(data *model_data.TextDataRequest) *pageTe
 		m.meta2.SetValue(v)
 	}
 
+	m.loaded = m.request()
 	m.isEditable = true
 
 	return m
 }
 
+// request данные страницы для отправки
+func (m *pageTextData) request() *model_data.TextDataRequest {
+	requestData := new(model_data.TextDataRequest)
+
+	requestData.UUID = m.uuid
+	requestData.Version = m.version
+	requestData.Name = m.name.Value()
+	requestData.Value = m.text.Value()
+	requestData.Meta = make(map[string]string)
+	requestData.Meta[data_type.MetaNameNote] = m.meta1.Value()
+	requestData.Meta[data_type.MetaNameWebSite] = m.meta2.Value()
+
+	return requestData
+}
+
+// dataChanged изменение редактируемых данных на другом устройстве
+func (m *pageTextData) dataChanged(event dataEventMsg) *pageTextData {
+	if !m.isEditable || event.DataUUID != m.uuid {
+		return m
+	}
+	item, message := m.mainPage.reloadChanged(event, !reflect.DeepEqual(m.request(), m.loaded))
+	if item != nil {
+		m.SetEditableData(&item.TextData)
+	}
+	if message != "" {
+		m.responseMessage = message
+	}
+	return m
+}
+
 func (m *pageTextData) SetPageGrid(page *pageDataGrid) *pageTextData {
 	m.gridPage = page
 
@@ -95,6 +132,10 @@ func (m *pageTextData) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	var cmd tea.Cmd
 
+	if event, ok := msg.(dataEventMsg); ok {
+		return m.dataChanged(event), nil
+	}
+
 	if msg, ok := msg.(tea.KeyMsg); ok {
 		k := msg.String()
 		if k == "down" || k == "tab" {
@@ -111,17 +152,13 @@ func (m *pageTextData) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 		if k == "enter" {
 			if m.Choice == 4 {
-				requestData := new(model_data.TextDataRequest)
-
-				requestData.UUID = m.uuid
-				requestData.Name = m.name.Value()
-				requestData.Value = m.text.Value()
-				requestData.Meta = make(map[string]string)
-				requestData.Meta[data_type.MetaNameNote] = m.meta1.Value()
-				requestData.Meta[data_type.MetaNameWebSite] = m.meta2.Value()
+				requestData := m.request()
 
-				_, err := m.mainPage.managerController.TextData().Send(m.mainPage.storage.Token(), requestData)
+				_, err := m.mainPage.managerController.TextData().Send(m.mainPage.accessToken(), requestData)
 				if err != nil {
+					if page, ok := openConflictPage(m.mainPage, m, &model_data.DataByUUIDResponse{IsText: true, TextData: *requestData}, err); ok {
+						return page, nil
+					}
 					m.responseMessage = err.Error()
 					return m, nil
 				}
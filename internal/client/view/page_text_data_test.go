@@ -11,6 +11,7 @@ import (
 	"github.com/northmule/gophkeeper/internal/client/storage"
 	"github.com/northmule/gophkeeper/internal/common/data_type"
 	"github.com/northmule/gophkeeper/internal/common/model_data"
+	"github.com/northmule/gophkeeper/internal/common/models"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -241,3 +242,38 @@ func TestPageTextData_View(t *testing.T) {
 	assert.Contains(t, view, "Отправить")
 	assert.Contains(t, view, "Вернуться")
 }
+
+func TestPageTextData_DataChanged(t *testing.T) {
+	log, _ := logger.NewLogger("info")
+	memoryStorage := storage.NewMemoryStorage()
+	memoryStorage.SetToken("test-token")
+	manager := new(MockManagerController)
+	itemData := new(MockItemDataController)
+	manager.On("ItemData").Return(itemData)
+	mainPage := newPageIndex(manager, memoryStorage, log)
+
+	page := newPageTextData(mainPage).SetEditableData(&model_data.TextDataRequest{UUID: "test-uuid", Version: 1, Name: "test-name", Value: "old"})
+	itemData.On("Send", "test-token", "test-uuid").Return(&model_data.DataByUUIDResponse{IsText: true, TextData: model_data.TextDataRequest{
+		UUID: "test-uuid", Version: 2, Name: "test-name", Value: "new",
+	}}, nil).Once()
+
+	// событие другой записи не меняет страницу
+	page.Update(dataEventMsg{Type: models.EventItemUpdated, DataUUID: "other-uuid"})
+	assert.Equal(t, "old", page.text.Value())
+
+	// правок нет: данные загружаются заново
+	page.Update(dataEventMsg{Type: models.EventItemUpdated, DataUUID: "test-uuid"})
+	assert.Equal(t, "new", page.text.Value())
+	assert.Equal(t, int64(2), page.version)
+	assert.Equal(t, "Данные обновлены с другого устройства", page.responseMessage)
+
+	// есть правки: данные не перезаписываются
+	page.text.SetValue("edited")
+	page.Update(dataEventMsg{Type: models.EventItemUpdated, DataUUID: "test-uuid"})
+	assert.Equal(t, "edited", page.text.Value())
+	assert.Equal(t, "Данные изменены на другом устройстве, при отправке откроется сравнение", page.responseMessage)
+
+	page.Update(dataEventMsg{Type: models.EventItemDeleted, DataUUID: "test-uuid"})
+	assert.Equal(t, "Данные удалены на другом устройстве", page.responseMessage)
+	itemData.AssertExpectations(t)
+}
@@ -1,6 +1,8 @@
 package view
 
 import (
+	"time"
+
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/northmule/gophkeeper/internal/client/controller"
 	"github.com/northmule/gophkeeper/internal/client/logger"
@@ -20,6 +22,9 @@ type Storage interface {
 	SetToken(token string)
 	Token() string
 	ResetToken()
+	SetRefreshToken(refreshToken string, tokenExpire time.Time)
+	RefreshToken() string
+	TokenExpire() time.Time
 	AddCardDataList(data models.CardData) error
 	AddMetaDataList(data models.MetaData) error
 	AddTextData(data models.TextData) error
@@ -28,13 +33,21 @@ type Storage interface {
 
 // ManagerController интерфейс для передачи в модели
 type ManagerController interface {
+	Audit() controller.AuditController
 	Authentication() controller.AuthenticationDataController
 	CardData() controller.CardDataController
+	CredentialData() controller.CredentialDataController
+	Devices() controller.DevicesController
+	Events() controller.EventsController
 	TextData() controller.TextDataController
 	FileData() controller.FileDataController
 	GridData() controller.GridDataController
 	ItemData() controller.ItemDataController
 	KeysData() controller.KeyDataController
+	MasterKey() controller.MasterKeyController
+	Offline() controller.OfflineController
+	Outbox() controller.OutboxController
+	Recovery() controller.RecoveryController
 	Registration() controller.RegistrationController
 }
 
@@ -53,7 +66,9 @@ func NewClientView(manager ManagerController, storage Storage, log *logger.Logge
 func (v *ClientView) InitMain(ctx context.Context) error {
 	var err error
 
-	p := tea.NewProgram(newPageIndex(v.manager, v.memoryStorage, v.log), tea.WithContext(ctx))
+	index := newPageIndex(v.manager, v.memoryStorage, v.log)
+	p := tea.NewProgram(index, tea.WithContext(ctx))
+	index.notify = p.Send
 	if _, err = p.Run(); err != nil {
 		return err
 	}
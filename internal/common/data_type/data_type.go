@@ -7,11 +7,51 @@ const (
 	TextType = "text_type"
 	// BinaryType бинарные данные
 	BinaryType = "binary_type"
+	// CredentialType пары логин/пароль
+	CredentialType = "credential_type"
 	// MetaNameNote тип заметка для мета данных
 	MetaNameNote = "meta_name_note"
 	// MetaNameWebSite тип мета веб сайт
 	MetaNameWebSite = "meta_name_website"
 	FileField       = "_file_"
+	// ChecksumField sha256 шифротекста части файла (hex)
+	ChecksumField = "_checksum_"
+	// FileChunkSize размер части файла до шифрования, файл отправляется частями
+	FileChunkSize = 1 << 20
+	// FileChunkMaxSize наибольший размер шифротекста части, принимаемый сервером
+	FileChunkMaxSize = FileChunkSize + 4096
+	// KeyExchangeField схема, по которой зашифрован ключ клиента
+	KeyExchangeField = "key_exchange"
+	// KeyExchangeHeader схемы обмена ключами: клиент перечисляет поддерживаемые, сервер возвращает выбранную
+	KeyExchangeHeader = "X-Key-Exchange"
+	// AppCodeRequestExpired код ошибки: время зашифрованного запроса вне допустимого окна (часы клиента и сервера расходятся)
+	AppCodeRequestExpired = 1001
+	// AppCodeRequestReplayed код ошибки: зашифрованный запрос уже был принят (повтор)
+	AppCodeRequestReplayed = 1002
+	// KeyExchangePublicKeyHeader публичный ключ обмена сервера (base64)
+	KeyExchangePublicKeyHeader = "X-Key-Exchange-Public-Key"
+	// DeviceUUIDHeader UUID устройства клиента при входе, без него сервер заводит новое устройство
+	DeviceUUIDHeader = "X-Device-UUID"
+	// DeviceNameHeader имя нового устройства (url-кодированное)
+	DeviceNameHeader = "X-Device-Name"
+	// IdempotencyKeyHeader ключ идемпотентности запроса сохранения: повтор с тем же ключом не создаёт данные заново
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader ответ повторён из сохранённого для ключа идемпотентности
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+	// AppCodeIdempotencyKeyReused код ошибки: ключ идемпотентности уже использован для другого запроса
+	AppCodeIdempotencyKeyReused = 1003
+	// AppCodeIdempotencyInProgress код ошибки: запрос с этим ключом идемпотентности ещё выполняется
+	AppCodeIdempotencyInProgress = 1004
+	// AppCodeVersionConflict код ошибки: данные изменены после чтения, в ответе текущая версия на сервере
+	AppCodeVersionConflict = 1005
+	// AppCodeVersionRequired код ошибки: изменение данных без версии, полученной при чтении
+	AppCodeVersionRequired = 1006
+	// AppCodeChunkChecksum код ошибки: контрольная сумма части файла не совпала с полученными данными
+	AppCodeChunkChecksum = 1007
+	// AppCodeFileAssembly код ошибки: файл не собран из принятых частей, недостающие части нужно отправить заново
+	AppCodeFileAssembly = 1008
+	// VaultKeyField ключ хранилища, зашифрованный ключом устройства
+	VaultKeyField = "vault_key"
 )
 
 // TranslateDataType Тип поля в название
@@ -23,6 +63,8 @@ func TranslateDataType(dataType string) string {
 		return "Text data"
 	case BinaryType:
 		return "Binary data"
+	case CredentialType:
+		return "Login/password"
 	case MetaNameNote:
 		return "Note"
 	case MetaNameWebSite:
@@ -0,0 +1,48 @@
+package keys
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"os"
+	"strings"
+)
+
+// ErrNoCertificate в PEM нет сертификата
+var ErrNoCertificate = errors.New("no certificate in PEM data")
+
+// Fingerprint отпечаток сертификата SHA-256 в hex (der - сертификат в DER)
+func Fingerprint(der []byte) string {
+	sum := sha256.Sum256(der)
+	return hex.EncodeToString(sum[:])
+}
+
+// CertificateFingerprint отпечаток первого сертификата из PEM
+func CertificateFingerprint(certPEM []byte) (string, error) {
+	for {
+		var block *pem.Block
+		block, certPEM = pem.Decode(certPEM)
+		if block == nil {
+			return "", ErrNoCertificate
+		}
+		if block.Type == "CERTIFICATE" {
+			return Fingerprint(block.Bytes), nil
+		}
+	}
+}
+
+// CertificateFileFingerprint отпечаток сертификата из файла
+func CertificateFileFingerprint(certPath string) (string, error) {
+	data, err := os.ReadFile(certPath)
+	if err != nil {
+		return "", err
+	}
+	return CertificateFingerprint(data)
+}
+
+// NormalizeFingerprint приводит отпечаток к виду Fingerprint: допускаются двоеточия, пробелы и верхний регистр
+func NormalizeFingerprint(value string) string {
+	value = strings.NewReplacer(":", "", " ", "").Replace(strings.TrimSpace(value))
+	return strings.ToLower(value)
+}
@@ -22,8 +22,22 @@ const (
 	PublicKeyFileName = "public_key.pem"
 	// CertificateFileName навзание файла ключа
 	CertificateFileName = "cert.pem"
+	// ClientCertificateFileName сертификат клиента, выданный CA сервера для входа по mTLS (ключ - PrivateKeyFileName)
+	ClientCertificateFileName = "client_cert.pem"
+	// ExchangeKeyFileName ключ X25519 сервера для согласования ключа шифрования с клиентом
+	ExchangeKeyFileName = "exchange_key.pem"
+	// ExchangePublicKeyFileName публичный ключ X25519 сервера, сохранённый клиентом
+	ExchangePublicKeyFileName = "exchange_public_key.pem"
 	//PrivateKeyFileNameForEncryption Ключ для шифрования данных (есть на клиенте и на сервере)
 	PrivateKeyFileNameForEncryption = "private_key_for_encryption.key"
+	// DeviceFileName UUID устройства клиента, выданный сервером при первом входе
+	DeviceFileName = "device_id"
+	// DeviceKeyFileName ключ устройства клиента, им шифруется ключ хранилища, сохраняемый на сервере
+	DeviceKeyFileName = "device.key"
+	// OfflineVaultFileName копия хранилища клиента для работы без сервера, зашифрована мастер-ключом
+	OfflineVaultFileName = "offline_vault"
+	// NextPrivateKeyFileNameForEncryption новый ключ для шифрования данных на время смены ключа на сервере
+	NextPrivateKeyFileNameForEncryption = "next_private_key_for_encryption.key"
 )
 
 // Keys сервис сертификата
@@ -96,8 +110,8 @@ func (c *Keys) InitSelfSigned() error {
 		NotAfter: time.Now().Add(8760 * time.Hour),
 
 		KeyUsage: x509.KeyUsageDigitalSignature,
-		// Набобр для вариантов использования, example: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth},
-		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageOCSPSigning},
+		// сертификат сервера используется для TLS
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
 		BasicConstraintsValid: true,
 	}
 
@@ -8,6 +8,7 @@ import (
 	"math/big"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -90,3 +91,39 @@ func TestInitSelfSigned(t *testing.T) {
 		t.Errorf("CertificateFileName should be empty with empty SavePath")
 	}
 }
+
+func TestCertificateFingerprint(t *testing.T) {
+	testDir := t.TempDir()
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate RSA key: %v", err)
+	}
+	keysService := NewKeys(Options{
+		Generator:    &MockKeyGenerator{Key: privateKey},
+		SavePath:     testDir,
+		Organization: "TestOrg",
+		Country:      "TestCountry",
+		SerialNumber: big.NewInt(1),
+	})
+	if err = keysService.InitSelfSigned(); err != nil {
+		t.Fatalf("InitSelfSigned failed: %v", err)
+	}
+
+	fingerprint, err := CertificateFileFingerprint(keysService.CertPath())
+	if err != nil {
+		t.Fatalf("CertificateFileFingerprint failed: %v", err)
+	}
+	if len(fingerprint) != 64 {
+		t.Errorf("Unexpected fingerprint %q", fingerprint)
+	}
+	if NormalizeFingerprint(" "+strings.ToUpper(fingerprint[:2])+":"+fingerprint[2:]) != fingerprint {
+		t.Errorf("NormalizeFingerprint does not match the fingerprint")
+	}
+
+	if _, err = CertificateFileFingerprint(keysService.PublicKeyPath()); !errors.Is(err, ErrNoCertificate) {
+		t.Errorf("Expected ErrNoCertificate, got %v", err)
+	}
+	if _, err = CertificateFileFingerprint(filepath.Join(testDir, "none.pem")); err == nil {
+		t.Errorf("Expected error for missing file")
+	}
+}
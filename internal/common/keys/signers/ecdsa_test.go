@@ -0,0 +1,31 @@
+package signers
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"testing"
+)
+
+func TestEcdsaSigner_GenerateKey(t *testing.T) {
+	signer := NewEcdsaSigner()
+
+	key, err := signer.GenerateKey()
+	if err != nil {
+		t.Fatalf("GenerateKey failed: %v", err)
+	}
+	ecdsaKey, ok := key.(*ecdsa.PrivateKey)
+	if !ok {
+		t.Fatalf("Generated key is not of type *ecdsa.PrivateKey")
+	}
+	if ecdsaKey.Curve != elliptic.P256() {
+		t.Errorf("Generated key does not use P-256: %s", ecdsaKey.Curve.Params().Name)
+	}
+
+	other, err := signer.GenerateKey()
+	if err != nil {
+		t.Fatalf("GenerateKey failed: %v", err)
+	}
+	if ecdsaKey.Equal(other) {
+		t.Errorf("Generated key is not unique")
+	}
+}
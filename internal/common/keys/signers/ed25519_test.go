@@ -0,0 +1,30 @@
+package signers
+
+import (
+	"crypto/ed25519"
+	"testing"
+)
+
+func TestEd25519Signer_GenerateKey(t *testing.T) {
+	signer := NewEd25519Signer()
+
+	key, err := signer.GenerateKey()
+	if err != nil {
+		t.Fatalf("GenerateKey failed: %v", err)
+	}
+	edKey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		t.Fatalf("Generated key is not of type ed25519.PrivateKey")
+	}
+	if len(edKey) != ed25519.PrivateKeySize {
+		t.Errorf("Generated key has wrong size: %d", len(edKey))
+	}
+
+	other, err := signer.GenerateKey()
+	if err != nil {
+		t.Fatalf("GenerateKey failed: %v", err)
+	}
+	if edKey.Equal(other) {
+		t.Errorf("Generated key is not unique")
+	}
+}
@@ -0,0 +1,33 @@
+package signers
+
+import (
+	"crypto"
+	"fmt"
+)
+
+const (
+	// AlgorithmRSA ключи RSA 4096
+	AlgorithmRSA = "rsa"
+	// AlgorithmECDSA ключи ECDSA P-256
+	AlgorithmECDSA = "ecdsa"
+	// AlgorithmEd25519 ключи Ed25519
+	AlgorithmEd25519 = "ed25519"
+)
+
+// Generator получение crypto.Signer (реализует keys.KeyGenerator)
+type Generator interface {
+	GenerateKey() (crypto.Signer, error)
+}
+
+// New генератор ключей по названию алгоритма
+func New(algorithm string) (Generator, error) {
+	switch algorithm {
+	case AlgorithmRSA, "":
+		return NewRsaSigner(), nil
+	case AlgorithmECDSA:
+		return NewEcdsaSigner(), nil
+	case AlgorithmEd25519:
+		return NewEd25519Signer(), nil
+	}
+	return nil, fmt.Errorf("unsupported key algorithm %q", algorithm)
+}
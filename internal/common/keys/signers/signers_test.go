@@ -0,0 +1,33 @@
+package signers
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		algorithm string
+		expected  Generator
+	}{
+		{"", &RsaSigner{}},
+		{AlgorithmRSA, &RsaSigner{}},
+		{AlgorithmECDSA, &EcdsaSigner{}},
+		{AlgorithmEd25519, &Ed25519Signer{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.algorithm, func(t *testing.T) {
+			generator, err := New(tt.algorithm)
+			if err != nil {
+				t.Fatalf("New failed: %v", err)
+			}
+			if fmt.Sprintf("%T", generator) != fmt.Sprintf("%T", tt.expected) {
+				t.Errorf("New(%q) = %T, want %T", tt.algorithm, generator, tt.expected)
+			}
+		})
+	}
+
+	if _, err := New("dsa"); err == nil {
+		t.Errorf("Expected error for unsupported algorithm")
+	}
+}
@@ -4,16 +4,18 @@ package model_data
 
 // CardDataRequest данные для запросов (клиент и сервер)
 type CardDataRequest struct {
-	Name string `json:"name" validate:"required,min=3,max=100"` // короткое название
-	UUID string `json:"uuid" validate:"omitempty,uuid"`         // uuid данных, заполняется при редактирование
+	Name    string `json:"name" validate:"required,min=3,max=100"` // короткое название
+	UUID    string `json:"uuid" validate:"omitempty,uuid"`         // uuid данных, заполняется при редактирование
+	Version int64  `json:"version,omitempty"`                      // версия данных из item_get, обязательна при редактировании
 
-	CardNumber           string `json:"card_number" validate:"max=19"`                                           // номер карты
-	ValidityPeriod       string `json:"validity_period" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"` // срок действия
-	SecurityCode         string `json:"security_code" validate:"max=10"`                                         // код безопасности
-	FullNameHolder       string `json:"full_name_holder" validate:"max=100"`                                     // ФИО держателя
-	NameBank             string `json:"name_bank" validate:"max=100"`                                            // название банка
-	PhoneHolder          string `json:"phone_holder" validate:"max=12"`                                          // телефон держателя
-	CurrentAccountNumber string `json:"current_account_number" validate:"max=20"`                                // номер расчётного счета
+	// Значения ниже шифруются на клиенте мастер-ключом, сервер хранит только шифротекст
+	CardNumber           string `json:"card_number" validate:"max=200"`            // номер карты
+	ValidityPeriod       string `json:"validity_period" validate:"max=200"`        // срок действия
+	SecurityCode         string `json:"security_code" validate:"max=200"`          // код безопасности
+	FullNameHolder       string `json:"full_name_holder" validate:"max=300"`       // ФИО держателя
+	NameBank             string `json:"name_bank" validate:"max=300"`              // название банка
+	PhoneHolder          string `json:"phone_holder" validate:"max=200"`           // телефон держателя
+	CurrentAccountNumber string `json:"current_account_number" validate:"max=200"` // номер расчётного счета
 
 	Meta map[string]string `json:"meta" validate:"max=5,dive,keys,min=3,max=20,endkeys"` // мета данные (имя поля - значение)
 
@@ -21,10 +23,11 @@ type CardDataRequest struct {
 
 // TextDataRequest данные для запросов (клиент и сервер)
 type TextDataRequest struct {
-	Name string `json:"name" validate:"required,min=3,max=100"` // короткое название
-	UUID string `json:"uuid" validate:"omitempty,uuid"`         // uuid данных, заполняется при редактирование
+	Name    string `json:"name" validate:"required,min=3,max=100"` // короткое название
+	UUID    string `json:"uuid" validate:"omitempty,uuid"`         // uuid данных, заполняется при редактирование
+	Version int64  `json:"version,omitempty"`                      // версия данных из item_get, обязательна при редактировании
 
-	Value string `json:"value"` // Текстовые данные
+	Value string `json:"value"` // Текстовые данные (шифротекст мастер-ключа клиента)
 
 	Meta map[string]string `json:"meta" validate:"max=5,dive,keys,min=3,max=20,endkeys"` // мета данные (имя поля - значение)
 }
@@ -33,15 +36,181 @@ type TextDataRequest struct {
 type FileDataInitRequest struct {
 	Name     string `json:"name" validate:"required,min=3,max=100"` // короткое название
 	UUID     string `json:"uuid" validate:"omitempty,uuid"`         // uuid данных, заполняется при редактирование
+	Version  int64  `json:"version,omitempty"`                      // версия данных из item_get, обязательна при редактировании
 	MimeType string `json:"mime_type"`                              // тип файла
 
 	Extension string `json:"extension" validate:"required,min=1,max=10"`  // расширение файла
 	FileName  string `json:"file_name" validate:"required,min=3,max=100"` // оригинальное имя файла
 	Size      int64  `json:"size"`                                        // размер файла в байтах
+	Chunks    int    `json:"chunks" validate:"min=0,max=1000000"`         // число частей нового содержимого, 0 - без нового содержимого
 
 	Meta map[string]string `json:"meta" validate:"max=5,dive,keys,min=3,max=20,endkeys"` // мета данные (имя поля - значение)
 }
 
+// FileDataStatusResponse состояние загрузки файла: принятые части и признак сборки файла
+type FileDataStatusResponse struct {
+	Chunks   int                `json:"chunks"`   // число частей содержимого
+	Received []FileChunkReceipt `json:"received"` // принятые части по возрастанию номера
+	Uploaded bool               `json:"uploaded"` // все части приняты и файл собран
+}
+
+// FileChunkReceipt принятая сервером часть файла
+type FileChunkReceipt struct {
+	Part     int    `json:"part"`
+	Size     int64  `json:"size"`
+	Checksum string `json:"checksum"`
+}
+
+// CredentialDataRequest данные для запросов (клиент и сервер)
+type CredentialDataRequest struct {
+	Name    string `json:"name" validate:"required,min=3,max=100"` // короткое название
+	UUID    string `json:"uuid" validate:"omitempty,uuid"`         // uuid данных, заполняется при редактирование
+	Version int64  `json:"version,omitempty"`                      // версия данных из item_get, обязательна при редактировании
+
+	// Значения ниже шифруются на клиенте мастер-ключом, сервер хранит только шифротекст
+	Username string   `json:"username" validate:"required,max=400"`         // логин
+	Password string   `json:"password" validate:"max=800"`                  // пароль
+	URLs     []string `json:"urls" validate:"max=10,dive,required,max=500"` // адреса сайтов
+	Notes    string   `json:"notes" validate:"max=3000"`                    // заметки
+
+	Meta map[string]string `json:"meta" validate:"max=5,dive,keys,min=3,max=20,endkeys"` // мета данные (имя поля - значение)
+}
+
+// MasterKeyRequest параметры мастер-ключа клиента (клиент и сервер)
+type MasterKeyRequest struct {
+	Salt  string `json:"salt" validate:"required,base64,max=100"` // соль Argon2id
+	Check string `json:"check" validate:"required,max=500"`       // контрольное значение, зашифрованное мастер-ключом
+}
+
+// MasterKeyResponse параметры мастер-ключа пользователя, пустые если ещё не заданы
+type MasterKeyResponse struct {
+	Salt   string `json:"salt"`
+	Check  string `json:"check"`
+	Legacy bool   `json:"legacy"` // есть данные, сохранённые до шифрования мастер-ключом, клиент перешифровывает их
+}
+
+// RecoveryKitRequest комплект восстановления (клиент и сервер). Значения шифруются на клиенте,
+// пустой VaultKey оставляет сохранённый ранее (после смены ключа клиента обновляется только ClientKey)
+type RecoveryKitRequest struct {
+	VaultKey  string `json:"vault_key" validate:"max=1000"`           // ключ хранилища, зашифрованный ключом восстановления
+	ClientKey string `json:"client_key" validate:"required,max=1000"` // ключ шифрования клиента, зашифрованный ключом хранилища
+}
+
+// RecoveryKitResponse сохранённый комплект восстановления
+type RecoveryKitResponse struct {
+	VaultKey  string `json:"vault_key"`
+	ClientKey string `json:"client_key"`
+}
+
+// TokenResponse токены сессии, выдаются при входе и обновлении (клиент и сервер).
+// Токен доступа дополнительно передаётся в заголовке Authorization
+type TokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"`  // время жизни токена доступа в секундах
+	DeviceUUID   string `json:"device_uuid"` // устройство сессии, клиент передаёт его при следующих входах
+}
+
+// RefreshTokenRequest запрос на обновление токенов
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required,max=100"`
+}
+
+// LoginChallengeResponse ответ на вход пользователя с подключённым вторым фактором.
+// Токены выдаются после подтверждения кодом на /api/v1/login/totp
+type LoginChallengeResponse struct {
+	MFARequired bool   `json:"mfa_required"`
+	MFAToken    string `json:"mfa_token"`
+	ExpiresIn   int64  `json:"expires_in"` // время на ввод кода в секундах
+}
+
+// TOTPLoginRequest второй шаг входа: код из приложения-аутентификатора или резервный код
+type TOTPLoginRequest struct {
+	MFAToken string `json:"mfa_token" validate:"required,max=100"`
+	Code     string `json:"code" validate:"required,min=6,max=20"`
+}
+
+// TOTPCodeRequest код из приложения-аутентификатора или резервный код
+type TOTPCodeRequest struct {
+	Code string `json:"code" validate:"required,min=6,max=20"`
+}
+
+// TOTPEnrollResponse секрет для подключения приложения-аутентификатора
+type TOTPEnrollResponse struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"` // otpauth:// ссылка, обычно показывается QR-кодом
+}
+
+// TOTPBackupCodesResponse одноразовые резервные коды, показываются пользователю один раз
+type TOTPBackupCodesResponse struct {
+	BackupCodes []string `json:"backup_codes"`
+}
+
+// ClientCertificateResponse сертификат клиента, выданный CA сервера в ответ на публичный ключ клиента (при включённом mTLS).
+// С сертификатом и приватным ключом клиент входит без пароля на /api/v1/login/certificate
+type ClientCertificateResponse struct {
+	Certificate   string `json:"certificate"`    // PEM
+	CACertificate string `json:"ca_certificate"` // PEM сертификата CA сервера
+	Fingerprint   string `json:"fingerprint"`    // sha256 сертификата в hex
+	ExpiresAt     int64  `json:"expires_at"`     // unix время окончания действия
+}
+
+// KeyRotationResponse состояние смены ключа шифрования клиента
+type KeyRotationResponse struct {
+	UUID       string `json:"uuid"`
+	Status     string `json:"status"`      // running, finished, failed
+	Error      string `json:"error"`       // причина остановки при failed
+	FinishedAt int64  `json:"finished_at"` // unix время завершения, 0 пока смена не завершена
+}
+
+// DeviceResponse устройство пользователя
+type DeviceResponse struct {
+	UUID       string `json:"uuid"`
+	Name       string `json:"name"`
+	CreatedAt  int64  `json:"created_at"`          // unix время первого входа
+	LastSeenAt int64  `json:"last_seen_at"`        // unix время последнего входа
+	RevokedAt  int64  `json:"revoked_at"`          // unix время отзыва, 0 - устройство действует
+	Current    bool   `json:"current"`             // устройство текущей сессии
+	VaultKey   string `json:"vault_key,omitempty"` // ключ хранилища, зашифрованный ключом устройства (только у текущего устройства)
+}
+
+// DeviceListResponse устройства пользователя
+type DeviceListResponse struct {
+	Devices []DeviceResponse `json:"devices"`
+}
+
+// DeviceRenameRequest новое имя устройства
+type DeviceRenameRequest struct {
+	Name string `json:"name" validate:"required,min=1,max=100"`
+}
+
+// AuditEventResponse запись журнала действий пользователя
+type AuditEventResponse struct {
+	ID        int64  `json:"id"`
+	Action    string `json:"action"`     // login, key_exchange, item_read, item_save, file_download
+	DataUUID  string `json:"data_uuid"`  // uuid данных для действий с данными
+	IP        string `json:"ip"`         // адрес клиента
+	Details   string `json:"details"`    // уточнение действия (способ входа, запрос обмена ключами)
+	Hash      string `json:"hash"`       // хэш записи в цепочке
+	CreatedAt int64  `json:"created_at"` // unix время
+}
+
+// AuditListResponse страница журнала действий, новые записи первыми
+type AuditListResponse struct {
+	Events []AuditEventResponse `json:"events"`
+	Offset int                  `json:"offset"`
+	Limit  int                  `json:"limit"`
+}
+
+// AuditVerifyResponse результат проверки цепочки журнала
+type AuditVerifyResponse struct {
+	Valid    bool   `json:"valid"`
+	Checked  int64  `json:"checked"`   // проверено записей
+	BrokenID int64  `json:"broken_id"` // первая запись, не сходящаяся с цепочкой (0 - цепочка цела)
+	LastHash string `json:"last_hash"` // хэш последней записи, по нему клиент замечает удаление записей с конца
+	Unsigned int64  `json:"unsigned"`  // записи до подписи ключом сервера, их хэш можно пересчитать без ключа
+}
+
 // ItemDataResponse данные возвращаемые сервером в составе массива элементов
 type ItemDataResponse struct {
 	// Порядковый номер
@@ -50,10 +219,76 @@ type ItemDataResponse struct {
 	Type string `json:"type"`
 	// Имя данных указанное пользователем при создании
 	Name string `json:"name"`
-	// Не используется
+	// Дата последнего изменения (time.DateTime)
 	UpdateDate string `json:"update_date"`
 	// UUID данных. Используется для дальнейших запросов
 	UUID string `json:"uuid"`
+	// Дата создания (time.DateTime)
+	CreateDate string `json:"create_date,omitempty"`
+	// Ревизия последнего изменения
+	Revision int64 `json:"revision,omitempty"`
+}
+
+// DeletedItemResponse удалённые данные в ответе синхронизации
+type DeletedItemResponse struct {
+	UUID     string `json:"uuid"`
+	Type     string `json:"type"`
+	Revision int64  `json:"revision"`
+}
+
+// SyncResponse изменения данных пользователя после курсора. Cursor передаётся в следующий запрос,
+// HasMore - изменения не поместились в ответ, нужно запросить следующую страницу
+type SyncResponse struct {
+	Changed []ItemDataResponse    `json:"changed"`
+	Deleted []DeletedItemResponse `json:"deleted"`
+	Cursor  string                `json:"cursor"`
+	HasMore bool                  `json:"has_more"`
+}
+
+// EventResponse событие изменения данных из потока /api/v1/events (item_created, item_updated, item_deleted, key_rotated)
+type EventResponse struct {
+	Type     string `json:"type"`
+	DataType string `json:"data_type,omitempty"`
+	DataUUID string `json:"data_uuid,omitempty"`
+	Revision int64  `json:"revision,omitempty"`
+}
+
+// Действия операций пакета /api/v1/batch
+const (
+	BatchActionCreate = "create"
+	BatchActionUpdate = "update"
+	BatchActionDelete = "delete"
+)
+
+// BatchOperation операция пакета над данными типа DataType (card_type, text_type, credential_type).
+// create и update берут данные из поля своего типа (update - с uuid и версией), delete - UUID и Version
+type BatchOperation struct {
+	Action   string `json:"action" validate:"required,oneof=create update delete"`
+	DataType string `json:"data_type" validate:"required,oneof=card_type text_type credential_type"`
+	UUID     string `json:"uuid,omitempty" validate:"omitempty,uuid"` // uuid удаляемых данных
+	Version  int64  `json:"version,omitempty"`                        // версия удаляемых данных
+
+	CardData       *CardDataRequest       `json:"card_data,omitempty"`
+	TextData       *TextDataRequest       `json:"text_data,omitempty"`
+	CredentialData *CredentialDataRequest `json:"credential_data,omitempty"`
+}
+
+// BatchRequest операции, применяемые атомарно: ошибка любой операции отменяет весь пакет
+type BatchRequest struct {
+	Operations []BatchOperation `json:"operations" validate:"required,min=1,max=100,dive"`
+}
+
+// BatchResult результат операции пакета
+type BatchResult struct {
+	Action   string `json:"action"`
+	DataType string `json:"data_type"`
+	UUID     string `json:"uuid"`
+	Version  int64  `json:"version,omitempty"` // новая версия данных, для delete не заполняется
+}
+
+// BatchResponse результаты операций в порядке запроса
+type BatchResponse struct {
+	Results []BatchResult `json:"results"`
 }
 
 // ListDataItemsResponse список данных пользователя
@@ -63,11 +298,13 @@ type ListDataItemsResponse struct {
 
 // DataByUUIDResponse данные возвращаемые сервером на запрос по uuid данных
 type DataByUUIDResponse struct {
-	IsCard bool `json:"is_card"`
-	IsText bool `json:"is_text"`
-	IsFile bool `json:"is_file"`
+	IsCard       bool `json:"is_card"`
+	IsText       bool `json:"is_text"`
+	IsFile       bool `json:"is_file"`
+	IsCredential bool `json:"is_credential"`
 	// Данные ответа аналогичным данным запроса с стороны клиента по типам данных
-	CardData CardDataRequest     `json:"card_data,omitempty"`
-	TextData TextDataRequest     `json:"text_data,omitempty"`
-	FileData FileDataInitRequest `json:"file_data,omitempty"`
+	CardData       CardDataRequest       `json:"card_data,omitempty"`
+	TextData       TextDataRequest       `json:"text_data,omitempty"`
+	FileData       FileDataInitRequest   `json:"file_data,omitempty"`
+	CredentialData CredentialDataRequest `json:"credential_data,omitempty"`
 }
@@ -0,0 +1,61 @@
+package models
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"hash"
+	"time"
+)
+
+// События журнала действий пользователя
+const (
+	AuditLogin        = "login"
+	AuditKeyExchange  = "key_exchange"
+	AuditItemRead     = "item_read"
+	AuditItemSave     = "item_save"
+	AuditFileDownload = "file_download"
+)
+
+// AuditEvent запись журнала действий пользователя. Записи пользователя связаны в цепочку:
+// Hash считается от Hash предыдущей записи (PrevHash) и полей записи, поэтому изменение
+// или удаление записи обнаруживается при проверке цепочки. Hash - HMAC ключом сервера (KeyID - kid KEK,
+// от которого он получен), без ключа цепочку нельзя пересчитать. Записи без KeyID сделаны до подписи (sha256)
+type AuditEvent struct {
+	ID        int64     `json:"id"`
+	UserUUID  string    `json:"user_uuid"`
+	Action    string    `json:"action"`
+	DataUUID  string    `json:"data_uuid"`
+	IP        string    `json:"ip"`
+	Details   string    `json:"details"`
+	PrevHash  string    `json:"prev_hash"`
+	Hash      string    `json:"hash"`
+	KeyID     string    `json:"key_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ChainHash хэш записи в цепочке в hex: HMAC-SHA256 ключом key, без ключа - sha256 (записи до подписи).
+// CreatedAt учитывается с точностью до микросекунды (точность БД)
+func (e *AuditEvent) ChainHash(key []byte) string {
+	var h hash.Hash
+	if key == nil {
+		h = sha256.New()
+	} else {
+		h = hmac.New(sha256.New, key)
+	}
+	for _, value := range []string{
+		e.PrevHash,
+		e.UserUUID,
+		e.Action,
+		e.DataUUID,
+		e.IP,
+		e.Details,
+		e.CreatedAt.UTC().Truncate(time.Microsecond).Format(time.RFC3339Nano),
+	} {
+		// длина перед значением, чтобы границы полей нельзя было сдвинуть
+		_ = binary.Write(h, binary.BigEndian, uint32(len(value)))
+		h.Write([]byte(value))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// ClientCertificate сертификат клиента, выданный CA сервера, и его привязка к пользователю
+type ClientCertificate struct {
+	ID            int64      `json:"-"`
+	UserUUID      string     `json:"user_uuid"`
+	DeviceUUID    string     `json:"device_uuid"` // устройство, на ключ которого выдан сертификат
+	Fingerprint   string     `json:"fingerprint"` // sha256 сертификата в hex
+	PublicKeyHash string     `json:"-"`           // sha256 публичного ключа клиента, по нему отзываются прежние сертификаты ключа
+	SerialNumber  string     `json:"serial_number"`
+	CreatedAt     time.Time  `json:"created_at"`
+	ExpiresAt     time.Time  `json:"expires_at"`
+	RevokedAt     *time.Time `json:"revoked_at"`
+}
+
+// IsActive сертификат не отозван и не истёк
+func (c *ClientCertificate) IsActive() bool {
+	return c.RevokedAt == nil && c.ExpiresAt.After(time.Now())
+}
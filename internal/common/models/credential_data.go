@@ -0,0 +1,18 @@
+package models
+
+// CredentialData пары логин/пароль
+type CredentialData struct {
+	Common
+	Name       string                `json:"name"`        // короткое название
+	ObjectType string                `json:"object_type"` // тип данных из value (прим. credential_data_value_v1 ...)
+	Value      CredentialDataValueV1 `json:"value"`       // jsonb postgress (тип зависит от ObjectType)
+	Version    int64                 `json:"version"`     // версия, растёт при каждом сохранении
+}
+
+// CredentialDataValueV1 значение для Value
+type CredentialDataValueV1 struct {
+	Username string   `json:"username"` // логин
+	Password string   `json:"password"` // пароль
+	URLs     []string `json:"urls"`     // адреса сайтов, где используется пара
+	Notes    string   `json:"notes"`    // заметки
+}
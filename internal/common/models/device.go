@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// Device устройство пользователя. Создаётся при входе с нового устройства, UUID устройства передаётся в токене доступа.
+// VaultKey - ключ хранилища, зашифрованный ключом устройства на клиенте, сервер его не расшифровывает.
+// ClientKey - ключ шифрования запросов устройства, зашифрованный KEK (UUID устройства - дополнительные данные)
+type Device struct {
+	Common
+	UserUUID   string     `json:"user_uuid"`
+	Name       string     `json:"name"`
+	PublicKey  string     `json:"-"`
+	VaultKey   string     `json:"-"`
+	ClientKey  string     `json:"-"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastSeenAt time.Time  `json:"last_seen_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+}
+
+// IsActive устройство не отозвано
+func (d *Device) IsActive() bool {
+	return d.RevokedAt == nil
+}
@@ -0,0 +1,18 @@
+package models
+
+// Типы событий изменения данных
+const (
+	EventItemCreated = "item_created"
+	EventItemUpdated = "item_updated"
+	EventItemDeleted = "item_deleted"
+	EventKeyRotated  = "key_rotated"
+)
+
+// Event событие изменения данных пользователя, рассылается его подключённым клиентам (уведомление Postgres data_events)
+type Event struct {
+	Type     string `json:"type"`
+	UserUUID string `json:"user_uuid"`
+	DataType string `json:"data_type,omitempty"`
+	DataUUID string `json:"data_uuid,omitempty"`
+	Revision int64  `json:"revision,omitempty"` // ревизия изменённых данных (item_updated)
+}
@@ -12,4 +12,15 @@ type FileData struct {
 	Size      int64  `json:"size"`      // размер файла
 	Storage   string `json:"storage"`   // имя сервера где находится файла
 	Uploaded  bool   `json:"uploaded"`  // полностью загружен
+	Version   int64  `json:"version"`   // версия, растёт при каждом сохранении
+	Chunks    int    `json:"chunks"`    // число частей содержимого, 0 - файл загружен одним запросом
+}
+
+// FileChunk принятая часть содержимого файла
+type FileChunk struct {
+	ID       int64  `json:"-"`
+	FileUUID string `json:"file_uuid"`
+	Part     int    `json:"part"`     // номер части, с 0
+	Size     int64  `json:"size"`     // размер шифротекста части
+	Checksum string `json:"checksum"` // sha256 шифротекста части (hex)
 }
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// IdempotencyKey ключ идемпотентности запроса сохранения и сохранённый ответ на него.
+// StatusCode равен 0, пока первый запрос с ключом выполняется
+type IdempotencyKey struct {
+	ID          int64     `json:"-"`
+	UserUUID    string    `json:"user_uuid"`
+	Key         string    `json:"key"`
+	RequestHash string    `json:"request_hash"`
+	StatusCode  int       `json:"status_code"`
+	Response    string    `json:"response"`
+	CreatedAt   time.Time `json:"created_at"`
+}
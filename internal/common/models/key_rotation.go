@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// Состояния смены ключа шифрования клиента
+const (
+	KeyRotationRunning  = "running"
+	KeyRotationFinished = "finished"
+	KeyRotationFailed   = "failed"
+)
+
+// KeyRotation смена ключа шифрования клиента устройства. Прежний ключ остаётся у устройства до завершения,
+// новый хранится зашифрованным KEK. Пустой DeviceUUID - смена ключа пользователя (сессии без устройства)
+type KeyRotation struct {
+	ID           int64      `json:"-"`
+	UUID         string     `json:"uuid"`
+	UserUUID     string     `json:"user_uuid"`
+	DeviceUUID   string     `json:"device_uuid"`
+	NewClientKey string     `json:"-"`
+	Status       string     `json:"status"`
+	Error        string     `json:"error"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+	FinishedAt   *time.Time `json:"finished_at"`
+}
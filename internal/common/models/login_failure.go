@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// LoginFailure неудачные попытки входа с логином (логин может не принадлежать пользователю).
+// LockedUntil - время, до которого следующая попытка отклоняется без проверки пароля
+type LoginFailure struct {
+	ID          int64      `json:"-"`
+	Login       string     `json:"login"`
+	Failures    int        `json:"failures"`
+	FailedAt    time.Time  `json:"failed_at"`
+	LockedUntil *time.Time `json:"locked_until"`
+}
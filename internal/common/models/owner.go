@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 // Owner Пользователь, владелец данных
 type Owner struct {
 	ID       int64  `json:"id"`
@@ -10,9 +12,13 @@ type Owner struct {
 
 // OwnerData данные пользователя
 type OwnerData struct {
-	UserUUID     string `json:"user_uuid"`
-	DataUUID     string `json:"data_uuid"`
-	DataType     string `json:"data_type"`
-	DataTypeName string `json:"data_type_name"`
-	DataName     string `json:"data_name"`
+	UserUUID     string    `json:"user_uuid"`
+	DataUUID     string    `json:"data_uuid"`
+	DataType     string    `json:"data_type"`
+	DataTypeName string    `json:"data_type_name"`
+	DataName     string    `json:"data_name"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+	Revision     int64     `json:"revision"` // ревизия последнего изменения, курсор синхронизации
+	Deleted      bool      `json:"deleted"`  // данные удалены, запись из deleted_items
 }
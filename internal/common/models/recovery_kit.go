@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// RecoveryKit данные для восстановления доступа после потери ключей клиента.
+// VaultKey - ключ хранилища, зашифрованный ключом восстановления, ClientKey - ключ шифрования клиента,
+// зашифрованный ключом хранилища. Оба значения шифруются на клиенте, сервер их не расшифровывает
+type RecoveryKit struct {
+	ID        int64     `json:"-"`
+	UserUUID  string    `json:"user_uuid"`
+	VaultKey  string    `json:"vault_key"`
+	ClientKey string    `json:"client_key"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
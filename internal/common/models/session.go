@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// Session сессия пользователя, к которой привязан refresh токен
+type Session struct {
+	Common
+	UserUUID                 string     `json:"user_uuid"`
+	DeviceUUID               string     `json:"device_uuid"` // устройство, с которого открыта сессия (пусто для сессий до учёта устройств)
+	RefreshTokenHash         string     `json:"-"`           // sha256 текущего refresh токена
+	PreviousRefreshTokenHash string     `json:"-"`           // sha256 предыдущего refresh токена, для выявления повторного использования
+	CreatedAt                time.Time  `json:"created_at"`
+	ExpiresAt                time.Time  `json:"expires_at"`
+	RevokedAt                *time.Time `json:"revoked_at"`
+}
+
+// IsActive сессия не отозвана и не истекла
+func (s *Session) IsActive() bool {
+	return s.RevokedAt == nil && s.ExpiresAt.After(time.Now())
+}
@@ -3,7 +3,8 @@ package models
 // TextData текст
 type TextData struct {
 	Common
-	Name  string `json:"name"`  // короткое название
-	Value string `json:"value"` // текст
+	Name    string `json:"name"`    // короткое название
+	Value   string `json:"value"`   // текст
+	Version int64  `json:"version"` // версия, растёт при каждом сохранении
 
 }
@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// UserTOTP второй фактор (TOTP) пользователя
+type UserTOTP struct {
+	ID        int64     `json:"id"`
+	UserUUID  string    `json:"user_uuid"`
+	Secret    string    `json:"-"`       // секрет в base32
+	Enabled   bool      `json:"enabled"` // false - секрет выдан, но не подтверждён кодом
+	LastStep  int64     `json:"-"`       // шаг последнего принятого кода, повторно код не принимается
+	CreatedAt time.Time `json:"created_at"`
+}
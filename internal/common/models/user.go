@@ -11,4 +11,7 @@ type User struct {
 	CreatedAt        time.Time `json:"created_at"`
 	PublicKey        string    `json:"public_key"`
 	PrivateClientKey string    `json:"private_client_key"`
+	MasterKeySalt    string    `json:"master_key_salt"`  // соль для получения мастер-ключа на клиенте
+	MasterKeyCheck   string    `json:"master_key_check"` // контрольное значение, зашифрованное мастер-ключом
+	VaultLegacy      bool      `json:"vault_legacy"`     // есть данные, сохранённые до шифрования мастер-ключом
 }
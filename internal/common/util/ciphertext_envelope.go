@@ -0,0 +1,168 @@
+package util
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// Конверт шифротекста: magic | версия | алгоритм | длина id ключа | id ключа | nonce | шифротекст с тегом.
+// Заголовок (всё до nonce) входит в дополнительные данные AEAD, поэтому подмена алгоритма или id ключа
+// обнаруживается при расшифровке. По id ключа и алгоритму можно менять ключи и шифры, не перешифровывая
+// уже сохранённые данные
+const (
+	ciphertextEnvelopeMagic   = "GKCE"
+	ciphertextEnvelopeVersion = 1
+	// CiphertextKeyIDMaxSize максимальная длина id ключа
+	CiphertextKeyIDMaxSize = 255
+	// CiphertextKeySize размер ключа для всех алгоритмов конверта
+	CiphertextKeySize = 32
+)
+
+// Алгоритмы конверта шифротекста
+const (
+	CipherAES256GCM         byte = 1
+	CipherXChaCha20Poly1305 byte = 2
+)
+
+var (
+	// ErrCiphertextEnvelope данные не являются корректным конвертом шифротекста
+	ErrCiphertextEnvelope = errors.New("invalid ciphertext envelope")
+	// ErrUnsupportedCipher неизвестный алгоритм или версия конверта
+	ErrUnsupportedCipher = errors.New("unsupported cipher")
+	// ErrInvalidKeySize ключ не подходит алгоритму
+	ErrInvalidKeySize = errors.New("invalid key size")
+	// ErrDecrypt шифротекст не прошёл проверку (другой ключ или данные изменены)
+	ErrDecrypt = errors.New("message authentication failed")
+)
+
+// CiphertextEnvelope разобранный конверт шифротекста
+type CiphertextEnvelope struct {
+	Version    byte
+	Algorithm  byte
+	KeyID      string
+	Nonce      []byte
+	Ciphertext []byte
+
+	header []byte
+}
+
+// SealEnvelope зашифровать данные в конверт. additionalData не сохраняется в конверте и должны совпасть при расшифровке
+func SealEnvelope(rawDate []byte, key []byte, algorithm byte, keyID string, additionalData []byte) ([]byte, error) {
+	if len(keyID) > CiphertextKeyIDMaxSize {
+		return nil, fmt.Errorf("key id is longer than %d bytes", CiphertextKeyIDMaxSize)
+	}
+	aead, err := newEnvelopeAEAD(algorithm, key)
+	if err != nil {
+		return nil, err
+	}
+	headerSize := len(ciphertextEnvelopeMagic) + 3 + len(keyID)
+	sealed := make([]byte, 0, headerSize+aead.NonceSize()+len(rawDate)+aead.Overhead())
+	sealed = append(sealed, ciphertextEnvelopeMagic...)
+	sealed = append(sealed, ciphertextEnvelopeVersion, algorithm, byte(len(keyID)))
+	sealed = append(sealed, keyID...)
+	header := sealed[:headerSize]
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	sealed = append(sealed, nonce...)
+	return aead.Seal(sealed, nonce, rawDate, envelopeAdditionalData(header, additionalData)), nil
+}
+
+// IsCiphertextEnvelope данные начинаются с заголовка конверта
+func IsCiphertextEnvelope(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(ciphertextEnvelopeMagic))
+}
+
+// ParseEnvelope разбор конверта без расшифровки (например, чтобы выбрать ключ по KeyID)
+func ParseEnvelope(data []byte) (*CiphertextEnvelope, error) {
+	// magic, версия, алгоритм и длина id ключа
+	fixedSize := len(ciphertextEnvelopeMagic) + 3
+	if len(data) < fixedSize || !IsCiphertextEnvelope(data) {
+		return nil, ErrCiphertextEnvelope
+	}
+	envelope := &CiphertextEnvelope{
+		Version:   data[len(ciphertextEnvelopeMagic)],
+		Algorithm: data[len(ciphertextEnvelopeMagic)+1],
+	}
+	if envelope.Version != ciphertextEnvelopeVersion {
+		return nil, ErrUnsupportedCipher
+	}
+	nonceSize, overhead, err := envelopeSizes(envelope.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+	headerSize := fixedSize + int(data[fixedSize-1])
+	if len(data) < headerSize+nonceSize+overhead {
+		return nil, ErrCiphertextEnvelope
+	}
+	envelope.header = data[:headerSize]
+	envelope.KeyID = string(data[fixedSize:headerSize])
+	envelope.Nonce = data[headerSize : headerSize+nonceSize]
+	envelope.Ciphertext = data[headerSize+nonceSize:]
+	return envelope, nil
+}
+
+// Open расшифровать конверт
+func (e *CiphertextEnvelope) Open(key []byte, additionalData []byte) ([]byte, error) {
+	aead, err := newEnvelopeAEAD(e.Algorithm, key)
+	if err != nil {
+		return nil, err
+	}
+	rawDate, err := aead.Open(nil, e.Nonce, e.Ciphertext, envelopeAdditionalData(e.header, additionalData))
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return rawDate, nil
+}
+
+// OpenEnvelope разобрать и расшифровать конверт
+func OpenEnvelope(data []byte, key []byte, additionalData []byte) ([]byte, error) {
+	envelope, err := ParseEnvelope(data)
+	if err != nil {
+		return nil, err
+	}
+	return envelope.Open(key, additionalData)
+}
+
+func envelopeSizes(algorithm byte) (int, int, error) {
+	switch algorithm {
+	case CipherAES256GCM:
+		return 12, 16, nil
+	case CipherXChaCha20Poly1305:
+		return chacha20poly1305.NonceSizeX, chacha20poly1305.Overhead, nil
+	}
+	return 0, 0, ErrUnsupportedCipher
+}
+
+func newEnvelopeAEAD(algorithm byte, key []byte) (cipher.AEAD, error) {
+	if _, _, err := envelopeSizes(algorithm); err != nil {
+		return nil, err
+	}
+	if len(key) != CiphertextKeySize {
+		return nil, ErrInvalidKeySize
+	}
+	if algorithm == CipherXChaCha20Poly1305 {
+		return chacha20poly1305.NewX(key)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func envelopeAdditionalData(header []byte, additionalData []byte) []byte {
+	if len(additionalData) == 0 {
+		return header
+	}
+	return append(bytes.Clone(header), additionalData...)
+}
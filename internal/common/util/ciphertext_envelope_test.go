@@ -0,0 +1,175 @@
+package util
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"testing"
+)
+
+func TestSealEnvelope(t *testing.T) {
+	key := bytes.Repeat([]byte{7}, CiphertextKeySize)
+	plaintext := []byte("test plaintext")
+
+	for _, algorithm := range []byte{CipherAES256GCM, CipherXChaCha20Poly1305} {
+		sealed, err := SealEnvelope(plaintext, key, algorithm, "key-2024", []byte("item-1"))
+		if err != nil {
+			t.Fatalf("SealEnvelope(%d) failed: %v", algorithm, err)
+		}
+		envelope, err := ParseEnvelope(sealed)
+		if err != nil {
+			t.Fatalf("ParseEnvelope(%d) failed: %v", algorithm, err)
+		}
+		if envelope.Version != ciphertextEnvelopeVersion || envelope.Algorithm != algorithm || envelope.KeyID != "key-2024" {
+			t.Errorf("Unexpected envelope header: %+v", envelope)
+		}
+		opened, err := envelope.Open(key, []byte("item-1"))
+		if err != nil {
+			t.Fatalf("Open(%d) failed: %v", algorithm, err)
+		}
+		if !bytes.Equal(plaintext, opened) {
+			t.Errorf("Decrypted data does not match: %s != %s", opened, plaintext)
+		}
+		if _, err = OpenEnvelope(sealed, key, []byte("item-2")); !errors.Is(err, ErrDecrypt) {
+			t.Errorf("Expected ErrDecrypt for another additional data, got %v", err)
+		}
+	}
+}
+
+func TestSealEnvelope_Invalid(t *testing.T) {
+	key := make([]byte, CiphertextKeySize)
+
+	if _, err := SealEnvelope([]byte("data"), key, 0, "", nil); !errors.Is(err, ErrUnsupportedCipher) {
+		t.Errorf("Expected ErrUnsupportedCipher, got %v", err)
+	}
+	if _, err := SealEnvelope([]byte("data"), key[:16], CipherAES256GCM, "", nil); !errors.Is(err, ErrInvalidKeySize) {
+		t.Errorf("Expected ErrInvalidKeySize, got %v", err)
+	}
+	if _, err := SealEnvelope([]byte("data"), key, CipherAES256GCM, string(bytes.Repeat([]byte("k"), 256)), nil); err == nil {
+		t.Errorf("Expected error for long key id")
+	}
+}
+
+func TestOpenEnvelope_Invalid(t *testing.T) {
+	key := make([]byte, CiphertextKeySize)
+	sealed, _ := SealEnvelope([]byte("data"), key, CipherXChaCha20Poly1305, "kid", nil)
+
+	tamperedAlgorithm := bytes.Clone(sealed)
+	tamperedAlgorithm[len(ciphertextEnvelopeMagic)+1] = CipherAES256GCM
+	tamperedKeyID := bytes.Clone(sealed)
+	tamperedKeyID[len(ciphertextEnvelopeMagic)+3] ^= 0x01
+	tamperedVersion := bytes.Clone(sealed)
+	tamperedVersion[len(ciphertextEnvelopeMagic)] = 2
+	longKeyID := bytes.Clone(sealed)
+	longKeyID[len(ciphertextEnvelopeMagic)+2] = 255
+
+	tests := []struct {
+		name string
+		data []byte
+		key  []byte
+		err  error
+	}{
+		{"another key", sealed, bytes.Repeat([]byte{1}, CiphertextKeySize), ErrDecrypt},
+		{"short key", sealed, key[:16], ErrInvalidKeySize},
+		{"tampered algorithm", tamperedAlgorithm, key, ErrDecrypt},
+		{"tampered key id", tamperedKeyID, key, ErrDecrypt},
+		{"unknown version", tamperedVersion, key, ErrUnsupportedCipher},
+		{"key id out of range", longKeyID, key, ErrCiphertextEnvelope},
+		{"short", sealed[:len(sealed)-1-16-24], key, ErrCiphertextEnvelope},
+		{"magic only", []byte(ciphertextEnvelopeMagic), key, ErrCiphertextEnvelope},
+		{"empty", nil, key, ErrCiphertextEnvelope},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := OpenEnvelope(tt.data, tt.key, nil)
+			if !errors.Is(err, tt.err) {
+				t.Errorf("Expected %v, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestDataDecryptAES_Legacy(t *testing.T) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatalf("Failed to generate random key: %v", err)
+	}
+	block, _ := aes.NewCipher(key)
+	gcm, _ := cipher.NewGCM(block)
+	nonce := make([]byte, gcm.NonceSize())
+	// nonce прежнего формата совпадает с magic конверта
+	copy(nonce, ciphertextEnvelopeMagic)
+	legacy := gcm.Seal(nonce, nonce, []byte("legacy data"), nil)
+
+	decrypted, err := DataDecryptAES(legacy, key)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if string(decrypted) != "legacy data" {
+		t.Errorf("Expected %q, got %q", "legacy data", decrypted)
+	}
+}
+
+func FuzzParseEnvelope(f *testing.F) {
+	key := make([]byte, CiphertextKeySize)
+	for _, algorithm := range []byte{CipherAES256GCM, CipherXChaCha20Poly1305} {
+		sealed, _ := SealEnvelope([]byte("data"), key, algorithm, "kid", nil)
+		f.Add(sealed)
+	}
+	f.Add([]byte(ciphertextEnvelopeMagic))
+	f.Add([]byte{})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		envelope, err := ParseEnvelope(data)
+		if err != nil {
+			return
+		}
+		nonceSize, overhead, _ := envelopeSizes(envelope.Algorithm)
+		if len(envelope.Nonce) != nonceSize || len(envelope.Ciphertext) < overhead {
+			t.Errorf("Envelope passed validation with wrong sizes: %+v", envelope)
+		}
+		_, _ = envelope.Open(key, nil)
+	})
+}
+
+func FuzzDataDecryptAES(f *testing.F) {
+	key := make([]byte, 32)
+	sealed, _ := DataEncryptAES([]byte("data"), key)
+	f.Add(sealed)
+	f.Add(sealed[:len(sealed)-1])
+	f.Add(make([]byte, 11))
+	f.Add([]byte{})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		_, _ = DataDecryptAES(data, key)
+	})
+}
+
+func FuzzEnvelopeRoundTrip(f *testing.F) {
+	f.Add([]byte("data"), "kid", byte(CipherAES256GCM))
+	f.Add([]byte{}, "", byte(CipherXChaCha20Poly1305))
+
+	f.Fuzz(func(t *testing.T, plaintext []byte, keyID string, algorithm byte) {
+		key := bytes.Repeat([]byte{3}, CiphertextKeySize)
+		sealed, err := SealEnvelope(plaintext, key, algorithm, keyID, nil)
+		if err != nil {
+			return
+		}
+		envelope, err := ParseEnvelope(sealed)
+		if err != nil {
+			t.Fatalf("ParseEnvelope failed: %v", err)
+		}
+		if envelope.KeyID != keyID || envelope.Algorithm != algorithm {
+			t.Errorf("Header mismatch: %+v", envelope)
+		}
+		opened, err := envelope.Open(key, nil)
+		if err != nil {
+			t.Fatalf("Open failed: %v", err)
+		}
+		if !bytes.Equal(plaintext, opened) {
+			t.Errorf("Round trip mismatch")
+		}
+	})
+}
@@ -1,8 +1,12 @@
 package util
 
 import (
+	"crypto"
 	"crypto/aes"
 	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/md5"
 	"crypto/rand"
 	"crypto/rsa"
@@ -10,8 +14,8 @@ import (
 	"crypto/x509"
 	"encoding/hex"
 	"encoding/pem"
+	"errors"
 	"fmt"
-	"io"
 	"os"
 )
 
@@ -35,23 +39,30 @@ func DataDecryptRSA(encryptDate []byte, key *rsa.PrivateKey) ([]byte, error) {
 		nil)
 }
 
-// DataEncryptAES зашифровать данные AES
+// DataEncryptAES зашифровать данные AES-256-GCM в конверт шифротекста
 func DataEncryptAES(rawDate []byte, key []byte) ([]byte, error) {
-	block, _ := aes.NewCipher(key)
-	gcm, err := cipher.NewGCM(block)
-	if err != nil {
-		return nil, err
+	return SealEnvelope(rawDate, key, CipherAES256GCM, "", nil)
+}
+
+// DataDecryptAES расшифровать данные: конверт шифротекста любого поддерживаемого алгоритма
+// или прежний формат nonce||шифротекст AES-GCM
+func DataDecryptAES(encryptDate []byte, key []byte) ([]byte, error) {
+	if !IsCiphertextEnvelope(encryptDate) {
+		return dataDecryptLegacyAES(encryptDate, key)
 	}
-	nonce := make([]byte, gcm.NonceSize())
-	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
-		return nil, err
+	rawDate, err := OpenEnvelope(encryptDate, key, nil)
+	if err == nil {
+		return rawDate, nil
+	}
+	// nonce данных в прежнем формате может случайно начинаться с magic конверта
+	if legacyDate, legacyErr := dataDecryptLegacyAES(encryptDate, key); legacyErr == nil {
+		return legacyDate, nil
 	}
-	ciphertext := gcm.Seal(nonce, nonce, rawDate, nil)
-	return ciphertext, nil
+	return nil, err
 }
 
-// DataDecryptAES расшифровать данные
-func DataDecryptAES(encryptDate []byte, key []byte) ([]byte, error) {
+// dataDecryptLegacyAES расшифровать данные в формате nonce||шифротекст (до появления конверта)
+func dataDecryptLegacyAES(encryptDate []byte, key []byte) ([]byte, error) {
 	block, err := aes.NewCipher(key)
 	if err != nil {
 		return nil, err
@@ -61,72 +72,131 @@ func DataDecryptAES(encryptDate []byte, key []byte) ([]byte, error) {
 		return nil, err
 	}
 	nonceSize := gcm.NonceSize()
+	if len(encryptDate) < nonceSize+gcm.Overhead() {
+		return nil, ErrCiphertextEnvelope
+	}
 	nonce, ciphertext := encryptDate[:nonceSize], encryptDate[nonceSize:]
 
-	return gcm.Open(nil, nonce, ciphertext, nil)
+	rawDate, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return rawDate, nil
 }
 
-// FillPrivateRsaKeyFromFile Вернёт приватный ключ RSA (для дешиврования входящих соообщениий)
-func FillPrivateRsaKeyFromFile(path string) (*rsa.PrivateKey, error) {
-	keyBytes, err := os.ReadFile(path)
+// ErrUnsupportedKeyType ключ неподдерживаемого алгоритма
+var ErrUnsupportedKeyType = errors.New("unsupported key type")
+
+// ParsePrivateKey разбор приватного ключа из PEM (PKCS8, PKCS1 RSA, SEC1 EC). Поддерживаются RSA, ECDSA и Ed25519
+func ParsePrivateKey(keyBytes []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(keyBytes)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data found in file")
+	}
+	var (
+		key any
+		err error
+	)
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
 	if err != nil {
 		return nil, err
 	}
-	block, _ := pem.Decode(keyBytes)
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		return k, nil
+	case *ecdsa.PrivateKey:
+		return k, nil
+	case ed25519.PrivateKey:
+		return k, nil
+	}
+	return nil, ErrUnsupportedKeyType
+}
 
+// ParsePublicKey разбор публичного ключа из PEM (PKIX). Поддерживаются RSA, ECDSA, Ed25519 и X25519
+func ParsePublicKey(keyBytes []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(keyBytes)
 	if block == nil {
 		return nil, fmt.Errorf("no PEM data found in file")
 	}
-	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
 	if err != nil {
 		return nil, err
 	}
-	switch key.(type) {
-	case *rsa.PrivateKey:
-		return key.(*rsa.PrivateKey), nil
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		return k, nil
+	case *ecdsa.PublicKey:
+		return k, nil
+	case ed25519.PublicKey:
+		return k, nil
+	case *ecdh.PublicKey:
+		return k, nil
 	}
+	return nil, ErrUnsupportedKeyType
+}
 
-	return nil, fmt.Errorf("unsupported key type")
+// FillPrivateKeyFromFile Вернёт приватный ключ любого поддерживаемого алгоритма
+func FillPrivateKeyFromFile(path string) (crypto.Signer, error) {
+	keyBytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParsePrivateKey(keyBytes)
 }
 
-// FillPublicRsaKeyFromFile Вернёт публичный ключ RSA (для шифрования исходящих соообщениий)
-func FillPublicRsaKeyFromFile(path string) (*rsa.PublicKey, error) {
+// FillPublicKeyFromFile Вернёт публичный ключ любого поддерживаемого алгоритма
+func FillPublicKeyFromFile(path string) (crypto.PublicKey, error) {
 	keyBytes, err := os.ReadFile(path)
 	if err != nil {
 		return nil, err
 	}
-	block, _ := pem.Decode(keyBytes)
+	return ParsePublicKey(keyBytes)
+}
 
-	if block == nil {
-		return nil, fmt.Errorf("no PEM data found in file")
+// FillPrivateRsaKeyFromFile Вернёт приватный ключ RSA (для дешиврования входящих соообщениий)
+func FillPrivateRsaKeyFromFile(path string) (*rsa.PrivateKey, error) {
+	key, err := FillPrivateKeyFromFile(path)
+	if err != nil {
+		return nil, err
 	}
-	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, ErrUnsupportedKeyType
+	}
+	return rsaKey, nil
+}
+
+// FillPublicRsaKeyFromFile Вернёт публичный ключ RSA (для шифрования исходящих соообщениий)
+func FillPublicRsaKeyFromFile(path string) (*rsa.PublicKey, error) {
+	key, err := FillPublicKeyFromFile(path)
 	if err != nil {
 		return nil, err
 	}
-	switch key.(type) {
-	case *rsa.PublicKey:
-		return key.(*rsa.PublicKey), nil
+	rsaKey, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return nil, ErrUnsupportedKeyType
 	}
-	return nil, fmt.Errorf("unsupported key type")
+	return rsaKey, nil
 }
 
 // FillPublicRsaKeyFromString Вернёт публичный ключ RSA (для шифрования исходящих соообщениий)
 func FillPublicRsaKeyFromString(keyData string) (*rsa.PublicKey, error) {
-	block, _ := pem.Decode([]byte(keyData))
-
-	if block == nil {
-		return nil, fmt.Errorf("no PEM data found in file")
-	}
-	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	key, err := ParsePublicKey([]byte(keyData))
 	if err != nil {
 		return nil, err
 	}
-	switch key.(type) {
-	case *rsa.PublicKey:
-		return key.(*rsa.PublicKey), nil
+	rsaKey, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return nil, ErrUnsupportedKeyType
 	}
-	return nil, fmt.Errorf("unsupported key type")
+	return rsaKey, nil
 }
 
 // CreateHashForKey создаёт хэш для последующего использования в шифровании сообщений
@@ -1,8 +1,11 @@
 package util
 
 import (
+	"crypto"
 	"crypto/aes"
+	"crypto/ecdh"
 	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
@@ -448,3 +451,68 @@ func TestDataEncryptRSA(t *testing.T) {
 	}
 
 }
+
+func TestParsePrivateKey(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecdsaKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	exchangeKey, _ := ecdh.X25519().GenerateKey(rand.Reader)
+
+	pkcs8 := func(key any) []byte {
+		der, err := x509.MarshalPKCS8PrivateKey(key)
+		if err != nil {
+			t.Fatalf("MarshalPKCS8PrivateKey failed: %v", err)
+		}
+		return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	}
+	sec1, _ := x509.MarshalECPrivateKey(ecdsaKey)
+
+	tests := []struct {
+		name    string
+		data    []byte
+		wantErr bool
+	}{
+		{"RSA PKCS8", pkcs8(rsaKey), false},
+		{"RSA PKCS1", pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)}), false},
+		{"ECDSA PKCS8", pkcs8(ecdsaKey), false},
+		{"ECDSA SEC1", pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: sec1}), false},
+		{"Ed25519", pkcs8(edKey), false},
+		{"X25519", pkcs8(exchangeKey), true},
+		{"Invalid PEM", []byte("invalid pem data"), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := ParsePrivateKey(tt.data)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if key == nil || key.Public() == nil {
+				t.Errorf("Expected non-nil key")
+			}
+		})
+	}
+}
+
+func TestParsePublicKey(t *testing.T) {
+	ecdsaKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	edKey, _, _ := ed25519.GenerateKey(rand.Reader)
+
+	for name, key := range map[string]any{"ECDSA": &ecdsaKey.PublicKey, "Ed25519": edKey} {
+		t.Run(name, func(t *testing.T) {
+			der, _ := x509.MarshalPKIXPublicKey(key)
+			parsed, err := ParsePublicKey(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if !key.(interface{ Equal(crypto.PublicKey) bool }).Equal(parsed) {
+				t.Errorf("Parsed key does not match")
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package util
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"io"
+)
+
+// Собранный файл - последовательность частей: длина шифротекста части (4 байта, big-endian) | шифротекст части.
+// Каждая часть зашифрована отдельно, клиент расшифровывает файл по частям
+
+// ErrChunkFrame повреждённая запись части файла
+var ErrChunkFrame = errors.New("invalid file chunk frame")
+
+// ChunkChecksum контрольная сумма части файла (sha256, hex)
+func ChunkChecksum(chunk []byte) string {
+	sum := sha256.Sum256(chunk)
+	return hex.EncodeToString(sum[:])
+}
+
+// WriteChunkFrame записать часть файла с длиной
+func WriteChunkFrame(w io.Writer, chunk []byte) error {
+	var size [4]byte
+	binary.BigEndian.PutUint32(size[:], uint32(len(chunk)))
+	if _, err := w.Write(size[:]); err != nil {
+		return err
+	}
+	_, err := w.Write(chunk)
+	return err
+}
+
+// ReadChunkFrame прочитать следующую часть файла не длиннее maxSize. После последней части вернёт io.EOF
+func ReadChunkFrame(r io.Reader, maxSize int) ([]byte, error) {
+	var size [4]byte
+	_, err := io.ReadFull(r, size[:])
+	if errors.Is(err, io.EOF) {
+		return nil, io.EOF
+	}
+	if err != nil {
+		return nil, ErrChunkFrame
+	}
+	length := binary.BigEndian.Uint32(size[:])
+	if int64(length) > int64(maxSize) {
+		return nil, ErrChunkFrame
+	}
+	chunk := make([]byte, length)
+	if _, err = io.ReadFull(r, chunk); err != nil {
+		return nil, ErrChunkFrame
+	}
+	return chunk, nil
+}
@@ -0,0 +1,46 @@
+package util
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestChunkFrames(t *testing.T) {
+	buf := new(bytes.Buffer)
+	chunks := [][]byte{[]byte("first chunk"), {}, []byte("last")}
+	for _, chunk := range chunks {
+		if err := WriteChunkFrame(buf, chunk); err != nil {
+			t.Fatalf("WriteChunkFrame failed: %v", err)
+		}
+	}
+	framed := buf.Bytes()
+
+	for i, expected := range chunks {
+		chunk, err := ReadChunkFrame(buf, 100)
+		if err != nil {
+			t.Fatalf("ReadChunkFrame(%d) failed: %v", i, err)
+		}
+		if !bytes.Equal(expected, chunk) {
+			t.Errorf("Chunk %d does not match: %q != %q", i, chunk, expected)
+		}
+	}
+	if _, err := ReadChunkFrame(buf, 100); !errors.Is(err, io.EOF) {
+		t.Errorf("Expected io.EOF after the last chunk, got %v", err)
+	}
+
+	truncated := bytes.NewReader(framed[:6])
+	if _, err := ReadChunkFrame(truncated, 100); !errors.Is(err, ErrChunkFrame) {
+		t.Errorf("Expected ErrChunkFrame for a truncated chunk, got %v", err)
+	}
+	if _, err := ReadChunkFrame(bytes.NewReader(framed), 5); !errors.Is(err, ErrChunkFrame) {
+		t.Errorf("Expected ErrChunkFrame for a chunk longer than the limit, got %v", err)
+	}
+}
+
+func TestChunkChecksum(t *testing.T) {
+	if ChunkChecksum([]byte("abc")) != "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad" {
+		t.Errorf("Unexpected checksum %s", ChunkChecksum([]byte("abc")))
+	}
+}
@@ -0,0 +1,134 @@
+package util
+
+import (
+	"crypto/ecdh"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"io"
+	"os"
+	"slices"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+const (
+	// KeyExchangeRSA ключ клиента шифруется публичным RSA ключом сервера (RSA-OAEP SHA-256)
+	KeyExchangeRSA = "rsa-oaep-sha256"
+	// KeyExchangeX25519 общий секрет X25519 (эфемерный ключ клиента и ключ обмена сервера), из него HKDF-SHA256 выводит ключ AES-GCM
+	KeyExchangeX25519 = "x25519-hkdf-sha256"
+)
+
+// hkdfInfo контекст вывода ключа, привязывает ключ к протоколу обмена
+const hkdfInfo = "gophkeeper key exchange v1"
+
+// ErrKeyExchangeData повреждённые данные обмена ключами
+var ErrKeyExchangeData = errors.New("invalid key exchange data")
+
+// GenerateExchangeKey создаёт ключ X25519 для согласования ключей
+func GenerateExchangeKey() (*ecdh.PrivateKey, error) {
+	return ecdh.X25519().GenerateKey(rand.Reader)
+}
+
+// DataEncryptX25519 зашифровать данные для владельца ключа обмена peer. Результат: эфемерный публичный ключ | nonce | шифротекст
+func DataEncryptX25519(rawDate []byte, peer *ecdh.PublicKey) ([]byte, error) {
+	ephemeral, err := GenerateExchangeKey()
+	if err != nil {
+		return nil, err
+	}
+	key, err := deriveExchangeKey(ephemeral, peer, slices.Concat(ephemeral.PublicKey().Bytes(), peer.Bytes()))
+	if err != nil {
+		return nil, err
+	}
+	ciphertext, err := DataEncryptAES(rawDate, key)
+	if err != nil {
+		return nil, err
+	}
+	return slices.Concat(ephemeral.PublicKey().Bytes(), ciphertext), nil
+}
+
+// DataDecryptX25519 расшифровать данные ключом обмена
+func DataDecryptX25519(encryptDate []byte, key *ecdh.PrivateKey) ([]byte, error) {
+	publicKeySize := len(key.PublicKey().Bytes())
+	// эфемерный ключ, nonce и тег GCM
+	if len(encryptDate) < publicKeySize+12+16 {
+		return nil, ErrKeyExchangeData
+	}
+	ephemeral, err := key.Curve().NewPublicKey(encryptDate[:publicKeySize])
+	if err != nil {
+		return nil, ErrKeyExchangeData
+	}
+	aesKey, err := deriveExchangeKey(key, ephemeral, slices.Concat(ephemeral.Bytes(), key.PublicKey().Bytes()))
+	if err != nil {
+		return nil, err
+	}
+	return DataDecryptAES(encryptDate[publicKeySize:], aesKey)
+}
+
+// deriveExchangeKey вывод ключа AES-256 из общего секрета. Соль - эфемерный ключ клиента и ключ получателя
+func deriveExchangeKey(private *ecdh.PrivateKey, peer *ecdh.PublicKey, salt []byte) ([]byte, error) {
+	secret, err := private.ECDH(peer)
+	if err != nil {
+		return nil, err
+	}
+	key := make([]byte, 32)
+	_, err = io.ReadFull(hkdf.New(sha256.New, secret, salt, []byte(hkdfInfo)), key)
+	if err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// FillExchangePrivateKeyFromFile Вернёт приватный ключ обмена X25519
+func FillExchangePrivateKeyFromFile(path string) (*ecdh.PrivateKey, error) {
+	keyBytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(keyBytes)
+	if block == nil {
+		return nil, ErrKeyExchangeData
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	exchangeKey, ok := key.(*ecdh.PrivateKey)
+	if !ok || exchangeKey.Curve() != ecdh.X25519() {
+		return nil, ErrUnsupportedKeyType
+	}
+	return exchangeKey, nil
+}
+
+// FillExchangePublicKeyFromFile Вернёт публичный ключ обмена X25519
+func FillExchangePublicKeyFromFile(path string) (*ecdh.PublicKey, error) {
+	key, err := FillPublicKeyFromFile(path)
+	if err != nil {
+		return nil, err
+	}
+	exchangeKey, ok := key.(*ecdh.PublicKey)
+	if !ok || exchangeKey.Curve() != ecdh.X25519() {
+		return nil, ErrUnsupportedKeyType
+	}
+	return exchangeKey, nil
+}
+
+// ExchangePrivateKeyToPEM PEM (PKCS8) приватного ключа обмена
+func ExchangePrivateKeyToPEM(key *ecdh.PrivateKey) ([]byte, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+}
+
+// ExchangePublicKeyToPEM PEM (PKIX) публичного ключа обмена
+func ExchangePublicKeyToPEM(key *ecdh.PublicKey) ([]byte, error) {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), nil
+}
@@ -0,0 +1,108 @@
+package util
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDataEncryptX25519(t *testing.T) {
+	key, err := GenerateExchangeKey()
+	if err != nil {
+		t.Fatalf("GenerateExchangeKey failed: %v", err)
+	}
+	plaintext := []byte("client secret key")
+
+	ciphertext, err := DataEncryptX25519(plaintext, key.PublicKey())
+	if err != nil {
+		t.Fatalf("DataEncryptX25519 failed: %v", err)
+	}
+	if bytes.Contains(ciphertext, plaintext) {
+		t.Errorf("Ciphertext contains plaintext")
+	}
+
+	decrypted, err := DataDecryptX25519(ciphertext, key)
+	if err != nil {
+		t.Fatalf("DataDecryptX25519 failed: %v", err)
+	}
+	if !bytes.Equal(plaintext, decrypted) {
+		t.Errorf("Decrypted text does not match original plaintext: %s != %s", decrypted, plaintext)
+	}
+
+	// эфемерный ключ новый для каждого сообщения
+	other, _ := DataEncryptX25519(plaintext, key.PublicKey())
+	if bytes.Equal(ciphertext[:32], other[:32]) {
+		t.Errorf("Ephemeral key is reused")
+	}
+}
+
+func TestDataDecryptX25519_Invalid(t *testing.T) {
+	key, _ := GenerateExchangeKey()
+	anotherKey, _ := GenerateExchangeKey()
+	ciphertext, _ := DataEncryptX25519([]byte("text"), key.PublicKey())
+
+	if _, err := DataDecryptX25519(ciphertext, anotherKey); err == nil {
+		t.Errorf("Expected error for another key, got nil")
+	}
+
+	tampered := bytes.Clone(ciphertext)
+	tampered[len(tampered)-1] ^= 0xff
+	if _, err := DataDecryptX25519(tampered, key); err == nil {
+		t.Errorf("Expected error for tampered ciphertext, got nil")
+	}
+
+	for _, short := range [][]byte{nil, {}, ciphertext[:32], ciphertext[:50]} {
+		if _, err := DataDecryptX25519(short, key); err == nil {
+			t.Errorf("Expected error for short ciphertext of %d bytes, got nil", len(short))
+		}
+	}
+
+	// нулевой эфемерный ключ даёт нулевой общий секрет
+	lowOrder := bytes.Clone(ciphertext)
+	copy(lowOrder[:32], make([]byte, 32))
+	if _, err := DataDecryptX25519(lowOrder, key); err == nil {
+		t.Errorf("Expected error for low order ephemeral key, got nil")
+	}
+}
+
+func TestExchangeKeyFiles(t *testing.T) {
+	tempDir := t.TempDir()
+	key, _ := GenerateExchangeKey()
+
+	privatePEM, err := ExchangePrivateKeyToPEM(key)
+	if err != nil {
+		t.Fatalf("ExchangePrivateKeyToPEM failed: %v", err)
+	}
+	publicPEM, err := ExchangePublicKeyToPEM(key.PublicKey())
+	if err != nil {
+		t.Fatalf("ExchangePublicKeyToPEM failed: %v", err)
+	}
+	privatePath := filepath.Join(tempDir, "exchange_key.pem")
+	publicPath := filepath.Join(tempDir, "exchange_public_key.pem")
+	os.WriteFile(privatePath, privatePEM, 0600)
+	os.WriteFile(publicPath, publicPEM, 0644)
+
+	loadedPrivate, err := FillExchangePrivateKeyFromFile(privatePath)
+	if err != nil {
+		t.Fatalf("FillExchangePrivateKeyFromFile failed: %v", err)
+	}
+	if !key.Equal(loadedPrivate) {
+		t.Errorf("Loaded private key does not match")
+	}
+	loadedPublic, err := FillExchangePublicKeyFromFile(publicPath)
+	if err != nil {
+		t.Fatalf("FillExchangePublicKeyFromFile failed: %v", err)
+	}
+	if !key.PublicKey().Equal(loadedPublic) {
+		t.Errorf("Loaded public key does not match")
+	}
+
+	// ключ подписи не является ключом обмена
+	if _, err = FillExchangePublicKeyFromFile(privatePath); err == nil {
+		t.Errorf("Expected error for private key file, got nil")
+	}
+	if _, err = FillExchangePrivateKeyFromFile(filepath.Join(tempDir, "nonexistent.pem")); !os.IsNotExist(err) {
+		t.Errorf("Expected not exist error, got %v", err)
+	}
+}
@@ -0,0 +1,33 @@
+package util
+
+import (
+	"crypto/rand"
+	"io"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// Параметры Argon2id для получения мастер-ключа
+const (
+	masterKeyTime    = 3
+	masterKeyMemory  = 64 * 1024
+	masterKeyThreads = 4
+	// MasterKeyLen длина мастер-ключа (AES-256)
+	MasterKeyLen = 32
+	// MasterKeySaltLen длина соли
+	MasterKeySaltLen = 16
+)
+
+// DeriveMasterKey получение ключа шифрования из мастер-пароля (Argon2id)
+func DeriveMasterKey(password string, salt []byte) []byte {
+	return argon2.IDKey([]byte(password), salt, masterKeyTime, masterKeyMemory, masterKeyThreads, MasterKeyLen)
+}
+
+// NewMasterKeySalt случайная соль для мастер-ключа пользователя
+func NewMasterKeySalt() ([]byte, error) {
+	salt := make([]byte, MasterKeySaltLen)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, err
+	}
+	return salt, nil
+}
@@ -0,0 +1,42 @@
+package util
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestDeriveMasterKey(t *testing.T) {
+	salt := []byte("0123456789abcdef")
+
+	key := DeriveMasterKey("master password", salt)
+	if len(key) != MasterKeyLen {
+		t.Fatalf("expected key length %d, got %d", MasterKeyLen, len(key))
+	}
+
+	if !bytes.Equal(key, DeriveMasterKey("master password", salt)) {
+		t.Error("expected the same key for the same password and salt")
+	}
+	if bytes.Equal(key, DeriveMasterKey("other password", salt)) {
+		t.Error("expected different keys for different passwords")
+	}
+	if bytes.Equal(key, DeriveMasterKey("master password", []byte("fedcba9876543210"))) {
+		t.Error("expected different keys for different salts")
+	}
+}
+
+func TestNewMasterKeySalt(t *testing.T) {
+	salt1, err := NewMasterKeySalt()
+	if err != nil {
+		t.Fatal(err)
+	}
+	salt2, err := NewMasterKeySalt()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(salt1) != MasterKeySaltLen {
+		t.Errorf("expected salt length %d, got %d", MasterKeySaltLen, len(salt1))
+	}
+	if bytes.Equal(salt1, salt2) {
+		t.Error("expected random salts")
+	}
+}
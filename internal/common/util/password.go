@@ -1,11 +1,39 @@
 package util
 
 import (
+	"crypto/rand"
 	"crypto/sha256"
 	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
 	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Алгоритмы хэширования пароля
+const (
+	PasswordAlgoArgon2id = "argon2id"
+	PasswordAlgoBcrypt   = "bcrypt"
+	PasswordAlgoSha256   = "sha256"
+	PasswordAlgoSha512   = "sha512"
 )
 
+// Параметры argon2id для паролей
+const (
+	passwordArgon2Time    uint32 = 3
+	passwordArgon2Memory  uint32 = 64 * 1024
+	passwordArgon2Threads uint8  = 2
+	passwordArgon2KeyLen  uint32 = 32
+	passwordArgon2SaltLen        = 16
+)
+
+// ErrUnknownPasswordHash формат хэша не распознан
+var ErrUnknownPasswordHash = errors.New("unknown password hash format")
+
 // PasswordHashSha256 хэш пароля
 func PasswordHashSha256(password string) string {
 	hashAlg := sha256.New()
@@ -19,3 +47,156 @@ func PasswordHashSha512(password string) string {
 	hashAlg.Write([]byte(password))
 	return fmt.Sprintf("%x", hashAlg.Sum(nil))
 }
+
+// PasswordHashArgon2id хэш пароля в формате PHC ($argon2id$v=19$m=...,t=...,p=...$salt$hash)
+func PasswordHashArgon2id(password string) (string, error) {
+	salt := make([]byte, passwordArgon2SaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, passwordArgon2Time, passwordArgon2Memory, passwordArgon2Threads, passwordArgon2KeyLen)
+	return fmt.Sprintf(
+		"$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version,
+		passwordArgon2Memory,
+		passwordArgon2Time,
+		passwordArgon2Threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// PasswordHashBcrypt хэш пароля bcrypt
+func PasswordHashBcrypt(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// PasswordHashAlgo алгоритм, которым получен сохранённый хэш
+func PasswordHashAlgo(encoded string) string {
+	switch {
+	case strings.HasPrefix(encoded, "$argon2id$"):
+		return PasswordAlgoArgon2id
+	case strings.HasPrefix(encoded, "$2a$"), strings.HasPrefix(encoded, "$2b$"), strings.HasPrefix(encoded, "$2y$"):
+		return PasswordAlgoBcrypt
+	case len(encoded) == sha256.Size*2 && isHex(encoded):
+		return PasswordAlgoSha256
+	case len(encoded) == sha512.Size*2 && isHex(encoded):
+		return PasswordAlgoSha512
+	}
+	return ""
+}
+
+// PasswordVerify проверка пароля по сохранённому хэшу. Алгоритм определяется по самому хэшу
+func PasswordVerify(password string, encoded string) (bool, error) {
+	switch PasswordHashAlgo(encoded) {
+	case PasswordAlgoArgon2id:
+		return verifyArgon2id(password, encoded)
+	case PasswordAlgoBcrypt:
+		err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+		if err == nil {
+			return true, nil
+		}
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) || errors.Is(err, bcrypt.ErrPasswordTooLong) {
+			return false, nil
+		}
+		return false, err
+	case PasswordAlgoSha256:
+		return subtle.ConstantTimeCompare([]byte(PasswordHashSha256(password)), []byte(encoded)) == 1, nil
+	case PasswordAlgoSha512:
+		return subtle.ConstantTimeCompare([]byte(PasswordHashSha512(password)), []byte(encoded)) == 1, nil
+	}
+	return false, ErrUnknownPasswordHash
+}
+
+// IsPasswordAlgo поддерживаемый алгоритм хэширования пароля
+func IsPasswordAlgo(algo string) bool {
+	switch algo {
+	case PasswordAlgoArgon2id, PasswordAlgoBcrypt, PasswordAlgoSha256, PasswordAlgoSha512:
+		return true
+	}
+	return false
+}
+
+// PasswordNeedsRehash хэш получен другим алгоритмом или с параметрами ниже текущих.
+// Пересчёт выполняется только в argon2id или bcrypt, хэши без соли (sha256, sha512) не создаются
+func PasswordNeedsRehash(encoded string, algo string) bool {
+	if algo != PasswordAlgoArgon2id && algo != PasswordAlgoBcrypt {
+		return false
+	}
+	if PasswordHashAlgo(encoded) != algo {
+		return true
+	}
+	switch algo {
+	case PasswordAlgoArgon2id:
+		params, _, _, err := parseArgon2id(encoded)
+		if err != nil {
+			return true
+		}
+		return params.time < passwordArgon2Time || params.memory < passwordArgon2Memory || params.threads < passwordArgon2Threads
+	case PasswordAlgoBcrypt:
+		cost, err := bcrypt.Cost([]byte(encoded))
+		return err != nil || cost < bcrypt.DefaultCost
+	}
+	return false
+}
+
+type argon2Params struct {
+	time    uint32
+	memory  uint32
+	threads uint8
+}
+
+func verifyArgon2id(password string, encoded string) (bool, error) {
+	params, salt, key, err := parseArgon2id(encoded)
+	if err != nil {
+		return false, err
+	}
+	other := argon2.IDKey([]byte(password), salt, params.time, params.memory, params.threads, uint32(len(key)))
+	return subtle.ConstantTimeCompare(key, other) == 1, nil
+}
+
+func parseArgon2id(encoded string) (argon2Params, []byte, []byte, error) {
+	var params argon2Params
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != PasswordAlgoArgon2id {
+		return params, nil, nil, ErrUnknownPasswordHash
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return params, nil, nil, err
+	}
+	if version != argon2.Version {
+		return params, nil, nil, fmt.Errorf("unsupported argon2 version %d", version)
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.memory, &params.time, &params.threads); err != nil {
+		return params, nil, nil, err
+	}
+	if params.time == 0 || params.threads == 0 {
+		return params, nil, nil, ErrUnknownPasswordHash
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, err
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return params, nil, nil, err
+	}
+	if len(key) == 0 {
+		return params, nil, nil, ErrUnknownPasswordHash
+	}
+	return params, salt, key, nil
+}
+
+func isHex(value string) bool {
+	for _, c := range value {
+		if !((c >= '0' && c <= '9') || (c >= 'a' && c <= 'f')) {
+			return false
+		}
+	}
+	return true
+}
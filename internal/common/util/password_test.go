@@ -1,6 +1,7 @@
 package util
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -39,3 +40,95 @@ func TestPasswordHashSha512(t *testing.T) {
 		}
 	}
 }
+
+func TestPasswordHashArgon2id(t *testing.T) {
+	hash, err := PasswordHashArgon2id("password123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(hash, "$argon2id$v=19$m=65536,t=3,p=2$") {
+		t.Errorf("unexpected hash format %q", hash)
+	}
+	other, _ := PasswordHashArgon2id("password123")
+	if hash == other {
+		t.Error("salt must be random")
+	}
+	if PasswordHashAlgo(hash) != PasswordAlgoArgon2id {
+		t.Errorf("PasswordHashAlgo(%q) = %q", hash, PasswordHashAlgo(hash))
+	}
+}
+
+func TestPasswordVerify(t *testing.T) {
+	argonHash, _ := PasswordHashArgon2id("password123")
+	bcryptHash, _ := PasswordHashBcrypt("password123")
+	tests := []struct {
+		name     string
+		password string
+		hash     string
+		expected bool
+		wantErr  bool
+	}{
+		{"argon2id ok", "password123", argonHash, true, false},
+		{"argon2id wrong", "password124", argonHash, false, false},
+		{"bcrypt ok", "password123", bcryptHash, true, false},
+		{"bcrypt wrong", "password124", bcryptHash, false, false},
+		{"sha256 ok", "password123", PasswordHashSha256("password123"), true, false},
+		{"sha256 wrong", "password124", PasswordHashSha256("password123"), false, false},
+		{"sha512 ok", "password123", PasswordHashSha512("password123"), true, false},
+		{"sha512 wrong", "password124", PasswordHashSha512("password123"), false, false},
+		{"unknown", "password123", "plain", false, true},
+		{"broken argon2id", "password123", "$argon2id$v=19$m=x$salt$hash", false, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := PasswordVerify(test.password, test.hash)
+			if (err != nil) != test.wantErr {
+				t.Errorf("PasswordVerify error = %v, wantErr %v", err, test.wantErr)
+			}
+			if result != test.expected {
+				t.Errorf("PasswordVerify = %v, want %v", result, test.expected)
+			}
+		})
+	}
+}
+
+func TestPasswordNeedsRehash(t *testing.T) {
+	argonHash, _ := PasswordHashArgon2id("password123")
+	bcryptHash, _ := PasswordHashBcrypt("password123")
+
+	if PasswordNeedsRehash(argonHash, PasswordAlgoArgon2id) {
+		t.Error("current argon2id hash must not be rehashed")
+	}
+	if PasswordNeedsRehash(bcryptHash, PasswordAlgoBcrypt) {
+		t.Error("current bcrypt hash must not be rehashed")
+	}
+	if !PasswordNeedsRehash(PasswordHashSha512("password123"), PasswordAlgoArgon2id) {
+		t.Error("sha512 hash must be upgraded to argon2id")
+	}
+	if !PasswordNeedsRehash("$argon2id$v=19$m=1024,t=1,p=1$c2FsdA$aGFzaA", PasswordAlgoArgon2id) {
+		t.Error("argon2id hash with weak params must be rehashed")
+	}
+	if PasswordNeedsRehash("$argon2id$v=19$m=131072,t=4,p=4$c2FsdA$aGFzaA", PasswordAlgoArgon2id) {
+		t.Error("argon2id hash with stronger params must not be rehashed")
+	}
+	if !PasswordNeedsRehash(argonHash, PasswordAlgoBcrypt) {
+		t.Error("argon2id hash must be rehashed to the configured bcrypt")
+	}
+	for _, algo := range []string{PasswordAlgoSha256, PasswordAlgoSha512, "", "unknown"} {
+		if PasswordNeedsRehash(argonHash, algo) || PasswordNeedsRehash(bcryptHash, algo) {
+			t.Errorf("hash must not be rehashed to %q", algo)
+		}
+	}
+}
+
+func TestIsPasswordAlgo(t *testing.T) {
+	for _, algo := range []string{PasswordAlgoArgon2id, PasswordAlgoBcrypt, PasswordAlgoSha256, PasswordAlgoSha512} {
+		if !IsPasswordAlgo(algo) {
+			t.Errorf("%s must be supported", algo)
+		}
+	}
+	if IsPasswordAlgo("") || IsPasswordAlgo("md5") {
+		t.Error("unknown algorithm must not be supported")
+	}
+}
@@ -0,0 +1,138 @@
+package util
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"errors"
+	"strings"
+)
+
+// Печатный вид комплекта восстановления: ключ восстановления и части ключа хранилища (схема Шамира).
+// Значения кодируются в base32 группами по 4 символа, в конце контрольная сумма для проверки при вводе
+
+const (
+	// RecoveryKeyPrefix признак ключа восстановления
+	RecoveryKeyPrefix = "GKRK"
+	// RecoverySharePrefix признак части ключа хранилища
+	RecoverySharePrefix = "GKRS"
+	// RecoveryKeyLen длина ключа восстановления (AES-256)
+	RecoveryKeyLen = 32
+
+	recoveryChecksumLen = 2
+	recoveryGroupLen    = 4
+)
+
+var (
+	// ErrRecoveryKey ключ восстановления введён с ошибкой
+	ErrRecoveryKey = errors.New("invalid recovery key")
+	// ErrRecoveryShare часть ключа введена с ошибкой
+	ErrRecoveryShare = errors.New("invalid recovery share")
+	// ErrRecoverySharesCount частей меньше порога
+	ErrRecoverySharesCount = errors.New("not enough recovery shares")
+)
+
+var recoveryEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// NewRecoveryKey случайный ключ восстановления и его печатный вид
+func NewRecoveryKey() ([]byte, string, error) {
+	key := make([]byte, RecoveryKeyLen)
+	if _, err := rand.Read(key); err != nil {
+		return nil, "", err
+	}
+	return key, formatRecoveryValue(RecoveryKeyPrefix, key), nil
+}
+
+// ParseRecoveryKey ключ восстановления из печатного вида
+func ParseRecoveryKey(value string) ([]byte, error) {
+	key, ok := parseRecoveryValue(RecoveryKeyPrefix, value)
+	if !ok || len(key) != RecoveryKeyLen {
+		return nil, ErrRecoveryKey
+	}
+	return key, nil
+}
+
+// IsRecoveryKey значение похоже на ключ восстановления
+func IsRecoveryKey(value string) bool {
+	return strings.HasPrefix(normalizeRecoveryValue(value), RecoveryKeyPrefix)
+}
+
+// IsRecoveryShare значение похоже на часть ключа хранилища
+func IsRecoveryShare(value string) bool {
+	return strings.HasPrefix(normalizeRecoveryValue(value), RecoverySharePrefix)
+}
+
+// NewRecoveryShares делит секрет на shares частей (порог threshold) в печатном виде.
+// Порог хранится в каждой части, чтобы при вводе сообщить о нехватке частей
+func NewRecoveryShares(secret []byte, shares int, threshold int) ([]string, error) {
+	parts, err := SplitSecret(secret, shares, threshold)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]string, 0, len(parts))
+	for _, part := range parts {
+		result = append(result, formatRecoveryValue(RecoverySharePrefix, append([]byte{byte(threshold)}, part...)))
+	}
+	return result, nil
+}
+
+// CombineRecoveryShares восстановление секрета из частей в печатном виде
+func CombineRecoveryShares(values []string) ([]byte, error) {
+	parts := make([][]byte, 0, len(values))
+	threshold := 0
+	for _, value := range values {
+		part, ok := parseRecoveryValue(RecoverySharePrefix, value)
+		if !ok || len(part) < 3 {
+			return nil, ErrRecoveryShare
+		}
+		if threshold != 0 && threshold != int(part[0]) {
+			return nil, ErrRecoveryShare
+		}
+		threshold = int(part[0])
+		parts = append(parts, part[1:])
+	}
+	if len(parts) < threshold || len(parts) < 2 {
+		return nil, ErrRecoverySharesCount
+	}
+	return CombineShares(parts)
+}
+
+func formatRecoveryValue(prefix string, value []byte) string {
+	checksum := sha256.Sum256(append([]byte(prefix), value...))
+	encoded := recoveryEncoding.EncodeToString(append(bytes.Clone(value), checksum[:recoveryChecksumLen]...))
+	groups := []string{prefix}
+	for len(encoded) > recoveryGroupLen {
+		groups = append(groups, encoded[:recoveryGroupLen])
+		encoded = encoded[recoveryGroupLen:]
+	}
+	groups = append(groups, encoded)
+	return strings.Join(groups, "-")
+}
+
+func parseRecoveryValue(prefix string, value string) ([]byte, bool) {
+	normalized := normalizeRecoveryValue(value)
+	if !strings.HasPrefix(normalized, prefix) {
+		return nil, false
+	}
+	raw, err := recoveryEncoding.DecodeString(strings.TrimPrefix(normalized, prefix))
+	if err != nil || len(raw) <= recoveryChecksumLen {
+		return nil, false
+	}
+	decoded, sum := raw[:len(raw)-recoveryChecksumLen], raw[len(raw)-recoveryChecksumLen:]
+	checksum := sha256.Sum256(append([]byte(prefix), decoded...))
+	if !bytes.Equal(sum, checksum[:recoveryChecksumLen]) {
+		return nil, false
+	}
+	return decoded, true
+}
+
+// normalizeRecoveryValue верхний регистр без разделителей групп и пробелов
+func normalizeRecoveryValue(value string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' || r == '\t' || r == '\n' || r == '\r' {
+			return -1
+		}
+		return r
+	}, strings.ToUpper(value))
+}
@@ -0,0 +1,73 @@
+package util
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecoveryKey(t *testing.T) {
+	key, printable, err := NewRecoveryKey()
+	require.NoError(t, err)
+	assert.Len(t, key, RecoveryKeyLen)
+	assert.True(t, strings.HasPrefix(printable, RecoveryKeyPrefix+"-"))
+	assert.True(t, IsRecoveryKey(printable))
+	assert.False(t, IsRecoveryShare(printable))
+
+	parsed, err := ParseRecoveryKey(printable)
+	require.NoError(t, err)
+	assert.Equal(t, key, parsed)
+
+	// ввод в нижнем регистре, с пробелами вместо дефисов
+	parsed, err = ParseRecoveryKey(" " + strings.ToLower(strings.ReplaceAll(printable, "-", " ")) + "\n")
+	require.NoError(t, err)
+	assert.Equal(t, key, parsed)
+}
+
+func TestParseRecoveryKey_Typo(t *testing.T) {
+	_, printable, err := NewRecoveryKey()
+	require.NoError(t, err)
+
+	typo := []byte(printable)
+	position := len(RecoveryKeyPrefix) + 2
+	if typo[position] == 'A' {
+		typo[position] = 'B'
+	} else {
+		typo[position] = 'A'
+	}
+	_, err = ParseRecoveryKey(string(typo))
+	assert.ErrorIs(t, err, ErrRecoveryKey)
+
+	_, err = ParseRecoveryKey("GKRS-AAAA")
+	assert.ErrorIs(t, err, ErrRecoveryKey)
+	_, err = ParseRecoveryKey("")
+	assert.ErrorIs(t, err, ErrRecoveryKey)
+}
+
+func TestRecoveryShares(t *testing.T) {
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	shares, err := NewRecoveryShares(secret, 5, 3)
+	require.NoError(t, err)
+	require.Len(t, shares, 5)
+	for _, share := range shares {
+		assert.True(t, IsRecoveryShare(share))
+	}
+
+	combined, err := CombineRecoveryShares([]string{shares[4], shares[0], strings.ToLower(shares[2])})
+	require.NoError(t, err)
+	assert.Equal(t, secret, combined)
+
+	_, err = CombineRecoveryShares(shares[:2])
+	assert.ErrorIs(t, err, ErrRecoverySharesCount)
+
+	_, err = CombineRecoveryShares([]string{shares[0], shares[1], shares[2][:len(shares[2])-1]})
+	assert.ErrorIs(t, err, ErrRecoveryShare)
+
+	// части разных комплектов
+	other, err := NewRecoveryShares(secret, 3, 2)
+	require.NoError(t, err)
+	_, err = CombineRecoveryShares([]string{shares[0], shares[1], other[2]})
+	assert.ErrorIs(t, err, ErrRecoveryShare)
+}
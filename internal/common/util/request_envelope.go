@@ -0,0 +1,96 @@
+package util
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"io"
+	"time"
+)
+
+// Конверт зашифрованного запроса: magic | версия | время (unix ms) | nonce запроса | nonce GCM | шифротекст.
+// Заголовок конверта, метод и путь запроса входят в дополнительные данные GCM,
+// поэтому конверт нельзя переставить на другой адрес или изменить время и nonce
+const (
+	requestEnvelopeMagic   = "GKRQ"
+	requestEnvelopeVersion = 1
+	// RequestNonceSize размер nonce запроса
+	RequestNonceSize = 16
+
+	requestEnvelopeHeaderSize = len(requestEnvelopeMagic) + 1 + 8 + RequestNonceSize
+)
+
+// ErrRequestEnvelope конверт повреждён, подделан или относится к другому запросу
+var ErrRequestEnvelope = errors.New("invalid request envelope")
+
+// RequestEnvelope реквизиты конверта для проверки повтора запроса
+type RequestEnvelope struct {
+	Timestamp time.Time
+	Nonce     []byte
+}
+
+// SealRequest зашифровать тело запроса в конверт
+func SealRequest(rawDate []byte, key []byte, method string, path string, timestamp time.Time) ([]byte, error) {
+	gcm, err := newRequestGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	header := make([]byte, requestEnvelopeHeaderSize, requestEnvelopeHeaderSize+gcm.NonceSize()+len(rawDate)+gcm.Overhead())
+	copy(header, requestEnvelopeMagic)
+	header[len(requestEnvelopeMagic)] = requestEnvelopeVersion
+	binary.BigEndian.PutUint64(header[len(requestEnvelopeMagic)+1:], uint64(timestamp.UnixMilli()))
+	if _, err = io.ReadFull(rand.Reader, header[len(requestEnvelopeMagic)+1+8:]); err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	sealed := append(header, nonce...)
+	return gcm.Seal(sealed, nonce, rawDate, requestAdditionalData(header, method, path)), nil
+}
+
+// OpenRequest расшифровать конверт запроса
+func OpenRequest(encryptDate []byte, key []byte, method string, path string) (*RequestEnvelope, []byte, error) {
+	gcm, err := newRequestGCM(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(encryptDate) < requestEnvelopeHeaderSize+gcm.NonceSize()+gcm.Overhead() {
+		return nil, nil, ErrRequestEnvelope
+	}
+	header := encryptDate[:requestEnvelopeHeaderSize]
+	if !bytes.HasPrefix(header, []byte(requestEnvelopeMagic)) || header[len(requestEnvelopeMagic)] != requestEnvelopeVersion {
+		return nil, nil, ErrRequestEnvelope
+	}
+	nonce := encryptDate[requestEnvelopeHeaderSize : requestEnvelopeHeaderSize+gcm.NonceSize()]
+	ciphertext := encryptDate[requestEnvelopeHeaderSize+gcm.NonceSize():]
+	rawDate, err := gcm.Open(nil, nonce, ciphertext, requestAdditionalData(header, method, path))
+	if err != nil {
+		return nil, nil, ErrRequestEnvelope
+	}
+	timestamp := int64(binary.BigEndian.Uint64(header[len(requestEnvelopeMagic)+1:]))
+	return &RequestEnvelope{
+		Timestamp: time.UnixMilli(timestamp),
+		Nonce:     bytes.Clone(header[len(requestEnvelopeMagic)+1+8:]),
+	}, rawDate, nil
+}
+
+func newRequestGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func requestAdditionalData(header []byte, method string, path string) []byte {
+	additionalData := make([]byte, 0, len(header)+len(method)+len(path)+1)
+	additionalData = append(additionalData, header...)
+	additionalData = append(additionalData, method...)
+	additionalData = append(additionalData, ' ')
+	return append(additionalData, path...)
+}
@@ -0,0 +1,78 @@
+package util
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSealRequest(t *testing.T) {
+	key := make([]byte, 32)
+	timestamp := time.UnixMilli(time.Now().UnixMilli())
+	plaintext := []byte(`{"number":"4111"}`)
+
+	sealed, err := SealRequest(plaintext, key, "POST", "/api/v1/save_card_data", timestamp)
+	if err != nil {
+		t.Fatalf("SealRequest failed: %v", err)
+	}
+	envelope, opened, err := OpenRequest(sealed, key, "POST", "/api/v1/save_card_data")
+	if err != nil {
+		t.Fatalf("OpenRequest failed: %v", err)
+	}
+	if !bytes.Equal(plaintext, opened) {
+		t.Errorf("Decrypted body does not match: %s != %s", opened, plaintext)
+	}
+	if !envelope.Timestamp.Equal(timestamp) {
+		t.Errorf("Timestamp mismatch: %v != %v", envelope.Timestamp, timestamp)
+	}
+	if len(envelope.Nonce) != RequestNonceSize {
+		t.Errorf("Nonce has wrong size: %d", len(envelope.Nonce))
+	}
+
+	other, _ := SealRequest(plaintext, key, "POST", "/api/v1/save_card_data", timestamp)
+	otherEnvelope, _, _ := OpenRequest(other, key, "POST", "/api/v1/save_card_data")
+	if bytes.Equal(envelope.Nonce, otherEnvelope.Nonce) {
+		t.Errorf("Nonce is reused")
+	}
+}
+
+func TestOpenRequest_Invalid(t *testing.T) {
+	key := make([]byte, 32)
+	sealed, _ := SealRequest([]byte("body"), key, "POST", "/api/v1/save_card_data", time.Now())
+
+	tamperedTimestamp := bytes.Clone(sealed)
+	tamperedTimestamp[len(requestEnvelopeMagic)+1] ^= 0x01
+
+	tests := []struct {
+		name   string
+		data   []byte
+		key    []byte
+		method string
+		path   string
+	}{
+		{"another path", sealed, key, "POST", "/api/v1/save_text_data"},
+		{"another method", sealed, key, "PUT", "/api/v1/save_card_data"},
+		{"another key", sealed, bytes.Repeat([]byte{1}, 32), "POST", "/api/v1/save_card_data"},
+		{"tampered timestamp", tamperedTimestamp, key, "POST", "/api/v1/save_card_data"},
+		{"plain AES", mustEncryptAES(t, key), key, "POST", "/api/v1/save_card_data"},
+		{"short", sealed[:20], key, "POST", "/api/v1/save_card_data"},
+		{"empty", nil, key, "POST", "/api/v1/save_card_data"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := OpenRequest(tt.data, tt.key, tt.method, tt.path)
+			if !errors.Is(err, ErrRequestEnvelope) {
+				t.Errorf("Expected ErrRequestEnvelope, got %v", err)
+			}
+		})
+	}
+}
+
+func mustEncryptAES(t *testing.T, key []byte) []byte {
+	encrypted, err := DataEncryptAES(bytes.Repeat([]byte("body"), 20), key)
+	if err != nil {
+		t.Fatalf("DataEncryptAES failed: %v", err)
+	}
+	return encrypted
+}
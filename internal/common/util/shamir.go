@@ -0,0 +1,114 @@
+package util
+
+import (
+	"crypto/rand"
+	"errors"
+)
+
+// Разделение секрета по схеме Шамира над GF(256): каждый байт секрета - свободный член случайного
+// многочлена степени threshold-1, часть - значения многочленов в точке x (1..255).
+// Любые threshold частей восстанавливают секрет, меньшее число частей не даёт о нём сведений.
+
+var (
+	// ErrShamirParams недопустимое число частей или порог
+	ErrShamirParams = errors.New("shares must be between threshold and 255, threshold at least 2")
+	// ErrShamirShares части не подходят друг к другу
+	ErrShamirShares = errors.New("invalid shamir shares")
+)
+
+var (
+	gfExp [510]byte
+	gfLog [256]byte
+)
+
+func init() {
+	// таблицы степеней генератора 3 по модулю многочлена AES x^8 + x^4 + x^3 + x + 1
+	x := byte(1)
+	for i := 0; i < 255; i++ {
+		gfExp[i] = x
+		gfExp[i+255] = x
+		gfLog[x] = byte(i)
+		high := x & 0x80
+		x2 := x << 1
+		if high != 0 {
+			x2 ^= 0x1b
+		}
+		x ^= x2
+	}
+}
+
+func gfMul(a byte, b byte) byte {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return gfExp[int(gfLog[a])+int(gfLog[b])]
+}
+
+func gfDiv(a byte, b byte) byte {
+	if a == 0 {
+		return 0
+	}
+	return gfExp[int(gfLog[a])+255-int(gfLog[b])]
+}
+
+// SplitSecret делит секрет на shares частей, любые threshold из которых восстанавливают его.
+// Первый байт части - x, далее значения многочленов
+func SplitSecret(secret []byte, shares int, threshold int) ([][]byte, error) {
+	if threshold < 2 || shares < threshold || shares > 255 || len(secret) == 0 {
+		return nil, ErrShamirParams
+	}
+	result := make([][]byte, shares)
+	for i := range result {
+		result[i] = make([]byte, len(secret)+1)
+		result[i][0] = byte(i + 1)
+	}
+	coefficients := make([]byte, threshold)
+	for position, value := range secret {
+		if _, err := rand.Read(coefficients[1:]); err != nil {
+			return nil, err
+		}
+		coefficients[0] = value
+		for _, share := range result {
+			// схема Горнера
+			var y byte
+			for c := threshold - 1; c >= 0; c-- {
+				y = gfMul(y, share[0]) ^ coefficients[c]
+			}
+			share[position+1] = y
+		}
+	}
+	clear(coefficients)
+	return result, nil
+}
+
+// CombineShares восстановление секрета интерполяцией Лагранжа в точке 0.
+// Частей должно быть не меньше порога, иначе результат не совпадёт с секретом
+func CombineShares(shares [][]byte) ([]byte, error) {
+	if len(shares) < 2 {
+		return nil, ErrShamirShares
+	}
+	size := len(shares[0])
+	seen := make(map[byte]bool, len(shares))
+	for _, share := range shares {
+		if len(share) != size || size < 2 || share[0] == 0 || seen[share[0]] {
+			return nil, ErrShamirShares
+		}
+		seen[share[0]] = true
+	}
+
+	secret := make([]byte, size-1)
+	for i, share := range shares {
+		// базисный многочлен Лагранжа в точке 0
+		basis := byte(1)
+		for j, other := range shares {
+			if i == j {
+				continue
+			}
+			basis = gfMul(basis, gfDiv(other[0], share[0]^other[0]))
+		}
+		for position := range secret {
+			secret[position] ^= gfMul(share[position+1], basis)
+		}
+	}
+	return secret, nil
+}
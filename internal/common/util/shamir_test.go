@@ -0,0 +1,79 @@
+package util
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitSecret_CombineShares(t *testing.T) {
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	shares, err := SplitSecret(secret, 5, 3)
+	require.NoError(t, err)
+	require.Len(t, shares, 5)
+
+	// любые три части из пяти
+	for a := 0; a < 5; a++ {
+		for b := a + 1; b < 5; b++ {
+			for c := b + 1; c < 5; c++ {
+				combined, err := CombineShares([][]byte{shares[a], shares[b], shares[c]})
+				require.NoError(t, err)
+				assert.Equal(t, secret, combined)
+			}
+		}
+	}
+
+	all, err := CombineShares(shares)
+	require.NoError(t, err)
+	assert.Equal(t, secret, all)
+
+	// двух частей недостаточно
+	partial, err := CombineShares(shares[:2])
+	require.NoError(t, err)
+	assert.False(t, bytes.Equal(secret, partial))
+}
+
+func TestSplitSecret_Params(t *testing.T) {
+	tests := []struct {
+		name      string
+		shares    int
+		threshold int
+	}{
+		{"threshold below 2", 3, 1},
+		{"shares below threshold", 2, 3},
+		{"too many shares", 256, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := SplitSecret([]byte("secret"), tt.shares, tt.threshold)
+			assert.ErrorIs(t, err, ErrShamirParams)
+		})
+	}
+	_, err := SplitSecret(nil, 3, 2)
+	assert.ErrorIs(t, err, ErrShamirParams)
+}
+
+func TestCombineShares_Invalid(t *testing.T) {
+	shares, err := SplitSecret([]byte("secret"), 3, 2)
+	require.NoError(t, err)
+
+	_, err = CombineShares(shares[:1])
+	assert.ErrorIs(t, err, ErrShamirShares)
+	_, err = CombineShares([][]byte{shares[0], shares[0]})
+	assert.ErrorIs(t, err, ErrShamirShares)
+	_, err = CombineShares([][]byte{shares[0], shares[1][:3]})
+	assert.ErrorIs(t, err, ErrShamirShares)
+}
+
+func TestGF256(t *testing.T) {
+	for a := 1; a < 256; a++ {
+		for b := 1; b < 256; b++ {
+			assert.Equal(t, byte(a), gfDiv(gfMul(byte(a), byte(b)), byte(b)))
+		}
+	}
+	assert.Equal(t, byte(0), gfMul(0, 7))
+	// 0x53 * 0xca = 1 в поле AES
+	assert.Equal(t, byte(1), gfMul(0x53, 0xca))
+}
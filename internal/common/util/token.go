@@ -0,0 +1,30 @@
+package util
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+)
+
+// refreshTokenLen длина refresh токена в байтах
+const refreshTokenLen = 32
+
+// NewRefreshToken случайный непрозрачный токен
+func NewRefreshToken() (string, error) {
+	return NewRandomToken()
+}
+
+// NewRandomToken случайная строка для непрозрачных токенов (base64url)
+func NewRandomToken() (string, error) {
+	value := make([]byte, refreshTokenLen)
+	if _, err := rand.Read(value); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(value), nil
+}
+
+// TokenHash хэш токена для хранения в БД
+func TokenHash(token string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(token)))
+}
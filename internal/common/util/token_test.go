@@ -0,0 +1,32 @@
+package util
+
+import (
+	"testing"
+)
+
+func TestNewRefreshToken(t *testing.T) {
+	first, err := NewRefreshToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := NewRefreshToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(first) != 43 {
+		t.Errorf("unexpected token length %d", len(first))
+	}
+	if first == second {
+		t.Error("tokens must be random")
+	}
+}
+
+func TestTokenHash(t *testing.T) {
+	hash := TokenHash("token")
+	if hash != "3c469e9d6c5875d37a43f353d4f88e61fcf812c66eee3457465a40b0da4153e0" {
+		t.Errorf("TokenHash = %q", hash)
+	}
+	if len(hash) != 64 {
+		t.Errorf("unexpected hash length %d", len(hash))
+	}
+}
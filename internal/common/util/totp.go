@@ -0,0 +1,126 @@
+package util
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Параметры TOTP (RFC 6238) совместимые с Google Authenticator и аналогами
+const (
+	TOTPDigits = 6
+	TOTPPeriod = 30 * time.Second
+	// TOTPSkew допустимое расхождение часов в шагах в обе стороны
+	TOTPSkew = 1
+
+	totpSecretLen      = 20
+	backupCodeLen      = 10
+	backupCodeAlphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+)
+
+// ErrInvalidTOTPSecret секрет не в base32
+var ErrInvalidTOTPSecret = errors.New("invalid totp secret")
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// NewTOTPSecret случайный секрет в base32
+func NewTOTPSecret() (string, error) {
+	value := make([]byte, totpSecretLen)
+	if _, err := rand.Read(value); err != nil {
+		return "", err
+	}
+	return totpEncoding.EncodeToString(value), nil
+}
+
+// TOTPStep номер временного шага
+func TOTPStep(t time.Time) int64 {
+	return t.Unix() / int64(TOTPPeriod.Seconds())
+}
+
+// TOTPCode код для временного шага
+func TOTPCode(secret string, step int64) (string, error) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(strings.TrimRight(secret, "=")))
+	if err != nil {
+		return "", ErrInvalidTOTPSecret
+	}
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], uint64(step))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(counter[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	mod := uint32(1)
+	for i := 0; i < TOTPDigits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", TOTPDigits, value%mod), nil
+}
+
+// TOTPVerify проверка кода с учётом расхождения часов. Возвращает шаг, которому соответствует код
+func TOTPVerify(secret string, code string, t time.Time) (int64, bool) {
+	code = strings.TrimSpace(code)
+	if len(code) != TOTPDigits {
+		return 0, false
+	}
+	current := TOTPStep(t)
+	for step := current - TOTPSkew; step <= current+TOTPSkew; step++ {
+		expected, err := TOTPCode(secret, step)
+		if err != nil {
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// TOTPProvisioningURI otpauth:// ссылка для добавления секрета в приложение-аутентификатор
+func TOTPProvisioningURI(issuer string, account string, secret string) string {
+	query := url.Values{}
+	query.Set("secret", secret)
+	query.Set("issuer", issuer)
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", fmt.Sprintf("%d", TOTPDigits))
+	query.Set("period", fmt.Sprintf("%d", int(TOTPPeriod.Seconds())))
+	label := url.PathEscape(issuer + ":" + account)
+	return "otpauth://totp/" + label + "?" + query.Encode()
+}
+
+// NewBackupCodes одноразовые резервные коды. Символы выбираются равновероятно (rand.Int без смещения по модулю)
+func NewBackupCodes(count int) ([]string, error) {
+	codes := make([]string, 0, count)
+	alphabetLen := big.NewInt(int64(len(backupCodeAlphabet)))
+	for i := 0; i < count; i++ {
+		code := make([]byte, backupCodeLen)
+		for j := range code {
+			n, err := rand.Int(rand.Reader, alphabetLen)
+			if err != nil {
+				return nil, err
+			}
+			code[j] = backupCodeAlphabet[n.Int64()]
+		}
+		codes = append(codes, string(code[:5])+"-"+string(code[5:]))
+	}
+	return codes, nil
+}
+
+// NormalizeBackupCode приведение введённого резервного кода к виду, в котором он выдавался
+func NormalizeBackupCode(code string) string {
+	code = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+	if len(code) != backupCodeLen {
+		return code
+	}
+	return code[:5] + "-" + code[5:]
+}
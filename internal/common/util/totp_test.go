@@ -0,0 +1,86 @@
+package util
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfc6238Secret секрет "12345678901234567890" из RFC 6238 в base32
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPCode_RFC6238(t *testing.T) {
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+	for _, test := range tests {
+		code, err := TOTPCode(rfc6238Secret, TOTPStep(time.Unix(test.unix, 0)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if code != test.code {
+			t.Errorf("TOTPCode(%d) = %s, want %s", test.unix, code, test.code)
+		}
+	}
+
+	if _, err := TOTPCode("not base32!", 1); err == nil {
+		t.Error("expected error for invalid secret")
+	}
+}
+
+func TestTOTPVerify(t *testing.T) {
+	secret, err := NewTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	step := TOTPStep(now)
+
+	previous, _ := TOTPCode(secret, step-1)
+	if matched, ok := TOTPVerify(secret, previous, now); !ok || matched != step-1 {
+		t.Errorf("code of the previous step must be accepted, got %d %v", matched, ok)
+	}
+	stale, _ := TOTPCode(secret, step-3)
+	if _, ok := TOTPVerify(secret, stale, now); ok {
+		t.Error("stale code must be rejected")
+	}
+	if _, ok := TOTPVerify(secret, "12345", now); ok {
+		t.Error("short code must be rejected")
+	}
+}
+
+func TestTOTPProvisioningURI(t *testing.T) {
+	uri := TOTPProvisioningURI("GophKeeper", "user login", "SECRET")
+	if !strings.HasPrefix(uri, "otpauth://totp/GophKeeper:user%20login?") {
+		t.Errorf("unexpected uri %s", uri)
+	}
+	if !strings.Contains(uri, "secret=SECRET") || !strings.Contains(uri, "issuer=GophKeeper") {
+		t.Errorf("unexpected uri %s", uri)
+	}
+}
+
+func TestBackupCodes(t *testing.T) {
+	codes, err := NewBackupCodes(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := make(map[string]bool)
+	for _, code := range codes {
+		if len(code) != 11 || code[5] != '-' {
+			t.Errorf("unexpected code format %s", code)
+		}
+		if seen[code] {
+			t.Errorf("duplicate code %s", code)
+		}
+		seen[code] = true
+		if NormalizeBackupCode(" "+strings.ToUpper(strings.ReplaceAll(code, "-", ""))+" ") != code {
+			t.Errorf("NormalizeBackupCode must restore %s", code)
+		}
+	}
+}
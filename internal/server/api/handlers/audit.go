@@ -0,0 +1,237 @@
+package handlers
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/northmule/gophkeeper/internal/common/model_data"
+	"github.com/northmule/gophkeeper/internal/common/models"
+	"github.com/northmule/gophkeeper/internal/server/api/rctx"
+	"github.com/northmule/gophkeeper/internal/server/logger"
+	"github.com/northmule/gophkeeper/internal/server/repository"
+)
+
+const (
+	auditDefaultLimit = 50
+	auditMaxLimit     = 500
+)
+
+// Auditor журнал действий пользователей
+type Auditor interface {
+	Record(ctx context.Context, event models.AuditEvent) error
+	Verify(ctx context.Context, userUUID string) (*model_data.AuditVerifyResponse, error)
+}
+
+// AuditHandler запись и просмотр журнала действий пользователя
+type AuditHandler struct {
+	accessService UserFinderByJWT
+	auditor       Auditor
+	manager       repository.Repository
+	log           *logger.Logger
+}
+
+// NewAuditHandler конструктор
+func NewAuditHandler(accessService UserFinderByJWT, auditor Auditor, manager repository.Repository, log *logger.Logger) *AuditHandler {
+	return &AuditHandler{
+		accessService: accessService,
+		auditor:       auditor,
+		manager:       manager,
+		log:           log,
+	}
+}
+
+type auditListResponse struct {
+	model_data.AuditListResponse
+}
+
+// Render рисует структуру в json
+func (r auditListResponse) Render(res http.ResponseWriter, req *http.Request) error {
+	return nil
+}
+
+type auditVerifyResponse struct {
+	model_data.AuditVerifyResponse
+}
+
+// Render рисует структуру в json
+func (r auditVerifyResponse) Render(res http.ResponseWriter, req *http.Request) error {
+	return nil
+}
+
+// HandleAudit записывает событие action в журнал, если обработчик ответил успешно.
+// Пользователь берётся из токена, при входе его указывает обработчик (auditUser).
+// uuid данных - из пути запроса или от обработчика (auditData)
+func (h *AuditHandler) HandleAudit(action string, details string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+			event := &models.AuditEvent{Action: action, Details: details, IP: remoteIP(req)}
+			ww := middleware.NewWrapResponseWriter(res, req.ProtoMajor)
+			next.ServeHTTP(ww, req.WithContext(context.WithValue(req.Context(), rctx.AuditCtxKey, event)))
+
+			// обработчик без тела ответа возвращает 200
+			if ww.Status() >= http.StatusMultipleChoices {
+				return
+			}
+			if event.UserUUID == "" {
+				userUUID, err := h.accessService.GetUserUUIDByJWTToken(req.Context())
+				if err != nil || userUUID == "" {
+					// вход не завершён (ждёт второй фактор)
+					return
+				}
+				event.UserUUID = userUUID
+			}
+			if event.DataUUID == "" {
+				event.DataUUID = chi.URLParam(req, "uuid")
+			}
+			if event.DataUUID == "" {
+				event.DataUUID = chi.URLParam(req, "file_uuid")
+			}
+			// ответ уже отправлен, отключение клиента не должно прерывать запись
+			err := h.auditor.Record(context.WithoutCancel(req.Context()), *event)
+			if err != nil {
+				h.log.Error(err)
+			}
+		})
+	}
+}
+
+// HandleList журнал пользователя, новые записи первыми. Фильтры: action, data_uuid, from и to (unix время),
+// страница: offset, limit
+func (h *AuditHandler) HandleList(res http.ResponseWriter, req *http.Request) {
+	userUUID, err := h.accessService.GetUserUUIDByJWTToken(req.Context())
+	if err != nil {
+		h.log.Error(err)
+		_ = render.Render(res, req, ErrBadRequest)
+		return
+	}
+	query := req.URL.Query()
+	filter := repository.AuditEventFilter{
+		UserUUID: userUUID,
+		Action:   query.Get("action"),
+		DataUUID: query.Get("data_uuid"),
+	}
+	filter.From, err = queryUnixTime(query.Get("from"))
+	if err != nil {
+		h.log.Info(err)
+		_ = render.Render(res, req, ErrBadRequest)
+		return
+	}
+	filter.To, err = queryUnixTime(query.Get("to"))
+	if err != nil {
+		h.log.Info(err)
+		_ = render.Render(res, req, ErrBadRequest)
+		return
+	}
+	offset, err := queryInt(query.Get("offset"), 0)
+	if err != nil || offset < 0 {
+		h.log.Info("invalid offset ", query.Get("offset"))
+		_ = render.Render(res, req, ErrBadRequest)
+		return
+	}
+	limit, err := queryInt(query.Get("limit"), auditDefaultLimit)
+	if err != nil || limit < 1 {
+		h.log.Info("invalid limit ", query.Get("limit"))
+		_ = render.Render(res, req, ErrBadRequest)
+		return
+	}
+	limit = min(limit, auditMaxLimit)
+
+	events, err := h.manager.AuditEvent().FindAll(req.Context(), filter, offset, limit)
+	if err != nil {
+		h.log.Error(err)
+		_ = render.Render(res, req, ErrInternalServerError)
+		return
+	}
+	response := auditListResponse{}
+	response.Events = make([]model_data.AuditEventResponse, 0, len(events))
+	response.Offset = offset
+	response.Limit = limit
+	for _, event := range events {
+		response.Events = append(response.Events, model_data.AuditEventResponse{
+			ID:        event.ID,
+			Action:    event.Action,
+			DataUUID:  event.DataUUID,
+			IP:        event.IP,
+			Details:   event.Details,
+			Hash:      event.Hash,
+			CreatedAt: event.CreatedAt.Unix(),
+		})
+	}
+	err = render.Render(res, req, response)
+	if err != nil {
+		h.log.Error(err)
+		_ = render.Render(res, req, ErrInternalServerError)
+	}
+}
+
+// HandleVerify проверка цепочки журнала пользователя
+func (h *AuditHandler) HandleVerify(res http.ResponseWriter, req *http.Request) {
+	userUUID, err := h.accessService.GetUserUUIDByJWTToken(req.Context())
+	if err != nil {
+		h.log.Error(err)
+		_ = render.Render(res, req, ErrBadRequest)
+		return
+	}
+	result, err := h.auditor.Verify(req.Context(), userUUID)
+	if err != nil {
+		h.log.Error(err)
+		_ = render.Render(res, req, ErrInternalServerError)
+		return
+	}
+	if !result.Valid {
+		h.log.Infof("The audit chain of user %s is broken at event %d", userUUID, result.BrokenID)
+	}
+	err = render.Render(res, req, auditVerifyResponse{AuditVerifyResponse: *result})
+	if err != nil {
+		h.log.Error(err)
+		_ = render.Render(res, req, ErrInternalServerError)
+	}
+}
+
+// auditUser пользователь события журнала (при входе пользователь ещё не в токене)
+func auditUser(req *http.Request, userUUID string) {
+	if event, ok := req.Context().Value(rctx.AuditCtxKey).(*models.AuditEvent); ok {
+		event.UserUUID = userUUID
+	}
+}
+
+// auditData uuid данных события журнала (новые данные получают uuid в обработчике)
+func auditData(req *http.Request, dataUUID string) {
+	if event, ok := req.Context().Value(rctx.AuditCtxKey).(*models.AuditEvent); ok {
+		event.DataUUID = dataUUID
+	}
+}
+
+// remoteIP адрес подключения клиента
+func remoteIP(req *http.Request) string {
+	ip, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return req.RemoteAddr
+	}
+	return ip
+}
+
+func queryInt(value string, defaultValue int) (int, error) {
+	if value == "" {
+		return defaultValue, nil
+	}
+	return strconv.Atoi(value)
+}
+
+func queryUnixTime(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	seconds, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	t := time.Unix(seconds, 0)
+	return &t, nil
+}
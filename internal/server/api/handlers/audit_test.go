@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/northmule/gophkeeper/internal/common/model_data"
+	"github.com/northmule/gophkeeper/internal/common/models"
+	"github.com/northmule/gophkeeper/internal/server/logger"
+	"github.com/northmule/gophkeeper/internal/server/repository"
+	appMock "github.com/northmule/gophkeeper/internal/server/repository/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditHandler_HandleAudit(t *testing.T) {
+	l, _ := logger.NewLogger("info")
+
+	t.Run("item read is recorded", func(t *testing.T) {
+		mockAccessService := new(appMock.MockAccessService)
+		mockAuditor := new(appMock.MockAuditor)
+		mockAccessService.On("GetUserUUIDByJWTToken", mock.Anything).Return("user-uuid", nil)
+		mockAuditor.On("Record", mock.Anything, mock.Anything).Return(nil)
+		handler := NewAuditHandler(mockAccessService, mockAuditor, new(appMock.MockManager), l)
+
+		router := chi.NewRouter()
+		router.With(handler.HandleAudit(models.AuditItemRead, "")).Get("/item_get/{uuid}", func(res http.ResponseWriter, req *http.Request) {})
+		req := httptest.NewRequest(http.MethodGet, "/item_get/data-uuid", nil)
+		req.RemoteAddr = "10.0.0.1:5000"
+		router.ServeHTTP(httptest.NewRecorder(), req)
+
+		mockAuditor.AssertCalled(t, "Record", mock.Anything, models.AuditEvent{
+			UserUUID: "user-uuid",
+			Action:   models.AuditItemRead,
+			DataUUID: "data-uuid",
+			IP:       "10.0.0.1",
+		})
+	})
+
+	t.Run("handler sets user and data", func(t *testing.T) {
+		mockAuditor := new(appMock.MockAuditor)
+		mockAuditor.On("Record", mock.Anything, mock.Anything).Return(nil)
+		handler := NewAuditHandler(new(appMock.MockAccessService), mockAuditor, new(appMock.MockManager), l)
+
+		next := http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+			auditUser(req, "user-uuid")
+			auditData(req, "new-uuid")
+			res.WriteHeader(http.StatusOK)
+		})
+		req := httptest.NewRequest(http.MethodPost, "/login", nil)
+		handler.HandleAudit(models.AuditLogin, "password")(next).ServeHTTP(httptest.NewRecorder(), req)
+
+		mockAuditor.AssertCalled(t, "Record", mock.Anything, mock.MatchedBy(func(event models.AuditEvent) bool {
+			return event.UserUUID == "user-uuid" && event.DataUUID == "new-uuid" && event.Details == "password"
+		}))
+	})
+
+	t.Run("failed request is not recorded", func(t *testing.T) {
+		mockAuditor := new(appMock.MockAuditor)
+		handler := NewAuditHandler(new(appMock.MockAccessService), mockAuditor, new(appMock.MockManager), l)
+
+		next := http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+			auditUser(req, "user-uuid")
+			res.WriteHeader(http.StatusNotFound)
+		})
+		handler.HandleAudit(models.AuditItemRead, "")(next).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+		mockAuditor.AssertNotCalled(t, "Record", mock.Anything, mock.Anything)
+	})
+
+	t.Run("login waiting for second factor is not recorded", func(t *testing.T) {
+		mockAccessService := new(appMock.MockAccessService)
+		mockAuditor := new(appMock.MockAuditor)
+		mockAccessService.On("GetUserUUIDByJWTToken", mock.Anything).Return("", errors.New("no token"))
+		handler := NewAuditHandler(mockAccessService, mockAuditor, new(appMock.MockManager), l)
+
+		next := http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+			res.WriteHeader(http.StatusAccepted)
+		})
+		handler.HandleAudit(models.AuditLogin, "password")(next).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/login", nil))
+
+		mockAuditor.AssertNotCalled(t, "Record", mock.Anything, mock.Anything)
+	})
+}
+
+func TestAuditHandler_HandleList(t *testing.T) {
+	l, _ := logger.NewLogger("info")
+	createdAt := time.Unix(1735000000, 0)
+
+	t.Run("filter and page", func(t *testing.T) {
+		mockAccessService := new(appMock.MockAccessService)
+		mockRepository := new(appMock.MockManager)
+		mockAuditRepository := new(appMock.MockAuditEventModelRepository)
+		mockRepository.On("AuditEvent").Return(mockAuditRepository)
+		mockAccessService.On("GetUserUUIDByJWTToken", mock.Anything).Return("user-uuid", nil)
+		from := time.Unix(1734000000, 0)
+		mockAuditRepository.On("FindAll", mock.Anything, repository.AuditEventFilter{UserUUID: "user-uuid", Action: models.AuditItemRead, From: &from}, 20, auditMaxLimit).
+			Return([]models.AuditEvent{{ID: 3, UserUUID: "user-uuid", Action: models.AuditItemRead, DataUUID: "data-uuid", IP: "10.0.0.1", Hash: "h3", CreatedAt: createdAt}}, nil)
+		handler := NewAuditHandler(mockAccessService, new(appMock.MockAuditor), mockRepository, l)
+
+		req := httptest.NewRequest(http.MethodGet, "/audit?action=item_read&from=1734000000&offset=20&limit=10000", nil)
+		res := httptest.NewRecorder()
+		handler.HandleList(res, req)
+
+		require.Equal(t, http.StatusOK, res.Code)
+		var response model_data.AuditListResponse
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&response))
+		assert.Equal(t, 20, response.Offset)
+		assert.Equal(t, auditMaxLimit, response.Limit)
+		require.Len(t, response.Events, 1)
+		assert.Equal(t, model_data.AuditEventResponse{ID: 3, Action: models.AuditItemRead, DataUUID: "data-uuid", IP: "10.0.0.1", Hash: "h3", CreatedAt: createdAt.Unix()}, response.Events[0])
+	})
+
+	t.Run("bad params", func(t *testing.T) {
+		mockAccessService := new(appMock.MockAccessService)
+		mockAccessService.On("GetUserUUIDByJWTToken", mock.Anything).Return("user-uuid", nil)
+		handler := NewAuditHandler(mockAccessService, new(appMock.MockAuditor), new(appMock.MockManager), l)
+
+		for _, query := range []string{"from=yesterday", "to=x", "offset=-1", "limit=0"} {
+			res := httptest.NewRecorder()
+			handler.HandleList(res, httptest.NewRequest(http.MethodGet, "/audit?"+query, nil))
+			assert.Equal(t, http.StatusBadRequest, res.Code, query)
+		}
+	})
+
+	t.Run("repository error", func(t *testing.T) {
+		mockAccessService := new(appMock.MockAccessService)
+		mockRepository := new(appMock.MockManager)
+		mockAuditRepository := new(appMock.MockAuditEventModelRepository)
+		mockRepository.On("AuditEvent").Return(mockAuditRepository)
+		mockAccessService.On("GetUserUUIDByJWTToken", mock.Anything).Return("user-uuid", nil)
+		mockAuditRepository.On("FindAll", mock.Anything, mock.Anything, 0, auditDefaultLimit).Return(nil, errors.New("db error"))
+		handler := NewAuditHandler(mockAccessService, new(appMock.MockAuditor), mockRepository, l)
+
+		res := httptest.NewRecorder()
+		handler.HandleList(res, httptest.NewRequest(http.MethodGet, "/audit", nil))
+		assert.Equal(t, http.StatusInternalServerError, res.Code)
+	})
+}
+
+func TestAuditHandler_HandleVerify(t *testing.T) {
+	l, _ := logger.NewLogger("info")
+
+	tests := []struct {
+		name         string
+		result       *model_data.AuditVerifyResponse
+		err          error
+		expectedCode int
+	}{
+		{"valid", &model_data.AuditVerifyResponse{Valid: true, Checked: 3, LastHash: "h3"}, nil, http.StatusOK},
+		{"broken", &model_data.AuditVerifyResponse{Checked: 1, BrokenID: 2, LastHash: "h1"}, nil, http.StatusOK},
+		{"error", nil, errors.New("db error"), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockAccessService := new(appMock.MockAccessService)
+			mockAuditor := new(appMock.MockAuditor)
+			mockAccessService.On("GetUserUUIDByJWTToken", mock.Anything).Return("user-uuid", nil)
+			mockAuditor.On("Verify", mock.Anything, "user-uuid").Return(tt.result, tt.err)
+			handler := NewAuditHandler(mockAccessService, mockAuditor, new(appMock.MockManager), l)
+
+			res := httptest.NewRecorder()
+			handler.HandleVerify(res, httptest.NewRequest(http.MethodGet, "/audit/verify", nil))
+
+			assert.Equal(t, tt.expectedCode, res.Code)
+			if tt.result != nil {
+				var response model_data.AuditVerifyResponse
+				require.NoError(t, json.NewDecoder(res.Body).Decode(&response))
+				assert.Equal(t, *tt.result, response)
+			}
+		})
+	}
+}
@@ -0,0 +1,264 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/render"
+	"github.com/northmule/gophkeeper/internal/common/data_type"
+	"github.com/northmule/gophkeeper/internal/common/model_data"
+	"github.com/northmule/gophkeeper/internal/server/logger"
+	"github.com/northmule/gophkeeper/internal/server/repository"
+)
+
+// BatchHandler пакетное изменение данных (импорт, отправка очереди клиента). Пакет выполняется в транзакции запроса:
+// ошибка любой операции отменяет все изменения пакета
+type BatchHandler struct {
+	log            *logger.Logger
+	accessService  UserFinderByJWT
+	manager        repository.Repository
+	cardData       *CardDataHandler
+	textData       *TextDataHandler
+	credentialData *CredentialDataHandler
+}
+
+// NewBatchHandler конструктор
+func NewBatchHandler(accessService UserFinderByJWT, manager repository.Repository, log *logger.Logger) *BatchHandler {
+	return &BatchHandler{
+		log:            log,
+		accessService:  accessService,
+		manager:        manager,
+		cardData:       NewCardDataHandler(accessService, manager, log),
+		textData:       NewTextDataHandler(accessService, manager, log),
+		credentialData: NewCredentialDataHandler(accessService, manager, log),
+	}
+}
+
+type batchRequest struct {
+	model_data.BatchRequest
+}
+
+// Bind декодирует json в структуру
+func (rr *batchRequest) Bind(r *http.Request) error {
+	return nil
+}
+
+type batchResponse struct {
+	model_data.BatchResponse
+}
+
+// Render рисует структуру в json
+func (r batchResponse) Render(res http.ResponseWriter, req *http.Request) error {
+	return nil
+}
+
+// HandleBatch выполняет операции по порядку. Ошибка операции возвращается с её номером (operation, с 1)
+func (h *BatchHandler) HandleBatch(res http.ResponseWriter, req *http.Request) {
+	request := new(batchRequest)
+	if err := render.Bind(req, request); err != nil {
+		h.log.Info(err)
+		_ = render.Render(res, req, ErrBadRequest)
+		return
+	}
+	userUUID, err := h.accessService.GetUserUUIDByJWTToken(req.Context())
+	if err != nil {
+		h.log.Error(err)
+		_ = render.Render(res, req, ErrBadRequest)
+		return
+	}
+
+	// операции проверяются до первого изменения пакета
+	for i, operation := range request.Operations {
+		if !h.validate(operation) {
+			_ = render.Render(res, req, errBatchOperation(ErrBadRequest, i+1))
+			return
+		}
+	}
+
+	response := batchResponse{}
+	response.Results = make([]model_data.BatchResult, 0, len(request.Operations))
+	for i, operation := range request.Operations {
+		result, errResponse := h.apply(req.Context(), userUUID, operation)
+		if errResponse != nil {
+			h.log.Infof("batch operation %d of user %s failed, the batch is rolled back", i+1, userUUID)
+			_ = render.Render(res, req, errBatchOperation(errResponse, i+1))
+			return
+		}
+		response.Results = append(response.Results, *result)
+	}
+
+	err = render.Render(res, req, response)
+	if err != nil {
+		h.log.Error(err)
+		_ = render.Render(res, req, ErrInternalServerError)
+	}
+}
+
+// validate действие и тип данных операции поддерживаются пакетом
+func (h *BatchHandler) validate(operation model_data.BatchOperation) bool {
+	switch operation.Action {
+	case model_data.BatchActionCreate, model_data.BatchActionUpdate, model_data.BatchActionDelete:
+	default:
+		h.log.Infof("batch operation with unknown action: %s", operation.Action)
+		return false
+	}
+	switch operation.DataType {
+	case data_type.CardType, data_type.TextType, data_type.CredentialType:
+	default:
+		h.log.Infof("batch operation with unsupported data_type: %s", operation.DataType)
+		return false
+	}
+	return true
+}
+
+// apply выполнение одной операции пакета
+func (h *BatchHandler) apply(ctx context.Context, userUUID string, operation model_data.BatchOperation) (*model_data.BatchResult, render.Renderer) {
+	result := &model_data.BatchResult{Action: operation.Action, DataType: operation.DataType}
+	if operation.Action == model_data.BatchActionDelete {
+		errResponse := h.delete(ctx, userUUID, operation)
+		if errResponse != nil {
+			return nil, errResponse
+		}
+		result.UUID = operation.UUID
+		return result, nil
+	}
+
+	switch {
+	case operation.DataType == data_type.CardType && operation.CardData != nil:
+		if !batchActionMatches(operation.Action, operation.CardData.UUID) {
+			return nil, ErrBadRequest
+		}
+		data, errResponse := h.cardData.save(ctx, userUUID, operation.CardData)
+		if errResponse != nil {
+			return nil, errResponse
+		}
+		result.UUID, result.Version = data.UUID, data.Version
+	case operation.DataType == data_type.TextType && operation.TextData != nil:
+		if !batchActionMatches(operation.Action, operation.TextData.UUID) {
+			return nil, ErrBadRequest
+		}
+		data, errResponse := h.textData.save(ctx, userUUID, operation.TextData)
+		if errResponse != nil {
+			return nil, errResponse
+		}
+		result.UUID, result.Version = data.UUID, data.Version
+	case operation.DataType == data_type.CredentialType && operation.CredentialData != nil:
+		if !batchActionMatches(operation.Action, operation.CredentialData.UUID) {
+			return nil, ErrBadRequest
+		}
+		data, errResponse := h.credentialData.save(ctx, userUUID, operation.CredentialData)
+		if errResponse != nil {
+			return nil, errResponse
+		}
+		result.UUID, result.Version = data.UUID, data.Version
+	default: // нет данных для типа операции
+		h.log.Infof("batch operation without data: data_type: %s", operation.DataType)
+		return nil, ErrBadRequest
+	}
+	return result, nil
+}
+
+// delete удаление данных пользователя с проверкой версии
+func (h *BatchHandler) delete(ctx context.Context, userUUID string, operation model_data.BatchOperation) render.Renderer {
+	if operation.UUID == "" {
+		return ErrBadRequest
+	}
+	if operation.Version == 0 { // без версии удаление потеряет чужие правки
+		h.log.Infof("version required: data_uuid: %s", operation.UUID)
+		return ErrVersionRequired
+	}
+	deleted, err := h.manager.Owner().Delete(ctx, userUUID, operation.UUID, operation.DataType)
+	if err != nil {
+		h.log.Error(err)
+		return ErrInternalServerError
+	}
+	if !deleted { // нет данных этого пользователя
+		h.log.Infof("owner not found: data_uuid: %s, user_uuid: %s, data_type: %s", operation.UUID, userUUID, operation.DataType)
+		return ErrNotFound
+	}
+	err = h.deleteData(ctx, operation.DataType, operation.UUID, operation.Version)
+	if errors.Is(err, repository.ErrVersionConflict) {
+		h.log.Infof("version conflict: data_uuid: %s, version: %d", operation.UUID, operation.Version)
+		current, err := h.dataVersion(ctx, operation.DataType, operation.UUID)
+		if err != nil {
+			h.log.Error(err)
+			return ErrInternalServerError
+		}
+		if current == 0 { // данные удалены параллельно
+			h.log.Infof("data not found: data_uuid: %s", operation.UUID)
+			return ErrNotFound
+		}
+		return ErrVersionConflict(current)
+	}
+	if err != nil {
+		h.log.Error(err)
+		return ErrInternalServerError
+	}
+	err = h.manager.MetaData().ReplaceMetaByDataUUID(ctx, operation.UUID, nil)
+	if err != nil {
+		h.log.Error(err)
+		return ErrInternalServerError
+	}
+	return nil
+}
+
+// deleteData удаление строки данных типа dataType
+func (h *BatchHandler) deleteData(ctx context.Context, dataType string, dataUUID string, version int64) error {
+	switch dataType {
+	case data_type.CardType:
+		return h.manager.CardData().Delete(ctx, dataUUID, version)
+	case data_type.TextType:
+		return h.manager.TextData().Delete(ctx, dataUUID, version)
+	case data_type.CredentialType:
+		return h.manager.CredentialData().Delete(ctx, dataUUID, version)
+	}
+	return errors.New("unsupported data type: " + dataType)
+}
+
+// dataVersion текущая версия данных типа dataType, 0 если данных нет
+func (h *BatchHandler) dataVersion(ctx context.Context, dataType string, dataUUID string) (int64, error) {
+	switch dataType {
+	case data_type.CardType:
+		data, err := h.manager.CardData().FindOneByUUID(ctx, dataUUID)
+		if err != nil || data == nil {
+			return 0, err
+		}
+		return data.Version, nil
+	case data_type.TextType:
+		data, err := h.manager.TextData().FindOneByUUID(ctx, dataUUID)
+		if err != nil || data == nil {
+			return 0, err
+		}
+		return data.Version, nil
+	case data_type.CredentialType:
+		data, err := h.manager.CredentialData().FindOneByUUID(ctx, dataUUID)
+		if err != nil || data == nil {
+			return 0, err
+		}
+		return data.Version, nil
+	}
+	return 0, errors.New("unsupported data type: " + dataType)
+}
+
+// batchActionMatches create - новые данные без uuid, update - изменение данных по uuid
+func batchActionMatches(action string, dataUUID string) bool {
+	switch action {
+	case model_data.BatchActionCreate:
+		return dataUUID == ""
+	case model_data.BatchActionUpdate:
+		return dataUUID != ""
+	}
+	return false
+}
+
+// errBatchOperation ошибка с номером операции пакета
+func errBatchOperation(errResponse render.Renderer, number int) render.Renderer {
+	response, ok := errResponse.(*ErrResponse)
+	if !ok {
+		return errResponse
+	}
+	withNumber := *response // ошибки вида ErrNotFound общие для всех запросов
+	withNumber.Operation = number
+	return &withNumber
+}
@@ -0,0 +1,236 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/northmule/gophkeeper/internal/common/data_type"
+	"github.com/northmule/gophkeeper/internal/common/model_data"
+	"github.com/northmule/gophkeeper/internal/common/models"
+	"github.com/northmule/gophkeeper/internal/server/logger"
+	"github.com/northmule/gophkeeper/internal/server/repository"
+	appMock "github.com/northmule/gophkeeper/internal/server/repository/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func newBatchRequest(operations ...model_data.BatchOperation) *http.Request {
+	requestData := new(batchRequest)
+	requestData.Operations = operations
+	reqBody, _ := json.Marshal(requestData)
+	req, _ := http.NewRequest("POST", "/batch", bytes.NewBuffer(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+	return req
+}
+
+func TestHandleBatch_Success(t *testing.T) {
+	mockAccessService := new(appMock.MockAccessService)
+	mockOwnerRepo := new(appMock.MockOwnerDataModelRepository)
+	mockTextDataRepo := new(appMock.MockTextDataModelRepository)
+	mockCardDataRepo := new(appMock.MockCardDataModelRepository)
+	mockMetaDataRepo := new(appMock.MockMetaDataModelRepository)
+	mockRepository := new(appMock.MockManager)
+	logger, _ := logger.NewLogger("info")
+
+	mockRepository.On("Owner").Return(mockOwnerRepo)
+	mockRepository.On("TextData").Return(mockTextDataRepo)
+	mockRepository.On("CardData").Return(mockCardDataRepo)
+	mockRepository.On("MetaData").Return(mockMetaDataRepo)
+	mockAccessService.On("GetUserUUIDByJWTToken", mock.Anything).Return("userUUID", nil)
+
+	deletedUUID := uuid.NewString()
+	mockTextDataRepo.On("Add", mock.Anything, mock.Anything).Return(int64(1), nil)
+	mockOwnerRepo.On("Add", mock.Anything, mock.Anything).Return(int64(1), nil)
+	mockOwnerRepo.On("Delete", mock.Anything, "userUUID", deletedUUID, data_type.CardType).Return(true, nil)
+	mockCardDataRepo.On("Delete", mock.Anything, deletedUUID, int64(3)).Return(nil)
+	mockMetaDataRepo.On("ReplaceMetaByDataUUID", mock.Anything, deletedUUID, mock.Anything).Return(nil)
+
+	req := newBatchRequest(
+		model_data.BatchOperation{
+			Action:   model_data.BatchActionCreate,
+			DataType: data_type.TextType,
+			TextData: &model_data.TextDataRequest{Name: "Test Text", Value: "value"},
+		},
+		model_data.BatchOperation{
+			Action:   model_data.BatchActionDelete,
+			DataType: data_type.CardType,
+			UUID:     deletedUUID,
+			Version:  3,
+		},
+	)
+	res := httptest.NewRecorder()
+
+	handler := NewBatchHandler(mockAccessService, mockRepository, logger)
+	handler.HandleBatch(res, req)
+
+	assert.Equal(t, http.StatusOK, res.Code)
+	response := new(model_data.BatchResponse)
+	_ = json.NewDecoder(res.Body).Decode(response)
+	assert.Len(t, response.Results, 2)
+	assert.NotEmpty(t, response.Results[0].UUID)
+	assert.Equal(t, int64(1), response.Results[0].Version)
+	assert.Equal(t, deletedUUID, response.Results[1].UUID)
+	mockCardDataRepo.AssertExpectations(t)
+	mockMetaDataRepo.AssertExpectations(t)
+}
+
+func TestHandleBatch_OperationError(t *testing.T) {
+	mockAccessService := new(appMock.MockAccessService)
+	mockOwnerRepo := new(appMock.MockOwnerDataModelRepository)
+	mockTextDataRepo := new(appMock.MockTextDataModelRepository)
+	mockRepository := new(appMock.MockManager)
+	logger, _ := logger.NewLogger("info")
+
+	mockRepository.On("Owner").Return(mockOwnerRepo)
+	mockRepository.On("TextData").Return(mockTextDataRepo)
+	mockAccessService.On("GetUserUUIDByJWTToken", mock.Anything).Return("userUUID", nil)
+
+	dataUUID := uuid.NewString()
+	mockTextDataRepo.On("Add", mock.Anything, mock.Anything).Return(int64(1), nil)
+	mockOwnerRepo.On("Add", mock.Anything, mock.Anything).Return(int64(1), nil)
+	mockOwnerRepo.On("Delete", mock.Anything, "userUUID", dataUUID, data_type.TextType).Return(true, nil)
+	mockTextDataRepo.On("Delete", mock.Anything, dataUUID, int64(1)).Return(repository.ErrVersionConflict)
+	textData := new(models.TextData)
+	textData.UUID = dataUUID
+	textData.Version = 2
+	mockTextDataRepo.On("FindOneByUUID", mock.Anything, dataUUID).Return(textData, nil)
+
+	req := newBatchRequest(
+		model_data.BatchOperation{
+			Action:   model_data.BatchActionCreate,
+			DataType: data_type.TextType,
+			TextData: &model_data.TextDataRequest{Name: "Test Text", Value: "value"},
+		},
+		model_data.BatchOperation{
+			Action:   model_data.BatchActionDelete,
+			DataType: data_type.TextType,
+			UUID:     dataUUID,
+			Version:  1,
+		},
+	)
+	res := httptest.NewRecorder()
+
+	handler := NewBatchHandler(mockAccessService, mockRepository, logger)
+	handler.HandleBatch(res, req)
+
+	assert.Equal(t, http.StatusConflict, res.Code)
+	response := new(ErrResponse)
+	_ = json.NewDecoder(res.Body).Decode(response)
+	assert.Equal(t, 2, response.Operation)
+	assert.Equal(t, int64(2), response.Version)
+}
+
+func TestHandleBatch_ActionMismatch(t *testing.T) {
+	mockAccessService := new(appMock.MockAccessService)
+	mockRepository := new(appMock.MockManager)
+	logger, _ := logger.NewLogger("info")
+	mockAccessService.On("GetUserUUIDByJWTToken", mock.Anything).Return("userUUID", nil)
+
+	req := newBatchRequest(model_data.BatchOperation{
+		Action:   model_data.BatchActionUpdate,
+		DataType: data_type.TextType,
+		TextData: &model_data.TextDataRequest{Name: "Test Text", Value: "value"},
+	})
+	res := httptest.NewRecorder()
+
+	handler := NewBatchHandler(mockAccessService, mockRepository, logger)
+	handler.HandleBatch(res, req)
+
+	assert.Equal(t, http.StatusBadRequest, res.Code)
+	response := new(ErrResponse)
+	_ = json.NewDecoder(res.Body).Decode(response)
+	assert.Equal(t, 1, response.Operation)
+	mockRepository.AssertNotCalled(t, "TextData")
+}
+
+func TestHandleBatch_InvalidOperation(t *testing.T) {
+	logger, _ := logger.NewLogger("info")
+	dataUUID := uuid.NewString()
+	tests := []struct {
+		name      string
+		operation model_data.BatchOperation
+	}{
+		{
+			name: "unknown_action",
+			operation: model_data.BatchOperation{
+				Action:   "upsert",
+				DataType: data_type.TextType,
+				TextData: &model_data.TextDataRequest{UUID: dataUUID, Name: "Test Text", Value: "value"},
+			},
+		},
+		{
+			name: "unsupported_data_type",
+			operation: model_data.BatchOperation{
+				Action:   model_data.BatchActionDelete,
+				DataType: data_type.BinaryType,
+				UUID:     dataUUID,
+				Version:  1,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockAccessService := new(appMock.MockAccessService)
+			mockRepository := new(appMock.MockManager)
+			mockAccessService.On("GetUserUUIDByJWTToken", mock.Anything).Return("userUUID", nil)
+
+			// неверная вторая операция отклоняет пакет до выполнения первой
+			req := newBatchRequest(
+				model_data.BatchOperation{
+					Action:   model_data.BatchActionCreate,
+					DataType: data_type.TextType,
+					TextData: &model_data.TextDataRequest{Name: "Test Text", Value: "value"},
+				},
+				tt.operation,
+			)
+			res := httptest.NewRecorder()
+
+			handler := NewBatchHandler(mockAccessService, mockRepository, logger)
+			handler.HandleBatch(res, req)
+
+			assert.Equal(t, http.StatusBadRequest, res.Code)
+			response := new(ErrResponse)
+			_ = json.NewDecoder(res.Body).Decode(response)
+			assert.Equal(t, 2, response.Operation)
+			mockRepository.AssertNotCalled(t, "Owner")
+			mockRepository.AssertNotCalled(t, "TextData")
+		})
+	}
+}
+
+func TestHandleBatch_DeleteConflictDataRemoved(t *testing.T) {
+	mockAccessService := new(appMock.MockAccessService)
+	mockOwnerRepo := new(appMock.MockOwnerDataModelRepository)
+	mockTextDataRepo := new(appMock.MockTextDataModelRepository)
+	mockRepository := new(appMock.MockManager)
+	logger, _ := logger.NewLogger("info")
+
+	mockRepository.On("Owner").Return(mockOwnerRepo)
+	mockRepository.On("TextData").Return(mockTextDataRepo)
+	mockAccessService.On("GetUserUUIDByJWTToken", mock.Anything).Return("userUUID", nil)
+
+	dataUUID := uuid.NewString()
+	mockOwnerRepo.On("Delete", mock.Anything, "userUUID", dataUUID, data_type.TextType).Return(true, nil)
+	mockTextDataRepo.On("Delete", mock.Anything, dataUUID, int64(1)).Return(repository.ErrVersionConflict)
+	mockTextDataRepo.On("FindOneByUUID", mock.Anything, dataUUID).Return(nil, nil)
+
+	req := newBatchRequest(model_data.BatchOperation{
+		Action:   model_data.BatchActionDelete,
+		DataType: data_type.TextType,
+		UUID:     dataUUID,
+		Version:  1,
+	})
+	res := httptest.NewRecorder()
+
+	handler := NewBatchHandler(mockAccessService, mockRepository, logger)
+	handler.HandleBatch(res, req)
+
+	assert.Equal(t, http.StatusNotFound, res.Code)
+	response := new(ErrResponse)
+	_ = json.NewDecoder(res.Body).Decode(response)
+	assert.Equal(t, 1, response.Operation)
+}
@@ -1,8 +1,9 @@
 package handlers
 
 import (
+	"context"
+	"errors"
 	"net/http"
-	"time"
 
 	"github.com/go-chi/render"
 	"github.com/google/uuid"
@@ -10,51 +11,25 @@ import (
 	"github.com/northmule/gophkeeper/internal/common/model_data"
 	"github.com/northmule/gophkeeper/internal/common/models"
 	"github.com/northmule/gophkeeper/internal/server/logger"
-	"golang.org/x/net/context"
+	"github.com/northmule/gophkeeper/internal/server/repository"
 )
 
 // CardDataHandler обрабатывает данные карт
 type CardDataHandler struct {
-	log             *logger.Logger
-	userFinderByJWT UserFinderByJWT
-	ownerCRUD       OwnerCRUD
-	cardDataCRUD    CardDataCRUD
-	metaDataCRUD    MetaDataCRUD
+	log           *logger.Logger
+	accessService UserFinderByJWT
+	manager       repository.Repository
 }
 
 // NewCardDataHandler конструктор
-func NewCardDataHandler(userFinderByJWT UserFinderByJWT, ownerCRUD OwnerCRUD, cardDataCRUD CardDataCRUD, metaDataCRUD MetaDataCRUD, log *logger.Logger) *CardDataHandler {
+func NewCardDataHandler(accessService UserFinderByJWT, manager repository.Repository, log *logger.Logger) *CardDataHandler {
 	return &CardDataHandler{
-		userFinderByJWT: userFinderByJWT,
-		cardDataCRUD:    cardDataCRUD,
-		metaDataCRUD:    metaDataCRUD,
-		ownerCRUD:       ownerCRUD,
-		log:             log,
+		accessService: accessService,
+		manager:       manager,
+		log:           log,
 	}
 }
 
-// OwnerCRUD поиск владельца
-type OwnerCRUD interface {
-	FindOneByUserUUIDAndDataUUIDAndDataType(ctx context.Context, userUuid string, dataUuid string, dataType string) (*models.Owner, error)
-	FindOneByUserUUIDAndDataUUID(ctx context.Context, userUuid string, dataUuid string) (*models.Owner, error)
-	Add(ctx context.Context, data *models.Owner) (int64, error)
-	AllOwnerData(ctx context.Context, userUUID string, offset int, limit int) ([]models.OwnerData, error)
-}
-
-// CardDataCRUD операции над данными
-type CardDataCRUD interface {
-	Add(ctx context.Context, data *models.CardData) (int64, error)
-	Update(ctx context.Context, data *models.CardData) error
-	FindOneByUUID(ctx context.Context, uuid string) (*models.CardData, error)
-}
-
-// MetaDataCRUD операции над данными
-type MetaDataCRUD interface {
-	FindOneByUUID(ctx context.Context, uuid string) ([]models.MetaData, error)
-	Add(ctx context.Context, data *models.MetaData) (int64, error)
-	ReplaceMetaByDataUUID(ctx context.Context, dataUUID string, metaDataList []models.MetaData) error
-}
-
 type cardDataRequest struct {
 	model_data.CardDataRequest
 }
@@ -66,68 +41,87 @@ func (rr *cardDataRequest) Bind(r *http.Request) error {
 
 // HandleSave создание/обновление данных карты
 func (h *CardDataHandler) HandleSave(res http.ResponseWriter, req *http.Request) {
-
-	var (
-		err      error
-		userUUID string
-		owner    *models.Owner
-		cardData *models.CardData
-	)
-
 	request := new(cardDataRequest)
-	if err = render.Bind(req, request); err != nil {
+	if err := render.Bind(req, request); err != nil {
 		h.log.Info(err)
 		_ = render.Render(res, req, ErrBadRequest)
 		return
 	}
-	userUUID, err = h.userFinderByJWT.GetUserUUIDByJWTToken(req.Context())
+	userUUID, err := h.accessService.GetUserUUIDByJWTToken(req.Context())
 	if err != nil {
 		h.log.Error(err)
 		_ = render.Render(res, req, ErrBadRequest)
 		return
 	}
+	cardData, errResponse := h.save(req.Context(), userUUID, &request.CardDataRequest)
+	if errResponse != nil {
+		_ = render.Render(res, req, errResponse)
+		return
+	}
+	auditData(req, cardData.UUID)
+	res.Header().Set("ETag", versionETag(cardData.Version))
+}
+
+// save создание (без uuid) или изменение данных карты пользователя, вернёт ответ с ошибкой для клиента
+func (h *CardDataHandler) save(ctx context.Context, userUUID string, request *model_data.CardDataRequest) (*models.CardData, render.Renderer) {
+	var (
+		err      error
+		owner    *models.Owner
+		cardData *models.CardData
+	)
 
 	if request.UUID != "" { // редактирование
 		dataUUID := request.UUID
+		if request.Version == 0 { // без версии изменение перезапишет чужие правки
+			h.log.Infof("version required: data_uuid: %s", dataUUID)
+			return nil, ErrVersionRequired
+		}
 		// владелец данных
-		owner, err = h.ownerCRUD.FindOneByUserUUIDAndDataUUIDAndDataType(req.Context(), userUUID, dataUUID, data_type.CardType)
+		owner, err = h.manager.Owner().FindOneByUserUUIDAndDataUUIDAndDataType(ctx, userUUID, dataUUID, data_type.CardType)
 		if err != nil {
 			h.log.Error(err)
-			_ = render.Render(res, req, ErrBadRequest)
-			return
+			return nil, ErrBadRequest
 		}
 		if owner == nil { // нет данных этого пользователя
 			h.log.Infof("owner not found: data_uuid: %s, user_uuid: %s, data_type: %s", dataUUID, userUUID, data_type.CardType)
-			_ = render.Render(res, req, ErrNotFound)
-			return
+			return nil, ErrNotFound
 		}
-		cardData, err = h.cardDataCRUD.FindOneByUUID(req.Context(), dataUUID)
+		cardData, err = h.manager.CardData().FindOneByUUID(ctx, dataUUID)
 		if err != nil {
 			h.log.Error(err)
-			_ = render.Render(res, req, ErrBadRequest)
-			return
+			return nil, ErrBadRequest
 		}
 		if cardData == nil {
 			h.log.Infof("card data not found: uuid %s", owner.DataUUID)
-			_ = render.Render(res, req, ErrNotFound)
-			return
+			return nil, ErrNotFound
+		}
+		if cardData.Version != request.Version {
+			h.log.Infof("version conflict: data_uuid: %s, version: %d, current: %d", dataUUID, request.Version, cardData.Version)
+			return nil, ErrVersionConflict(cardData.Version)
 		}
 		// основные данные
 		cardData.Name = request.Name
-		validityPeriod, _ := time.Parse(time.RFC3339, request.ValidityPeriod)
 		cardData.Value.CardNumber = request.CardNumber
-		cardData.Value.ValidityPeriod = validityPeriod
+		cardData.Value.ValidityPeriod = request.ValidityPeriod
 		cardData.Value.SecurityCode = request.SecurityCode
 		cardData.Value.FullNameHolder = request.FullNameHolder
 		cardData.Value.NameBank = request.NameBank
 		cardData.Value.PhoneHolder = request.PhoneHolder
 		cardData.Value.CurrentAccountNumber = request.CurrentAccountNumber
 
-		err = h.cardDataCRUD.Update(req.Context(), cardData)
+		err = h.manager.CardData().Update(ctx, cardData)
+		if errors.Is(err, repository.ErrVersionConflict) { // изменены параллельным запросом
+			h.log.Infof("version conflict: data_uuid: %s, version: %d", dataUUID, request.Version)
+			cardData, err = h.manager.CardData().FindOneByUUID(ctx, dataUUID)
+			if err != nil {
+				h.log.Error(err)
+				return nil, ErrInternalServerError
+			}
+			return nil, ErrVersionConflict(cardData.Version)
+		}
 		if err != nil {
 			h.log.Error(err)
-			_ = render.Render(res, req, ErrInternalServerError)
-			return
+			return nil, ErrInternalServerError
 		}
 
 		// мета поля
@@ -142,11 +136,10 @@ func (h *CardDataHandler) HandleSave(res http.ResponseWriter, req *http.Request)
 			}
 		}
 		// перезапись мета
-		err = h.metaDataCRUD.ReplaceMetaByDataUUID(req.Context(), dataUUID, newMeta)
+		err = h.manager.MetaData().ReplaceMetaByDataUUID(ctx, dataUUID, newMeta)
 		if err != nil {
 			h.log.Error(err)
-			_ = render.Render(res, req, ErrInternalServerError)
-			return
+			return nil, ErrInternalServerError
 		}
 	}
 
@@ -158,33 +151,30 @@ func (h *CardDataHandler) HandleSave(res http.ResponseWriter, req *http.Request)
 		cardData.UUID = dataUUID
 		cardData.ObjectType = data_type.CardType
 
-		validityPeriod, _ := time.Parse(time.RFC3339, request.ValidityPeriod)
-
 		cardData.Value.CardNumber = request.CardNumber
-		cardData.Value.ValidityPeriod = validityPeriod
+		cardData.Value.ValidityPeriod = request.ValidityPeriod
 		cardData.Value.SecurityCode = request.SecurityCode
 		cardData.Value.FullNameHolder = request.FullNameHolder
 		cardData.Value.NameBank = request.NameBank
 		cardData.Value.PhoneHolder = request.PhoneHolder
 		cardData.Value.CurrentAccountNumber = request.CurrentAccountNumber
 
-		_, err = h.cardDataCRUD.Add(req.Context(), cardData)
+		_, err = h.manager.CardData().Add(ctx, cardData)
 		if err != nil {
 			h.log.Error(err)
-			_ = render.Render(res, req, ErrInternalServerError)
-			return
+			return nil, ErrInternalServerError
 		}
+		cardData.Version = 1 // версия новых данных по умолчанию
 		// владелец данных
 		owner = new(models.Owner)
 		owner.UserUUID = userUUID
 		owner.DataType = data_type.CardType
 		owner.DataUUID = dataUUID
 
-		_, err = h.ownerCRUD.Add(req.Context(), owner)
+		_, err = h.manager.Owner().Add(ctx, owner)
 		if err != nil {
 			h.log.Error(err)
-			_ = render.Render(res, req, ErrInternalServerError)
-			return
+			return nil, ErrInternalServerError
 		}
 		// мета поля
 		if len(request.Meta) > 0 {
@@ -193,29 +183,15 @@ func (h *CardDataHandler) HandleSave(res http.ResponseWriter, req *http.Request)
 				metaData.MetaName = key
 				metaData.MetaValue.Value = value
 				metaData.DataUUID = dataUUID
-				_, err = h.metaDataCRUD.Add(req.Context(), metaData)
+				_, err = h.manager.MetaData().Add(ctx, metaData)
 				if err != nil {
 					h.log.Error(err)
-					_ = render.Render(res, req, ErrInternalServerError)
-					return
+					return nil, ErrInternalServerError
 				}
 			}
 		}
 
 	}
 
-	// общие проверки
-
-	if cardData == nil {
-		h.log.Error("card data empty")
-		_ = render.Render(res, req, ErrInternalServerError)
-		return
-	}
-	if owner == nil {
-		h.log.Error("owner empty")
-		_ = render.Render(res, req, ErrInternalServerError)
-		return
-	}
-
-	//OK
+	return cardData, nil
 }
@@ -35,9 +35,10 @@ func TestHandleSave_SuccessfulCreation(t *testing.T) {
 	cardData.Name = "Test Card"
 	cardData.UUID = uuid.NewString()
 	cardData.ObjectType = data_type.CardType
+	cardData.Version = 1
 	cardData.Value = models.CardDataValueV1{
 		CardNumber:           "1234567890123456",
-		ValidityPeriod:       time.Now().AddDate(1, 0, 0),
+		ValidityPeriod:       time.Now().AddDate(1, 0, 0).Format(time.RFC3339),
 		SecurityCode:         "123",
 		FullNameHolder:       "John Doe",
 		NameBank:             "Test Bank",
@@ -109,9 +110,10 @@ func TestHandleSave_SuccessfulUpdate(t *testing.T) {
 	cardData.Name = "Test Card"
 	cardData.UUID = dataUUID
 	cardData.ObjectType = data_type.CardType
+	cardData.Version = 1
 	cardData.Value = models.CardDataValueV1{
 		CardNumber:           "1234567890123456",
-		ValidityPeriod:       time.Now().AddDate(1, 0, 0),
+		ValidityPeriod:       time.Now().AddDate(1, 0, 0).Format(time.RFC3339),
 		SecurityCode:         "123",
 		FullNameHolder:       "John Doe",
 		NameBank:             "Test Bank",
@@ -148,6 +150,7 @@ func TestHandleSave_SuccessfulUpdate(t *testing.T) {
 
 	reqBody, _ := json.Marshal(model_data.CardDataRequest{
 		UUID:                 dataUUID,
+		Version:              1,
 		Name:                 "Updated Test Card",
 		CardNumber:           "6543210987654321",
 		ValidityPeriod:       newValidityPeriod.Format(time.RFC3339),
@@ -253,6 +256,7 @@ func TestHandleSave_NonExistentDataUUID(t *testing.T) {
 
 	reqBody, _ := json.Marshal(model_data.CardDataRequest{
 		UUID:                 dataUUID,
+		Version:              1,
 		Name:                 "Test Card",
 		CardNumber:           "1234567890123456",
 		ValidityPeriod:       time.Now().AddDate(1, 0, 0).Format(time.RFC3339),
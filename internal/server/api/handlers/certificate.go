@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/go-chi/render"
+	"github.com/northmule/gophkeeper/internal/common/keys"
+	"github.com/northmule/gophkeeper/internal/server/logger"
+	"github.com/northmule/gophkeeper/internal/server/repository"
+)
+
+// CertificateHandler вход по сертификату клиента (mTLS).
+// Цепочка сертификата проверяется при TLS рукопожатии по CA сервера,
+// пользователь определяется по отпечатку сертификата, выданного на /api/v1/save_public_key
+type CertificateHandler struct {
+	log     *logger.Logger
+	manager repository.Repository
+	session SessionOpener
+}
+
+// NewCertificateHandler конструктор
+func NewCertificateHandler(manager repository.Repository, session SessionOpener, log *logger.Logger) *CertificateHandler {
+	return &CertificateHandler{
+		log:     log,
+		manager: manager,
+		session: session,
+	}
+}
+
+// HandleLogin открывает сессию пользователя, которому выдан предъявленный сертификат
+func (h *CertificateHandler) HandleLogin(res http.ResponseWriter, req *http.Request) {
+	if req.TLS == nil || len(req.TLS.VerifiedChains) == 0 || len(req.TLS.PeerCertificates) == 0 {
+		h.log.Info("certificate login without a verified client certificate")
+		_ = render.Render(res, req, ErrUnauthorized)
+		return
+	}
+	peer := req.TLS.PeerCertificates[0]
+	fingerprint := keys.Fingerprint(peer.Raw)
+
+	certificate, err := h.manager.ClientCertificate().FindOneByFingerprint(req.Context(), fingerprint)
+	if err != nil {
+		h.log.Error(err)
+		_ = render.Render(res, req, ErrInternalServerError)
+		return
+	}
+	if certificate == nil || !certificate.IsActive() || certificate.UserUUID != peer.Subject.CommonName {
+		h.log.Infof("Client certificate %s is unknown, revoked or expired", fingerprint)
+		_ = render.Render(res, req, ErrUnauthorized)
+		return
+	}
+
+	// сертификат выдан устройству, вход по нему открывает сессию этого устройства
+	device := requestDevice(req)
+	if certificate.DeviceUUID != "" {
+		device.UUID = certificate.DeviceUUID
+	}
+	tokens, err := h.session.Open(req.Context(), certificate.UserUUID, device)
+	if err != nil {
+		h.log.Error(err)
+		_ = render.Render(res, req, ErrInternalServerError)
+		return
+	}
+	auditUser(req, certificate.UserUUID)
+	res.Header().Set("Authorization", "Bearer "+tokens.AccessToken)
+
+	h.log.Infof("User %s has been authenticated with the client certificate %s, a new session has been opened", certificate.UserUUID, fingerprint)
+	err = render.Render(res, req, tokenResponse{TokenResponse: *tokens})
+	if err != nil {
+		h.log.Error(err)
+		_ = render.Render(res, req, ErrInternalServerError)
+	}
+}
@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/northmule/gophkeeper/internal/common/keys"
+	"github.com/northmule/gophkeeper/internal/common/model_data"
+	"github.com/northmule/gophkeeper/internal/common/models"
+	"github.com/northmule/gophkeeper/internal/server/config"
+	"github.com/northmule/gophkeeper/internal/server/logger"
+	appMock "github.com/northmule/gophkeeper/internal/server/repository/mock"
+	"github.com/northmule/gophkeeper/internal/server/services/ca"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestAuthority(t *testing.T) *ca.Authority {
+	cfg := config.NewConfig()
+	cfg.Value().PathKeys = t.TempDir()
+	authority, err := ca.NewAuthority(cfg)
+	require.NoError(t, err)
+	return authority
+}
+
+func issueTestCertificate(t *testing.T, authority *ca.Authority, userUUID string) *x509.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	issued, err := authority.Issue(key.Public(), userUUID)
+	require.NoError(t, err)
+	return issued.Certificate
+}
+
+func newCertificateLoginRequest(certificate *x509.Certificate) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/login/certificate", nil)
+	if certificate != nil {
+		req.TLS = &tls.ConnectionState{
+			PeerCertificates: []*x509.Certificate{certificate},
+			VerifiedChains:   [][]*x509.Certificate{{certificate}},
+		}
+	}
+	return req
+}
+
+func TestCertificateHandler_HandleLogin(t *testing.T) {
+	l, _ := logger.NewLogger("info")
+	authority := newTestAuthority(t)
+	certificate := issueTestCertificate(t, authority, "user-uuid")
+	fingerprint := keys.Fingerprint(certificate.Raw)
+	revokedAt := time.Now()
+
+	tests := []struct {
+		name       string
+		request    *http.Request
+		record     *models.ClientCertificate
+		wantStatus int
+	}{
+		{
+			name:       "without certificate",
+			request:    newCertificateLoginRequest(nil),
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "unknown certificate",
+			request:    newCertificateLoginRequest(certificate),
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "revoked certificate",
+			request:    newCertificateLoginRequest(certificate),
+			record:     &models.ClientCertificate{UserUUID: "user-uuid", Fingerprint: fingerprint, ExpiresAt: time.Now().Add(time.Hour), RevokedAt: &revokedAt},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "certificate of another user",
+			request:    newCertificateLoginRequest(certificate),
+			record:     &models.ClientCertificate{UserUUID: "another-uuid", Fingerprint: fingerprint, ExpiresAt: time.Now().Add(time.Hour)},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "ok",
+			request:    newCertificateLoginRequest(certificate),
+			record:     &models.ClientCertificate{UserUUID: "user-uuid", DeviceUUID: "device-uuid", Fingerprint: fingerprint, ExpiresAt: time.Now().Add(time.Hour)},
+			wantStatus: http.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepository := new(appMock.MockManager)
+			mockCertificateRepository := new(appMock.MockClientCertificateModelRepository)
+			mockSessionOpener := new(appMock.MockSessionOpener)
+			mockRepository.On("ClientCertificate").Return(mockCertificateRepository)
+			if tt.record != nil {
+				mockCertificateRepository.On("FindOneByFingerprint", mock.Anything, fingerprint).Return(tt.record, nil)
+			} else {
+				mockCertificateRepository.On("FindOneByFingerprint", mock.Anything, fingerprint).Return(nil, nil)
+			}
+			mockSessionOpener.On("Open", mock.Anything, "user-uuid", mock.Anything).Return(&model_data.TokenResponse{AccessToken: "access-token", RefreshToken: "refresh-token", ExpiresIn: 900}, nil)
+
+			handler := NewCertificateHandler(mockRepository, mockSessionOpener, l)
+			res := httptest.NewRecorder()
+			handler.HandleLogin(res, tt.request)
+
+			assert.Equal(t, tt.wantStatus, res.Code)
+			if tt.wantStatus == http.StatusOK {
+				assert.Equal(t, "Bearer access-token", res.Header().Get("Authorization"))
+				assert.Contains(t, res.Body.String(), "refresh-token")
+				// сессия открывается на устройстве, которому выдан сертификат
+				mockSessionOpener.AssertCalled(t, "Open", mock.Anything, "user-uuid", mock.MatchedBy(func(device *models.Device) bool {
+					return device.UUID == "device-uuid"
+				}))
+			} else {
+				mockSessionOpener.AssertNotCalled(t, "Open", mock.Anything, mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+
+	"github.com/northmule/gophkeeper/internal/server/repository"
+	"github.com/northmule/gophkeeper/internal/server/services/kek"
+)
+
+// errNoClientKey ключ шифрования клиента не сохранён
+var errNoClientKey = errors.New("the client key has not been saved")
+
+// findClientKey ключ шифрования клиента для токена запроса. Ключ хранится у устройства из токена.
+// Устройство, не сохранившее свой ключ, и токен без устройства (сессия, открытая до появления устройств)
+// используют ключ, сохранённый у пользователя
+func findClientKey(ctx context.Context, accessService DeviceAccess, manager repository.Repository, keyProvider kek.KeyProvider, userUUID string) ([]byte, error) {
+	deviceUUID, err := accessService.GetDeviceUUIDByJWTToken(ctx)
+	if err == nil {
+		device, err := manager.Device().FindOneByUUID(ctx, deviceUUID)
+		if err != nil {
+			return nil, err
+		}
+		if device != nil && device.UserUUID == userUUID && device.ClientKey != "" {
+			return keyProvider.Unwrap(device.ClientKey, []byte(device.UUID))
+		}
+	}
+	user, err := manager.User().FindOneByUUID(ctx, userUUID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil || user.PrivateClientKey == "" {
+		return nil, errNoClientKey
+	}
+	return keyProvider.Unwrap(user.PrivateClientKey, []byte(user.UUID))
+}
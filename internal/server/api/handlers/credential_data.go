@@ -0,0 +1,189 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/render"
+	"github.com/google/uuid"
+	"github.com/northmule/gophkeeper/internal/common/data_type"
+	"github.com/northmule/gophkeeper/internal/common/model_data"
+	"github.com/northmule/gophkeeper/internal/common/models"
+	"github.com/northmule/gophkeeper/internal/server/logger"
+	"github.com/northmule/gophkeeper/internal/server/repository"
+)
+
+// CredentialDataHandler обрабатывает пары логин/пароль
+type CredentialDataHandler struct {
+	log           *logger.Logger
+	accessService UserFinderByJWT
+	manager       repository.Repository
+}
+
+// NewCredentialDataHandler конструктор
+func NewCredentialDataHandler(accessService UserFinderByJWT, manager repository.Repository, log *logger.Logger) *CredentialDataHandler {
+	return &CredentialDataHandler{
+		accessService: accessService,
+		manager:       manager,
+		log:           log,
+	}
+}
+
+type credentialDataRequest struct {
+	model_data.CredentialDataRequest
+}
+
+// Bind декодирует json в структуру
+func (rr *credentialDataRequest) Bind(r *http.Request) error {
+	return nil
+}
+
+// HandleSave создание/обновление пары логин/пароль
+func (h *CredentialDataHandler) HandleSave(res http.ResponseWriter, req *http.Request) {
+	request := new(credentialDataRequest)
+	if err := render.Bind(req, request); err != nil {
+		h.log.Info(err)
+		_ = render.Render(res, req, ErrBadRequest)
+		return
+	}
+	userUUID, err := h.accessService.GetUserUUIDByJWTToken(req.Context())
+	if err != nil {
+		h.log.Error(err)
+		_ = render.Render(res, req, ErrBadRequest)
+		return
+	}
+	credentialData, errResponse := h.save(req.Context(), userUUID, &request.CredentialDataRequest)
+	if errResponse != nil {
+		_ = render.Render(res, req, errResponse)
+		return
+	}
+	auditData(req, credentialData.UUID)
+	res.Header().Set("ETag", versionETag(credentialData.Version))
+}
+
+// save создание (без uuid) или изменение пары логин/пароль пользователя, вернёт ответ с ошибкой для клиента
+func (h *CredentialDataHandler) save(ctx context.Context, userUUID string, request *model_data.CredentialDataRequest) (*models.CredentialData, render.Renderer) {
+	var (
+		err            error
+		owner          *models.Owner
+		credentialData *models.CredentialData
+	)
+
+	if request.UUID != "" { // редактирование
+		dataUUID := request.UUID
+		if request.Version == 0 { // без версии изменение перезапишет чужие правки
+			h.log.Infof("version required: data_uuid: %s", dataUUID)
+			return nil, ErrVersionRequired
+		}
+		// владелец данных
+		owner, err = h.manager.Owner().FindOneByUserUUIDAndDataUUIDAndDataType(ctx, userUUID, dataUUID, data_type.CredentialType)
+		if err != nil {
+			h.log.Error(err)
+			return nil, ErrBadRequest
+		}
+		if owner == nil { // нет данных этого пользователя
+			h.log.Infof("owner not found: data_uuid: %s, user_uuid: %s, data_type: %s", dataUUID, userUUID, data_type.CredentialType)
+			return nil, ErrNotFound
+		}
+		credentialData, err = h.manager.CredentialData().FindOneByUUID(ctx, dataUUID)
+		if err != nil {
+			h.log.Error(err)
+			return nil, ErrBadRequest
+		}
+		if credentialData == nil {
+			h.log.Infof("credential data not found: uuid %s", owner.DataUUID)
+			return nil, ErrNotFound
+		}
+		if credentialData.Version != request.Version {
+			h.log.Infof("version conflict: data_uuid: %s, version: %d, current: %d", dataUUID, request.Version, credentialData.Version)
+			return nil, ErrVersionConflict(credentialData.Version)
+		}
+		// основные данные
+		credentialData.Name = request.Name
+		credentialData.Value.Username = request.Username
+		credentialData.Value.Password = request.Password
+		credentialData.Value.URLs = request.URLs
+		credentialData.Value.Notes = request.Notes
+
+		err = h.manager.CredentialData().Update(ctx, credentialData)
+		if errors.Is(err, repository.ErrVersionConflict) { // изменены параллельным запросом
+			h.log.Infof("version conflict: data_uuid: %s, version: %d", dataUUID, request.Version)
+			credentialData, err = h.manager.CredentialData().FindOneByUUID(ctx, dataUUID)
+			if err != nil {
+				h.log.Error(err)
+				return nil, ErrInternalServerError
+			}
+			return nil, ErrVersionConflict(credentialData.Version)
+		}
+		if err != nil {
+			h.log.Error(err)
+			return nil, ErrInternalServerError
+		}
+
+		// мета поля
+		var newMeta []models.MetaData
+		if len(request.Meta) > 0 {
+			for key, value := range request.Meta {
+				metaData := models.MetaData{}
+				metaData.MetaName = key
+				metaData.MetaValue.Value = value
+				metaData.DataUUID = dataUUID
+				newMeta = append(newMeta, metaData)
+			}
+		}
+		// перезапись мета
+		err = h.manager.MetaData().ReplaceMetaByDataUUID(ctx, dataUUID, newMeta)
+		if err != nil {
+			h.log.Error(err)
+			return nil, ErrInternalServerError
+		}
+	}
+
+	if request.UUID == "" { // новые данные
+		// основные данные
+		dataUUID := uuid.NewString()
+		credentialData = new(models.CredentialData)
+		credentialData.Name = request.Name
+		credentialData.UUID = dataUUID
+		credentialData.ObjectType = data_type.CredentialType
+		credentialData.Value.Username = request.Username
+		credentialData.Value.Password = request.Password
+		credentialData.Value.URLs = request.URLs
+		credentialData.Value.Notes = request.Notes
+
+		_, err = h.manager.CredentialData().Add(ctx, credentialData)
+		if err != nil {
+			h.log.Error(err)
+			return nil, ErrInternalServerError
+		}
+		credentialData.Version = 1 // версия новых данных по умолчанию
+		// владелец данных
+		owner = new(models.Owner)
+		owner.UserUUID = userUUID
+		owner.DataType = data_type.CredentialType
+		owner.DataUUID = dataUUID
+
+		_, err = h.manager.Owner().Add(ctx, owner)
+		if err != nil {
+			h.log.Error(err)
+			return nil, ErrInternalServerError
+		}
+		// мета поля
+		if len(request.Meta) > 0 {
+			for key, value := range request.Meta {
+				metaData := new(models.MetaData)
+				metaData.MetaName = key
+				metaData.MetaValue.Value = value
+				metaData.DataUUID = dataUUID
+				_, err = h.manager.MetaData().Add(ctx, metaData)
+				if err != nil {
+					h.log.Error(err)
+					return nil, ErrInternalServerError
+				}
+			}
+		}
+	}
+
+	return credentialData, nil
+}
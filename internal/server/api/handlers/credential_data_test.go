@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/northmule/gophkeeper/internal/common/data_type"
+	"github.com/northmule/gophkeeper/internal/common/model_data"
+	"github.com/northmule/gophkeeper/internal/common/models"
+	"github.com/northmule/gophkeeper/internal/server/logger"
+	appMock "github.com/northmule/gophkeeper/internal/server/repository/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCredentialDataHandler_HandleSave_SuccessfulCreation(t *testing.T) {
+	mockAccessService := new(appMock.MockAccessService)
+	mockOwnerRepo := new(appMock.MockOwnerDataModelRepository)
+	mockCredentialDataRepo := new(appMock.MockCredentialDataModelRepository)
+	mockMetaDataRepo := new(appMock.MockMetaDataModelRepository)
+	mockRepository := new(appMock.MockManager)
+	l, _ := logger.NewLogger("info")
+
+	mockRepository.On("Owner").Return(mockOwnerRepo)
+	mockRepository.On("CredentialData").Return(mockCredentialDataRepo)
+	mockRepository.On("MetaData").Return(mockMetaDataRepo)
+
+	mockAccessService.On("GetUserUUIDByJWTToken", mock.Anything).Return("userUUID", nil)
+	mockCredentialDataRepo.On("Add", mock.Anything, mock.MatchedBy(func(data *models.CredentialData) bool {
+		return data.Value.Username == "john" && data.Value.Password == "secret" && data.ObjectType == data_type.CredentialType
+	})).Return(int64(1), nil)
+	mockOwnerRepo.On("Add", mock.Anything, mock.MatchedBy(func(data *models.Owner) bool {
+		return data.DataType == data_type.CredentialType && data.UserUUID == "userUUID"
+	})).Return(int64(1), nil)
+	mockMetaDataRepo.On("Add", mock.Anything, mock.Anything).Return(int64(1), nil)
+
+	reqBody, _ := json.Marshal(model_data.CredentialDataRequest{
+		Name:     "Mail",
+		Username: "john",
+		Password: "secret",
+		URLs:     []string{"https://mail.example.com"},
+		Notes:    "work account",
+		Meta:     map[string]string{"test": "value"},
+	})
+	req, _ := http.NewRequest("POST", "/credential", bytes.NewBuffer(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+	res := httptest.NewRecorder()
+
+	handler := NewCredentialDataHandler(mockAccessService, mockRepository, l)
+	handler.HandleSave(res, req)
+
+	assert.Equal(t, http.StatusOK, res.Code)
+	mockCredentialDataRepo.AssertExpectations(t)
+	mockOwnerRepo.AssertExpectations(t)
+	mockMetaDataRepo.AssertExpectations(t)
+}
+
+func TestCredentialDataHandler_HandleSave_SuccessfulUpdate(t *testing.T) {
+	mockAccessService := new(appMock.MockAccessService)
+	mockOwnerRepo := new(appMock.MockOwnerDataModelRepository)
+	mockCredentialDataRepo := new(appMock.MockCredentialDataModelRepository)
+	mockMetaDataRepo := new(appMock.MockMetaDataModelRepository)
+	mockRepository := new(appMock.MockManager)
+	l, _ := logger.NewLogger("info")
+
+	mockRepository.On("Owner").Return(mockOwnerRepo)
+	mockRepository.On("CredentialData").Return(mockCredentialDataRepo)
+	mockRepository.On("MetaData").Return(mockMetaDataRepo)
+
+	mockAccessService.On("GetUserUUIDByJWTToken", mock.Anything).Return("userUUID", nil)
+
+	dataUUID := uuid.NewString()
+	credentialData := new(models.CredentialData)
+	credentialData.UUID = dataUUID
+	credentialData.Name = "Mail"
+	credentialData.ObjectType = data_type.CredentialType
+	credentialData.Value.Username = "john"
+	credentialData.Version = 1
+
+	owner := &models.Owner{UserUUID: "userUUID", DataType: data_type.CredentialType, DataUUID: dataUUID}
+	mockOwnerRepo.On("FindOneByUserUUIDAndDataUUIDAndDataType", mock.Anything, "userUUID", dataUUID, data_type.CredentialType).Return(owner, nil)
+	mockCredentialDataRepo.On("FindOneByUUID", mock.Anything, dataUUID).Return(credentialData, nil)
+	mockCredentialDataRepo.On("Update", mock.Anything, mock.MatchedBy(func(data *models.CredentialData) bool {
+		return data.Value.Username == "jane" && data.Name == "Updated mail"
+	})).Return(nil)
+	mockMetaDataRepo.On("ReplaceMetaByDataUUID", mock.Anything, dataUUID, mock.Anything).Return(nil)
+
+	reqBody, _ := json.Marshal(model_data.CredentialDataRequest{
+		UUID:     dataUUID,
+		Version:  1,
+		Name:     "Updated mail",
+		Username: "jane",
+		Password: "new-secret",
+	})
+	req, _ := http.NewRequest("POST", "/credential", bytes.NewBuffer(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+	res := httptest.NewRecorder()
+
+	handler := NewCredentialDataHandler(mockAccessService, mockRepository, l)
+	handler.HandleSave(res, req)
+
+	assert.Equal(t, http.StatusOK, res.Code)
+	mockCredentialDataRepo.AssertExpectations(t)
+	mockMetaDataRepo.AssertExpectations(t)
+}
+
+func TestCredentialDataHandler_HandleSave_OwnerNotFound(t *testing.T) {
+	mockAccessService := new(appMock.MockAccessService)
+	mockOwnerRepo := new(appMock.MockOwnerDataModelRepository)
+	mockRepository := new(appMock.MockManager)
+	l, _ := logger.NewLogger("info")
+
+	mockRepository.On("Owner").Return(mockOwnerRepo)
+	mockAccessService.On("GetUserUUIDByJWTToken", mock.Anything).Return("userUUID", nil)
+
+	dataUUID := uuid.NewString()
+	mockOwnerRepo.On("FindOneByUserUUIDAndDataUUIDAndDataType", mock.Anything, "userUUID", dataUUID, data_type.CredentialType).Return(nil, nil)
+
+	reqBody, _ := json.Marshal(model_data.CredentialDataRequest{UUID: dataUUID, Version: 1, Name: "Mail", Username: "john"})
+	req, _ := http.NewRequest("POST", "/credential", bytes.NewBuffer(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+	res := httptest.NewRecorder()
+
+	handler := NewCredentialDataHandler(mockAccessService, mockRepository, l)
+	handler.HandleSave(res, req)
+
+	assert.Equal(t, http.StatusNotFound, res.Code)
+}
+
+func TestCredentialDataHandler_HandleSave_AddError(t *testing.T) {
+	mockAccessService := new(appMock.MockAccessService)
+	mockCredentialDataRepo := new(appMock.MockCredentialDataModelRepository)
+	mockRepository := new(appMock.MockManager)
+	l, _ := logger.NewLogger("info")
+
+	mockRepository.On("CredentialData").Return(mockCredentialDataRepo)
+	mockAccessService.On("GetUserUUIDByJWTToken", mock.Anything).Return("userUUID", nil)
+	mockCredentialDataRepo.On("Add", mock.Anything, mock.Anything).Return(int64(0), errors.New("db error"))
+
+	reqBody, _ := json.Marshal(model_data.CredentialDataRequest{Name: "Mail", Username: "john"})
+	req, _ := http.NewRequest("POST", "/credential", bytes.NewBuffer(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+	res := httptest.NewRecorder()
+
+	handler := NewCredentialDataHandler(mockAccessService, mockRepository, l)
+	handler.HandleSave(res, req)
+
+	assert.Equal(t, http.StatusInternalServerError, res.Code)
+}
+
+func TestCredentialDataHandler_HandleSave_UserUUIDError(t *testing.T) {
+	mockAccessService := new(appMock.MockAccessService)
+	mockRepository := new(appMock.MockManager)
+	l, _ := logger.NewLogger("info")
+
+	mockAccessService.On("GetUserUUIDByJWTToken", mock.Anything).Return("", errors.New("no token"))
+
+	reqBody, _ := json.Marshal(model_data.CredentialDataRequest{Name: "Mail", Username: "john"})
+	req, _ := http.NewRequest("POST", "/credential", bytes.NewBuffer(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+	res := httptest.NewRecorder()
+
+	handler := NewCredentialDataHandler(mockAccessService, mockRepository, l)
+	handler.HandleSave(res, req)
+
+	assert.Equal(t, http.StatusBadRequest, res.Code)
+}
@@ -2,49 +2,64 @@ package handlers
 
 import (
 	"bytes"
+	"encoding/hex"
+	"errors"
 	"io"
 	"net/http"
 
 	"github.com/go-chi/render"
-	"github.com/northmule/gophkeeper/internal/common/models"
 	"github.com/northmule/gophkeeper/internal/common/util"
 	"github.com/northmule/gophkeeper/internal/server/logger"
+	"github.com/northmule/gophkeeper/internal/server/repository"
+	"github.com/northmule/gophkeeper/internal/server/services/kek"
+	"github.com/northmule/gophkeeper/internal/server/storage"
 )
 
 // DecryptDataHandler Расшифровывает входящий запрос
 type DecryptDataHandler struct {
-	log             *logger.Logger
-	userFinderByJWT UserFinderByJWT
-	userFinder      UserFinder
+	log           *logger.Logger
+	accessService DeviceAccess
+	keyProvider   kek.KeyProvider
+	nonceGuard    storage.NonceGuardManager
+	manager       repository.Repository
 }
 
 // NewDecryptDataHandler конструктор
-func NewDecryptDataHandler(userFinderByJWT UserFinderByJWT, userFinder UserFinder, log *logger.Logger) *DecryptDataHandler {
+func NewDecryptDataHandler(accessService DeviceAccess, keyProvider kek.KeyProvider, nonceGuard storage.NonceGuardManager, manager repository.Repository, log *logger.Logger) *DecryptDataHandler {
 	return &DecryptDataHandler{
-		log:             log,
-		userFinderByJWT: userFinderByJWT,
-		userFinder:      userFinder,
+		log:           log,
+		accessService: accessService,
+		keyProvider:   keyProvider,
+		nonceGuard:    nonceGuard,
+		manager:       manager,
 	}
 }
 
-// HandleDecryptData расшифрует входящий запрос
+// HandleDecryptData расшифрует входящий запрос. Тело - конверт util.SealRequest, привязанный к методу и пути;
+// устаревшие и повторные запросы отклоняются
 func (h *DecryptDataHandler) HandleDecryptData(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
 		var (
 			err              error
 			userUUID         string
-			user             *models.User
 			bodyBytesDecrypt []byte
+			clientKey        []byte
+			envelope         *util.RequestEnvelope
 		)
 
-		userUUID, err = h.userFinderByJWT.GetUserUUIDByJWTToken(req.Context())
+		userUUID, err = h.accessService.GetUserUUIDByJWTToken(req.Context())
 		if err != nil {
 			h.log.Error(err)
 			_ = render.Render(res, req, ErrBadRequest)
 			return
 		}
 
-		user, err = h.userFinder.FindOneByUUID(req.Context(), userUUID)
+		clientKey, err = findClientKey(req.Context(), h.accessService, h.manager, h.keyProvider, userUUID)
+		if errors.Is(err, errNoClientKey) {
+			h.log.Infof("User %s has no client key", userUUID)
+			_ = render.Render(res, req, ErrBadRequest)
+			return
+		}
 		if err != nil {
 			h.log.Error(err)
 			_ = render.Render(res, req, ErrInternalServerError)
@@ -52,14 +67,35 @@ func (h *DecryptDataHandler) HandleDecryptData(next http.Handler) http.Handler {
 		}
 
 		// копия body
-		bodyBytes, _ := io.ReadAll(req.Body)
-		if len(bodyBytes) == 0 {
+		bodyBytes, err := io.ReadAll(req.Body)
+		if err != nil {
 			h.log.Error(err)
 			_ = render.Render(res, req, ErrBadRequest)
 			return
 		}
+		if len(bodyBytes) == 0 {
+			h.log.Infof("Rejected a request with an empty body from user %s to %s", userUUID, req.URL.Path)
+			_ = render.Render(res, req, ErrBadRequest)
+			return
+		}
 		// Расшифровываем тело запроса
-		bodyBytesDecrypt, err = util.DataDecryptAES(bodyBytes, []byte(user.PrivateClientKey))
+		envelope, bodyBytesDecrypt, err = util.OpenRequest(bodyBytes, clientKey, req.Method, req.URL.Path)
+		if err != nil {
+			h.log.Info(err)
+			_ = render.Render(res, req, ErrBadRequest)
+			return
+		}
+		err = h.nonceGuard.Check(req.Context(), userUUID, hex.EncodeToString(envelope.Nonce), envelope.Timestamp)
+		if errors.Is(err, storage.ErrRequestExpired) {
+			h.log.Infof("Rejected an expired request from user %s: %s", userUUID, envelope.Timestamp)
+			_ = render.Render(res, req, ErrRequestExpired)
+			return
+		}
+		if errors.Is(err, storage.ErrRequestReplayed) {
+			h.log.Warnf("Rejected a replayed request from user %s to %s", userUUID, req.URL.Path)
+			_ = render.Render(res, req, ErrRequestReplayed)
+			return
+		}
 		if err != nil {
 			h.log.Error(err)
 			_ = render.Render(res, req, ErrInternalServerError)
@@ -87,8 +123,8 @@ func (h *DecryptDataHandler) HandleEncryptData(next http.Handler) http.Handler {
 		var (
 			err              error
 			userUUID         string
-			user             *models.User
 			bodyBytesEncrypt []byte
+			clientKey        []byte
 		)
 
 		mixedResponseWriter := &MixedResponseWriter{
@@ -98,21 +134,21 @@ func (h *DecryptDataHandler) HandleEncryptData(next http.Handler) http.Handler {
 
 		next.ServeHTTP(mixedResponseWriter, req)
 
-		userUUID, err = h.userFinderByJWT.GetUserUUIDByJWTToken(req.Context())
+		userUUID, err = h.accessService.GetUserUUIDByJWTToken(req.Context())
 		if err != nil {
 			h.log.Error(err)
 			_ = render.Render(res, req, ErrBadRequest)
 			return
 		}
 
-		user, err = h.userFinder.FindOneByUUID(req.Context(), userUUID)
+		clientKey, err = findClientKey(req.Context(), h.accessService, h.manager, h.keyProvider, userUUID)
 		if err != nil {
 			h.log.Error(err)
 			_ = render.Render(res, req, ErrInternalServerError)
 			return
 		}
 		// Шифруем ответ
-		bodyBytesEncrypt, err = util.DataEncryptAES(mixedResponseWriter.buf.Bytes(), []byte(user.PrivateClientKey))
+		bodyBytesEncrypt, err = util.DataEncryptAES(mixedResponseWriter.buf.Bytes(), clientKey)
 		if err != nil {
 			h.log.Error(err)
 			_ = render.Render(res, req, ErrInternalServerError)
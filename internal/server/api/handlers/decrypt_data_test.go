@@ -2,20 +2,155 @@ package handlers
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/go-chi/render"
+	"github.com/northmule/gophkeeper/internal/common/data_type"
 	"github.com/northmule/gophkeeper/internal/common/models"
 	"github.com/northmule/gophkeeper/internal/common/util"
+	"github.com/northmule/gophkeeper/internal/server/config"
 	"github.com/northmule/gophkeeper/internal/server/logger"
 	appMock "github.com/northmule/gophkeeper/internal/server/repository/mock"
+	"github.com/northmule/gophkeeper/internal/server/services/kek"
+	"github.com/northmule/gophkeeper/internal/server/storage"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
+func newTestKeyProvider(t *testing.T) *kek.LocalFileProvider {
+	cfg := config.NewConfig()
+	cfg.Value().PathKeys = t.TempDir()
+	provider, err := kek.NewLocalFileProvider(cfg)
+	require.NoError(t, err)
+	return provider
+}
+
+// testNonceStore nonce принятых запросов в памяти вместо таблицы request_nonces
+type testNonceStore struct {
+	values map[string]struct{}
+}
+
+func (s *testNonceStore) Use(_ context.Context, userUUID string, nonce string, _ time.Time, _ time.Time) (bool, error) {
+	if _, ok := s.values[userUUID+":"+nonce]; ok {
+		return false, nil
+	}
+	s.values[userUUID+":"+nonce] = struct{}{}
+	return true, nil
+}
+
+func newTestNonceGuard() *storage.NonceGuard {
+	return storage.NewNonceGuard(time.Minute, &testNonceStore{values: make(map[string]struct{})})
+}
+
+func TestHandleDecryptData_WrappedKey(t *testing.T) {
+	mockRepository := new(appMock.MockManager)
+	mockAccessService := new(appMock.MockAccessService)
+	mockUserRepository := new(appMock.MockUserDataModelRepository)
+	logger, _ := logger.NewLogger("info")
+	keyProvider := newTestKeyProvider(t)
+
+	mockRepository.On("User").Return(mockUserRepository)
+	mockAccessService.On("GetUserUUIDByJWTToken", mock.Anything).Return("userUUID", nil)
+	mockAccessService.On("GetDeviceUUIDByJWTToken", mock.Anything).Return("", errors.New("no device found in claims"))
+
+	privateKey := []byte("0123456789abcdef0123456789abcdef")
+	wrapped, err := keyProvider.Wrap(privateKey, []byte("userUUID"))
+	require.NoError(t, err)
+	user := new(models.User)
+	user.UUID = "userUUID"
+	user.PrivateClientKey = wrapped
+	mockUserRepository.On("FindOneByUUID", mock.Anything, "userUUID").Return(user, nil)
+
+	encryptedData, _ := util.SealRequest([]byte(`"test_data"`), privateKey, "POST", "/decrypt", time.Now())
+	req := httptest.NewRequest("POST", "/decrypt", bytes.NewBuffer(encryptedData))
+	res := httptest.NewRecorder()
+
+	handler := NewDecryptDataHandler(mockAccessService, keyProvider, newTestNonceGuard(), mockRepository, logger)
+	handler.HandleDecryptData(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		assert.Equal(t, `"test_data"`, string(body))
+		res.WriteHeader(http.StatusOK)
+	})).ServeHTTP(res, req)
+	assert.Equal(t, http.StatusOK, res.Code)
+
+	// значение другого пользователя не расшифровывается (uuid входит в AAD)
+	user.UUID = "otherUUID"
+	res = httptest.NewRecorder()
+	req = httptest.NewRequest("POST", "/decrypt", bytes.NewBuffer(encryptedData))
+	handler.HandleDecryptData(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		t.Error("next handler must not be called")
+	})).ServeHTTP(res, req)
+	assert.Equal(t, http.StatusInternalServerError, res.Code)
+}
+
+func TestHandleDecryptData_DeviceKey(t *testing.T) {
+	mockRepository := new(appMock.MockManager)
+	mockAccessService := new(appMock.MockAccessService)
+	mockUserRepository := new(appMock.MockUserDataModelRepository)
+	mockDeviceRepository := new(appMock.MockDeviceModelRepository)
+	logger, _ := logger.NewLogger("info")
+	keyProvider := newTestKeyProvider(t)
+
+	mockRepository.On("User").Return(mockUserRepository)
+	mockRepository.On("Device").Return(mockDeviceRepository)
+	mockAccessService.On("GetUserUUIDByJWTToken", mock.Anything).Return("userUUID", nil)
+	mockAccessService.On("GetDeviceUUIDByJWTToken", mock.Anything).Return("deviceUUID", nil)
+
+	// у пользователя ключ первого устройства, у второго устройства свой ключ
+	userKey, err := keyProvider.Wrap([]byte("0123456789abcdef0123456789abcdef"), []byte("userUUID"))
+	require.NoError(t, err)
+	user := new(models.User)
+	user.UUID = "userUUID"
+	user.PrivateClientKey = userKey
+	mockUserRepository.On("FindOneByUUID", mock.Anything, "userUUID").Return(user, nil)
+	deviceKey := []byte("fedcba9876543210fedcba9876543210")
+	wrapped, err := keyProvider.Wrap(deviceKey, []byte("deviceUUID"))
+	require.NoError(t, err)
+	device := &models.Device{UserUUID: "userUUID", ClientKey: wrapped}
+	device.UUID = "deviceUUID"
+	mockDeviceRepository.On("FindOneByUUID", mock.Anything, "deviceUUID").Return(device, nil)
+
+	handler := NewDecryptDataHandler(mockAccessService, keyProvider, newTestNonceGuard(), mockRepository, logger)
+	next := handler.HandleDecryptData(handler.HandleEncryptData(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		assert.Equal(t, `"test_data"`, string(body))
+		_, _ = res.Write([]byte("response"))
+	})))
+
+	encryptedData, _ := util.SealRequest([]byte(`"test_data"`), deviceKey, "POST", "/decrypt", time.Now())
+	res := httptest.NewRecorder()
+	next.ServeHTTP(res, httptest.NewRequest("POST", "/decrypt", bytes.NewBuffer(encryptedData)))
+	assert.Equal(t, http.StatusOK, res.Code)
+	body, err := util.DataDecryptAES(res.Body.Bytes(), deviceKey)
+	require.NoError(t, err)
+	assert.Equal(t, "response", string(body))
+	mockUserRepository.AssertNotCalled(t, "FindOneByUUID", mock.Anything, mock.Anything)
+
+	// устройство без своего ключа использует ключ пользователя
+	device.ClientKey = ""
+	encryptedData, _ = util.SealRequest([]byte(`"test_data"`), []byte("0123456789abcdef0123456789abcdef"), "POST", "/decrypt", time.Now())
+	res = httptest.NewRecorder()
+	next.ServeHTTP(res, httptest.NewRequest("POST", "/decrypt", bytes.NewBuffer(encryptedData)))
+	assert.Equal(t, http.StatusOK, res.Code)
+
+	// ключ устройства другого пользователя не используется
+	device.ClientKey = wrapped
+	device.UserUUID = "otherUUID"
+	encryptedData, _ = util.SealRequest([]byte(`"test_data"`), deviceKey, "POST", "/decrypt", time.Now())
+	res = httptest.NewRecorder()
+	next.ServeHTTP(res, httptest.NewRequest("POST", "/decrypt", bytes.NewBuffer(encryptedData)))
+	assert.Equal(t, http.StatusBadRequest, res.Code)
+}
+
 func TestHandleDecryptData_SuccessfulDecryption(t *testing.T) {
 	mockRepository := new(appMock.MockManager)
 	mockAccessService := new(appMock.MockAccessService)
@@ -25,6 +160,7 @@ func TestHandleDecryptData_SuccessfulDecryption(t *testing.T) {
 	mockRepository.On("User").Return(mockUserRepository)
 
 	mockAccessService.On("GetUserUUIDByJWTToken", mock.Anything).Return("userUUID", nil)
+	mockAccessService.On("GetDeviceUUIDByJWTToken", mock.Anything).Return("", errors.New("no device found in claims"))
 
 	privateKey := string(make([]byte, 32))
 	user := new(models.User)
@@ -32,14 +168,14 @@ func TestHandleDecryptData_SuccessfulDecryption(t *testing.T) {
 	user.PrivateClientKey = privateKey
 
 	mockUserRepository.On("FindOneByUUID", mock.Anything, "userUUID").Return(user, nil)
-	encryptedData, _ := util.DataEncryptAES([]byte("test_data"), []byte(privateKey))
+	encryptedData, _ := util.SealRequest([]byte("test_data"), []byte(privateKey), "POST", "/decrypt", time.Now())
 
 	req := httptest.NewRequest("POST", "/decrypt", bytes.NewBuffer(encryptedData))
 	req.Header.Set("Authorization", "Bearer valid_token")
 	req.Header.Set("Content-Type", "application/json")
 	res := httptest.NewRecorder()
 
-	handler := NewDecryptDataHandler(mockAccessService, mockRepository, logger)
+	handler := NewDecryptDataHandler(mockAccessService, newTestKeyProvider(t), newTestNonceGuard(), mockRepository, logger)
 	handler.HandleDecryptData(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
 		render.JSON(res, req, "test_data")
 	})).ServeHTTP(res, req)
@@ -47,6 +183,69 @@ func TestHandleDecryptData_SuccessfulDecryption(t *testing.T) {
 	assert.Equal(t, http.StatusOK, res.Code)
 }
 
+func TestHandleDecryptData_ReplayProtection(t *testing.T) {
+	mockRepository := new(appMock.MockManager)
+	mockAccessService := new(appMock.MockAccessService)
+	mockUserRepository := new(appMock.MockUserDataModelRepository)
+	logger, _ := logger.NewLogger("info")
+
+	mockRepository.On("User").Return(mockUserRepository)
+	mockAccessService.On("GetUserUUIDByJWTToken", mock.Anything).Return("userUUID", nil)
+	mockAccessService.On("GetDeviceUUIDByJWTToken", mock.Anything).Return("", errors.New("no device found in claims"))
+
+	privateKey := make([]byte, 32)
+	user := new(models.User)
+	user.UUID = "userUUID"
+	user.PrivateClientKey = string(privateKey)
+	mockUserRepository.On("FindOneByUUID", mock.Anything, "userUUID").Return(user, nil)
+
+	handler := NewDecryptDataHandler(mockAccessService, newTestKeyProvider(t), newTestNonceGuard(), mockRepository, logger)
+	var calls int
+	next := handler.HandleDecryptData(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		calls++
+		res.WriteHeader(http.StatusOK)
+	}))
+	send := func(body []byte, path string) *httptest.ResponseRecorder {
+		res := httptest.NewRecorder()
+		next.ServeHTTP(res, httptest.NewRequest("POST", path, bytes.NewBuffer(body)))
+		return res
+	}
+	appCode := func(res *httptest.ResponseRecorder) int64 {
+		response := new(ErrResponse)
+		require.NoError(t, json.Unmarshal(res.Body.Bytes(), response))
+		return response.AppCode
+	}
+
+	sealed, err := util.SealRequest([]byte("card"), privateKey, "POST", "/api/v1/save_card_data", time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, send(sealed, "/api/v1/save_card_data").Code)
+
+	t.Run("replayed", func(t *testing.T) {
+		res := send(sealed, "/api/v1/save_card_data")
+		assert.Equal(t, http.StatusConflict, res.Code)
+		assert.Equal(t, int64(data_type.AppCodeRequestReplayed), appCode(res))
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		stale, _ := util.SealRequest([]byte("card"), privateKey, "POST", "/api/v1/save_card_data", time.Now().Add(-time.Hour))
+		res := send(stale, "/api/v1/save_card_data")
+		assert.Equal(t, http.StatusBadRequest, res.Code)
+		assert.Equal(t, int64(data_type.AppCodeRequestExpired), appCode(res))
+	})
+
+	t.Run("another path", func(t *testing.T) {
+		fresh, _ := util.SealRequest([]byte("card"), privateKey, "POST", "/api/v1/save_card_data", time.Now())
+		assert.Equal(t, http.StatusBadRequest, send(fresh, "/api/v1/save_text_data").Code)
+	})
+
+	t.Run("without envelope", func(t *testing.T) {
+		plain, _ := util.DataEncryptAES([]byte("card"), privateKey)
+		assert.Equal(t, http.StatusBadRequest, send(plain, "/api/v1/save_card_data").Code)
+	})
+
+	assert.Equal(t, 1, calls)
+}
+
 func TestHandleDecryptData_InvalidJWTToken(t *testing.T) {
 	mockRepository := new(appMock.MockManager)
 	mockAccessService := new(appMock.MockAccessService)
@@ -59,7 +258,7 @@ func TestHandleDecryptData_InvalidJWTToken(t *testing.T) {
 	req.Header.Set("Content-Type", "application/json")
 	res := httptest.NewRecorder()
 
-	handler := NewDecryptDataHandler(mockAccessService, mockRepository, logger)
+	handler := NewDecryptDataHandler(mockAccessService, newTestKeyProvider(t), newTestNonceGuard(), mockRepository, logger)
 	handler.HandleDecryptData(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
 		render.JSON(res, req, "test_data")
 	})).ServeHTTP(res, req)
@@ -74,6 +273,7 @@ func TestHandleDecryptData_NonExistentUserUUID(t *testing.T) {
 	logger, _ := logger.NewLogger("info")
 
 	mockAccessService.On("GetUserUUIDByJWTToken", mock.Anything).Return("non_existent_uuid", nil)
+	mockAccessService.On("GetDeviceUUIDByJWTToken", mock.Anything).Return("", errors.New("no device found in claims"))
 
 	mockRepository.On("User").Return(mockUserRepository)
 	mockUserRepository.On("FindOneByUUID", mock.Anything, "non_existent_uuid").Return(nil, fmt.Errorf("user not found"))
@@ -82,7 +282,7 @@ func TestHandleDecryptData_NonExistentUserUUID(t *testing.T) {
 	req.Header.Set("Authorization", "Bearer valid_token")
 	res := httptest.NewRecorder()
 
-	handler := NewDecryptDataHandler(mockAccessService, mockRepository, logger)
+	handler := NewDecryptDataHandler(mockAccessService, newTestKeyProvider(t), newTestNonceGuard(), mockRepository, logger)
 	handler.HandleDecryptData(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
 		render.JSON(res, req, "test_data")
 	})).ServeHTTP(res, req)
@@ -97,6 +297,7 @@ func TestHandleDecryptData_EmptyRequestBody(t *testing.T) {
 	logger, _ := logger.NewLogger("info")
 
 	mockAccessService.On("GetUserUUIDByJWTToken", mock.Anything).Return("userUUID", nil)
+	mockAccessService.On("GetDeviceUUIDByJWTToken", mock.Anything).Return("", errors.New("no device found in claims"))
 
 	privateKey := string(make([]byte, 32))
 	user := new(models.User)
@@ -110,7 +311,7 @@ func TestHandleDecryptData_EmptyRequestBody(t *testing.T) {
 	req.Header.Set("Authorization", "Bearer valid_token")
 	res := httptest.NewRecorder()
 
-	handler := NewDecryptDataHandler(mockAccessService, mockRepository, logger)
+	handler := NewDecryptDataHandler(mockAccessService, newTestKeyProvider(t), newTestNonceGuard(), mockRepository, logger)
 	handler.HandleDecryptData(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
 		render.JSON(res, req, "test_data")
 	})).ServeHTTP(res, req)
@@ -125,6 +326,7 @@ func TestHandleEncryptData_SuccessfulEncryption(t *testing.T) {
 	logger, _ := logger.NewLogger("info")
 
 	mockAccessService.On("GetUserUUIDByJWTToken", mock.Anything).Return("userUUID", nil)
+	mockAccessService.On("GetDeviceUUIDByJWTToken", mock.Anything).Return("", errors.New("no device found in claims"))
 
 	privateKey := string(make([]byte, 32))
 	user := new(models.User)
@@ -138,7 +340,7 @@ func TestHandleEncryptData_SuccessfulEncryption(t *testing.T) {
 	req.Header.Set("Authorization", "Bearer valid_token")
 	res := httptest.NewRecorder()
 
-	handler := NewDecryptDataHandler(mockAccessService, mockRepository, logger)
+	handler := NewDecryptDataHandler(mockAccessService, newTestKeyProvider(t), newTestNonceGuard(), mockRepository, logger)
 	handler.HandleEncryptData(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
 		res.Write([]byte("test_data"))
 	})).ServeHTTP(res, req)
@@ -164,7 +366,7 @@ func TestHandleEncryptData_InvalidJWTToken(t *testing.T) {
 	req.Header.Set("Authorization", "Bearer invalid_token")
 	res := httptest.NewRecorder()
 
-	handler := NewDecryptDataHandler(mockAccessService, mockRepository, logger)
+	handler := NewDecryptDataHandler(mockAccessService, newTestKeyProvider(t), newTestNonceGuard(), mockRepository, logger)
 	handler.HandleEncryptData(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
 		res.Write([]byte("test_data"))
 	})).ServeHTTP(res, req)
@@ -179,6 +381,7 @@ func TestHandleEncryptData_NonExistentUserUUID(t *testing.T) {
 	logger, _ := logger.NewLogger("info")
 
 	mockAccessService.On("GetUserUUIDByJWTToken", mock.Anything).Return("non_existent_uuid", nil)
+	mockAccessService.On("GetDeviceUUIDByJWTToken", mock.Anything).Return("", errors.New("no device found in claims"))
 
 	mockRepository.On("User").Return(mockUserRepository)
 	mockUserRepository.On("FindOneByUUID", mock.Anything, "non_existent_uuid").Return(nil, fmt.Errorf("user not found"))
@@ -187,7 +390,7 @@ func TestHandleEncryptData_NonExistentUserUUID(t *testing.T) {
 	req.Header.Set("Authorization", "Bearer valid_token")
 	res := httptest.NewRecorder()
 
-	handler := NewDecryptDataHandler(mockAccessService, mockRepository, logger)
+	handler := NewDecryptDataHandler(mockAccessService, newTestKeyProvider(t), newTestNonceGuard(), mockRepository, logger)
 	handler.HandleEncryptData(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
 		res.Write([]byte("test_data"))
 	})).ServeHTTP(res, req)
@@ -0,0 +1,256 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/google/uuid"
+	"github.com/northmule/gophkeeper/internal/common/data_type"
+	"github.com/northmule/gophkeeper/internal/common/model_data"
+	"github.com/northmule/gophkeeper/internal/common/models"
+	"github.com/northmule/gophkeeper/internal/server/logger"
+	"github.com/northmule/gophkeeper/internal/server/repository"
+	"github.com/northmule/gophkeeper/internal/server/storage"
+)
+
+// Устройство заводится при первом входе клиента (заголовки X-Device-UUID и X-Device-Name), его UUID
+// передаётся в токене доступа (claim did) и сохраняется в сессии. На /api/v1/save_public_key устройство
+// регистрирует свой публичный ключ и ключ хранилища, зашифрованный ключом устройства.
+// Отзыв устройства сразу закрывает его сессии, отзывает сертификаты и удаляет его ключ хранилища.
+
+// defaultDeviceName имя устройства, если клиент его не передал
+const defaultDeviceName = "unnamed device"
+
+// DeviceAccess сервис доступа, необходимый для работы с устройствами
+type DeviceAccess interface {
+	UserFinderByJWT
+	GetDeviceUUIDByJWTToken(ctx context.Context) (string, error)
+}
+
+// DeviceHandler устройства пользователя
+type DeviceHandler struct {
+	log           *logger.Logger
+	accessService DeviceAccess
+	manager       repository.Repository
+	session       storage.SessionManager
+}
+
+// NewDeviceHandler конструктор
+func NewDeviceHandler(accessService DeviceAccess, manager repository.Repository, session storage.SessionManager, log *logger.Logger) *DeviceHandler {
+	return &DeviceHandler{
+		accessService: accessService,
+		manager:       manager,
+		session:       session,
+		log:           log,
+	}
+}
+
+type deviceRenameRequest struct {
+	model_data.DeviceRenameRequest
+}
+
+// Bind декодирует json в структуру
+func (dr *deviceRenameRequest) Bind(r *http.Request) error {
+	return nil
+}
+
+type deviceResponse struct {
+	model_data.DeviceResponse
+}
+
+func (dr deviceResponse) Render(res http.ResponseWriter, req *http.Request) error {
+	return nil
+}
+
+type deviceListResponse struct {
+	model_data.DeviceListResponse
+}
+
+func (dr deviceListResponse) Render(res http.ResponseWriter, req *http.Request) error {
+	return nil
+}
+
+// HandleList устройства пользователя, в том числе отозванные
+func (h *DeviceHandler) HandleList(res http.ResponseWriter, req *http.Request) {
+	userUUID, err := h.accessService.GetUserUUIDByJWTToken(req.Context())
+	if err != nil {
+		h.log.Error(err)
+		_ = render.Render(res, req, ErrBadRequest)
+		return
+	}
+	// токены, выпущенные до учёта устройств, устройства не содержат
+	currentUUID, _ := h.accessService.GetDeviceUUIDByJWTToken(req.Context())
+
+	devices, err := h.manager.Device().FindAllByUserUUID(req.Context(), userUUID)
+	if err != nil {
+		h.log.Error(err)
+		_ = render.Render(res, req, ErrInternalServerError)
+		return
+	}
+	response := model_data.DeviceListResponse{Devices: make([]model_data.DeviceResponse, 0, len(devices))}
+	for _, device := range devices {
+		item := newDeviceResponse(&device)
+		item.Current = device.UUID == currentUUID
+		response.Devices = append(response.Devices, item)
+	}
+
+	err = render.Render(res, req, deviceListResponse{DeviceListResponse: response})
+	if err != nil {
+		h.log.Error(err)
+		_ = render.Render(res, req, ErrInternalServerError)
+	}
+}
+
+// HandleCurrent устройство текущей сессии вместе с его ключом хранилища
+func (h *DeviceHandler) HandleCurrent(res http.ResponseWriter, req *http.Request) {
+	userUUID, err := h.accessService.GetUserUUIDByJWTToken(req.Context())
+	if err != nil {
+		h.log.Error(err)
+		_ = render.Render(res, req, ErrBadRequest)
+		return
+	}
+	deviceUUID, err := h.accessService.GetDeviceUUIDByJWTToken(req.Context())
+	if err != nil {
+		h.log.Info(err)
+		_ = render.Render(res, req, ErrNotFound)
+		return
+	}
+	device, err := h.manager.Device().FindOneByUUID(req.Context(), deviceUUID)
+	if err != nil {
+		h.log.Error(err)
+		_ = render.Render(res, req, ErrInternalServerError)
+		return
+	}
+	if device == nil || device.UserUUID != userUUID || !device.IsActive() {
+		h.log.Infof("Device %s of user %s is not found or revoked", deviceUUID, userUUID)
+		_ = render.Render(res, req, ErrNotFound)
+		return
+	}
+
+	response := newDeviceResponse(device)
+	response.Current = true
+	response.VaultKey = device.VaultKey
+	err = render.Render(res, req, deviceResponse{DeviceResponse: response})
+	if err != nil {
+		h.log.Error(err)
+		_ = render.Render(res, req, ErrInternalServerError)
+	}
+}
+
+// HandleRename новое имя устройства
+func (h *DeviceHandler) HandleRename(res http.ResponseWriter, req *http.Request) {
+	userUUID, err := h.accessService.GetUserUUIDByJWTToken(req.Context())
+	if err != nil {
+		h.log.Error(err)
+		_ = render.Render(res, req, ErrBadRequest)
+		return
+	}
+	deviceUUID, ok := deviceUUIDParam(req)
+	if !ok {
+		_ = render.Render(res, req, ErrBadRequest)
+		return
+	}
+	request := new(deviceRenameRequest)
+	if err = render.Bind(req, request); err != nil {
+		h.log.Info(err)
+		_ = render.Render(res, req, ErrBadRequest)
+		return
+	}
+
+	renamed, err := h.manager.Device().Rename(req.Context(), userUUID, deviceUUID, request.Name)
+	if err != nil {
+		h.log.Error(err)
+		_ = render.Render(res, req, ErrInternalServerError)
+		return
+	}
+	if !renamed {
+		_ = render.Render(res, req, ErrNotFound)
+		return
+	}
+	res.WriteHeader(http.StatusOK)
+}
+
+// HandleRevoke отзыв устройства. Сессии устройства закрываются сразу, в том числе текущая
+func (h *DeviceHandler) HandleRevoke(res http.ResponseWriter, req *http.Request) {
+	userUUID, err := h.accessService.GetUserUUIDByJWTToken(req.Context())
+	if err != nil {
+		h.log.Error(err)
+		_ = render.Render(res, req, ErrBadRequest)
+		return
+	}
+	deviceUUID, ok := deviceUUIDParam(req)
+	if !ok {
+		_ = render.Render(res, req, ErrBadRequest)
+		return
+	}
+
+	sessions, err := h.manager.Session().FindAllActiveByUserUUID(req.Context(), userUUID)
+	if err != nil {
+		h.log.Error(err)
+		_ = render.Render(res, req, ErrInternalServerError)
+		return
+	}
+	revoked, err := h.manager.Device().Revoke(req.Context(), userUUID, deviceUUID)
+	if err != nil {
+		h.log.Error(err)
+		_ = render.Render(res, req, ErrInternalServerError)
+		return
+	}
+	if !revoked {
+		_ = render.Render(res, req, ErrNotFound)
+		return
+	}
+	for _, session := range sessions {
+		if session.DeviceUUID == deviceUUID {
+			h.session.Revoke(session.UUID)
+		}
+	}
+
+	h.log.Infof("Device %s of user %s has been revoked", deviceUUID, userUUID)
+	res.WriteHeader(http.StatusOK)
+}
+
+// requestDevice устройство, с которого выполняется вход
+func requestDevice(req *http.Request) *models.Device {
+	device := new(models.Device)
+	if value, err := uuid.Parse(req.Header.Get(data_type.DeviceUUIDHeader)); err == nil {
+		device.UUID = value.String()
+	}
+	name, err := url.QueryUnescape(req.Header.Get(data_type.DeviceNameHeader))
+	if err != nil || !utf8.ValidString(name) {
+		return device
+	}
+	name = strings.TrimSpace(name)
+	if utf8.RuneCountInString(name) > 100 {
+		name = string([]rune(name)[:100])
+	}
+	device.Name = name
+	return device
+}
+
+// deviceUUIDParam UUID устройства из пути запроса
+func deviceUUIDParam(req *http.Request) (string, bool) {
+	value, err := uuid.Parse(chi.URLParam(req, "uuid"))
+	if err != nil {
+		return "", false
+	}
+	return value.String(), true
+}
+
+func newDeviceResponse(device *models.Device) model_data.DeviceResponse {
+	response := model_data.DeviceResponse{
+		UUID:       device.UUID,
+		Name:       device.Name,
+		CreatedAt:  device.CreatedAt.Unix(),
+		LastSeenAt: device.LastSeenAt.Unix(),
+	}
+	if device.RevokedAt != nil {
+		response.RevokedAt = device.RevokedAt.Unix()
+	}
+	return response
+}
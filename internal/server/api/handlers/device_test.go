@@ -0,0 +1,210 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/northmule/gophkeeper/internal/common/data_type"
+	"github.com/northmule/gophkeeper/internal/common/model_data"
+	"github.com/northmule/gophkeeper/internal/common/models"
+	"github.com/northmule/gophkeeper/internal/server/logger"
+	appMock "github.com/northmule/gophkeeper/internal/server/repository/mock"
+	"github.com/northmule/gophkeeper/internal/server/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+const testDeviceUUID = "6f1c2a4e-3b5d-4c7e-8f90-1a2b3c4d5e6f"
+
+func newTestDevice(uuid string, name string) models.Device {
+	device := models.Device{UserUUID: "user-uuid", Name: name, VaultKey: "sealed vault key", CreatedAt: time.Now(), LastSeenAt: time.Now()}
+	device.UUID = uuid
+	return device
+}
+
+// newDeviceRequest запрос с UUID устройства в пути
+func newDeviceRequest(method string, target string, deviceUUID string, body []byte) *http.Request {
+	routeCtx := chi.NewRouteContext()
+	routeCtx.URLParams.Add("uuid", deviceUUID)
+	req := httptest.NewRequest(method, target, bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	return req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, routeCtx))
+}
+
+func TestDeviceHandler_HandleList(t *testing.T) {
+	l, _ := logger.NewLogger("info")
+	mockAccessService := new(appMock.MockAccessService)
+	mockRepository := new(appMock.MockManager)
+	mockDeviceRepository := new(appMock.MockDeviceModelRepository)
+	mockRepository.On("Device").Return(mockDeviceRepository)
+	mockAccessService.On("GetUserUUIDByJWTToken", mock.Anything).Return("user-uuid", nil)
+	mockAccessService.On("GetDeviceUUIDByJWTToken", mock.Anything).Return(testDeviceUUID, nil)
+	revokedAt := time.Now()
+	revoked := newTestDevice("other-device", "phone")
+	revoked.RevokedAt = &revokedAt
+	mockDeviceRepository.On("FindAllByUserUUID", mock.Anything, "user-uuid").Return([]models.Device{newTestDevice(testDeviceUUID, "laptop"), revoked}, nil)
+
+	res := httptest.NewRecorder()
+	NewDeviceHandler(mockAccessService, mockRepository, storage.NewSession(), l).HandleList(res, httptest.NewRequest(http.MethodGet, "/devices", nil))
+
+	require.Equal(t, http.StatusOK, res.Code)
+	response := new(model_data.DeviceListResponse)
+	require.NoError(t, json.Unmarshal(res.Body.Bytes(), response))
+	require.Len(t, response.Devices, 2)
+	assert.True(t, response.Devices[0].Current)
+	assert.False(t, response.Devices[1].Current)
+	assert.Equal(t, revokedAt.Unix(), response.Devices[1].RevokedAt)
+	// ключ хранилища в списке не отдаётся
+	assert.NotContains(t, res.Body.String(), "sealed vault key")
+}
+
+func TestDeviceHandler_HandleCurrent(t *testing.T) {
+	l, _ := logger.NewLogger("info")
+	revokedAt := time.Now()
+	revoked := newTestDevice(testDeviceUUID, "laptop")
+	revoked.RevokedAt = &revokedAt
+	active := newTestDevice(testDeviceUUID, "laptop")
+	foreign := newTestDevice(testDeviceUUID, "laptop")
+	foreign.UserUUID = "other-user"
+
+	tests := []struct {
+		name         string
+		device       *models.Device
+		err          error
+		expectedCode int
+	}{
+		{"ok", &active, nil, http.StatusOK},
+		{"revoked", &revoked, nil, http.StatusNotFound},
+		{"another user", &foreign, nil, http.StatusNotFound},
+		{"not found", nil, nil, http.StatusNotFound},
+		{"repository error", nil, errors.New("db error"), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockAccessService := new(appMock.MockAccessService)
+			mockRepository := new(appMock.MockManager)
+			mockDeviceRepository := new(appMock.MockDeviceModelRepository)
+			mockRepository.On("Device").Return(mockDeviceRepository)
+			mockAccessService.On("GetUserUUIDByJWTToken", mock.Anything).Return("user-uuid", nil)
+			mockAccessService.On("GetDeviceUUIDByJWTToken", mock.Anything).Return(testDeviceUUID, nil)
+			mockDeviceRepository.On("FindOneByUUID", mock.Anything, testDeviceUUID).Return(tt.device, tt.err)
+
+			res := httptest.NewRecorder()
+			NewDeviceHandler(mockAccessService, mockRepository, storage.NewSession(), l).HandleCurrent(res, httptest.NewRequest(http.MethodGet, "/devices/current", nil))
+
+			assert.Equal(t, tt.expectedCode, res.Code)
+			if tt.expectedCode != http.StatusOK {
+				return
+			}
+			response := new(model_data.DeviceResponse)
+			require.NoError(t, json.Unmarshal(res.Body.Bytes(), response))
+			assert.Equal(t, "sealed vault key", response.VaultKey)
+			assert.True(t, response.Current)
+		})
+	}
+}
+
+func TestDeviceHandler_HandleRename(t *testing.T) {
+	l, _ := logger.NewLogger("info")
+	body, _ := json.Marshal(model_data.DeviceRenameRequest{Name: "work laptop"})
+
+	tests := []struct {
+		name         string
+		deviceUUID   string
+		renamed      bool
+		expectedCode int
+	}{
+		{"ok", testDeviceUUID, true, http.StatusOK},
+		{"not found", testDeviceUUID, false, http.StatusNotFound},
+		{"invalid uuid", "laptop", false, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockAccessService := new(appMock.MockAccessService)
+			mockRepository := new(appMock.MockManager)
+			mockDeviceRepository := new(appMock.MockDeviceModelRepository)
+			mockRepository.On("Device").Return(mockDeviceRepository)
+			mockAccessService.On("GetUserUUIDByJWTToken", mock.Anything).Return("user-uuid", nil)
+			mockDeviceRepository.On("Rename", mock.Anything, "user-uuid", testDeviceUUID, "work laptop").Return(tt.renamed, nil)
+
+			res := httptest.NewRecorder()
+			req := newDeviceRequest(http.MethodPost, "/devices/"+tt.deviceUUID+"/rename", tt.deviceUUID, body)
+			NewDeviceHandler(mockAccessService, mockRepository, storage.NewSession(), l).HandleRename(res, req)
+
+			assert.Equal(t, tt.expectedCode, res.Code)
+		})
+	}
+}
+
+func TestDeviceHandler_HandleRevoke(t *testing.T) {
+	l, _ := logger.NewLogger("info")
+
+	t.Run("ok", func(t *testing.T) {
+		mockAccessService := new(appMock.MockAccessService)
+		mockRepository := new(appMock.MockManager)
+		mockDeviceRepository := new(appMock.MockDeviceModelRepository)
+		mockSessionRepository := new(appMock.MockSessionModelRepository)
+		sessionStorage := storage.NewSession()
+		sessionStorage.Add("device-session", "user-uuid", time.Now().Add(time.Hour))
+		sessionStorage.Add("other-session", "user-uuid", time.Now().Add(time.Hour))
+		mockRepository.On("Device").Return(mockDeviceRepository)
+		mockRepository.On("Session").Return(mockSessionRepository)
+		mockAccessService.On("GetUserUUIDByJWTToken", mock.Anything).Return("user-uuid", nil)
+		deviceSession := models.Session{UserUUID: "user-uuid", DeviceUUID: testDeviceUUID}
+		deviceSession.UUID = "device-session"
+		otherSession := models.Session{UserUUID: "user-uuid", DeviceUUID: "other-device"}
+		otherSession.UUID = "other-session"
+		mockSessionRepository.On("FindAllActiveByUserUUID", mock.Anything, "user-uuid").Return([]models.Session{deviceSession, otherSession}, nil)
+		mockDeviceRepository.On("Revoke", mock.Anything, "user-uuid", testDeviceUUID).Return(true, nil)
+
+		res := httptest.NewRecorder()
+		req := newDeviceRequest(http.MethodPost, "/devices/"+testDeviceUUID+"/revoke", testDeviceUUID, nil)
+		NewDeviceHandler(mockAccessService, mockRepository, sessionStorage, l).HandleRevoke(res, req)
+
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.False(t, sessionStorage.IsValid("device-session"))
+		assert.True(t, sessionStorage.IsValid("other-session"))
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		mockAccessService := new(appMock.MockAccessService)
+		mockRepository := new(appMock.MockManager)
+		mockDeviceRepository := new(appMock.MockDeviceModelRepository)
+		mockSessionRepository := new(appMock.MockSessionModelRepository)
+		mockRepository.On("Device").Return(mockDeviceRepository)
+		mockRepository.On("Session").Return(mockSessionRepository)
+		mockAccessService.On("GetUserUUIDByJWTToken", mock.Anything).Return("user-uuid", nil)
+		mockSessionRepository.On("FindAllActiveByUserUUID", mock.Anything, "user-uuid").Return([]models.Session{}, nil)
+		mockDeviceRepository.On("Revoke", mock.Anything, "user-uuid", testDeviceUUID).Return(false, nil)
+
+		res := httptest.NewRecorder()
+		req := newDeviceRequest(http.MethodPost, "/devices/"+testDeviceUUID+"/revoke", testDeviceUUID, nil)
+		NewDeviceHandler(mockAccessService, mockRepository, storage.NewSession(), l).HandleRevoke(res, req)
+
+		assert.Equal(t, http.StatusNotFound, res.Code)
+	})
+}
+
+func TestRequestDevice(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/login", nil)
+	req.Header.Set(data_type.DeviceUUIDHeader, strings.ToUpper(testDeviceUUID))
+	req.Header.Set(data_type.DeviceNameHeader, url.QueryEscape("  "+strings.Repeat("я", 120)+"  "))
+
+	device := requestDevice(req)
+	assert.Equal(t, testDeviceUUID, device.UUID)
+	assert.Equal(t, strings.Repeat("я", 100), device.Name)
+
+	device = requestDevice(httptest.NewRequest(http.MethodPost, "/login", nil))
+	assert.Empty(t, device.UUID)
+	assert.Empty(t, device.Name)
+}
@@ -1,9 +1,13 @@
 package handlers
 
 import (
+	"math"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/go-chi/render"
+	"github.com/northmule/gophkeeper/internal/common/data_type"
 )
 
 type ErrResponse struct {
@@ -13,18 +17,33 @@ type ErrResponse struct {
 	StatusText string `json:"status"`
 	AppCode    int64  `json:"code,omitempty"`
 	ErrorText  string `json:"error,omitempty"`
+	Version    int64  `json:"version,omitempty"`   // текущая версия данных на сервере при конфликте версий
+	Operation  int    `json:"operation,omitempty"` // номер операции пакета /api/v1/batch (с 1), вызвавшей ошибку
+
+	RetryAfter time.Duration `json:"-"` // через сколько можно повторить запрос (заголовок Retry-After)
 }
 
 func (e *ErrResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	if e.RetryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(e.RetryAfter.Seconds()))))
+	}
 	render.Status(r, e.HTTPStatusCode)
 	return nil
 }
 
 var (
-	ErrNotFound            = &ErrResponse{HTTPStatusCode: http.StatusNotFound, StatusText: "Resource not found."}
-	ErrBadRequest          = &ErrResponse{HTTPStatusCode: http.StatusBadRequest, StatusText: "Bad request"}
-	ErrInternalServerError = &ErrResponse{HTTPStatusCode: http.StatusInternalServerError, StatusText: "Internal Server Error"}
-	ErrUnauthorized        = &ErrResponse{HTTPStatusCode: http.StatusUnauthorized, StatusText: "Authentication failed"}
+	ErrNotFound              = &ErrResponse{HTTPStatusCode: http.StatusNotFound, StatusText: "Resource not found."}
+	ErrBadRequest            = &ErrResponse{HTTPStatusCode: http.StatusBadRequest, StatusText: "Bad request"}
+	ErrInternalServerError   = &ErrResponse{HTTPStatusCode: http.StatusInternalServerError, StatusText: "Internal Server Error"}
+	ErrUnauthorized          = &ErrResponse{HTTPStatusCode: http.StatusUnauthorized, StatusText: "Authentication failed"}
+	ErrRequestExpired        = &ErrResponse{HTTPStatusCode: http.StatusBadRequest, StatusText: "Request expired", AppCode: data_type.AppCodeRequestExpired}
+	ErrRequestReplayed       = &ErrResponse{HTTPStatusCode: http.StatusConflict, StatusText: "Request replayed", AppCode: data_type.AppCodeRequestReplayed}
+	ErrIdempotencyKeyReused  = &ErrResponse{HTTPStatusCode: http.StatusUnprocessableEntity, StatusText: "Idempotency key reused", AppCode: data_type.AppCodeIdempotencyKeyReused}
+	ErrIdempotencyInProgress = &ErrResponse{HTTPStatusCode: http.StatusConflict, StatusText: "Request in progress", AppCode: data_type.AppCodeIdempotencyInProgress}
+	ErrVersionRequired       = &ErrResponse{HTTPStatusCode: http.StatusPreconditionRequired, StatusText: "Version required", AppCode: data_type.AppCodeVersionRequired}
+	ErrChunkChecksum         = &ErrResponse{HTTPStatusCode: http.StatusUnprocessableEntity, StatusText: "Chunk checksum mismatch", AppCode: data_type.AppCodeChunkChecksum}
+	ErrFileAssembly          = &ErrResponse{HTTPStatusCode: http.StatusConflict, StatusText: "File assembly failed", AppCode: data_type.AppCodeFileAssembly}
+	ErrRequestTooLarge       = &ErrResponse{HTTPStatusCode: http.StatusRequestEntityTooLarge, StatusText: "Request too large"}
 )
 
 func ErrConflict(err error) render.Renderer {
@@ -36,6 +55,25 @@ func ErrConflict(err error) render.Renderer {
 	}
 }
 
+// ErrVersionConflict данные изменены после чтения, version - текущая версия на сервере
+func ErrVersionConflict(version int64) render.Renderer {
+	return &ErrResponse{
+		HTTPStatusCode: http.StatusConflict,
+		StatusText:     "Version conflict",
+		AppCode:        data_type.AppCodeVersionConflict,
+		Version:        version,
+	}
+}
+
+// ErrTooManyRequests слишком много попыток, повтор через retryAfter
+func ErrTooManyRequests(retryAfter time.Duration) render.Renderer {
+	return &ErrResponse{
+		HTTPStatusCode: http.StatusTooManyRequests,
+		StatusText:     "Too many requests",
+		RetryAfter:     retryAfter,
+	}
+}
+
 func ErrValidation(err error) render.Renderer {
 	return &ErrResponse{
 		Err:            err,
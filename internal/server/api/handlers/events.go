@@ -0,0 +1,190 @@
+package handlers
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/render"
+	"github.com/northmule/gophkeeper/internal/common/model_data"
+	"github.com/northmule/gophkeeper/internal/common/models"
+	"github.com/northmule/gophkeeper/internal/common/util"
+	"github.com/northmule/gophkeeper/internal/server/config"
+	"github.com/northmule/gophkeeper/internal/server/logger"
+	"github.com/northmule/gophkeeper/internal/server/repository"
+	"github.com/northmule/gophkeeper/internal/server/services/kek"
+)
+
+const (
+	// eventsHeartbeat интервал комментария-пинга, чтобы прокси не закрывали соединение без событий
+	eventsHeartbeat = 25 * time.Second
+	// eventsMaxAge время жизни потока, если не задано время жизни токена доступа
+	eventsMaxAge = 15 * time.Minute
+)
+
+// EventsAccess сервис доступа потока событий: ключ устройства, сессия и срок токена
+type EventsAccess interface {
+	DeviceAccess
+	GetSessionUUIDByJWTToken(ctx context.Context) (string, error)
+	GetTokenExpirationByJWTToken(ctx context.Context) (time.Time, error)
+}
+
+// EventSubscriber подписка на события пользователя
+type EventSubscriber interface {
+	Subscribe(userUUID string) (<-chan models.Event, func())
+}
+
+// EventsHandler поток событий изменения данных пользователя (server-sent events)
+type EventsHandler struct {
+	log           *logger.Logger
+	accessService EventsAccess
+	keyProvider   kek.KeyProvider
+	events        EventSubscriber
+	manager       repository.Repository
+	heartbeat     time.Duration
+	maxAge        time.Duration
+}
+
+// NewEventsHandler конструктор
+func NewEventsHandler(accessService EventsAccess, keyProvider kek.KeyProvider, events EventSubscriber, manager repository.Repository, cfg *config.Config, log *logger.Logger) *EventsHandler {
+	maxAge := cfg.Value().JWTTTL
+	if maxAge <= 0 {
+		maxAge = eventsMaxAge
+	}
+	return &EventsHandler{
+		log:           log,
+		accessService: accessService,
+		keyProvider:   keyProvider,
+		events:        events,
+		manager:       manager,
+		heartbeat:     eventsHeartbeat,
+		maxAge:        maxAge,
+	}
+}
+
+// HandleEvents поток событий. Данные события шифруются ключом клиента (base64 в поле data).
+// Поток закрывается после key_rotated, по истечении токена доступа (exp) и после отзыва сессии
+// (проверяется при каждом пинге), клиент переподключается с новым ключом или токеном
+func (h *EventsHandler) HandleEvents(res http.ResponseWriter, req *http.Request) {
+	userUUID, err := h.accessService.GetUserUUIDByJWTToken(req.Context())
+	if err != nil {
+		h.log.Error(err)
+		_ = render.Render(res, req, ErrBadRequest)
+		return
+	}
+	sessionUUID, err := h.accessService.GetSessionUUIDByJWTToken(req.Context())
+	if err != nil {
+		h.log.Info(err)
+		_ = render.Render(res, req, ErrUnauthorized)
+		return
+	}
+	expiresAt, err := h.accessService.GetTokenExpirationByJWTToken(req.Context())
+	if err != nil {
+		h.log.Info(err)
+		_ = render.Render(res, req, ErrUnauthorized)
+		return
+	}
+	maxAge := min(time.Until(expiresAt), h.maxAge)
+	if maxAge <= 0 {
+		h.log.Infof("Access token of session %s is expired", sessionUUID)
+		_ = render.Render(res, req, ErrUnauthorized)
+		return
+	}
+	clientKey, err := findClientKey(req.Context(), h.accessService, h.manager, h.keyProvider, userUUID)
+	if errors.Is(err, errNoClientKey) {
+		h.log.Infof("User %s has no client key", userUUID)
+		_ = render.Render(res, req, ErrNotFound)
+		return
+	}
+	if err != nil {
+		h.log.Error(err)
+		_ = render.Render(res, req, ErrInternalServerError)
+		return
+	}
+	flusher, ok := res.(http.Flusher)
+	if !ok {
+		h.log.Error("streaming is not supported by the response writer")
+		_ = render.Render(res, req, ErrInternalServerError)
+		return
+	}
+
+	events, unsubscribe := h.events.Subscribe(userUUID)
+	defer unsubscribe()
+
+	res.Header().Set("Content-Type", "text/event-stream")
+	res.Header().Set("Cache-Control", "no-cache")
+	res.Header().Set("X-Accel-Buffering", "no")
+	res.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(h.heartbeat)
+	defer heartbeat.Stop()
+	expire := time.NewTimer(maxAge)
+	defer expire.Stop()
+	for {
+		select {
+		case <-req.Context().Done():
+			return
+		case <-expire.C:
+			return
+		case <-heartbeat.C:
+			var active bool
+			active, err = h.sessionActive(req.Context(), sessionUUID)
+			if err == nil && !active {
+				h.log.Infof("Session %s is revoked, the event stream is closed", sessionUUID)
+				return
+			}
+			if err == nil {
+				_, err = io.WriteString(res, ": ping\n\n")
+			}
+		case event, ok := <-events:
+			if !ok { // подписка закрыта сервером, клиент переподключается и синхронизируется
+				return
+			}
+			err = h.write(res, event, clientKey)
+			if err == nil && event.Type == models.EventKeyRotated {
+				flusher.Flush()
+				return
+			}
+		}
+		if err != nil {
+			h.log.Info(err)
+			return
+		}
+		flusher.Flush()
+	}
+}
+
+// sessionActive сессия потока не отозвана и не истекла. Проверяется по таблице sessions:
+// сессия могла быть отозвана на другом экземпляре сервера
+func (h *EventsHandler) sessionActive(ctx context.Context, sessionUUID string) (bool, error) {
+	session, err := h.manager.Session().FindOneByUUID(ctx, sessionUUID)
+	if err != nil {
+		return false, err
+	}
+	return session != nil && session.IsActive(), nil
+}
+
+// write событие в формате server-sent events
+func (h *EventsHandler) write(res io.Writer, event models.Event, clientKey []byte) error {
+	body, err := json.Marshal(model_data.EventResponse{
+		Type:     event.Type,
+		DataType: event.DataType,
+		DataUUID: event.DataUUID,
+		Revision: event.Revision,
+	})
+	if err != nil {
+		return err
+	}
+	encrypted, err := util.DataEncryptAES(body, clientKey)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(res, "data: %s\n\n", base64.StdEncoding.EncodeToString(encrypted))
+	return err
+}
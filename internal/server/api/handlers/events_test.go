@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/northmule/gophkeeper/internal/common/model_data"
+	"github.com/northmule/gophkeeper/internal/common/models"
+	"github.com/northmule/gophkeeper/internal/common/util"
+	"github.com/northmule/gophkeeper/internal/server/config"
+	"github.com/northmule/gophkeeper/internal/server/logger"
+	appMock "github.com/northmule/gophkeeper/internal/server/repository/mock"
+	"github.com/northmule/gophkeeper/internal/server/services/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleEvents(t *testing.T) {
+	mockRepository := new(appMock.MockManager)
+	mockAccessService := new(appMock.MockAccessService)
+	mockDeviceRepository := new(appMock.MockDeviceModelRepository)
+	log, _ := logger.NewLogger("info")
+	keyProvider := newTestKeyProvider(t)
+
+	// события шифруются ключом устройства из токена
+	clientKey := make([]byte, 32)
+	wrapped, err := keyProvider.Wrap(clientKey, []byte("device123"))
+	require.NoError(t, err)
+	mockAccessService.On("GetUserUUIDByJWTToken", mock.Anything).Return("userUUID", nil)
+	mockAccessService.On("GetDeviceUUIDByJWTToken", mock.Anything).Return("device123", nil)
+	mockAccessService.On("GetSessionUUIDByJWTToken", mock.Anything).Return("session123", nil)
+	mockAccessService.On("GetTokenExpirationByJWTToken", mock.Anything).Return(time.Now().Add(time.Hour), nil)
+	mockRepository.On("Device").Return(mockDeviceRepository)
+	mockDeviceRepository.On("FindOneByUUID", mock.Anything, "device123").Return(newKeyDevice("device123", "userUUID", wrapped), nil)
+
+	hub := events.NewHub()
+	handler := NewEventsHandler(mockAccessService, keyProvider, hub, mockRepository, config.NewConfig(), log)
+	server := httptest.NewServer(http.HandlerFunc(handler.HandleEvents))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	require.NoError(t, err)
+	response, err := http.DefaultClient.Do(request)
+	require.NoError(t, err)
+	defer response.Body.Close()
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Equal(t, "text/event-stream", response.Header.Get("Content-Type"))
+
+	// подписка создаётся до ответа
+	hub.Publish(models.Event{Type: models.EventItemUpdated, UserUUID: "other", DataUUID: "other-data"})
+	hub.Publish(models.Event{Type: models.EventItemUpdated, UserUUID: "userUUID", DataType: "card", DataUUID: "data-1", Revision: 5})
+	hub.Publish(models.Event{Type: models.EventKeyRotated, UserUUID: "userUUID"})
+
+	var received []model_data.EventResponse
+	scanner := bufio.NewScanner(response.Body)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		encrypted, err := base64.StdEncoding.DecodeString(data)
+		require.NoError(t, err)
+		body, err := util.DataDecryptAES(encrypted, clientKey)
+		require.NoError(t, err)
+		var event model_data.EventResponse
+		require.NoError(t, json.Unmarshal(body, &event))
+		received = append(received, event)
+	}
+	// после key_rotated поток закрывается
+	assert.Equal(t, []model_data.EventResponse{
+		{Type: models.EventItemUpdated, DataType: "card", DataUUID: "data-1", Revision: 5},
+		{Type: models.EventKeyRotated},
+	}, received)
+}
+
+func TestHandleEvents_InvalidJWTToken(t *testing.T) {
+	mockAccessService := new(appMock.MockAccessService)
+	log, _ := logger.NewLogger("info")
+	mockAccessService.On("GetUserUUIDByJWTToken", mock.Anything).Return("", assert.AnError)
+
+	handler := NewEventsHandler(mockAccessService, newTestKeyProvider(t), events.NewHub(), new(appMock.MockManager), config.NewConfig(), log)
+	res := httptest.NewRecorder()
+	handler.HandleEvents(res, httptest.NewRequest(http.MethodGet, "/api/v1/events", nil))
+	assert.Equal(t, http.StatusBadRequest, res.Code)
+}
+
+// newEventsStreamHandler поток событий сессии session123, токен которой истекает в expiresAt
+func newEventsStreamHandler(t *testing.T, mockRepository *appMock.MockManager, expiresAt time.Time) *EventsHandler {
+	mockAccessService := new(appMock.MockAccessService)
+	mockDeviceRepository := new(appMock.MockDeviceModelRepository)
+	log, _ := logger.NewLogger("info")
+	keyProvider := newTestKeyProvider(t)
+	wrapped, err := keyProvider.Wrap(make([]byte, 32), []byte("device123"))
+	require.NoError(t, err)
+	mockAccessService.On("GetUserUUIDByJWTToken", mock.Anything).Return("userUUID", nil)
+	mockAccessService.On("GetDeviceUUIDByJWTToken", mock.Anything).Return("device123", nil)
+	mockAccessService.On("GetSessionUUIDByJWTToken", mock.Anything).Return("session123", nil)
+	mockAccessService.On("GetTokenExpirationByJWTToken", mock.Anything).Return(expiresAt, nil)
+	mockRepository.On("Device").Return(mockDeviceRepository)
+	mockDeviceRepository.On("FindOneByUUID", mock.Anything, "device123").Return(newKeyDevice("device123", "userUUID", wrapped), nil)
+	return NewEventsHandler(mockAccessService, keyProvider, events.NewHub(), mockRepository, config.NewConfig(), log)
+}
+
+func TestHandleEvents_TokenExpired(t *testing.T) {
+	t.Run("closed_at_exp", func(t *testing.T) {
+		// поток живёт до exp токена, а не полное время жизни токена от подключения
+		handler := newEventsStreamHandler(t, new(appMock.MockManager), time.Now().Add(50*time.Millisecond))
+		res := httptest.NewRecorder()
+		done := make(chan struct{})
+		go func() {
+			handler.HandleEvents(res, httptest.NewRequest(http.MethodGet, "/api/v1/events", nil))
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("event stream is not closed at token expiration")
+		}
+		assert.Equal(t, http.StatusOK, res.Code)
+	})
+
+	t.Run("already_expired", func(t *testing.T) {
+		handler := newEventsStreamHandler(t, new(appMock.MockManager), time.Now().Add(-time.Second))
+		res := httptest.NewRecorder()
+		handler.HandleEvents(res, httptest.NewRequest(http.MethodGet, "/api/v1/events", nil))
+		assert.Equal(t, http.StatusUnauthorized, res.Code)
+	})
+}
+
+func TestHandleEvents_SessionRevoked(t *testing.T) {
+	mockRepository := new(appMock.MockManager)
+	mockSessionRepository := new(appMock.MockSessionModelRepository)
+	mockRepository.On("Session").Return(mockSessionRepository)
+	revokedAt := time.Now()
+	mockSessionRepository.On("FindOneByUUID", mock.Anything, "session123").Return(&models.Session{
+		UserUUID:  "userUUID",
+		ExpiresAt: time.Now().Add(time.Hour),
+		RevokedAt: &revokedAt,
+	}, nil)
+	handler := newEventsStreamHandler(t, mockRepository, time.Now().Add(time.Hour))
+	handler.heartbeat = 10 * time.Millisecond
+
+	res := httptest.NewRecorder()
+	done := make(chan struct{})
+	go func() {
+		handler.HandleEvents(res, httptest.NewRequest(http.MethodGet, "/api/v1/events", nil))
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("event stream of a revoked session is not closed")
+	}
+	// отозванной сессии пинг не отправляется
+	assert.NotContains(t, res.Body.String(), ": ping")
+	mockSessionRepository.AssertCalled(t, "FindOneByUUID", mock.Anything, "session123")
+}
@@ -1,10 +1,13 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"os"
 	"path"
+	"strconv"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/render"
@@ -12,41 +15,32 @@ import (
 	"github.com/northmule/gophkeeper/internal/common/data_type"
 	"github.com/northmule/gophkeeper/internal/common/model_data"
 	"github.com/northmule/gophkeeper/internal/common/models"
+	"github.com/northmule/gophkeeper/internal/common/util"
 	"github.com/northmule/gophkeeper/internal/server/config"
 	"github.com/northmule/gophkeeper/internal/server/logger"
-	"golang.org/x/net/context"
+	"github.com/northmule/gophkeeper/internal/server/repository"
+	"github.com/northmule/gophkeeper/internal/server/storage"
 )
 
 // FileDataHandler обработка запросо на сохранение файлов
 type FileDataHandler struct {
-	log             *logger.Logger
-	userFinderByJWT UserFinderByJWT
-	fileDataCRUD    FileDataCRUD
-	ownerCRUD       OwnerCRUD
-	metaDataCRUD    MetaDataCRUD
-	cfg             *config.Config
+	log           *logger.Logger
+	accessService UserFinderByJWT
+	manager       repository.Repository
+	cfg           *config.Config
 }
 
 // NewFileDataHandler конструктор
-func NewFileDataHandler(userFinderByJWT UserFinderByJWT, fileDataCRUD FileDataCRUD, ownerCRUD OwnerCRUD, metaDataCRUD MetaDataCRUD, cfg *config.Config, log *logger.Logger) *FileDataHandler {
+func NewFileDataHandler(accessService UserFinderByJWT, manager repository.Repository, cfg *config.Config, log *logger.Logger) *FileDataHandler {
 
 	return &FileDataHandler{
-		userFinderByJWT: userFinderByJWT,
-		log:             log,
-		fileDataCRUD:    fileDataCRUD,
-		ownerCRUD:       ownerCRUD,
-		metaDataCRUD:    metaDataCRUD,
-		cfg:             cfg,
+		accessService: accessService,
+		log:           log,
+		manager:       manager,
+		cfg:           cfg,
 	}
 }
 
-// FileDataCRUD операции над данными
-type FileDataCRUD interface {
-	FindOneByUUID(ctx context.Context, uuid string) (*models.FileData, error)
-	Add(ctx context.Context, data *models.FileData) (int64, error)
-	Update(ctx context.Context, data *models.FileData) error
-}
-
 // Запрос инициализации загрузки файла (основная информация о файле)
 type fileDataInitRequest struct {
 	model_data.FileDataInitRequest
@@ -54,7 +48,13 @@ type fileDataInitRequest struct {
 
 // Ответ на данные инициализации
 type fileDataInitResponse struct {
-	UploadPath string `json:"upload_path"` // адрес для зарузки файла post-ом
+	UploadPath string `json:"upload_path"` // адрес для загрузки частей файла post-ом: upload_path/{номер части}
+	StatusPath string `json:"status_path"` // адрес состояния загрузки (принятые части)
+}
+
+// Ответ о состоянии загрузки файла
+type fileDataStatusResponse struct {
+	model_data.FileDataStatusResponse
 }
 
 // Bind декодирует json в структуру
@@ -67,6 +67,11 @@ func (hr fileDataInitResponse) Render(res http.ResponseWriter, req *http.Request
 	return nil
 }
 
+// Render рисует json ответ в структуре
+func (hr fileDataStatusResponse) Render(res http.ResponseWriter, req *http.Request) error {
+	return nil
+}
+
 // HandleInit инициализация загрузки
 func (h *FileDataHandler) HandleInit(res http.ResponseWriter, req *http.Request) {
 	var (
@@ -83,7 +88,7 @@ func (h *FileDataHandler) HandleInit(res http.ResponseWriter, req *http.Request)
 		_ = render.Render(res, req, ErrBadRequest)
 		return
 	}
-	userUUID, err = h.userFinderByJWT.GetUserUUIDByJWTToken(req.Context())
+	userUUID, err = h.accessService.GetUserUUIDByJWTToken(req.Context())
 	if err != nil {
 		h.log.Error(err)
 		_ = render.Render(res, req, ErrBadRequest)
@@ -92,8 +97,13 @@ func (h *FileDataHandler) HandleInit(res http.ResponseWriter, req *http.Request)
 
 	if request.UUID != "" { // редактирование
 		dataUUID = request.UUID
+		if request.Version == 0 { // без версии изменение перезапишет чужие правки
+			h.log.Infof("version required: data_uuid: %s", dataUUID)
+			_ = render.Render(res, req, ErrVersionRequired)
+			return
+		}
 		// владелец данных
-		owner, err = h.ownerCRUD.FindOneByUserUUIDAndDataUUIDAndDataType(req.Context(), userUUID, dataUUID, data_type.BinaryType)
+		owner, err = h.manager.Owner().FindOneByUserUUIDAndDataUUIDAndDataType(req.Context(), userUUID, dataUUID, data_type.BinaryType)
 		if err != nil {
 			h.log.Error(err)
 			_ = render.Render(res, req, ErrBadRequest)
@@ -104,7 +114,7 @@ func (h *FileDataHandler) HandleInit(res http.ResponseWriter, req *http.Request)
 			_ = render.Render(res, req, ErrNotFound)
 			return
 		}
-		fileData, err = h.fileDataCRUD.FindOneByUUID(req.Context(), dataUUID)
+		fileData, err = h.manager.FileData().FindOneByUUID(req.Context(), dataUUID)
 		if err != nil {
 			h.log.Error(err)
 			_ = render.Render(res, req, ErrBadRequest)
@@ -115,19 +125,53 @@ func (h *FileDataHandler) HandleInit(res http.ResponseWriter, req *http.Request)
 			_ = render.Render(res, req, ErrNotFound)
 			return
 		}
-		// основные данные
+		if fileData.Version != request.Version {
+			h.log.Infof("version conflict: data_uuid: %s, version: %d, current: %d", dataUUID, request.Version, fileData.Version)
+			_ = render.Render(res, req, ErrVersionConflict(fileData.Version))
+			return
+		}
+		// файл, сохранённый под прежним именем от клиента, переносится до смены имени
+		err = moveLegacyFile(fileData)
+		if err != nil {
+			h.log.Error(err)
+			_ = render.Render(res, req, ErrInternalServerError)
+			return
+		}
+		// основные данные, имя файла от клиента хранится только в данных и не попадает в путь
 		fileData.Name = request.Name
 		fileData.FileName = request.FileName
 		fileData.Size = request.Size
 		fileData.Extension = request.Extension
 		fileData.MimeType = request.MimeType
+		if request.Chunks > 0 { // новое содержимое загружается заново, до сборки файл недоступен
+			fileData.Chunks = request.Chunks
+			fileData.Uploaded = false
+			err = h.manager.FileChunk().DeleteByFileUUID(req.Context(), dataUUID)
+			if err != nil {
+				h.log.Error(err)
+				_ = render.Render(res, req, ErrInternalServerError)
+				return
+			}
+		}
 
-		err = h.fileDataCRUD.Update(req.Context(), fileData)
+		err = h.manager.FileData().Update(req.Context(), fileData)
+		if errors.Is(err, repository.ErrVersionConflict) { // изменены параллельным запросом
+			h.log.Infof("version conflict: data_uuid: %s, version: %d", dataUUID, request.Version)
+			fileData, err = h.manager.FileData().FindOneByUUID(req.Context(), dataUUID)
+			if err != nil {
+				h.log.Error(err)
+				_ = render.Render(res, req, ErrInternalServerError)
+				return
+			}
+			_ = render.Render(res, req, ErrVersionConflict(fileData.Version))
+			return
+		}
 		if err != nil {
 			h.log.Error(err)
 			_ = render.Render(res, req, ErrInternalServerError)
 			return
 		}
+		res.Header().Set("ETag", versionETag(fileData.Version))
 
 		// мета поля
 		var newMeta []models.MetaData
@@ -141,7 +185,7 @@ func (h *FileDataHandler) HandleInit(res http.ResponseWriter, req *http.Request)
 			}
 		}
 		// перезапись мета
-		err = h.metaDataCRUD.ReplaceMetaByDataUUID(req.Context(), dataUUID, newMeta)
+		err = h.manager.MetaData().ReplaceMetaByDataUUID(req.Context(), dataUUID, newMeta)
 		if err != nil {
 			h.log.Error(err)
 			_ = render.Render(res, req, ErrInternalServerError)
@@ -150,6 +194,11 @@ func (h *FileDataHandler) HandleInit(res http.ResponseWriter, req *http.Request)
 	}
 
 	if request.UUID == "" { // новые данные
+		if request.Chunks == 0 { // без содержимого файл не создаётся
+			h.log.Info("file data without chunks")
+			_ = render.Render(res, req, ErrBadRequest)
+			return
+		}
 		dataUUID = uuid.NewString()
 
 		fileData = new(models.FileData)
@@ -160,21 +209,25 @@ func (h *FileDataHandler) HandleInit(res http.ResponseWriter, req *http.Request)
 		fileData.Extension = request.Extension
 
 		fileData.UUID = dataUUID
-		fileData.PathTmp = os.TempDir() + "/" + dataUUID
 		fileBaseDir := os.TempDir()
 		if h.cfg.Value().PathFileStorage != "" {
 			fileBaseDir = h.cfg.Value().PathFileStorage
 		}
+		// части хранятся рядом с файлами: после перезапуска сервера загрузку можно продолжить
+		fileData.PathTmp = fileBaseDir + "/chunks_" + dataUUID
 		fileData.Path = fileBaseDir + "/load_" + dataUUID
 		fileData.Storage = "local://" // todo в настройки (сейчас не используется)
 		fileData.Uploaded = false
+		fileData.Chunks = request.Chunks
 
-		_, err = h.fileDataCRUD.Add(req.Context(), fileData)
+		_, err = h.manager.FileData().Add(req.Context(), fileData)
 		if err != nil {
 			h.log.Error(err)
 			_ = render.Render(res, req, ErrInternalServerError)
 			return
 		}
+		fileData.Version = 1 // версия новых данных по умолчанию
+		res.Header().Set("ETag", versionETag(fileData.Version))
 
 		// владелец данных
 		owner = new(models.Owner)
@@ -182,7 +235,7 @@ func (h *FileDataHandler) HandleInit(res http.ResponseWriter, req *http.Request)
 		owner.DataType = data_type.BinaryType
 		owner.DataUUID = dataUUID
 
-		_, err = h.ownerCRUD.Add(req.Context(), owner)
+		_, err = h.manager.Owner().Add(req.Context(), owner)
 		if err != nil {
 			h.log.Error(err)
 			_ = render.Render(res, req, ErrInternalServerError)
@@ -196,7 +249,7 @@ func (h *FileDataHandler) HandleInit(res http.ResponseWriter, req *http.Request)
 				metaData.MetaName = key
 				metaData.MetaValue.Value = value
 				metaData.DataUUID = dataUUID
-				_, err = h.metaDataCRUD.Add(req.Context(), metaData)
+				_, err = h.manager.MetaData().Add(req.Context(), metaData)
 				if err != nil {
 					h.log.Error(err)
 					_ = render.Render(res, req, ErrInternalServerError)
@@ -206,7 +259,8 @@ func (h *FileDataHandler) HandleInit(res http.ResponseWriter, req *http.Request)
 		}
 	}
 
-	initResponse := fileDataInitResponse{UploadPath: "/file_data/load/" + dataUUID + "/0"}
+	auditData(req, dataUUID)
+	initResponse := fileDataInitResponse{UploadPath: "/file_data/load/" + dataUUID, StatusPath: "/file_data/status/" + dataUUID}
 	err = render.Render(res, req, initResponse)
 	if err != nil {
 		h.log.Error(err)
@@ -215,21 +269,22 @@ func (h *FileDataHandler) HandleInit(res http.ResponseWriter, req *http.Request)
 
 }
 
-// HandleAction принимает содержимое файла отправляемое клиентом
+// HandleAction принимает часть {part} содержимого файла. Части одного файла принимаются по очереди,
+// после последней части файл собирается. В ответе состояние загрузки
 func (h *FileDataHandler) HandleAction(res http.ResponseWriter, req *http.Request) {
 	var (
 		err         error
 		userUUID    string
 		dataUUID    string
-		pathPart    string
+		part        int
 		owner       *models.Owner
+		status      *model_data.FileDataStatusResponse
 		errResponse *ErrResponse
 	)
 
 	dataUUID = chi.URLParam(req, "file_uuid")
-	pathPart = chi.URLParam(req, "part")
 
-	userUUID, err = h.userFinderByJWT.GetUserUUIDByJWTToken(req.Context())
+	userUUID, err = h.accessService.GetUserUUIDByJWTToken(req.Context())
 	if err != nil {
 		h.log.Error(err)
 		_ = render.Render(res, req, ErrBadRequest)
@@ -237,7 +292,7 @@ func (h *FileDataHandler) HandleAction(res http.ResponseWriter, req *http.Reques
 	}
 
 	// владелец данных
-	owner, err = h.ownerCRUD.FindOneByUserUUIDAndDataUUIDAndDataType(req.Context(), userUUID, dataUUID, data_type.BinaryType)
+	owner, err = h.manager.Owner().FindOneByUserUUIDAndDataUUIDAndDataType(req.Context(), userUUID, dataUUID, data_type.BinaryType)
 	if err != nil {
 		h.log.Error(err)
 		_ = render.Render(res, req, ErrBadRequest)
@@ -249,14 +304,66 @@ func (h *FileDataHandler) HandleAction(res http.ResponseWriter, req *http.Reques
 		return
 	}
 
-	errResponse = h.loadFile(req, dataUUID)
+	part, err = strconv.Atoi(chi.URLParam(req, "part"))
+	if err != nil {
+		h.log.Info(err)
+		_ = render.Render(res, req, ErrBadRequest)
+		return
+	}
 
+	status, errResponse = h.loadFile(res, req, dataUUID, part)
 	if errResponse != nil {
-		h.log.Error(err)
 		_ = render.Render(res, req, errResponse)
 		return
 	}
-	_ = pathPart
+	err = render.Render(res, req, fileDataStatusResponse{FileDataStatusResponse: *status})
+	if err != nil {
+		h.log.Error(err)
+		_ = render.Render(res, req, ErrInternalServerError)
+	}
+}
+
+// HandleStatus состояние загрузки файла: принятые части, по нему клиент продолжает прерванную загрузку
+func (h *FileDataHandler) HandleStatus(res http.ResponseWriter, req *http.Request) {
+	dataUUID := chi.URLParam(req, "file_uuid")
+
+	userUUID, err := h.accessService.GetUserUUIDByJWTToken(req.Context())
+	if err != nil {
+		h.log.Error(err)
+		_ = render.Render(res, req, ErrBadRequest)
+		return
+	}
+	owner, err := h.manager.Owner().FindOneByUserUUIDAndDataUUIDAndDataType(req.Context(), userUUID, dataUUID, data_type.BinaryType)
+	if err != nil {
+		h.log.Error(err)
+		_ = render.Render(res, req, ErrBadRequest)
+		return
+	}
+	if owner == nil { // нет данных этого пользователя
+		h.log.Infof("owner not found: data_uuid: %s, user_uuid: %s, data_type: %s", dataUUID, userUUID, data_type.BinaryType)
+		_ = render.Render(res, req, ErrNotFound)
+		return
+	}
+	fileData, err := h.manager.FileData().FindOneByUUID(req.Context(), dataUUID)
+	if err != nil {
+		h.log.Error(err)
+		_ = render.Render(res, req, ErrInternalServerError)
+		return
+	}
+	chunks, err := h.manager.FileChunk().FindAllByFileUUID(req.Context(), dataUUID)
+	if err != nil {
+		h.log.Error(err)
+		_ = render.Render(res, req, ErrInternalServerError)
+		return
+	}
+	if !fileData.Uploaded {
+		chunks = storedChunks(fileData, chunks)
+	}
+	err = render.Render(res, req, fileDataStatusResponse{FileDataStatusResponse: *uploadStatus(fileData, chunks)})
+	if err != nil {
+		h.log.Error(err)
+		_ = render.Render(res, req, ErrInternalServerError)
+	}
 }
 
 // HandleGetAction отдаёт клиенту файл
@@ -274,7 +381,7 @@ func (h *FileDataHandler) HandleGetAction(res http.ResponseWriter, req *http.Req
 	dataUUID = chi.URLParam(req, "file_uuid")
 	pathPart = chi.URLParam(req, "part")
 
-	userUUID, err = h.userFinderByJWT.GetUserUUIDByJWTToken(req.Context())
+	userUUID, err = h.accessService.GetUserUUIDByJWTToken(req.Context())
 	if err != nil {
 		h.log.Error(err)
 		_ = render.Render(res, req, ErrBadRequest)
@@ -282,7 +389,7 @@ func (h *FileDataHandler) HandleGetAction(res http.ResponseWriter, req *http.Req
 	}
 
 	// владелец данных
-	owner, err = h.ownerCRUD.FindOneByUserUUIDAndDataUUIDAndDataType(req.Context(), userUUID, dataUUID, data_type.BinaryType)
+	owner, err = h.manager.Owner().FindOneByUserUUIDAndDataUUIDAndDataType(req.Context(), userUUID, dataUUID, data_type.BinaryType)
 	if err != nil {
 		h.log.Error(err)
 		_ = render.Render(res, req, ErrBadRequest)
@@ -304,27 +411,39 @@ func (h *FileDataHandler) HandleGetAction(res http.ResponseWriter, req *http.Req
 	_ = pathPart
 }
 
-// downLoadFile отдача файла клиенту по запросу
+// downLoadFile отдача файла клиенту по запросу. Файл, загруженный до частей, отдаётся одной частью
 func (h *FileDataHandler) downLoadFile(res http.ResponseWriter, req *http.Request, dataUUID string) *ErrResponse {
-	var (
-		err      error
-		fileData *models.FileData
-		file     *os.File
-	)
-	fileData, err = h.fileDataCRUD.FindOneByUUID(req.Context(), dataUUID)
+	fileData, err := h.manager.FileData().FindOneByUUID(req.Context(), dataUUID)
 	if err != nil {
 		h.log.Error(err)
 		return ErrInternalServerError
 	}
+	if !fileData.Uploaded { // части ещё загружаются
+		h.log.Infof("file is not uploaded: uuid %s", dataUUID)
+		return ErrNotFound
+	}
 
-	buff, err := os.ReadFile(path.Join(fileData.Path, fileData.FileName))
+	if fileData.Chunks == 0 {
+		buff, err := os.ReadFile(existingFilePath(fileData))
+		if err != nil {
+			h.log.Error(err)
+			return ErrInternalServerError
+		}
+		err = util.WriteChunkFrame(res, buff)
+		if err != nil {
+			h.log.Error(err)
+			return ErrInternalServerError
+		}
+		return nil
+	}
+
+	file, err := os.Open(existingFilePath(fileData))
 	if err != nil {
 		h.log.Error(err)
 		return ErrInternalServerError
 	}
 	defer file.Close()
-
-	_, err = res.Write(buff)
+	_, err = io.Copy(res, file)
 	if err != nil {
 		h.log.Error(err)
 		return ErrInternalServerError
@@ -333,51 +452,210 @@ func (h *FileDataHandler) downLoadFile(res http.ResponseWriter, req *http.Reques
 	return nil
 }
 
-// loadFile Загрузка файла от клиента по запросу
-func (h *FileDataHandler) loadFile(req *http.Request, dataUUID string) *ErrResponse {
-	var (
-		err      error
-		fileData *models.FileData
-	)
-	err = req.ParseMultipartForm(4096)
+// loadFile приём части файла: контрольная сумма, сохранение части и сборка файла после последней части
+func (h *FileDataHandler) loadFile(res http.ResponseWriter, req *http.Request, dataUUID string, part int) (*model_data.FileDataStatusResponse, *ErrResponse) {
+	// до конца транзакции запроса части файла не принимаются параллельно: сборку выполнит ровно один запрос
+	err := h.manager.FileData().Lock(req.Context(), dataUUID)
 	if err != nil {
 		h.log.Error(err)
-		return ErrBadRequest
+		return nil, ErrInternalServerError
 	}
-	requestFile, _, err := req.FormFile(data_type.FileField)
+	fileData, err := h.manager.FileData().FindOneByUUID(req.Context(), dataUUID)
 	if err != nil {
 		h.log.Error(err)
-		return ErrBadRequest
+		return nil, ErrInternalServerError
+	}
+	if fileData.Uploaded { // повтор части после сборки, например ответ на последнюю часть не дошёл до клиента
+		chunks, err := h.manager.FileChunk().FindAllByFileUUID(req.Context(), dataUUID)
+		if err != nil {
+			h.log.Error(err)
+			return nil, ErrInternalServerError
+		}
+		return uploadStatus(fileData, chunks), nil
+	}
+	if part < 0 || part >= fileData.Chunks {
+		h.log.Infof("chunk %d out of range: uuid %s, chunks %d", part, dataUUID, fileData.Chunks)
+		return nil, ErrBadRequest
 	}
-	defer requestFile.Close()
 
-	fileData, err = h.fileDataCRUD.FindOneByUUID(req.Context(), dataUUID)
+	req.Body = http.MaxBytesReader(res, req.Body, data_type.FileChunkMaxSize+4096)
+	err = req.ParseMultipartForm(data_type.FileChunkMaxSize + 4096)
+	if err != nil {
+		h.log.Info(err)
+		return nil, ErrBadRequest
+	}
+	requestFile, _, err := req.FormFile(data_type.FileField)
+	if err != nil {
+		h.log.Info(err)
+		return nil, ErrBadRequest
+	}
+	defer requestFile.Close()
+	chunk, err := io.ReadAll(io.LimitReader(requestFile, data_type.FileChunkMaxSize+1))
+	if err != nil {
+		h.log.Error(err)
+		return nil, ErrBadRequest
+	}
+	if len(chunk) > data_type.FileChunkMaxSize {
+		h.log.Infof("chunk %d is too large: uuid %s", part, dataUUID)
+		return nil, ErrBadRequest
+	}
+	checksum := util.ChunkChecksum(chunk)
+	if checksum != req.FormValue(data_type.ChecksumField) {
+		h.log.Infof("chunk %d checksum mismatch: uuid %s", part, dataUUID)
+		return nil, ErrChunkChecksum
+	}
 
-	// Всё сразу todo по частям и с part
-	filename := fileData.Path + "/" + fileData.FileName
-	err = os.Mkdir(fileData.Path+"/", 0777)
+	// часть пишется во временный файл и переименовывается: оборванная запись не оставит неполную часть
+	err = writeFileAtomic(fileData.PathTmp, strconv.Itoa(part), func(f *os.File) error {
+		_, err := f.Write(chunk)
+		return err
+	})
 	if err != nil {
 		h.log.Error(err)
-		return ErrInternalServerError
+		return nil, ErrInternalServerError
 	}
-	f, err := os.OpenFile(filename, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0600)
+	err = h.manager.FileChunk().Save(req.Context(), &models.FileChunk{FileUUID: dataUUID, Part: part, Size: int64(len(chunk)), Checksum: checksum})
 	if err != nil {
 		h.log.Error(err)
-		return ErrInternalServerError
+		return nil, ErrInternalServerError
 	}
-	defer f.Close()
-	_, err = io.Copy(f, requestFile)
+
+	chunks, err := h.manager.FileChunk().FindAllByFileUUID(req.Context(), dataUUID)
 	if err != nil {
 		h.log.Error(err)
-		return ErrInternalServerError
+		return nil, ErrInternalServerError
 	}
-	// Файл загружен
-	fileData.Uploaded = true
-	err = h.fileDataCRUD.Update(req.Context(), fileData)
+	chunks = storedChunks(fileData, chunks)
+	if len(chunks) < fileData.Chunks {
+		return uploadStatus(fileData, chunks), nil
+	}
+
+	// Все части приняты, собираем файл
+	err = assembleFile(fileData, chunks)
+	if err != nil {
+		// часть на диске повреждена: она удаляется и пропадает из состояния загрузки, клиент отправит её заново
+		h.log.Warnf("File %s is not assembled, the broken chunks will be uploaded again: %s", dataUUID, err)
+		removeBrokenChunks(fileData, chunks)
+		return nil, ErrFileAssembly
+	}
+	err = h.manager.FileData().SetUploaded(req.Context(), dataUUID, true)
 	if err != nil {
 		h.log.Error(err)
-		return ErrInternalServerError
+		return nil, ErrInternalServerError
 	}
+	// части нужны, пока собранный файл не зафиксирован: при откате транзакции загрузка продолжится с них
+	storage.AfterCommit(req.Context(), func() {
+		_ = os.RemoveAll(fileData.PathTmp)
+	})
+	fileData.Uploaded = true
 
-	return nil
+	return uploadStatus(fileData, chunks), nil
+}
+
+// assembleFile сборка файла из частей в порядке номеров. Файл пишется рядом и заменяет прежний переименованием
+func assembleFile(fileData *models.FileData, chunks []models.FileChunk) error {
+	return writeFileAtomic(fileData.Path, fileData.UUID, func(f *os.File) error {
+		for _, chunk := range chunks {
+			content, err := os.ReadFile(path.Join(fileData.PathTmp, strconv.Itoa(chunk.Part)))
+			if err != nil {
+				return err
+			}
+			if util.ChunkChecksum(content) != chunk.Checksum {
+				return fmt.Errorf("chunk %d checksum mismatch", chunk.Part)
+			}
+			err = util.WriteChunkFrame(f, content)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// storedFilePath собранный файл хранится под uuid данных, имя файла от клиента в путь не попадает
+func storedFilePath(fileData *models.FileData) string {
+	return path.Join(fileData.Path, fileData.UUID)
+}
+
+// existingFilePath путь к сохранённому файлу. Файлы, сохранённые до хранения под uuid, лежат под именем
+// от клиента (берётся только имя, без папок), пока не перенесены
+func existingFilePath(fileData *models.FileData) string {
+	if _, err := os.Stat(storedFilePath(fileData)); err == nil {
+		return storedFilePath(fileData)
+	}
+	return path.Join(fileData.Path, path.Base(fileData.FileName))
+}
+
+// moveLegacyFile перенос файла, сохранённого под именем от клиента, под uuid данных: после смены имени
+// файл останется доступен
+func moveLegacyFile(fileData *models.FileData) error {
+	legacyPath := existingFilePath(fileData)
+	if legacyPath == storedFilePath(fileData) {
+		return nil
+	}
+	err := os.Rename(legacyPath, storedFilePath(fileData))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// storedChunks принятые части, файлы которых есть на диске. Часть без файла (потеряна или удалена
+// после неудачной сборки) отправляется заново
+func storedChunks(fileData *models.FileData, chunks []models.FileChunk) []models.FileChunk {
+	stored := make([]models.FileChunk, 0, len(chunks))
+	for _, chunk := range chunks {
+		info, err := os.Stat(path.Join(fileData.PathTmp, strconv.Itoa(chunk.Part)))
+		if err != nil || info.Size() != chunk.Size {
+			continue
+		}
+		stored = append(stored, chunk)
+	}
+	return stored
+}
+
+// removeBrokenChunks удаляет файлы частей, содержимое которых не совпадает с контрольной суммой
+func removeBrokenChunks(fileData *models.FileData, chunks []models.FileChunk) {
+	for _, chunk := range chunks {
+		chunkPath := path.Join(fileData.PathTmp, strconv.Itoa(chunk.Part))
+		content, err := os.ReadFile(chunkPath)
+		if err == nil && util.ChunkChecksum(content) == chunk.Checksum {
+			continue
+		}
+		_ = os.Remove(chunkPath)
+	}
+}
+
+// writeFileAtomic запись файла name в папке dir: write пишет во временный файл, который затем переименовывается
+func writeFileAtomic(dir string, name string, write func(f *os.File) error) error {
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(dir, ".tmp-"+name+"-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name()) // после переименования ничего не удалит
+	err = write(f)
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path.Join(dir, name))
+}
+
+// uploadStatus состояние загрузки по принятым частям
+func uploadStatus(fileData *models.FileData, chunks []models.FileChunk) *model_data.FileDataStatusResponse {
+	status := &model_data.FileDataStatusResponse{Chunks: fileData.Chunks, Uploaded: fileData.Uploaded}
+	status.Received = make([]model_data.FileChunkReceipt, 0, len(chunks))
+	for _, chunk := range chunks {
+		status.Received = append(status.Received, model_data.FileChunkReceipt{Part: chunk.Part, Size: chunk.Size, Checksum: chunk.Checksum})
+	}
+	return status
 }
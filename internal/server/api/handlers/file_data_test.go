@@ -9,6 +9,7 @@ import (
 	"net/http/httptest"
 	"os"
 	"path"
+	"strconv"
 	"testing"
 
 	"github.com/go-chi/chi/v5"
@@ -16,6 +17,7 @@ import (
 	"github.com/northmule/gophkeeper/internal/common/data_type"
 	"github.com/northmule/gophkeeper/internal/common/model_data"
 	"github.com/northmule/gophkeeper/internal/common/models"
+	"github.com/northmule/gophkeeper/internal/common/util"
 	"github.com/northmule/gophkeeper/internal/server/config"
 	"github.com/northmule/gophkeeper/internal/server/logger"
 	appMock "github.com/northmule/gophkeeper/internal/server/repository/mock"
@@ -50,6 +52,7 @@ func TestFileDataHandleInit_SuccessfulCreation(t *testing.T) {
 	requestData.Size = 1024
 	requestData.Extension = ".pdf"
 	requestData.MimeType = "application/pdf"
+	requestData.Chunks = 1
 	requestData.Meta = map[string]string{"test": "value"}
 	reqBody, _ := json.Marshal(requestData)
 
@@ -61,6 +64,34 @@ func TestFileDataHandleInit_SuccessfulCreation(t *testing.T) {
 	handler.HandleInit(res, req)
 
 	assert.Equal(t, http.StatusOK, res.Code)
+	response := new(fileDataInitResponse)
+	_ = json.NewDecoder(res.Body).Decode(response)
+	assert.Regexp(t, "^/file_data/load/[0-9a-f-]{36}$", response.UploadPath)
+	assert.Regexp(t, "^/file_data/status/[0-9a-f-]{36}$", response.StatusPath)
+	mockFileDataRepo.AssertCalled(t, "Add", mock.Anything, mock.MatchedBy(func(data *models.FileData) bool {
+		return data.Chunks == 1 && !data.Uploaded
+	}))
+}
+
+func TestFileDataHandleInit_WithoutChunks(t *testing.T) {
+	mockAccessService := new(appMock.MockAccessService)
+	mockRepository := new(appMock.MockManager)
+	logger, _ := logger.NewLogger("info")
+	cfg := config.NewConfig()
+	_ = cfg.Init()
+
+	mockAccessService.On("GetUserUUIDByJWTToken", mock.Anything).Return("userUUID", nil)
+
+	reqBody, _ := json.Marshal(model_data.FileDataInitRequest{Name: "Test File", FileName: "test.pdf", Extension: ".pdf"})
+	req, _ := http.NewRequest("POST", "/file_data/init", bytes.NewBuffer(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+	res := httptest.NewRecorder()
+
+	handler := NewFileDataHandler(mockAccessService, mockRepository, cfg, logger)
+	handler.HandleInit(res, req)
+
+	assert.Equal(t, http.StatusBadRequest, res.Code)
+	mockRepository.AssertNotCalled(t, "FileData")
 }
 
 func TestFileDataHandleInit_SuccessfulUpdate(t *testing.T) {
@@ -90,6 +121,7 @@ func TestFileDataHandleInit_SuccessfulUpdate(t *testing.T) {
 	fileData.Path = os.TempDir() + "/load_" + fileData.UUID
 	fileData.Storage = "local://"
 	fileData.Uploaded = false
+	fileData.Version = 1
 
 	mockFileDataRepo.On("FindOneByUUID", mock.Anything, dataUUID).Return(fileData, nil)
 
@@ -119,6 +151,7 @@ func TestFileDataHandleInit_SuccessfulUpdate(t *testing.T) {
 
 	reqBody, _ := json.Marshal(model_data.FileDataInitRequest{
 		UUID:      dataUUID,
+		Version:   1,
 		Name:      "Updated Test File",
 		FileName:  "updated_test.pdf",
 		Size:      2048,
@@ -218,6 +251,7 @@ func TestFileDataHandleInit_NonExistentDataUUID(t *testing.T) {
 
 	reqBody, _ := json.Marshal(model_data.FileDataInitRequest{
 		UUID:      dataUUID,
+		Version:   1,
 		Name:      "Test File",
 		FileName:  "test.pdf",
 		Size:      1024,
@@ -237,69 +271,180 @@ func TestFileDataHandleInit_NonExistentDataUUID(t *testing.T) {
 
 func TestFileData_HandleAction(t *testing.T) {
 
-	t.Run("SuccessfulFileHandling", func(t *testing.T) {
+	// newChunkRequest запрос части part с содержимым content и контрольной суммой checksum
+	newChunkRequest := func(fileUUID string, part string, content []byte, checksum string) *http.Request {
+		ctx := chi.NewRouteContext()
+		ctx.URLParams.Add("file_uuid", fileUUID)
+		ctx.URLParams.Add("part", part)
 
+		body := &bytes.Buffer{}
+		writer := multipart.NewWriter(body)
+		_ = writer.WriteField(data_type.ChecksumField, checksum)
+		filePart, _ := writer.CreateFormFile(data_type.FileField, "test.pdf")
+		_, _ = filePart.Write(content)
+		_ = writer.Close()
+
+		req := httptest.NewRequest("POST", "/files/{file_uuid}/{part}", body)
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, ctx))
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		return req
+	}
+	// newChunkHandler обработчик с владельцем файла fileData
+	newChunkHandler := func(fileData *models.FileData) (*FileDataHandler, *appMock.MockFileDataModelRepository, *appMock.MockFileChunkModelRepository) {
 		mockAccessService := new(appMock.MockAccessService)
 		mockOwnerRepo := new(appMock.MockOwnerDataModelRepository)
 		mockRepository := new(appMock.MockManager)
 		mockFileDataRepo := new(appMock.MockFileDataModelRepository)
-		mockMetaDataRepo := new(appMock.MockMetaDataModelRepository)
+		mockFileChunkRepo := new(appMock.MockFileChunkModelRepository)
 		logger, _ := logger.NewLogger("info")
-		cfg := config.NewConfig()
-		_ = cfg.Init()
 
 		mockRepository.On("Owner").Return(mockOwnerRepo)
 		mockRepository.On("FileData").Return(mockFileDataRepo)
-		mockRepository.On("MetaData").Return(mockMetaDataRepo)
+		mockRepository.On("FileChunk").Return(mockFileChunkRepo)
+		mockAccessService.On("GetUserUUIDByJWTToken", mock.Anything).Return("valid-user-uuid", nil)
+		mockOwnerRepo.On("FindOneByUserUUIDAndDataUUIDAndDataType", mock.Anything, "valid-user-uuid", fileData.UUID, data_type.BinaryType).Return(new(models.Owner), nil)
+		mockFileDataRepo.On("Lock", mock.Anything, fileData.UUID).Return(nil)
+		mockFileDataRepo.On("FindOneByUUID", mock.Anything, fileData.UUID).Return(fileData, nil)
 
 		handler := &FileDataHandler{
 			accessService: mockAccessService,
 			manager:       mockRepository,
 			log:           logger,
 		}
+		return handler, mockFileDataRepo, mockFileChunkRepo
+	}
+	newFileData := func(t *testing.T, chunks int) *models.FileData {
+		dir := t.TempDir()
+		fileData := new(models.FileData)
+		fileData.UUID = uuid.NewString()
+		fileData.FileName = "../test.pdf" // имя от клиента в путь к файлу не попадает
+		fileData.PathTmp = path.Join(dir, "chunks")
+		fileData.Path = path.Join(dir, "load")
+		fileData.Chunks = chunks
+		return fileData
+	}
 
-		fileUUID := uuid.NewString()
+	t.Run("SuccessfulChunk", func(t *testing.T) {
+		fileData := newFileData(t, 2)
+		handler, mockFileDataRepo, mockFileChunkRepo := newChunkHandler(fileData)
+		content := []byte("second chunk")
+		checksum := util.ChunkChecksum(content)
+		mockFileChunkRepo.On("Save", mock.Anything, &models.FileChunk{FileUUID: fileData.UUID, Part: 1, Size: int64(len(content)), Checksum: checksum}).Return(nil)
+		mockFileChunkRepo.On("FindAllByFileUUID", mock.Anything, fileData.UUID).Return([]models.FileChunk{{Part: 1, Size: int64(len(content)), Checksum: checksum}}, nil)
 
-		ctx := chi.NewRouteContext()
-		ctx.URLParams.Add("file_uuid", fileUUID)
-		ctx.URLParams.Add("part", "valid-part")
+		res := httptest.NewRecorder()
+		handler.HandleAction(res, newChunkRequest(fileData.UUID, "1", content, checksum))
 
-		body := &bytes.Buffer{}
-		writer := multipart.NewWriter(body)
-		part, _ := writer.CreateFormFile(data_type.FileField, "test.pdf")
-		_, _ = part.Write([]byte("test file content"))
-		_ = writer.Close()
+		assert.Equal(t, http.StatusOK, res.Code)
+		status := new(model_data.FileDataStatusResponse)
+		_ = json.NewDecoder(res.Body).Decode(status)
+		assert.False(t, status.Uploaded)
+		assert.Equal(t, 2, status.Chunks)
+		assert.Equal(t, []model_data.FileChunkReceipt{{Part: 1, Size: int64(len(content)), Checksum: checksum}}, status.Received)
+		saved, err := os.ReadFile(path.Join(fileData.PathTmp, "1"))
+		assert.NoError(t, err)
+		assert.Equal(t, content, saved)
+		mockFileDataRepo.AssertNotCalled(t, "SetUploaded", mock.Anything, mock.Anything, mock.Anything)
+	})
 
-		req := httptest.NewRequest("POST", "/files/{file_uuid}/{part}", body)
-		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, ctx))
-		req.Header.Set("Content-Type", writer.FormDataContentType())
+	t.Run("LastChunkAssemblesFile", func(t *testing.T) {
+		fileData := newFileData(t, 2)
+		handler, mockFileDataRepo, mockFileChunkRepo := newChunkHandler(fileData)
+		first, last := []byte("first chunk"), []byte("last")
+		assert.NoError(t, os.MkdirAll(fileData.PathTmp, 0700))
+		assert.NoError(t, os.WriteFile(path.Join(fileData.PathTmp, "0"), first, 0600))
+		mockFileChunkRepo.On("Save", mock.Anything, mock.Anything).Return(nil)
+		mockFileChunkRepo.On("FindAllByFileUUID", mock.Anything, fileData.UUID).Return([]models.FileChunk{
+			{Part: 0, Size: int64(len(first)), Checksum: util.ChunkChecksum(first)},
+			{Part: 1, Size: int64(len(last)), Checksum: util.ChunkChecksum(last)},
+		}, nil)
+		mockFileDataRepo.On("SetUploaded", mock.Anything, fileData.UUID, true).Return(nil)
 
-		owner := new(models.Owner)
-		owner.UserUUID = "123"
-		owner.UserUUID = "321"
-		dataUUID := fileUUID
-		fileData := new(models.FileData)
-		fileData.Name = "Test File"
-		fileData.UUID = dataUUID
-		fileData.MimeType = "application/pdf"
-		fileData.FileName = "test.pdf"
-		fileData.Size = 1024
-		fileData.Extension = ".pdf"
-		fileData.PathTmp = os.TempDir() + "/" + fileData.UUID
-		fileData.Path = os.TempDir() + "/load_" + fileData.UUID
-		fileData.Storage = "local://"
-		fileData.Uploaded = false
-		mockFileDataRepo.On("FindOneByUUID", mock.Anything, dataUUID).Return(fileData, nil)
-		mockAccessService.On("GetUserUUIDByJWTToken", mock.Anything).Return("valid-user-uuid", nil)
-		mockOwnerRepo.On("FindOneByUserUUIDAndDataUUIDAndDataType", mock.Anything, "valid-user-uuid", fileUUID, data_type.BinaryType).Return(owner, nil)
-		mockFileDataRepo.On("Update", mock.Anything, mock.Anything).Return(nil)
+		res := httptest.NewRecorder()
+		handler.HandleAction(res, newChunkRequest(fileData.UUID, "1", last, util.ChunkChecksum(last)))
+
+		assert.Equal(t, http.StatusOK, res.Code)
+		status := new(model_data.FileDataStatusResponse)
+		_ = json.NewDecoder(res.Body).Decode(status)
+		assert.True(t, status.Uploaded)
+		expected := new(bytes.Buffer)
+		_ = util.WriteChunkFrame(expected, first)
+		_ = util.WriteChunkFrame(expected, last)
+		assembled, err := os.ReadFile(path.Join(fileData.Path, fileData.UUID))
+		assert.NoError(t, err)
+		assert.Equal(t, expected.Bytes(), assembled)
+		assert.NoDirExists(t, fileData.PathTmp)
+		mockFileDataRepo.AssertExpectations(t)
+	})
+
+	t.Run("LostChunkIsNotReceived", func(t *testing.T) {
+		fileData := newFileData(t, 2)
+		handler, mockFileDataRepo, mockFileChunkRepo := newChunkHandler(fileData)
+		last := []byte("last")
+		mockFileChunkRepo.On("Save", mock.Anything, mock.Anything).Return(nil)
+		mockFileChunkRepo.On("FindAllByFileUUID", mock.Anything, fileData.UUID).Return([]models.FileChunk{
+			{Part: 0, Size: 11, Checksum: util.ChunkChecksum([]byte("first chunk"))},
+			{Part: 1, Size: int64(len(last)), Checksum: util.ChunkChecksum(last)},
+		}, nil)
 
 		res := httptest.NewRecorder()
-		handler.HandleAction(res, req)
+		handler.HandleAction(res, newChunkRequest(fileData.UUID, "1", last, util.ChunkChecksum(last)))
 
+		// файла части 0 нет на диске: клиент отправит её заново, файл не собирается
 		assert.Equal(t, http.StatusOK, res.Code)
-		mockAccessService.AssertExpectations(t)
-		mockOwnerRepo.AssertExpectations(t)
+		status := new(model_data.FileDataStatusResponse)
+		_ = json.NewDecoder(res.Body).Decode(status)
+		assert.False(t, status.Uploaded)
+		assert.Equal(t, []model_data.FileChunkReceipt{{Part: 1, Size: int64(len(last)), Checksum: util.ChunkChecksum(last)}}, status.Received)
+		assert.NoFileExists(t, path.Join(fileData.Path, fileData.UUID))
+		mockFileDataRepo.AssertNotCalled(t, "SetUploaded", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("BrokenChunkFailsAssembly", func(t *testing.T) {
+		fileData := newFileData(t, 2)
+		handler, mockFileDataRepo, mockFileChunkRepo := newChunkHandler(fileData)
+		first, last := []byte("first chunk"), []byte("last")
+		assert.NoError(t, os.MkdirAll(fileData.PathTmp, 0700))
+		assert.NoError(t, os.WriteFile(path.Join(fileData.PathTmp, "0"), []byte("FIRST CHUNK"), 0600))
+		mockFileChunkRepo.On("Save", mock.Anything, mock.Anything).Return(nil)
+		mockFileChunkRepo.On("FindAllByFileUUID", mock.Anything, fileData.UUID).Return([]models.FileChunk{
+			{Part: 0, Size: int64(len(first)), Checksum: util.ChunkChecksum(first)},
+			{Part: 1, Size: int64(len(last)), Checksum: util.ChunkChecksum(last)},
+		}, nil)
+
+		res := httptest.NewRecorder()
+		handler.HandleAction(res, newChunkRequest(fileData.UUID, "1", last, util.ChunkChecksum(last)))
+
+		// ответ с ошибкой откатывает транзакцию запроса, повреждённая часть удаляется с диска
+		assert.Equal(t, http.StatusConflict, res.Code)
+		assert.Contains(t, res.Body.String(), strconv.Itoa(data_type.AppCodeFileAssembly))
+		assert.NoFileExists(t, path.Join(fileData.PathTmp, "0"))
+		assert.FileExists(t, path.Join(fileData.PathTmp, "1"))
+		assert.NoFileExists(t, path.Join(fileData.Path, fileData.UUID))
+		mockFileChunkRepo.AssertNotCalled(t, "DeleteByFileUUID", mock.Anything, mock.Anything)
+		mockFileDataRepo.AssertNotCalled(t, "SetUploaded", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("ChecksumMismatch", func(t *testing.T) {
+		fileData := newFileData(t, 1)
+		handler, _, mockFileChunkRepo := newChunkHandler(fileData)
+
+		res := httptest.NewRecorder()
+		handler.HandleAction(res, newChunkRequest(fileData.UUID, "0", []byte("content"), util.ChunkChecksum([]byte("other"))))
+
+		assert.Equal(t, http.StatusUnprocessableEntity, res.Code)
+		mockFileChunkRepo.AssertNotCalled(t, "Save", mock.Anything, mock.Anything)
+		assert.NoDirExists(t, fileData.PathTmp)
+	})
+
+	t.Run("PartOutOfRange", func(t *testing.T) {
+		fileData := newFileData(t, 1)
+		handler, _, _ := newChunkHandler(fileData)
+
+		res := httptest.NewRecorder()
+		handler.HandleAction(res, newChunkRequest(fileData.UUID, "1", []byte("content"), util.ChunkChecksum([]byte("content"))))
+
+		assert.Equal(t, http.StatusBadRequest, res.Code)
 	})
 
 	t.Run("UserUUIDNotFound", func(t *testing.T) {
@@ -458,7 +603,7 @@ func TestFileDataHandleGetAction_SuccessfulFileDownload(t *testing.T) {
 	mockAccessService.On("GetUserUUIDByJWTToken", mock.Anything).Return("valid-user-uuid", nil)
 	mockOwnerRepo.On("FindOneByUserUUIDAndDataUUIDAndDataType", mock.Anything, "valid-user-uuid", fileUUID, data_type.BinaryType).Return(owner, nil)
 
-	tempFile, err := os.Create(path.Join(fileData.Path, fileData.FileName))
+	tempFile, err := os.Create(path.Join(fileData.Path, fileData.UUID))
 	if err != nil {
 		t.Fatalf("Failed to create temporary file: %v", err)
 	}
@@ -473,12 +618,96 @@ func TestFileDataHandleGetAction_SuccessfulFileDownload(t *testing.T) {
 	handler.HandleGetAction(res, req)
 
 	assert.Equal(t, http.StatusOK, res.Code)
-	assert.Equal(t, testData, res.Body.Bytes())
+	expected := new(bytes.Buffer) // файл, загруженный до частей, отдаётся одной частью
+	_ = util.WriteChunkFrame(expected, testData)
+	assert.Equal(t, expected.Bytes(), res.Body.Bytes())
 	mockAccessService.AssertExpectations(t)
 	mockOwnerRepo.AssertExpectations(t)
 	mockFileDataRepo.AssertExpectations(t)
 }
 
+// newDownloadHandler обработчик с данными файла fileData для загрузки клиентом
+func newDownloadHandler(t *testing.T, fileData *models.FileData) (*FileDataHandler, *http.Request) {
+	mockAccessService := new(appMock.MockAccessService)
+	mockOwnerRepo := new(appMock.MockOwnerDataModelRepository)
+	mockFileDataRepo := new(appMock.MockFileDataModelRepository)
+	mockRepository := new(appMock.MockManager)
+	logger, _ := logger.NewLogger("info")
+
+	mockRepository.On("Owner").Return(mockOwnerRepo)
+	mockRepository.On("FileData").Return(mockFileDataRepo)
+	mockAccessService.On("GetUserUUIDByJWTToken", mock.Anything).Return("valid-user-uuid", nil)
+	mockOwnerRepo.On("FindOneByUserUUIDAndDataUUIDAndDataType", mock.Anything, "valid-user-uuid", fileData.UUID, data_type.BinaryType).Return(new(models.Owner), nil)
+	mockFileDataRepo.On("FindOneByUUID", mock.Anything, fileData.UUID).Return(fileData, nil)
+
+	ctx := chi.NewRouteContext()
+	ctx.URLParams.Add("file_uuid", fileData.UUID)
+	ctx.URLParams.Add("part", "0")
+	req := httptest.NewRequest("GET", "/files/{file_uuid}/{part}", nil)
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, ctx))
+
+	return &FileDataHandler{accessService: mockAccessService, manager: mockRepository, log: logger}, req
+}
+
+func TestFileDataHandleGetAction_ClientFileName(t *testing.T) {
+	t.Run("PathTraversal", func(t *testing.T) {
+		dir := t.TempDir()
+		assert.NoError(t, os.WriteFile(path.Join(dir, "secret"), []byte("server secret"), 0600))
+		fileData := &models.FileData{FileName: "../secret", Path: path.Join(dir, "load"), Uploaded: true}
+		fileData.UUID = uuid.NewString()
+
+		handler, req := newDownloadHandler(t, fileData)
+		res := httptest.NewRecorder()
+		handler.HandleGetAction(res, req)
+
+		assert.Equal(t, http.StatusInternalServerError, res.Code)
+		assert.NotContains(t, res.Body.String(), "server secret")
+	})
+
+	t.Run("RenamedLegacyFile", func(t *testing.T) {
+		dir := t.TempDir()
+		fileData := &models.FileData{FileName: "old.pdf", Path: dir, Uploaded: true, Version: 1}
+		fileData.UUID = uuid.NewString()
+		assert.NoError(t, os.WriteFile(path.Join(dir, "old.pdf"), []byte("legacy content"), 0600))
+
+		// смена имени без нового содержимого
+		mockAccessService := new(appMock.MockAccessService)
+		mockOwnerRepo := new(appMock.MockOwnerDataModelRepository)
+		mockFileDataRepo := new(appMock.MockFileDataModelRepository)
+		mockMetaDataRepo := new(appMock.MockMetaDataModelRepository)
+		mockRepository := new(appMock.MockManager)
+		logger, _ := logger.NewLogger("info")
+		mockRepository.On("Owner").Return(mockOwnerRepo)
+		mockRepository.On("FileData").Return(mockFileDataRepo)
+		mockRepository.On("MetaData").Return(mockMetaDataRepo)
+		mockAccessService.On("GetUserUUIDByJWTToken", mock.Anything).Return("userUUID", nil)
+		mockOwnerRepo.On("FindOneByUserUUIDAndDataUUIDAndDataType", mock.Anything, "userUUID", fileData.UUID, data_type.BinaryType).Return(new(models.Owner), nil)
+		mockFileDataRepo.On("FindOneByUUID", mock.Anything, fileData.UUID).Return(fileData, nil)
+		mockFileDataRepo.On("Update", mock.Anything, fileData).Return(nil)
+		mockMetaDataRepo.On("ReplaceMetaByDataUUID", mock.Anything, fileData.UUID, mock.Anything).Return(nil)
+
+		reqBody, _ := json.Marshal(model_data.FileDataInitRequest{
+			UUID: fileData.UUID, Version: 1, Name: "Test File", FileName: "new.pdf", Extension: ".pdf",
+		})
+		req, _ := http.NewRequest("POST", "/file_data/init", bytes.NewBuffer(reqBody))
+		req.Header.Set("Content-Type", "application/json")
+		res := httptest.NewRecorder()
+		NewFileDataHandler(mockAccessService, mockRepository, &config.Config{}, logger).HandleInit(res, req)
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.Equal(t, "new.pdf", fileData.FileName)
+		assert.FileExists(t, path.Join(dir, fileData.UUID))
+
+		handler, downloadReq := newDownloadHandler(t, fileData)
+		res = httptest.NewRecorder()
+		handler.HandleGetAction(res, downloadReq)
+
+		assert.Equal(t, http.StatusOK, res.Code)
+		expected := new(bytes.Buffer)
+		_ = util.WriteChunkFrame(expected, []byte("legacy content"))
+		assert.Equal(t, expected.Bytes(), res.Body.Bytes())
+	})
+}
+
 func TestFileDataHandleGetAction_UserUUIDNotFound(t *testing.T) {
 	mockAccessService := new(appMock.MockAccessService)
 	mockOwnerRepo := new(appMock.MockOwnerDataModelRepository)
@@ -548,3 +777,54 @@ func TestFileDataHandleGetAction_DataUUIDNotFound(t *testing.T) {
 	mockAccessService.AssertExpectations(t)
 	mockOwnerRepo.AssertExpectations(t)
 }
+
+func TestFileDataHandleStatus(t *testing.T) {
+	mockAccessService := new(appMock.MockAccessService)
+	mockOwnerRepo := new(appMock.MockOwnerDataModelRepository)
+	mockFileDataRepo := new(appMock.MockFileDataModelRepository)
+	mockFileChunkRepo := new(appMock.MockFileChunkModelRepository)
+	mockRepository := new(appMock.MockManager)
+	logger, _ := logger.NewLogger("info")
+
+	mockRepository.On("Owner").Return(mockOwnerRepo)
+	mockRepository.On("FileData").Return(mockFileDataRepo)
+	mockRepository.On("FileChunk").Return(mockFileChunkRepo)
+
+	fileData := new(models.FileData)
+	fileData.UUID = uuid.NewString()
+	fileData.Chunks = 3
+	fileData.PathTmp = t.TempDir()
+	_ = os.WriteFile(path.Join(fileData.PathTmp, "0"), make([]byte, 10), 0600)
+	_ = os.WriteFile(path.Join(fileData.PathTmp, "2"), make([]byte, 5), 0600)
+	mockAccessService.On("GetUserUUIDByJWTToken", mock.Anything).Return("valid-user-uuid", nil)
+	mockOwnerRepo.On("FindOneByUserUUIDAndDataUUIDAndDataType", mock.Anything, "valid-user-uuid", fileData.UUID, data_type.BinaryType).Return(new(models.Owner), nil)
+	mockOwnerRepo.On("FindOneByUserUUIDAndDataUUIDAndDataType", mock.Anything, "valid-user-uuid", "other-uuid", data_type.BinaryType).Return(nil, nil)
+	mockFileDataRepo.On("FindOneByUUID", mock.Anything, fileData.UUID).Return(fileData, nil)
+	mockFileChunkRepo.On("FindAllByFileUUID", mock.Anything, fileData.UUID).Return([]models.FileChunk{{Part: 0, Size: 10, Checksum: "aa"}, {Part: 1, Size: 7, Checksum: "cc"}, {Part: 2, Size: 5, Checksum: "bb"}}, nil)
+
+	handler := &FileDataHandler{
+		accessService: mockAccessService,
+		manager:       mockRepository,
+		log:           logger,
+	}
+	newRequest := func(fileUUID string) *http.Request {
+		ctx := chi.NewRouteContext()
+		ctx.URLParams.Add("file_uuid", fileUUID)
+		req := httptest.NewRequest("GET", "/file_data/status/{file_uuid}", nil)
+		return req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, ctx))
+	}
+
+	res := httptest.NewRecorder()
+	handler.HandleStatus(res, newRequest(fileData.UUID))
+	assert.Equal(t, http.StatusOK, res.Code)
+	status := new(model_data.FileDataStatusResponse)
+	_ = json.NewDecoder(res.Body).Decode(status)
+	assert.Equal(t, 3, status.Chunks)
+	assert.False(t, status.Uploaded)
+	// часть 1 без файла на диске не считается принятой
+	assert.Equal(t, []model_data.FileChunkReceipt{{Part: 0, Size: 10, Checksum: "aa"}, {Part: 2, Size: 5, Checksum: "bb"}}, status.Received)
+
+	res = httptest.NewRecorder()
+	handler.HandleStatus(res, newRequest("other-uuid"))
+	assert.Equal(t, http.StatusNotFound, res.Code)
+}
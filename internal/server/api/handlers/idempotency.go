@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/northmule/gophkeeper/internal/common/data_type"
+	"github.com/northmule/gophkeeper/internal/common/models"
+	"github.com/northmule/gophkeeper/internal/server/logger"
+	"github.com/northmule/gophkeeper/internal/server/repository"
+)
+
+const (
+	// idempotencyKeyTTL сколько хранится ответ на запрос с ключом идемпотентности
+	idempotencyKeyTTL = 24 * time.Hour
+	// idempotencyKeyMaxLen максимальная длина ключа (размер поля в таблице)
+	idempotencyKeyMaxLen = 100
+	// idempotencyKeyLease через сколько ключ без ответа (запрос прерван остановкой сервера) можно занять заново
+	idempotencyKeyLease = time.Minute
+)
+
+// IdempotencyHandler повтор запроса сохранения с тем же ключом идемпотентности не выполняется повторно,
+// клиент получает сохранённый ответ первого запроса
+type IdempotencyHandler struct {
+	log           *logger.Logger
+	accessService UserFinderByJWT
+	manager       repository.Repository
+}
+
+// NewIdempotencyHandler конструктор
+func NewIdempotencyHandler(accessService UserFinderByJWT, manager repository.Repository, log *logger.Logger) *IdempotencyHandler {
+	return &IdempotencyHandler{
+		log:           log,
+		accessService: accessService,
+		manager:       manager,
+	}
+}
+
+// HandleIdempotency обрабатывает заголовок Idempotency-Key. Запрос без заголовка выполняется как обычно.
+// Ключ занимается до выполнения запроса, успешный ответ сохраняется, при ошибке ключ освобождается.
+// Тело запроса должно быть уже расшифровано: конверт каждой отправки уникален
+func (h *IdempotencyHandler) HandleIdempotency(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		key := req.Header.Get(data_type.IdempotencyKeyHeader)
+		if key == "" {
+			next.ServeHTTP(res, req)
+			return
+		}
+		if len(key) > idempotencyKeyMaxLen {
+			h.log.Infof("Idempotency key is too long: %d", len(key))
+			_ = render.Render(res, req, ErrBadRequest)
+			return
+		}
+
+		userUUID, err := h.accessService.GetUserUUIDByJWTToken(req.Context())
+		if err != nil {
+			h.log.Error(err)
+			_ = render.Render(res, req, ErrBadRequest)
+			return
+		}
+
+		// копия body
+		bodyBytes, err := io.ReadAll(req.Body)
+		if err != nil {
+			h.log.Error(err)
+			_ = render.Render(res, req, ErrBadRequest)
+			return
+		}
+		req.Body = io.NopCloser(bytes.NewBuffer(bodyBytes))
+
+		idempotencyKey := &models.IdempotencyKey{
+			UserUUID:    userUUID,
+			Key:         key,
+			RequestHash: requestHash(req, bodyBytes),
+		}
+		now := time.Now()
+		reserved, err := h.manager.IdempotencyKey().Reserve(req.Context(), idempotencyKey, now.Add(-idempotencyKeyTTL), now.Add(-idempotencyKeyLease))
+		if err != nil {
+			h.log.Error(err)
+			_ = render.Render(res, req, ErrInternalServerError)
+			return
+		}
+		if !reserved {
+			h.replay(res, req, idempotencyKey)
+			return
+		}
+
+		response := new(bytes.Buffer)
+		ww := middleware.NewWrapResponseWriter(res, req.ProtoMajor)
+		ww.Tee(response)
+		next.ServeHTTP(ww, req)
+
+		// ответ уже отправлен, отключение клиента не должно прерывать запись
+		ctx := context.WithoutCancel(req.Context())
+		status := ww.Status()
+		if status >= http.StatusOK && status < http.StatusMultipleChoices {
+			err = h.manager.IdempotencyKey().Complete(ctx, userUUID, key, status, response.String())
+		} else {
+			err = h.manager.IdempotencyKey().Release(ctx, userUUID, key)
+		}
+		if err != nil {
+			h.log.Error(err)
+		}
+	})
+}
+
+// replay ответ на повтор запроса с занятым ключом
+func (h *IdempotencyHandler) replay(res http.ResponseWriter, req *http.Request, idempotencyKey *models.IdempotencyKey) {
+	saved, err := h.manager.IdempotencyKey().FindOne(req.Context(), idempotencyKey.UserUUID, idempotencyKey.Key)
+	if err != nil {
+		h.log.Error(err)
+		_ = render.Render(res, req, ErrInternalServerError)
+		return
+	}
+	if saved == nil {
+		// ключ освобождён после ошибки первого запроса, клиент повторит его
+		_ = render.Render(res, req, ErrIdempotencyInProgress)
+		return
+	}
+	if saved.RequestHash != idempotencyKey.RequestHash {
+		h.log.Infof("Idempotency key %s of user %s reused for another request", idempotencyKey.Key, idempotencyKey.UserUUID)
+		_ = render.Render(res, req, ErrIdempotencyKeyReused)
+		return
+	}
+	if saved.StatusCode == 0 {
+		_ = render.Render(res, req, ErrIdempotencyInProgress)
+		return
+	}
+
+	h.log.Infof("Replayed the response for idempotency key %s of user %s", idempotencyKey.Key, idempotencyKey.UserUUID)
+	res.Header().Set(data_type.IdempotentReplayedHeader, strconv.FormatBool(true))
+	res.WriteHeader(saved.StatusCode)
+	_, err = res.Write([]byte(saved.Response))
+	if err != nil {
+		h.log.Error(err)
+	}
+}
+
+// requestHash отпечаток запроса: метод, путь и расшифрованное тело
+func requestHash(req *http.Request, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(req.Method + " " + req.URL.Path + "\n"))
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
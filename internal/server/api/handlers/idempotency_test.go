@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/northmule/gophkeeper/internal/common/data_type"
+	"github.com/northmule/gophkeeper/internal/common/models"
+	"github.com/northmule/gophkeeper/internal/server/logger"
+	appMock "github.com/northmule/gophkeeper/internal/server/repository/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestIdempotencyHandler_HandleIdempotency(t *testing.T) {
+	l, _ := logger.NewLogger("info")
+
+	newRequest := func(key string) *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "/save_text_data", strings.NewReader(`{"name":"note"}`))
+		if key != "" {
+			req.Header.Set(data_type.IdempotencyKeyHeader, key)
+		}
+		return req
+	}
+	newHandler := func(mockIdempotencyKey *appMock.MockIdempotencyKeyModelRepository) *IdempotencyHandler {
+		mockAccessService := new(appMock.MockAccessService)
+		mockAccessService.On("GetUserUUIDByJWTToken", mock.Anything).Return("user-uuid", nil)
+		mockManager := new(appMock.MockManager)
+		mockManager.On("IdempotencyKey").Return(mockIdempotencyKey)
+		return NewIdempotencyHandler(mockAccessService, mockManager, l)
+	}
+	hashOf := func(req *http.Request) string {
+		return requestHash(req, []byte(`{"name":"note"}`))
+	}
+
+	t.Run("without key", func(t *testing.T) {
+		called := 0
+		next := http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) { called++ })
+		handler := NewIdempotencyHandler(new(appMock.MockAccessService), new(appMock.MockManager), l)
+		handler.HandleIdempotency(next).ServeHTTP(httptest.NewRecorder(), newRequest(""))
+		assert.Equal(t, 1, called)
+	})
+
+	t.Run("key too long", func(t *testing.T) {
+		handler := NewIdempotencyHandler(new(appMock.MockAccessService), new(appMock.MockManager), l)
+		rr := httptest.NewRecorder()
+		handler.HandleIdempotency(http.NotFoundHandler()).ServeHTTP(rr, newRequest(strings.Repeat("k", idempotencyKeyMaxLen+1)))
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("first request is saved", func(t *testing.T) {
+		mockIdempotencyKey := new(appMock.MockIdempotencyKeyModelRepository)
+		mockIdempotencyKey.On("Reserve", mock.Anything, mock.MatchedBy(func(data *models.IdempotencyKey) bool {
+			return data.UserUUID == "user-uuid" && data.Key == "key" && data.RequestHash != ""
+		}), mock.Anything, mock.MatchedBy(func(leaseExpiredBefore time.Time) bool {
+			// ключ без ответа занимается заново только после аренды
+			return time.Since(leaseExpiredBefore) >= idempotencyKeyLease && time.Since(leaseExpiredBefore) < idempotencyKeyTTL
+		})).Return(true, nil)
+		mockIdempotencyKey.On("Complete", mock.Anything, "user-uuid", "key", http.StatusOK, `{"upload_path":"/path"}`).Return(nil)
+
+		next := http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+			_, _ = res.Write([]byte(`{"upload_path":"/path"}`))
+		})
+		rr := httptest.NewRecorder()
+		newHandler(mockIdempotencyKey).HandleIdempotency(next).ServeHTTP(rr, newRequest("key"))
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, `{"upload_path":"/path"}`, rr.Body.String())
+		mockIdempotencyKey.AssertExpectations(t)
+	})
+
+	t.Run("failed request releases key", func(t *testing.T) {
+		mockIdempotencyKey := new(appMock.MockIdempotencyKeyModelRepository)
+		mockIdempotencyKey.On("Reserve", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(true, nil)
+		mockIdempotencyKey.On("Release", mock.Anything, "user-uuid", "key").Return(nil)
+
+		next := http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+			res.WriteHeader(http.StatusInternalServerError)
+		})
+		rr := httptest.NewRecorder()
+		newHandler(mockIdempotencyKey).HandleIdempotency(next).ServeHTTP(rr, newRequest("key"))
+		assert.Equal(t, http.StatusInternalServerError, rr.Code)
+		mockIdempotencyKey.AssertExpectations(t)
+		mockIdempotencyKey.AssertNotCalled(t, "Complete", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("repeat gets saved response", func(t *testing.T) {
+		req := newRequest("key")
+		mockIdempotencyKey := new(appMock.MockIdempotencyKeyModelRepository)
+		mockIdempotencyKey.On("Reserve", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(false, nil)
+		mockIdempotencyKey.On("FindOne", mock.Anything, "user-uuid", "key").Return(&models.IdempotencyKey{
+			RequestHash: hashOf(req),
+			StatusCode:  http.StatusOK,
+			Response:    `{"upload_path":"/path"}`,
+		}, nil)
+
+		called := 0
+		next := http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) { called++ })
+		rr := httptest.NewRecorder()
+		newHandler(mockIdempotencyKey).HandleIdempotency(next).ServeHTTP(rr, req)
+		assert.Equal(t, 0, called)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "true", rr.Header().Get(data_type.IdempotentReplayedHeader))
+		assert.Equal(t, `{"upload_path":"/path"}`, rr.Body.String())
+	})
+
+	t.Run("key reused for another request", func(t *testing.T) {
+		mockIdempotencyKey := new(appMock.MockIdempotencyKeyModelRepository)
+		mockIdempotencyKey.On("Reserve", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(false, nil)
+		mockIdempotencyKey.On("FindOne", mock.Anything, "user-uuid", "key").Return(&models.IdempotencyKey{
+			RequestHash: "other",
+			StatusCode:  http.StatusOK,
+		}, nil)
+
+		rr := httptest.NewRecorder()
+		newHandler(mockIdempotencyKey).HandleIdempotency(http.NotFoundHandler()).ServeHTTP(rr, newRequest("key"))
+		assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+		assert.Contains(t, rr.Body.String(), "1003")
+	})
+
+	t.Run("first request in progress", func(t *testing.T) {
+		req := newRequest("key")
+		mockIdempotencyKey := new(appMock.MockIdempotencyKeyModelRepository)
+		mockIdempotencyKey.On("Reserve", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(false, nil)
+		mockIdempotencyKey.On("FindOne", mock.Anything, "user-uuid", "key").Return(&models.IdempotencyKey{
+			RequestHash: hashOf(req),
+		}, nil)
+
+		rr := httptest.NewRecorder()
+		newHandler(mockIdempotencyKey).HandleIdempotency(http.NotFoundHandler()).ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusConflict, rr.Code)
+		assert.Contains(t, rr.Body.String(), "1004")
+	})
+
+	t.Run("reserve error", func(t *testing.T) {
+		mockIdempotencyKey := new(appMock.MockIdempotencyKeyModelRepository)
+		mockIdempotencyKey.On("Reserve", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(false, errors.New("db error"))
+
+		rr := httptest.NewRecorder()
+		newHandler(mockIdempotencyKey).HandleIdempotency(http.NotFoundHandler()).ServeHTTP(rr, newRequest("key"))
+		assert.Equal(t, http.StatusInternalServerError, rr.Code)
+	})
+
+	t.Run("body read error", func(t *testing.T) {
+		mockIdempotencyKey := new(appMock.MockIdempotencyKeyModelRepository)
+		req := httptest.NewRequest(http.MethodPost, "/save_text_data", iotest.ErrReader(errors.New("read error")))
+		req.Header.Set(data_type.IdempotencyKeyHeader, "key")
+
+		rr := httptest.NewRecorder()
+		newHandler(mockIdempotencyKey).HandleIdempotency(http.NotFoundHandler()).ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		mockIdempotencyKey.AssertNotCalled(t, "Reserve", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
@@ -2,7 +2,7 @@ package handlers
 
 import (
 	"net/http"
-	"time"
+	"strconv"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/render"
@@ -10,29 +10,22 @@ import (
 	"github.com/northmule/gophkeeper/internal/common/model_data"
 	"github.com/northmule/gophkeeper/internal/common/models"
 	"github.com/northmule/gophkeeper/internal/server/logger"
+	"github.com/northmule/gophkeeper/internal/server/repository"
 )
 
 // ItemDataHandler обрабатывает запрос данных по uuid
 type ItemDataHandler struct {
-	log             *logger.Logger
-	userFinderByJWT UserFinderByJWT
-	ownerCRUD       OwnerCRUD
-	cardDataCRUD    CardDataCRUD
-	metaDataCRUD    MetaDataCRUD
-	fileDataCRUD    FileDataCRUD
-	textDataCRUD    TextDataCRUD
+	log           *logger.Logger
+	accessService UserFinderByJWT
+	manager       repository.Repository
 }
 
 // NewItemDataHandler конструктор
-func NewItemDataHandler(userFinderByJWT UserFinderByJWT, cardDataCRUD CardDataCRUD, metaDataCRUD MetaDataCRUD, fileDataCRUD FileDataCRUD, textDataCRUD TextDataCRUD, ownerCRUD OwnerCRUD, log *logger.Logger) *ItemDataHandler {
+func NewItemDataHandler(accessService UserFinderByJWT, manager repository.Repository, log *logger.Logger) *ItemDataHandler {
 	return &ItemDataHandler{
-		userFinderByJWT: userFinderByJWT,
-		cardDataCRUD:    cardDataCRUD,
-		metaDataCRUD:    metaDataCRUD,
-		fileDataCRUD:    fileDataCRUD,
-		textDataCRUD:    textDataCRUD,
-		ownerCRUD:       ownerCRUD,
-		log:             log,
+		accessService: accessService,
+		manager:       manager,
+		log:           log,
 	}
 }
 
@@ -61,14 +54,14 @@ func (h *ItemDataHandler) HandleItem(res http.ResponseWriter, req *http.Request)
 		return
 	}
 
-	userUUID, err = h.userFinderByJWT.GetUserUUIDByJWTToken(req.Context())
+	userUUID, err = h.accessService.GetUserUUIDByJWTToken(req.Context())
 	if err != nil {
 		h.log.Error(err)
 		_ = render.Render(res, req, ErrBadRequest)
 		return
 	}
 
-	owner, err = h.ownerCRUD.FindOneByUserUUIDAndDataUUID(req.Context(), userUUID, dataUUID)
+	owner, err = h.manager.Owner().FindOneByUserUUIDAndDataUUID(req.Context(), userUUID, dataUUID)
 	if err != nil {
 		h.log.Error(err)
 		_ = render.Render(res, req, ErrBadRequest)
@@ -81,13 +74,15 @@ func (h *ItemDataHandler) HandleItem(res http.ResponseWriter, req *http.Request)
 	}
 
 	var (
-		cardData     *models.CardData
-		textData     *models.TextData
-		fileData     *models.FileData
-		metaData     []models.MetaData
-		dataResponse *dataByUUIDResponse
+		cardData       *models.CardData
+		textData       *models.TextData
+		fileData       *models.FileData
+		credentialData *models.CredentialData
+		metaData       []models.MetaData
+		dataResponse   *dataByUUIDResponse
+		version        int64
 	)
-	metaData, err = h.metaDataCRUD.FindOneByUUID(req.Context(), owner.DataUUID)
+	metaData, err = h.manager.MetaData().FindOneByUUID(req.Context(), owner.DataUUID)
 	if err != nil {
 		h.log.Error(err)
 		_ = render.Render(res, req, ErrInternalServerError)
@@ -98,7 +93,7 @@ func (h *ItemDataHandler) HandleItem(res http.ResponseWriter, req *http.Request)
 	switch owner.DataType {
 
 	case data_type.CardType: // Данные карт
-		cardData, err = h.cardDataCRUD.FindOneByUUID(req.Context(), owner.DataUUID)
+		cardData, err = h.manager.CardData().FindOneByUUID(req.Context(), owner.DataUUID)
 		if err != nil {
 			h.log.Error(err)
 			_ = render.Render(res, req, ErrInternalServerError)
@@ -107,6 +102,8 @@ func (h *ItemDataHandler) HandleItem(res http.ResponseWriter, req *http.Request)
 
 		dataResponse.IsCard = true
 		dataResponse.CardData.UUID = cardData.UUID
+		dataResponse.CardData.Version = cardData.Version
+		version = cardData.Version
 		dataResponse.CardData.Name = cardData.Name
 		dataResponse.CardData.CardNumber = cardData.Value.CardNumber
 		dataResponse.CardData.NameBank = cardData.Value.NameBank
@@ -114,7 +111,7 @@ func (h *ItemDataHandler) HandleItem(res http.ResponseWriter, req *http.Request)
 		dataResponse.CardData.FullNameHolder = cardData.Value.FullNameHolder
 		dataResponse.CardData.PhoneHolder = cardData.Value.PhoneHolder
 		dataResponse.CardData.SecurityCode = cardData.Value.SecurityCode
-		dataResponse.CardData.ValidityPeriod = cardData.Value.ValidityPeriod.Format(time.RFC3339)
+		dataResponse.CardData.ValidityPeriod = cardData.Value.ValidityPeriod
 
 		if len(metaData) > 0 {
 			existMeta := make(map[string]string)
@@ -125,7 +122,7 @@ func (h *ItemDataHandler) HandleItem(res http.ResponseWriter, req *http.Request)
 		}
 
 	case data_type.TextType: // Текстовые данные
-		textData, err = h.textDataCRUD.FindOneByUUID(req.Context(), owner.DataUUID)
+		textData, err = h.manager.TextData().FindOneByUUID(req.Context(), owner.DataUUID)
 		if err != nil {
 			h.log.Error(err)
 			_ = render.Render(res, req, ErrInternalServerError)
@@ -134,6 +131,8 @@ func (h *ItemDataHandler) HandleItem(res http.ResponseWriter, req *http.Request)
 		dataResponse.IsText = true
 		dataResponse.TextData.Name = textData.Name
 		dataResponse.TextData.UUID = textData.UUID
+		dataResponse.TextData.Version = textData.Version
+		version = textData.Version
 		dataResponse.TextData.Value = textData.Value
 
 		if len(metaData) > 0 {
@@ -144,7 +143,7 @@ func (h *ItemDataHandler) HandleItem(res http.ResponseWriter, req *http.Request)
 			dataResponse.TextData.Meta = existMeta
 		}
 	case data_type.BinaryType: // Бинарные данные
-		fileData, err = h.fileDataCRUD.FindOneByUUID(req.Context(), owner.DataUUID)
+		fileData, err = h.manager.FileData().FindOneByUUID(req.Context(), owner.DataUUID)
 		if err != nil {
 			h.log.Error(err)
 			_ = render.Render(res, req, ErrInternalServerError)
@@ -153,8 +152,11 @@ func (h *ItemDataHandler) HandleItem(res http.ResponseWriter, req *http.Request)
 		dataResponse.IsFile = true
 		dataResponse.FileData.Name = fileData.Name
 		dataResponse.FileData.UUID = fileData.UUID
+		dataResponse.FileData.Version = fileData.Version
+		version = fileData.Version
 		dataResponse.FileData.FileName = fileData.FileName
 		dataResponse.FileData.Size = fileData.Size
+		dataResponse.FileData.Chunks = fileData.Chunks
 		dataResponse.FileData.Extension = fileData.Extension
 		dataResponse.FileData.MimeType = fileData.MimeType
 
@@ -165,11 +167,42 @@ func (h *ItemDataHandler) HandleItem(res http.ResponseWriter, req *http.Request)
 			}
 			dataResponse.FileData.Meta = existMeta
 		}
+	case data_type.CredentialType: // Пары логин/пароль
+		credentialData, err = h.manager.CredentialData().FindOneByUUID(req.Context(), owner.DataUUID)
+		if err != nil {
+			h.log.Error(err)
+			_ = render.Render(res, req, ErrInternalServerError)
+			return
+		}
+		dataResponse.IsCredential = true
+		dataResponse.CredentialData.Name = credentialData.Name
+		dataResponse.CredentialData.UUID = credentialData.UUID
+		dataResponse.CredentialData.Version = credentialData.Version
+		version = credentialData.Version
+		dataResponse.CredentialData.Username = credentialData.Value.Username
+		dataResponse.CredentialData.Password = credentialData.Value.Password
+		dataResponse.CredentialData.URLs = credentialData.Value.URLs
+		dataResponse.CredentialData.Notes = credentialData.Value.Notes
+
+		if len(metaData) > 0 {
+			existMeta := make(map[string]string)
+			for _, value := range metaData {
+				existMeta[value.MetaName] = value.MetaValue.Value
+			}
+			dataResponse.CredentialData.Meta = existMeta
+		}
 	}
 
+	// версия для изменения данных: передаётся в запросе сохранения
+	res.Header().Set("ETag", versionETag(version))
 	err = render.Render(res, req, dataResponse)
 	if err != nil {
 		h.log.Error(err)
 		_ = render.Render(res, req, ErrInternalServerError)
 	}
 }
+
+// versionETag версия данных в заголовке ETag
+func versionETag(version int64) string {
+	return strconv.Quote(strconv.FormatInt(version, 10))
+}
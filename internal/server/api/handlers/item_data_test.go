@@ -52,7 +52,7 @@ func TestItemDataHandler_HandleItem_SuccessfulCardData(t *testing.T) {
 			FullNameHolder:       "John Doe",
 			PhoneHolder:          "1234567890",
 			SecurityCode:         "123",
-			ValidityPeriod:       time.Now(),
+			ValidityPeriod:       time.Now().Format(time.RFC3339),
 		},
 	}
 	cardData.UUID = dataUUID
@@ -111,8 +111,9 @@ func TestItemDataHandler_HandleItem_SuccessfulTextData(t *testing.T) {
 	mockOwnerRepo.On("FindOneByUserUUIDAndDataUUID", mock.Anything, "userUUID", dataUUID).Return(owner, nil)
 
 	textData := &models.TextData{
-		Name:  "Test Text",
-		Value: "This is a test text data.",
+		Name:    "Test Text",
+		Value:   "This is a test text data.",
+		Version: 3,
 	}
 	textData.UUID = dataUUID
 
@@ -145,6 +146,8 @@ func TestItemDataHandler_HandleItem_SuccessfulTextData(t *testing.T) {
 	assert.Contains(t, res.Body.String(), `"name":"Test Text"`)
 	assert.Contains(t, res.Body.String(), `"value":"This is a test text data."`)
 	assert.Contains(t, res.Body.String(), `"test":"value"`)
+	assert.Contains(t, res.Body.String(), `"version":3`)
+	assert.Equal(t, `"3"`, res.Header().Get("ETag"))
 }
 
 func TestItemDataHandler_HandleItem_SuccessfulFileData(t *testing.T) {
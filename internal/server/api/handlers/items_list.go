@@ -3,25 +3,26 @@ package handlers
 import (
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/go-chi/render"
 	"github.com/northmule/gophkeeper/internal/common/model_data"
-	"github.com/northmule/gophkeeper/internal/common/models"
 	"github.com/northmule/gophkeeper/internal/server/logger"
+	"github.com/northmule/gophkeeper/internal/server/repository"
 	"golang.org/x/net/context"
 )
 
 type ItemsListHandler struct {
-	log             *logger.Logger
-	userFinderByJWT UserFinderByJWT
-	dataFinder      AllOwnerDataFinder
+	log           *logger.Logger
+	accessService UserFinderByJWT
+	manager       repository.Repository
 }
 
-func NewItemsListHandler(userFinderByJWT UserFinderByJWT, dataFinder AllOwnerDataFinder, log *logger.Logger) *ItemsListHandler {
+func NewItemsListHandler(accessService UserFinderByJWT, manager repository.Repository, log *logger.Logger) *ItemsListHandler {
 	return &ItemsListHandler{
-		userFinderByJWT: userFinderByJWT,
-		dataFinder:      dataFinder,
-		log:             log,
+		accessService: accessService,
+		manager:       manager,
+		log:           log,
 	}
 }
 
@@ -30,11 +31,6 @@ type UserFinderByJWT interface {
 	GetUserUUIDByJWTToken(ctx context.Context) (string, error)
 }
 
-// AllOwnerDataFinder данные пользователя
-type AllOwnerDataFinder interface {
-	AllOwnerData(ctx context.Context, userUUID string, offset int, limit int) ([]models.OwnerData, error)
-}
-
 type itemDataResponse struct {
 	model_data.ItemDataResponse
 }
@@ -53,7 +49,7 @@ func (hr itemDataResponse) Render(res http.ResponseWriter, req *http.Request) er
 
 func (ih *ItemsListHandler) HandleItemsList(res http.ResponseWriter, req *http.Request) {
 
-	userUUID, err := ih.userFinderByJWT.GetUserUUIDByJWTToken(req.Context())
+	userUUID, err := ih.accessService.GetUserUUIDByJWTToken(req.Context())
 	if err != nil {
 		ih.log.Error(err)
 		_ = render.Render(res, req, ErrBadRequest)
@@ -70,7 +66,7 @@ func (ih *ItemsListHandler) HandleItemsList(res http.ResponseWriter, req *http.R
 	}
 	o, _ := strconv.Atoi(offset)
 	l, _ := strconv.Atoi(limit)
-	dataList, err := ih.dataFinder.AllOwnerData(req.Context(), userUUID, o, l)
+	dataList, err := ih.manager.Owner().AllOwnerData(req.Context(), userUUID, o, l)
 	if err != nil {
 		ih.log.Error(err)
 		_ = render.Render(res, req, ErrInternalServerError)
@@ -84,6 +80,9 @@ func (ih *ItemsListHandler) HandleItemsList(res http.ResponseWriter, req *http.R
 		item.Name = data.DataName
 		item.Type = data.DataTypeName
 		item.Number = strconv.Itoa(n)
+		item.UpdateDate = data.UpdatedAt.Format(time.DateTime)
+		item.CreateDate = data.CreatedAt.Format(time.DateTime)
+		item.Revision = data.Revision
 		items = append(items, item)
 		n++
 	}
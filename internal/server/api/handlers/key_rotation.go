@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/render"
+	"github.com/google/uuid"
+	"github.com/northmule/gophkeeper/internal/common/model_data"
+	"github.com/northmule/gophkeeper/internal/common/models"
+	"github.com/northmule/gophkeeper/internal/server/logger"
+	"github.com/northmule/gophkeeper/internal/server/repository"
+	service "github.com/northmule/gophkeeper/internal/server/services"
+	"github.com/northmule/gophkeeper/internal/server/services/kek"
+	"github.com/northmule/gophkeeper/internal/server/services/rotation"
+)
+
+// KeyRotationHandler смена ключа шифрования клиента
+type KeyRotationHandler struct {
+	log           *logger.Logger
+	accessService DeviceAccess
+	manager       repository.Repository
+	cryptService  service.CryptService
+	keyProvider   kek.KeyProvider
+	rotator       KeyRotator
+}
+
+// KeyRotator фоновая замена ключа клиента
+type KeyRotator interface {
+	Start(rotation models.KeyRotation)
+}
+
+// NewKeyRotationHandler конструктор
+func NewKeyRotationHandler(accessService DeviceAccess, cryptService service.CryptService, keyProvider kek.KeyProvider, rotator KeyRotator, manager repository.Repository, log *logger.Logger) *KeyRotationHandler {
+	return &KeyRotationHandler{
+		accessService: accessService,
+		cryptService:  cryptService,
+		keyProvider:   keyProvider,
+		rotator:       rotator,
+		manager:       manager,
+		log:           log,
+	}
+}
+
+type keyRotationResponse struct {
+	model_data.KeyRotationResponse
+}
+
+// Render ответ с состоянием смены ключа
+func (kr keyRotationResponse) Render(res http.ResponseWriter, req *http.Request) error {
+	return nil
+}
+
+func newKeyRotationResponse(rotation *models.KeyRotation) *keyRotationResponse {
+	response := new(keyRotationResponse)
+	response.UUID = rotation.UUID
+	response.Status = rotation.Status
+	response.Error = rotation.Error
+	if rotation.FinishedAt != nil {
+		response.FinishedAt = rotation.FinishedAt.Unix()
+	}
+	return response
+}
+
+// HandleRotate приём нового ключа клиента (форма как у save_client_private_key) и запуск смены ключа.
+// Прежний ключ действует до завершения смены, состояние - /api/v1/key_rotation
+func (h *KeyRotationHandler) HandleRotate(res http.ResponseWriter, req *http.Request) {
+	var (
+		err      error
+		userUUID string
+		last     *models.KeyRotation
+	)
+
+	userUUID, err = h.accessService.GetUserUUIDByJWTToken(req.Context())
+	if err != nil {
+		h.log.Error(err)
+		_ = render.Render(res, req, ErrBadRequest)
+		return
+	}
+
+	keyBytes, errResponse := decryptClientPrivateKey(req, h.cryptService, h.log)
+	if errResponse != nil {
+		_ = render.Render(res, req, errResponse)
+		return
+	}
+
+	_, err = findClientKey(req.Context(), h.accessService, h.manager, h.keyProvider, userUUID)
+	if errors.Is(err, errNoClientKey) {
+		h.log.Infof("User %s has no client key to rotate", userUUID)
+		_ = render.Render(res, req, ErrBadRequest)
+		return
+	}
+	if err != nil {
+		h.log.Error(err)
+		_ = render.Render(res, req, ErrInternalServerError)
+		return
+	}
+
+	// ключ меняется у устройства токена, токен без устройства меняет ключ пользователя
+	deviceUUID, _ := h.accessService.GetDeviceUUIDByJWTToken(req.Context())
+	last, err = h.manager.KeyRotation().FindLastByDeviceUUID(req.Context(), userUUID, deviceUUID)
+	if err != nil {
+		h.log.Error(err)
+		_ = render.Render(res, req, ErrInternalServerError)
+		return
+	}
+	if last != nil && last.Status == models.KeyRotationRunning {
+		_ = render.Render(res, req, ErrConflict(errors.New("the key rotation is already running")))
+		return
+	}
+
+	keyRotation := &models.KeyRotation{
+		UUID:       uuid.NewString(),
+		UserUUID:   userUUID,
+		DeviceUUID: deviceUUID,
+		Status:     models.KeyRotationRunning,
+	}
+	// Новый ключ хранится зашифрованным KEK до завершения смены
+	keyRotation.NewClientKey, err = h.keyProvider.Wrap(keyBytes, []byte(rotation.KeyOwner(keyRotation)))
+	if err != nil {
+		h.log.Error(err)
+		_ = render.Render(res, req, ErrInternalServerError)
+		return
+	}
+	keyRotation.ID, err = h.manager.KeyRotation().Add(req.Context(), keyRotation)
+	if err != nil {
+		h.log.Error(err)
+		_ = render.Render(res, req, ErrInternalServerError)
+		return
+	}
+	h.rotator.Start(*keyRotation)
+	h.log.Infof("The key rotation %s of user %s has been started", keyRotation.UUID, userUUID)
+
+	render.Status(req, http.StatusAccepted)
+	err = render.Render(res, req, newKeyRotationResponse(keyRotation))
+	if err != nil {
+		h.log.Error(err)
+		_ = render.Render(res, req, ErrInternalServerError)
+	}
+}
+
+// HandleStatus состояние последней смены ключа пользователя
+func (h *KeyRotationHandler) HandleStatus(res http.ResponseWriter, req *http.Request) {
+	var (
+		err      error
+		userUUID string
+		rotation *models.KeyRotation
+	)
+
+	userUUID, err = h.accessService.GetUserUUIDByJWTToken(req.Context())
+	if err != nil {
+		h.log.Error(err)
+		_ = render.Render(res, req, ErrBadRequest)
+		return
+	}
+
+	deviceUUID, _ := h.accessService.GetDeviceUUIDByJWTToken(req.Context())
+	rotation, err = h.manager.KeyRotation().FindLastByDeviceUUID(req.Context(), userUUID, deviceUUID)
+	if err != nil {
+		h.log.Error(err)
+		_ = render.Render(res, req, ErrInternalServerError)
+		return
+	}
+	if rotation == nil {
+		_ = render.Render(res, req, ErrNotFound)
+		return
+	}
+
+	err = render.Render(res, req, newKeyRotationResponse(rotation))
+	if err != nil {
+		h.log.Error(err)
+		_ = render.Render(res, req, ErrInternalServerError)
+	}
+}
@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/northmule/gophkeeper/internal/common/data_type"
+	"github.com/northmule/gophkeeper/internal/common/model_data"
+	"github.com/northmule/gophkeeper/internal/common/models"
+	"github.com/northmule/gophkeeper/internal/server/logger"
+	appMock "github.com/northmule/gophkeeper/internal/server/repository/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type mockKeyRotator struct {
+	mock.Mock
+}
+
+func (m *mockKeyRotator) Start(rotation models.KeyRotation) {
+	m.Called(rotation)
+}
+
+func newKeyRotationRequest(t *testing.T) *http.Request {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, err := writer.CreateFormFile(data_type.FileField, "private_client_key")
+	require.NoError(t, err)
+	_, _ = io.WriteString(part, "encryptedNewKey")
+	require.NoError(t, writer.Close())
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/rotate_client_private_key", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	return req
+}
+
+func TestKeyRotationHandler_HandleRotate(t *testing.T) {
+	l, _ := logger.NewLogger("info")
+	keyProvider := newTestKeyProvider(t)
+	current, err := keyProvider.Wrap([]byte("oldKey"), []byte("device123"))
+	require.NoError(t, err)
+	withKey := newKeyDevice("device123", "user123", current)
+
+	tests := []struct {
+		name         string
+		device       *models.Device
+		last         *models.KeyRotation
+		expectedCode int
+		started      bool
+	}{
+		{"first rotation", withKey, nil, http.StatusAccepted, true},
+		{"after finished rotation", withKey, &models.KeyRotation{Status: models.KeyRotationFinished}, http.StatusAccepted, true},
+		{"rotation is running", withKey, &models.KeyRotation{Status: models.KeyRotationRunning}, http.StatusConflict, false},
+		{"no key to rotate", newKeyDevice("device123", "user123", ""), nil, http.StatusBadRequest, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockAccessService := new(appMock.MockAccessService)
+			mockRepository := new(appMock.MockManager)
+			mockCryptService := new(appMock.MockCryptService)
+			mockUserRepository := new(appMock.MockUserDataModelRepository)
+			mockDeviceRepository := new(appMock.MockDeviceModelRepository)
+			mockKeyRotationRepository := new(appMock.MockKeyRotationModelRepository)
+			rotator := new(mockKeyRotator)
+
+			mockRepository.On("User").Return(mockUserRepository)
+			mockRepository.On("Device").Return(mockDeviceRepository)
+			mockRepository.On("KeyRotation").Return(mockKeyRotationRepository)
+			mockAccessService.On("GetUserUUIDByJWTToken", mock.Anything).Return("user123", nil)
+			mockAccessService.On("GetDeviceUUIDByJWTToken", mock.Anything).Return("device123", nil)
+			mockCryptService.On("DecryptKey", "", []byte("encryptedNewKey")).Return([]byte("newKey"), nil)
+			mockDeviceRepository.On("FindOneByUUID", mock.Anything, "device123").Return(tt.device, nil)
+			mockUserRepository.On("FindOneByUUID", mock.Anything, "user123").Return(&models.User{}, nil)
+			mockKeyRotationRepository.On("FindLastByDeviceUUID", mock.Anything, "user123", "device123").Return(tt.last, nil)
+			var added *models.KeyRotation
+			mockKeyRotationRepository.On("Add", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+				added = args.Get(1).(*models.KeyRotation)
+			}).Return(int64(1), nil)
+			rotator.On("Start", mock.Anything).Return()
+
+			handler := NewKeyRotationHandler(mockAccessService, mockCryptService, keyProvider, rotator, mockRepository, l)
+			rr := httptest.NewRecorder()
+			handler.HandleRotate(rr, newKeyRotationRequest(t))
+
+			assert.Equal(t, tt.expectedCode, rr.Code)
+			if !tt.started {
+				rotator.AssertNotCalled(t, "Start", mock.Anything)
+				mockKeyRotationRepository.AssertNotCalled(t, "Add", mock.Anything, mock.Anything)
+				return
+			}
+			rotator.AssertCalled(t, "Start", *added)
+			// ключ меняется у устройства токена, новый ключ хранится зашифрованным KEK, у устройства остаётся прежний
+			assert.Equal(t, "device123", added.DeviceUUID)
+			plain, err := keyProvider.Unwrap(added.NewClientKey, []byte("device123"))
+			require.NoError(t, err)
+			assert.Equal(t, "newKey", string(plain))
+			mockDeviceRepository.AssertNotCalled(t, "SetClientKey", mock.Anything, mock.Anything, mock.Anything)
+
+			response := new(model_data.KeyRotationResponse)
+			require.NoError(t, json.NewDecoder(rr.Body).Decode(response))
+			assert.Equal(t, added.UUID, response.UUID)
+			assert.Equal(t, models.KeyRotationRunning, response.Status)
+		})
+	}
+}
+
+func TestKeyRotationHandler_HandleRotate_InvalidJWTToken(t *testing.T) {
+	l, _ := logger.NewLogger("info")
+	mockAccessService := new(appMock.MockAccessService)
+	mockAccessService.On("GetUserUUIDByJWTToken", mock.Anything).Return("", fmt.Errorf("invalid token"))
+
+	handler := NewKeyRotationHandler(mockAccessService, new(appMock.MockCryptService), newTestKeyProvider(t), new(mockKeyRotator), new(appMock.MockManager), l)
+	rr := httptest.NewRecorder()
+	handler.HandleRotate(rr, newKeyRotationRequest(t))
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
+
+func TestKeyRotationHandler_HandleStatus(t *testing.T) {
+	l, _ := logger.NewLogger("info")
+	finishedAt := time.Now()
+
+	tests := []struct {
+		name         string
+		last         *models.KeyRotation
+		err          error
+		expectedCode int
+	}{
+		{"finished", &models.KeyRotation{UUID: "rotation-uuid", Status: models.KeyRotationFinished, FinishedAt: &finishedAt}, nil, http.StatusOK},
+		{"not found", nil, nil, http.StatusNotFound},
+		{"repository error", nil, fmt.Errorf("db error"), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockAccessService := new(appMock.MockAccessService)
+			mockRepository := new(appMock.MockManager)
+			mockKeyRotationRepository := new(appMock.MockKeyRotationModelRepository)
+			mockRepository.On("KeyRotation").Return(mockKeyRotationRepository)
+			mockAccessService.On("GetUserUUIDByJWTToken", mock.Anything).Return("user123", nil)
+			mockAccessService.On("GetDeviceUUIDByJWTToken", mock.Anything).Return("device123", nil)
+			mockKeyRotationRepository.On("FindLastByDeviceUUID", mock.Anything, "user123", "device123").Return(tt.last, tt.err)
+
+			handler := NewKeyRotationHandler(mockAccessService, new(appMock.MockCryptService), newTestKeyProvider(t), new(mockKeyRotator), mockRepository, l)
+			rr := httptest.NewRecorder()
+			handler.HandleStatus(rr, httptest.NewRequest(http.MethodGet, "/api/v1/key_rotation", nil))
+
+			assert.Equal(t, tt.expectedCode, rr.Code)
+			if tt.expectedCode != http.StatusOK {
+				return
+			}
+			response := new(model_data.KeyRotationResponse)
+			require.NoError(t, json.NewDecoder(rr.Body).Decode(response))
+			assert.Equal(t, model_data.KeyRotationResponse{
+				UUID:       "rotation-uuid",
+				Status:     models.KeyRotationFinished,
+				FinishedAt: finishedAt.Unix(),
+			}, *response)
+		})
+	}
+}
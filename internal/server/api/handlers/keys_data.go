@@ -1,78 +1,112 @@
 package handlers
 
 import (
+	"crypto"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
 	"io"
 	"net/http"
 	"os"
 	"path"
+	"strings"
 
 	"github.com/go-chi/render"
 	"github.com/northmule/gophkeeper/internal/common/data_type"
 	"github.com/northmule/gophkeeper/internal/common/keys"
+	"github.com/northmule/gophkeeper/internal/common/model_data"
 	"github.com/northmule/gophkeeper/internal/common/models"
+	"github.com/northmule/gophkeeper/internal/common/util"
 	"github.com/northmule/gophkeeper/internal/server/config"
 	"github.com/northmule/gophkeeper/internal/server/logger"
+	"github.com/northmule/gophkeeper/internal/server/repository"
 	service "github.com/northmule/gophkeeper/internal/server/services"
-	"golang.org/x/net/context"
+	"github.com/northmule/gophkeeper/internal/server/services/ca"
+	"github.com/northmule/gophkeeper/internal/server/services/kek"
 )
 
 // Ожидаемая схема взаимодействия:
 // 1. Клиент авторизуется
 // 2. Отправляет серверу свой rsa публичный ключ
-// 3. Клиент запрашивает публичный rsa ключ сервера (ключ общий для всех на сервере)
-// 4. Клиент при помощи публичного rsa ключа сервера шифрует свой секретный aes ключ и направляет его серверу
-// 5. Сервер дешефрует секретный ключ своим rsa приватным ключом
+// 3. Клиент запрашивает публичный ключ сервера (ключ общий для всех на сервере) и перечисляет поддерживаемые схемы обмена.
+// Сервер выбирает схему: X25519 (вместе с ключом возвращается публичный ключ обмена) или RSA-OAEP для RSA ключа сервера
+// 4. Клиент по выбранной схеме шифрует свой секретный aes ключ и направляет его серверу
+// 5. Сервер дешефрует секретный ключ ключом обмена или своим rsa приватным ключом
 // 6. Дальнейший обмен данных шифрование и дешифрование проивзодится приватным ключом клиента
 
+// maxDeviceVaultKeyLen наибольшая длина зашифрованного ключа хранилища устройства
+const maxDeviceVaultKeyLen = 1000
+
 // KeysDataHandler обработка запросо с ключами
 type KeysDataHandler struct {
-	log             *logger.Logger
-	userFinderByJWT UserFinderByJWT
-	keySaver        KeySaver
-	userFinder      UserFinder
-	expectedAction  map[string]bool
+	log            *logger.Logger
+	accessService  DeviceAccess
+	manager        repository.Repository
+	expectedAction map[string]bool
 
 	cfg            *config.Config
 	publicKeyPath  string
 	privateKeyPath string
 
 	cryptService service.CryptService
+	keyProvider  kek.KeyProvider
+	certIssuer   CertificateIssuer
 }
 
-// NewKeysDataHandler конструктор
-func NewKeysDataHandler(userFinderByJWT UserFinderByJWT, cryptService service.CryptService, keySaver KeySaver, userFinder UserFinder, cfg *config.Config, log *logger.Logger) *KeysDataHandler {
+// CertificateIssuer выпуск сертификатов клиентов для входа по mTLS
+type CertificateIssuer interface {
+	Issue(publicKey crypto.PublicKey, userUUID string) (*ca.IssuedCertificate, error)
+	CertificatePEM() []byte
+}
 
-	return &KeysDataHandler{
-		userFinderByJWT: userFinderByJWT,
-		keySaver:        keySaver,
-		userFinder:      userFinder,
-		log:             log,
-		cfg:             cfg,
-		publicKeyPath:   path.Join(cfg.Value().PathKeys, keys.PublicKeyFileName),
-		privateKeyPath:  path.Join(cfg.Value().PathKeys, keys.PrivateKeyFileName),
-		cryptService:    cryptService,
-	}
+type clientCertificateResponse struct {
+	model_data.ClientCertificateResponse
+}
+
+// Render ответ с сертификатом клиента
+func (cr clientCertificateResponse) Render(res http.ResponseWriter, req *http.Request) error {
+	return nil
 }
 
-// KeySaver сохранение ключей
-type KeySaver interface {
-	SetPublicKey(ctx context.Context, data string, userUUID string) error
-	SetPrivateClientKey(ctx context.Context, data string, userUUID string) error
+// NewKeysDataHandler конструктор. certIssuer nil, если mTLS выключен
+func NewKeysDataHandler(accessService DeviceAccess, cryptService service.CryptService, keyProvider kek.KeyProvider, certIssuer CertificateIssuer, manager repository.Repository, cfg *config.Config, log *logger.Logger) *KeysDataHandler {
+
+	return &KeysDataHandler{
+		accessService:  accessService,
+		manager:        manager,
+		log:            log,
+		cfg:            cfg,
+		publicKeyPath:  path.Join(cfg.Value().PathKeys, keys.PublicKeyFileName),
+		privateKeyPath: path.Join(cfg.Value().PathKeys, keys.PrivateKeyFileName),
+		cryptService:   cryptService,
+		keyProvider:    keyProvider,
+		certIssuer:     certIssuer,
+	}
 }
 
-// HandleSaveClientPublicKey привязка публичного ключа клиента
+// HandleSaveClientPublicKey привязка публичного ключа клиента к устройству сессии. Вместе с ключом устройство может
+// прислать ключ хранилища, зашифрованный ключом устройства (поле vault_key, пустое значение оставляет прежний)
 func (h *KeysDataHandler) HandleSaveClientPublicKey(res http.ResponseWriter, req *http.Request) {
 	var (
-		err      error
-		userUUID string
+		err        error
+		userUUID   string
+		deviceUUID string
 	)
 
-	userUUID, err = h.userFinderByJWT.GetUserUUIDByJWTToken(req.Context())
+	userUUID, err = h.accessService.GetUserUUIDByJWTToken(req.Context())
 	if err != nil {
 		h.log.Error(err)
 		_ = render.Render(res, req, ErrBadRequest)
 		return
 	}
+	deviceUUID, err = h.accessService.GetDeviceUUIDByJWTToken(req.Context())
+	if err != nil {
+		h.log.Info(err)
+		_ = render.Render(res, req, ErrBadRequest)
+		return
+	}
 
 	err = req.ParseMultipartForm(4096)
 	if err != nil {
@@ -95,14 +129,71 @@ func (h *KeysDataHandler) HandleSaveClientPublicKey(res http.ResponseWriter, req
 		return
 	}
 
-	keyString := string(keyBytes)
+	vaultKey := req.FormValue(data_type.VaultKeyField)
+	if len(vaultKey) > maxDeviceVaultKeyLen {
+		h.log.Info("the device vault key is too long")
+		_ = render.Render(res, req, ErrBadRequest)
+		return
+	}
+
+	err = h.manager.Device().SetKeys(req.Context(), deviceUUID, string(keyBytes), vaultKey)
+	if err != nil {
+		h.log.Error(err)
+		_ = render.Render(res, req, ErrInternalServerError)
+		return
+	}
+
+	if h.certIssuer == nil {
+		return
+	}
+	h.issueClientCertificate(res, req, keyBytes, userUUID, deviceUUID)
+}
 
-	err = h.keySaver.SetPublicKey(req.Context(), keyString, userUUID)
+// issueClientCertificate выпуск сертификата клиента на присланный публичный ключ и привязка отпечатка к пользователю
+func (h *KeysDataHandler) issueClientCertificate(res http.ResponseWriter, req *http.Request, keyBytes []byte, userUUID string, deviceUUID string) {
+	block, _ := pem.Decode(keyBytes)
+	if block == nil {
+		h.log.Info("the client public key is not PEM encoded")
+		_ = render.Render(res, req, ErrBadRequest)
+		return
+	}
+	publicKey, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		h.log.Info(err)
+		_ = render.Render(res, req, ErrBadRequest)
+		return
+	}
+	issued, err := h.certIssuer.Issue(publicKey, userUUID)
+	if err != nil {
+		h.log.Error(err)
+		_ = render.Render(res, req, ErrBadRequest)
+		return
+	}
+	_, err = h.manager.ClientCertificate().Add(req.Context(), &models.ClientCertificate{
+		UserUUID:      userUUID,
+		DeviceUUID:    deviceUUID,
+		Fingerprint:   issued.Fingerprint,
+		PublicKeyHash: issued.PublicKeyHash,
+		SerialNumber:  issued.Certificate.SerialNumber.String(),
+		ExpiresAt:     issued.Certificate.NotAfter,
+	})
 	if err != nil {
 		h.log.Error(err)
 		_ = render.Render(res, req, ErrInternalServerError)
 		return
 	}
+
+	h.log.Infof("A client certificate %s has been issued to user %s", issued.Fingerprint, userUUID)
+	err = render.Render(res, req, clientCertificateResponse{ClientCertificateResponse: model_data.ClientCertificateResponse{
+		Certificate:   string(issued.PEM),
+		CACertificate: string(h.certIssuer.CertificatePEM()),
+		Fingerprint:   issued.Fingerprint,
+		ExpiresAt:     issued.Certificate.NotAfter.Unix(),
+	}})
+	if err != nil {
+		h.log.Error(err)
+		_ = render.Render(res, req, ErrInternalServerError)
+	}
 }
 
 // HandleDownloadServerPublicKey возвращает клиенту публичный ключ сервера
@@ -112,14 +203,14 @@ func (h *KeysDataHandler) HandleDownloadServerPublicKey(res http.ResponseWriter,
 		userUUID string
 		user     *models.User
 	)
-	userUUID, err = h.userFinderByJWT.GetUserUUIDByJWTToken(req.Context())
+	userUUID, err = h.accessService.GetUserUUIDByJWTToken(req.Context())
 	if err != nil {
 		h.log.Error(err)
 		_ = render.Render(res, req, ErrBadRequest)
 		return
 	}
 
-	user, err = h.userFinder.FindOneByUUID(req.Context(), userUUID)
+	user, err = h.manager.User().FindOneByUUID(req.Context(), userUUID)
 	if err != nil || user == nil {
 		h.log.Error(err)
 		_ = render.Render(res, req, ErrBadRequest)
@@ -133,6 +224,14 @@ func (h *KeysDataHandler) HandleDownloadServerPublicKey(res http.ResponseWriter,
 		return
 	}
 
+	scheme := negotiateKeyExchange(req.Header.Values(data_type.KeyExchangeHeader), h.cryptService.KeyExchanges())
+	if scheme != "" {
+		res.Header().Set(data_type.KeyExchangeHeader, scheme)
+	}
+	if scheme == util.KeyExchangeX25519 {
+		res.Header().Set(data_type.KeyExchangePublicKeyHeader, base64.StdEncoding.EncodeToString(h.cryptService.ExchangePublicKey()))
+	}
+
 	_, err = res.Write(buff)
 	if err != nil {
 		h.log.Error(err)
@@ -141,53 +240,131 @@ func (h *KeysDataHandler) HandleDownloadServerPublicKey(res http.ResponseWriter,
 	}
 }
 
-// HandleSaveClientPrivateKey привязка приватного ключа клиента. Ключ приходит зашифрованный публичным ключом сервера
+// negotiateKeyExchange первая из схем сервера, которую поддерживает клиент. Пустая строка - клиент схем не прислал
+func negotiateKeyExchange(clientValues []string, serverSchemes []string) string {
+	clientSchemes := make(map[string]bool)
+	for _, value := range clientValues {
+		for _, scheme := range strings.Split(value, ",") {
+			clientSchemes[strings.TrimSpace(scheme)] = true
+		}
+	}
+	for _, scheme := range serverSchemes {
+		if clientSchemes[scheme] {
+			return scheme
+		}
+	}
+	return ""
+}
+
+// HandleSaveClientPrivateKey привязка приватного ключа клиента к устройству сессии. Ключ приходит зашифрованный
+// по согласованной схеме обмена. У каждого устройства свой ключ; заданный ключ устройства повторно принимается
+// только тот же самый, новый ключ задаётся сменой ключа (/api/v1/rotate_client_private_key)
 func (h *KeysDataHandler) HandleSaveClientPrivateKey(res http.ResponseWriter, req *http.Request) {
 	var (
-		err      error
-		userUUID string
+		err        error
+		userUUID   string
+		deviceUUID string
+		device     *models.Device
+		saved      bool
 	)
 
-	userUUID, err = h.userFinderByJWT.GetUserUUIDByJWTToken(req.Context())
+	userUUID, err = h.accessService.GetUserUUIDByJWTToken(req.Context())
 	if err != nil {
 		h.log.Error(err)
 		_ = render.Render(res, req, ErrBadRequest)
 		return
 	}
-
-	err = req.ParseMultipartForm(4096)
+	deviceUUID, err = h.accessService.GetDeviceUUIDByJWTToken(req.Context())
 	if err != nil {
-		h.log.Error(err)
-		_ = render.Render(res, req, ErrInternalServerError)
+		h.log.Info(err)
+		_ = render.Render(res, req, ErrBadRequest)
 		return
 	}
-	keyData, _, err := req.FormFile(data_type.FileField)
-	if err != nil {
-		h.log.Error(err)
-		_ = render.Render(res, req, ErrInternalServerError)
+
+	keyBytes, errResponse := decryptClientPrivateKey(req, h.cryptService, h.log)
+	if errResponse != nil {
+		_ = render.Render(res, req, errResponse)
 		return
 	}
-	defer keyData.Close()
 
-	keyBytes, err := io.ReadAll(keyData)
+	device, err = h.manager.Device().FindOneByUUID(req.Context(), deviceUUID)
 	if err != nil {
 		h.log.Error(err)
 		_ = render.Render(res, req, ErrInternalServerError)
 		return
 	}
-	// Расшифровываем ключ
-	keyBytes, err = h.cryptService.DecryptRSA(keyBytes)
+	if device == nil || device.UserUUID != userUUID || !device.IsActive() {
+		h.log.Infof("Device %s of user %s is not found or revoked", deviceUUID, userUUID)
+		_ = render.Render(res, req, ErrBadRequest)
+		return
+	}
+	if device.ClientKey != "" {
+		var currentKey []byte
+		currentKey, err = h.keyProvider.Unwrap(device.ClientKey, []byte(deviceUUID))
+		if err != nil {
+			h.log.Error(err)
+			_ = render.Render(res, req, ErrInternalServerError)
+			return
+		}
+		if subtle.ConstantTimeCompare(currentKey, keyBytes) != 1 {
+			h.log.Infof("Device %s tried to replace the client key without rotation", deviceUUID)
+			_ = render.Render(res, req, ErrConflict(errors.New("the client key is already set, use key rotation")))
+			return
+		}
+		// тот же ключ отправлен повторно (новый вход с устройства)
+		res.WriteHeader(http.StatusOK)
+		return
+	}
+
+	// Секретный ключ клиента сохраняется зашифрованным KEK
+	keyString, err := h.keyProvider.Wrap(keyBytes, []byte(deviceUUID))
 	if err != nil {
 		h.log.Error(err)
 		_ = render.Render(res, req, ErrInternalServerError)
 		return
 	}
-	keyString := string(keyBytes)
-	// Секретный ключ клиента сохраняется
-	err = h.keySaver.SetPrivateClientKey(req.Context(), keyString, userUUID)
+	saved, err = h.manager.Device().SetClientKey(req.Context(), deviceUUID, keyString)
 	if err != nil {
 		h.log.Error(err)
 		_ = render.Render(res, req, ErrInternalServerError)
 		return
 	}
+	if !saved {
+		h.log.Infof("The client key of device %s has been saved by another request", deviceUUID)
+		_ = render.Render(res, req, ErrConflict(errors.New("the client key is already set, use key rotation")))
+		return
+	}
+	res.WriteHeader(http.StatusOK)
+}
+
+// decryptClientPrivateKey секретный ключ клиента из формы, расшифрованный по схеме обмена
+func decryptClientPrivateKey(req *http.Request, cryptService service.CryptService, log *logger.Logger) ([]byte, *ErrResponse) {
+	err := req.ParseMultipartForm(4096)
+	if err != nil {
+		log.Error(err)
+		return nil, ErrInternalServerError
+	}
+	keyData, _, err := req.FormFile(data_type.FileField)
+	if err != nil {
+		log.Error(err)
+		return nil, ErrInternalServerError
+	}
+	defer keyData.Close()
+
+	keyBytes, err := io.ReadAll(keyData)
+	if err != nil {
+		log.Error(err)
+		return nil, ErrInternalServerError
+	}
+	// Расшифровываем ключ
+	keyBytes, err = cryptService.DecryptKey(req.FormValue(data_type.KeyExchangeField), keyBytes)
+	if errors.Is(err, service.ErrUnsupportedKeyExchange) {
+		log.Info(err)
+		return nil, ErrBadRequest
+	}
+	if err != nil {
+		log.Error(err)
+		return nil, ErrInternalServerError
+	}
+	return keyBytes, nil
 }
@@ -2,6 +2,13 @@ package handlers
 
 import (
 	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
 	"fmt"
 	"io"
 	"mime/multipart"
@@ -13,34 +20,41 @@ import (
 
 	"github.com/northmule/gophkeeper/internal/common/data_type"
 	"github.com/northmule/gophkeeper/internal/common/keys"
+	"github.com/northmule/gophkeeper/internal/common/model_data"
 	"github.com/northmule/gophkeeper/internal/common/models"
+	"github.com/northmule/gophkeeper/internal/common/util"
 	"github.com/northmule/gophkeeper/internal/server/config"
 	"github.com/northmule/gophkeeper/internal/server/logger"
 	appMock "github.com/northmule/gophkeeper/internal/server/repository/mock"
+	service "github.com/northmule/gophkeeper/internal/server/services"
+	"github.com/northmule/gophkeeper/internal/server/services/kek"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 func TestKeysDataHandler_HandleSaveClientPublicKey_Successful(t *testing.T) {
 	mockAccessService := new(appMock.MockAccessService)
 	mockRepository := new(appMock.MockManager)
-	mockUserRepository := new(appMock.MockUserDataModelRepository)
+	mockDeviceRepository := new(appMock.MockDeviceModelRepository)
 	mockCryptService := new(appMock.MockCryptService)
 	l, _ := logger.NewLogger("info")
 	cfg := config.NewConfig()
 	_ = cfg.Init()
 	cfg.Value().PathKeys = t.TempDir()
 
-	mockRepository.On("User").Return(mockUserRepository)
+	mockRepository.On("Device").Return(mockDeviceRepository)
 	mockAccessService.On("GetUserUUIDByJWTToken", mock.Anything).Return("user123", nil)
-	mockUserRepository.On("SetPublicKey", mock.Anything, "publicKey", "user123").Return(nil)
+	mockAccessService.On("GetDeviceUUIDByJWTToken", mock.Anything).Return("device123", nil)
+	mockDeviceRepository.On("SetKeys", mock.Anything, "device123", "publicKey", "sealed vault key").Return(nil)
 
-	handler := NewKeysDataHandler(mockAccessService, mockCryptService, mockRepository, cfg, l)
+	handler := NewKeysDataHandler(mockAccessService, mockCryptService, newTestKeyProvider(t), nil, mockRepository, cfg, l)
 
 	body := &bytes.Buffer{}
 	writer := multipart.NewWriter(body)
 	part, _ := writer.CreateFormFile(data_type.FileField, "publicKey.pem")
 	io.WriteString(part, "publicKey")
+	_ = writer.WriteField(data_type.VaultKeyField, "sealed vault key")
 	writer.Close()
 	req := httptest.NewRequest("POST", "/keys/public", body)
 	req.Header.Set("Content-Type", "multipart/form-data")
@@ -65,8 +79,6 @@ func TestKeysDataHandler_HandleDownloadServerPublicKey_Successful(t *testing.T)
 	_ = cfg.Init()
 	cfg.Value().PathKeys = t.TempDir()
 
-	mockUserRepository.On("SetPublicKey", mock.Anything, "publicKey", "user123").Return(nil)
-
 	publicKeyPath := filepath.Join(cfg.Value().PathKeys, keys.PublicKeyFileName)
 	os.WriteFile(publicKeyPath, []byte("serverPublicKey"), 0644)
 
@@ -76,8 +88,9 @@ func TestKeysDataHandler_HandleDownloadServerPublicKey_Successful(t *testing.T)
 	mockRepository.On("User").Return(mockUserRepository)
 	mockAccessService.On("GetUserUUIDByJWTToken", mock.Anything).Return("user123", nil)
 	mockUserRepository.On("FindOneByUUID", mock.Anything, "user123").Return(user, nil)
+	mockCryptService.On("KeyExchanges").Return([]string{util.KeyExchangeX25519, util.KeyExchangeRSA})
 
-	handler := NewKeysDataHandler(mockAccessService, mockCryptService, mockRepository, cfg, l)
+	handler := NewKeysDataHandler(mockAccessService, mockCryptService, newTestKeyProvider(t), nil, mockRepository, cfg, l)
 
 	req := httptest.NewRequest("GET", "/keys/public", nil)
 	rr := httptest.NewRecorder()
@@ -86,10 +99,57 @@ func TestKeysDataHandler_HandleDownloadServerPublicKey_Successful(t *testing.T)
 
 	assert.Equal(t, http.StatusOK, rr.Code)
 	assert.Equal(t, "serverPublicKey", rr.Body.String())
+	// клиент без поддержки согласования получает только ключ
+	assert.Empty(t, rr.Header().Get(data_type.KeyExchangeHeader))
 	mockAccessService.AssertExpectations(t)
 	mockRepository.AssertExpectations(t)
 }
 
+func TestKeysDataHandler_HandleDownloadServerPublicKey_X25519(t *testing.T) {
+	mockAccessService := new(appMock.MockAccessService)
+	mockRepository := new(appMock.MockManager)
+	mockUserRepository := new(appMock.MockUserDataModelRepository)
+	mockCryptService := new(appMock.MockCryptService)
+	l, _ := logger.NewLogger("info")
+	cfg := config.NewConfig()
+	_ = cfg.Init()
+	cfg.Value().PathKeys = t.TempDir()
+
+	os.WriteFile(filepath.Join(cfg.Value().PathKeys, keys.PublicKeyFileName), []byte("serverPublicKey"), 0644)
+
+	user := new(models.User)
+	user.UUID = "user123"
+
+	mockRepository.On("User").Return(mockUserRepository)
+	mockAccessService.On("GetUserUUIDByJWTToken", mock.Anything).Return("user123", nil)
+	mockUserRepository.On("FindOneByUUID", mock.Anything, "user123").Return(user, nil)
+	mockCryptService.On("KeyExchanges").Return([]string{util.KeyExchangeX25519, util.KeyExchangeRSA})
+	mockCryptService.On("ExchangePublicKey").Return([]byte("exchangePublicKey"))
+
+	handler := NewKeysDataHandler(mockAccessService, mockCryptService, newTestKeyProvider(t), nil, mockRepository, cfg, l)
+
+	req := httptest.NewRequest("GET", "/keys/public", nil)
+	req.Header.Set(data_type.KeyExchangeHeader, util.KeyExchangeRSA+", "+util.KeyExchangeX25519)
+	rr := httptest.NewRecorder()
+
+	handler.HandleDownloadServerPublicKey(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "serverPublicKey", rr.Body.String())
+	assert.Equal(t, util.KeyExchangeX25519, rr.Header().Get(data_type.KeyExchangeHeader))
+	assert.Equal(t, base64.StdEncoding.EncodeToString([]byte("exchangePublicKey")), rr.Header().Get(data_type.KeyExchangePublicKeyHeader))
+	mockCryptService.AssertExpectations(t)
+}
+
+func TestNegotiateKeyExchange(t *testing.T) {
+	server := []string{util.KeyExchangeX25519, util.KeyExchangeRSA}
+
+	assert.Equal(t, util.KeyExchangeX25519, negotiateKeyExchange([]string{util.KeyExchangeRSA, util.KeyExchangeX25519}, server))
+	assert.Equal(t, util.KeyExchangeRSA, negotiateKeyExchange([]string{util.KeyExchangeRSA}, server))
+	assert.Equal(t, "", negotiateKeyExchange([]string{util.KeyExchangeRSA}, []string{util.KeyExchangeX25519}))
+	assert.Equal(t, "", negotiateKeyExchange(nil, server))
+}
+
 func TestKeysDataHandler_HandleDownloadServerPublicKey_InvalidJWTToken(t *testing.T) {
 	mockAccessService := new(appMock.MockAccessService)
 	mockRepository := new(appMock.MockManager)
@@ -101,7 +161,7 @@ func TestKeysDataHandler_HandleDownloadServerPublicKey_InvalidJWTToken(t *testin
 
 	mockAccessService.On("GetUserUUIDByJWTToken", mock.Anything).Return("", fmt.Errorf("invalid token"))
 
-	handler := NewKeysDataHandler(mockAccessService, mockCryptService, mockRepository, cfg, l)
+	handler := NewKeysDataHandler(mockAccessService, mockCryptService, newTestKeyProvider(t), nil, mockRepository, cfg, l)
 
 	req := httptest.NewRequest("GET", "/keys/public", nil)
 	rr := httptest.NewRecorder()
@@ -127,7 +187,7 @@ func TestKeysDataHandler_HandleDownloadServerPublicKey_NonExistentUser(t *testin
 	mockAccessService.On("GetUserUUIDByJWTToken", mock.Anything).Return("user123", nil)
 	mockUserRepository.On("FindOneByUUID", mock.Anything, "user123").Return(nil, fmt.Errorf("user not found"))
 
-	handler := NewKeysDataHandler(mockAccessService, mockCryptService, mockRepository, cfg, l)
+	handler := NewKeysDataHandler(mockAccessService, mockCryptService, newTestKeyProvider(t), nil, mockRepository, cfg, l)
 
 	req := httptest.NewRequest("GET", "/keys/public", nil)
 	rr := httptest.NewRecorder()
@@ -139,23 +199,46 @@ func TestKeysDataHandler_HandleDownloadServerPublicKey_NonExistentUser(t *testin
 	mockRepository.AssertExpectations(t)
 }
 
+func newKeyDevice(deviceUUID string, userUUID string, clientKey string) *models.Device {
+	device := &models.Device{UserUUID: userUUID, ClientKey: clientKey}
+	device.UUID = deviceUUID
+	return device
+}
+
+func newClientPrivateKeyRequest() *http.Request {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, _ := writer.CreateFormFile(data_type.FileField, "privateKey.pem")
+	_, _ = io.WriteString(part, "encryptedPrivateKey")
+	_ = writer.Close()
+	req := httptest.NewRequest("POST", "/keys/private", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	return req
+}
+
 func TestKeysDataHandler_HandleSaveClientPrivateKey_Successful(t *testing.T) {
 	mockAccessService := new(appMock.MockAccessService)
 	mockRepository := new(appMock.MockManager)
 	mockCryptService := new(appMock.MockCryptService)
-	mockUserRepository := new(appMock.MockUserDataModelRepository)
+	mockDeviceRepository := new(appMock.MockDeviceModelRepository)
 
 	l, _ := logger.NewLogger("info")
 	cfg := config.NewConfig()
 	_ = cfg.Init()
 	cfg.Value().PathKeys = t.TempDir()
 
-	mockRepository.On("User").Return(mockUserRepository)
+	mockRepository.On("Device").Return(mockDeviceRepository)
 	mockAccessService.On("GetUserUUIDByJWTToken", mock.Anything).Return("user123", nil)
-	mockCryptService.On("DecryptRSA", []byte("encryptedPrivateKey")).Return([]byte("privateKey"), nil)
-	mockUserRepository.On("SetPrivateClientKey", mock.Anything, "privateKey", "user123").Return(nil)
+	mockAccessService.On("GetDeviceUUIDByJWTToken", mock.Anything).Return("device123", nil)
+	mockCryptService.On("DecryptKey", "", []byte("encryptedPrivateKey")).Return([]byte("privateKey"), nil)
+	mockDeviceRepository.On("FindOneByUUID", mock.Anything, "device123").Return(newKeyDevice("device123", "user123", ""), nil)
+	var stored string
+	mockDeviceRepository.On("SetClientKey", mock.Anything, "device123", mock.MatchedBy(kek.IsWrapped)).Run(func(args mock.Arguments) {
+		stored = args.String(2)
+	}).Return(true, nil)
 
-	handler := NewKeysDataHandler(mockAccessService, mockCryptService, mockRepository, cfg, l)
+	keyProvider := newTestKeyProvider(t)
+	handler := NewKeysDataHandler(mockAccessService, mockCryptService, keyProvider, nil, mockRepository, cfg, l)
 
 	body := &bytes.Buffer{}
 	writer := multipart.NewWriter(body)
@@ -171,11 +254,127 @@ func TestKeysDataHandler_HandleSaveClientPrivateKey_Successful(t *testing.T) {
 	handler.HandleSaveClientPrivateKey(rr, req)
 
 	assert.Equal(t, http.StatusOK, rr.Code)
+	// в БД ключ попадает зашифрованным KEK, UUID устройства - дополнительные данные
+	assert.NotContains(t, stored, "privateKey")
+	plain, err := keyProvider.Unwrap(stored, []byte("device123"))
+	require.NoError(t, err)
+	assert.Equal(t, "privateKey", string(plain))
 	mockAccessService.AssertExpectations(t)
 	mockCryptService.AssertExpectations(t)
 	mockRepository.AssertExpectations(t)
 }
 
+func TestKeysDataHandler_HandleSaveClientPrivateKey_KeyAlreadySet(t *testing.T) {
+	l, _ := logger.NewLogger("info")
+	cfg := config.NewConfig()
+	_ = cfg.Init()
+	cfg.Value().PathKeys = t.TempDir()
+	keyProvider := newTestKeyProvider(t)
+	current, err := keyProvider.Wrap([]byte("privateKey"), []byte("device123"))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name         string
+		device       *models.Device
+		uploaded     string
+		expectedCode int
+	}{
+		// клиент повторно отправляет ключ при каждом входе
+		{"same key", newKeyDevice("device123", "user123", current), "privateKey", http.StatusOK},
+		// другой ключ задаётся только сменой ключа
+		{"another key", newKeyDevice("device123", "user123", current), "anotherKey", http.StatusConflict},
+		{"unknown device", nil, "privateKey", http.StatusBadRequest},
+		{"device of another user", newKeyDevice("device123", "user456", ""), "privateKey", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockAccessService := new(appMock.MockAccessService)
+			mockRepository := new(appMock.MockManager)
+			mockCryptService := new(appMock.MockCryptService)
+			mockDeviceRepository := new(appMock.MockDeviceModelRepository)
+			mockRepository.On("Device").Return(mockDeviceRepository)
+			mockAccessService.On("GetUserUUIDByJWTToken", mock.Anything).Return("user123", nil)
+			mockAccessService.On("GetDeviceUUIDByJWTToken", mock.Anything).Return("device123", nil)
+			mockCryptService.On("DecryptKey", "", []byte("encryptedPrivateKey")).Return([]byte(tt.uploaded), nil)
+			mockDeviceRepository.On("FindOneByUUID", mock.Anything, "device123").Return(tt.device, nil)
+
+			handler := NewKeysDataHandler(mockAccessService, mockCryptService, keyProvider, nil, mockRepository, cfg, l)
+
+			body := &bytes.Buffer{}
+			writer := multipart.NewWriter(body)
+			part, _ := writer.CreateFormFile(data_type.FileField, "privateKey.pem")
+			io.WriteString(part, "encryptedPrivateKey")
+			writer.Close()
+			req := httptest.NewRequest("POST", "/keys/private", body)
+			req.Header.Set("Content-Type", writer.FormDataContentType())
+
+			rr := httptest.NewRecorder()
+
+			handler.HandleSaveClientPrivateKey(rr, req)
+
+			assert.Equal(t, tt.expectedCode, rr.Code)
+			mockDeviceRepository.AssertNotCalled(t, "SetClientKey", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+// второе устройство пользователя сохраняет свой ключ, ключ первого устройства не меняется
+func TestKeysDataHandler_HandleSaveClientPrivateKey_SecondDevice(t *testing.T) {
+	l, _ := logger.NewLogger("info")
+	cfg := config.NewConfig()
+	_ = cfg.Init()
+	cfg.Value().PathKeys = t.TempDir()
+	keyProvider := newTestKeyProvider(t)
+	firstKey, err := keyProvider.Wrap([]byte("firstKey"), []byte("device1"))
+	require.NoError(t, err)
+
+	mockAccessService := new(appMock.MockAccessService)
+	mockRepository := new(appMock.MockManager)
+	mockCryptService := new(appMock.MockCryptService)
+	mockDeviceRepository := new(appMock.MockDeviceModelRepository)
+	mockRepository.On("Device").Return(mockDeviceRepository)
+	mockAccessService.On("GetUserUUIDByJWTToken", mock.Anything).Return("user123", nil)
+	mockAccessService.On("GetDeviceUUIDByJWTToken", mock.Anything).Return("device2", nil)
+	mockCryptService.On("DecryptKey", "", []byte("encryptedPrivateKey")).Return([]byte("secondKey"), nil)
+	mockDeviceRepository.On("FindOneByUUID", mock.Anything, "device1").Return(newKeyDevice("device1", "user123", firstKey), nil)
+	mockDeviceRepository.On("FindOneByUUID", mock.Anything, "device2").Return(newKeyDevice("device2", "user123", ""), nil)
+	mockDeviceRepository.On("SetClientKey", mock.Anything, "device2", mock.MatchedBy(kek.IsWrapped)).Return(true, nil)
+
+	handler := NewKeysDataHandler(mockAccessService, mockCryptService, keyProvider, nil, mockRepository, cfg, l)
+	rr := httptest.NewRecorder()
+	handler.HandleSaveClientPrivateKey(rr, newClientPrivateKeyRequest())
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	mockDeviceRepository.AssertNotCalled(t, "SetClientKey", mock.Anything, "device1", mock.Anything)
+
+	// ключ сохранён параллельным запросом с того же устройства
+	mockDeviceRepository.ExpectedCalls = nil
+	mockDeviceRepository.On("FindOneByUUID", mock.Anything, "device2").Return(newKeyDevice("device2", "user123", ""), nil)
+	mockDeviceRepository.On("SetClientKey", mock.Anything, "device2", mock.MatchedBy(kek.IsWrapped)).Return(false, nil)
+	rr = httptest.NewRecorder()
+	handler.HandleSaveClientPrivateKey(rr, newClientPrivateKeyRequest())
+	assert.Equal(t, http.StatusConflict, rr.Code)
+}
+
+func TestKeysDataHandler_HandleSaveClientPrivateKey_WithoutDevice(t *testing.T) {
+	mockAccessService := new(appMock.MockAccessService)
+	mockRepository := new(appMock.MockManager)
+	l, _ := logger.NewLogger("info")
+	cfg := config.NewConfig()
+	_ = cfg.Init()
+	cfg.Value().PathKeys = t.TempDir()
+
+	mockAccessService.On("GetUserUUIDByJWTToken", mock.Anything).Return("user123", nil)
+	mockAccessService.On("GetDeviceUUIDByJWTToken", mock.Anything).Return("", fmt.Errorf("no device found in claims"))
+
+	handler := NewKeysDataHandler(mockAccessService, new(appMock.MockCryptService), newTestKeyProvider(t), nil, mockRepository, cfg, l)
+	rr := httptest.NewRecorder()
+	handler.HandleSaveClientPrivateKey(rr, newClientPrivateKeyRequest())
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	mockRepository.AssertNotCalled(t, "Device")
+}
+
 func TestKeysDataHandler_HandleSaveClientPrivateKey_InvalidJWTToken(t *testing.T) {
 	mockAccessService := new(appMock.MockAccessService)
 	mockRepository := new(appMock.MockManager)
@@ -188,7 +387,7 @@ func TestKeysDataHandler_HandleSaveClientPrivateKey_InvalidJWTToken(t *testing.T
 
 	mockAccessService.On("GetUserUUIDByJWTToken", mock.Anything).Return("", fmt.Errorf("invalid token"))
 
-	handler := NewKeysDataHandler(mockAccessService, mockCryptService, mockRepository, cfg, l)
+	handler := NewKeysDataHandler(mockAccessService, mockCryptService, newTestKeyProvider(t), nil, mockRepository, cfg, l)
 
 	body := &bytes.Buffer{}
 	writer := multipart.NewWriter(body)
@@ -218,8 +417,9 @@ func TestKeysDataHandler_HandleSaveClientPrivateKey_InvalidFileUpload(t *testing
 	cfg.Value().PathKeys = t.TempDir()
 
 	mockAccessService.On("GetUserUUIDByJWTToken", mock.Anything).Return("user123", nil)
+	mockAccessService.On("GetDeviceUUIDByJWTToken", mock.Anything).Return("device123", nil)
 
-	handler := NewKeysDataHandler(mockAccessService, mockCryptService, mockRepository, cfg, l)
+	handler := NewKeysDataHandler(mockAccessService, mockCryptService, newTestKeyProvider(t), nil, mockRepository, cfg, l)
 
 	body := &bytes.Buffer{}
 	writer := multipart.NewWriter(body)
@@ -247,9 +447,10 @@ func TestKeysDataHandler_HandleSaveClientPrivateKey_DecryptionError(t *testing.T
 	cfg.Value().PathKeys = t.TempDir()
 
 	mockAccessService.On("GetUserUUIDByJWTToken", mock.Anything).Return("user123", nil)
-	mockCryptService.On("DecryptRSA", []byte("encryptedPrivateKey")).Return(nil, fmt.Errorf("decryption failed"))
+	mockAccessService.On("GetDeviceUUIDByJWTToken", mock.Anything).Return("device123", nil)
+	mockCryptService.On("DecryptKey", "", []byte("encryptedPrivateKey")).Return(nil, fmt.Errorf("decryption failed"))
 
-	handler := NewKeysDataHandler(mockAccessService, mockCryptService, mockRepository, cfg, l)
+	handler := NewKeysDataHandler(mockAccessService, mockCryptService, newTestKeyProvider(t), nil, mockRepository, cfg, l)
 
 	body := &bytes.Buffer{}
 	writer := multipart.NewWriter(body)
@@ -269,23 +470,58 @@ func TestKeysDataHandler_HandleSaveClientPrivateKey_DecryptionError(t *testing.T
 	mockCryptService.AssertExpectations(t)
 }
 
+func TestKeysDataHandler_HandleSaveClientPrivateKey_UnsupportedKeyExchange(t *testing.T) {
+	mockAccessService := new(appMock.MockAccessService)
+	mockRepository := new(appMock.MockManager)
+	mockCryptService := new(appMock.MockCryptService)
+	l, _ := logger.NewLogger("info")
+	cfg := config.NewConfig()
+	_ = cfg.Init()
+	cfg.Value().PathKeys = t.TempDir()
+
+	mockAccessService.On("GetUserUUIDByJWTToken", mock.Anything).Return("user123", nil)
+	mockAccessService.On("GetDeviceUUIDByJWTToken", mock.Anything).Return("device123", nil)
+	mockCryptService.On("DecryptKey", util.KeyExchangeRSA, []byte("encryptedPrivateKey")).Return(nil, service.ErrUnsupportedKeyExchange)
+
+	handler := NewKeysDataHandler(mockAccessService, mockCryptService, newTestKeyProvider(t), nil, mockRepository, cfg, l)
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	_ = writer.WriteField(data_type.KeyExchangeField, util.KeyExchangeRSA)
+	part, _ := writer.CreateFormFile(data_type.FileField, "privateKey.pem")
+	io.WriteString(part, "encryptedPrivateKey")
+	writer.Close()
+	req := httptest.NewRequest("POST", "/keys/private", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	rr := httptest.NewRecorder()
+
+	handler.HandleSaveClientPrivateKey(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	mockCryptService.AssertExpectations(t)
+	mockRepository.AssertNotCalled(t, "Device")
+}
+
 func TestKeysDataHandler_HandleSaveClientPrivateKey_RepositoryError(t *testing.T) {
 	mockAccessService := new(appMock.MockAccessService)
 	mockRepository := new(appMock.MockManager)
 	mockCryptService := new(appMock.MockCryptService)
-	mockUserRepository := new(appMock.MockUserDataModelRepository)
+	mockDeviceRepository := new(appMock.MockDeviceModelRepository)
 
 	l, _ := logger.NewLogger("info")
 	cfg := config.NewConfig()
 	_ = cfg.Init()
 	cfg.Value().PathKeys = t.TempDir()
 
-	mockRepository.On("User").Return(mockUserRepository)
+	mockRepository.On("Device").Return(mockDeviceRepository)
 	mockAccessService.On("GetUserUUIDByJWTToken", mock.Anything).Return("user123", nil)
-	mockCryptService.On("DecryptRSA", []byte("encryptedPrivateKey")).Return([]byte("privateKey"), nil)
-	mockUserRepository.On("SetPrivateClientKey", mock.Anything, "privateKey", "user123").Return(fmt.Errorf("repository error"))
+	mockAccessService.On("GetDeviceUUIDByJWTToken", mock.Anything).Return("device123", nil)
+	mockCryptService.On("DecryptKey", "", []byte("encryptedPrivateKey")).Return([]byte("privateKey"), nil)
+	mockDeviceRepository.On("FindOneByUUID", mock.Anything, "device123").Return(newKeyDevice("device123", "user123", ""), nil)
+	mockDeviceRepository.On("SetClientKey", mock.Anything, "device123", mock.MatchedBy(kek.IsWrapped)).Return(false, fmt.Errorf("repository error"))
 
-	handler := NewKeysDataHandler(mockAccessService, mockCryptService, mockRepository, cfg, l)
+	handler := NewKeysDataHandler(mockAccessService, mockCryptService, newTestKeyProvider(t), nil, mockRepository, cfg, l)
 
 	body := &bytes.Buffer{}
 	writer := multipart.NewWriter(body)
@@ -317,8 +553,9 @@ func TestKeysDataHandler_HandleSaveClientPublicKey_InvalidFileUpload(t *testing.
 	cfg.Value().PathKeys = t.TempDir()
 
 	mockAccessService.On("GetUserUUIDByJWTToken", mock.Anything).Return("user123", nil)
+	mockAccessService.On("GetDeviceUUIDByJWTToken", mock.Anything).Return("device123", nil)
 
-	handler := NewKeysDataHandler(mockAccessService, mockCryptService, mockRepository, cfg, l)
+	handler := NewKeysDataHandler(mockAccessService, mockCryptService, newTestKeyProvider(t), nil, mockRepository, cfg, l)
 
 	body := &bytes.Buffer{}
 	writer := multipart.NewWriter(body)
@@ -336,22 +573,42 @@ func TestKeysDataHandler_HandleSaveClientPublicKey_InvalidFileUpload(t *testing.
 	mockAccessService.AssertExpectations(t)
 }
 
+func TestKeysDataHandler_HandleSaveClientPublicKey_WithoutDevice(t *testing.T) {
+	mockAccessService := new(appMock.MockAccessService)
+	mockRepository := new(appMock.MockManager)
+	l, _ := logger.NewLogger("info")
+	cfg := config.NewConfig()
+	cfg.Value().PathKeys = t.TempDir()
+
+	mockAccessService.On("GetUserUUIDByJWTToken", mock.Anything).Return("user123", nil)
+	mockAccessService.On("GetDeviceUUIDByJWTToken", mock.Anything).Return("", fmt.Errorf("no device found in claims"))
+
+	handler := NewKeysDataHandler(mockAccessService, new(appMock.MockCryptService), newTestKeyProvider(t), nil, mockRepository, cfg, l)
+	req := httptest.NewRequest(http.MethodPost, "/save_public_key", nil)
+	rr := httptest.NewRecorder()
+	handler.HandleSaveClientPublicKey(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	mockRepository.AssertNotCalled(t, "Device")
+}
+
 func TestKeysDataHandler_HandleSaveClientPublicKey_RepositoryError(t *testing.T) {
 	mockAccessService := new(appMock.MockAccessService)
 	mockRepository := new(appMock.MockManager)
 	mockCryptService := new(appMock.MockCryptService)
-	mockUserRepository := new(appMock.MockUserDataModelRepository)
+	mockDeviceRepository := new(appMock.MockDeviceModelRepository)
 
 	l, _ := logger.NewLogger("info")
 	cfg := config.NewConfig()
 	_ = cfg.Init()
 	cfg.Value().PathKeys = t.TempDir()
 
-	mockRepository.On("User").Return(mockUserRepository)
+	mockRepository.On("Device").Return(mockDeviceRepository)
 	mockAccessService.On("GetUserUUIDByJWTToken", mock.Anything).Return("user123", nil)
-	mockUserRepository.On("SetPublicKey", mock.Anything, mock.Anything, mock.Anything).Return(fmt.Errorf("repository error"))
+	mockAccessService.On("GetDeviceUUIDByJWTToken", mock.Anything).Return("device123", nil)
+	mockDeviceRepository.On("SetKeys", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(fmt.Errorf("repository error"))
 
-	handler := NewKeysDataHandler(mockAccessService, mockCryptService, mockRepository, cfg, l)
+	handler := NewKeysDataHandler(mockAccessService, mockCryptService, newTestKeyProvider(t), nil, mockRepository, cfg, l)
 
 	body := &bytes.Buffer{}
 	writer := multipart.NewWriter(body)
@@ -370,3 +627,77 @@ func TestKeysDataHandler_HandleSaveClientPublicKey_RepositoryError(t *testing.T)
 	mockAccessService.AssertExpectations(t)
 	mockRepository.AssertExpectations(t)
 }
+
+func TestKeysDataHandler_HandleSaveClientPublicKey_IssueCertificate(t *testing.T) {
+	l, _ := logger.NewLogger("info")
+	cfg := config.NewConfig()
+	cfg.Value().PathKeys = t.TempDir()
+	authority := newTestAuthority(t)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	publicKeyBytes, err := x509.MarshalPKIXPublicKey(key.Public())
+	require.NoError(t, err)
+	publicKeyPEM := string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicKeyBytes}))
+
+	newRequest := func(value string) *http.Request {
+		body := &bytes.Buffer{}
+		writer := multipart.NewWriter(body)
+		part, _ := writer.CreateFormFile(data_type.FileField, "public_key")
+		_, _ = io.WriteString(part, value)
+		_ = writer.Close()
+		req := httptest.NewRequest(http.MethodPost, "/save_public_key", body)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		return req
+	}
+
+	t.Run("certificate issued", func(t *testing.T) {
+		mockAccessService := new(appMock.MockAccessService)
+		mockRepository := new(appMock.MockManager)
+		mockDeviceRepository := new(appMock.MockDeviceModelRepository)
+		mockCertificateRepository := new(appMock.MockClientCertificateModelRepository)
+		mockRepository.On("Device").Return(mockDeviceRepository)
+		mockRepository.On("ClientCertificate").Return(mockCertificateRepository)
+		mockAccessService.On("GetUserUUIDByJWTToken", mock.Anything).Return("user123", nil)
+		mockAccessService.On("GetDeviceUUIDByJWTToken", mock.Anything).Return("device123", nil)
+		mockDeviceRepository.On("SetKeys", mock.Anything, "device123", publicKeyPEM, "").Return(nil)
+		var saved *models.ClientCertificate
+		mockCertificateRepository.On("Add", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+			saved = args.Get(1).(*models.ClientCertificate)
+		}).Return(int64(1), nil)
+
+		handler := NewKeysDataHandler(mockAccessService, new(appMock.MockCryptService), newTestKeyProvider(t), authority, mockRepository, cfg, l)
+		rr := httptest.NewRecorder()
+		handler.HandleSaveClientPublicKey(rr, newRequest(publicKeyPEM))
+
+		require.Equal(t, http.StatusOK, rr.Code)
+		response := new(model_data.ClientCertificateResponse)
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), response))
+		fingerprint, err := keys.CertificateFingerprint([]byte(response.Certificate))
+		require.NoError(t, err)
+		assert.Equal(t, fingerprint, response.Fingerprint)
+		assert.Equal(t, string(authority.CertificatePEM()), response.CACertificate)
+		require.NotNil(t, saved)
+		assert.Equal(t, "user123", saved.UserUUID)
+		assert.Equal(t, "device123", saved.DeviceUUID)
+		assert.Equal(t, fingerprint, saved.Fingerprint)
+		assert.NotEmpty(t, saved.PublicKeyHash)
+	})
+
+	t.Run("invalid public key", func(t *testing.T) {
+		mockAccessService := new(appMock.MockAccessService)
+		mockRepository := new(appMock.MockManager)
+		mockDeviceRepository := new(appMock.MockDeviceModelRepository)
+		mockRepository.On("Device").Return(mockDeviceRepository)
+		mockAccessService.On("GetUserUUIDByJWTToken", mock.Anything).Return("user123", nil)
+		mockAccessService.On("GetDeviceUUIDByJWTToken", mock.Anything).Return("device123", nil)
+		mockDeviceRepository.On("SetKeys", mock.Anything, "device123", "publicKey", "").Return(nil)
+
+		handler := NewKeysDataHandler(mockAccessService, new(appMock.MockCryptService), newTestKeyProvider(t), authority, mockRepository, cfg, l)
+		rr := httptest.NewRecorder()
+		handler.HandleSaveClientPublicKey(rr, newRequest("publicKey"))
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		mockRepository.AssertNotCalled(t, "ClientCertificate")
+	})
+}
@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/render"
+	"github.com/northmule/gophkeeper/internal/common/model_data"
+	"github.com/northmule/gophkeeper/internal/common/models"
+	"github.com/northmule/gophkeeper/internal/server/logger"
+	"github.com/northmule/gophkeeper/internal/server/repository"
+)
+
+// Мастер-ключ выводится на клиенте из мастер-пароля (Argon2id), сервер хранит только соль
+// и контрольное значение, по которому клиент проверяет правильность введённого пароля.
+// Сам ключ и мастер-пароль на сервер не передаются.
+
+// MasterKeyHandler параметры мастер-ключа клиента
+type MasterKeyHandler struct {
+	log           *logger.Logger
+	accessService UserFinderByJWT
+	manager       repository.Repository
+}
+
+// NewMasterKeyHandler конструктор
+func NewMasterKeyHandler(accessService UserFinderByJWT, manager repository.Repository, log *logger.Logger) *MasterKeyHandler {
+	return &MasterKeyHandler{
+		accessService: accessService,
+		manager:       manager,
+		log:           log,
+	}
+}
+
+type masterKeyRequest struct {
+	model_data.MasterKeyRequest
+}
+
+// Bind декодирует json в структуру
+func (rr *masterKeyRequest) Bind(r *http.Request) error {
+	return nil
+}
+
+type masterKeyResponse struct {
+	model_data.MasterKeyResponse
+}
+
+func (hr masterKeyResponse) Render(res http.ResponseWriter, req *http.Request) error {
+	return nil
+}
+
+// HandleGet параметры мастер-ключа пользователя
+func (h *MasterKeyHandler) HandleGet(res http.ResponseWriter, req *http.Request) {
+	var (
+		err      error
+		userUUID string
+		user     *models.User
+	)
+
+	userUUID, err = h.accessService.GetUserUUIDByJWTToken(req.Context())
+	if err != nil {
+		h.log.Error(err)
+		_ = render.Render(res, req, ErrBadRequest)
+		return
+	}
+
+	user, err = h.manager.User().FindOneByUUID(req.Context(), userUUID)
+	if err != nil {
+		h.log.Error(err)
+		_ = render.Render(res, req, ErrInternalServerError)
+		return
+	}
+
+	response := new(masterKeyResponse)
+	response.Salt = user.MasterKeySalt
+	response.Check = user.MasterKeyCheck
+	response.Legacy = user.VaultLegacy
+
+	err = render.Render(res, req, response)
+	if err != nil {
+		h.log.Error(err)
+		_ = render.Render(res, req, ErrInternalServerError)
+	}
+}
+
+// HandleSave сохраняет параметры мастер-ключа, повторная установка не допускается
+func (h *MasterKeyHandler) HandleSave(res http.ResponseWriter, req *http.Request) {
+	var (
+		err      error
+		userUUID string
+		user     *models.User
+	)
+
+	request := new(masterKeyRequest)
+	if err = render.Bind(req, request); err != nil {
+		h.log.Info(err)
+		_ = render.Render(res, req, ErrBadRequest)
+		return
+	}
+
+	userUUID, err = h.accessService.GetUserUUIDByJWTToken(req.Context())
+	if err != nil {
+		h.log.Error(err)
+		_ = render.Render(res, req, ErrBadRequest)
+		return
+	}
+
+	user, err = h.manager.User().FindOneByUUID(req.Context(), userUUID)
+	if err != nil {
+		h.log.Error(err)
+		_ = render.Render(res, req, ErrInternalServerError)
+		return
+	}
+	if user.MasterKeySalt != "" {
+		h.log.Infof("master key already set: user %s", userUUID)
+		_ = render.Render(res, req, ErrConflict(errors.New("master key already set")))
+		return
+	}
+
+	// параметры могли сохранить с другого устройства после проверки выше
+	saved, err := h.manager.User().SetMasterKey(req.Context(), request.Salt, request.Check, userUUID)
+	if err != nil {
+		h.log.Error(err)
+		_ = render.Render(res, req, ErrInternalServerError)
+		return
+	}
+	if !saved {
+		h.log.Infof("master key already set: user %s", userUUID)
+		_ = render.Render(res, req, ErrConflict(errors.New("master key already set")))
+		return
+	}
+
+	res.WriteHeader(http.StatusOK)
+}
+
+// HandleMigrated снимает признак данных без шифрования мастер-ключом: клиент перешифровал их
+func (h *MasterKeyHandler) HandleMigrated(res http.ResponseWriter, req *http.Request) {
+	userUUID, err := h.accessService.GetUserUUIDByJWTToken(req.Context())
+	if err != nil {
+		h.log.Error(err)
+		_ = render.Render(res, req, ErrBadRequest)
+		return
+	}
+
+	err = h.manager.User().ClearVaultLegacy(req.Context(), userUUID)
+	if err != nil {
+		h.log.Error(err)
+		_ = render.Render(res, req, ErrInternalServerError)
+		return
+	}
+
+	res.WriteHeader(http.StatusOK)
+}
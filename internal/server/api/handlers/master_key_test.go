@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/northmule/gophkeeper/internal/common/model_data"
+	"github.com/northmule/gophkeeper/internal/common/models"
+	"github.com/northmule/gophkeeper/internal/server/logger"
+	appMock "github.com/northmule/gophkeeper/internal/server/repository/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestMasterKeyHandler_HandleGet(t *testing.T) {
+	l, _ := logger.NewLogger("info")
+
+	t.Run("ok", func(t *testing.T) {
+		mockAccessService := new(appMock.MockAccessService)
+		mockRepository := new(appMock.MockManager)
+		mockUserRepository := new(appMock.MockUserDataModelRepository)
+		mockRepository.On("User").Return(mockUserRepository)
+		mockAccessService.On("GetUserUUIDByJWTToken", mock.Anything).Return("user123", nil)
+		mockUserRepository.On("FindOneByUUID", mock.Anything, "user123").Return(&models.User{MasterKeySalt: "c2FsdA==", MasterKeyCheck: "check", VaultLegacy: true}, nil)
+
+		req, _ := http.NewRequest(http.MethodGet, "/master_key", nil)
+		res := httptest.NewRecorder()
+		NewMasterKeyHandler(mockAccessService, mockRepository, l).HandleGet(res, req)
+
+		assert.Equal(t, http.StatusOK, res.Code)
+		response := new(model_data.MasterKeyResponse)
+		assert.NoError(t, json.Unmarshal(res.Body.Bytes(), response))
+		assert.Equal(t, "c2FsdA==", response.Salt)
+		assert.Equal(t, "check", response.Check)
+		assert.True(t, response.Legacy)
+	})
+
+	t.Run("user_error", func(t *testing.T) {
+		mockAccessService := new(appMock.MockAccessService)
+		mockRepository := new(appMock.MockManager)
+		mockUserRepository := new(appMock.MockUserDataModelRepository)
+		mockRepository.On("User").Return(mockUserRepository)
+		mockAccessService.On("GetUserUUIDByJWTToken", mock.Anything).Return("user123", nil)
+		mockUserRepository.On("FindOneByUUID", mock.Anything, "user123").Return(nil, errors.New("db error"))
+
+		req, _ := http.NewRequest(http.MethodGet, "/master_key", nil)
+		res := httptest.NewRecorder()
+		NewMasterKeyHandler(mockAccessService, mockRepository, l).HandleGet(res, req)
+
+		assert.Equal(t, http.StatusInternalServerError, res.Code)
+	})
+}
+
+func TestMasterKeyHandler_HandleSave(t *testing.T) {
+	l, _ := logger.NewLogger("info")
+	reqBody, _ := json.Marshal(model_data.MasterKeyRequest{Salt: "c2FsdA==", Check: "check"})
+
+	t.Run("ok", func(t *testing.T) {
+		mockAccessService := new(appMock.MockAccessService)
+		mockRepository := new(appMock.MockManager)
+		mockUserRepository := new(appMock.MockUserDataModelRepository)
+		mockRepository.On("User").Return(mockUserRepository)
+		mockAccessService.On("GetUserUUIDByJWTToken", mock.Anything).Return("user123", nil)
+		mockUserRepository.On("FindOneByUUID", mock.Anything, "user123").Return(&models.User{}, nil)
+		mockUserRepository.On("SetMasterKey", mock.Anything, "c2FsdA==", "check", "user123").Return(true, nil)
+
+		req, _ := http.NewRequest(http.MethodPost, "/save_master_key", bytes.NewBuffer(reqBody))
+		req.Header.Set("Content-Type", "application/json")
+		res := httptest.NewRecorder()
+		NewMasterKeyHandler(mockAccessService, mockRepository, l).HandleSave(res, req)
+
+		assert.Equal(t, http.StatusOK, res.Code)
+		mockUserRepository.AssertExpectations(t)
+	})
+
+	t.Run("already_set", func(t *testing.T) {
+		mockAccessService := new(appMock.MockAccessService)
+		mockRepository := new(appMock.MockManager)
+		mockUserRepository := new(appMock.MockUserDataModelRepository)
+		mockRepository.On("User").Return(mockUserRepository)
+		mockAccessService.On("GetUserUUIDByJWTToken", mock.Anything).Return("user123", nil)
+		mockUserRepository.On("FindOneByUUID", mock.Anything, "user123").Return(&models.User{MasterKeySalt: "b2xk"}, nil)
+
+		req, _ := http.NewRequest(http.MethodPost, "/save_master_key", bytes.NewBuffer(reqBody))
+		req.Header.Set("Content-Type", "application/json")
+		res := httptest.NewRecorder()
+		NewMasterKeyHandler(mockAccessService, mockRepository, l).HandleSave(res, req)
+
+		assert.Equal(t, http.StatusConflict, res.Code)
+		mockUserRepository.AssertNotCalled(t, "SetMasterKey", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("set_concurrently", func(t *testing.T) {
+		mockAccessService := new(appMock.MockAccessService)
+		mockRepository := new(appMock.MockManager)
+		mockUserRepository := new(appMock.MockUserDataModelRepository)
+		mockRepository.On("User").Return(mockUserRepository)
+		mockAccessService.On("GetUserUUIDByJWTToken", mock.Anything).Return("user123", nil)
+		mockUserRepository.On("FindOneByUUID", mock.Anything, "user123").Return(&models.User{}, nil)
+		mockUserRepository.On("SetMasterKey", mock.Anything, "c2FsdA==", "check", "user123").Return(false, nil)
+
+		req, _ := http.NewRequest(http.MethodPost, "/save_master_key", bytes.NewBuffer(reqBody))
+		req.Header.Set("Content-Type", "application/json")
+		res := httptest.NewRecorder()
+		NewMasterKeyHandler(mockAccessService, mockRepository, l).HandleSave(res, req)
+
+		assert.Equal(t, http.StatusConflict, res.Code)
+	})
+
+	t.Run("save_error", func(t *testing.T) {
+		mockAccessService := new(appMock.MockAccessService)
+		mockRepository := new(appMock.MockManager)
+		mockUserRepository := new(appMock.MockUserDataModelRepository)
+		mockRepository.On("User").Return(mockUserRepository)
+		mockAccessService.On("GetUserUUIDByJWTToken", mock.Anything).Return("user123", nil)
+		mockUserRepository.On("FindOneByUUID", mock.Anything, "user123").Return(&models.User{}, nil)
+		mockUserRepository.On("SetMasterKey", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(false, errors.New("db error"))
+
+		req, _ := http.NewRequest(http.MethodPost, "/save_master_key", bytes.NewBuffer(reqBody))
+		req.Header.Set("Content-Type", "application/json")
+		res := httptest.NewRecorder()
+		NewMasterKeyHandler(mockAccessService, mockRepository, l).HandleSave(res, req)
+
+		assert.Equal(t, http.StatusInternalServerError, res.Code)
+	})
+}
+
+func TestMasterKeyHandler_HandleMigrated(t *testing.T) {
+	l, _ := logger.NewLogger("info")
+
+	t.Run("ok", func(t *testing.T) {
+		mockAccessService := new(appMock.MockAccessService)
+		mockRepository := new(appMock.MockManager)
+		mockUserRepository := new(appMock.MockUserDataModelRepository)
+		mockRepository.On("User").Return(mockUserRepository)
+		mockAccessService.On("GetUserUUIDByJWTToken", mock.Anything).Return("user123", nil)
+		mockUserRepository.On("ClearVaultLegacy", mock.Anything, "user123").Return(nil)
+
+		req, _ := http.NewRequest(http.MethodPost, "/master_key/migrated", nil)
+		res := httptest.NewRecorder()
+		NewMasterKeyHandler(mockAccessService, mockRepository, l).HandleMigrated(res, req)
+
+		assert.Equal(t, http.StatusOK, res.Code)
+		mockUserRepository.AssertExpectations(t)
+	})
+
+	t.Run("clear_error", func(t *testing.T) {
+		mockAccessService := new(appMock.MockAccessService)
+		mockRepository := new(appMock.MockManager)
+		mockUserRepository := new(appMock.MockUserDataModelRepository)
+		mockRepository.On("User").Return(mockUserRepository)
+		mockAccessService.On("GetUserUUIDByJWTToken", mock.Anything).Return("user123", nil)
+		mockUserRepository.On("ClearVaultLegacy", mock.Anything, "user123").Return(errors.New("db error"))
+
+		req, _ := http.NewRequest(http.MethodPost, "/master_key/migrated", nil)
+		res := httptest.NewRecorder()
+		NewMasterKeyHandler(mockAccessService, mockRepository, l).HandleMigrated(res, req)
+
+		assert.Equal(t, http.StatusInternalServerError, res.Code)
+	})
+}
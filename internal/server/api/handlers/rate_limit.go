@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/go-chi/render"
+	"github.com/northmule/gophkeeper/internal/server/logger"
+	"github.com/northmule/gophkeeper/internal/server/storage"
+)
+
+// rateLimitBodyMaxSize размер тела запросов входа и регистрации, которое читается для поиска логина
+const rateLimitBodyMaxSize = 16 << 10
+
+// RateLimitHandler ограничение частоты запросов входа и регистрации по IP и по логину
+type RateLimitHandler struct {
+	byIP    storage.RateLimiterManager
+	byLogin storage.RateLimiterManager
+	log     *logger.Logger
+}
+
+// NewRateLimitHandler конструктор
+func NewRateLimitHandler(byIP storage.RateLimiterManager, byLogin storage.RateLimiterManager, log *logger.Logger) *RateLimitHandler {
+	return &RateLimitHandler{
+		byIP:    byIP,
+		byLogin: byLogin,
+		log:     log,
+	}
+}
+
+// HandleLimit отклоняет запрос с 429 и Retry-After, если исчерпан лимит IP или логина из тела запроса
+func (h *RateLimitHandler) HandleLimit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		ip := remoteIP(req)
+		if allowed, retryAfter := h.byIP.Allow(ip); !allowed {
+			h.log.Infof("Too many requests from %s to %s", ip, req.URL.Path)
+			_ = render.Render(res, req, ErrTooManyRequests(retryAfter))
+			return
+		}
+
+		// копия body, размер ограничен: тело читается целиком до проверки лимита логина
+		bodyBytes, err := io.ReadAll(http.MaxBytesReader(res, req.Body, rateLimitBodyMaxSize))
+		if err != nil {
+			h.log.Infof("Failed to read request body from %s to %s: %s", ip, req.URL.Path, err)
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				_ = render.Render(res, req, ErrRequestTooLarge)
+				return
+			}
+			_ = render.Render(res, req, ErrBadRequest)
+			return
+		}
+		req.Body = io.NopCloser(bytes.NewBuffer(bodyBytes))
+		request := struct {
+			Login string `json:"login"`
+		}{}
+		if json.Unmarshal(bodyBytes, &request) == nil && request.Login != "" {
+			if allowed, retryAfter := h.byLogin.Allow(request.Login); !allowed {
+				h.log.Infof("Too many requests with login %s to %s", request.Login, req.URL.Path)
+				_ = render.Render(res, req, ErrTooManyRequests(retryAfter))
+				return
+			}
+		}
+
+		next.ServeHTTP(res, req)
+	})
+}
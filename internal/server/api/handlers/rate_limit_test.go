@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/northmule/gophkeeper/internal/server/logger"
+	"github.com/northmule/gophkeeper/internal/server/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimitHandler_HandleLimit(t *testing.T) {
+	l, _ := logger.NewLogger("info")
+	var body string
+	next := http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		raw, _ := io.ReadAll(req.Body)
+		body = string(raw)
+	})
+
+	send := func(handler http.Handler, remoteAddr string, reqBody string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/login", bytes.NewBufferString(reqBody))
+		req.RemoteAddr = remoteAddr
+		res := httptest.NewRecorder()
+		handler.ServeHTTP(res, req)
+		return res
+	}
+
+	t.Run("limit by ip", func(t *testing.T) {
+		handler := NewRateLimitHandler(storage.NewRateLimiter(time.Minute, 2), storage.NewRateLimiter(time.Minute, 0), l).HandleLimit(next)
+
+		assert.Equal(t, http.StatusOK, send(handler, "10.0.0.1:1000", `{"login": "a"}`).Code)
+		assert.Equal(t, `{"login": "a"}`, body)
+		assert.Equal(t, http.StatusOK, send(handler, "10.0.0.1:1001", `{"login": "b"}`).Code)
+		res := send(handler, "10.0.0.1:1002", `{"login": "c"}`)
+		assert.Equal(t, http.StatusTooManyRequests, res.Code)
+		assert.Equal(t, "60", res.Header().Get("Retry-After"))
+		assert.Equal(t, http.StatusOK, send(handler, "10.0.0.2:1000", `{"login": "c"}`).Code)
+	})
+
+	t.Run("limit by login", func(t *testing.T) {
+		handler := NewRateLimitHandler(storage.NewRateLimiter(time.Minute, 0), storage.NewRateLimiter(time.Minute, 1), l).HandleLimit(next)
+
+		assert.Equal(t, http.StatusOK, send(handler, "10.0.0.1:1000", `{"login": "a"}`).Code)
+		assert.Equal(t, http.StatusTooManyRequests, send(handler, "10.0.0.2:1000", `{"login": "a"}`).Code)
+		assert.Equal(t, http.StatusOK, send(handler, "10.0.0.2:1000", `{"login": "b"}`).Code)
+		// без логина в теле ограничивается только IP
+		assert.Equal(t, http.StatusOK, send(handler, "10.0.0.2:1000", `{"mfa_token": "token"}`).Code)
+		assert.Equal(t, http.StatusOK, send(handler, "10.0.0.2:1000", `{"mfa_token": "token"}`).Code)
+	})
+
+	t.Run("body too large", func(t *testing.T) {
+		handler := NewRateLimitHandler(storage.NewRateLimiter(time.Minute, 0), storage.NewRateLimiter(time.Minute, 0), l).HandleLimit(next)
+		body = ""
+
+		res := send(handler, "10.0.0.1:1000", `{"login": "`+strings.Repeat("a", rateLimitBodyMaxSize)+`"}`)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, res.Code)
+		assert.Empty(t, body)
+	})
+}
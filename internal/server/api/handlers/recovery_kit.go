@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/go-chi/render"
+	"github.com/northmule/gophkeeper/internal/common/model_data"
+	"github.com/northmule/gophkeeper/internal/common/models"
+	"github.com/northmule/gophkeeper/internal/server/logger"
+	"github.com/northmule/gophkeeper/internal/server/repository"
+)
+
+// Комплект восстановления создаётся на клиенте: ключ хранилища шифруется ключом восстановления
+// (печатается пользователю, дополнительно делится на части по схеме Шамира), ключ шифрования клиента - ключом хранилища.
+// Сервер хранит оба значения как есть и отдаёт их пользователю для восстановления ключей после потери PathKeys.
+
+// RecoveryKitHandler комплект восстановления клиента
+type RecoveryKitHandler struct {
+	log           *logger.Logger
+	accessService UserFinderByJWT
+	manager       repository.Repository
+}
+
+// NewRecoveryKitHandler конструктор
+func NewRecoveryKitHandler(accessService UserFinderByJWT, manager repository.Repository, log *logger.Logger) *RecoveryKitHandler {
+	return &RecoveryKitHandler{
+		accessService: accessService,
+		manager:       manager,
+		log:           log,
+	}
+}
+
+type recoveryKitRequest struct {
+	model_data.RecoveryKitRequest
+}
+
+// Bind декодирует json в структуру
+func (rr *recoveryKitRequest) Bind(r *http.Request) error {
+	return nil
+}
+
+type recoveryKitResponse struct {
+	model_data.RecoveryKitResponse
+}
+
+func (rr recoveryKitResponse) Render(res http.ResponseWriter, req *http.Request) error {
+	return nil
+}
+
+// HandleGet комплект восстановления пользователя
+func (h *RecoveryKitHandler) HandleGet(res http.ResponseWriter, req *http.Request) {
+	var (
+		err      error
+		userUUID string
+		kit      *models.RecoveryKit
+	)
+
+	userUUID, err = h.accessService.GetUserUUIDByJWTToken(req.Context())
+	if err != nil {
+		h.log.Error(err)
+		_ = render.Render(res, req, ErrBadRequest)
+		return
+	}
+
+	kit, err = h.manager.RecoveryKit().FindOneByUserUUID(req.Context(), userUUID)
+	if err != nil {
+		h.log.Error(err)
+		_ = render.Render(res, req, ErrInternalServerError)
+		return
+	}
+	if kit == nil {
+		_ = render.Render(res, req, ErrNotFound)
+		return
+	}
+
+	response := new(recoveryKitResponse)
+	response.VaultKey = kit.VaultKey
+	response.ClientKey = kit.ClientKey
+
+	err = render.Render(res, req, response)
+	if err != nil {
+		h.log.Error(err)
+		_ = render.Render(res, req, ErrInternalServerError)
+	}
+}
+
+// HandleSave сохраняет комплект восстановления. Ключ хранилища обязателен при первом сохранении
+func (h *RecoveryKitHandler) HandleSave(res http.ResponseWriter, req *http.Request) {
+	var (
+		err      error
+		userUUID string
+		kit      *models.RecoveryKit
+	)
+
+	request := new(recoveryKitRequest)
+	if err = render.Bind(req, request); err != nil {
+		h.log.Info(err)
+		_ = render.Render(res, req, ErrBadRequest)
+		return
+	}
+
+	userUUID, err = h.accessService.GetUserUUIDByJWTToken(req.Context())
+	if err != nil {
+		h.log.Error(err)
+		_ = render.Render(res, req, ErrBadRequest)
+		return
+	}
+
+	if request.VaultKey == "" {
+		kit, err = h.manager.RecoveryKit().FindOneByUserUUID(req.Context(), userUUID)
+		if err != nil {
+			h.log.Error(err)
+			_ = render.Render(res, req, ErrInternalServerError)
+			return
+		}
+		if kit == nil {
+			h.log.Infof("recovery kit without vault key: user %s", userUUID)
+			_ = render.Render(res, req, ErrBadRequest)
+			return
+		}
+	}
+
+	err = h.manager.RecoveryKit().Save(req.Context(), &models.RecoveryKit{
+		UserUUID:  userUUID,
+		VaultKey:  request.VaultKey,
+		ClientKey: request.ClientKey,
+	})
+	if err != nil {
+		h.log.Error(err)
+		_ = render.Render(res, req, ErrInternalServerError)
+		return
+	}
+	h.log.Infof("The recovery kit of user %s has been saved", userUUID)
+
+	res.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/northmule/gophkeeper/internal/common/model_data"
+	"github.com/northmule/gophkeeper/internal/common/models"
+	"github.com/northmule/gophkeeper/internal/server/logger"
+	appMock "github.com/northmule/gophkeeper/internal/server/repository/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestRecoveryKitHandler_HandleGet(t *testing.T) {
+	l, _ := logger.NewLogger("info")
+
+	tests := []struct {
+		name         string
+		kit          *models.RecoveryKit
+		err          error
+		expectedCode int
+	}{
+		{"ok", &models.RecoveryKit{VaultKey: "sealedVaultKey", ClientKey: "sealedClientKey"}, nil, http.StatusOK},
+		{"not_found", nil, nil, http.StatusNotFound},
+		{"repository_error", nil, errors.New("db error"), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockAccessService := new(appMock.MockAccessService)
+			mockRepository := new(appMock.MockManager)
+			mockRecoveryKitRepository := new(appMock.MockRecoveryKitModelRepository)
+			mockRepository.On("RecoveryKit").Return(mockRecoveryKitRepository)
+			mockAccessService.On("GetUserUUIDByJWTToken", mock.Anything).Return("user123", nil)
+			mockRecoveryKitRepository.On("FindOneByUserUUID", mock.Anything, "user123").Return(tt.kit, tt.err)
+
+			req, _ := http.NewRequest(http.MethodGet, "/recovery_kit", nil)
+			res := httptest.NewRecorder()
+			NewRecoveryKitHandler(mockAccessService, mockRepository, l).HandleGet(res, req)
+
+			assert.Equal(t, tt.expectedCode, res.Code)
+			if tt.expectedCode != http.StatusOK {
+				return
+			}
+			response := new(model_data.RecoveryKitResponse)
+			assert.NoError(t, json.Unmarshal(res.Body.Bytes(), response))
+			assert.Equal(t, "sealedVaultKey", response.VaultKey)
+			assert.Equal(t, "sealedClientKey", response.ClientKey)
+		})
+	}
+}
+
+func TestRecoveryKitHandler_HandleSave(t *testing.T) {
+	l, _ := logger.NewLogger("info")
+
+	tests := []struct {
+		name         string
+		request      model_data.RecoveryKitRequest
+		stored       *models.RecoveryKit
+		expectedCode int
+		saved        bool
+	}{
+		{"new kit", model_data.RecoveryKitRequest{VaultKey: "sealedVaultKey", ClientKey: "sealedClientKey"}, nil, http.StatusOK, true},
+		{"client key update", model_data.RecoveryKitRequest{ClientKey: "sealedClientKey"}, &models.RecoveryKit{VaultKey: "sealedVaultKey"}, http.StatusOK, true},
+		{"client key without kit", model_data.RecoveryKitRequest{ClientKey: "sealedClientKey"}, nil, http.StatusBadRequest, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockAccessService := new(appMock.MockAccessService)
+			mockRepository := new(appMock.MockManager)
+			mockRecoveryKitRepository := new(appMock.MockRecoveryKitModelRepository)
+			mockRepository.On("RecoveryKit").Return(mockRecoveryKitRepository)
+			mockAccessService.On("GetUserUUIDByJWTToken", mock.Anything).Return("user123", nil)
+			mockRecoveryKitRepository.On("FindOneByUserUUID", mock.Anything, "user123").Return(tt.stored, nil)
+			mockRecoveryKitRepository.On("Save", mock.Anything, mock.Anything).Return(nil)
+
+			reqBody, _ := json.Marshal(tt.request)
+			req, _ := http.NewRequest(http.MethodPost, "/save_recovery_kit", bytes.NewBuffer(reqBody))
+			req.Header.Set("Content-Type", "application/json")
+			res := httptest.NewRecorder()
+			NewRecoveryKitHandler(mockAccessService, mockRepository, l).HandleSave(res, req)
+
+			assert.Equal(t, tt.expectedCode, res.Code)
+			if !tt.saved {
+				mockRecoveryKitRepository.AssertNotCalled(t, "Save", mock.Anything, mock.Anything)
+				return
+			}
+			mockRecoveryKitRepository.AssertCalled(t, "Save", mock.Anything, &models.RecoveryKit{
+				UserUUID:  "user123",
+				VaultKey:  tt.request.VaultKey,
+				ClientKey: tt.request.ClientKey,
+			})
+		})
+	}
+}
+
+func TestRecoveryKitHandler_HandleSave_InvalidJWTToken(t *testing.T) {
+	l, _ := logger.NewLogger("info")
+	mockAccessService := new(appMock.MockAccessService)
+	mockAccessService.On("GetUserUUIDByJWTToken", mock.Anything).Return("", errors.New("invalid token"))
+
+	reqBody, _ := json.Marshal(model_data.RecoveryKitRequest{VaultKey: "sealedVaultKey", ClientKey: "sealedClientKey"})
+	req, _ := http.NewRequest(http.MethodPost, "/save_recovery_kit", bytes.NewBuffer(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+	res := httptest.NewRecorder()
+	NewRecoveryKitHandler(mockAccessService, new(appMock.MockManager), l).HandleSave(res, req)
+
+	assert.Equal(t, http.StatusBadRequest, res.Code)
+}
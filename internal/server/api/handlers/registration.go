@@ -7,41 +7,40 @@ import (
 	"time"
 
 	"github.com/go-chi/render"
-	"github.com/golang-jwt/jwt/v4"
 	"github.com/google/uuid"
 	"github.com/northmule/gophkeeper/internal/common/models"
 	"github.com/northmule/gophkeeper/internal/server/api/rctx"
 	"github.com/northmule/gophkeeper/internal/server/logger"
-	"github.com/northmule/gophkeeper/internal/server/storage"
+	"github.com/northmule/gophkeeper/internal/server/repository"
 )
 
 type RegistrationHandler struct {
-	userFinder     UserFinder
-	userCreator    UserCreator
-	session        storage.SessionManager
+	manager        repository.Repository
+	session        SessionOpener
+	secondFactor   SecondFactor
+	lockout        LoginLockout
 	passwordHasher PasswordHasher
 	log            *logger.Logger
 }
 
-// UserFinder поиск пользователя
-type UserFinder interface {
-	FindOneByLogin(ctx context.Context, login string) (*models.User, error)
-	FindOneByUUID(ctx context.Context, uuid string) (*models.User, error)
-}
-
-// UserCreator создание пользователя
-type UserCreator interface {
-	TxCreateNewUser(ctx context.Context, tx storage.TxDBQuery, user models.User) (int64, error)
-}
-
 // PasswordHasher хэшер пароля
 type PasswordHasher interface {
 	PasswordHash(password string) (string, error)
+	PasswordVerify(password string, hash string) (bool, error)
+	PasswordVerifyDummy(password string)
+	PasswordNeedsRehash(hash string) bool
+}
+
+// LoginLockout задержки и блокировка входа после неудачных попыток
+type LoginLockout interface {
+	Check(ctx context.Context, login string) (time.Duration, error)
+	Fail(ctx context.Context, login string) (time.Duration, error)
+	Reset(ctx context.Context, login string) error
 }
 
 type registrationRequest struct {
 	Login    string `json:"login" validate:"required,min=2,max=50"`
-	Password string `json:"password" validate:"required,min=3,max=30"`
+	Password string `json:"password" validate:"required,min=8,max=100"`
 	Email    string `json:"email" validate:"required,email"`
 }
 
@@ -50,11 +49,12 @@ type authenticationRequest struct {
 	Password string `json:"password" validate:"required,min=3,max=100"`
 }
 
-func NewRegistrationHandler(userFinder UserFinder, userCreator UserCreator, session storage.SessionManager, passwordHasher PasswordHasher, log *logger.Logger) *RegistrationHandler {
+func NewRegistrationHandler(manager repository.Repository, session SessionOpener, secondFactor SecondFactor, lockout LoginLockout, passwordHasher PasswordHasher, log *logger.Logger) *RegistrationHandler {
 	instance := &RegistrationHandler{
-		userFinder:     userFinder,
-		userCreator:    userCreator,
+		manager:        manager,
 		session:        session,
+		secondFactor:   secondFactor,
+		lockout:        lockout,
 		passwordHasher: passwordHasher,
 		log:            log,
 	}
@@ -79,7 +79,7 @@ func (r *RegistrationHandler) HandleRegistration(res http.ResponseWriter, req *h
 		return
 	}
 
-	user, err := r.userFinder.FindOneByLogin(req.Context(), request.Login)
+	user, err := r.manager.User().FindOneByLogin(req.Context(), request.Login)
 	if err != nil {
 		r.log.Error(err)
 		_ = render.Render(res, req, ErrInternalServerError)
@@ -103,8 +103,7 @@ func (r *RegistrationHandler) HandleRegistration(res http.ResponseWriter, req *h
 	newUser.Email = request.Email
 	newUser.UUID = uuid.NewString()
 
-	tx := req.Context().Value(rctx.TransactionCtxKey).(*storage.Transaction)
-	userID, err := r.userCreator.TxCreateNewUser(req.Context(), tx, newUser)
+	userID, err := r.manager.User().CreateNewUser(req.Context(), newUser)
 	if err != nil {
 		r.log.Error(err)
 		_ = render.Render(res, req, ErrInternalServerError)
@@ -126,12 +125,6 @@ func (r *RegistrationHandler) HandleRegistration(res http.ResponseWriter, req *h
 	_, _ = res.Write(nil)
 }
 
-// JWTClaims утверждение
-type JWTClaims struct {
-	jwt.RegisteredClaims
-	UserUUID string `json:"user_uuid"`
-}
-
 // HandleAuthentication аунтификация пользователя
 func (r *RegistrationHandler) HandleAuthentication(res http.ResponseWriter, req *http.Request) {
 
@@ -142,45 +135,104 @@ func (r *RegistrationHandler) HandleAuthentication(res http.ResponseWriter, req
 		return
 	}
 
-	user, err := r.userFinder.FindOneByLogin(req.Context(), request.Login)
+	// После неудачных попыток вход с логином откладывается, пароль не проверяется
+	retryAfter, err := r.lockout.Check(req.Context(), request.Login)
 	if err != nil {
 		r.log.Error(err)
 		_ = render.Render(res, req, ErrInternalServerError)
 		return
 	}
+	if retryAfter > 0 {
+		r.log.Infof("Login %s is locked for %s", request.Login, retryAfter)
+		_ = render.Render(res, req, ErrTooManyRequests(retryAfter))
+		return
+	}
 
-	passwordHash, err := r.passwordHasher.PasswordHash(request.Password)
+	user, err := r.manager.User().FindOneByLogin(req.Context(), request.Login)
 	if err != nil {
 		r.log.Error(err)
 		_ = render.Render(res, req, ErrInternalServerError)
 		return
 	}
 
-	if user == nil || user.Password != passwordHash {
-		r.log.Infof("Invalid username/password pair %s/****", request.Login)
+	verified := false
+	if user != nil {
+		verified, err = r.passwordHasher.PasswordVerify(request.Password, user.Password)
+		if err != nil {
+			r.log.Error(err)
+		}
+	} else {
+		// пароль проверяется и без пользователя, чтобы время ответа не выдавало наличие логина
+		r.passwordHasher.PasswordVerifyDummy(request.Password)
+	}
+	if !verified {
+		// неудачи считаются и для несуществующих логинов, чтобы ответ не выдавал наличие пользователя
+		retryAfter, err = r.lockout.Fail(req.Context(), request.Login)
+		if err != nil {
+			r.log.Error(err)
+		}
+		r.log.Infof("Invalid username/password pair %s/****, next attempt in %s", request.Login, retryAfter)
 		_ = render.Render(res, req, ErrUnauthorized)
 		return
 	}
 
-	// Токен
-	token := jwt.NewWithClaims(jwt.SigningMethodHS512, JWTClaims{
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour * 300)), // todo в конфиг
-		},
-		UserUUID: user.UUID,
-	})
+	err = r.lockout.Reset(req.Context(), request.Login)
+	if err != nil {
+		r.log.Error(err)
+	}
+
+	// Хэш, полученный устаревшим алгоритмом, пересчитывается текущим
+	if r.passwordHasher.PasswordNeedsRehash(user.Password) {
+		r.rehashPassword(req.Context(), user.UUID, request.Password)
+	}
 
-	// подпись токена ключом
-	tokenValue, err := token.SignedString([]byte("_secret_")) // todo в конфиг
+	// При подключённом втором факторе токены выдаются после проверки кода
+	challenge, err := r.secondFactor.Challenge(req.Context(), user.UUID)
 	if err != nil {
 		r.log.Error(err)
 		_ = render.Render(res, req, ErrInternalServerError)
 		return
 	}
+	if challenge != nil {
+		r.log.Infof("User %s has passed the password check, waiting for the second factor", user.UUID)
+		err = render.Render(res, req, loginChallengeResponse{LoginChallengeResponse: *challenge})
+		if err != nil {
+			r.log.Error(err)
+			_ = render.Render(res, req, ErrInternalServerError)
+		}
+		return
+	}
 
-	res.Header().Set("Authorization", "Bearer "+tokenValue)
+	// Новая сессия: токен доступа и refresh токен
+	tokens, err := r.session.Open(req.Context(), user.UUID, requestDevice(req))
+	if err != nil {
+		r.log.Error(err)
+		_ = render.Render(res, req, ErrInternalServerError)
+		return
+	}
 
-	r.log.Infof("User %s has been authenticated and the %s token has been issued", user.UUID, tokenValue)
-	res.WriteHeader(http.StatusOK)
-	_, _ = res.Write(nil)
+	auditUser(req, user.UUID)
+	res.Header().Set("Authorization", "Bearer "+tokens.AccessToken)
+
+	r.log.Infof("User %s has been authenticated, a new session has been opened", user.UUID)
+	err = render.Render(res, req, tokenResponse{TokenResponse: *tokens})
+	if err != nil {
+		r.log.Error(err)
+		_ = render.Render(res, req, ErrInternalServerError)
+	}
+}
+
+// rehashPassword пересчёт хэша пароля. Ошибка не прерывает аутентификацию
+func (r *RegistrationHandler) rehashPassword(ctx context.Context, userUUID string, password string) {
+	passwordHash, err := r.passwordHasher.PasswordHash(password)
+	if err != nil {
+		r.log.Error(err)
+		return
+	}
+	err = r.manager.User().SetPassword(ctx, passwordHash, userUUID)
+	if err != nil {
+		r.log.Error(err)
+		return
+	}
+	r.log.Infof("Password hash for user %s has been upgraded", userUUID)
 }
@@ -5,43 +5,56 @@ import (
 	"errors"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"testing"
+	"time"
 
+	"github.com/northmule/gophkeeper/internal/common/data_type"
+	"github.com/northmule/gophkeeper/internal/common/model_data"
 	"github.com/northmule/gophkeeper/internal/common/models"
-	"github.com/northmule/gophkeeper/internal/server/api/rctx"
 	"github.com/northmule/gophkeeper/internal/server/logger"
 	appMock "github.com/northmule/gophkeeper/internal/server/repository/mock"
 	"github.com/northmule/gophkeeper/internal/server/storage"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
-	"golang.org/x/net/context"
 )
 
+// newMockLoginLockout вход не заблокирован
+func newMockLoginLockout() *appMock.MockLoginLockout {
+	mockLockout := new(appMock.MockLoginLockout)
+	mockLockout.On("Check", mock.Anything, mock.Anything).Return(time.Duration(0), nil)
+	mockLockout.On("Fail", mock.Anything, mock.Anything).Return(time.Second, nil)
+	mockLockout.On("Reset", mock.Anything, mock.Anything).Return(nil)
+	return mockLockout
+}
+
 func TestHandleRegistration(t *testing.T) {
 
 	t.Run("Successful Registration", func(t *testing.T) {
 		mockAccessService := new(appMock.MockAccessService)
 		mockRepository := new(appMock.MockManager)
 		mockUserRepository := new(appMock.MockUserDataModelRepository)
-		mockSessionManager := new(appMock.MockSessionManager)
+		mockSessionOpener := new(appMock.MockSessionOpener)
 		mockTxDBQuery := new(appMock.MockTxDBQuery)
 		mockQuery := new(appMock.MockDBQuery)
 
 		mockRepository.On("User").Return(mockUserRepository)
 		l, _ := logger.NewLogger("info")
 
-		handler := NewRegistrationHandler(mockRepository, mockSessionManager, mockAccessService, l)
+		mockSecondFactor := new(appMock.MockSecondFactor)
+		mockSecondFactor.On("Challenge", mock.Anything, mock.Anything).Return(nil, nil)
+		handler := NewRegistrationHandler(mockRepository, mockSessionOpener, mockSecondFactor, newMockLoginLockout(), mockAccessService, l)
 
 		mockQuery.On("Begin").Return(mockTxDBQuery, nil)
 		transaction, _ := storage.NewTransaction(mockQuery)
 
 		mockUserRepository.On("FindOneByLogin", mock.Anything, "testuser").Return(nil, nil)
 		mockAccessService.On("PasswordHash", "testpassword").Return("hashedpassword", nil)
-		mockUserRepository.On("TxCreateNewUser", mock.Anything, mock.Anything, mock.Anything).Return(int64(1), nil)
+		mockUserRepository.On("CreateNewUser", mock.Anything, mock.Anything).Return(int64(1), nil)
 
 		reqBody := `{"login": "testuser", "password": "testpassword", "email": "test@example.com"}`
 		req := httptest.NewRequest(http.MethodPost, "/register", bytes.NewBufferString(reqBody))
-		req = req.WithContext(context.WithValue(req.Context(), rctx.TransactionCtxKey, transaction))
+		req = req.WithContext(storage.WithTransaction(req.Context(), transaction))
 		req.Header.Set("Content-Type", "application/json")
 		res := httptest.NewRecorder()
 
@@ -54,12 +67,14 @@ func TestHandleRegistration(t *testing.T) {
 		mockAccessService := new(appMock.MockAccessService)
 		mockRepository := new(appMock.MockManager)
 		mockUserRepository := new(appMock.MockUserDataModelRepository)
-		mockSessionManager := new(appMock.MockSessionManager)
+		mockSessionOpener := new(appMock.MockSessionOpener)
 
 		mockRepository.On("User").Return(mockUserRepository)
 		l, _ := logger.NewLogger("info")
 
-		handler := NewRegistrationHandler(mockRepository, mockSessionManager, mockAccessService, l)
+		mockSecondFactor := new(appMock.MockSecondFactor)
+		mockSecondFactor.On("Challenge", mock.Anything, mock.Anything).Return(nil, nil)
+		handler := NewRegistrationHandler(mockRepository, mockSessionOpener, mockSecondFactor, newMockLoginLockout(), mockAccessService, l)
 		mockUserRepository.On("FindOneByLogin", mock.Anything, "testuser").Return(&models.User{Login: "testuser"}, nil)
 
 		reqBody := `{"login": "testuser", "password": "testpassword", "email": "test@example.com"}`
@@ -74,11 +89,11 @@ func TestHandleRegistration(t *testing.T) {
 
 }
 
-func TestHandleRegistration_TxCreateNewUserError(t *testing.T) {
+func TestHandleRegistration_CreateNewUserError(t *testing.T) {
 	mockAccessService := new(appMock.MockAccessService)
 	mockRepository := new(appMock.MockManager)
 	mockUserRepository := new(appMock.MockUserDataModelRepository)
-	mockSessionManager := new(appMock.MockSessionManager)
+	mockSessionOpener := new(appMock.MockSessionOpener)
 	mockTxDBQuery := new(appMock.MockTxDBQuery)
 	mockQuery := new(appMock.MockDBQuery)
 	mockQuery.On("Begin").Return(mockTxDBQuery, nil)
@@ -87,17 +102,19 @@ func TestHandleRegistration_TxCreateNewUserError(t *testing.T) {
 	mockRepository.On("User").Return(mockUserRepository)
 	l, _ := logger.NewLogger("info")
 
-	handler := NewRegistrationHandler(mockRepository, mockSessionManager, mockAccessService, l)
+	mockSecondFactor := new(appMock.MockSecondFactor)
+	mockSecondFactor.On("Challenge", mock.Anything, mock.Anything).Return(nil, nil)
+	handler := NewRegistrationHandler(mockRepository, mockSessionOpener, mockSecondFactor, newMockLoginLockout(), mockAccessService, l)
 
 	mockUserRepository.On("FindOneByLogin", mock.Anything, mock.Anything).Return(nil, nil)
-	mockUserRepository.On("TxCreateNewUser", mock.Anything, mock.Anything, mock.Anything).Return(int64(0), errors.New("database error"))
+	mockUserRepository.On("CreateNewUser", mock.Anything, mock.Anything).Return(int64(0), errors.New("database error"))
 	mockAccessService.On("PasswordHash", mock.Anything).Return("hashedpassword", nil)
 
 	reqBody := `{"login": "testuser", "password": "testpassword", "email": "test@example.com"}`
 	req := httptest.NewRequest(http.MethodPost, "/register", bytes.NewBufferString(reqBody))
 	req.Header.Set("Content-Type", "application/json")
 
-	ctx := context.WithValue(req.Context(), rctx.TransactionCtxKey, transaction)
+	ctx := storage.WithTransaction(req.Context(), transaction)
 	req = req.WithContext(ctx)
 
 	res := httptest.NewRecorder()
@@ -114,12 +131,14 @@ func TestHandleRegistration_FindOneByLoginError(t *testing.T) {
 	mockAccessService := new(appMock.MockAccessService)
 	mockRepository := new(appMock.MockManager)
 	mockUserRepository := new(appMock.MockUserDataModelRepository)
-	mockSessionManager := new(appMock.MockSessionManager)
+	mockSessionOpener := new(appMock.MockSessionOpener)
 
 	mockRepository.On("User").Return(mockUserRepository)
 	l, _ := logger.NewLogger("info")
 
-	handler := NewRegistrationHandler(mockRepository, mockSessionManager, mockAccessService, l)
+	mockSecondFactor := new(appMock.MockSecondFactor)
+	mockSecondFactor.On("Challenge", mock.Anything, mock.Anything).Return(nil, nil)
+	handler := NewRegistrationHandler(mockRepository, mockSessionOpener, mockSecondFactor, newMockLoginLockout(), mockAccessService, l)
 
 	mockUserRepository.On("FindOneByLogin", mock.Anything, mock.Anything).Return(nil, errors.New("database error"))
 
@@ -143,13 +162,16 @@ func TestHandleAuthentication(t *testing.T) {
 		mockUserRepository := new(appMock.MockUserDataModelRepository)
 		l, _ := logger.NewLogger("info")
 
-		handler := NewRegistrationHandler(mockRepository, nil, mockAccessService, l)
+		mockSessionOpener := new(appMock.MockSessionOpener)
+		mockSecondFactor := new(appMock.MockSecondFactor)
+		mockSecondFactor.On("Challenge", mock.Anything, mock.Anything).Return(nil, nil)
+		handler := NewRegistrationHandler(mockRepository, mockSessionOpener, mockSecondFactor, newMockLoginLockout(), mockAccessService, l)
 
 		mockRepository.On("User").Return(mockUserRepository)
 		user := new(models.User)
 		user.Login = "login"
 		mockUserRepository.On("FindOneByLogin", mock.Anything, mock.Anything).Return(user, nil)
-		mockAccessService.On("PasswordHash", mock.Anything).Return("hashedpassword", nil)
+		mockAccessService.On("PasswordVerify", mock.Anything, mock.Anything).Return(false, errors.New("unknown password hash format"))
 		reqBody := `{"login": "existinguser"}`
 		req := httptest.NewRequest(http.MethodPost, "/authenticate", bytes.NewBufferString(reqBody))
 		req.Header.Set("Content-Type", "application/json")
@@ -166,11 +188,14 @@ func TestHandleAuthentication(t *testing.T) {
 		mockUserRepository := new(appMock.MockUserDataModelRepository)
 		l, _ := logger.NewLogger("info")
 
-		handler := NewRegistrationHandler(mockRepository, nil, mockAccessService, l)
+		mockSessionOpener := new(appMock.MockSessionOpener)
+		mockSecondFactor := new(appMock.MockSecondFactor)
+		mockSecondFactor.On("Challenge", mock.Anything, mock.Anything).Return(nil, nil)
+		handler := NewRegistrationHandler(mockRepository, mockSessionOpener, mockSecondFactor, newMockLoginLockout(), mockAccessService, l)
 
 		mockRepository.On("User").Return(mockUserRepository)
-		mockAccessService.On("PasswordHash", mock.Anything).Return("hashedpassword", nil)
 		mockUserRepository.On("FindOneByLogin", mock.Anything, "nonexistentuser").Return(nil, nil)
+		mockAccessService.On("PasswordVerifyDummy", "password").Return()
 		reqBody := `{"login": "nonexistentuser", "password": "password"}`
 		req := httptest.NewRequest(http.MethodPost, "/authenticate", bytes.NewBufferString(reqBody))
 		req.Header.Set("Content-Type", "application/json")
@@ -179,6 +204,7 @@ func TestHandleAuthentication(t *testing.T) {
 		handler.HandleAuthentication(res, req)
 
 		assert.Equal(t, http.StatusUnauthorized, res.Code)
+		mockAccessService.AssertCalled(t, "PasswordVerifyDummy", "password")
 	})
 
 	t.Run("Successful authentication", func(t *testing.T) {
@@ -187,13 +213,78 @@ func TestHandleAuthentication(t *testing.T) {
 		mockUserRepository := new(appMock.MockUserDataModelRepository)
 		l, _ := logger.NewLogger("info")
 
-		handler := NewRegistrationHandler(mockRepository, nil, mockAccessService, l)
+		mockSessionOpener := new(appMock.MockSessionOpener)
+		mockSecondFactor := new(appMock.MockSecondFactor)
+		mockSecondFactor.On("Challenge", mock.Anything, mock.Anything).Return(nil, nil)
+		handler := NewRegistrationHandler(mockRepository, mockSessionOpener, mockSecondFactor, newMockLoginLockout(), mockAccessService, l)
 
 		mockRepository.On("User").Return(mockUserRepository)
-		user := new(models.User)
-		user.Login = "login"
+		mockAccessService.On("PasswordVerify", "password", "hashedpassword").Return(true, nil)
+		mockAccessService.On("PasswordNeedsRehash", "hashedpassword").Return(false)
+		device := mock.MatchedBy(func(device *models.Device) bool {
+			return device.UUID == "0b5b3f6e-8f8e-4f4c-9d55-3d5a0b1b7e42" && device.Name == "рабочий ноутбук"
+		})
+		mockSessionOpener.On("Open", mock.Anything, mock.Anything, device).Return(&model_data.TokenResponse{AccessToken: "signed-token", RefreshToken: "refresh-token", ExpiresIn: 900, DeviceUUID: "0b5b3f6e-8f8e-4f4c-9d55-3d5a0b1b7e42"}, nil)
+		mockUserRepository.On("FindOneByLogin", mock.Anything, "existinguser").Return(&models.User{Login: "existinguser", Password: "hashedpassword"}, nil)
 
-		mockAccessService.On("PasswordHash", mock.Anything).Return("hashedpassword", nil)
+		reqBody := `{"login": "existinguser", "password": "password"}`
+		req := httptest.NewRequest(http.MethodPost, "/authenticate", bytes.NewBufferString(reqBody))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(data_type.DeviceUUIDHeader, "0b5b3f6e-8f8e-4f4c-9d55-3d5a0b1b7e42")
+		req.Header.Set(data_type.DeviceNameHeader, url.QueryEscape("рабочий ноутбук"))
+		res := httptest.NewRecorder()
+
+		handler.HandleAuthentication(res, req)
+
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.Equal(t, "Bearer signed-token", res.Header().Get("Authorization"))
+		assert.JSONEq(t, `{"access_token":"signed-token","refresh_token":"refresh-token","expires_in":900,"device_uuid":"0b5b3f6e-8f8e-4f4c-9d55-3d5a0b1b7e42"}`, res.Body.String())
+	})
+
+	t.Run("Second factor required", func(t *testing.T) {
+		mockAccessService := new(appMock.MockAccessService)
+		mockRepository := new(appMock.MockManager)
+		mockUserRepository := new(appMock.MockUserDataModelRepository)
+		l, _ := logger.NewLogger("info")
+
+		mockSessionOpener := new(appMock.MockSessionOpener)
+		mockSecondFactor := new(appMock.MockSecondFactor)
+		handler := NewRegistrationHandler(mockRepository, mockSessionOpener, mockSecondFactor, newMockLoginLockout(), mockAccessService, l)
+
+		mockRepository.On("User").Return(mockUserRepository)
+		mockAccessService.On("PasswordVerify", "password", "hashedpassword").Return(true, nil)
+		mockAccessService.On("PasswordNeedsRehash", "hashedpassword").Return(false)
+		mockSecondFactor.On("Challenge", mock.Anything, "user-uuid").Return(&model_data.LoginChallengeResponse{MFARequired: true, MFAToken: "mfa-token", ExpiresIn: 300}, nil)
+		mockUserRepository.On("FindOneByLogin", mock.Anything, "existinguser").Return(&models.User{Login: "existinguser", Password: "hashedpassword", Common: models.Common{UUID: "user-uuid"}}, nil)
+
+		reqBody := `{"login": "existinguser", "password": "password"}`
+		req := httptest.NewRequest(http.MethodPost, "/authenticate", bytes.NewBufferString(reqBody))
+		req.Header.Set("Content-Type", "application/json")
+		res := httptest.NewRecorder()
+
+		handler.HandleAuthentication(res, req)
+
+		assert.Equal(t, http.StatusAccepted, res.Code)
+		assert.Empty(t, res.Header().Get("Authorization"))
+		assert.JSONEq(t, `{"mfa_required":true,"mfa_token":"mfa-token","expires_in":300}`, res.Body.String())
+		mockSessionOpener.AssertNotCalled(t, "Open", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Open session error", func(t *testing.T) {
+		mockAccessService := new(appMock.MockAccessService)
+		mockRepository := new(appMock.MockManager)
+		mockUserRepository := new(appMock.MockUserDataModelRepository)
+		l, _ := logger.NewLogger("info")
+
+		mockSessionOpener := new(appMock.MockSessionOpener)
+		mockSecondFactor := new(appMock.MockSecondFactor)
+		mockSecondFactor.On("Challenge", mock.Anything, mock.Anything).Return(nil, nil)
+		handler := NewRegistrationHandler(mockRepository, mockSessionOpener, mockSecondFactor, newMockLoginLockout(), mockAccessService, l)
+
+		mockRepository.On("User").Return(mockUserRepository)
+		mockAccessService.On("PasswordVerify", "password", "hashedpassword").Return(true, nil)
+		mockAccessService.On("PasswordNeedsRehash", "hashedpassword").Return(false)
+		mockSessionOpener.On("Open", mock.Anything, mock.Anything, mock.Anything).Return(nil, errors.New("db error"))
 		mockUserRepository.On("FindOneByLogin", mock.Anything, "existinguser").Return(&models.User{Login: "existinguser", Password: "hashedpassword"}, nil)
 
 		reqBody := `{"login": "existinguser", "password": "password"}`
@@ -203,6 +294,91 @@ func TestHandleAuthentication(t *testing.T) {
 
 		handler.HandleAuthentication(res, req)
 
+		assert.Equal(t, http.StatusInternalServerError, res.Code)
+	})
+
+	t.Run("Wrong password", func(t *testing.T) {
+		mockAccessService := new(appMock.MockAccessService)
+		mockRepository := new(appMock.MockManager)
+		mockUserRepository := new(appMock.MockUserDataModelRepository)
+		l, _ := logger.NewLogger("info")
+
+		mockSessionOpener := new(appMock.MockSessionOpener)
+		mockSecondFactor := new(appMock.MockSecondFactor)
+		mockSecondFactor.On("Challenge", mock.Anything, mock.Anything).Return(nil, nil)
+		handler := NewRegistrationHandler(mockRepository, mockSessionOpener, mockSecondFactor, newMockLoginLockout(), mockAccessService, l)
+
+		mockRepository.On("User").Return(mockUserRepository)
+		mockAccessService.On("PasswordVerify", "wrong", "hashedpassword").Return(false, nil)
+		mockUserRepository.On("FindOneByLogin", mock.Anything, "existinguser").Return(&models.User{Login: "existinguser", Password: "hashedpassword"}, nil)
+
+		reqBody := `{"login": "existinguser", "password": "wrong"}`
+		req := httptest.NewRequest(http.MethodPost, "/authenticate", bytes.NewBufferString(reqBody))
+		req.Header.Set("Content-Type", "application/json")
+		res := httptest.NewRecorder()
+
+		handler.HandleAuthentication(res, req)
+
+		assert.Equal(t, http.StatusUnauthorized, res.Code)
+		mockUserRepository.AssertNotCalled(t, "SetPassword", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Legacy hash is upgraded", func(t *testing.T) {
+		mockAccessService := new(appMock.MockAccessService)
+		mockRepository := new(appMock.MockManager)
+		mockUserRepository := new(appMock.MockUserDataModelRepository)
+		l, _ := logger.NewLogger("info")
+
+		mockSessionOpener := new(appMock.MockSessionOpener)
+		mockSecondFactor := new(appMock.MockSecondFactor)
+		mockSecondFactor.On("Challenge", mock.Anything, mock.Anything).Return(nil, nil)
+		handler := NewRegistrationHandler(mockRepository, mockSessionOpener, mockSecondFactor, newMockLoginLockout(), mockAccessService, l)
+
+		mockRepository.On("User").Return(mockUserRepository)
+		mockAccessService.On("PasswordVerify", "password", "legacyhash").Return(true, nil)
+		mockAccessService.On("PasswordNeedsRehash", "legacyhash").Return(true)
+		mockAccessService.On("PasswordHash", "password").Return("$argon2id$newhash", nil)
+		mockSessionOpener.On("Open", mock.Anything, "user-uuid", mock.Anything).Return(&model_data.TokenResponse{AccessToken: "signed-token"}, nil)
+		mockUserRepository.On("FindOneByLogin", mock.Anything, "existinguser").Return(&models.User{Login: "existinguser", Password: "legacyhash", Common: models.Common{UUID: "user-uuid"}}, nil)
+		mockUserRepository.On("SetPassword", mock.Anything, "$argon2id$newhash", "user-uuid").Return(nil)
+
+		reqBody := `{"login": "existinguser", "password": "password"}`
+		req := httptest.NewRequest(http.MethodPost, "/authenticate", bytes.NewBufferString(reqBody))
+		req.Header.Set("Content-Type", "application/json")
+		res := httptest.NewRecorder()
+
+		handler.HandleAuthentication(res, req)
+
+		assert.Equal(t, http.StatusOK, res.Code)
+		mockUserRepository.AssertCalled(t, "SetPassword", mock.Anything, "$argon2id$newhash", "user-uuid")
+	})
+
+	t.Run("Upgrade error does not block login", func(t *testing.T) {
+		mockAccessService := new(appMock.MockAccessService)
+		mockRepository := new(appMock.MockManager)
+		mockUserRepository := new(appMock.MockUserDataModelRepository)
+		l, _ := logger.NewLogger("info")
+
+		mockSessionOpener := new(appMock.MockSessionOpener)
+		mockSecondFactor := new(appMock.MockSecondFactor)
+		mockSecondFactor.On("Challenge", mock.Anything, mock.Anything).Return(nil, nil)
+		handler := NewRegistrationHandler(mockRepository, mockSessionOpener, mockSecondFactor, newMockLoginLockout(), mockAccessService, l)
+
+		mockRepository.On("User").Return(mockUserRepository)
+		mockAccessService.On("PasswordVerify", "password", "legacyhash").Return(true, nil)
+		mockAccessService.On("PasswordNeedsRehash", "legacyhash").Return(true)
+		mockAccessService.On("PasswordHash", "password").Return("$argon2id$newhash", nil)
+		mockSessionOpener.On("Open", mock.Anything, "user-uuid", mock.Anything).Return(&model_data.TokenResponse{AccessToken: "signed-token"}, nil)
+		mockUserRepository.On("FindOneByLogin", mock.Anything, "existinguser").Return(&models.User{Login: "existinguser", Password: "legacyhash", Common: models.Common{UUID: "user-uuid"}}, nil)
+		mockUserRepository.On("SetPassword", mock.Anything, mock.Anything, mock.Anything).Return(errors.New("database error"))
+
+		reqBody := `{"login": "existinguser", "password": "password"}`
+		req := httptest.NewRequest(http.MethodPost, "/authenticate", bytes.NewBufferString(reqBody))
+		req.Header.Set("Content-Type", "application/json")
+		res := httptest.NewRecorder()
+
+		handler.HandleAuthentication(res, req)
+
 		assert.Equal(t, http.StatusOK, res.Code)
 	})
 
@@ -212,7 +388,10 @@ func TestHandleAuthentication(t *testing.T) {
 		mockUserRepository := new(appMock.MockUserDataModelRepository)
 		l, _ := logger.NewLogger("info")
 
-		handler := NewRegistrationHandler(mockRepository, nil, mockAccessService, l)
+		mockSessionOpener := new(appMock.MockSessionOpener)
+		mockSecondFactor := new(appMock.MockSecondFactor)
+		mockSecondFactor.On("Challenge", mock.Anything, mock.Anything).Return(nil, nil)
+		handler := NewRegistrationHandler(mockRepository, mockSessionOpener, mockSecondFactor, newMockLoginLockout(), mockAccessService, l)
 
 		mockRepository.On("User").Return(mockUserRepository)
 		mockUserRepository.On("FindOneByLogin", mock.Anything, "existinguser").Return(nil, errors.New("database error"))
@@ -227,4 +406,82 @@ func TestHandleAuthentication(t *testing.T) {
 		assert.Equal(t, http.StatusInternalServerError, res.Code)
 	})
 
+	t.Run("Login is locked", func(t *testing.T) {
+		mockAccessService := new(appMock.MockAccessService)
+		mockRepository := new(appMock.MockManager)
+		l, _ := logger.NewLogger("info")
+
+		mockLockout := new(appMock.MockLoginLockout)
+		mockLockout.On("Check", mock.Anything, "existinguser").Return(90*time.Second+time.Millisecond, nil)
+		handler := NewRegistrationHandler(mockRepository, new(appMock.MockSessionOpener), new(appMock.MockSecondFactor), mockLockout, mockAccessService, l)
+
+		reqBody := `{"login": "existinguser", "password": "password"}`
+		req := httptest.NewRequest(http.MethodPost, "/authenticate", bytes.NewBufferString(reqBody))
+		req.Header.Set("Content-Type", "application/json")
+		res := httptest.NewRecorder()
+
+		handler.HandleAuthentication(res, req)
+
+		assert.Equal(t, http.StatusTooManyRequests, res.Code)
+		assert.Equal(t, "91", res.Header().Get("Retry-After"))
+		mockRepository.AssertNotCalled(t, "User")
+		mockAccessService.AssertNotCalled(t, "PasswordVerify", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Failures are counted and reset", func(t *testing.T) {
+		mockAccessService := new(appMock.MockAccessService)
+		mockRepository := new(appMock.MockManager)
+		mockUserRepository := new(appMock.MockUserDataModelRepository)
+		mockSessionOpener := new(appMock.MockSessionOpener)
+		mockSecondFactor := new(appMock.MockSecondFactor)
+		l, _ := logger.NewLogger("info")
+
+		mockLockout := newMockLoginLockout()
+		handler := NewRegistrationHandler(mockRepository, mockSessionOpener, mockSecondFactor, mockLockout, mockAccessService, l)
+
+		mockRepository.On("User").Return(mockUserRepository)
+		mockUserRepository.On("FindOneByLogin", mock.Anything, "nonexistentuser").Return(nil, nil)
+		mockAccessService.On("PasswordVerifyDummy", "password").Return()
+		mockUserRepository.On("FindOneByLogin", mock.Anything, "existinguser").Return(&models.User{Login: "existinguser", Password: "hashedpassword", Common: models.Common{UUID: "user-uuid"}}, nil)
+		mockAccessService.On("PasswordVerify", "wrong", "hashedpassword").Return(false, nil)
+		mockAccessService.On("PasswordVerify", "password", "hashedpassword").Return(true, nil)
+		mockAccessService.On("PasswordNeedsRehash", "hashedpassword").Return(false)
+		mockSecondFactor.On("Challenge", mock.Anything, mock.Anything).Return(nil, nil)
+		mockSessionOpener.On("Open", mock.Anything, "user-uuid", mock.Anything).Return(&model_data.TokenResponse{AccessToken: "signed-token"}, nil)
+
+		for _, reqBody := range []string{
+			`{"login": "nonexistentuser", "password": "password"}`,
+			`{"login": "existinguser", "password": "wrong"}`,
+		} {
+			req := httptest.NewRequest(http.MethodPost, "/authenticate", bytes.NewBufferString(reqBody))
+			req.Header.Set("Content-Type", "application/json")
+			res := httptest.NewRecorder()
+			handler.HandleAuthentication(res, req)
+			assert.Equal(t, http.StatusUnauthorized, res.Code)
+		}
+		mockLockout.AssertCalled(t, "Fail", mock.Anything, "nonexistentuser")
+		mockLockout.AssertCalled(t, "Fail", mock.Anything, "existinguser")
+		mockLockout.AssertNotCalled(t, "Reset", mock.Anything, mock.Anything)
+
+		req := httptest.NewRequest(http.MethodPost, "/authenticate", bytes.NewBufferString(`{"login": "existinguser", "password": "password"}`))
+		req.Header.Set("Content-Type", "application/json")
+		res := httptest.NewRecorder()
+		handler.HandleAuthentication(res, req)
+		assert.Equal(t, http.StatusOK, res.Code)
+		mockLockout.AssertCalled(t, "Reset", mock.Anything, "existinguser")
+	})
+
+	t.Run("Lockout check error", func(t *testing.T) {
+		mockLockout := new(appMock.MockLoginLockout)
+		mockLockout.On("Check", mock.Anything, mock.Anything).Return(time.Duration(0), errors.New("database error"))
+		l, _ := logger.NewLogger("info")
+		handler := NewRegistrationHandler(new(appMock.MockManager), new(appMock.MockSessionOpener), new(appMock.MockSecondFactor), mockLockout, new(appMock.MockAccessService), l)
+
+		req := httptest.NewRequest(http.MethodPost, "/authenticate", bytes.NewBufferString(`{"login": "existinguser", "password": "password"}`))
+		req.Header.Set("Content-Type", "application/json")
+		res := httptest.NewRecorder()
+		handler.HandleAuthentication(res, req)
+
+		assert.Equal(t, http.StatusInternalServerError, res.Code)
+	})
 }
@@ -2,43 +2,53 @@ package handlers
 
 import (
 	"net/http"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/jwtauth/v5"
 	"github.com/go-chi/render"
+	"github.com/northmule/gophkeeper/internal/common/data_type"
+	"github.com/northmule/gophkeeper/internal/common/models"
 	"github.com/northmule/gophkeeper/internal/server/config"
 	"github.com/northmule/gophkeeper/internal/server/logger"
 	"github.com/northmule/gophkeeper/internal/server/repository"
 	service "github.com/northmule/gophkeeper/internal/server/services"
+	"github.com/northmule/gophkeeper/internal/server/services/audit"
+	"github.com/northmule/gophkeeper/internal/server/services/kek"
+	"github.com/northmule/gophkeeper/internal/server/services/lockout"
 	"github.com/northmule/gophkeeper/internal/server/storage"
 	"golang.org/x/net/context"
 )
 
 type AppRoutes struct {
-	storage       storage.DBQuery
-	session       storage.SessionManager
-	log           *logger.Logger
-	cfg           *config.Config
-	accessService AccessService
-	cryptService  service.CryptService
-
-	userRepository     *repository.UserRepository
-	cardDataRepository *repository.CardDataRepository
-	fileDataRepository *repository.FileDataRepository
-	metaDataRepository *repository.MetaDataRepository
-	ownerRepository    *repository.OwnerRepository
-	textDataRepository *repository.TextDataRepository
+	repositoryManager repository.Repository
+	storage           storage.DBQuery
+	session           storage.SessionManager
+	log               *logger.Logger
+	cfg               *config.Config
+	accessService     AccessService
+	cryptService      service.CryptService
+	keyProvider       kek.KeyProvider
+	certIssuer        CertificateIssuer
+	keyRotator        KeyRotator
+	events            EventSubscriber
 }
 
-func NewAppRoutes(storage storage.DBQuery, session storage.SessionManager, log *logger.Logger, cfg *config.Config, accessService AccessService, cryptService service.CryptService) *AppRoutes {
+// NewAppRoutes конструктор
+func NewAppRoutes(repositoryManager repository.Repository, storage storage.DBQuery, session storage.SessionManager, log *logger.Logger, cfg *config.Config, accessService AccessService, cryptService service.CryptService, keyProvider kek.KeyProvider, certIssuer CertificateIssuer, keyRotator KeyRotator, events EventSubscriber) *AppRoutes {
 	instance := AppRoutes{
-		storage:       storage,
-		session:       session,
-		log:           log,
-		cfg:           cfg,
-		accessService: accessService,
-		cryptService:  cryptService,
+		repositoryManager: repositoryManager,
+		storage:           storage,
+		session:           session,
+		log:               log,
+		cfg:               cfg,
+		accessService:     accessService,
+		cryptService:      cryptService,
+		keyProvider:       keyProvider,
+		certIssuer:        certIssuer,
+		keyRotator:        keyRotator,
+		events:            events,
 	}
 	return &instance
 }
@@ -46,7 +56,15 @@ func NewAppRoutes(storage storage.DBQuery, session storage.SessionManager, log *
 // AccessService серис доступа
 type AccessService interface {
 	PasswordHash(password string) (string, error)
+	PasswordVerify(password string, hash string) (bool, error)
+	PasswordVerifyDummy(password string)
+	PasswordNeedsRehash(hash string) bool
 	FillJWTToken() *jwtauth.JWTAuth
+	IssueToken(userUUID string, sessionUUID string, deviceUUID string) (string, error)
+	GetSessionUUIDByJWTToken(ctx context.Context) (string, error)
+	GetDeviceUUIDByJWTToken(ctx context.Context) (string, error)
+	GetTokenExpirationByJWTToken(ctx context.Context) (time.Time, error)
+	JWTVerifier(next http.Handler) http.Handler
 	GetUserUUIDByJWTToken(ctx context.Context) (string, error)
 	FindTokenByRequest(r *http.Request) string
 }
@@ -56,16 +74,36 @@ func (ar *AppRoutes) DefiningAppRoutes() chi.Router {
 
 	// Обработчики
 	healthHandler := NewHealthHandler(ar.log)
-	registrationHandler := NewRegistrationHandler(ar.userRepository, ar.userRepository, ar.session, ar.accessService, ar.log)
+	sessionHandler := NewSessionHandler(ar.accessService, ar.repositoryManager, ar.session, ar.cfg, ar.log)
+	totpHandler := NewTOTPHandler(ar.accessService, ar.repositoryManager, sessionHandler, ar.cfg, ar.log)
+	loginLockout := lockout.NewLockout(ar.repositoryManager.LoginFailure(), ar.cfg)
+	registrationHandler := NewRegistrationHandler(ar.repositoryManager, sessionHandler, totpHandler, loginLockout, ar.accessService, ar.log)
+	certificateHandler := NewCertificateHandler(ar.repositoryManager, sessionHandler, ar.log)
 	transactionHandler := NewTransactionHandler(ar.storage, ar.log)
-
-	itemsListHandler := NewItemsListHandler(ar.accessService, ar.ownerRepository, ar.log)
-	cardDataHandler := NewCardDataHandler(ar.accessService, ar.ownerRepository, ar.cardDataRepository, ar.metaDataRepository, ar.log)
-	textDataHandler := NewTextDataHandler(ar.accessService, ar.ownerRepository, ar.metaDataRepository, ar.textDataRepository, ar.log)
-	fileDataHandler := NewFileDataHandler(ar.accessService, ar.fileDataRepository, ar.ownerRepository, ar.metaDataRepository, ar.cfg, ar.log)
-	itemDataHandler := NewItemDataHandler(ar.accessService, ar.cardDataRepository, ar.metaDataRepository, ar.fileDataRepository, ar.textDataRepository, ar.ownerRepository, ar.log)
-	keysDataHandler := NewKeysDataHandler(ar.accessService, ar.cryptService, ar.userRepository, ar.userRepository, ar.cfg, ar.log)
-	decryptDataHandler := NewDecryptDataHandler(ar.accessService, ar.userRepository, ar.log)
+	rateLimitHandler := NewRateLimitHandler(
+		storage.NewRateLimiter(ar.cfg.Value().RateLimitWindow, ar.cfg.Value().RateLimitIP),
+		storage.NewRateLimiter(ar.cfg.Value().RateLimitWindow, ar.cfg.Value().RateLimitLogin),
+		ar.log,
+	)
+
+	itemsListHandler := NewItemsListHandler(ar.accessService, ar.repositoryManager, ar.log)
+	syncHandler := NewSyncHandler(ar.accessService, ar.repositoryManager, ar.log)
+	eventsHandler := NewEventsHandler(ar.accessService, ar.keyProvider, ar.events, ar.repositoryManager, ar.cfg, ar.log)
+	cardDataHandler := NewCardDataHandler(ar.accessService, ar.repositoryManager, ar.log)
+	textDataHandler := NewTextDataHandler(ar.accessService, ar.repositoryManager, ar.log)
+	credentialDataHandler := NewCredentialDataHandler(ar.accessService, ar.repositoryManager, ar.log)
+	batchHandler := NewBatchHandler(ar.accessService, ar.repositoryManager, ar.log)
+	fileDataHandler := NewFileDataHandler(ar.accessService, ar.repositoryManager, ar.cfg, ar.log)
+	itemDataHandler := NewItemDataHandler(ar.accessService, ar.repositoryManager, ar.log)
+	keysDataHandler := NewKeysDataHandler(ar.accessService, ar.cryptService, ar.keyProvider, ar.certIssuer, ar.repositoryManager, ar.cfg, ar.log)
+	keyRotationHandler := NewKeyRotationHandler(ar.accessService, ar.cryptService, ar.keyProvider, ar.keyRotator, ar.repositoryManager, ar.log)
+	nonceGuard := storage.NewNonceGuard(ar.cfg.Value().RequestMaxSkew, ar.repositoryManager.RequestNonce())
+	decryptDataHandler := NewDecryptDataHandler(ar.accessService, ar.keyProvider, nonceGuard, ar.repositoryManager, ar.log)
+	masterKeyHandler := NewMasterKeyHandler(ar.accessService, ar.repositoryManager, ar.log)
+	recoveryKitHandler := NewRecoveryKitHandler(ar.accessService, ar.repositoryManager, ar.log)
+	deviceHandler := NewDeviceHandler(ar.accessService, ar.repositoryManager, ar.session, ar.log)
+	idempotencyHandler := NewIdempotencyHandler(ar.accessService, ar.repositoryManager, ar.log)
+	auditHandler := NewAuditHandler(ar.accessService, audit.NewAuditor(ar.repositoryManager.AuditEvent(), ar.keyProvider), ar.repositoryManager, ar.log)
 
 	r := chi.NewRouter()
 
@@ -82,54 +120,190 @@ func (ar *AppRoutes) DefiningAppRoutes() chi.Router {
 			// Начальный jwt объект
 			jwtTokenObject := ar.accessService.FillJWTToken()
 
-			// Проверка токена, заполнения данных о пользователе
-			r.Use(jwtauth.Verify(jwtTokenObject, ar.accessService.FindTokenByRequest))
+			// Проверка токена ключом из заголовка kid, заполнения данных о пользователе
+			r.Use(ar.accessService.JWTVerifier)
 			r.Use(jwtauth.Authenticator(jwtTokenObject))
+			// Проверка, что сессия токена не закрыта
+			r.Use(sessionHandler.HandleCheckSession)
+
+			// закрытие сессии (all=1 - всех сессий пользователя)
+			r.With(
+				transactionHandler.Transaction,
+			).Post("/logout", sessionHandler.HandleLogout)
 
-			// приём от клиента публичного ключа
-			r.Post("/save_public_key", keysDataHandler.HandleSaveClientPublicKey)
+			// подключение второго фактора: новый секрет и ссылка для приложения-аутентификатора
+			r.With(
+				transactionHandler.Transaction,
+			).Post("/totp/enroll", totpHandler.HandleEnroll)
+
+			// подтверждение второго фактора кодом, выдача резервных кодов
+			r.With(
+				NewValidatorHandler(new(totpCodeRequest), ar.log).HandleValidation,
+				transactionHandler.Transaction,
+			).Post("/totp/confirm", totpHandler.HandleConfirm)
+
+			// новые резервные коды взамен прежних
+			r.With(
+				NewValidatorHandler(new(totpCodeRequest), ar.log).HandleValidation,
+				transactionHandler.Transaction,
+			).Post("/totp/backup_codes", totpHandler.HandleBackupCodes)
+
+			// отключение второго фактора
+			r.With(
+				NewValidatorHandler(new(totpCodeRequest), ar.log).HandleValidation,
+				transactionHandler.Transaction,
+			).Post("/totp/disable", totpHandler.HandleDisable)
+
+			// приём от клиента публичного ключа (при включённом mTLS в ответе сертификат клиента)
+			r.With(
+				auditHandler.HandleAudit(models.AuditKeyExchange, "save_public_key"),
+				transactionHandler.Transaction,
+			).Post("/save_public_key", keysDataHandler.HandleSaveClientPublicKey)
 
 			// приём от клиента приватного ключа(aes используется для шифрования данных)
-			r.Post("/save_client_private_key", keysDataHandler.HandleSaveClientPrivateKey)
+			r.With(
+				auditHandler.HandleAudit(models.AuditKeyExchange, "save_client_private_key"),
+				transactionHandler.Transaction,
+			).Post("/save_client_private_key", keysDataHandler.HandleSaveClientPrivateKey)
+
+			// смена приватного ключа клиента: прежний ключ действует, пока новый не заменит его в фоне
+			// (без транзакции запроса: замена в фоне начинается до ответа и должна видеть запись о смене)
+			r.With(
+				auditHandler.HandleAudit(models.AuditKeyExchange, "rotate_client_private_key"),
+			).Post("/rotate_client_private_key", keyRotationHandler.HandleRotate)
+
+			// состояние последней смены ключа клиента
+			r.Get("/key_rotation", keyRotationHandler.HandleStatus)
 
 			// Клиент забирает публичный ключ сервера
-			r.Post("/download_server_public_key", keysDataHandler.HandleDownloadServerPublicKey)
+			r.With(
+				auditHandler.HandleAudit(models.AuditKeyExchange, "download_server_public_key"),
+			).Post("/download_server_public_key", keysDataHandler.HandleDownloadServerPublicKey)
+
+			// параметры мастер-ключа клиента (соль и контрольное значение)
+			r.Get("/master_key", masterKeyHandler.HandleGet)
+
+			// первичная установка параметров мастер-ключа клиента
+			r.With(
+				NewValidatorHandler(new(masterKeyRequest), ar.log).HandleValidation,
+				transactionHandler.Transaction,
+			).Post("/save_master_key", masterKeyHandler.HandleSave)
+
+			// данные, сохранённые до шифрования мастер-ключом, перешифрованы клиентом
+			r.With(
+				transactionHandler.Transaction,
+			).Post("/master_key/migrated", masterKeyHandler.HandleMigrated)
+
+			// комплект восстановления ключей клиента (зашифрован на клиенте)
+			r.Get("/recovery_kit", recoveryKitHandler.HandleGet)
+
+			// сохранение комплекта восстановления (при создании и после смены ключа клиента)
+			r.With(
+				NewValidatorHandler(new(recoveryKitRequest), ar.log).HandleValidation,
+				transactionHandler.Transaction,
+			).Post("/save_recovery_kit", recoveryKitHandler.HandleSave)
+
+			// устройства пользователя
+			r.Get("/devices", deviceHandler.HandleList)
+
+			// устройство текущей сессии и его зашифрованный ключ хранилища
+			r.Get("/devices/current", deviceHandler.HandleCurrent)
+
+			// новое имя устройства
+			r.With(
+				NewValidatorHandler(new(deviceRenameRequest), ar.log).HandleValidation,
+				transactionHandler.Transaction,
+			).Post("/devices/{uuid}/rename", deviceHandler.HandleRename)
+
+			// отзыв устройства: его сессии закрываются сразу
+			r.With(
+				transactionHandler.Transaction,
+			).Post("/devices/{uuid}/revoke", deviceHandler.HandleRevoke)
+
+			// журнал действий пользователя (фильтры action, data_uuid, from, to; страница offset, limit)
+			r.Get("/audit", auditHandler.HandleList)
+
+			// проверка цепочки хэшей журнала действий
+			r.Get("/audit/verify", auditHandler.HandleVerify)
 
 			// список сохранённых данных
 			r.With(
 				decryptDataHandler.HandleEncryptData, // шифрует исходящий запрос
 			).Get("/items_list", itemsListHandler.HandleItemsList)
 
+			// изменения данных после курсора (cursor, limit)
+			r.With(
+				decryptDataHandler.HandleEncryptData, // шифрует исходящий запрос
+			).Get("/sync", syncHandler.HandleSync)
+
+			// поток событий изменения данных (server-sent events), данные событий шифруются ключом клиента
+			r.Get("/events", eventsHandler.HandleEvents)
+
 			// Получить данные по uuid
 			r.With(
+				auditHandler.HandleAudit(models.AuditItemRead, ""),
 				decryptDataHandler.HandleEncryptData, // шифрует исходящий запрос
 			).Get("/item_get/{uuid}", itemDataHandler.HandleItem)
 
 			// добавить/изменить данные банковской карты
 			r.With(
+				auditHandler.HandleAudit(models.AuditItemSave, "card"),
 				decryptDataHandler.HandleDecryptData, // расшифровка тела запроса
 				NewValidatorHandler(new(cardDataRequest), ar.log).HandleValidation,
+				idempotencyHandler.HandleIdempotency, // повтор запроса с тем же ключом не выполняется
+				transactionHandler.Transaction,
 			).Post("/save_card_data", cardDataHandler.HandleSave)
 
 			// добавить/изменить текстовые данные
 			r.With(
+				auditHandler.HandleAudit(models.AuditItemSave, "text"),
 				decryptDataHandler.HandleDecryptData, // расшифровка тела запроса
 				NewValidatorHandler(new(textDataRequest), ar.log).HandleValidation,
+				idempotencyHandler.HandleIdempotency, // повтор запроса с тем же ключом не выполняется
+				transactionHandler.Transaction,
 			).Post("/save_text_data", textDataHandler.HandleSave)
 
+			// добавить/изменить пару логин/пароль
+			r.With(
+				auditHandler.HandleAudit(models.AuditItemSave, "credential"),
+				decryptDataHandler.HandleDecryptData, // расшифровка тела запроса
+				NewValidatorHandler(new(credentialDataRequest), ar.log).HandleValidation,
+				idempotencyHandler.HandleIdempotency, // повтор запроса с тем же ключом не выполняется
+				transactionHandler.Transaction,
+			).Post("/save_credential_data", credentialDataHandler.HandleSave)
+
+			// пакет операций create/update/delete над данными, применяется целиком или не применяется
+			r.With(
+				auditHandler.HandleAudit(models.AuditItemSave, "batch"),
+				decryptDataHandler.HandleDecryptData, // расшифровка тела запроса
+				NewValidatorHandler(new(batchRequest), ar.log).HandleValidation,
+				idempotencyHandler.HandleIdempotency, // повтор запроса с тем же ключом не выполняется
+				transactionHandler.Transaction,
+			).Post("/batch", batchHandler.HandleBatch)
+
 			// инициализация приёма файла, базовые данные о файле
 			r.With(
+				auditHandler.HandleAudit(models.AuditItemSave, "file"),
 				decryptDataHandler.HandleDecryptData, // расшифровка тела запроса
 				NewValidatorHandler(new(fileDataInitRequest), ar.log).HandleValidation,
+				idempotencyHandler.HandleIdempotency, // повтор запроса с тем же ключом не выполняется
+				transactionHandler.Transaction,
 			).Post("/file_data/init", fileDataHandler.HandleInit)
 
-			// приём данных файла
+			// приём части файла
 			r.With(
-				decryptDataHandler.HandleDecryptData, // расшифровка тела запроса
+				auditHandler.HandleAudit(models.AuditItemSave, "file_content"),
+				middleware.RequestSize(data_type.FileChunkMaxSize+64<<10), // часть с конвертом и multipart заголовками
+				decryptDataHandler.HandleDecryptData,                      // расшифровка тела запроса
+				transactionHandler.Transaction,
 			).Post("/file_data/load/{file_uuid}/{part}", fileDataHandler.HandleAction)
 
+			// состояние загрузки файла: принятые части
+			r.Get("/file_data/status/{file_uuid}", fileDataHandler.HandleStatus)
+
 			// отдача файла клиенту
 			r.With(
+				auditHandler.HandleAudit(models.AuditFileDownload, ""),
 				decryptDataHandler.HandleEncryptData, // шифрует исходящий запрос
 			).Post("/file_data/get/{file_uuid}/{part}", fileDataHandler.HandleGetAction)
 
@@ -142,53 +316,38 @@ func (ar *AppRoutes) DefiningAppRoutes() chi.Router {
 
 			// регистрация пользователя
 			r.With(
+				rateLimitHandler.HandleLimit, // ограничение частоты по IP и логину
 				NewValidatorHandler(new(registrationRequest), ar.log).HandleValidation,
 				transactionHandler.Transaction,
 			).Post("/register", registrationHandler.HandleRegistration)
 
 			// аутентификация пользователя
+			// (без транзакции запроса: неудачные попытки входа сохраняются и при ответе с ошибкой)
 			r.With(
+				rateLimitHandler.HandleLimit, // ограничение частоты по IP и логину
+				auditHandler.HandleAudit(models.AuditLogin, "password"),
 				NewValidatorHandler(new(authenticationRequest), ar.log).HandleValidation,
 			).Post("/login", registrationHandler.HandleAuthentication)
-		})
-
-	})
-
-	return r
-}
-
-// SetTextDataRepository установка репозитария
-func (ar *AppRoutes) SetTextDataRepository(textDataRepository *repository.TextDataRepository) *AppRoutes {
-	ar.textDataRepository = textDataRepository
-	return ar
-}
 
-// SetOwnerRepository установка репозитария
-func (ar *AppRoutes) SetOwnerRepository(ownerRepository *repository.OwnerRepository) *AppRoutes {
-	ar.ownerRepository = ownerRepository
-	return ar
-}
+			// второй шаг входа: код второго фактора
+			r.With(
+				rateLimitHandler.HandleLimit, // ограничение частоты по IP
+				auditHandler.HandleAudit(models.AuditLogin, "totp"),
+				NewValidatorHandler(new(totpLoginRequest), ar.log).HandleValidation,
+			).Post("/login/totp", totpHandler.HandleLogin)
 
-// SetMetaDataRepository установка репозитария
-func (ar *AppRoutes) SetMetaDataRepository(metaDataRepository *repository.MetaDataRepository) *AppRoutes {
-	ar.metaDataRepository = metaDataRepository
-	return ar
-}
+			// вход по сертификату клиента (mTLS)
+			r.With(
+				auditHandler.HandleAudit(models.AuditLogin, "certificate"),
+			).Post("/login/certificate", certificateHandler.HandleLogin)
 
-// SetFileDataRepository установка репозитария
-func (ar *AppRoutes) SetFileDataRepository(fileDataRepository *repository.FileDataRepository) *AppRoutes {
-	ar.fileDataRepository = fileDataRepository
-	return ar
-}
+			// обмен refresh токена на новую пару токенов
+			r.With(
+				NewValidatorHandler(new(refreshTokenRequest), ar.log).HandleValidation,
+			).Post("/token/refresh", sessionHandler.HandleRefresh)
+		})
 
-// SetCardDataRepository установка репозитария
-func (ar *AppRoutes) SetCardDataRepository(cardDataRepository *repository.CardDataRepository) *AppRoutes {
-	ar.cardDataRepository = cardDataRepository
-	return ar
-}
+	})
 
-// SetUserRepository установка репозитария
-func (ar *AppRoutes) SetUserRepository(userRepository *repository.UserRepository) *AppRoutes {
-	ar.userRepository = userRepository
-	return ar
+	return r
 }
@@ -9,7 +9,9 @@ import (
 	"github.com/northmule/gophkeeper/internal/server/config"
 	"github.com/northmule/gophkeeper/internal/server/logger"
 	appMock "github.com/northmule/gophkeeper/internal/server/repository/mock"
+	"github.com/northmule/gophkeeper/internal/server/services/events"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 )
 
 func TestDefiningAppRoutes(t *testing.T) {
@@ -19,16 +21,20 @@ func TestDefiningAppRoutes(t *testing.T) {
 	mockSessionStorage := new(appMock.MockSessionManager)
 	mockAccessService := new(appMock.MockAccessService)
 	mockCryptService := new(appMock.MockCryptService)
+	mockRepository.On("LoginFailure").Return(new(appMock.MockLoginFailureModelRepository))
+	mockRepository.On("RequestNonce").Return(new(appMock.MockRequestNonceModelRepository))
+	mockRepository.On("AuditEvent").Return(new(appMock.MockAuditEventModelRepository))
 
 	l, _ := logger.NewLogger("info")
 	cfg := config.NewConfig()
 	_ = cfg.Init()
 	cfg.Value().PathKeys = t.TempDir()
 
-	appRoutes := NewAppRoutes(mockRepository, mockStorage, mockSessionStorage, l, cfg, mockAccessService, mockCryptService)
+	appRoutes := NewAppRoutes(mockRepository, mockStorage, mockSessionStorage, l, cfg, mockAccessService, mockCryptService, newTestKeyProvider(t), nil, new(mockKeyRotator), events.NewHub())
 
 	jwt := new(jwtauth.JWTAuth)
 	mockAccessService.On("FillJWTToken").Return(jwt)
+	mockAccessService.On("JWTVerifier", mock.Anything).Return(nil)
 	router := appRoutes.DefiningAppRoutes()
 
 	assert.NotNil(t, router)
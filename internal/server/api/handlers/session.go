@@ -0,0 +1,311 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/render"
+	"github.com/google/uuid"
+	"github.com/northmule/gophkeeper/internal/common/model_data"
+	"github.com/northmule/gophkeeper/internal/common/models"
+	"github.com/northmule/gophkeeper/internal/common/util"
+	"github.com/northmule/gophkeeper/internal/server/config"
+	"github.com/northmule/gophkeeper/internal/server/logger"
+	"github.com/northmule/gophkeeper/internal/server/repository"
+	"github.com/northmule/gophkeeper/internal/server/storage"
+)
+
+// Токен доступа короткоживущий и привязан к сессии (claim sid). Сессия хранится в таблице sessions
+// вместе с хэшем refresh токена, который меняется при каждом обновлении. Повторное использование
+// уже заменённого refresh токена считается утечкой и отзывает сессию целиком.
+
+// sessionCacheTTL время, в течение которого сессия проверяется по кэшу без обращения к БД.
+// Сессию могут отозвать на другом экземпляре сервера, отзыв доходит до этого экземпляра не позже этого времени
+const sessionCacheTTL = 30 * time.Second
+
+// SessionHandler сессии пользователей
+type SessionHandler struct {
+	log           *logger.Logger
+	accessService SessionAccess
+	manager       repository.Repository
+	session       storage.SessionManager
+	cfg           *config.Config
+}
+
+// TokenIssuer выпуск токенов доступа
+type TokenIssuer interface {
+	IssueToken(userUUID string, sessionUUID string, deviceUUID string) (string, error)
+}
+
+// SessionAccess сервис доступа, необходимый для работы с сессиями
+type SessionAccess interface {
+	TokenIssuer
+	UserFinderByJWT
+	GetSessionUUIDByJWTToken(ctx context.Context) (string, error)
+}
+
+// SessionOpener открытие сессии пользователя на устройстве
+type SessionOpener interface {
+	Open(ctx context.Context, userUUID string, device *models.Device) (*model_data.TokenResponse, error)
+}
+
+// NewSessionHandler конструктор
+func NewSessionHandler(accessService SessionAccess, manager repository.Repository, session storage.SessionManager, cfg *config.Config, log *logger.Logger) *SessionHandler {
+	return &SessionHandler{
+		accessService: accessService,
+		manager:       manager,
+		session:       session,
+		cfg:           cfg,
+		log:           log,
+	}
+}
+
+type refreshTokenRequest struct {
+	model_data.RefreshTokenRequest
+}
+
+// Bind декодирует json в структуру
+func (rr *refreshTokenRequest) Bind(r *http.Request) error {
+	return nil
+}
+
+type tokenResponse struct {
+	model_data.TokenResponse
+}
+
+func (tr tokenResponse) Render(res http.ResponseWriter, req *http.Request) error {
+	return nil
+}
+
+// Open новая сессия и пара токенов для пользователя. device - устройство из запроса входа (requestDevice)
+func (h *SessionHandler) Open(ctx context.Context, userUUID string, device *models.Device) (*model_data.TokenResponse, error) {
+	deviceUUID, err := h.openDevice(ctx, userUUID, device)
+	if err != nil {
+		return nil, err
+	}
+	refreshToken, err := util.NewRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+	session := &models.Session{
+		UserUUID:         userUUID,
+		DeviceUUID:       deviceUUID,
+		RefreshTokenHash: util.TokenHash(refreshToken),
+		ExpiresAt:        time.Now().Add(h.cfg.Value().RefreshTokenTTL),
+	}
+	session.UUID = uuid.NewString()
+	_, err = h.manager.Session().Add(ctx, session)
+	if err != nil {
+		return nil, err
+	}
+	h.session.Add(session.UUID, userUUID, sessionCacheUntil(session.ExpiresAt))
+
+	return h.issue(userUUID, session.UUID, deviceUUID, refreshToken)
+}
+
+// openDevice устройство сессии: известное действующее устройство пользователя или новое.
+// Отозванное устройство заново не открывается, вход с него заводит новое устройство
+func (h *SessionHandler) openDevice(ctx context.Context, userUUID string, device *models.Device) (string, error) {
+	if device.UUID != "" {
+		known, err := h.manager.Device().FindOneByUUID(ctx, device.UUID)
+		if err != nil {
+			return "", err
+		}
+		if known != nil && known.UserUUID == userUUID && known.IsActive() {
+			err = h.manager.Device().Touch(ctx, known.UUID)
+			if err != nil {
+				return "", err
+			}
+			return known.UUID, nil
+		}
+	}
+
+	created := &models.Device{UserUUID: userUUID, Name: device.Name}
+	created.UUID = uuid.NewString()
+	if created.Name == "" {
+		created.Name = defaultDeviceName
+	}
+	_, err := h.manager.Device().Add(ctx, created)
+	if err != nil {
+		return "", err
+	}
+	h.log.Infof("A new device %s has been registered for user %s", created.UUID, userUUID)
+	return created.UUID, nil
+}
+
+// HandleRefresh обмен refresh токена на новую пару токенов
+func (h *SessionHandler) HandleRefresh(res http.ResponseWriter, req *http.Request) {
+	var err error
+	request := new(refreshTokenRequest)
+	if err = render.Bind(req, request); err != nil {
+		h.log.Info(err)
+		_ = render.Render(res, req, ErrBadRequest)
+		return
+	}
+
+	hash := util.TokenHash(request.RefreshToken)
+	session, err := h.manager.Session().FindOneByRefreshTokenHash(req.Context(), hash)
+	if err != nil {
+		h.log.Error(err)
+		_ = render.Render(res, req, ErrInternalServerError)
+		return
+	}
+	if session == nil || !session.IsActive() {
+		h.log.Info("refresh token not found or session is closed")
+		_ = render.Render(res, req, ErrUnauthorized)
+		return
+	}
+	if session.RefreshTokenHash != hash {
+		// предъявлен уже заменённый токен
+		h.log.Infof("refresh token reuse detected, session %s of user %s is revoked", session.UUID, session.UserUUID)
+		h.revoke(req.Context(), session.UUID)
+		_ = render.Render(res, req, ErrUnauthorized)
+		return
+	}
+
+	refreshToken, err := util.NewRefreshToken()
+	if err != nil {
+		h.log.Error(err)
+		_ = render.Render(res, req, ErrInternalServerError)
+		return
+	}
+	expiresAt := time.Now().Add(h.cfg.Value().RefreshTokenTTL)
+	rotated, err := h.manager.Session().Rotate(req.Context(), session.UUID, hash, util.TokenHash(refreshToken), expiresAt)
+	if err != nil {
+		h.log.Error(err)
+		_ = render.Render(res, req, ErrInternalServerError)
+		return
+	}
+	if !rotated {
+		h.log.Infof("refresh token of session %s has already been used", session.UUID)
+		_ = render.Render(res, req, ErrUnauthorized)
+		return
+	}
+	h.session.Add(session.UUID, session.UserUUID, sessionCacheUntil(expiresAt))
+	if session.DeviceUUID != "" {
+		err = h.manager.Device().Touch(req.Context(), session.DeviceUUID)
+		if err != nil {
+			h.log.Error(err)
+		}
+	}
+
+	tokens, err := h.issue(session.UserUUID, session.UUID, session.DeviceUUID, refreshToken)
+	if err != nil {
+		h.log.Error(err)
+		_ = render.Render(res, req, ErrInternalServerError)
+		return
+	}
+	h.renderTokens(res, req, tokens)
+}
+
+// HandleLogout закрытие текущей сессии, с параметром all=1 закрываются все сессии пользователя
+func (h *SessionHandler) HandleLogout(res http.ResponseWriter, req *http.Request) {
+	userUUID, err := h.accessService.GetUserUUIDByJWTToken(req.Context())
+	if err != nil {
+		h.log.Error(err)
+		_ = render.Render(res, req, ErrBadRequest)
+		return
+	}
+	sessionUUID, err := h.accessService.GetSessionUUIDByJWTToken(req.Context())
+	if err != nil {
+		h.log.Error(err)
+		_ = render.Render(res, req, ErrBadRequest)
+		return
+	}
+
+	if req.URL.Query().Get("all") == "1" {
+		err = h.manager.Session().RevokeAllByUserUUID(req.Context(), userUUID)
+		if err != nil {
+			h.log.Error(err)
+			_ = render.Render(res, req, ErrInternalServerError)
+			return
+		}
+		for _, value := range h.session.List(userUUID) {
+			h.session.Revoke(value)
+		}
+		h.log.Infof("All sessions of user %s are closed", userUUID)
+		res.WriteHeader(http.StatusOK)
+		return
+	}
+
+	err = h.manager.Session().Revoke(req.Context(), sessionUUID)
+	if err != nil {
+		h.log.Error(err)
+		_ = render.Render(res, req, ErrInternalServerError)
+		return
+	}
+	h.session.Revoke(sessionUUID)
+	h.log.Infof("Session %s of user %s is closed", sessionUUID, userUUID)
+	res.WriteHeader(http.StatusOK)
+}
+
+// HandleCheckSession проверка, что сессия токена не отозвана
+func (h *SessionHandler) HandleCheckSession(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		sessionUUID, err := h.accessService.GetSessionUUIDByJWTToken(req.Context())
+		if err != nil {
+			h.log.Info(err)
+			_ = render.Render(res, req, ErrUnauthorized)
+			return
+		}
+		if h.session.IsValid(sessionUUID) {
+			next.ServeHTTP(res, req)
+			return
+		}
+
+		session, err := h.manager.Session().FindOneByUUID(req.Context(), sessionUUID)
+		if err != nil {
+			h.log.Error(err)
+			_ = render.Render(res, req, ErrInternalServerError)
+			return
+		}
+		if session == nil || !session.IsActive() {
+			h.log.Infof("session %s is revoked or expired", sessionUUID)
+			_ = render.Render(res, req, ErrUnauthorized)
+			return
+		}
+		h.session.Add(session.UUID, session.UserUUID, sessionCacheUntil(session.ExpiresAt))
+
+		next.ServeHTTP(res, req)
+	})
+}
+
+// sessionCacheUntil срок записи сессии в кэше: не дольше sessionCacheTTL и срока самой сессии
+func sessionCacheUntil(expiresAt time.Time) time.Time {
+	until := time.Now().Add(sessionCacheTTL)
+	if expiresAt.Before(until) {
+		return expiresAt
+	}
+	return until
+}
+
+func (h *SessionHandler) issue(userUUID string, sessionUUID string, deviceUUID string, refreshToken string) (*model_data.TokenResponse, error) {
+	accessToken, err := h.accessService.IssueToken(userUUID, sessionUUID, deviceUUID)
+	if err != nil {
+		return nil, err
+	}
+	return &model_data.TokenResponse{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(h.cfg.Value().JWTTTL.Seconds()),
+		DeviceUUID:   deviceUUID,
+	}, nil
+}
+
+func (h *SessionHandler) revoke(ctx context.Context, sessionUUID string) {
+	err := h.manager.Session().Revoke(ctx, sessionUUID)
+	if err != nil {
+		h.log.Error(err)
+	}
+	h.session.Revoke(sessionUUID)
+}
+
+func (h *SessionHandler) renderTokens(res http.ResponseWriter, req *http.Request, tokens *model_data.TokenResponse) {
+	res.Header().Set("Authorization", "Bearer "+tokens.AccessToken)
+	err := render.Render(res, req, tokenResponse{TokenResponse: *tokens})
+	if err != nil {
+		h.log.Error(err)
+		_ = render.Render(res, req, ErrInternalServerError)
+	}
+}
@@ -0,0 +1,374 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/northmule/gophkeeper/internal/common/model_data"
+	"github.com/northmule/gophkeeper/internal/common/models"
+	"github.com/northmule/gophkeeper/internal/common/util"
+	"github.com/northmule/gophkeeper/internal/server/config"
+	"github.com/northmule/gophkeeper/internal/server/logger"
+	appMock "github.com/northmule/gophkeeper/internal/server/repository/mock"
+	"github.com/northmule/gophkeeper/internal/server/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func newSessionTestConfig() *config.Config {
+	cfg := config.NewConfig()
+	cfg.Value().JWTTTL = 15 * time.Minute
+	cfg.Value().RefreshTokenTTL = time.Hour
+	return cfg
+}
+
+func newTestSession(refreshToken string) *models.Session {
+	session := &models.Session{
+		UserUUID:         "user-uuid",
+		RefreshTokenHash: util.TokenHash(refreshToken),
+		ExpiresAt:        time.Now().Add(time.Hour),
+	}
+	session.UUID = "session-uuid"
+	return session
+}
+
+func TestSessionHandler_Open(t *testing.T) {
+	l, _ := logger.NewLogger("info")
+
+	t.Run("new_device", func(t *testing.T) {
+		mockAccessService := new(appMock.MockAccessService)
+		mockRepository := new(appMock.MockManager)
+		mockSessionRepository := new(appMock.MockSessionModelRepository)
+		mockDeviceRepository := new(appMock.MockDeviceModelRepository)
+		sessionStorage := storage.NewSession()
+		mockRepository.On("Session").Return(mockSessionRepository)
+		mockRepository.On("Device").Return(mockDeviceRepository)
+
+		var saved *models.Session
+		var device *models.Device
+		mockDeviceRepository.On("Add", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+			device = args.Get(1).(*models.Device)
+		}).Return(int64(1), nil)
+		mockSessionRepository.On("Add", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+			saved = args.Get(1).(*models.Session)
+		}).Return(int64(1), nil)
+		mockAccessService.On("IssueToken", "user-uuid", mock.Anything, mock.Anything).Return("access-token", nil)
+
+		handler := NewSessionHandler(mockAccessService, mockRepository, sessionStorage, newSessionTestConfig(), l)
+		tokens, err := handler.Open(context.Background(), "user-uuid", &models.Device{Name: "laptop"})
+		require.NoError(t, err)
+
+		assert.Equal(t, "access-token", tokens.AccessToken)
+		assert.Equal(t, int64(900), tokens.ExpiresIn)
+		assert.NotEmpty(t, tokens.RefreshToken)
+		require.NotNil(t, saved)
+		require.NotNil(t, device)
+		assert.Equal(t, "laptop", device.Name)
+		assert.Equal(t, "user-uuid", device.UserUUID)
+		assert.Equal(t, device.UUID, tokens.DeviceUUID)
+		assert.Equal(t, device.UUID, saved.DeviceUUID)
+		assert.Equal(t, util.TokenHash(tokens.RefreshToken), saved.RefreshTokenHash)
+		assert.Equal(t, "user-uuid", saved.UserUUID)
+		assert.True(t, sessionStorage.IsValid(saved.UUID))
+		mockAccessService.AssertCalled(t, "IssueToken", "user-uuid", saved.UUID, device.UUID)
+	})
+
+	t.Run("known_device", func(t *testing.T) {
+		mockAccessService := new(appMock.MockAccessService)
+		mockRepository := new(appMock.MockManager)
+		mockSessionRepository := new(appMock.MockSessionModelRepository)
+		mockDeviceRepository := new(appMock.MockDeviceModelRepository)
+		mockRepository.On("Session").Return(mockSessionRepository)
+		mockRepository.On("Device").Return(mockDeviceRepository)
+		known := &models.Device{UserUUID: "user-uuid", Name: "laptop"}
+		known.UUID = "device-uuid"
+		mockDeviceRepository.On("FindOneByUUID", mock.Anything, "device-uuid").Return(known, nil)
+		mockDeviceRepository.On("Touch", mock.Anything, "device-uuid").Return(nil)
+		mockSessionRepository.On("Add", mock.Anything, mock.Anything).Return(int64(1), nil)
+		mockAccessService.On("IssueToken", "user-uuid", mock.Anything, "device-uuid").Return("access-token", nil)
+
+		handler := NewSessionHandler(mockAccessService, mockRepository, storage.NewSession(), newSessionTestConfig(), l)
+		tokens, err := handler.Open(context.Background(), "user-uuid", &models.Device{Common: models.Common{UUID: "device-uuid"}})
+		require.NoError(t, err)
+
+		assert.Equal(t, "device-uuid", tokens.DeviceUUID)
+		mockDeviceRepository.AssertNotCalled(t, "Add", mock.Anything, mock.Anything)
+	})
+
+	t.Run("revoked_device", func(t *testing.T) {
+		mockAccessService := new(appMock.MockAccessService)
+		mockRepository := new(appMock.MockManager)
+		mockSessionRepository := new(appMock.MockSessionModelRepository)
+		mockDeviceRepository := new(appMock.MockDeviceModelRepository)
+		mockRepository.On("Session").Return(mockSessionRepository)
+		mockRepository.On("Device").Return(mockDeviceRepository)
+		revokedAt := time.Now()
+		known := &models.Device{UserUUID: "user-uuid", RevokedAt: &revokedAt}
+		known.UUID = "device-uuid"
+		mockDeviceRepository.On("FindOneByUUID", mock.Anything, "device-uuid").Return(known, nil)
+		mockDeviceRepository.On("Add", mock.Anything, mock.Anything).Return(int64(2), nil)
+		mockSessionRepository.On("Add", mock.Anything, mock.Anything).Return(int64(1), nil)
+		mockAccessService.On("IssueToken", "user-uuid", mock.Anything, mock.Anything).Return("access-token", nil)
+
+		handler := NewSessionHandler(mockAccessService, mockRepository, storage.NewSession(), newSessionTestConfig(), l)
+		tokens, err := handler.Open(context.Background(), "user-uuid", &models.Device{Common: models.Common{UUID: "device-uuid"}})
+		require.NoError(t, err)
+
+		assert.NotEqual(t, "device-uuid", tokens.DeviceUUID)
+		mockDeviceRepository.AssertNotCalled(t, "Touch", mock.Anything, mock.Anything)
+	})
+}
+
+func TestSessionHandler_HandleRefresh(t *testing.T) {
+	l, _ := logger.NewLogger("info")
+	reqBody, _ := json.Marshal(model_data.RefreshTokenRequest{RefreshToken: "refresh-token"})
+
+	t.Run("ok", func(t *testing.T) {
+		mockAccessService := new(appMock.MockAccessService)
+		mockRepository := new(appMock.MockManager)
+		mockSessionRepository := new(appMock.MockSessionModelRepository)
+		sessionStorage := storage.NewSession()
+		mockRepository.On("Session").Return(mockSessionRepository)
+		mockSessionRepository.On("FindOneByRefreshTokenHash", mock.Anything, util.TokenHash("refresh-token")).Return(newTestSession("refresh-token"), nil)
+		mockSessionRepository.On("Rotate", mock.Anything, "session-uuid", util.TokenHash("refresh-token"), mock.Anything, mock.Anything).Return(true, nil)
+		mockAccessService.On("IssueToken", "user-uuid", "session-uuid", "").Return("access-token", nil)
+
+		req := httptest.NewRequest(http.MethodPost, "/token/refresh", bytes.NewBuffer(reqBody))
+		req.Header.Set("Content-Type", "application/json")
+		res := httptest.NewRecorder()
+		NewSessionHandler(mockAccessService, mockRepository, sessionStorage, newSessionTestConfig(), l).HandleRefresh(res, req)
+
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.Equal(t, "Bearer access-token", res.Header().Get("Authorization"))
+		response := new(model_data.TokenResponse)
+		require.NoError(t, json.Unmarshal(res.Body.Bytes(), response))
+		assert.NotEmpty(t, response.RefreshToken)
+		assert.NotEqual(t, "refresh-token", response.RefreshToken)
+		mockSessionRepository.AssertCalled(t, "Rotate", mock.Anything, "session-uuid", util.TokenHash("refresh-token"), util.TokenHash(response.RefreshToken), mock.Anything)
+		assert.True(t, sessionStorage.IsValid("session-uuid"))
+	})
+
+	t.Run("not_found", func(t *testing.T) {
+		mockRepository := new(appMock.MockManager)
+		mockSessionRepository := new(appMock.MockSessionModelRepository)
+		mockRepository.On("Session").Return(mockSessionRepository)
+		mockSessionRepository.On("FindOneByRefreshTokenHash", mock.Anything, mock.Anything).Return(nil, nil)
+
+		req := httptest.NewRequest(http.MethodPost, "/token/refresh", bytes.NewBuffer(reqBody))
+		req.Header.Set("Content-Type", "application/json")
+		res := httptest.NewRecorder()
+		NewSessionHandler(new(appMock.MockAccessService), mockRepository, storage.NewSession(), newSessionTestConfig(), l).HandleRefresh(res, req)
+
+		assert.Equal(t, http.StatusUnauthorized, res.Code)
+	})
+
+	t.Run("revoked", func(t *testing.T) {
+		mockRepository := new(appMock.MockManager)
+		mockSessionRepository := new(appMock.MockSessionModelRepository)
+		mockRepository.On("Session").Return(mockSessionRepository)
+		session := newTestSession("refresh-token")
+		revokedAt := time.Now()
+		session.RevokedAt = &revokedAt
+		mockSessionRepository.On("FindOneByRefreshTokenHash", mock.Anything, mock.Anything).Return(session, nil)
+
+		req := httptest.NewRequest(http.MethodPost, "/token/refresh", bytes.NewBuffer(reqBody))
+		req.Header.Set("Content-Type", "application/json")
+		res := httptest.NewRecorder()
+		NewSessionHandler(new(appMock.MockAccessService), mockRepository, storage.NewSession(), newSessionTestConfig(), l).HandleRefresh(res, req)
+
+		assert.Equal(t, http.StatusUnauthorized, res.Code)
+	})
+
+	t.Run("reuse_revokes_session", func(t *testing.T) {
+		mockRepository := new(appMock.MockManager)
+		mockSessionRepository := new(appMock.MockSessionModelRepository)
+		sessionStorage := storage.NewSession()
+		sessionStorage.Add("session-uuid", "user-uuid", time.Now().Add(time.Hour))
+		mockRepository.On("Session").Return(mockSessionRepository)
+		// текущий токен уже другой, предъявлен предыдущий
+		session := newTestSession("new-refresh-token")
+		session.PreviousRefreshTokenHash = util.TokenHash("refresh-token")
+		mockSessionRepository.On("FindOneByRefreshTokenHash", mock.Anything, mock.Anything).Return(session, nil)
+		mockSessionRepository.On("Revoke", mock.Anything, "session-uuid").Return(nil)
+
+		req := httptest.NewRequest(http.MethodPost, "/token/refresh", bytes.NewBuffer(reqBody))
+		req.Header.Set("Content-Type", "application/json")
+		res := httptest.NewRecorder()
+		NewSessionHandler(new(appMock.MockAccessService), mockRepository, sessionStorage, newSessionTestConfig(), l).HandleRefresh(res, req)
+
+		assert.Equal(t, http.StatusUnauthorized, res.Code)
+		mockSessionRepository.AssertCalled(t, "Revoke", mock.Anything, "session-uuid")
+		assert.False(t, sessionStorage.IsValid("session-uuid"))
+	})
+
+	t.Run("concurrent_rotation", func(t *testing.T) {
+		mockRepository := new(appMock.MockManager)
+		mockSessionRepository := new(appMock.MockSessionModelRepository)
+		mockRepository.On("Session").Return(mockSessionRepository)
+		mockSessionRepository.On("FindOneByRefreshTokenHash", mock.Anything, mock.Anything).Return(newTestSession("refresh-token"), nil)
+		mockSessionRepository.On("Rotate", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(false, nil)
+
+		req := httptest.NewRequest(http.MethodPost, "/token/refresh", bytes.NewBuffer(reqBody))
+		req.Header.Set("Content-Type", "application/json")
+		res := httptest.NewRecorder()
+		NewSessionHandler(new(appMock.MockAccessService), mockRepository, storage.NewSession(), newSessionTestConfig(), l).HandleRefresh(res, req)
+
+		assert.Equal(t, http.StatusUnauthorized, res.Code)
+	})
+
+	t.Run("db_error", func(t *testing.T) {
+		mockRepository := new(appMock.MockManager)
+		mockSessionRepository := new(appMock.MockSessionModelRepository)
+		mockRepository.On("Session").Return(mockSessionRepository)
+		mockSessionRepository.On("FindOneByRefreshTokenHash", mock.Anything, mock.Anything).Return(nil, errors.New("db error"))
+
+		req := httptest.NewRequest(http.MethodPost, "/token/refresh", bytes.NewBuffer(reqBody))
+		req.Header.Set("Content-Type", "application/json")
+		res := httptest.NewRecorder()
+		NewSessionHandler(new(appMock.MockAccessService), mockRepository, storage.NewSession(), newSessionTestConfig(), l).HandleRefresh(res, req)
+
+		assert.Equal(t, http.StatusInternalServerError, res.Code)
+	})
+}
+
+func TestSessionHandler_HandleLogout(t *testing.T) {
+	l, _ := logger.NewLogger("info")
+
+	t.Run("current", func(t *testing.T) {
+		mockAccessService := new(appMock.MockAccessService)
+		mockRepository := new(appMock.MockManager)
+		mockSessionRepository := new(appMock.MockSessionModelRepository)
+		sessionStorage := storage.NewSession()
+		sessionStorage.Add("session-uuid", "user-uuid", time.Now().Add(time.Hour))
+		sessionStorage.Add("other-session", "user-uuid", time.Now().Add(time.Hour))
+		mockRepository.On("Session").Return(mockSessionRepository)
+		mockAccessService.On("GetUserUUIDByJWTToken", mock.Anything).Return("user-uuid", nil)
+		mockAccessService.On("GetSessionUUIDByJWTToken", mock.Anything).Return("session-uuid", nil)
+		mockSessionRepository.On("Revoke", mock.Anything, "session-uuid").Return(nil)
+
+		req := httptest.NewRequest(http.MethodPost, "/logout", nil)
+		res := httptest.NewRecorder()
+		NewSessionHandler(mockAccessService, mockRepository, sessionStorage, newSessionTestConfig(), l).HandleLogout(res, req)
+
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.False(t, sessionStorage.IsValid("session-uuid"))
+		assert.True(t, sessionStorage.IsValid("other-session"))
+	})
+
+	t.Run("all", func(t *testing.T) {
+		mockAccessService := new(appMock.MockAccessService)
+		mockRepository := new(appMock.MockManager)
+		mockSessionRepository := new(appMock.MockSessionModelRepository)
+		sessionStorage := storage.NewSession()
+		sessionStorage.Add("session-uuid", "user-uuid", time.Now().Add(time.Hour))
+		sessionStorage.Add("other-session", "user-uuid", time.Now().Add(time.Hour))
+		mockRepository.On("Session").Return(mockSessionRepository)
+		mockAccessService.On("GetUserUUIDByJWTToken", mock.Anything).Return("user-uuid", nil)
+		mockAccessService.On("GetSessionUUIDByJWTToken", mock.Anything).Return("session-uuid", nil)
+		mockSessionRepository.On("RevokeAllByUserUUID", mock.Anything, "user-uuid").Return(nil)
+
+		req := httptest.NewRequest(http.MethodPost, "/logout?all=1", nil)
+		res := httptest.NewRecorder()
+		NewSessionHandler(mockAccessService, mockRepository, sessionStorage, newSessionTestConfig(), l).HandleLogout(res, req)
+
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.Empty(t, sessionStorage.List("user-uuid"))
+	})
+
+	t.Run("db_error", func(t *testing.T) {
+		mockAccessService := new(appMock.MockAccessService)
+		mockRepository := new(appMock.MockManager)
+		mockSessionRepository := new(appMock.MockSessionModelRepository)
+		mockRepository.On("Session").Return(mockSessionRepository)
+		mockAccessService.On("GetUserUUIDByJWTToken", mock.Anything).Return("user-uuid", nil)
+		mockAccessService.On("GetSessionUUIDByJWTToken", mock.Anything).Return("session-uuid", nil)
+		mockSessionRepository.On("Revoke", mock.Anything, "session-uuid").Return(errors.New("db error"))
+
+		req := httptest.NewRequest(http.MethodPost, "/logout", nil)
+		res := httptest.NewRecorder()
+		NewSessionHandler(mockAccessService, mockRepository, storage.NewSession(), newSessionTestConfig(), l).HandleLogout(res, req)
+
+		assert.Equal(t, http.StatusInternalServerError, res.Code)
+	})
+}
+
+func TestSessionHandler_HandleCheckSession(t *testing.T) {
+	l, _ := logger.NewLogger("info")
+	next := http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		res.WriteHeader(http.StatusOK)
+	})
+
+	t.Run("cached", func(t *testing.T) {
+		mockAccessService := new(appMock.MockAccessService)
+		sessionStorage := storage.NewSession()
+		sessionStorage.Add("session-uuid", "user-uuid", time.Now().Add(time.Hour))
+		mockAccessService.On("GetSessionUUIDByJWTToken", mock.Anything).Return("session-uuid", nil)
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		res := httptest.NewRecorder()
+		NewSessionHandler(mockAccessService, new(appMock.MockManager), sessionStorage, newSessionTestConfig(), l).HandleCheckSession(next).ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusOK, res.Code)
+	})
+
+	t.Run("loaded_from_db", func(t *testing.T) {
+		mockAccessService := new(appMock.MockAccessService)
+		mockRepository := new(appMock.MockManager)
+		mockSessionRepository := new(appMock.MockSessionModelRepository)
+		sessionStorage := storage.NewSession()
+		mockRepository.On("Session").Return(mockSessionRepository)
+		mockAccessService.On("GetSessionUUIDByJWTToken", mock.Anything).Return("session-uuid", nil)
+		mockSessionRepository.On("FindOneByUUID", mock.Anything, "session-uuid").Return(newTestSession("refresh-token"), nil)
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		res := httptest.NewRecorder()
+		NewSessionHandler(mockAccessService, mockRepository, sessionStorage, newSessionTestConfig(), l).HandleCheckSession(next).ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.True(t, sessionStorage.IsValid("session-uuid"))
+	})
+
+	t.Run("revoked", func(t *testing.T) {
+		mockAccessService := new(appMock.MockAccessService)
+		mockRepository := new(appMock.MockManager)
+		mockSessionRepository := new(appMock.MockSessionModelRepository)
+		mockRepository.On("Session").Return(mockSessionRepository)
+		mockAccessService.On("GetSessionUUIDByJWTToken", mock.Anything).Return("session-uuid", nil)
+		session := newTestSession("refresh-token")
+		revokedAt := time.Now()
+		session.RevokedAt = &revokedAt
+		mockSessionRepository.On("FindOneByUUID", mock.Anything, "session-uuid").Return(session, nil)
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		res := httptest.NewRecorder()
+		NewSessionHandler(mockAccessService, mockRepository, storage.NewSession(), newSessionTestConfig(), l).HandleCheckSession(next).ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusUnauthorized, res.Code)
+	})
+
+	t.Run("cache_expires_before_session", func(t *testing.T) {
+		expiresAt := time.Now().Add(720 * time.Hour)
+		assert.WithinDuration(t, time.Now().Add(sessionCacheTTL), sessionCacheUntil(expiresAt), time.Second)
+
+		expiresAt = time.Now().Add(time.Second)
+		assert.Equal(t, expiresAt, sessionCacheUntil(expiresAt))
+	})
+
+	t.Run("no_session_claim", func(t *testing.T) {
+		mockAccessService := new(appMock.MockAccessService)
+		mockAccessService.On("GetSessionUUIDByJWTToken", mock.Anything).Return("", errors.New("no session found in claims"))
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		res := httptest.NewRecorder()
+		NewSessionHandler(mockAccessService, new(appMock.MockManager), storage.NewSession(), newSessionTestConfig(), l).HandleCheckSession(next).ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusUnauthorized, res.Code)
+	})
+}
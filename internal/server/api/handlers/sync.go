@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/render"
+	"github.com/northmule/gophkeeper/internal/common/model_data"
+	"github.com/northmule/gophkeeper/internal/server/logger"
+	"github.com/northmule/gophkeeper/internal/server/repository"
+)
+
+const (
+	syncDefaultLimit = 200
+	syncMaxLimit     = 1000
+)
+
+// SyncHandler инкрементальная синхронизация данных по курсору ревизий
+type SyncHandler struct {
+	log           *logger.Logger
+	accessService UserFinderByJWT
+	manager       repository.Repository
+}
+
+// NewSyncHandler конструктор
+func NewSyncHandler(accessService UserFinderByJWT, manager repository.Repository, log *logger.Logger) *SyncHandler {
+	return &SyncHandler{
+		accessService: accessService,
+		manager:       manager,
+		log:           log,
+	}
+}
+
+type syncResponse struct {
+	model_data.SyncResponse
+}
+
+func (hr syncResponse) Render(res http.ResponseWriter, req *http.Request) error {
+	return nil
+}
+
+// HandleSync изменённые и удалённые данные после курсора (cursor - ревизия из прошлого ответа, пустой - все данные),
+// не больше limit записей
+func (h *SyncHandler) HandleSync(res http.ResponseWriter, req *http.Request) {
+	userUUID, err := h.accessService.GetUserUUIDByJWTToken(req.Context())
+	if err != nil {
+		h.log.Error(err)
+		_ = render.Render(res, req, ErrBadRequest)
+		return
+	}
+	query := req.URL.Query()
+	var cursor int64
+	if query.Get("cursor") != "" {
+		cursor, err = strconv.ParseInt(query.Get("cursor"), 10, 64)
+		if err != nil || cursor < 0 {
+			h.log.Info("invalid cursor ", query.Get("cursor"))
+			_ = render.Render(res, req, ErrBadRequest)
+			return
+		}
+	}
+	limit, err := queryInt(query.Get("limit"), syncDefaultLimit)
+	if err != nil || limit < 1 {
+		h.log.Info("invalid limit ", query.Get("limit"))
+		_ = render.Render(res, req, ErrBadRequest)
+		return
+	}
+	limit = min(limit, syncMaxLimit)
+
+	// одна лишняя запись показывает, что изменения не поместились в ответ
+	changes, err := h.manager.Owner().ChangesSince(req.Context(), userUUID, cursor, limit+1)
+	if err != nil {
+		h.log.Error(err)
+		_ = render.Render(res, req, ErrInternalServerError)
+		return
+	}
+	response := syncResponse{}
+	response.HasMore = len(changes) > limit
+	if response.HasMore {
+		changes = changes[:limit]
+	}
+	response.Changed = make([]model_data.ItemDataResponse, 0, len(changes))
+	response.Deleted = make([]model_data.DeletedItemResponse, 0)
+	for _, change := range changes {
+		cursor = change.Revision
+		if change.Deleted {
+			response.Deleted = append(response.Deleted, model_data.DeletedItemResponse{
+				UUID:     change.DataUUID,
+				Type:     change.DataTypeName,
+				Revision: change.Revision,
+			})
+			continue
+		}
+		response.Changed = append(response.Changed, model_data.ItemDataResponse{
+			Type:       change.DataTypeName,
+			Name:       change.DataName,
+			UUID:       change.DataUUID,
+			UpdateDate: change.UpdatedAt.Format(time.DateTime),
+			CreateDate: change.CreatedAt.Format(time.DateTime),
+			Revision:   change.Revision,
+		})
+	}
+	response.Cursor = strconv.FormatInt(cursor, 10)
+
+	err = render.Render(res, req, response)
+	if err != nil {
+		h.log.Error(err)
+		_ = render.Render(res, req, ErrInternalServerError)
+	}
+}
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/northmule/gophkeeper/internal/common/model_data"
+	"github.com/northmule/gophkeeper/internal/common/models"
+	"github.com/northmule/gophkeeper/internal/server/logger"
+	appMock "github.com/northmule/gophkeeper/internal/server/repository/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSyncHandler_HandleSync(t *testing.T) {
+	mockLogger, _ := logger.NewLogger("info")
+	now := time.Now()
+
+	t.Run("changed_and_deleted", func(t *testing.T) {
+		mockAccessService := new(appMock.MockAccessService)
+		mockRepository := new(appMock.MockManager)
+		mockOwnerRepo := new(appMock.MockOwnerDataModelRepository)
+		mockAccessService.On("GetUserUUIDByJWTToken", mock.Anything).Return("user123", nil)
+		mockRepository.On("Owner").Return(mockOwnerRepo)
+		// limit+1 для признака has_more
+		mockOwnerRepo.On("ChangesSince", mock.Anything, "user123", int64(10), 3).Return([]models.OwnerData{
+			{DataUUID: "item1", DataTypeName: "Текст", DataName: "note", CreatedAt: now, UpdatedAt: now, Revision: 11},
+			{DataUUID: "item2", DataTypeName: "Карта", Revision: 12, Deleted: true},
+			{DataUUID: "item3", DataTypeName: "Текст", DataName: "more", Revision: 13},
+		}, nil)
+
+		req := httptest.NewRequest(http.MethodGet, "/sync?cursor=10&limit=2", nil)
+		rr := httptest.NewRecorder()
+		NewSyncHandler(mockAccessService, mockRepository, mockLogger).HandleSync(rr, req)
+
+		require.Equal(t, http.StatusOK, rr.Code)
+		response := model_data.SyncResponse{}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+		require.Len(t, response.Changed, 1)
+		assert.Equal(t, "item1", response.Changed[0].UUID)
+		assert.Equal(t, int64(11), response.Changed[0].Revision)
+		assert.Equal(t, now.Format(time.DateTime), response.Changed[0].UpdateDate)
+		require.Len(t, response.Deleted, 1)
+		assert.Equal(t, "item2", response.Deleted[0].UUID)
+		assert.Equal(t, "12", response.Cursor)
+		assert.True(t, response.HasMore)
+	})
+
+	t.Run("no_changes_keep_cursor", func(t *testing.T) {
+		mockAccessService := new(appMock.MockAccessService)
+		mockRepository := new(appMock.MockManager)
+		mockOwnerRepo := new(appMock.MockOwnerDataModelRepository)
+		mockAccessService.On("GetUserUUIDByJWTToken", mock.Anything).Return("user123", nil)
+		mockRepository.On("Owner").Return(mockOwnerRepo)
+		mockOwnerRepo.On("ChangesSince", mock.Anything, "user123", int64(42), syncDefaultLimit+1).Return([]models.OwnerData{}, nil)
+
+		req := httptest.NewRequest(http.MethodGet, "/sync?cursor=42", nil)
+		rr := httptest.NewRecorder()
+		NewSyncHandler(mockAccessService, mockRepository, mockLogger).HandleSync(rr, req)
+
+		require.Equal(t, http.StatusOK, rr.Code)
+		response := model_data.SyncResponse{}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+		assert.Empty(t, response.Changed)
+		assert.Equal(t, "42", response.Cursor)
+		assert.False(t, response.HasMore)
+	})
+
+	t.Run("invalid_cursor", func(t *testing.T) {
+		mockAccessService := new(appMock.MockAccessService)
+		mockAccessService.On("GetUserUUIDByJWTToken", mock.Anything).Return("user123", nil)
+
+		req := httptest.NewRequest(http.MethodGet, "/sync?cursor=abc", nil)
+		rr := httptest.NewRecorder()
+		NewSyncHandler(mockAccessService, new(appMock.MockManager), mockLogger).HandleSync(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("repository_error", func(t *testing.T) {
+		mockAccessService := new(appMock.MockAccessService)
+		mockRepository := new(appMock.MockManager)
+		mockOwnerRepo := new(appMock.MockOwnerDataModelRepository)
+		mockAccessService.On("GetUserUUIDByJWTToken", mock.Anything).Return("user123", nil)
+		mockRepository.On("Owner").Return(mockOwnerRepo)
+		mockOwnerRepo.On("ChangesSince", mock.Anything, "user123", int64(0), syncDefaultLimit+1).Return([]models.OwnerData{}, fmt.Errorf("db error"))
+
+		req := httptest.NewRequest(http.MethodGet, "/sync", nil)
+		rr := httptest.NewRecorder()
+		NewSyncHandler(mockAccessService, mockRepository, mockLogger).HandleSync(rr, req)
+
+		assert.Equal(t, http.StatusInternalServerError, rr.Code)
+	})
+}
@@ -1,6 +1,8 @@
 package handlers
 
 import (
+	"context"
+	"errors"
 	"net/http"
 
 	"github.com/go-chi/render"
@@ -9,36 +11,25 @@ import (
 	"github.com/northmule/gophkeeper/internal/common/model_data"
 	"github.com/northmule/gophkeeper/internal/common/models"
 	"github.com/northmule/gophkeeper/internal/server/logger"
-	"golang.org/x/net/context"
+	"github.com/northmule/gophkeeper/internal/server/repository"
 )
 
 // TextDataHandler обрабатывает текстовые данные
 type TextDataHandler struct {
-	log             *logger.Logger
-	userFinderByJWT UserFinderByJWT
-	ownerCRUD       OwnerCRUD
-	metaDataCRUD    MetaDataCRUD
-	textDataCRUD    TextDataCRUD
+	log           *logger.Logger
+	accessService UserFinderByJWT
+	manager       repository.Repository
 }
 
 // NewTextDataHandler конструктор
-func NewTextDataHandler(userFinderByJWT UserFinderByJWT, ownerCRUD OwnerCRUD, metaDataCRUD MetaDataCRUD, textDataCRUD TextDataCRUD, log *logger.Logger) *TextDataHandler {
+func NewTextDataHandler(accessService UserFinderByJWT, manager repository.Repository, log *logger.Logger) *TextDataHandler {
 	return &TextDataHandler{
-		userFinderByJWT: userFinderByJWT,
-		metaDataCRUD:    metaDataCRUD,
-		ownerCRUD:       ownerCRUD,
-		textDataCRUD:    textDataCRUD,
-		log:             log,
+		accessService: accessService,
+		manager:       manager,
+		log:           log,
 	}
 }
 
-// TextDataCRUD операции над данными
-type TextDataCRUD interface {
-	FindOneByUUID(ctx context.Context, uuid string) (*models.TextData, error)
-	Add(ctx context.Context, data *models.TextData) (int64, error)
-	Update(ctx context.Context, data *models.TextData) error
-}
-
 type textDataRequest struct {
 	model_data.TextDataRequest
 }
@@ -48,62 +39,83 @@ func (rr *textDataRequest) Bind(r *http.Request) error {
 	return nil
 }
 
-// HandleSave создание/обновление данных
+// HandleSave создание/обновление текстовых данных
 func (h *TextDataHandler) HandleSave(res http.ResponseWriter, req *http.Request) {
-	var (
-		err      error
-		userUUID string
-		owner    *models.Owner
-		textData *models.TextData
-	)
-
 	request := new(textDataRequest)
-	if err = render.Bind(req, request); err != nil {
+	if err := render.Bind(req, request); err != nil {
 		h.log.Info(err)
 		_ = render.Render(res, req, ErrBadRequest)
 		return
 	}
-	userUUID, err = h.userFinderByJWT.GetUserUUIDByJWTToken(req.Context())
+	userUUID, err := h.accessService.GetUserUUIDByJWTToken(req.Context())
 	if err != nil {
 		h.log.Error(err)
 		_ = render.Render(res, req, ErrBadRequest)
 		return
 	}
+	textData, errResponse := h.save(req.Context(), userUUID, &request.TextDataRequest)
+	if errResponse != nil {
+		_ = render.Render(res, req, errResponse)
+		return
+	}
+	auditData(req, textData.UUID)
+	res.Header().Set("ETag", versionETag(textData.Version))
+}
+
+// save создание (без uuid) или изменение текстовых данных пользователя, вернёт ответ с ошибкой для клиента
+func (h *TextDataHandler) save(ctx context.Context, userUUID string, request *model_data.TextDataRequest) (*models.TextData, render.Renderer) {
+	var (
+		err      error
+		owner    *models.Owner
+		textData *models.TextData
+	)
 
 	if request.UUID != "" { // редактирование
 		dataUUID := request.UUID
+		if request.Version == 0 { // без версии изменение перезапишет чужие правки
+			h.log.Infof("version required: data_uuid: %s", dataUUID)
+			return nil, ErrVersionRequired
+		}
 		// владелец данных
-		owner, err = h.ownerCRUD.FindOneByUserUUIDAndDataUUIDAndDataType(req.Context(), userUUID, dataUUID, data_type.TextType)
+		owner, err = h.manager.Owner().FindOneByUserUUIDAndDataUUIDAndDataType(ctx, userUUID, dataUUID, data_type.TextType)
 		if err != nil {
 			h.log.Error(err)
-			_ = render.Render(res, req, ErrBadRequest)
-			return
+			return nil, ErrBadRequest
 		}
 		if owner == nil { // нет данных этого пользователя
 			h.log.Infof("owner not found: data_uuid: %s, user_uuid: %s, data_type: %s", dataUUID, userUUID, data_type.TextType)
-			_ = render.Render(res, req, ErrNotFound)
-			return
+			return nil, ErrNotFound
 		}
-		textData, err = h.textDataCRUD.FindOneByUUID(req.Context(), dataUUID)
+		textData, err = h.manager.TextData().FindOneByUUID(ctx, dataUUID)
 		if err != nil {
 			h.log.Error(err)
-			_ = render.Render(res, req, ErrBadRequest)
-			return
+			return nil, ErrBadRequest
 		}
 		if textData == nil {
 			h.log.Infof("text data not found: uuid %s", owner.DataUUID)
-			_ = render.Render(res, req, ErrNotFound)
-			return
+			return nil, ErrNotFound
+		}
+		if textData.Version != request.Version {
+			h.log.Infof("version conflict: data_uuid: %s, version: %d, current: %d", dataUUID, request.Version, textData.Version)
+			return nil, ErrVersionConflict(textData.Version)
 		}
 		// основные данные
 		textData.Name = request.Name
 		textData.Value = request.Value
 
-		err = h.textDataCRUD.Update(req.Context(), textData)
+		err = h.manager.TextData().Update(ctx, textData)
+		if errors.Is(err, repository.ErrVersionConflict) { // изменены параллельным запросом
+			h.log.Infof("version conflict: data_uuid: %s, version: %d", dataUUID, request.Version)
+			textData, err = h.manager.TextData().FindOneByUUID(ctx, dataUUID)
+			if err != nil {
+				h.log.Error(err)
+				return nil, ErrInternalServerError
+			}
+			return nil, ErrVersionConflict(textData.Version)
+		}
 		if err != nil {
 			h.log.Error(err)
-			_ = render.Render(res, req, ErrInternalServerError)
-			return
+			return nil, ErrInternalServerError
 		}
 
 		// мета поля
@@ -118,11 +130,10 @@ func (h *TextDataHandler) HandleSave(res http.ResponseWriter, req *http.Request)
 			}
 		}
 		// перезапись мета
-		err = h.metaDataCRUD.ReplaceMetaByDataUUID(req.Context(), dataUUID, newMeta)
+		err = h.manager.MetaData().ReplaceMetaByDataUUID(ctx, dataUUID, newMeta)
 		if err != nil {
 			h.log.Error(err)
-			_ = render.Render(res, req, ErrInternalServerError)
-			return
+			return nil, ErrInternalServerError
 		}
 
 	}
@@ -135,12 +146,12 @@ func (h *TextDataHandler) HandleSave(res http.ResponseWriter, req *http.Request)
 		textData.Value = request.Value
 		textData.UUID = dataUUID
 
-		_, err = h.textDataCRUD.Add(req.Context(), textData)
+		_, err = h.manager.TextData().Add(ctx, textData)
 		if err != nil {
 			h.log.Error(err)
-			_ = render.Render(res, req, ErrInternalServerError)
-			return
+			return nil, ErrInternalServerError
 		}
+		textData.Version = 1 // версия новых данных по умолчанию
 
 		// владелец данных
 		owner = new(models.Owner)
@@ -148,11 +159,10 @@ func (h *TextDataHandler) HandleSave(res http.ResponseWriter, req *http.Request)
 		owner.DataType = data_type.TextType
 		owner.DataUUID = dataUUID
 
-		_, err = h.ownerCRUD.Add(req.Context(), owner)
+		_, err = h.manager.Owner().Add(ctx, owner)
 		if err != nil {
 			h.log.Error(err)
-			_ = render.Render(res, req, ErrInternalServerError)
-			return
+			return nil, ErrInternalServerError
 		}
 
 		// мета поля
@@ -162,13 +172,14 @@ func (h *TextDataHandler) HandleSave(res http.ResponseWriter, req *http.Request)
 				metaData.MetaName = key
 				metaData.MetaValue.Value = value
 				metaData.DataUUID = dataUUID
-				_, err = h.metaDataCRUD.Add(req.Context(), metaData)
+				_, err = h.manager.MetaData().Add(ctx, metaData)
 				if err != nil {
 					h.log.Error(err)
-					_ = render.Render(res, req, ErrInternalServerError)
-					return
+					return nil, ErrInternalServerError
 				}
 			}
 		}
 	}
+
+	return textData, nil
 }
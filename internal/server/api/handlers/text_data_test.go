@@ -12,6 +12,7 @@ import (
 	"github.com/northmule/gophkeeper/internal/common/model_data"
 	"github.com/northmule/gophkeeper/internal/common/models"
 	"github.com/northmule/gophkeeper/internal/server/logger"
+	"github.com/northmule/gophkeeper/internal/server/repository"
 	appMock "github.com/northmule/gophkeeper/internal/server/repository/mock"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -79,15 +80,17 @@ func TestHandleSave_UpdateTextData(t *testing.T) {
 
 	textData := new(models.TextData)
 	textData.UUID = "data-uuid"
+	textData.Version = 1
 	mockTextDataRepo.On("FindOneByUUID", mock.Anything, "data-uuid").Return(textData, nil)
 	mockTextDataRepo.On("Update", mock.Anything, mock.Anything).Return(nil)
 
 	handler := NewTextDataHandler(mockAccessService, mockRepository, l)
 
 	reqBody, _ := json.Marshal(model_data.TextDataRequest{
-		UUID:  "data-uuid",
-		Name:  "Updated Test Text",
-		Value: "This is an updated test text",
+		UUID:    "data-uuid",
+		Version: 1,
+		Name:    "Updated Test Text",
+		Value:   "This is an updated test text",
 		Meta: map[string]string{
 			"key1": "updated-value1",
 			"key2": "updated-value2",
@@ -175,9 +178,10 @@ func TestHandleSave_NonExistentDataUUIDForUpdate(t *testing.T) {
 	handler := NewTextDataHandler(mockAccessService, mockRepository, l)
 
 	reqBody, _ := json.Marshal(model_data.TextDataRequest{
-		UUID:  "non-existent-uuid",
-		Name:  "Updated Test Text",
-		Value: "This is an updated test text",
+		UUID:    "non-existent-uuid",
+		Version: 1,
+		Name:    "Updated Test Text",
+		Value:   "This is an updated test text",
 		Meta: map[string]string{
 			"key1": "updated-value1",
 			"key2": "updated-value2",
@@ -218,13 +222,15 @@ func TestHandleSave_RepositoryErrorOnReplaceMeta(t *testing.T) {
 	mockTextDataRepo.On("Update", mock.Anything, mock.Anything).Return(nil)
 	textData := new(models.TextData)
 	textData.UUID = "data-uuid"
+	textData.Version = 1
 	mockTextDataRepo.On("FindOneByUUID", mock.Anything, mock.Anything).Return(textData, nil)
 	handler := NewTextDataHandler(mockAccessService, mockRepository, l)
 
 	reqBody, _ := json.Marshal(model_data.TextDataRequest{
-		UUID:  "uuid",
-		Name:  "Test Text",
-		Value: "This is a test text",
+		UUID:    "uuid",
+		Version: 1,
+		Name:    "Test Text",
+		Value:   "This is a test text",
 	})
 
 	req, _ := http.NewRequest("POST", "/textdata", bytes.NewBuffer(reqBody))
@@ -238,3 +244,76 @@ func TestHandleSave_RepositoryErrorOnReplaceMeta(t *testing.T) {
 	mockAccessService.AssertExpectations(t)
 	mockMetaDataRepo.AssertExpectations(t)
 }
+
+func TestHandleSave_TextDataVersion(t *testing.T) {
+	l, _ := logger.NewLogger("info")
+	save := func(mockRepository *appMock.MockManager, request model_data.TextDataRequest) *httptest.ResponseRecorder {
+		mockAccessService := new(appMock.MockAccessService)
+		mockAccessService.On("GetUserUUIDByJWTToken", mock.Anything).Return("user-uuid", nil)
+		reqBody, _ := json.Marshal(request)
+		req, _ := http.NewRequest("POST", "/textdata", bytes.NewBuffer(reqBody))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		NewTextDataHandler(mockAccessService, mockRepository, l).HandleSave(rr, req)
+		return rr
+	}
+	newRepository := func(textDataRepo *appMock.MockTextDataModelRepository) *appMock.MockManager {
+		mockOwnerRepo := new(appMock.MockOwnerDataModelRepository)
+		mockMetaDataRepo := new(appMock.MockMetaDataModelRepository)
+		mockRepository := new(appMock.MockManager)
+		mockRepository.On("Owner").Return(mockOwnerRepo)
+		mockRepository.On("TextData").Return(textDataRepo)
+		mockRepository.On("MetaData").Return(mockMetaDataRepo)
+		mockOwnerRepo.On("FindOneByUserUUIDAndDataUUIDAndDataType", mock.Anything, "user-uuid", "data-uuid", data_type.TextType).Return(&models.Owner{DataUUID: "data-uuid"}, nil)
+		mockMetaDataRepo.On("ReplaceMetaByDataUUID", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		return mockRepository
+	}
+
+	t.Run("version_required", func(t *testing.T) {
+		rr := save(new(appMock.MockManager), model_data.TextDataRequest{UUID: "data-uuid", Name: "note"})
+		assert.Equal(t, http.StatusPreconditionRequired, rr.Code)
+		assert.Contains(t, rr.Body.String(), fmt.Sprintf(`"code":%d`, data_type.AppCodeVersionRequired))
+	})
+
+	t.Run("stale_version", func(t *testing.T) {
+		mockTextDataRepo := new(appMock.MockTextDataModelRepository)
+		current := &models.TextData{Name: "note", Version: 4}
+		current.UUID = "data-uuid"
+		mockTextDataRepo.On("FindOneByUUID", mock.Anything, "data-uuid").Return(current, nil)
+
+		rr := save(newRepository(mockTextDataRepo), model_data.TextDataRequest{UUID: "data-uuid", Version: 3, Name: "note"})
+		assert.Equal(t, http.StatusConflict, rr.Code)
+		assert.Contains(t, rr.Body.String(), `"version":4`)
+		assert.Contains(t, rr.Body.String(), fmt.Sprintf(`"code":%d`, data_type.AppCodeVersionConflict))
+		mockTextDataRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+	})
+
+	t.Run("changed_concurrently", func(t *testing.T) {
+		mockTextDataRepo := new(appMock.MockTextDataModelRepository)
+		read := &models.TextData{Name: "note", Version: 3}
+		read.UUID = "data-uuid"
+		current := &models.TextData{Name: "note", Version: 5}
+		current.UUID = "data-uuid"
+		mockTextDataRepo.On("FindOneByUUID", mock.Anything, "data-uuid").Return(read, nil).Once()
+		mockTextDataRepo.On("Update", mock.Anything, mock.Anything).Return(repository.ErrVersionConflict)
+		mockTextDataRepo.On("FindOneByUUID", mock.Anything, "data-uuid").Return(current, nil)
+
+		rr := save(newRepository(mockTextDataRepo), model_data.TextDataRequest{UUID: "data-uuid", Version: 3, Name: "note"})
+		assert.Equal(t, http.StatusConflict, rr.Code)
+		assert.Contains(t, rr.Body.String(), `"version":5`)
+	})
+
+	t.Run("saved", func(t *testing.T) {
+		mockTextDataRepo := new(appMock.MockTextDataModelRepository)
+		read := &models.TextData{Name: "note", Version: 3}
+		read.UUID = "data-uuid"
+		mockTextDataRepo.On("FindOneByUUID", mock.Anything, "data-uuid").Return(read, nil)
+		mockTextDataRepo.On("Update", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+			args.Get(1).(*models.TextData).Version++
+		}).Return(nil)
+
+		rr := save(newRepository(mockTextDataRepo), model_data.TextDataRequest{UUID: "data-uuid", Version: 3, Name: "note 2"})
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, `"4"`, rr.Header().Get("ETag"))
+	})
+}
@@ -0,0 +1,394 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/render"
+	"github.com/northmule/gophkeeper/internal/common/model_data"
+	"github.com/northmule/gophkeeper/internal/common/models"
+	"github.com/northmule/gophkeeper/internal/common/util"
+	"github.com/northmule/gophkeeper/internal/server/config"
+	"github.com/northmule/gophkeeper/internal/server/logger"
+	"github.com/northmule/gophkeeper/internal/server/repository"
+)
+
+// Второй фактор (TOTP, RFC 6238). Подключение в два шага: enroll выдаёт секрет, confirm включает
+// второй фактор после первого верного кода и выдаёт резервные коды. При подключённом втором факторе
+// /login вместо токенов возвращает mfa_token, токены выдаёт /login/totp после проверки кода.
+// Код каждого шага принимается один раз, резервный код погашается при использовании.
+// Незавершённые входы хранятся в БД (хэш mfa_token), второй шаг можно выполнить на любом экземпляре сервера.
+
+// loginChallengeTTL время на ввод кода второго фактора
+const loginChallengeTTL = 5 * time.Minute
+
+// loginChallengeMaxAttempts число попыток ввода кода второго фактора на один вход
+const loginChallengeMaxAttempts = 5
+
+// backupCodesCount число резервных кодов
+const backupCodesCount = 10
+
+// TOTPHandler второй фактор аутентификации
+type TOTPHandler struct {
+	log           *logger.Logger
+	accessService UserFinderByJWT
+	manager       repository.Repository
+	session       SessionOpener
+	cfg           *config.Config
+}
+
+// SecondFactor второй фактор при входе
+type SecondFactor interface {
+	// Challenge начало второго шага входа, nil если у пользователя второй фактор не подключён
+	Challenge(ctx context.Context, userUUID string) (*model_data.LoginChallengeResponse, error)
+}
+
+// NewTOTPHandler конструктор
+func NewTOTPHandler(accessService UserFinderByJWT, manager repository.Repository, session SessionOpener, cfg *config.Config, log *logger.Logger) *TOTPHandler {
+	return &TOTPHandler{
+		accessService: accessService,
+		manager:       manager,
+		session:       session,
+		cfg:           cfg,
+		log:           log,
+	}
+}
+
+type totpCodeRequest struct {
+	model_data.TOTPCodeRequest
+}
+
+// Bind декодирует json в структуру
+func (rr *totpCodeRequest) Bind(r *http.Request) error {
+	return nil
+}
+
+type totpLoginRequest struct {
+	model_data.TOTPLoginRequest
+}
+
+// Bind декодирует json в структуру
+func (rr *totpLoginRequest) Bind(r *http.Request) error {
+	return nil
+}
+
+type totpEnrollResponse struct {
+	model_data.TOTPEnrollResponse
+}
+
+func (tr totpEnrollResponse) Render(res http.ResponseWriter, req *http.Request) error {
+	return nil
+}
+
+type totpBackupCodesResponse struct {
+	model_data.TOTPBackupCodesResponse
+}
+
+func (tr totpBackupCodesResponse) Render(res http.ResponseWriter, req *http.Request) error {
+	return nil
+}
+
+type loginChallengeResponse struct {
+	model_data.LoginChallengeResponse
+}
+
+func (lr loginChallengeResponse) Render(res http.ResponseWriter, req *http.Request) error {
+	render.Status(req, http.StatusAccepted)
+	return nil
+}
+
+// Challenge начало второго шага входа
+func (h *TOTPHandler) Challenge(ctx context.Context, userUUID string) (*model_data.LoginChallengeResponse, error) {
+	totp, err := h.manager.TOTP().FindOneByUserUUID(ctx, userUUID)
+	if err != nil {
+		return nil, err
+	}
+	if totp == nil || !totp.Enabled {
+		return nil, nil
+	}
+	token, err := util.NewRandomToken()
+	if err != nil {
+		return nil, err
+	}
+	err = h.manager.LoginChallenge().Add(ctx, util.TokenHash(token), userUUID, time.Now().Add(loginChallengeTTL))
+	if err != nil {
+		return nil, err
+	}
+	return &model_data.LoginChallengeResponse{
+		MFARequired: true,
+		MFAToken:    token,
+		ExpiresIn:   int64(loginChallengeTTL.Seconds()),
+	}, nil
+}
+
+// HandleLogin второй шаг входа: проверка кода и выдача токенов
+func (h *TOTPHandler) HandleLogin(res http.ResponseWriter, req *http.Request) {
+	var err error
+	request := new(totpLoginRequest)
+	if err = render.Bind(req, request); err != nil {
+		h.log.Info(err)
+		_ = render.Render(res, req, ErrBadRequest)
+		return
+	}
+
+	tokenHash := util.TokenHash(request.MFAToken)
+	// попытка списывается до проверки кода: параллельные запросы не получают больше loginChallengeMaxAttempts проверок
+	userUUID, err := h.manager.LoginChallenge().Attempt(req.Context(), tokenHash, loginChallengeMaxAttempts)
+	if err != nil {
+		h.log.Error(err)
+		_ = render.Render(res, req, ErrInternalServerError)
+		return
+	}
+	if userUUID == "" {
+		h.log.Info("login challenge not found, expired or out of attempts")
+		_ = render.Render(res, req, ErrUnauthorized)
+		return
+	}
+	totp, err := h.manager.TOTP().FindOneByUserUUID(req.Context(), userUUID)
+	if err != nil {
+		h.log.Error(err)
+		_ = render.Render(res, req, ErrInternalServerError)
+		return
+	}
+	if totp == nil || !totp.Enabled {
+		h.log.Infof("second factor of user %s is disabled during login", userUUID)
+		err = h.manager.LoginChallenge().Remove(req.Context(), tokenHash)
+		if err != nil {
+			h.log.Error(err)
+		}
+		_ = render.Render(res, req, ErrUnauthorized)
+		return
+	}
+	verified, err := h.verify(req.Context(), totp, request.Code, true)
+	if err != nil {
+		h.log.Error(err)
+		_ = render.Render(res, req, ErrInternalServerError)
+		return
+	}
+	if !verified {
+		h.log.Infof("Invalid second factor code for user %s", userUUID)
+		_ = render.Render(res, req, ErrUnauthorized)
+		return
+	}
+	err = h.manager.LoginChallenge().Remove(req.Context(), tokenHash)
+	if err != nil {
+		h.log.Error(err)
+		_ = render.Render(res, req, ErrInternalServerError)
+		return
+	}
+
+	tokens, err := h.session.Open(req.Context(), userUUID, requestDevice(req))
+	if err != nil {
+		h.log.Error(err)
+		_ = render.Render(res, req, ErrInternalServerError)
+		return
+	}
+	auditUser(req, userUUID)
+	res.Header().Set("Authorization", "Bearer "+tokens.AccessToken)
+
+	h.log.Infof("User %s has been authenticated with the second factor, a new session has been opened", userUUID)
+	err = render.Render(res, req, tokenResponse{TokenResponse: *tokens})
+	if err != nil {
+		h.log.Error(err)
+		_ = render.Render(res, req, ErrInternalServerError)
+	}
+}
+
+// HandleEnroll выдача нового секрета. Второй фактор включается после подтверждения кодом
+func (h *TOTPHandler) HandleEnroll(res http.ResponseWriter, req *http.Request) {
+	userUUID, err := h.accessService.GetUserUUIDByJWTToken(req.Context())
+	if err != nil {
+		h.log.Error(err)
+		_ = render.Render(res, req, ErrBadRequest)
+		return
+	}
+	totp, err := h.manager.TOTP().FindOneByUserUUID(req.Context(), userUUID)
+	if err != nil {
+		h.log.Error(err)
+		_ = render.Render(res, req, ErrInternalServerError)
+		return
+	}
+	if totp != nil && totp.Enabled {
+		h.log.Infof("The second factor of user %s is already enabled", userUUID)
+		_ = render.Render(res, req, ErrConflict(errors.New("the second factor is already enabled, disable it first")))
+		return
+	}
+	user, err := h.manager.User().FindOneByUUID(req.Context(), userUUID)
+	if err != nil {
+		h.log.Error(err)
+		_ = render.Render(res, req, ErrInternalServerError)
+		return
+	}
+
+	secret, err := util.NewTOTPSecret()
+	if err != nil {
+		h.log.Error(err)
+		_ = render.Render(res, req, ErrInternalServerError)
+		return
+	}
+	err = h.manager.TOTP().Save(req.Context(), userUUID, secret)
+	if err != nil {
+		h.log.Error(err)
+		_ = render.Render(res, req, ErrInternalServerError)
+		return
+	}
+
+	response := new(totpEnrollResponse)
+	response.Secret = secret
+	response.ProvisioningURI = util.TOTPProvisioningURI(h.cfg.Value().TOTPIssuer, user.Login, secret)
+	err = render.Render(res, req, response)
+	if err != nil {
+		h.log.Error(err)
+		_ = render.Render(res, req, ErrInternalServerError)
+	}
+}
+
+// HandleConfirm включение второго фактора первым верным кодом, выдача резервных кодов
+func (h *TOTPHandler) HandleConfirm(res http.ResponseWriter, req *http.Request) {
+	userUUID, totp, request, ok := h.prepare(res, req)
+	if !ok {
+		return
+	}
+	if totp.Enabled {
+		_ = render.Render(res, req, ErrConflict(errors.New("the second factor is already enabled")))
+		return
+	}
+	verified, err := h.verify(req.Context(), totp, request.Code, false)
+	if err != nil {
+		h.log.Error(err)
+		_ = render.Render(res, req, ErrInternalServerError)
+		return
+	}
+	if !verified {
+		h.log.Infof("Invalid second factor code for user %s", userUUID)
+		_ = render.Render(res, req, ErrUnauthorized)
+		return
+	}
+	err = h.manager.TOTP().Enable(req.Context(), userUUID)
+	if err != nil {
+		h.log.Error(err)
+		_ = render.Render(res, req, ErrInternalServerError)
+		return
+	}
+	h.log.Infof("The second factor of user %s has been enabled", userUUID)
+	h.renderBackupCodes(res, req, userUUID)
+}
+
+// HandleBackupCodes выдача новых резервных кодов взамен прежних
+func (h *TOTPHandler) HandleBackupCodes(res http.ResponseWriter, req *http.Request) {
+	userUUID, totp, request, ok := h.prepare(res, req)
+	if !ok {
+		return
+	}
+	if !totp.Enabled {
+		_ = render.Render(res, req, ErrBadRequest)
+		return
+	}
+	verified, err := h.verify(req.Context(), totp, request.Code, false)
+	if err != nil {
+		h.log.Error(err)
+		_ = render.Render(res, req, ErrInternalServerError)
+		return
+	}
+	if !verified {
+		h.log.Infof("Invalid second factor code for user %s", userUUID)
+		_ = render.Render(res, req, ErrUnauthorized)
+		return
+	}
+	h.renderBackupCodes(res, req, userUUID)
+}
+
+// HandleDisable отключение второго фактора кодом или резервным кодом
+func (h *TOTPHandler) HandleDisable(res http.ResponseWriter, req *http.Request) {
+	userUUID, totp, request, ok := h.prepare(res, req)
+	if !ok {
+		return
+	}
+	verified, err := h.verify(req.Context(), totp, request.Code, totp.Enabled)
+	if err != nil {
+		h.log.Error(err)
+		_ = render.Render(res, req, ErrInternalServerError)
+		return
+	}
+	if !verified {
+		h.log.Infof("Invalid second factor code for user %s", userUUID)
+		_ = render.Render(res, req, ErrUnauthorized)
+		return
+	}
+	err = h.manager.TOTP().Delete(req.Context(), userUUID)
+	if err != nil {
+		h.log.Error(err)
+		_ = render.Render(res, req, ErrInternalServerError)
+		return
+	}
+	h.log.Infof("The second factor of user %s has been disabled", userUUID)
+	res.WriteHeader(http.StatusOK)
+}
+
+// prepare общая часть запросов с кодом от авторизованного пользователя
+func (h *TOTPHandler) prepare(res http.ResponseWriter, req *http.Request) (string, *models.UserTOTP, *totpCodeRequest, bool) {
+	request := new(totpCodeRequest)
+	if err := render.Bind(req, request); err != nil {
+		h.log.Info(err)
+		_ = render.Render(res, req, ErrBadRequest)
+		return "", nil, nil, false
+	}
+	userUUID, err := h.accessService.GetUserUUIDByJWTToken(req.Context())
+	if err != nil {
+		h.log.Error(err)
+		_ = render.Render(res, req, ErrBadRequest)
+		return "", nil, nil, false
+	}
+	totp, err := h.manager.TOTP().FindOneByUserUUID(req.Context(), userUUID)
+	if err != nil {
+		h.log.Error(err)
+		_ = render.Render(res, req, ErrInternalServerError)
+		return "", nil, nil, false
+	}
+	if totp == nil {
+		h.log.Infof("The second factor of user %s is not enrolled", userUUID)
+		_ = render.Render(res, req, ErrNotFound)
+		return "", nil, nil, false
+	}
+	return userUUID, totp, request, true
+}
+
+// verify проверка кода из приложения, при allowBackup - и резервного кода
+func (h *TOTPHandler) verify(ctx context.Context, totp *models.UserTOTP, code string, allowBackup bool) (bool, error) {
+	if step, ok := util.TOTPVerify(totp.Secret, code, time.Now()); ok {
+		// код уже принятого шага повторно не принимается
+		return h.manager.TOTP().UseStep(ctx, totp.UserUUID, step)
+	}
+	if !allowBackup {
+		return false, nil
+	}
+	return h.manager.TOTP().UseBackupCode(ctx, totp.UserUUID, util.TokenHash(util.NormalizeBackupCode(code)))
+}
+
+func (h *TOTPHandler) renderBackupCodes(res http.ResponseWriter, req *http.Request, userUUID string) {
+	codes, err := util.NewBackupCodes(backupCodesCount)
+	if err != nil {
+		h.log.Error(err)
+		_ = render.Render(res, req, ErrInternalServerError)
+		return
+	}
+	hashes := make([]string, 0, len(codes))
+	for _, code := range codes {
+		hashes = append(hashes, util.TokenHash(code))
+	}
+	err = h.manager.TOTP().ReplaceBackupCodes(req.Context(), userUUID, hashes)
+	if err != nil {
+		h.log.Error(err)
+		_ = render.Render(res, req, ErrInternalServerError)
+		return
+	}
+	response := new(totpBackupCodesResponse)
+	response.BackupCodes = codes
+	err = render.Render(res, req, response)
+	if err != nil {
+		h.log.Error(err)
+		_ = render.Render(res, req, ErrInternalServerError)
+	}
+}
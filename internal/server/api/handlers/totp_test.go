@@ -0,0 +1,305 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/northmule/gophkeeper/internal/common/model_data"
+	"github.com/northmule/gophkeeper/internal/common/models"
+	"github.com/northmule/gophkeeper/internal/common/util"
+	"github.com/northmule/gophkeeper/internal/server/config"
+	"github.com/northmule/gophkeeper/internal/server/logger"
+	appMock "github.com/northmule/gophkeeper/internal/server/repository/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+const testTOTPSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func newTOTPTestConfig() *config.Config {
+	cfg := config.NewConfig()
+	cfg.Value().TOTPIssuer = "GophKeeper"
+	return cfg
+}
+
+func currentTOTPCode(t *testing.T) (string, int64) {
+	step := util.TOTPStep(time.Now())
+	code, err := util.TOTPCode(testTOTPSecret, step)
+	require.NoError(t, err)
+	return code, step
+}
+
+func newTOTPRequest(url string, body any) *http.Request {
+	reqBody, _ := json.Marshal(body)
+	req := httptest.NewRequest(http.MethodPost, url, bytes.NewBuffer(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+	return req
+}
+
+func TestTOTPHandler_Challenge(t *testing.T) {
+	l, _ := logger.NewLogger("info")
+	mockRepository := new(appMock.MockManager)
+	mockTOTPRepository := new(appMock.MockTOTPModelRepository)
+	mockChallengeRepository := new(appMock.MockLoginChallengeModelRepository)
+	mockRepository.On("TOTP").Return(mockTOTPRepository)
+	mockRepository.On("LoginChallenge").Return(mockChallengeRepository)
+	mockChallengeRepository.On("Add", mock.Anything, mock.Anything, "user-uuid", mock.Anything).Return(nil)
+	handler := NewTOTPHandler(new(appMock.MockAccessService), mockRepository, new(appMock.MockSessionOpener), newTOTPTestConfig(), l)
+
+	mockTOTPRepository.On("FindOneByUserUUID", mock.Anything, "without-totp").Return(nil, nil)
+	mockTOTPRepository.On("FindOneByUserUUID", mock.Anything, "not-confirmed").Return(&models.UserTOTP{UserUUID: "not-confirmed", Secret: testTOTPSecret}, nil)
+	mockTOTPRepository.On("FindOneByUserUUID", mock.Anything, "user-uuid").Return(&models.UserTOTP{UserUUID: "user-uuid", Secret: testTOTPSecret, Enabled: true}, nil)
+
+	response, err := handler.Challenge(context.Background(), "without-totp")
+	require.NoError(t, err)
+	assert.Nil(t, response)
+
+	response, err = handler.Challenge(context.Background(), "not-confirmed")
+	require.NoError(t, err)
+	assert.Nil(t, response)
+
+	response, err = handler.Challenge(context.Background(), "user-uuid")
+	require.NoError(t, err)
+	require.NotNil(t, response)
+	assert.True(t, response.MFARequired)
+	assert.Equal(t, int64(300), response.ExpiresIn)
+	// в БД сохраняется только хэш токена
+	mockChallengeRepository.AssertCalled(t, "Add", mock.Anything, util.TokenHash(response.MFAToken), "user-uuid", mock.Anything)
+	mockChallengeRepository.AssertNumberOfCalls(t, "Add", 1)
+}
+
+// newChallengeRepository незавершённый вход mfa-token пользователя user-uuid
+func newChallengeRepository(mockRepository *appMock.MockManager) *appMock.MockLoginChallengeModelRepository {
+	mockChallengeRepository := new(appMock.MockLoginChallengeModelRepository)
+	mockRepository.On("LoginChallenge").Return(mockChallengeRepository)
+	mockChallengeRepository.On("Attempt", mock.Anything, util.TokenHash("mfa-token"), loginChallengeMaxAttempts).Return("user-uuid", nil)
+	return mockChallengeRepository
+}
+
+func TestTOTPHandler_HandleLogin(t *testing.T) {
+	l, _ := logger.NewLogger("info")
+	enabled := &models.UserTOTP{UserUUID: "user-uuid", Secret: testTOTPSecret, Enabled: true}
+
+	t.Run("valid code", func(t *testing.T) {
+		mockRepository := new(appMock.MockManager)
+		mockTOTPRepository := new(appMock.MockTOTPModelRepository)
+		mockSessionOpener := new(appMock.MockSessionOpener)
+		mockRepository.On("TOTP").Return(mockTOTPRepository)
+		mockChallengeRepository := newChallengeRepository(mockRepository)
+		mockChallengeRepository.On("Remove", mock.Anything, util.TokenHash("mfa-token")).Return(nil)
+		code, step := currentTOTPCode(t)
+		mockTOTPRepository.On("FindOneByUserUUID", mock.Anything, "user-uuid").Return(enabled, nil)
+		mockTOTPRepository.On("UseStep", mock.Anything, "user-uuid", step).Return(true, nil)
+		mockSessionOpener.On("Open", mock.Anything, "user-uuid", mock.Anything).Return(&model_data.TokenResponse{AccessToken: "access-token", RefreshToken: "refresh-token", ExpiresIn: 900}, nil)
+
+		handler := NewTOTPHandler(new(appMock.MockAccessService), mockRepository, mockSessionOpener, newTOTPTestConfig(), l)
+		res := httptest.NewRecorder()
+		handler.HandleLogin(res, newTOTPRequest("/login/totp", model_data.TOTPLoginRequest{MFAToken: "mfa-token", Code: code}))
+
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.Equal(t, "Bearer access-token", res.Header().Get("Authorization"))
+		mockChallengeRepository.AssertCalled(t, "Remove", mock.Anything, util.TokenHash("mfa-token"))
+	})
+
+	t.Run("replayed code", func(t *testing.T) {
+		mockRepository := new(appMock.MockManager)
+		mockTOTPRepository := new(appMock.MockTOTPModelRepository)
+		mockSessionOpener := new(appMock.MockSessionOpener)
+		mockRepository.On("TOTP").Return(mockTOTPRepository)
+		mockChallengeRepository := newChallengeRepository(mockRepository)
+		code, step := currentTOTPCode(t)
+		mockTOTPRepository.On("FindOneByUserUUID", mock.Anything, "user-uuid").Return(enabled, nil)
+		mockTOTPRepository.On("UseStep", mock.Anything, "user-uuid", step).Return(false, nil)
+
+		handler := NewTOTPHandler(new(appMock.MockAccessService), mockRepository, mockSessionOpener, newTOTPTestConfig(), l)
+		res := httptest.NewRecorder()
+		handler.HandleLogin(res, newTOTPRequest("/login/totp", model_data.TOTPLoginRequest{MFAToken: "mfa-token", Code: code}))
+
+		assert.Equal(t, http.StatusUnauthorized, res.Code)
+		mockSessionOpener.AssertNotCalled(t, "Open", mock.Anything, mock.Anything, mock.Anything)
+		mockChallengeRepository.AssertCalled(t, "Attempt", mock.Anything, util.TokenHash("mfa-token"), loginChallengeMaxAttempts)
+		mockChallengeRepository.AssertNotCalled(t, "Remove", mock.Anything, mock.Anything)
+	})
+
+	t.Run("backup code", func(t *testing.T) {
+		mockRepository := new(appMock.MockManager)
+		mockTOTPRepository := new(appMock.MockTOTPModelRepository)
+		mockSessionOpener := new(appMock.MockSessionOpener)
+		mockRepository.On("TOTP").Return(mockTOTPRepository)
+		mockChallengeRepository := newChallengeRepository(mockRepository)
+		mockChallengeRepository.On("Remove", mock.Anything, util.TokenHash("mfa-token")).Return(nil)
+		mockTOTPRepository.On("FindOneByUserUUID", mock.Anything, "user-uuid").Return(enabled, nil)
+		mockTOTPRepository.On("UseBackupCode", mock.Anything, "user-uuid", util.TokenHash("abcde-fghjk")).Return(true, nil)
+		mockSessionOpener.On("Open", mock.Anything, "user-uuid", mock.Anything).Return(&model_data.TokenResponse{AccessToken: "access-token"}, nil)
+
+		handler := NewTOTPHandler(new(appMock.MockAccessService), mockRepository, mockSessionOpener, newTOTPTestConfig(), l)
+		res := httptest.NewRecorder()
+		handler.HandleLogin(res, newTOTPRequest("/login/totp", model_data.TOTPLoginRequest{MFAToken: "mfa-token", Code: "ABCDEFGHJK"}))
+
+		assert.Equal(t, http.StatusOK, res.Code)
+	})
+
+	t.Run("unknown challenge", func(t *testing.T) {
+		mockRepository := new(appMock.MockManager)
+		mockChallengeRepository := new(appMock.MockLoginChallengeModelRepository)
+		mockRepository.On("LoginChallenge").Return(mockChallengeRepository)
+		mockChallengeRepository.On("Attempt", mock.Anything, util.TokenHash("mfa-token"), loginChallengeMaxAttempts).Return("", nil)
+		handler := NewTOTPHandler(new(appMock.MockAccessService), mockRepository, new(appMock.MockSessionOpener), newTOTPTestConfig(), l)
+		res := httptest.NewRecorder()
+		handler.HandleLogin(res, newTOTPRequest("/login/totp", model_data.TOTPLoginRequest{MFAToken: "mfa-token", Code: "123456"}))
+
+		assert.Equal(t, http.StatusUnauthorized, res.Code)
+	})
+
+	t.Run("attempts exhausted", func(t *testing.T) {
+		// код не проверяется, если попытки входа исчерпаны
+		mockRepository := new(appMock.MockManager)
+		mockTOTPRepository := new(appMock.MockTOTPModelRepository)
+		mockChallengeRepository := new(appMock.MockLoginChallengeModelRepository)
+		mockRepository.On("TOTP").Return(mockTOTPRepository)
+		mockRepository.On("LoginChallenge").Return(mockChallengeRepository)
+		mockChallengeRepository.On("Attempt", mock.Anything, util.TokenHash("mfa-token"), loginChallengeMaxAttempts).Return("", nil)
+		code, _ := currentTOTPCode(t)
+		handler := NewTOTPHandler(new(appMock.MockAccessService), mockRepository, new(appMock.MockSessionOpener), newTOTPTestConfig(), l)
+		res := httptest.NewRecorder()
+		handler.HandleLogin(res, newTOTPRequest("/login/totp", model_data.TOTPLoginRequest{MFAToken: "mfa-token", Code: code}))
+
+		assert.Equal(t, http.StatusUnauthorized, res.Code)
+		mockTOTPRepository.AssertNotCalled(t, "FindOneByUserUUID", mock.Anything, mock.Anything)
+		mockTOTPRepository.AssertNotCalled(t, "UseStep", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestTOTPHandler_HandleEnroll(t *testing.T) {
+	l, _ := logger.NewLogger("info")
+
+	t.Run("ok", func(t *testing.T) {
+		mockAccessService := new(appMock.MockAccessService)
+		mockRepository := new(appMock.MockManager)
+		mockTOTPRepository := new(appMock.MockTOTPModelRepository)
+		mockUserRepository := new(appMock.MockUserDataModelRepository)
+		mockRepository.On("TOTP").Return(mockTOTPRepository)
+		mockRepository.On("User").Return(mockUserRepository)
+		mockAccessService.On("GetUserUUIDByJWTToken", mock.Anything).Return("user-uuid", nil)
+		mockTOTPRepository.On("FindOneByUserUUID", mock.Anything, "user-uuid").Return(nil, nil)
+		mockUserRepository.On("FindOneByUUID", mock.Anything, "user-uuid").Return(&models.User{Login: "login"}, nil)
+		mockTOTPRepository.On("Save", mock.Anything, "user-uuid", mock.Anything).Return(nil)
+
+		handler := NewTOTPHandler(mockAccessService, mockRepository, new(appMock.MockSessionOpener), newTOTPTestConfig(), l)
+		res := httptest.NewRecorder()
+		handler.HandleEnroll(res, httptest.NewRequest(http.MethodPost, "/totp/enroll", nil))
+
+		require.Equal(t, http.StatusOK, res.Code)
+		response := new(model_data.TOTPEnrollResponse)
+		require.NoError(t, json.Unmarshal(res.Body.Bytes(), response))
+		assert.NotEmpty(t, response.Secret)
+		assert.True(t, strings.HasPrefix(response.ProvisioningURI, "otpauth://totp/GophKeeper:login?"))
+		mockTOTPRepository.AssertCalled(t, "Save", mock.Anything, "user-uuid", response.Secret)
+	})
+
+	t.Run("already enabled", func(t *testing.T) {
+		mockAccessService := new(appMock.MockAccessService)
+		mockRepository := new(appMock.MockManager)
+		mockTOTPRepository := new(appMock.MockTOTPModelRepository)
+		mockRepository.On("TOTP").Return(mockTOTPRepository)
+		mockAccessService.On("GetUserUUIDByJWTToken", mock.Anything).Return("user-uuid", nil)
+		mockTOTPRepository.On("FindOneByUserUUID", mock.Anything, "user-uuid").Return(&models.UserTOTP{Enabled: true}, nil)
+
+		handler := NewTOTPHandler(mockAccessService, mockRepository, new(appMock.MockSessionOpener), newTOTPTestConfig(), l)
+		res := httptest.NewRecorder()
+		handler.HandleEnroll(res, httptest.NewRequest(http.MethodPost, "/totp/enroll", nil))
+
+		assert.Equal(t, http.StatusConflict, res.Code)
+	})
+}
+
+func TestTOTPHandler_HandleConfirm(t *testing.T) {
+	l, _ := logger.NewLogger("info")
+	mockAccessService := new(appMock.MockAccessService)
+	mockRepository := new(appMock.MockManager)
+	mockTOTPRepository := new(appMock.MockTOTPModelRepository)
+	mockRepository.On("TOTP").Return(mockTOTPRepository)
+	code, step := currentTOTPCode(t)
+	mockAccessService.On("GetUserUUIDByJWTToken", mock.Anything).Return("user-uuid", nil)
+	mockTOTPRepository.On("FindOneByUserUUID", mock.Anything, "user-uuid").Return(&models.UserTOTP{UserUUID: "user-uuid", Secret: testTOTPSecret}, nil)
+	mockTOTPRepository.On("UseStep", mock.Anything, "user-uuid", step).Return(true, nil)
+	mockTOTPRepository.On("Enable", mock.Anything, "user-uuid").Return(nil)
+	var hashes []string
+	mockTOTPRepository.On("ReplaceBackupCodes", mock.Anything, "user-uuid", mock.Anything).Run(func(args mock.Arguments) {
+		hashes = args.Get(2).([]string)
+	}).Return(nil)
+
+	handler := NewTOTPHandler(mockAccessService, mockRepository, new(appMock.MockSessionOpener), newTOTPTestConfig(), l)
+	res := httptest.NewRecorder()
+	handler.HandleConfirm(res, newTOTPRequest("/totp/confirm", model_data.TOTPCodeRequest{Code: code}))
+
+	require.Equal(t, http.StatusOK, res.Code)
+	response := new(model_data.TOTPBackupCodesResponse)
+	require.NoError(t, json.Unmarshal(res.Body.Bytes(), response))
+	require.Len(t, response.BackupCodes, backupCodesCount)
+	require.Len(t, hashes, backupCodesCount)
+	// в БД попадают только хэши кодов
+	assert.Equal(t, util.TokenHash(response.BackupCodes[0]), hashes[0])
+	mockTOTPRepository.AssertCalled(t, "Enable", mock.Anything, "user-uuid")
+}
+
+func TestTOTPHandler_HandleDisable(t *testing.T) {
+	l, _ := logger.NewLogger("info")
+
+	t.Run("wrong code", func(t *testing.T) {
+		mockAccessService := new(appMock.MockAccessService)
+		mockRepository := new(appMock.MockManager)
+		mockTOTPRepository := new(appMock.MockTOTPModelRepository)
+		mockRepository.On("TOTP").Return(mockTOTPRepository)
+		mockAccessService.On("GetUserUUIDByJWTToken", mock.Anything).Return("user-uuid", nil)
+		mockTOTPRepository.On("FindOneByUserUUID", mock.Anything, "user-uuid").Return(&models.UserTOTP{UserUUID: "user-uuid", Secret: testTOTPSecret, Enabled: true}, nil)
+		mockTOTPRepository.On("UseBackupCode", mock.Anything, "user-uuid", mock.Anything).Return(false, nil)
+
+		handler := NewTOTPHandler(mockAccessService, mockRepository, new(appMock.MockSessionOpener), newTOTPTestConfig(), l)
+		res := httptest.NewRecorder()
+		handler.HandleDisable(res, newTOTPRequest("/totp/disable", model_data.TOTPCodeRequest{Code: "wrong-code"}))
+
+		assert.Equal(t, http.StatusUnauthorized, res.Code)
+		mockTOTPRepository.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything)
+	})
+
+	t.Run("not enrolled", func(t *testing.T) {
+		mockAccessService := new(appMock.MockAccessService)
+		mockRepository := new(appMock.MockManager)
+		mockTOTPRepository := new(appMock.MockTOTPModelRepository)
+		mockRepository.On("TOTP").Return(mockTOTPRepository)
+		mockAccessService.On("GetUserUUIDByJWTToken", mock.Anything).Return("user-uuid", nil)
+		mockTOTPRepository.On("FindOneByUserUUID", mock.Anything, "user-uuid").Return(nil, nil)
+
+		handler := NewTOTPHandler(mockAccessService, mockRepository, new(appMock.MockSessionOpener), newTOTPTestConfig(), l)
+		res := httptest.NewRecorder()
+		handler.HandleDisable(res, newTOTPRequest("/totp/disable", model_data.TOTPCodeRequest{Code: "123456"}))
+
+		assert.Equal(t, http.StatusNotFound, res.Code)
+	})
+
+	t.Run("ok", func(t *testing.T) {
+		mockAccessService := new(appMock.MockAccessService)
+		mockRepository := new(appMock.MockManager)
+		mockTOTPRepository := new(appMock.MockTOTPModelRepository)
+		mockRepository.On("TOTP").Return(mockTOTPRepository)
+		code, step := currentTOTPCode(t)
+		mockAccessService.On("GetUserUUIDByJWTToken", mock.Anything).Return("user-uuid", nil)
+		mockTOTPRepository.On("FindOneByUserUUID", mock.Anything, "user-uuid").Return(&models.UserTOTP{UserUUID: "user-uuid", Secret: testTOTPSecret, Enabled: true}, nil)
+		mockTOTPRepository.On("UseStep", mock.Anything, "user-uuid", step).Return(true, nil)
+		mockTOTPRepository.On("Delete", mock.Anything, "user-uuid").Return(nil)
+
+		handler := NewTOTPHandler(mockAccessService, mockRepository, new(appMock.MockSessionOpener), newTOTPTestConfig(), l)
+		res := httptest.NewRecorder()
+		handler.HandleDisable(res, newTOTPRequest("/totp/disable", model_data.TOTPCodeRequest{Code: code}))
+
+		assert.Equal(t, http.StatusOK, res.Code)
+	})
+}
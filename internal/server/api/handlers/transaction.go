@@ -1,15 +1,17 @@
 package handlers
 
 import (
-	"context"
-
+	"bytes"
+	"database/sql"
+	"errors"
 	"net/http"
 
-	"github.com/northmule/gophkeeper/internal/server/api/rctx"
+	"github.com/go-chi/render"
 	"github.com/northmule/gophkeeper/internal/server/logger"
 	"github.com/northmule/gophkeeper/internal/server/storage"
 )
 
+// TransactionHandler выполнение запроса в одной транзакции
 type TransactionHandler struct {
 	db  storage.DBQuery
 	log *logger.Logger
@@ -25,48 +27,92 @@ func NewTransactionHandler(db storage.DBQuery, log *logger.Logger) *TransactionH
 	return instance
 }
 
-// Transaction открывает транзакцию в начале отбработки запроса
+// Transaction открывает транзакцию в начале обработки запроса, репозитории выполняют в ней все запросы.
+// Ответ придерживается до фиксации: ответ с ошибкой (статус от 400) или ошибки транзакции откатывают изменения,
+// при ошибке фиксации клиент получает 500 вместо ответа обработчика
 func (th *TransactionHandler) Transaction(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
-		var err error
-
 		transaction, err := storage.NewTransaction(th.db)
-		th.log.Info("The beginning of the transaction")
 		if err != nil {
 			th.log.Errorf("The transaction is not open: %s", err)
-			res.WriteHeader(http.StatusInternalServerError)
+			_ = render.Render(res, req, ErrInternalServerError)
 			return
 		}
+		done := false
 		defer func() {
+			if done {
+				return
+			}
+			if err := transaction.Rollback(); err != nil && !errors.Is(err, sql.ErrTxDone) {
+				th.log.Errorf("Rollback request error: %s", err)
+			}
 			if r := recover(); r != nil {
-				th.log.Info("An application error has occurred. Rollback the transaction.", r)
-				if err = transaction.Rollback(); err != nil {
-					th.log.Errorf("Commit request error: %s", err)
-				}
+				th.log.Info("An application error has occurred. The transaction has been rolled back.")
+				panic(r)
 			}
-			transaction = nil
 		}()
-		ctx := context.WithValue(req.Context(), rctx.TransactionCtxKey, transaction)
-		req = req.WithContext(ctx)
 
-		next.ServeHTTP(res, req)
+		response := newBufferedResponse()
+		next.ServeHTTP(response, req.WithContext(storage.WithTransaction(req.Context(), transaction)))
 
 		if len(transaction.Error()) > 0 {
 			th.log.Info("Errors occurred during the execution of the transaction", transaction.Error())
-			if err = transaction.Rollback(); err != nil {
-				th.log.Errorf("Commit request error: %s", err)
-
-			}
-			th.log.Info("The transaction has been rolled back, the data has not been changed")
-			res.WriteHeader(http.StatusInternalServerError)
+			_ = render.Render(res, req, ErrInternalServerError)
+			return
+		}
+		if response.status >= http.StatusBadRequest {
+			th.log.Infof("The transaction has been rolled back, response status %d", response.status)
+			response.writeTo(res)
 			return
 		}
 
-		err = transaction.Commit()
-		if err != nil {
+		done = true
+		if err = transaction.Commit(); err != nil {
 			th.log.Errorf("Commit request error: %s", err)
-			res.WriteHeader(http.StatusInternalServerError)
+			_ = render.Render(res, req, ErrInternalServerError)
+			return
 		}
-		th.log.Info("The transaction is completed. All changes are saved.")
+		response.writeTo(res)
 	})
 }
+
+// bufferedResponse ответ обработчика, отправляемый после фиксации транзакции
+type bufferedResponse struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func newBufferedResponse() *bufferedResponse {
+	return &bufferedResponse{header: make(http.Header)}
+}
+
+// Header заголовки ответа
+func (r *bufferedResponse) Header() http.Header {
+	return r.header
+}
+
+// WriteHeader статус ответа, учитывается первый
+func (r *bufferedResponse) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+}
+
+// Write тело ответа
+func (r *bufferedResponse) Write(b []byte) (int, error) {
+	r.WriteHeader(http.StatusOK)
+	return r.body.Write(b)
+}
+
+// writeTo отправка ответа клиенту
+func (r *bufferedResponse) writeTo(res http.ResponseWriter) {
+	for key, values := range r.header {
+		res.Header()[key] = values
+	}
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	res.WriteHeader(r.status)
+	_, _ = res.Write(r.body.Bytes())
+}
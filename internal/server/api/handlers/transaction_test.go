@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-chi/render"
+	"github.com/northmule/gophkeeper/internal/server/logger"
+	"github.com/northmule/gophkeeper/internal/server/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransactionHandler_Transaction(t *testing.T) {
+	log, _ := logger.NewLogger("info")
+
+	// обработчик пишет в БД через транзакцию запроса и отвечает статусом status
+	handlerWithStatus := func(status int) http.Handler {
+		return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+			_, err := storage.Query(req.Context(), nil).ExecContext(req.Context(), "insert into owner")
+			require.NoError(t, err)
+			res.Header().Set("ETag", `"2"`)
+			if status != http.StatusOK {
+				_ = render.Render(res, req, &ErrResponse{HTTPStatusCode: status, StatusText: "error"})
+				return
+			}
+			_, _ = res.Write([]byte(`{"ok":true}`))
+		})
+	}
+
+	t.Run("commit", func(t *testing.T) {
+		db, dbMock, err := sqlmock.New()
+		require.NoError(t, err)
+		dbMock.ExpectBegin()
+		dbMock.ExpectExec("insert into owner").WillReturnResult(sqlmock.NewResult(1, 1))
+		dbMock.ExpectCommit()
+
+		res := httptest.NewRecorder()
+		NewTransactionHandler(db, log).Transaction(handlerWithStatus(http.StatusOK)).ServeHTTP(res, httptest.NewRequest(http.MethodPost, "/", nil))
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.Equal(t, `{"ok":true}`, res.Body.String())
+		assert.Equal(t, `"2"`, res.Header().Get("ETag"))
+		assert.NoError(t, dbMock.ExpectationsWereMet())
+	})
+
+	t.Run("rollback on error response", func(t *testing.T) {
+		db, dbMock, err := sqlmock.New()
+		require.NoError(t, err)
+		dbMock.ExpectBegin()
+		dbMock.ExpectExec("insert into owner").WillReturnResult(sqlmock.NewResult(1, 1))
+		dbMock.ExpectRollback()
+
+		res := httptest.NewRecorder()
+		NewTransactionHandler(db, log).Transaction(handlerWithStatus(http.StatusConflict)).ServeHTTP(res, httptest.NewRequest(http.MethodPost, "/", nil))
+		assert.Equal(t, http.StatusConflict, res.Code)
+		assert.NoError(t, dbMock.ExpectationsWereMet())
+	})
+
+	t.Run("commit error", func(t *testing.T) {
+		db, dbMock, err := sqlmock.New()
+		require.NoError(t, err)
+		dbMock.ExpectBegin()
+		dbMock.ExpectExec("insert into owner").WillReturnResult(sqlmock.NewResult(1, 1))
+		dbMock.ExpectCommit().WillReturnError(errors.New("serialization failure"))
+
+		res := httptest.NewRecorder()
+		NewTransactionHandler(db, log).Transaction(handlerWithStatus(http.StatusOK)).ServeHTTP(res, httptest.NewRequest(http.MethodPost, "/", nil))
+		assert.Equal(t, http.StatusInternalServerError, res.Code)
+		assert.Empty(t, res.Header().Get("ETag"))
+		assert.NoError(t, dbMock.ExpectationsWereMet())
+	})
+
+	t.Run("panic", func(t *testing.T) {
+		db, dbMock, err := sqlmock.New()
+		require.NoError(t, err)
+		dbMock.ExpectBegin()
+		dbMock.ExpectRollback()
+
+		handler := NewTransactionHandler(db, log).Transaction(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+			panic("handler failed")
+		}))
+		assert.Panics(t, func() {
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/", nil))
+		})
+		assert.NoError(t, dbMock.ExpectationsWereMet())
+	})
+
+	t.Run("begin error", func(t *testing.T) {
+		db, dbMock, err := sqlmock.New()
+		require.NoError(t, err)
+		dbMock.ExpectBegin().WillReturnError(errors.New("connection refused"))
+
+		res := httptest.NewRecorder()
+		NewTransactionHandler(db, log).Transaction(handlerWithStatus(http.StatusOK)).ServeHTTP(res, httptest.NewRequest(http.MethodPost, "/", nil))
+		assert.Equal(t, http.StatusInternalServerError, res.Code)
+	})
+}
@@ -58,6 +58,38 @@ func (v *ValidatorHandler) HandleValidation(next http.Handler) http.Handler {
 
 			err = render.Bind(req, requestType)
 			err = errors.Join(err, validate.Struct(requestType))
+		case *credentialDataRequest:
+
+			err = render.Bind(req, requestType)
+			err = errors.Join(err, validate.Struct(requestType))
+		case *batchRequest:
+
+			err = render.Bind(req, requestType)
+			err = errors.Join(err, validate.Struct(requestType))
+		case *masterKeyRequest:
+
+			err = render.Bind(req, requestType)
+			err = errors.Join(err, validate.Struct(requestType))
+		case *recoveryKitRequest:
+
+			err = render.Bind(req, requestType)
+			err = errors.Join(err, validate.Struct(requestType))
+		case *refreshTokenRequest:
+
+			err = render.Bind(req, requestType)
+			err = errors.Join(err, validate.Struct(requestType))
+		case *totpCodeRequest:
+
+			err = render.Bind(req, requestType)
+			err = errors.Join(err, validate.Struct(requestType))
+		case *totpLoginRequest:
+
+			err = render.Bind(req, requestType)
+			err = errors.Join(err, validate.Struct(requestType))
+		case *deviceRenameRequest:
+
+			err = render.Bind(req, requestType)
+			err = errors.Join(err, validate.Struct(requestType))
 
 			// Пропускаем не известные
 		default:
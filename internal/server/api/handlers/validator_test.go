@@ -53,6 +53,15 @@ func TestValidatorHandler_HandleValidation_ValidationErrors(t *testing.T) {
 		handler.HandleValidation(next).ServeHTTP(rr, req)
 		assert.Equal(t, http.StatusBadRequest, rr.Code)
 	})
+	t.Run("registrationRequest short password", func(t *testing.T) {
+		reqBody := `{"login": "123456", "password":"1123132", "email":"admin@mail.ru"}`
+		req, _ := http.NewRequest("POST", "/test", bytes.NewBufferString(reqBody))
+		req.Header.Set("Content-Type", "application/json")
+		handler := NewValidatorHandler(new(registrationRequest), l)
+		rr := httptest.NewRecorder()
+		handler.HandleValidation(next).ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
 	t.Run("authenticationRequest", func(t *testing.T) {
 		reqBody := `{"name": "", "login": "1"}`
 		req, _ := http.NewRequest("POST", "/test", bytes.NewBufferString(reqBody))
@@ -99,7 +108,7 @@ func TestValidatorHandler_HandleValidation_ValidationSuccess(t *testing.T) {
 		assert.Equal(t, http.StatusOK, rr.Code)
 	})
 	t.Run("registrationRequest", func(t *testing.T) {
-		reqBody := `{"login": "123456", "password":"1123132", "email":"admin@mail.ru"}`
+		reqBody := `{"login": "123456", "password":"11231324", "email":"admin@mail.ru"}`
 		req, _ := http.NewRequest("POST", "/test", bytes.NewBufferString(reqBody))
 		req.Header.Set("Content-Type", "application/json")
 		handler := NewValidatorHandler(new(registrationRequest), l)
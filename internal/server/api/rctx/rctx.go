@@ -6,10 +6,14 @@ type key int
 const (
 	// UserCtxKey объект с пользователем
 	UserCtxKey key = iota
-	// TransactionCtxKey транзакция в рамках запроса
-	TransactionCtxKey
+	// AuditCtxKey событие журнала действий, которое запишется после успешного ответа
+	AuditCtxKey
 )
 
 const (
 	MapKeyUserUUID = "user_uuid"
+	// MapKeySessionUUID UUID сессии, к которой выпущен токен
+	MapKeySessionUUID = "sid"
+	// MapKeyDeviceUUID UUID устройства, с которого открыта сессия
+	MapKeyDeviceUUID = "did"
 )
@@ -1,6 +1,10 @@
 package config
 
 import (
+	"fmt"
+	"time"
+
+	"github.com/northmule/gophkeeper/internal/common/util"
 	"github.com/spf13/viper"
 )
 
@@ -22,8 +26,55 @@ type ServerConfig struct {
 	// PathKeys место хранения ключей сервера
 	PathKeys      string `mapstructure:"PATH_KEYS"`
 	OverwriteKeys bool   `mapstructure:"OVERWRITE_KEYS"`
+	// KeyAlgorithm алгоритм создаваемых ключей сервера: rsa, ecdsa, ed25519
+	KeyAlgorithm string `mapstructure:"KEY_ALGORITHM"`
 	// MigrationsApply - true будут применяться миграции
 	MigrationsApply bool `mapstructure:"MIGRATIONS_APPLY"`
+	// JWTAlg алгоритм подписи токенов: HS512, RS256, EdDSA
+	JWTAlg string `mapstructure:"JWT_ALG"`
+	// JWTKeys ключи подписи токенов в формате kid:значение
+	// (секрет для HS512, путь к PEM файлу ключа для RS256 и EdDSA)
+	JWTKeys []string `mapstructure:"JWT_KEYS"`
+	// JWTActiveKeyID kid ключа, которым подписываются новые токены. Остальные ключи только проверяют токены
+	JWTActiveKeyID string `mapstructure:"JWT_ACTIVE_KID"`
+	// JWTTTL время жизни токена доступа
+	JWTTTL time.Duration `mapstructure:"JWT_TTL"`
+	// RefreshTokenTTL время жизни refresh токена (сессии), продлевается при каждом обновлении
+	RefreshTokenTTL time.Duration `mapstructure:"REFRESH_TOKEN_TTL"`
+	// TOTPIssuer название сервиса в приложении-аутентификаторе
+	TOTPIssuer string `mapstructure:"TOTP_ISSUER"`
+	// KEKKeys ключи шифрования ключей клиентов в формате kid:путь к файлу
+	KEKKeys []string `mapstructure:"KEK_KEYS"`
+	// KEKActiveKeyID kid ключа, которым шифруются ключи клиентов. Остальные ключи только расшифровывают
+	KEKActiveKeyID string `mapstructure:"KEK_ACTIVE_KID"`
+	// TLSEnable - true сервер принимает подключения по HTTPS
+	TLSEnable bool `mapstructure:"TLS_ENABLE"`
+	// TLSCertFile сертификат сервера. Без значения используется самоподписанный cert.pem из PathKeys
+	TLSCertFile string `mapstructure:"TLS_CERT_FILE"`
+	// TLSKeyFile приватный ключ сертификата. Без значения используется private_key.pem из PathKeys
+	TLSKeyFile string `mapstructure:"TLS_KEY_FILE"`
+	// MTLSEnable - true сервер выпускает сертификаты клиентов и принимает вход по сертификату (нужен TLS_ENABLE)
+	MTLSEnable bool `mapstructure:"MTLS_ENABLE"`
+	// MTLSCertTTL время жизни сертификата клиента
+	MTLSCertTTL time.Duration `mapstructure:"MTLS_CERT_TTL"`
+	// RequestMaxSkew допустимое расхождение времени зашифрованного запроса и сервера
+	RequestMaxSkew time.Duration `mapstructure:"REQUEST_MAX_SKEW"`
+	// RateLimitWindow окно ограничения частоты запросов входа и регистрации
+	RateLimitWindow time.Duration `mapstructure:"RATE_LIMIT_WINDOW"`
+	// RateLimitIP число запросов входа и регистрации с одного IP за окно (0 - без ограничения)
+	RateLimitIP int `mapstructure:"RATE_LIMIT_IP"`
+	// RateLimitLogin число запросов входа и регистрации с одним логином за окно (0 - без ограничения)
+	RateLimitLogin int `mapstructure:"RATE_LIMIT_LOGIN"`
+	// LoginDelay задержка после первой неудачной попытки входа, удваивается с каждой следующей (0 - без задержек)
+	LoginDelay time.Duration `mapstructure:"LOGIN_DELAY"`
+	// LoginLockoutThreshold число неудачных попыток входа подряд до блокировки логина (0 - без блокировки)
+	LoginLockoutThreshold int `mapstructure:"LOGIN_LOCKOUT_THRESHOLD"`
+	// LoginLockoutDuration первая блокировка логина, удваивается с каждой следующей неудачной попыткой
+	LoginLockoutDuration time.Duration `mapstructure:"LOGIN_LOCKOUT_DURATION"`
+	// LoginLockoutMax наибольшая блокировка логина
+	LoginLockoutMax time.Duration `mapstructure:"LOGIN_LOCKOUT_MAX"`
+	// LoginFailuresReset счётчик неудачных попыток сбрасывается, если столько времени не было новых
+	LoginFailuresReset time.Duration `mapstructure:"LOGIN_FAILURES_RESET"`
 }
 
 // ErrorCfg сообщение с ошибкой
@@ -43,6 +94,22 @@ func (c *Config) Init() error {
 	c.v.AddConfigPath(".")
 	c.v.SetConfigName(".server")
 	c.v.SetConfigType("env")
+	c.v.SetDefault("PASSWORD_ALGO_HASHING", util.PasswordAlgoArgon2id)
+	c.v.SetDefault("KEY_ALGORITHM", "rsa")
+	c.v.SetDefault("JWT_ALG", "HS512")
+	c.v.SetDefault("JWT_TTL", "15m")
+	c.v.SetDefault("REFRESH_TOKEN_TTL", "720h")
+	c.v.SetDefault("TOTP_ISSUER", "GophKeeper")
+	c.v.SetDefault("MTLS_CERT_TTL", "8760h")
+	c.v.SetDefault("REQUEST_MAX_SKEW", "5m")
+	c.v.SetDefault("RATE_LIMIT_WINDOW", "1m")
+	c.v.SetDefault("RATE_LIMIT_IP", 30)
+	c.v.SetDefault("RATE_LIMIT_LOGIN", 10)
+	c.v.SetDefault("LOGIN_DELAY", "1s")
+	c.v.SetDefault("LOGIN_LOCKOUT_THRESHOLD", 5)
+	c.v.SetDefault("LOGIN_LOCKOUT_DURATION", "15m")
+	c.v.SetDefault("LOGIN_LOCKOUT_MAX", "24h")
+	c.v.SetDefault("LOGIN_FAILURES_RESET", "24h")
 	err = c.v.ReadInConfig()
 	if err != nil {
 		return ErrorCfg(err)
@@ -53,6 +120,10 @@ func (c *Config) Init() error {
 		return ErrorCfg(err)
 	}
 
+	if !util.IsPasswordAlgo(c.value.PasswordAlgoHashing) {
+		return ErrorCfg(fmt.Errorf("unknown PASSWORD_ALGO_HASHING %q", c.value.PasswordAlgoHashing))
+	}
+
 	return nil
 }
 
@@ -0,0 +1,141 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/northmule/gophkeeper/internal/common/models"
+	"github.com/northmule/gophkeeper/internal/server/storage"
+)
+
+// AuditEventFilter отбор записей журнала пользователя. Пустые поля не ограничивают выборку
+type AuditEventFilter struct {
+	UserUUID string
+	Action   string
+	DataUUID string
+	From     *time.Time
+	To       *time.Time
+}
+
+// AuditEventRepository репозитарий журнала действий пользователей
+type AuditEventRepository struct {
+	store        storage.DBQuery
+	sqlFindChain *sql.Stmt
+}
+
+// NewAuditEventRepository конструктор
+func NewAuditEventRepository(store storage.DBQuery) (*AuditEventRepository, error) {
+	var err error
+	instance := new(AuditEventRepository)
+	instance.store = store
+	instance.sqlFindChain, err = store.Prepare(`select id, user_uuid, action, data_uuid, ip, details, prev_hash, hash, key_id, created_at from audit_events where user_uuid = $1 and id > $2 order by id limit $3`)
+	if err != nil {
+		return nil, ErrorMsg(err)
+	}
+	return instance, nil
+}
+
+// Add запись в конец цепочки пользователя: PrevHash заполняется здесь, Hash - chainHash от записи с PrevHash.
+// Записи одного пользователя добавляются по очереди (блокировка на время транзакции)
+func (r *AuditEventRepository) Add(ctx context.Context, event *models.AuditEvent, chainHash func(event *models.AuditEvent) string) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeOut)
+	defer cancel()
+	tx, err := storage.BeginTx(ctx, r.store)
+	if err != nil {
+		return 0, ErrorMsg(err)
+	}
+	_, err = tx.ExecContext(ctx, `select pg_advisory_xact_lock(hashtext($1))`, "audit_events:"+event.UserUUID)
+	if err != nil {
+		return 0, ErrorMsg(errors.Join(err, tx.Rollback()))
+	}
+	var prevHash string
+	err = tx.QueryRowContext(ctx, `select hash from audit_events where user_uuid = $1 order by id desc limit 1`, event.UserUUID).Scan(&prevHash)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrorMsg(errors.Join(err, tx.Rollback()))
+	}
+	event.CreatedAt = event.CreatedAt.UTC().Truncate(time.Microsecond)
+	event.PrevHash = prevHash
+	event.Hash = chainHash(event)
+	var id int64
+	err = tx.QueryRowContext(
+		ctx,
+		`insert into audit_events (user_uuid, action, data_uuid, ip, details, prev_hash, hash, key_id, created_at) values ($1, $2, $3, $4, $5, $6, $7, $8, $9) returning id`,
+		event.UserUUID, event.Action, event.DataUUID, event.IP, event.Details, event.PrevHash, event.Hash, event.KeyID, event.CreatedAt,
+	).Scan(&id)
+	if err != nil {
+		return 0, ErrorMsg(errors.Join(err, tx.Rollback()))
+	}
+	if err = tx.Commit(); err != nil {
+		return 0, ErrorMsg(err)
+	}
+	event.ID = id
+	return id, nil
+}
+
+// FindAll записи журнала по фильтру, новые первыми
+func (r *AuditEventRepository) FindAll(ctx context.Context, filter AuditEventFilter, offset int, limit int) ([]models.AuditEvent, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeOut)
+	defer cancel()
+	conditions := []string{"user_uuid = $1"}
+	args := []any{filter.UserUUID}
+	addCondition := func(condition string, value any) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+	if filter.Action != "" {
+		addCondition("action = $%d", filter.Action)
+	}
+	if filter.DataUUID != "" {
+		addCondition("data_uuid = $%d", filter.DataUUID)
+	}
+	if filter.From != nil {
+		addCondition("created_at >= $%d", filter.From.UTC())
+	}
+	if filter.To != nil {
+		addCondition("created_at < $%d", filter.To.UTC())
+	}
+	args = append(args, offset, limit)
+	query := fmt.Sprintf(
+		`select id, user_uuid, action, data_uuid, ip, details, prev_hash, hash, key_id, created_at from audit_events where %s order by id desc offset $%d limit $%d`,
+		strings.Join(conditions, " and "), len(args)-1, len(args),
+	)
+	rows, err := storage.Query(ctx, r.store).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, ErrorMsg(err)
+	}
+	return scanAuditEvents(rows)
+}
+
+// FindChain часть цепочки пользователя после записи afterID в порядке добавления
+func (r *AuditEventRepository) FindChain(ctx context.Context, userUUID string, afterID int64, limit int) ([]models.AuditEvent, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeOut)
+	defer cancel()
+	rows, err := storage.Stmt(ctx, r.sqlFindChain).QueryContext(ctx, userUUID, afterID, limit)
+	if err != nil {
+		return nil, ErrorMsg(err)
+	}
+	return scanAuditEvents(rows)
+}
+
+// scanAuditEvents чтение записей журнала
+func scanAuditEvents(rows *sql.Rows) ([]models.AuditEvent, error) {
+	defer rows.Close()
+	events := make([]models.AuditEvent, 0)
+	for rows.Next() {
+		var event models.AuditEvent
+		err := rows.Scan(&event.ID, &event.UserUUID, &event.Action, &event.DataUUID, &event.IP, &event.Details, &event.PrevHash, &event.Hash, &event.KeyID, &event.CreatedAt)
+		if err != nil {
+			return nil, ErrorMsg(err)
+		}
+		events = append(events, event)
+	}
+	err := rows.Err()
+	if err != nil {
+		return nil, ErrorMsg(err)
+	}
+	return events, nil
+}
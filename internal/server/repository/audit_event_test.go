@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/northmule/gophkeeper/internal/common/models"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"golang.org/x/net/context"
+)
+
+type AuditEventRepositoryTestSuite struct {
+	suite.Suite
+	DB         *sql.DB
+	mock       sqlmock.Sqlmock
+	repository *AuditEventRepository
+}
+
+func (s *AuditEventRepositoryTestSuite) SetupTest() {
+	var err error
+	s.DB, s.mock, err = sqlmock.New()
+	require.NoError(s.T(), err)
+	s.mock.ExpectPrepare("select id, user_uuid, action")
+	s.repository, err = NewAuditEventRepository(s.DB)
+	require.NoError(s.T(), err)
+}
+
+func TestAuditEventRepositoryTestSuite(t *testing.T) {
+	suite.Run(t, new(AuditEventRepositoryTestSuite))
+}
+
+var auditEventColumns = []string{"id", "user_uuid", "action", "data_uuid", "ip", "details", "prev_hash", "hash", "key_id", "created_at"}
+
+// testChainHash подпись записи тестовым ключом
+func testChainHash(event *models.AuditEvent) string {
+	return event.ChainHash([]byte("audit-key"))
+}
+
+func (s *AuditEventRepositoryTestSuite) TestAdd() {
+	event := &models.AuditEvent{UserUUID: "user-uuid", Action: models.AuditItemRead, DataUUID: "data-uuid", KeyID: "k1", CreatedAt: time.Now()}
+	s.mock.ExpectBegin()
+	s.mock.ExpectExec("select pg_advisory_xact_lock").
+		WithArgs("audit_events:user-uuid").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	s.mock.ExpectQuery("select hash from audit_events").
+		WithArgs("user-uuid").
+		WillReturnRows(sqlmock.NewRows([]string{"hash"}).AddRow("prev-hash"))
+	s.mock.ExpectQuery("insert into audit_events").
+		WithArgs("user-uuid", models.AuditItemRead, "data-uuid", "", "", "prev-hash", sqlmock.AnyArg(), "k1", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+	s.mock.ExpectCommit()
+
+	id, err := s.repository.Add(context.Background(), event, testChainHash)
+	require.NoError(s.T(), err)
+	s.Equal(int64(7), id)
+	s.Equal("prev-hash", event.PrevHash)
+	// хэш считается после заполнения PrevHash
+	s.Equal(event.ChainHash([]byte("audit-key")), event.Hash)
+	s.NoError(s.mock.ExpectationsWereMet())
+}
+
+func (s *AuditEventRepositoryTestSuite) TestAdd_FirstEvent() {
+	event := &models.AuditEvent{UserUUID: "user-uuid", Action: models.AuditLogin, CreatedAt: time.Now()}
+	s.mock.ExpectBegin()
+	s.mock.ExpectExec("select pg_advisory_xact_lock").WillReturnResult(sqlmock.NewResult(0, 0))
+	s.mock.ExpectQuery("select hash from audit_events").WillReturnRows(sqlmock.NewRows([]string{"hash"}))
+	s.mock.ExpectQuery("insert into audit_events").
+		WithArgs("user-uuid", models.AuditLogin, "", "", "", "", sqlmock.AnyArg(), "", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	s.mock.ExpectCommit()
+
+	_, err := s.repository.Add(context.Background(), event, testChainHash)
+	require.NoError(s.T(), err)
+	s.Empty(event.PrevHash)
+}
+
+func (s *AuditEventRepositoryTestSuite) TestAdd_Error() {
+	s.mock.ExpectBegin()
+	s.mock.ExpectExec("select pg_advisory_xact_lock").WillReturnResult(sqlmock.NewResult(0, 0))
+	s.mock.ExpectQuery("select hash from audit_events").WillReturnError(errors.New("db error"))
+	s.mock.ExpectRollback()
+
+	_, err := s.repository.Add(context.Background(), &models.AuditEvent{UserUUID: "user-uuid"}, testChainHash)
+	s.Error(err)
+	s.NoError(s.mock.ExpectationsWereMet())
+}
+
+func (s *AuditEventRepositoryTestSuite) TestFindAll() {
+	from := time.Now().Add(-time.Hour)
+	s.mock.ExpectQuery(`where user_uuid = \$1 and action = \$2 and created_at >= \$3 order by id desc offset \$4 limit \$5`).
+		WithArgs("user-uuid", models.AuditItemSave, from.UTC(), 10, 20).
+		WillReturnRows(sqlmock.NewRows(auditEventColumns).
+			AddRow(2, "user-uuid", models.AuditItemSave, "data-uuid", "127.0.0.1", "", "h1", "h2", "k1", time.Now()))
+
+	events, err := s.repository.FindAll(context.Background(), AuditEventFilter{UserUUID: "user-uuid", Action: models.AuditItemSave, From: &from}, 10, 20)
+	require.NoError(s.T(), err)
+	s.Len(events, 1)
+	s.Equal("data-uuid", events[0].DataUUID)
+	s.Equal("k1", events[0].KeyID)
+}
+
+func (s *AuditEventRepositoryTestSuite) TestFindChain() {
+	s.mock.ExpectQuery("select").
+		WithArgs("user-uuid", int64(5), 100).
+		WillReturnRows(sqlmock.NewRows(auditEventColumns).
+			AddRow(6, "user-uuid", models.AuditLogin, "", "", "", "h5", "h6", "", time.Now()))
+
+	events, err := s.repository.FindChain(context.Background(), "user-uuid", 5, 100)
+	require.NoError(s.T(), err)
+	s.Len(events, 1)
+	s.Equal("h6", events[0].Hash)
+}
+
+func (s *AuditEventRepositoryTestSuite) TestFindChain_Error() {
+	s.mock.ExpectQuery("select").WillReturnError(errors.New("db error"))
+
+	_, err := s.repository.FindChain(context.Background(), "user-uuid", 0, 100)
+	s.Error(err)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 
 	"github.com/northmule/gophkeeper/internal/common/models"
 	"github.com/northmule/gophkeeper/internal/server/storage"
@@ -21,7 +22,7 @@ func NewCardDataRepository(store storage.DBQuery) (*CardDataRepository, error) {
 	var err error
 	instance := new(CardDataRepository)
 	instance.store = store
-	instance.sqlFindOneByUUID, err = store.Prepare(`select id, value, object_type, name, uuid from card_data where uuid = $1 limit 1`)
+	instance.sqlFindOneByUUID, err = store.Prepare(`select id, value, object_type, name, uuid, version from card_data where uuid = $1 limit 1`)
 	if err != nil {
 		return nil, ErrorMsg(err)
 	}
@@ -33,7 +34,7 @@ func (r *CardDataRepository) FindOneByUUID(ctx context.Context, uuid string) (*m
 
 	ctx, cancel := context.WithTimeout(ctx, timeOut)
 	defer cancel()
-	rows, err := r.sqlFindOneByUUID.QueryContext(ctx, uuid)
+	rows, err := storage.Stmt(ctx, r.sqlFindOneByUUID).QueryContext(ctx, uuid)
 	if err != nil {
 		return nil, ErrorMsg(err)
 	}
@@ -45,7 +46,7 @@ func (r *CardDataRepository) FindOneByUUID(ctx context.Context, uuid string) (*m
 	if rows.Next() {
 		data.Value = models.CardDataValueV1{}
 		var jsonbValue string
-		err = rows.Scan(&data.ID, &jsonbValue, &data.ObjectType, &data.Name, &data.UUID)
+		err = rows.Scan(&data.ID, &jsonbValue, &data.ObjectType, &data.Name, &data.UUID, &data.Version)
 		if err != nil {
 			return nil, ErrorMsg(err)
 		}
@@ -62,7 +63,7 @@ func (r *CardDataRepository) FindOneByUUID(ctx context.Context, uuid string) (*m
 func (r *CardDataRepository) Add(ctx context.Context, data *models.CardData) (int64, error) {
 	ctx, cancel := context.WithTimeout(ctx, timeOut)
 	defer cancel()
-	rows := r.store.QueryRowContext(ctx, `insert into card_data (name, object_type, "value", uuid) values ($1, $2, $3, $4) returning id`, data.Name, data.ObjectType, data.Value, data.UUID)
+	rows := storage.Query(ctx, r.store).QueryRowContext(ctx, `insert into card_data (name, object_type, "value", uuid) values ($1, $2, $3, $4) returning id`, data.Name, data.ObjectType, data.Value, data.UUID)
 	err := rows.Err()
 	if err != nil {
 		return 0, ErrorMsg(err)
@@ -76,11 +77,36 @@ func (r *CardDataRepository) Add(ctx context.Context, data *models.CardData) (in
 	return id, nil
 }
 
-// Update Обновление основных полей
+// Update Обновление основных полей, если версия данных не изменилась с data.Version.
+// Новая версия записывается в data.Version, иначе вернёт ErrVersionConflict
 func (r *CardDataRepository) Update(ctx context.Context, data *models.CardData) error {
 	ctx, cancel := context.WithTimeout(ctx, timeOut)
 	defer cancel()
-	rows := r.store.QueryRowContext(ctx, `update card_data set name = $1, value = $2 where uuid = $3`, data.Name, data.Value, data.UUID)
+	value, err := json.Marshal(data.Value)
+	if err != nil {
+		return ErrorMsg(err)
+	}
+	err = storage.Query(ctx, r.store).QueryRowContext(ctx, `update card_data set name = $1, value = $2, version = version + 1 where uuid = $3 and version = $4 returning version`, data.Name, string(value), data.UUID, data.Version).Scan(&data.Version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrVersionConflict
+	}
+	if err != nil {
+		return ErrorMsg(err)
+	}
+	return nil
+}
 
-	return rows.Err()
+// Delete удаление данных, если версия не изменилась с version, иначе вернёт ErrVersionConflict
+func (r *CardDataRepository) Delete(ctx context.Context, uuid string, version int64) error {
+	ctx, cancel := context.WithTimeout(ctx, timeOut)
+	defer cancel()
+	var id int64
+	err := storage.Query(ctx, r.store).QueryRowContext(ctx, `delete from card_data where uuid = $1 and version = $2 returning id`, uuid, version).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrVersionConflict
+	}
+	if err != nil {
+		return ErrorMsg(err)
+	}
+	return nil
 }
@@ -7,6 +7,7 @@ import (
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/northmule/gophkeeper/internal/common/models"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 	"golang.org/x/net/context"
@@ -46,8 +47,8 @@ func (s *CardDataRepositoryTestSuite) TestFindOneByUUID_ValidUUID() {
 
 	s.mock.ExpectQuery("select").
 		WithArgs(uuid).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "value", "object_type", "name", "uuid"}).
-			AddRow(expectedData.ID, string(jsonValue), expectedData.ObjectType, expectedData.Name, expectedData.UUID))
+		WillReturnRows(sqlmock.NewRows([]string{"id", "value", "object_type", "name", "uuid", "version"}).
+			AddRow(expectedData.ID, string(jsonValue), expectedData.ObjectType, expectedData.Name, expectedData.UUID, expectedData.Version))
 
 	data, err := s.repository.FindOneByUUID(context.Background(), uuid)
 	require.NoError(s.T(), err)
@@ -59,7 +60,7 @@ func (s *CardDataRepositoryTestSuite) TestFindOneByUUID_InvalidUUID() {
 
 	s.mock.ExpectQuery("select").
 		WithArgs(uuid).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "value", "object_type", "name", "uuid"}))
+		WillReturnRows(sqlmock.NewRows([]string{"id", "value", "object_type", "name", "uuid", "version"}))
 
 	data, err := s.repository.FindOneByUUID(context.Background(), uuid)
 	require.NoError(s.T(), err)
@@ -127,14 +128,17 @@ func (s *CardDataRepositoryTestSuite) TestUpdate_ValidData() {
 		Name:       "Updated Card Name",
 	}
 	data.UUID = "existing-uuid"
+	data.Version = 2
 	jsonValue, err := json.Marshal(data.Value)
 	require.NoError(s.T(), err)
 
-	s.mock.ExpectQuery("update card_data set name = $1, value = $2 where uuid = $3").
-		WithArgs(data.Name, string(jsonValue), data.UUID).
-		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	s.mock.ExpectQuery("update card_data set name").
+		WithArgs(data.Name, string(jsonValue), data.UUID, int64(2)).
+		WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(3))
 
-	s.repository.Update(context.Background(), data)
+	err = s.repository.Update(context.Background(), data)
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), int64(3), data.Version)
 }
 
 func (s *CardDataRepositoryTestSuite) TestUpdate_InvalidData() {
@@ -158,10 +162,27 @@ func (s *CardDataRepositoryTestSuite) TestUpdate_NonExistentUUID() {
 	jsonValue, err := json.Marshal(data.Value)
 	require.NoError(s.T(), err)
 
-	s.mock.ExpectQuery("update card_data set name = $1, value = $2 where uuid = $3").
-		WithArgs(data.Name, string(jsonValue), data.UUID).
+	s.mock.ExpectQuery("update card_data set name").
+		WithArgs(data.Name, string(jsonValue), data.UUID, int64(0)).
 		WillReturnError(sql.ErrNoRows)
 
+	// нет строки с этой версией: данные изменены или удалены
 	err = s.repository.Update(context.Background(), data)
-	require.Error(s.T(), err)
+	assert.ErrorIs(s.T(), err, ErrVersionConflict)
+}
+
+func (s *CardDataRepositoryTestSuite) TestDelete() {
+	s.mock.ExpectQuery("delete from card_data").
+		WithArgs("test-uuid", int64(2)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	err := s.repository.Delete(context.Background(), "test-uuid", 2)
+	require.NoError(s.T(), err)
+
+	// версия изменена или данных нет
+	s.mock.ExpectQuery("delete from card_data").
+		WithArgs("test-uuid", int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	err = s.repository.Delete(context.Background(), "test-uuid", 1)
+	assert.ErrorIs(s.T(), err, ErrVersionConflict)
+	require.NoError(s.T(), s.mock.ExpectationsWereMet())
 }
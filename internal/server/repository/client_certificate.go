@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/northmule/gophkeeper/internal/common/models"
+	"github.com/northmule/gophkeeper/internal/server/storage"
+)
+
+// ClientCertificateRepository репозитарий сертификатов клиентов
+type ClientCertificateRepository struct {
+	store                   storage.DBQuery
+	sqlFindOneByFingerprint *sql.Stmt
+}
+
+// NewClientCertificateRepository конструктор
+func NewClientCertificateRepository(store storage.DBQuery) (*ClientCertificateRepository, error) {
+	var err error
+	instance := new(ClientCertificateRepository)
+	instance.store = store
+	instance.sqlFindOneByFingerprint, err = store.Prepare(`select id, user_uuid, device_uuid, fingerprint, public_key_hash, serial_number, created_at, expires_at, revoked_at from client_certificates where fingerprint = $1 limit 1`)
+	if err != nil {
+		return nil, ErrorMsg(err)
+	}
+	return instance, nil
+}
+
+// FindOneByFingerprint поиск сертификата по отпечатку, nil если сертификат не выдавался
+func (r *ClientCertificateRepository) FindOneByFingerprint(ctx context.Context, fingerprint string) (*models.ClientCertificate, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeOut)
+	defer cancel()
+	rows, err := storage.Stmt(ctx, r.sqlFindOneByFingerprint).QueryContext(ctx, fingerprint)
+	if err != nil {
+		return nil, ErrorMsg(err)
+	}
+	defer rows.Close()
+	err = rows.Err()
+	if err != nil {
+		return nil, ErrorMsg(err)
+	}
+	if !rows.Next() {
+		return nil, nil
+	}
+	certificate := new(models.ClientCertificate)
+	var (
+		deviceUUID sql.NullString
+		revokedAt  sql.NullTime
+	)
+	err = rows.Scan(&certificate.ID, &certificate.UserUUID, &deviceUUID, &certificate.Fingerprint, &certificate.PublicKeyHash, &certificate.SerialNumber, &certificate.CreatedAt, &certificate.ExpiresAt, &revokedAt)
+	if err != nil {
+		return nil, ErrorMsg(err)
+	}
+	certificate.DeviceUUID = deviceUUID.String
+	if revokedAt.Valid {
+		certificate.RevokedAt = &revokedAt.Time
+	}
+	return certificate, nil
+}
+
+// Add новый сертификат. Прежние сертификаты этого же ключа пользователя отзываются
+func (r *ClientCertificateRepository) Add(ctx context.Context, data *models.ClientCertificate) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeOut)
+	defer cancel()
+	tx, err := storage.BeginTx(ctx, r.store)
+	if err != nil {
+		return 0, ErrorMsg(err)
+	}
+	_, err = tx.ExecContext(ctx, `update client_certificates set revoked_at = now() where user_uuid = $1 and public_key_hash = $2 and revoked_at is null`, data.UserUUID, data.PublicKeyHash)
+	if err != nil {
+		return 0, ErrorMsg(errors.Join(err, tx.Rollback()))
+	}
+	var id int64
+	err = tx.QueryRowContext(
+		ctx,
+		`insert into client_certificates (user_uuid, device_uuid, fingerprint, public_key_hash, serial_number, expires_at) values ($1, nullif($2, '')::uuid, $3, $4, $5, $6) returning id`,
+		data.UserUUID, data.DeviceUUID, data.Fingerprint, data.PublicKeyHash, data.SerialNumber, data.ExpiresAt,
+	).Scan(&id)
+	if err != nil {
+		return 0, ErrorMsg(errors.Join(err, tx.Rollback()))
+	}
+	if err = tx.Commit(); err != nil {
+		return 0, ErrorMsg(err)
+	}
+	return id, nil
+}
@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/northmule/gophkeeper/internal/common/models"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"golang.org/x/net/context"
+)
+
+type ClientCertificateRepositoryTestSuite struct {
+	suite.Suite
+	DB         *sql.DB
+	mock       sqlmock.Sqlmock
+	repository *ClientCertificateRepository
+}
+
+var clientCertificateRowColumns = []string{"id", "user_uuid", "device_uuid", "fingerprint", "public_key_hash", "serial_number", "created_at", "expires_at", "revoked_at"}
+
+func (s *ClientCertificateRepositoryTestSuite) SetupTest() {
+	var err error
+	s.DB, s.mock, err = sqlmock.New()
+	require.NoError(s.T(), err)
+	s.mock.ExpectPrepare("select id, user_uuid, device_uuid, fingerprint")
+	s.repository, err = NewClientCertificateRepository(s.DB)
+	require.NoError(s.T(), err)
+}
+
+func TestClientCertificateRepositoryTestSuite(t *testing.T) {
+	suite.Run(t, new(ClientCertificateRepositoryTestSuite))
+}
+
+func (s *ClientCertificateRepositoryTestSuite) TestFindOneByFingerprint() {
+	revokedAt := time.Now().Add(-time.Minute)
+	s.mock.ExpectQuery("select").
+		WithArgs("fingerprint").
+		WillReturnRows(sqlmock.NewRows(clientCertificateRowColumns).
+			AddRow(1, "user-uuid", "device-uuid", "fingerprint", "key-hash", "10", time.Now().Add(-time.Hour), time.Now().Add(time.Hour), revokedAt))
+
+	certificate, err := s.repository.FindOneByFingerprint(context.Background(), "fingerprint")
+	require.NoError(s.T(), err)
+	require.NotNil(s.T(), certificate)
+	s.Equal("user-uuid", certificate.UserUUID)
+	s.NotNil(certificate.RevokedAt)
+	s.False(certificate.IsActive())
+}
+
+func (s *ClientCertificateRepositoryTestSuite) TestFindOneByFingerprint_NotFound() {
+	s.mock.ExpectQuery("select").
+		WithArgs("fingerprint").
+		WillReturnRows(sqlmock.NewRows(clientCertificateRowColumns))
+
+	certificate, err := s.repository.FindOneByFingerprint(context.Background(), "fingerprint")
+	require.NoError(s.T(), err)
+	s.Nil(certificate)
+}
+
+func (s *ClientCertificateRepositoryTestSuite) TestFindOneByFingerprint_Error() {
+	s.mock.ExpectQuery("select").
+		WithArgs("fingerprint").
+		WillReturnError(errors.New("select failed"))
+
+	_, err := s.repository.FindOneByFingerprint(context.Background(), "fingerprint")
+	require.Error(s.T(), err)
+}
+
+func (s *ClientCertificateRepositoryTestSuite) TestAdd() {
+	certificate := &models.ClientCertificate{
+		UserUUID:      "user-uuid",
+		DeviceUUID:    "device-uuid",
+		Fingerprint:   "fingerprint",
+		PublicKeyHash: "key-hash",
+		SerialNumber:  "10",
+		ExpiresAt:     time.Now().Add(time.Hour),
+	}
+	s.mock.ExpectBegin()
+	s.mock.ExpectExec("update client_certificates set revoked_at").WithArgs("user-uuid", "key-hash").WillReturnResult(sqlmock.NewResult(0, 1))
+	s.mock.ExpectQuery("insert into client_certificates").
+		WithArgs("user-uuid", "device-uuid", "fingerprint", "key-hash", "10", certificate.ExpiresAt).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5))
+	s.mock.ExpectCommit()
+
+	id, err := s.repository.Add(context.Background(), certificate)
+	require.NoError(s.T(), err)
+	s.Equal(int64(5), id)
+	require.NoError(s.T(), s.mock.ExpectationsWereMet())
+}
+
+func (s *ClientCertificateRepositoryTestSuite) TestAdd_Error() {
+	s.mock.ExpectBegin()
+	s.mock.ExpectExec("update client_certificates set revoked_at").WillReturnResult(sqlmock.NewResult(0, 0))
+	s.mock.ExpectQuery("insert into client_certificates").WillReturnError(errors.New("insert failed"))
+	s.mock.ExpectRollback()
+
+	_, err := s.repository.Add(context.Background(), &models.ClientCertificate{UserUUID: "user-uuid"})
+	require.Error(s.T(), err)
+	require.NoError(s.T(), s.mock.ExpectationsWereMet())
+}
@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	"github.com/northmule/gophkeeper/internal/common/models"
+	"github.com/northmule/gophkeeper/internal/server/storage"
+)
+
+// CredentialDataRepository репозитарий пар логин/пароль
+type CredentialDataRepository struct {
+	store storage.DBQuery
+
+	sqlFindOneByUUID *sql.Stmt
+}
+
+// NewCredentialDataRepository конструктор
+func NewCredentialDataRepository(store storage.DBQuery) (*CredentialDataRepository, error) {
+	var err error
+	instance := new(CredentialDataRepository)
+	instance.store = store
+	instance.sqlFindOneByUUID, err = store.Prepare(`select id, value, object_type, name, uuid, version from credential_data where uuid = $1 limit 1`)
+	if err != nil {
+		return nil, ErrorMsg(err)
+	}
+	return instance, nil
+}
+
+// FindOneByUUID поиск значения по UUID
+func (r *CredentialDataRepository) FindOneByUUID(ctx context.Context, uuid string) (*models.CredentialData, error) {
+
+	ctx, cancel := context.WithTimeout(ctx, timeOut)
+	defer cancel()
+	rows, err := storage.Stmt(ctx, r.sqlFindOneByUUID).QueryContext(ctx, uuid)
+	if err != nil {
+		return nil, ErrorMsg(err)
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, ErrorMsg(err)
+	}
+	data := new(models.CredentialData)
+	if rows.Next() {
+		data.Value = models.CredentialDataValueV1{}
+		var jsonbValue string
+		err = rows.Scan(&data.ID, &jsonbValue, &data.ObjectType, &data.Name, &data.UUID, &data.Version)
+		if err != nil {
+			return nil, ErrorMsg(err)
+		}
+		err = json.Unmarshal([]byte(jsonbValue), &data.Value)
+		if err != nil {
+			return nil, ErrorMsg(err)
+		}
+	}
+
+	return data, nil
+}
+
+// Add Новое значение
+func (r *CredentialDataRepository) Add(ctx context.Context, data *models.CredentialData) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeOut)
+	defer cancel()
+	rows := storage.Query(ctx, r.store).QueryRowContext(ctx, `insert into credential_data (name, object_type, "value", uuid) values ($1, $2, $3, $4) returning id`, data.Name, data.ObjectType, data.Value, data.UUID)
+	err := rows.Err()
+	if err != nil {
+		return 0, ErrorMsg(err)
+	}
+
+	var id int64
+	err = rows.Scan(&id)
+	if err != nil {
+		return 0, ErrorMsg(err)
+	}
+	return id, nil
+}
+
+// Update Обновление основных полей, если версия данных не изменилась с data.Version.
+// Новая версия записывается в data.Version, иначе вернёт ErrVersionConflict
+func (r *CredentialDataRepository) Update(ctx context.Context, data *models.CredentialData) error {
+	ctx, cancel := context.WithTimeout(ctx, timeOut)
+	defer cancel()
+	value, err := json.Marshal(data.Value)
+	if err != nil {
+		return ErrorMsg(err)
+	}
+	err = storage.Query(ctx, r.store).QueryRowContext(ctx, `update credential_data set name = $1, value = $2, version = version + 1 where uuid = $3 and version = $4 returning version`, data.Name, string(value), data.UUID, data.Version).Scan(&data.Version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrVersionConflict
+	}
+	if err != nil {
+		return ErrorMsg(err)
+	}
+	return nil
+}
+
+// Delete удаление данных, если версия не изменилась с version, иначе вернёт ErrVersionConflict
+func (r *CredentialDataRepository) Delete(ctx context.Context, uuid string, version int64) error {
+	ctx, cancel := context.WithTimeout(ctx, timeOut)
+	defer cancel()
+	var id int64
+	err := storage.Query(ctx, r.store).QueryRowContext(ctx, `delete from credential_data where uuid = $1 and version = $2 returning id`, uuid, version).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrVersionConflict
+	}
+	if err != nil {
+		return ErrorMsg(err)
+	}
+	return nil
+}
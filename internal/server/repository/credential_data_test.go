@@ -0,0 +1,188 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/northmule/gophkeeper/internal/common/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"golang.org/x/net/context"
+)
+
+type CredentialDataRepositoryTestSuite struct {
+	suite.Suite
+	DB         *sql.DB
+	mock       sqlmock.Sqlmock
+	repository *CredentialDataRepository
+}
+
+func (s *CredentialDataRepositoryTestSuite) SetupTest() {
+	var err error
+	s.DB, s.mock, err = sqlmock.New()
+	require.NoError(s.T(), err)
+	s.mock.ExpectPrepare("select id, value")
+	s.repository, err = NewCredentialDataRepository(s.DB)
+	require.NoError(s.T(), err)
+}
+
+func TestCredentialDataRepositoryTestSuite(t *testing.T) {
+	suite.Run(t, new(CredentialDataRepositoryTestSuite))
+}
+
+func (s *CredentialDataRepositoryTestSuite) TestFindOneByUUID_ValidUUID() {
+	uuid := "valid-uuid"
+	expectedData := &models.CredentialData{
+		Value:      models.CredentialDataValueV1{Username: "user", URLs: []string{"https://example.com"}},
+		ObjectType: "credential",
+		Name:       "Credential Name",
+	}
+	expectedData.UUID = uuid
+	expectedData.ID = 1
+	jsonValue, err := json.Marshal(expectedData.Value)
+	require.NoError(s.T(), err)
+
+	s.mock.ExpectQuery("select").
+		WithArgs(uuid).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "value", "object_type", "name", "uuid", "version"}).
+			AddRow(expectedData.ID, string(jsonValue), expectedData.ObjectType, expectedData.Name, expectedData.UUID, expectedData.Version))
+
+	data, err := s.repository.FindOneByUUID(context.Background(), uuid)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), expectedData, data)
+}
+
+func (s *CredentialDataRepositoryTestSuite) TestFindOneByUUID_InvalidUUID() {
+	uuid := "invalid-uuid"
+
+	s.mock.ExpectQuery("select").
+		WithArgs(uuid).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "value", "object_type", "name", "uuid", "version"}))
+
+	data, err := s.repository.FindOneByUUID(context.Background(), uuid)
+	require.NoError(s.T(), err)
+	require.Empty(s.T(), data)
+}
+
+func (s *CredentialDataRepositoryTestSuite) TestFindOneByUUID_EmptyUUID() {
+	uuid := ""
+
+	data, err := s.repository.FindOneByUUID(context.Background(), uuid)
+	require.Error(s.T(), err)
+	require.Nil(s.T(), data)
+}
+
+func (s *CredentialDataRepositoryTestSuite) TestAdd_ValidData() {
+	data := &models.CredentialData{
+		Value:      models.CredentialDataValueV1{Username: "user", URLs: []string{"https://example.com"}},
+		ObjectType: "credential",
+		Name:       "Credential Name",
+	}
+	data.UUID = "new-uuid"
+
+	s.mock.ExpectQuery("insert into").
+		WithArgs(data.Name, data.ObjectType, data.Value, data.UUID).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+
+	_, _ = s.repository.Add(context.Background(), data)
+
+}
+
+func (s *CredentialDataRepositoryTestSuite) TestAdd_InvalidData() {
+	data := &models.CredentialData{
+		Value:      models.CredentialDataValueV1{Username: "user", URLs: []string{"https://example.com"}},
+		ObjectType: "credential",
+		Name:       "",
+	}
+	data.UUID = "new-uuid"
+	id, err := s.repository.Add(context.Background(), data)
+	require.Error(s.T(), err)
+	require.Equal(s.T(), int64(0), id)
+}
+func (s *CredentialDataRepositoryTestSuite) TestAdd_DuplicateUUID() {
+	data := &models.CredentialData{
+		Value:      models.CredentialDataValueV1{Username: "user", URLs: []string{"https://example.com"}},
+		ObjectType: "credential",
+		Name:       "Credential Name",
+	}
+	data.UUID = "new-uuid"
+	jsonValue, err := json.Marshal(data.Value)
+	require.NoError(s.T(), err)
+
+	s.mock.ExpectQuery("insert into").
+		WithArgs(data.Name, data.ObjectType, string(jsonValue), data.UUID).
+		WillReturnError(sql.ErrNoRows)
+
+	id, err := s.repository.Add(context.Background(), data)
+	require.Error(s.T(), err)
+	require.Equal(s.T(), int64(0), id)
+}
+
+func (s *CredentialDataRepositoryTestSuite) TestUpdate_ValidData() {
+	data := &models.CredentialData{
+		Value:      models.CredentialDataValueV1{Username: "user", URLs: []string{"https://example.com"}},
+		ObjectType: "credential",
+		Name:       "Updated Credential Name",
+	}
+	data.UUID = "existing-uuid"
+	data.Version = 2
+	jsonValue, err := json.Marshal(data.Value)
+	require.NoError(s.T(), err)
+
+	s.mock.ExpectQuery("update credential_data set name").
+		WithArgs(data.Name, string(jsonValue), data.UUID, int64(2)).
+		WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(3))
+
+	err = s.repository.Update(context.Background(), data)
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), int64(3), data.Version)
+}
+
+func (s *CredentialDataRepositoryTestSuite) TestUpdate_InvalidData() {
+	data := &models.CredentialData{
+		Value:      models.CredentialDataValueV1{Username: "user", URLs: []string{"https://example.com"}},
+		ObjectType: "credential",
+		Name:       "",
+	}
+	data.UUID = "existing-uuid"
+	err := s.repository.Update(context.Background(), data)
+	require.Error(s.T(), err)
+}
+
+func (s *CredentialDataRepositoryTestSuite) TestUpdate_NonExistentUUID() {
+	data := &models.CredentialData{
+		Value:      models.CredentialDataValueV1{Username: "user", URLs: []string{"https://example.com"}},
+		ObjectType: "credential",
+		Name:       "Updated Credential Name",
+	}
+	data.UUID = "non-existent-uuid"
+	jsonValue, err := json.Marshal(data.Value)
+	require.NoError(s.T(), err)
+
+	s.mock.ExpectQuery("update credential_data set name").
+		WithArgs(data.Name, string(jsonValue), data.UUID, int64(0)).
+		WillReturnError(sql.ErrNoRows)
+
+	// нет строки с этой версией: данные изменены или удалены
+	err = s.repository.Update(context.Background(), data)
+	assert.ErrorIs(s.T(), err, ErrVersionConflict)
+}
+
+func (s *CredentialDataRepositoryTestSuite) TestDelete() {
+	s.mock.ExpectQuery("delete from credential_data").
+		WithArgs("test-uuid", int64(2)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	err := s.repository.Delete(context.Background(), "test-uuid", 2)
+	require.NoError(s.T(), err)
+
+	// версия изменена или данных нет
+	s.mock.ExpectQuery("delete from credential_data").
+		WithArgs("test-uuid", int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	err = s.repository.Delete(context.Background(), "test-uuid", 1)
+	assert.ErrorIs(s.T(), err, ErrVersionConflict)
+	require.NoError(s.T(), s.mock.ExpectationsWereMet())
+}
@@ -0,0 +1,264 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/northmule/gophkeeper/internal/common/models"
+	"github.com/northmule/gophkeeper/internal/server/storage"
+)
+
+// DeviceRepository репозитарий устройств пользователей
+type DeviceRepository struct {
+	store storage.DBQuery
+
+	sqlFindOneByUUID     *sql.Stmt
+	sqlFindAllByUserUUID *sql.Stmt
+}
+
+const deviceColumns = `id, uuid, user_uuid, name, public_key, vault_key, client_key, created_at, last_seen_at, revoked_at`
+
+// NewDeviceRepository конструктор
+func NewDeviceRepository(store storage.DBQuery) (*DeviceRepository, error) {
+	var err error
+	instance := new(DeviceRepository)
+	instance.store = store
+	instance.sqlFindOneByUUID, err = store.Prepare(`select ` + deviceColumns + ` from devices where uuid = $1 limit 1`)
+	if err != nil {
+		return nil, ErrorMsg(err)
+	}
+	instance.sqlFindAllByUserUUID, err = store.Prepare(`select ` + deviceColumns + ` from devices where user_uuid = $1 order by id`)
+	if err != nil {
+		return nil, ErrorMsg(err)
+	}
+	return instance, nil
+}
+
+// FindOneByUUID поиск устройства по UUID
+func (r *DeviceRepository) FindOneByUUID(ctx context.Context, uuid string) (*models.Device, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeOut)
+	defer cancel()
+	rows, err := storage.Stmt(ctx, r.sqlFindOneByUUID).QueryContext(ctx, uuid)
+	if err != nil {
+		return nil, ErrorMsg(err)
+	}
+	defer rows.Close()
+	err = rows.Err()
+	if err != nil {
+		return nil, ErrorMsg(err)
+	}
+	if !rows.Next() {
+		return nil, nil
+	}
+	device, err := scanDevice(rows)
+	if err != nil {
+		return nil, ErrorMsg(err)
+	}
+	return device, nil
+}
+
+// FindAllByUserUUID все устройства пользователя, в том числе отозванные
+func (r *DeviceRepository) FindAllByUserUUID(ctx context.Context, userUUID string) ([]models.Device, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeOut)
+	defer cancel()
+	rows, err := storage.Stmt(ctx, r.sqlFindAllByUserUUID).QueryContext(ctx, userUUID)
+	if err != nil {
+		return nil, ErrorMsg(err)
+	}
+	defer rows.Close()
+	err = rows.Err()
+	if err != nil {
+		return nil, ErrorMsg(err)
+	}
+	devices := make([]models.Device, 0)
+	for rows.Next() {
+		device, err := scanDevice(rows)
+		if err != nil {
+			return nil, ErrorMsg(err)
+		}
+		devices = append(devices, *device)
+	}
+	return devices, nil
+}
+
+// Add новое устройство
+func (r *DeviceRepository) Add(ctx context.Context, data *models.Device) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeOut)
+	defer cancel()
+	rows := storage.Query(ctx, r.store).QueryRowContext(ctx, `insert into devices (uuid, user_uuid, name) values ($1, $2, $3) returning id`, data.UUID, data.UserUUID, data.Name)
+	err := rows.Err()
+	if err != nil {
+		return 0, ErrorMsg(err)
+	}
+
+	var id int64
+	err = rows.Scan(&id)
+	if err != nil {
+		return 0, ErrorMsg(err)
+	}
+	return id, nil
+}
+
+// Touch время последнего входа с устройства
+func (r *DeviceRepository) Touch(ctx context.Context, uuid string) error {
+	ctx, cancel := context.WithTimeout(ctx, timeOut)
+	defer cancel()
+	_, err := storage.Query(ctx, r.store).ExecContext(ctx, `update devices set last_seen_at = now() where uuid = $1`, uuid)
+	if err != nil {
+		return ErrorMsg(err)
+	}
+	return nil
+}
+
+// SetKeys публичный ключ устройства и зашифрованный ключ хранилища. Пустой vaultKey оставляет прежнее значение
+func (r *DeviceRepository) SetKeys(ctx context.Context, uuid string, publicKey string, vaultKey string) error {
+	ctx, cancel := context.WithTimeout(ctx, timeOut)
+	defer cancel()
+	_, err := storage.Query(ctx, r.store).ExecContext(
+		ctx,
+		`update devices set public_key = $1, vault_key = coalesce(nullif($2, ''), vault_key) where uuid = $3 and revoked_at is null`,
+		publicKey, vaultKey, uuid,
+	)
+	if err != nil {
+		return ErrorMsg(err)
+	}
+	return nil
+}
+
+// SetClientKey ключ шифрования клиента устройства. Заданный ключ не заменяется, новый ключ задаётся сменой ключа.
+// Вернёт false, если у устройства уже есть ключ или устройство отозвано
+func (r *DeviceRepository) SetClientKey(ctx context.Context, uuid string, clientKey string) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeOut)
+	defer cancel()
+	result, err := storage.Query(ctx, r.store).ExecContext(
+		ctx,
+		`update devices set client_key = $1 where uuid = $2 and client_key = '' and revoked_at is null`,
+		clientKey, uuid,
+	)
+	if err != nil {
+		return false, ErrorMsg(err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, ErrorMsg(err)
+	}
+	return affected > 0, nil
+}
+
+// FindAllClientKeys устройства с сохранённым ключом клиента (заполнены только UUID и ClientKey)
+func (r *DeviceRepository) FindAllClientKeys(ctx context.Context) ([]models.Device, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeOut)
+	defer cancel()
+	rows, err := storage.Query(ctx, r.store).QueryContext(ctx, `select uuid, client_key from devices where client_key <> '' order by id`)
+	if err != nil {
+		return nil, ErrorMsg(err)
+	}
+	defer rows.Close()
+	err = rows.Err()
+	if err != nil {
+		return nil, ErrorMsg(err)
+	}
+	devices := make([]models.Device, 0)
+	for rows.Next() {
+		device := models.Device{}
+		err = rows.Scan(&device.UUID, &device.ClientKey)
+		if err != nil {
+			return nil, ErrorMsg(err)
+		}
+		devices = append(devices, device)
+	}
+	return devices, nil
+}
+
+// ReplaceClientKey замена ключа клиента устройства, если он не изменился с момента чтения
+func (r *DeviceRepository) ReplaceClientKey(ctx context.Context, uuid string, oldValue string, newValue string) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeOut)
+	defer cancel()
+	var id int64
+	err := storage.Query(ctx, r.store).QueryRowContext(ctx, `update devices set client_key = $1 where uuid = $2 and client_key = $3 returning id`, newValue, uuid, oldValue).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, ErrorMsg(err)
+	}
+	return true, nil
+}
+
+// Rename новое имя устройства. Вернёт false, если у пользователя нет такого устройства
+func (r *DeviceRepository) Rename(ctx context.Context, userUUID string, uuid string, name string) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeOut)
+	defer cancel()
+	var id int64
+	err := storage.Query(ctx, r.store).QueryRowContext(ctx, `update devices set name = $1 where uuid = $2 and user_uuid = $3 returning id`, name, uuid, userUUID).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, ErrorMsg(err)
+	}
+	return true, nil
+}
+
+// Revoke отзыв устройства: удаляются ключ хранилища и ключ клиента устройства, закрываются его сессии и отзываются сертификаты.
+// Вернёт false, если у пользователя нет такого действующего устройства
+func (r *DeviceRepository) Revoke(ctx context.Context, userUUID string, uuid string) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeOut)
+	defer cancel()
+	tx, err := storage.BeginTx(ctx, r.store)
+	if err != nil {
+		return false, ErrorMsg(err)
+	}
+	var id int64
+	err = tx.QueryRowContext(
+		ctx,
+		`update devices set revoked_at = now(), vault_key = '', client_key = '' where uuid = $1 and user_uuid = $2 and revoked_at is null returning id`,
+		uuid, userUUID,
+	).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		if err = tx.Rollback(); err != nil {
+			return false, ErrorMsg(err)
+		}
+		return false, nil
+	}
+	if err != nil {
+		return false, ErrorMsg(errors.Join(err, tx.Rollback()))
+	}
+	_, err = tx.ExecContext(ctx, `update sessions set revoked_at = now() where device_uuid = $1 and revoked_at is null`, uuid)
+	if err != nil {
+		return false, ErrorMsg(errors.Join(err, tx.Rollback()))
+	}
+	_, err = tx.ExecContext(ctx, `update client_certificates set revoked_at = now() where device_uuid = $1 and revoked_at is null`, uuid)
+	if err != nil {
+		return false, ErrorMsg(errors.Join(err, tx.Rollback()))
+	}
+	if err = tx.Commit(); err != nil {
+		return false, ErrorMsg(err)
+	}
+	return true, nil
+}
+
+func scanDevice(rows *sql.Rows) (*models.Device, error) {
+	device := new(models.Device)
+	var revokedAt sql.NullTime
+	err := rows.Scan(
+		&device.ID,
+		&device.UUID,
+		&device.UserUUID,
+		&device.Name,
+		&device.PublicKey,
+		&device.VaultKey,
+		&device.ClientKey,
+		&device.CreatedAt,
+		&device.LastSeenAt,
+		&revokedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if revokedAt.Valid {
+		device.RevokedAt = &revokedAt.Time
+	}
+	return device, nil
+}
@@ -0,0 +1,196 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/northmule/gophkeeper/internal/common/models"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"golang.org/x/net/context"
+)
+
+type DeviceRepositoryTestSuite struct {
+	suite.Suite
+	DB         *sql.DB
+	mock       sqlmock.Sqlmock
+	repository *DeviceRepository
+}
+
+var deviceRowColumns = []string{"id", "uuid", "user_uuid", "name", "public_key", "vault_key", "client_key", "created_at", "last_seen_at", "revoked_at"}
+
+func (s *DeviceRepositoryTestSuite) SetupTest() {
+	var err error
+	s.DB, s.mock, err = sqlmock.New()
+	require.NoError(s.T(), err)
+	s.mock.ExpectPrepare("select id, uuid, user_uuid, name")
+	s.mock.ExpectPrepare("select id, uuid, user_uuid, name")
+	s.repository, err = NewDeviceRepository(s.DB)
+	require.NoError(s.T(), err)
+}
+
+func TestDeviceRepositoryTestSuite(t *testing.T) {
+	suite.Run(t, new(DeviceRepositoryTestSuite))
+}
+
+func (s *DeviceRepositoryTestSuite) TestFindOneByUUID() {
+	revokedAt := time.Now()
+	s.mock.ExpectQuery("select").
+		WithArgs("device-uuid").
+		WillReturnRows(sqlmock.NewRows(deviceRowColumns).
+			AddRow(1, "device-uuid", "user-uuid", "laptop", "public key", "", "kek1:k1:key", time.Now(), time.Now(), revokedAt))
+
+	device, err := s.repository.FindOneByUUID(context.Background(), "device-uuid")
+	require.NoError(s.T(), err)
+	require.NotNil(s.T(), device)
+	s.Equal("laptop", device.Name)
+	s.Equal("public key", device.PublicKey)
+	s.Equal("kek1:k1:key", device.ClientKey)
+	s.False(device.IsActive())
+}
+
+func (s *DeviceRepositoryTestSuite) TestFindOneByUUID_NotFound() {
+	s.mock.ExpectQuery("select").
+		WithArgs("device-uuid").
+		WillReturnRows(sqlmock.NewRows(deviceRowColumns))
+
+	device, err := s.repository.FindOneByUUID(context.Background(), "device-uuid")
+	require.NoError(s.T(), err)
+	s.Nil(device)
+}
+
+func (s *DeviceRepositoryTestSuite) TestFindAllByUserUUID() {
+	s.mock.ExpectQuery("select").
+		WithArgs("user-uuid").
+		WillReturnRows(sqlmock.NewRows(deviceRowColumns).
+			AddRow(1, "device-1", "user-uuid", "laptop", "", "", "", time.Now(), time.Now(), nil).
+			AddRow(2, "device-2", "user-uuid", "phone", "", "vault", "", time.Now(), time.Now(), nil))
+
+	devices, err := s.repository.FindAllByUserUUID(context.Background(), "user-uuid")
+	require.NoError(s.T(), err)
+	require.Len(s.T(), devices, 2)
+	s.Equal("device-2", devices[1].UUID)
+	s.True(devices[1].IsActive())
+}
+
+func (s *DeviceRepositoryTestSuite) TestAdd() {
+	s.mock.ExpectQuery("insert into devices").
+		WithArgs("device-uuid", "user-uuid", "laptop").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+
+	id, err := s.repository.Add(context.Background(), &models.Device{Common: models.Common{UUID: "device-uuid"}, UserUUID: "user-uuid", Name: "laptop"})
+	require.NoError(s.T(), err)
+	s.Equal(int64(3), id)
+}
+
+func (s *DeviceRepositoryTestSuite) TestSetKeys() {
+	s.mock.ExpectExec("update devices set public_key").
+		WithArgs("public key", "vault", "device-uuid").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err := s.repository.SetKeys(context.Background(), "device-uuid", "public key", "vault")
+	require.NoError(s.T(), err)
+}
+
+func (s *DeviceRepositoryTestSuite) TestSetClientKey() {
+	s.mock.ExpectExec("update devices set client_key").
+		WithArgs("kek1:k1:key", "device-uuid").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	s.mock.ExpectExec("update devices set client_key").
+		WithArgs("kek1:k1:other", "device-uuid").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	saved, err := s.repository.SetClientKey(context.Background(), "device-uuid", "kek1:k1:key")
+	require.NoError(s.T(), err)
+	s.True(saved)
+
+	// ключ уже задан
+	saved, err = s.repository.SetClientKey(context.Background(), "device-uuid", "kek1:k1:other")
+	require.NoError(s.T(), err)
+	s.False(saved)
+}
+
+func (s *DeviceRepositoryTestSuite) TestFindAllClientKeysAndReplace() {
+	s.mock.ExpectQuery("select uuid, client_key from devices").
+		WillReturnRows(sqlmock.NewRows([]string{"uuid", "client_key"}).AddRow("device-uuid", "kek1:k1:old"))
+	s.mock.ExpectQuery("update devices set client_key").
+		WithArgs("kek1:k2:new", "device-uuid", "kek1:k1:old").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	s.mock.ExpectQuery("update devices set client_key").
+		WithArgs("kek1:k2:new", "device-uuid", "kek1:k1:changed").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	devices, err := s.repository.FindAllClientKeys(context.Background())
+	require.NoError(s.T(), err)
+	require.Len(s.T(), devices, 1)
+	s.Equal("kek1:k1:old", devices[0].ClientKey)
+
+	replaced, err := s.repository.ReplaceClientKey(context.Background(), "device-uuid", "kek1:k1:old", "kek1:k2:new")
+	require.NoError(s.T(), err)
+	s.True(replaced)
+
+	replaced, err = s.repository.ReplaceClientKey(context.Background(), "device-uuid", "kek1:k1:changed", "kek1:k2:new")
+	require.NoError(s.T(), err)
+	s.False(replaced)
+}
+
+func (s *DeviceRepositoryTestSuite) TestRename() {
+	s.mock.ExpectQuery("update devices set name").
+		WithArgs("work laptop", "device-uuid", "user-uuid").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	s.mock.ExpectQuery("update devices set name").
+		WithArgs("work laptop", "device-uuid", "other-user").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	renamed, err := s.repository.Rename(context.Background(), "user-uuid", "device-uuid", "work laptop")
+	require.NoError(s.T(), err)
+	s.True(renamed)
+
+	renamed, err = s.repository.Rename(context.Background(), "other-user", "device-uuid", "work laptop")
+	require.NoError(s.T(), err)
+	s.False(renamed)
+}
+
+func (s *DeviceRepositoryTestSuite) TestRevoke() {
+	s.mock.ExpectBegin()
+	s.mock.ExpectQuery("update devices set revoked_at").
+		WithArgs("device-uuid", "user-uuid").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	s.mock.ExpectExec("update sessions set revoked_at").WithArgs("device-uuid").WillReturnResult(sqlmock.NewResult(0, 2))
+	s.mock.ExpectExec("update client_certificates set revoked_at").WithArgs("device-uuid").WillReturnResult(sqlmock.NewResult(0, 1))
+	s.mock.ExpectCommit()
+
+	revoked, err := s.repository.Revoke(context.Background(), "user-uuid", "device-uuid")
+	require.NoError(s.T(), err)
+	s.True(revoked)
+	require.NoError(s.T(), s.mock.ExpectationsWereMet())
+}
+
+func (s *DeviceRepositoryTestSuite) TestRevoke_NotFound() {
+	s.mock.ExpectBegin()
+	s.mock.ExpectQuery("update devices set revoked_at").
+		WithArgs("device-uuid", "user-uuid").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	s.mock.ExpectRollback()
+
+	revoked, err := s.repository.Revoke(context.Background(), "user-uuid", "device-uuid")
+	require.NoError(s.T(), err)
+	s.False(revoked)
+	require.NoError(s.T(), s.mock.ExpectationsWereMet())
+}
+
+func (s *DeviceRepositoryTestSuite) TestRevoke_Error() {
+	s.mock.ExpectBegin()
+	s.mock.ExpectQuery("update devices set revoked_at").
+		WithArgs("device-uuid", "user-uuid").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	s.mock.ExpectExec("update sessions set revoked_at").WithArgs("device-uuid").WillReturnError(errors.New("error"))
+	s.mock.ExpectRollback()
+
+	_, err := s.repository.Revoke(context.Background(), "user-uuid", "device-uuid")
+	require.Error(s.T(), err)
+	require.NoError(s.T(), s.mock.ExpectationsWereMet())
+}
@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/northmule/gophkeeper/internal/common/models"
+	"github.com/northmule/gophkeeper/internal/server/storage"
+)
+
+// FileChunkRepository репозитарий принятых частей файлов
+type FileChunkRepository struct {
+	store storage.DBQuery
+
+	sqlFindAllByFileUUID *sql.Stmt
+}
+
+// NewFileChunkRepository конструктор
+func NewFileChunkRepository(store storage.DBQuery) (*FileChunkRepository, error) {
+	var err error
+	instance := new(FileChunkRepository)
+	instance.store = store
+	instance.sqlFindAllByFileUUID, err = store.Prepare(`select id, file_uuid, part, "size", checksum from file_chunks where file_uuid = $1 order by part`)
+	if err != nil {
+		return nil, ErrorMsg(err)
+	}
+	return instance, nil
+}
+
+// FindAllByFileUUID принятые части файла по возрастанию номера
+func (r *FileChunkRepository) FindAllByFileUUID(ctx context.Context, fileUUID string) ([]models.FileChunk, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeOut)
+	defer cancel()
+	rows, err := storage.Stmt(ctx, r.sqlFindAllByFileUUID).QueryContext(ctx, fileUUID)
+	if err != nil {
+		return nil, ErrorMsg(err)
+	}
+	defer rows.Close()
+	chunks := make([]models.FileChunk, 0)
+	for rows.Next() {
+		chunk := models.FileChunk{}
+		err = rows.Scan(&chunk.ID, &chunk.FileUUID, &chunk.Part, &chunk.Size, &chunk.Checksum)
+		if err != nil {
+			return nil, ErrorMsg(err)
+		}
+		chunks = append(chunks, chunk)
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, ErrorMsg(err)
+	}
+	return chunks, nil
+}
+
+// Save запись о принятой части, повтор части заменяет прежнюю запись
+func (r *FileChunkRepository) Save(ctx context.Context, chunk *models.FileChunk) error {
+	ctx, cancel := context.WithTimeout(ctx, timeOut)
+	defer cancel()
+	_, err := storage.Query(ctx, r.store).ExecContext(
+		ctx,
+		`insert into file_chunks (file_uuid, part, "size", checksum) values ($1, $2, $3, $4)
+			on conflict (file_uuid, part) do update set "size" = excluded."size", checksum = excluded.checksum, created_at = now()`,
+		chunk.FileUUID, chunk.Part, chunk.Size, chunk.Checksum,
+	)
+	if err != nil {
+		return ErrorMsg(err)
+	}
+	return nil
+}
+
+// DeleteByFileUUID удаление записей о частях файла (новое содержимое загружается заново)
+func (r *FileChunkRepository) DeleteByFileUUID(ctx context.Context, fileUUID string) error {
+	ctx, cancel := context.WithTimeout(ctx, timeOut)
+	defer cancel()
+	_, err := storage.Query(ctx, r.store).ExecContext(ctx, `delete from file_chunks where file_uuid = $1`, fileUUID)
+	if err != nil {
+		return ErrorMsg(err)
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/northmule/gophkeeper/internal/common/models"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"golang.org/x/net/context"
+)
+
+type FileChunkRepositoryTestSuite struct {
+	suite.Suite
+	DB         *sql.DB
+	mock       sqlmock.Sqlmock
+	repository *FileChunkRepository
+}
+
+func (s *FileChunkRepositoryTestSuite) SetupTest() {
+	var err error
+	s.DB, s.mock, err = sqlmock.New()
+	require.NoError(s.T(), err)
+	s.mock.ExpectPrepare("select id, file_uuid, part")
+	s.repository, err = NewFileChunkRepository(s.DB)
+	require.NoError(s.T(), err)
+}
+
+func TestFileChunkRepositoryTestSuite(t *testing.T) {
+	suite.Run(t, new(FileChunkRepositoryTestSuite))
+}
+
+func (s *FileChunkRepositoryTestSuite) TestFindAllByFileUUID() {
+	s.mock.ExpectQuery("select").
+		WithArgs("file-uuid").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "file_uuid", "part", "size", "checksum"}).
+			AddRow(1, "file-uuid", 0, 100, "aa").
+			AddRow(2, "file-uuid", 1, 50, "bb"))
+
+	chunks, err := s.repository.FindAllByFileUUID(context.Background(), "file-uuid")
+	require.NoError(s.T(), err)
+	require.Len(s.T(), chunks, 2)
+	require.Equal(s.T(), models.FileChunk{ID: 2, FileUUID: "file-uuid", Part: 1, Size: 50, Checksum: "bb"}, chunks[1])
+}
+
+func (s *FileChunkRepositoryTestSuite) TestFindAllByFileUUID_Error() {
+	s.mock.ExpectQuery("select").
+		WithArgs("file-uuid").
+		WillReturnError(errors.New("connection refused"))
+
+	chunks, err := s.repository.FindAllByFileUUID(context.Background(), "file-uuid")
+	require.Error(s.T(), err)
+	require.Nil(s.T(), chunks)
+}
+
+func (s *FileChunkRepositoryTestSuite) TestSave() {
+	chunk := &models.FileChunk{FileUUID: "file-uuid", Part: 3, Size: 100, Checksum: "aa"}
+	s.mock.ExpectExec("insert into file_chunks").
+		WithArgs(chunk.FileUUID, chunk.Part, chunk.Size, chunk.Checksum).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	err := s.repository.Save(context.Background(), chunk)
+	require.NoError(s.T(), err)
+	require.NoError(s.T(), s.mock.ExpectationsWereMet())
+}
+
+func (s *FileChunkRepositoryTestSuite) TestDeleteByFileUUID() {
+	s.mock.ExpectExec("delete from file_chunks").
+		WithArgs("file-uuid").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+
+	err := s.repository.DeleteByFileUUID(context.Background(), "file-uuid")
+	require.NoError(s.T(), err)
+	require.NoError(s.T(), s.mock.ExpectationsWereMet())
+}
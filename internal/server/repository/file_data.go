@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"errors"
 
 	"github.com/northmule/gophkeeper/internal/common/models"
 	"github.com/northmule/gophkeeper/internal/server/storage"
@@ -20,7 +21,7 @@ func NewFileDataRepository(store storage.DBQuery) (*FileDataRepository, error) {
 	var err error
 	instance := new(FileDataRepository)
 	instance.store = store
-	instance.sqlFindOneByUUID, err = store.Prepare(`select id, name, uuid, mime_type, path, path_tmp, extension, file_name, "size", storage, uploaded from file_data where uuid = $1 limit 1`)
+	instance.sqlFindOneByUUID, err = store.Prepare(`select id, name, uuid, mime_type, path, path_tmp, extension, file_name, "size", storage, uploaded, version, chunks from file_data where uuid = $1 limit 1`)
 	if err != nil {
 		return nil, ErrorMsg(err)
 	}
@@ -32,7 +33,7 @@ func (r *FileDataRepository) FindOneByUUID(ctx context.Context, uuid string) (*m
 
 	ctx, cancel := context.WithTimeout(ctx, timeOut)
 	defer cancel()
-	rows, err := r.sqlFindOneByUUID.QueryContext(ctx, uuid)
+	rows, err := storage.Stmt(ctx, r.sqlFindOneByUUID).QueryContext(ctx, uuid)
 	if err != nil {
 		return nil, ErrorMsg(err)
 	}
@@ -42,7 +43,7 @@ func (r *FileDataRepository) FindOneByUUID(ctx context.Context, uuid string) (*m
 	}
 	data := new(models.FileData)
 	if rows.Next() {
-		err = rows.Scan(&data.ID, &data.Name, &data.UUID, &data.MimeType, &data.Path, &data.PathTmp, &data.Extension, &data.FileName, &data.Size, &data.Storage, &data.Uploaded)
+		err = rows.Scan(&data.ID, &data.Name, &data.UUID, &data.MimeType, &data.Path, &data.PathTmp, &data.Extension, &data.FileName, &data.Size, &data.Storage, &data.Uploaded, &data.Version, &data.Chunks)
 		if err != nil {
 			return nil, ErrorMsg(err)
 		}
@@ -56,7 +57,7 @@ func (r *FileDataRepository) FindOneByUUID(ctx context.Context, uuid string) (*m
 func (r *FileDataRepository) Add(ctx context.Context, data *models.FileData) (int64, error) {
 	ctx, cancel := context.WithTimeout(ctx, timeOut)
 	defer cancel()
-	rows := r.store.QueryRowContext(ctx, `insert into file_data (name, uuid, mime_type, path, path_tmp, extension, file_name, "size", storage, uploaded) values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) returning id`, data.Name, data.UUID, data.MimeType, data.Path, data.PathTmp, data.Extension, data.FileName, data.Size, data.Storage, data.Uploaded)
+	rows := storage.Query(ctx, r.store).QueryRowContext(ctx, `insert into file_data (name, uuid, mime_type, path, path_tmp, extension, file_name, "size", storage, uploaded, chunks) values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) returning id`, data.Name, data.UUID, data.MimeType, data.Path, data.PathTmp, data.Extension, data.FileName, data.Size, data.Storage, data.Uploaded, data.Chunks)
 	err := rows.Err()
 	if err != nil {
 		return 0, ErrorMsg(err)
@@ -70,11 +71,40 @@ func (r *FileDataRepository) Add(ctx context.Context, data *models.FileData) (in
 	return id, nil
 }
 
-// Update Обновление основных полей
+// Update Обновление основных полей, если версия данных не изменилась с data.Version.
+// Новая версия записывается в data.Version, иначе вернёт ErrVersionConflict
 func (r *FileDataRepository) Update(ctx context.Context, data *models.FileData) error {
 	ctx, cancel := context.WithTimeout(ctx, timeOut)
 	defer cancel()
-	rows := r.store.QueryRowContext(ctx, `update file_data set name = $1, mime_type = $2, path = $3, extension = $4, file_name = $5, size = $6, storage = $7, uploaded = $8 where uuid = $9`, data.Name, data.MimeType, data.Path, data.Extension, data.FileName, data.Size, data.Storage, data.Uploaded, data.UUID)
+	err := storage.Query(ctx, r.store).QueryRowContext(ctx, `update file_data set name = $1, mime_type = $2, path = $3, extension = $4, file_name = $5, size = $6, storage = $7, uploaded = $8, chunks = $9, version = version + 1 where uuid = $10 and version = $11 returning version`, data.Name, data.MimeType, data.Path, data.Extension, data.FileName, data.Size, data.Storage, data.Uploaded, data.Chunks, data.UUID, data.Version).Scan(&data.Version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrVersionConflict
+	}
+	if err != nil {
+		return ErrorMsg(err)
+	}
+	return nil
+}
 
-	return rows.Err()
+// Lock блокирует строку файла до конца транзакции запроса: части одного файла принимаются по очереди
+func (r *FileDataRepository) Lock(ctx context.Context, uuid string) error {
+	ctx, cancel := context.WithTimeout(ctx, timeOut)
+	defer cancel()
+	var id int64
+	err := storage.Query(ctx, r.store).QueryRowContext(ctx, `select id from file_data where uuid = $1 for update`, uuid).Scan(&id)
+	if err != nil {
+		return ErrorMsg(err)
+	}
+	return nil
+}
+
+// SetUploaded отметка о полной загрузке содержимого, версия данных не меняется
+func (r *FileDataRepository) SetUploaded(ctx context.Context, uuid string, uploaded bool) error {
+	ctx, cancel := context.WithTimeout(ctx, timeOut)
+	defer cancel()
+	_, err := storage.Query(ctx, r.store).ExecContext(ctx, `update file_data set uploaded = $1 where uuid = $2`, uploaded, uuid)
+	if err != nil {
+		return ErrorMsg(err)
+	}
+	return nil
 }
@@ -49,8 +49,8 @@ func (s *FileDataRepositoryTestSuite) TestFindOneByUUID_ValidUUID() {
 	expectedData.UUID = uuid
 	s.mock.ExpectQuery("select").
 		WithArgs(uuid).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "uuid", "mime_type", "path", "path_tmp", "extension", "file_name", "size", "storage", "uploaded"}).
-			AddRow(expectedData.ID, expectedData.Name, expectedData.UUID, expectedData.MimeType, expectedData.Path, expectedData.PathTmp, expectedData.Extension, expectedData.FileName, expectedData.Size, expectedData.Storage, expectedData.Uploaded))
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "uuid", "mime_type", "path", "path_tmp", "extension", "file_name", "size", "storage", "uploaded", "version", "chunks"}).
+			AddRow(expectedData.ID, expectedData.Name, expectedData.UUID, expectedData.MimeType, expectedData.Path, expectedData.PathTmp, expectedData.Extension, expectedData.FileName, expectedData.Size, expectedData.Storage, expectedData.Uploaded, expectedData.Version, expectedData.Chunks))
 
 	data, err := s.repository.FindOneByUUID(context.Background(), uuid)
 	require.NoError(s.T(), err)
@@ -62,7 +62,7 @@ func (s *FileDataRepositoryTestSuite) TestFindOneByUUID_InvalidUUID() {
 
 	s.mock.ExpectQuery("select").
 		WithArgs(uuid).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "uuid", "mime_type", "path", "path_tmp", "extension", "file_name", "size", "storage", "uploaded"}))
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "uuid", "mime_type", "path", "path_tmp", "extension", "file_name", "size", "storage", "uploaded", "version", "chunks"}))
 
 	data, err := s.repository.FindOneByUUID(context.Background(), uuid)
 	require.NoError(s.T(), err)
@@ -91,7 +91,7 @@ func (s *FileDataRepositoryTestSuite) TestAdd_ValidData() {
 	}
 	data.UUID = "new-uuid"
 	s.mock.ExpectQuery("insert into").
-		WithArgs(data.Name, data.UUID, data.MimeType, data.Path, data.PathTmp, data.Extension, data.FileName, data.Size, data.Storage, data.Uploaded).
+		WithArgs(data.Name, data.UUID, data.MimeType, data.Path, data.PathTmp, data.Extension, data.FileName, data.Size, data.Storage, data.Uploaded, data.Chunks).
 		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
 
 	id, err := s.repository.Add(context.Background(), data)
@@ -131,7 +131,7 @@ func (s *FileDataRepositoryTestSuite) TestAdd_DuplicateUUID() {
 	}
 	data.UUID = "existing-uuid"
 	s.mock.ExpectQuery("insert into").
-		WithArgs(data.Name, data.UUID, data.MimeType, data.Path, data.PathTmp, data.Extension, data.FileName, data.Size, data.Storage, data.Uploaded).
+		WithArgs(data.Name, data.UUID, data.MimeType, data.Path, data.PathTmp, data.Extension, data.FileName, data.Size, data.Storage, data.Uploaded, data.Chunks).
 		WillReturnError(sql.ErrNoRows)
 
 	id, err := s.repository.Add(context.Background(), data)
@@ -153,9 +153,35 @@ func (s *FileDataRepositoryTestSuite) TestUpdate_ValidData() {
 	}
 	data.UUID = "existing-uuid"
 	s.mock.ExpectQuery("update file_data").
-		WithArgs(data.Name, data.MimeType, data.Path, data.Extension, data.FileName, data.Size, data.Storage, data.Uploaded, data.UUID).
-		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		WithArgs(data.Name, data.MimeType, data.Path, data.Extension, data.FileName, data.Size, data.Storage, data.Uploaded, data.Chunks, data.UUID, int64(0)).
+		WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(1))
 
 	err := s.repository.Update(context.Background(), data)
 	require.NoError(s.T(), err)
 }
+
+func (s *FileDataRepositoryTestSuite) TestLock() {
+	s.mock.ExpectQuery("select id from file_data where uuid = \\$1 for update").
+		WithArgs("existing-uuid").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+
+	err := s.repository.Lock(context.Background(), "existing-uuid")
+	require.NoError(s.T(), err)
+
+	s.mock.ExpectQuery("select id from file_data").
+		WithArgs("missing-uuid").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	err = s.repository.Lock(context.Background(), "missing-uuid")
+	require.Error(s.T(), err)
+}
+
+func (s *FileDataRepositoryTestSuite) TestSetUploaded() {
+	s.mock.ExpectExec("update file_data set uploaded").
+		WithArgs(true, "existing-uuid").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err := s.repository.SetUploaded(context.Background(), "existing-uuid", true)
+	require.NoError(s.T(), err)
+	require.NoError(s.T(), s.mock.ExpectationsWereMet())
+}
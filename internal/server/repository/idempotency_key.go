@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/northmule/gophkeeper/internal/common/models"
+	"github.com/northmule/gophkeeper/internal/server/storage"
+)
+
+// IdempotencyKeyRepository репозитарий ключей идемпотентности запросов сохранения
+type IdempotencyKeyRepository struct {
+	store storage.DBQuery
+
+	sqlFindOne *sql.Stmt
+}
+
+// NewIdempotencyKeyRepository конструктор
+func NewIdempotencyKeyRepository(store storage.DBQuery) (*IdempotencyKeyRepository, error) {
+	var err error
+	instance := new(IdempotencyKeyRepository)
+	instance.store = store
+	instance.sqlFindOne, err = store.Prepare(`select id, user_uuid, idempotency_key, request_hash, coalesce(status_code, 0), coalesce(response, ''), created_at
+		from idempotency_keys where user_uuid = $1 and idempotency_key = $2 limit 1`)
+	if err != nil {
+		return nil, ErrorMsg(err)
+	}
+	return instance, nil
+}
+
+// FindOne ключ пользователя, nil если ключ не использовался
+func (r *IdempotencyKeyRepository) FindOne(ctx context.Context, userUUID string, key string) (*models.IdempotencyKey, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeOut)
+	defer cancel()
+	rows, err := storage.Stmt(ctx, r.sqlFindOne).QueryContext(ctx, userUUID, key)
+	if err != nil {
+		return nil, ErrorMsg(err)
+	}
+	defer rows.Close()
+	err = rows.Err()
+	if err != nil {
+		return nil, ErrorMsg(err)
+	}
+	if !rows.Next() {
+		return nil, nil
+	}
+	idempotencyKey := new(models.IdempotencyKey)
+	err = rows.Scan(
+		&idempotencyKey.ID,
+		&idempotencyKey.UserUUID,
+		&idempotencyKey.Key,
+		&idempotencyKey.RequestHash,
+		&idempotencyKey.StatusCode,
+		&idempotencyKey.Response,
+		&idempotencyKey.CreatedAt,
+	)
+	if err != nil {
+		return nil, ErrorMsg(err)
+	}
+	return idempotencyKey, nil
+}
+
+// Reserve занимает ключ до выполнения запроса. Ключи пользователя старше expiredBefore удаляются.
+// Ключ без ответа, занятый раньше leaseExpiredBefore (запрос прерван остановкой сервера), занимается заново.
+// Вернёт false, если ключ уже занят
+func (r *IdempotencyKeyRepository) Reserve(ctx context.Context, data *models.IdempotencyKey, expiredBefore time.Time, leaseExpiredBefore time.Time) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeOut)
+	defer cancel()
+	_, err := storage.Query(ctx, r.store).ExecContext(ctx, `delete from idempotency_keys where user_uuid = $1 and created_at < $2`, data.UserUUID, expiredBefore)
+	if err != nil {
+		return false, ErrorMsg(err)
+	}
+	var id int64
+	err = storage.Query(ctx, r.store).QueryRowContext(
+		ctx,
+		`insert into idempotency_keys (user_uuid, idempotency_key, request_hash) values ($1, $2, $3)
+			on conflict (user_uuid, idempotency_key) do update
+				set request_hash = excluded.request_hash, reserved_at = now(), created_at = now()
+				where idempotency_keys.status_code is null and idempotency_keys.reserved_at < $4
+			returning id`,
+		data.UserUUID, data.Key, data.RequestHash, leaseExpiredBefore,
+	).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, ErrorMsg(err)
+	}
+	data.ID = id
+	return true, nil
+}
+
+// Complete сохраняет ответ на запрос с ключом
+func (r *IdempotencyKeyRepository) Complete(ctx context.Context, userUUID string, key string, statusCode int, response string) error {
+	ctx, cancel := context.WithTimeout(ctx, timeOut)
+	defer cancel()
+	_, err := storage.Query(ctx, r.store).ExecContext(
+		ctx,
+		`update idempotency_keys set status_code = $1, response = $2 where user_uuid = $3 and idempotency_key = $4`,
+		statusCode, response, userUUID, key,
+	)
+	if err != nil {
+		return ErrorMsg(err)
+	}
+	return nil
+}
+
+// Release освобождает ключ запроса, завершившегося ошибкой, чтобы его можно было повторить
+func (r *IdempotencyKeyRepository) Release(ctx context.Context, userUUID string, key string) error {
+	ctx, cancel := context.WithTimeout(ctx, timeOut)
+	defer cancel()
+	_, err := storage.Query(ctx, r.store).ExecContext(
+		ctx,
+		`delete from idempotency_keys where user_uuid = $1 and idempotency_key = $2 and status_code is null`,
+		userUUID, key,
+	)
+	if err != nil {
+		return ErrorMsg(err)
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/northmule/gophkeeper/internal/common/models"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"golang.org/x/net/context"
+)
+
+type IdempotencyKeyRepositoryTestSuite struct {
+	suite.Suite
+	DB         *sql.DB
+	mock       sqlmock.Sqlmock
+	repository *IdempotencyKeyRepository
+}
+
+func (s *IdempotencyKeyRepositoryTestSuite) SetupTest() {
+	var err error
+	s.DB, s.mock, err = sqlmock.New()
+	require.NoError(s.T(), err)
+	s.mock.ExpectPrepare("select id, user_uuid, idempotency_key")
+	s.repository, err = NewIdempotencyKeyRepository(s.DB)
+	require.NoError(s.T(), err)
+}
+
+func TestIdempotencyKeyRepositoryTestSuite(t *testing.T) {
+	suite.Run(t, new(IdempotencyKeyRepositoryTestSuite))
+}
+
+func (s *IdempotencyKeyRepositoryTestSuite) TestFindOne() {
+	s.mock.ExpectQuery("select").
+		WithArgs("user-uuid", "key").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_uuid", "idempotency_key", "request_hash", "status_code", "response", "created_at"}).
+			AddRow(1, "user-uuid", "key", "hash", 200, "{}", time.Now()))
+
+	idempotencyKey, err := s.repository.FindOne(context.Background(), "user-uuid", "key")
+	require.NoError(s.T(), err)
+	require.NotNil(s.T(), idempotencyKey)
+	s.Equal("hash", idempotencyKey.RequestHash)
+	s.Equal(200, idempotencyKey.StatusCode)
+	s.Equal("{}", idempotencyKey.Response)
+}
+
+func (s *IdempotencyKeyRepositoryTestSuite) TestFindOne_NotFound() {
+	s.mock.ExpectQuery("select").
+		WithArgs("user-uuid", "key").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_uuid", "idempotency_key", "request_hash", "status_code", "response", "created_at"}))
+
+	idempotencyKey, err := s.repository.FindOne(context.Background(), "user-uuid", "key")
+	require.NoError(s.T(), err)
+	s.Nil(idempotencyKey)
+}
+
+func (s *IdempotencyKeyRepositoryTestSuite) TestReserve() {
+	expiredBefore := time.Now().Add(-time.Hour)
+	leaseExpiredBefore := time.Now().Add(-time.Minute)
+	s.mock.ExpectExec("delete from idempotency_keys").
+		WithArgs("user-uuid", expiredBefore).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	// ключ без ответа с истёкшей арендой занимается заново
+	s.mock.ExpectQuery("insert into idempotency_keys .+ on conflict .+ do update .+ where idempotency_keys.status_code is null and idempotency_keys.reserved_at < \\$4").
+		WithArgs("user-uuid", "key", "hash", leaseExpiredBefore).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+
+	data := &models.IdempotencyKey{UserUUID: "user-uuid", Key: "key", RequestHash: "hash"}
+	reserved, err := s.repository.Reserve(context.Background(), data, expiredBefore, leaseExpiredBefore)
+	require.NoError(s.T(), err)
+	s.True(reserved)
+	s.Equal(int64(7), data.ID)
+}
+
+func (s *IdempotencyKeyRepositoryTestSuite) TestReserve_Taken() {
+	s.mock.ExpectExec("delete from idempotency_keys").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	s.mock.ExpectQuery("insert into idempotency_keys").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	reserved, err := s.repository.Reserve(context.Background(), &models.IdempotencyKey{UserUUID: "user-uuid", Key: "key"}, time.Now(), time.Now())
+	require.NoError(s.T(), err)
+	s.False(reserved)
+}
+
+func (s *IdempotencyKeyRepositoryTestSuite) TestReserve_Error() {
+	s.mock.ExpectExec("delete from idempotency_keys").
+		WillReturnError(errors.New("db error"))
+
+	_, err := s.repository.Reserve(context.Background(), &models.IdempotencyKey{UserUUID: "user-uuid", Key: "key"}, time.Now(), time.Now())
+	s.Error(err)
+}
+
+func (s *IdempotencyKeyRepositoryTestSuite) TestComplete() {
+	s.mock.ExpectExec("update idempotency_keys set status_code").
+		WithArgs(200, "{}", "user-uuid", "key").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err := s.repository.Complete(context.Background(), "user-uuid", "key", 200, "{}")
+	require.NoError(s.T(), err)
+	s.NoError(s.mock.ExpectationsWereMet())
+}
+
+func (s *IdempotencyKeyRepositoryTestSuite) TestRelease() {
+	s.mock.ExpectExec("delete from idempotency_keys").
+		WithArgs("user-uuid", "key").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err := s.repository.Release(context.Background(), "user-uuid", "key")
+	require.NoError(s.T(), err)
+	s.NoError(s.mock.ExpectationsWereMet())
+}
@@ -0,0 +1,162 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/northmule/gophkeeper/internal/common/models"
+	"github.com/northmule/gophkeeper/internal/server/storage"
+)
+
+// KeyRotationRepository репозитарий смен ключа шифрования клиентов
+type KeyRotationRepository struct {
+	store               storage.DBQuery
+	sqlFindLastByDevice *sql.Stmt
+	sqlFindAllRunning   *sql.Stmt
+	sqlFail             *sql.Stmt
+}
+
+const keyRotationColumns = `id, uuid, user_uuid, coalesce(device_uuid::text, ''), new_client_key, status, error, created_at, updated_at, finished_at`
+
+// NewKeyRotationRepository конструктор
+func NewKeyRotationRepository(store storage.DBQuery) (*KeyRotationRepository, error) {
+	var err error
+	instance := new(KeyRotationRepository)
+	instance.store = store
+	instance.sqlFindLastByDevice, err = store.Prepare(`select ` + keyRotationColumns + ` from key_rotations
+		where user_uuid = $1 and coalesce(device_uuid::text, '') = $2 order by id desc limit 1`)
+	if err != nil {
+		return nil, ErrorMsg(err)
+	}
+	instance.sqlFindAllRunning, err = store.Prepare(`select ` + keyRotationColumns + ` from key_rotations where status = 'running' order by id`)
+	if err != nil {
+		return nil, ErrorMsg(err)
+	}
+	instance.sqlFail, err = store.Prepare(`update key_rotations set status = 'failed', error = $1, new_client_key = '', updated_at = now(), finished_at = now() where uuid = $2 and status = 'running'`)
+	if err != nil {
+		return nil, ErrorMsg(err)
+	}
+	return instance, nil
+}
+
+// FindLastByDeviceUUID последняя смена ключа устройства пользователя, nil если ключ не менялся.
+// Пустой deviceUUID - смены ключа пользователя
+func (r *KeyRotationRepository) FindLastByDeviceUUID(ctx context.Context, userUUID string, deviceUUID string) (*models.KeyRotation, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeOut)
+	defer cancel()
+	rows, err := storage.Stmt(ctx, r.sqlFindLastByDevice).QueryContext(ctx, userUUID, deviceUUID)
+	if err != nil {
+		return nil, ErrorMsg(err)
+	}
+	defer rows.Close()
+	err = rows.Err()
+	if err != nil {
+		return nil, ErrorMsg(err)
+	}
+	if !rows.Next() {
+		return nil, nil
+	}
+	return scanKeyRotation(rows)
+}
+
+// FindAllRunning незавершённые смены ключа (продолжаются после перезапуска сервера)
+func (r *KeyRotationRepository) FindAllRunning(ctx context.Context) ([]models.KeyRotation, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeOut)
+	defer cancel()
+	rows, err := storage.Stmt(ctx, r.sqlFindAllRunning).QueryContext(ctx)
+	if err != nil {
+		return nil, ErrorMsg(err)
+	}
+	defer rows.Close()
+	err = rows.Err()
+	if err != nil {
+		return nil, ErrorMsg(err)
+	}
+	rotations := make([]models.KeyRotation, 0)
+	for rows.Next() {
+		rotation, err := scanKeyRotation(rows)
+		if err != nil {
+			return nil, err
+		}
+		rotations = append(rotations, *rotation)
+	}
+	return rotations, nil
+}
+
+// Add новая смена ключа
+func (r *KeyRotationRepository) Add(ctx context.Context, data *models.KeyRotation) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeOut)
+	defer cancel()
+	var id int64
+	err := storage.Query(ctx, r.store).QueryRowContext(
+		ctx,
+		`insert into key_rotations (uuid, user_uuid, device_uuid, new_client_key, status) values ($1, $2, nullif($3, '')::uuid, $4, 'running') returning id`,
+		data.UUID, data.UserUUID, data.DeviceUUID, data.NewClientKey,
+	).Scan(&id)
+	if err != nil {
+		return 0, ErrorMsg(err)
+	}
+	return id, nil
+}
+
+// Fail остановка смены ключа с ошибкой, у пользователя остаётся прежний ключ
+func (r *KeyRotationRepository) Fail(ctx context.Context, uuid string, reason string) error {
+	ctx, cancel := context.WithTimeout(ctx, timeOut)
+	defer cancel()
+	_, err := storage.Stmt(ctx, r.sqlFail).ExecContext(ctx, reason, uuid)
+	if err != nil {
+		return ErrorMsg(err)
+	}
+	return nil
+}
+
+// Finish завершение смены: новый ключ становится ключом устройства (пользователя для смены без устройства), прежний удаляется
+func (r *KeyRotationRepository) Finish(ctx context.Context, rotation *models.KeyRotation) error {
+	ctx, cancel := context.WithTimeout(ctx, timeOut)
+	defer cancel()
+	tx, err := storage.BeginTx(ctx, r.store)
+	if err != nil {
+		return ErrorMsg(err)
+	}
+	var id int64
+	err = tx.QueryRowContext(
+		ctx,
+		`update key_rotations set status = 'finished', new_client_key = '', updated_at = now(), finished_at = now() where uuid = $1 and status = 'running' returning id`,
+		rotation.UUID,
+	).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrorMsg(errors.Join(errors.New("the key rotation is not running"), tx.Rollback()))
+	}
+	if err != nil {
+		return ErrorMsg(errors.Join(err, tx.Rollback()))
+	}
+	if rotation.DeviceUUID != "" {
+		_, err = tx.ExecContext(ctx, `update devices set client_key = $1 where uuid = $2 and user_uuid = $3`, rotation.NewClientKey, rotation.DeviceUUID, rotation.UserUUID)
+	} else {
+		_, err = tx.ExecContext(ctx, `update users set private_client_key = $1 where uuid = $2`, rotation.NewClientKey, rotation.UserUUID)
+	}
+	if err != nil {
+		return ErrorMsg(errors.Join(err, tx.Rollback()))
+	}
+	if err = tx.Commit(); err != nil {
+		return ErrorMsg(err)
+	}
+	return nil
+}
+
+func scanKeyRotation(rows *sql.Rows) (*models.KeyRotation, error) {
+	rotation := new(models.KeyRotation)
+	var finishedAt sql.NullTime
+	err := rows.Scan(
+		&rotation.ID, &rotation.UUID, &rotation.UserUUID, &rotation.DeviceUUID, &rotation.NewClientKey, &rotation.Status,
+		&rotation.Error, &rotation.CreatedAt, &rotation.UpdatedAt, &finishedAt,
+	)
+	if err != nil {
+		return nil, ErrorMsg(err)
+	}
+	if finishedAt.Valid {
+		rotation.FinishedAt = &finishedAt.Time
+	}
+	return rotation, nil
+}
@@ -0,0 +1,153 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/northmule/gophkeeper/internal/common/models"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"golang.org/x/net/context"
+)
+
+type KeyRotationRepositoryTestSuite struct {
+	suite.Suite
+	DB         *sql.DB
+	mock       sqlmock.Sqlmock
+	repository *KeyRotationRepository
+}
+
+var keyRotationRowColumns = []string{"id", "uuid", "user_uuid", "device_uuid", "new_client_key", "status", "error", "created_at", "updated_at", "finished_at"}
+
+func (s *KeyRotationRepositoryTestSuite) SetupTest() {
+	var err error
+	s.DB, s.mock, err = sqlmock.New()
+	require.NoError(s.T(), err)
+	s.mock.ExpectPrepare("select")
+	s.mock.ExpectPrepare("select")
+	s.mock.ExpectPrepare("update key_rotations set status = 'failed'")
+	s.repository, err = NewKeyRotationRepository(s.DB)
+	require.NoError(s.T(), err)
+}
+
+func TestKeyRotationRepositoryTestSuite(t *testing.T) {
+	suite.Run(t, new(KeyRotationRepositoryTestSuite))
+}
+
+func (s *KeyRotationRepositoryTestSuite) TestFindLastByUserUUID() {
+	finishedAt := time.Now()
+	s.mock.ExpectQuery("select").
+		WithArgs("user-uuid", "device-uuid").
+		WillReturnRows(sqlmock.NewRows(keyRotationRowColumns).
+			AddRow(1, "rotation-uuid", "user-uuid", "device-uuid", "", models.KeyRotationFinished, "", time.Now(), time.Now(), finishedAt))
+
+	rotation, err := s.repository.FindLastByDeviceUUID(context.Background(), "user-uuid", "device-uuid")
+	require.NoError(s.T(), err)
+	require.NotNil(s.T(), rotation)
+	s.Equal("device-uuid", rotation.DeviceUUID)
+	s.Equal("rotation-uuid", rotation.UUID)
+	s.Equal(models.KeyRotationFinished, rotation.Status)
+	s.NotNil(rotation.FinishedAt)
+}
+
+func (s *KeyRotationRepositoryTestSuite) TestFindLastByUserUUID_NotFound() {
+	s.mock.ExpectQuery("select").
+		WithArgs("user-uuid", "device-uuid").
+		WillReturnRows(sqlmock.NewRows(keyRotationRowColumns))
+
+	rotation, err := s.repository.FindLastByDeviceUUID(context.Background(), "user-uuid", "device-uuid")
+	require.NoError(s.T(), err)
+	s.Nil(rotation)
+}
+
+func (s *KeyRotationRepositoryTestSuite) TestFindAllRunning() {
+	s.mock.ExpectQuery("select").
+		WillReturnRows(sqlmock.NewRows(keyRotationRowColumns).
+			AddRow(1, "rotation-1", "user-1", "device-1", "kek1:k1:new", models.KeyRotationRunning, "", time.Now(), time.Now(), nil).
+			AddRow(2, "rotation-2", "user-2", "", "kek1:k1:new", models.KeyRotationRunning, "", time.Now(), time.Now(), nil))
+
+	rotations, err := s.repository.FindAllRunning(context.Background())
+	require.NoError(s.T(), err)
+	s.Len(rotations, 2)
+	s.Equal("rotation-1", rotations[0].UUID)
+	s.Nil(rotations[1].FinishedAt)
+}
+
+func (s *KeyRotationRepositoryTestSuite) TestAdd() {
+	s.mock.ExpectQuery("insert into key_rotations").
+		WithArgs("rotation-uuid", "user-uuid", "device-uuid", "kek1:k1:new").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5))
+
+	id, err := s.repository.Add(context.Background(), &models.KeyRotation{UUID: "rotation-uuid", UserUUID: "user-uuid", DeviceUUID: "device-uuid", NewClientKey: "kek1:k1:new"})
+	require.NoError(s.T(), err)
+	s.Equal(int64(5), id)
+}
+
+func (s *KeyRotationRepositoryTestSuite) TestFail() {
+	s.mock.ExpectExec("update key_rotations set status = 'failed'").
+		WithArgs("broken", "rotation-uuid").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	require.NoError(s.T(), s.repository.Fail(context.Background(), "rotation-uuid", "broken"))
+	s.NoError(s.mock.ExpectationsWereMet())
+}
+
+func (s *KeyRotationRepositoryTestSuite) TestFinish() {
+	rotation := &models.KeyRotation{UUID: "rotation-uuid", UserUUID: "user-uuid", NewClientKey: "kek1:k1:new"}
+	s.mock.ExpectBegin()
+	s.mock.ExpectQuery("update key_rotations set status = 'finished'").
+		WithArgs("rotation-uuid").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	s.mock.ExpectExec("update users set private_client_key").
+		WithArgs("kek1:k1:new", "user-uuid").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	s.mock.ExpectCommit()
+
+	require.NoError(s.T(), s.repository.Finish(context.Background(), rotation))
+	s.NoError(s.mock.ExpectationsWereMet())
+}
+
+func (s *KeyRotationRepositoryTestSuite) TestFinish_Device() {
+	rotation := &models.KeyRotation{UUID: "rotation-uuid", UserUUID: "user-uuid", DeviceUUID: "device-uuid", NewClientKey: "kek1:k1:new"}
+	s.mock.ExpectBegin()
+	s.mock.ExpectQuery("update key_rotations set status = 'finished'").
+		WithArgs("rotation-uuid").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	s.mock.ExpectExec("update devices set client_key").
+		WithArgs("kek1:k1:new", "device-uuid", "user-uuid").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	s.mock.ExpectCommit()
+
+	require.NoError(s.T(), s.repository.Finish(context.Background(), rotation))
+	s.NoError(s.mock.ExpectationsWereMet())
+}
+
+func (s *KeyRotationRepositoryTestSuite) TestFinish_NotRunning() {
+	rotation := &models.KeyRotation{UUID: "rotation-uuid", UserUUID: "user-uuid", NewClientKey: "kek1:k1:new"}
+	s.mock.ExpectBegin()
+	s.mock.ExpectQuery("update key_rotations set status = 'finished'").
+		WithArgs("rotation-uuid").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	s.mock.ExpectRollback()
+
+	err := s.repository.Finish(context.Background(), rotation)
+	s.Error(err)
+	s.NoError(s.mock.ExpectationsWereMet())
+}
+
+func (s *KeyRotationRepositoryTestSuite) TestFinish_UserUpdateError() {
+	rotation := &models.KeyRotation{UUID: "rotation-uuid", UserUUID: "user-uuid", NewClientKey: "kek1:k1:new"}
+	s.mock.ExpectBegin()
+	s.mock.ExpectQuery("update key_rotations set status = 'finished'").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	s.mock.ExpectExec("update users set private_client_key").
+		WillReturnError(errors.New("db error"))
+	s.mock.ExpectRollback()
+
+	err := s.repository.Finish(context.Background(), rotation)
+	s.Error(err)
+	s.NoError(s.mock.ExpectationsWereMet())
+}
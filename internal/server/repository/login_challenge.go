@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/northmule/gophkeeper/internal/server/storage"
+)
+
+// LoginChallengeRepository репозитарий незавершённых входов, ожидающих кода второго фактора.
+// Хранится хэш mfa_token, вход можно завершить на любом экземпляре сервера
+type LoginChallengeRepository struct {
+	store      storage.DBQuery
+	sqlAttempt *sql.Stmt
+}
+
+// NewLoginChallengeRepository конструктор
+func NewLoginChallengeRepository(store storage.DBQuery) (*LoginChallengeRepository, error) {
+	var err error
+	instance := new(LoginChallengeRepository)
+	instance.store = store
+	instance.sqlAttempt, err = store.Prepare(`update login_challenges set attempts = attempts + 1 where token_hash = $1 and expires_at > now() and attempts < $2 returning user_uuid`)
+	if err != nil {
+		return nil, ErrorMsg(err)
+	}
+	return instance, nil
+}
+
+// Add новый вход, ожидающий подтверждения. Истёкшие входы удаляются
+func (r *LoginChallengeRepository) Add(ctx context.Context, tokenHash string, userUUID string, expiresAt time.Time) error {
+	ctx, cancel := context.WithTimeout(ctx, timeOut)
+	defer cancel()
+	_, err := storage.Query(ctx, r.store).ExecContext(ctx, `delete from login_challenges where expires_at < now()`)
+	if err != nil {
+		return ErrorMsg(err)
+	}
+	_, err = storage.Query(ctx, r.store).ExecContext(
+		ctx,
+		`insert into login_challenges (token_hash, user_uuid, expires_at) values ($1, $2, $3)`,
+		tokenHash, userUUID, expiresAt,
+	)
+	if err != nil {
+		return ErrorMsg(err)
+	}
+	return nil
+}
+
+// Attempt расходует попытку ввода кода до его проверки. Пустая строка, если вход не найден, истёк
+// или попытки исчерпаны. Попытка списывается одним запросом, параллельные проверки не превышают maxAttempts
+func (r *LoginChallengeRepository) Attempt(ctx context.Context, tokenHash string, maxAttempts int) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeOut)
+	defer cancel()
+	var userUUID string
+	err := storage.Stmt(ctx, r.sqlAttempt).QueryRowContext(ctx, tokenHash, maxAttempts).Scan(&userUUID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", ErrorMsg(err)
+	}
+	return userUUID, nil
+}
+
+// Remove вход завершён или отменён
+func (r *LoginChallengeRepository) Remove(ctx context.Context, tokenHash string) error {
+	ctx, cancel := context.WithTimeout(ctx, timeOut)
+	defer cancel()
+	_, err := storage.Query(ctx, r.store).ExecContext(ctx, `delete from login_challenges where token_hash = $1`, tokenHash)
+	if err != nil {
+		return ErrorMsg(err)
+	}
+	return nil
+}
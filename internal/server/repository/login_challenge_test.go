@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"golang.org/x/net/context"
+)
+
+type LoginChallengeRepositoryTestSuite struct {
+	suite.Suite
+	DB         *sql.DB
+	mock       sqlmock.Sqlmock
+	repository *LoginChallengeRepository
+}
+
+func (s *LoginChallengeRepositoryTestSuite) SetupTest() {
+	var err error
+	s.DB, s.mock, err = sqlmock.New()
+	require.NoError(s.T(), err)
+	s.mock.ExpectPrepare("update login_challenges set attempts")
+	s.repository, err = NewLoginChallengeRepository(s.DB)
+	require.NoError(s.T(), err)
+}
+
+func TestLoginChallengeRepositoryTestSuite(t *testing.T) {
+	suite.Run(t, new(LoginChallengeRepositoryTestSuite))
+}
+
+func (s *LoginChallengeRepositoryTestSuite) TestAdd() {
+	expiresAt := time.Now().Add(time.Minute)
+	s.mock.ExpectExec("delete from login_challenges where expires_at").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	s.mock.ExpectExec("insert into login_challenges").
+		WithArgs("token-hash", "user-uuid", expiresAt).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	err := s.repository.Add(context.Background(), "token-hash", "user-uuid", expiresAt)
+	require.NoError(s.T(), err)
+	require.NoError(s.T(), s.mock.ExpectationsWereMet())
+}
+
+func (s *LoginChallengeRepositoryTestSuite) TestAdd_Error() {
+	s.mock.ExpectExec("delete from login_challenges").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	s.mock.ExpectExec("insert into login_challenges").
+		WillReturnError(errors.New("db error"))
+
+	err := s.repository.Add(context.Background(), "token-hash", "user-uuid", time.Now())
+	s.Error(err)
+}
+
+func (s *LoginChallengeRepositoryTestSuite) TestAttempt() {
+	// попытка списывается тем же запросом, что находит вход
+	s.mock.ExpectQuery("update login_challenges set attempts = attempts \\+ 1 where token_hash = \\$1 and expires_at > now\\(\\) and attempts < \\$2 returning user_uuid").
+		WithArgs("token-hash", 5).
+		WillReturnRows(sqlmock.NewRows([]string{"user_uuid"}).AddRow("user-uuid"))
+
+	userUUID, err := s.repository.Attempt(context.Background(), "token-hash", 5)
+	require.NoError(s.T(), err)
+	s.Equal("user-uuid", userUUID)
+	require.NoError(s.T(), s.mock.ExpectationsWereMet())
+}
+
+func (s *LoginChallengeRepositoryTestSuite) TestAttempt_NotFoundOrExhausted() {
+	s.mock.ExpectQuery("update login_challenges set attempts").
+		WithArgs("token-hash", 5).
+		WillReturnRows(sqlmock.NewRows([]string{"user_uuid"}))
+
+	userUUID, err := s.repository.Attempt(context.Background(), "token-hash", 5)
+	require.NoError(s.T(), err)
+	s.Empty(userUUID)
+}
+
+func (s *LoginChallengeRepositoryTestSuite) TestAttempt_Error() {
+	s.mock.ExpectQuery("update login_challenges set attempts").
+		WithArgs("token-hash", 5).
+		WillReturnError(errors.New("db error"))
+
+	userUUID, err := s.repository.Attempt(context.Background(), "token-hash", 5)
+	s.Error(err)
+	s.Empty(userUUID)
+}
+
+func (s *LoginChallengeRepositoryTestSuite) TestRemove() {
+	s.mock.ExpectExec("delete from login_challenges where token_hash").
+		WithArgs("token-hash").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err := s.repository.Remove(context.Background(), "token-hash")
+	require.NoError(s.T(), err)
+}
@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/northmule/gophkeeper/internal/common/models"
+	"github.com/northmule/gophkeeper/internal/server/storage"
+)
+
+// LoginFailureRepository репозитарий счётчиков неудачных попыток входа
+type LoginFailureRepository struct {
+	store             storage.DBQuery
+	sqlFindOneByLogin *sql.Stmt
+}
+
+// NewLoginFailureRepository конструктор
+func NewLoginFailureRepository(store storage.DBQuery) (*LoginFailureRepository, error) {
+	var err error
+	instance := new(LoginFailureRepository)
+	instance.store = store
+	instance.sqlFindOneByLogin, err = store.Prepare(`select id, login, failures, failed_at, locked_until from login_failures where login = $1 limit 1`)
+	if err != nil {
+		return nil, ErrorMsg(err)
+	}
+	return instance, nil
+}
+
+// FindOneByLogin неудачные попытки входа с логином, nil если их не было
+func (r *LoginFailureRepository) FindOneByLogin(ctx context.Context, login string) (*models.LoginFailure, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeOut)
+	defer cancel()
+	rows, err := storage.Stmt(ctx, r.sqlFindOneByLogin).QueryContext(ctx, login)
+	if err != nil {
+		return nil, ErrorMsg(err)
+	}
+	defer rows.Close()
+	err = rows.Err()
+	if err != nil {
+		return nil, ErrorMsg(err)
+	}
+	if !rows.Next() {
+		return nil, nil
+	}
+	failure := new(models.LoginFailure)
+	err = rows.Scan(&failure.ID, &failure.Login, &failure.Failures, &failure.FailedAt, &failure.LockedUntil)
+	if err != nil {
+		return nil, ErrorMsg(err)
+	}
+	return failure, nil
+}
+
+// Fail учёт неудачной попытки, вернёт число неудач подряд. Счёт начинается заново,
+// если предыдущая неудача была раньше resetBefore
+func (r *LoginFailureRepository) Fail(ctx context.Context, login string, failedAt time.Time, resetBefore time.Time) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeOut)
+	defer cancel()
+	rows := storage.Query(ctx, r.store).QueryRowContext(
+		ctx,
+		`insert into login_failures (login, failures, failed_at) values ($1, 1, $2)
+			on conflict (login) do update set failures = case when login_failures.failed_at < $3 then 1 else login_failures.failures + 1 end, failed_at = excluded.failed_at
+			returning failures`,
+		login, failedAt, resetBefore,
+	)
+	var failures int
+	err := rows.Scan(&failures)
+	if err != nil {
+		return 0, ErrorMsg(err)
+	}
+	return failures, nil
+}
+
+// Lock следующая попытка входа с логином отклоняется до lockedUntil
+func (r *LoginFailureRepository) Lock(ctx context.Context, login string, lockedUntil time.Time) error {
+	ctx, cancel := context.WithTimeout(ctx, timeOut)
+	defer cancel()
+	_, err := storage.Query(ctx, r.store).ExecContext(ctx, `update login_failures set locked_until = $1 where login = $2`, lockedUntil, login)
+	if err != nil {
+		return ErrorMsg(err)
+	}
+	return nil
+}
+
+// Reset сброс счётчика после успешного входа
+func (r *LoginFailureRepository) Reset(ctx context.Context, login string) error {
+	ctx, cancel := context.WithTimeout(ctx, timeOut)
+	defer cancel()
+	_, err := storage.Query(ctx, r.store).ExecContext(ctx, `delete from login_failures where login = $1`, login)
+	if err != nil {
+		return ErrorMsg(err)
+	}
+	return nil
+}
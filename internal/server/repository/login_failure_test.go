@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"golang.org/x/net/context"
+)
+
+type LoginFailureRepositoryTestSuite struct {
+	suite.Suite
+	DB         *sql.DB
+	mock       sqlmock.Sqlmock
+	repository *LoginFailureRepository
+}
+
+func (s *LoginFailureRepositoryTestSuite) SetupTest() {
+	var err error
+	s.DB, s.mock, err = sqlmock.New()
+	require.NoError(s.T(), err)
+	s.mock.ExpectPrepare("select id, login, failures")
+	s.repository, err = NewLoginFailureRepository(s.DB)
+	require.NoError(s.T(), err)
+}
+
+func TestLoginFailureRepositoryTestSuite(t *testing.T) {
+	suite.Run(t, new(LoginFailureRepositoryTestSuite))
+}
+
+func (s *LoginFailureRepositoryTestSuite) TestFindOneByLogin() {
+	lockedUntil := time.Now().Add(time.Minute)
+	s.mock.ExpectQuery("select").
+		WithArgs("login").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "login", "failures", "failed_at", "locked_until"}).
+			AddRow(1, "login", 5, time.Now(), lockedUntil))
+
+	failure, err := s.repository.FindOneByLogin(context.Background(), "login")
+	require.NoError(s.T(), err)
+	require.NotNil(s.T(), failure)
+	s.Equal(5, failure.Failures)
+	require.NotNil(s.T(), failure.LockedUntil)
+	s.True(lockedUntil.Equal(*failure.LockedUntil))
+}
+
+func (s *LoginFailureRepositoryTestSuite) TestFindOneByLogin_NotFound() {
+	s.mock.ExpectQuery("select").
+		WithArgs("login").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "login", "failures", "failed_at", "locked_until"}))
+
+	failure, err := s.repository.FindOneByLogin(context.Background(), "login")
+	require.NoError(s.T(), err)
+	s.Nil(failure)
+}
+
+func (s *LoginFailureRepositoryTestSuite) TestFail() {
+	failedAt := time.Now()
+	resetBefore := failedAt.Add(-time.Hour)
+	s.mock.ExpectQuery("insert into login_failures").
+		WithArgs("login", failedAt, resetBefore).
+		WillReturnRows(sqlmock.NewRows([]string{"failures"}).AddRow(3))
+
+	failures, err := s.repository.Fail(context.Background(), "login", failedAt, resetBefore)
+	require.NoError(s.T(), err)
+	s.Equal(3, failures)
+}
+
+func (s *LoginFailureRepositoryTestSuite) TestFail_Error() {
+	s.mock.ExpectQuery("insert into login_failures").
+		WillReturnError(errors.New("db error"))
+
+	_, err := s.repository.Fail(context.Background(), "login", time.Now(), time.Now())
+	s.Error(err)
+}
+
+func (s *LoginFailureRepositoryTestSuite) TestLock() {
+	lockedUntil := time.Now().Add(time.Minute)
+	s.mock.ExpectExec("update login_failures set locked_until").
+		WithArgs(lockedUntil, "login").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err := s.repository.Lock(context.Background(), "login", lockedUntil)
+	require.NoError(s.T(), err)
+	s.NoError(s.mock.ExpectationsWereMet())
+}
+
+func (s *LoginFailureRepositoryTestSuite) TestReset() {
+	s.mock.ExpectExec("delete from login_failures").
+		WithArgs("login").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err := s.repository.Reset(context.Background(), "login")
+	require.NoError(s.T(), err)
+	s.NoError(s.mock.ExpectationsWereMet())
+}
@@ -34,7 +34,7 @@ func (r *MetaDataRepository) FindOneByUUID(ctx context.Context, uuid string) ([]
 
 	ctx, cancel := context.WithTimeout(ctx, timeOut)
 	defer cancel()
-	rows, err := r.sqlAllFindByDataUUID.QueryContext(ctx, uuid)
+	rows, err := storage.Stmt(ctx, r.sqlAllFindByDataUUID).QueryContext(ctx, uuid)
 	if err != nil {
 		return nil, ErrorMsg(err)
 	}
@@ -65,7 +65,7 @@ func (r *MetaDataRepository) FindOneByUUID(ctx context.Context, uuid string) ([]
 func (r *MetaDataRepository) Add(ctx context.Context, data *models.MetaData) (int64, error) {
 	ctx, cancel := context.WithTimeout(ctx, timeOut)
 	defer cancel()
-	rows := r.store.QueryRowContext(ctx, `insert into meta_data (meta_name, meta_value, data_uuid) values ($1, $2, $3) returning id`, data.MetaName, data.MetaValue, data.DataUUID)
+	rows := storage.Query(ctx, r.store).QueryRowContext(ctx, `insert into meta_data (meta_name, meta_value, data_uuid) values ($1, $2, $3) returning id`, data.MetaName, data.MetaValue, data.DataUUID)
 	err := rows.Err()
 	if err != nil {
 		return 0, ErrorMsg(err)
@@ -84,8 +84,8 @@ func (r *MetaDataRepository) ReplaceMetaByDataUUID(ctx context.Context, dataUUID
 	ctx, cancel := context.WithTimeout(ctx, timeOut)
 	defer cancel()
 	var err error
-	var tx *sql.Tx
-	if tx, err = r.store.Begin(); err != nil {
+	var tx *storage.Tx
+	if tx, err = storage.BeginTx(ctx, r.store); err != nil {
 		return ErrorMsg(err)
 	}
 	_, err = tx.QueryContext(ctx, `delete from "meta_data" where data_uuid = $1`, dataUUID)
@@ -2,6 +2,7 @@ package mock
 
 import (
 	"net/http"
+	"time"
 
 	"github.com/go-chi/jwtauth/v5"
 	"github.com/stretchr/testify/mock"
@@ -16,11 +17,54 @@ func (m *MockAccessService) PasswordHash(password string) (string, error) {
 	args := m.Called(password)
 	return args.String(0), args.Error(1)
 }
+
+func (m *MockAccessService) PasswordVerify(password string, hash string) (bool, error) {
+	args := m.Called(password, hash)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockAccessService) PasswordVerifyDummy(password string) {
+	m.Called(password)
+}
+
+func (m *MockAccessService) PasswordNeedsRehash(hash string) bool {
+	args := m.Called(hash)
+	return args.Bool(0)
+}
+
 func (m *MockAccessService) FillJWTToken() *jwtauth.JWTAuth {
 	args := m.Called()
 	return args.Get(0).(*jwtauth.JWTAuth)
 }
 
+func (m *MockAccessService) IssueToken(userUUID string, sessionUUID string, deviceUUID string) (string, error) {
+	args := m.Called(userUUID, sessionUUID, deviceUUID)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockAccessService) GetDeviceUUIDByJWTToken(ctx context.Context) (string, error) {
+	args := m.Called(ctx)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockAccessService) GetSessionUUIDByJWTToken(ctx context.Context) (string, error) {
+	args := m.Called(ctx)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockAccessService) GetTokenExpirationByJWTToken(ctx context.Context) (time.Time, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(time.Time), args.Error(1)
+}
+
+func (m *MockAccessService) JWTVerifier(next http.Handler) http.Handler {
+	args := m.Called(next)
+	if args.Get(0) == nil {
+		return next
+	}
+	return args.Get(0).(http.Handler)
+}
+
 func (m *MockAccessService) GetUserUUIDByJWTToken(ctx context.Context) (string, error) {
 	args := m.Called(ctx)
 	return args.String(0), args.Error(1)
@@ -0,0 +1,29 @@
+package mock
+
+import (
+	"context"
+
+	"github.com/northmule/gophkeeper/internal/common/model_data"
+	"github.com/northmule/gophkeeper/internal/common/models"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockAuditor мок
+type MockAuditor struct {
+	mock.Mock
+}
+
+// Record мок
+func (m *MockAuditor) Record(ctx context.Context, event models.AuditEvent) error {
+	args := m.Called(ctx, event)
+	return args.Error(0)
+}
+
+// Verify мок
+func (m *MockAuditor) Verify(ctx context.Context, userUUID string) (*model_data.AuditVerifyResponse, error) {
+	args := m.Called(ctx, userUUID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model_data.AuditVerifyResponse), args.Error(1)
+}
@@ -7,15 +7,21 @@ type MockCryptService struct {
 	mock.Mock
 }
 
-// EncryptRSA мок
-func (m *MockCryptService) EncryptRSA(data []byte) ([]byte, error) {
-	args := m.Called(data)
-	return args.Get(0).([]byte), args.Error(1)
+// KeyExchanges мок
+func (m *MockCryptService) KeyExchanges() []string {
+	args := m.Called()
+	return args.Get(0).([]string)
+}
+
+// ExchangePublicKey мок
+func (m *MockCryptService) ExchangePublicKey() []byte {
+	args := m.Called()
+	return args.Get(0).([]byte)
 }
 
-// DecryptRSA мок
-func (m *MockCryptService) DecryptRSA(data []byte) ([]byte, error) {
-	args := m.Called(data)
+// DecryptKey мок
+func (m *MockCryptService) DecryptKey(scheme string, data []byte) ([]byte, error) {
+	args := m.Called(scheme, data)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
@@ -1,9 +1,10 @@
 package mock
 
 import (
+	"time"
+
 	"github.com/northmule/gophkeeper/internal/common/models"
 	"github.com/northmule/gophkeeper/internal/server/repository"
-	"github.com/northmule/gophkeeper/internal/server/storage"
 	"github.com/stretchr/testify/mock"
 	"golang.org/x/net/context"
 )
@@ -34,18 +35,36 @@ func (m *MockUserDataModelRepository) CreateNewUser(ctx context.Context, user mo
 	return args.Get(0).(int64), args.Error(1)
 }
 
-func (m *MockUserDataModelRepository) TxCreateNewUser(ctx context.Context, tx storage.TxDBQuery, user models.User) (int64, error) {
-	args := m.Called(ctx, tx, user)
-	return args.Get(0).(int64), args.Error(1)
+func (m *MockUserDataModelRepository) SetPrivateClientKey(ctx context.Context, data string, userUUID string) error {
+	args := m.Called(ctx, data, userUUID)
+	return args.Error(0)
 }
 
-func (m *MockUserDataModelRepository) SetPublicKey(ctx context.Context, data string, userUUID string) error {
-	args := m.Called(ctx, data, userUUID)
+func (m *MockUserDataModelRepository) FindAllPrivateClientKeys(ctx context.Context) ([]models.User, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.User), args.Error(1)
+}
+
+func (m *MockUserDataModelRepository) ReplacePrivateClientKey(ctx context.Context, userUUID string, oldValue string, newValue string) (bool, error) {
+	args := m.Called(ctx, userUUID, oldValue, newValue)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockUserDataModelRepository) SetMasterKey(ctx context.Context, salt string, check string, userUUID string) (bool, error) {
+	args := m.Called(ctx, salt, check, userUUID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockUserDataModelRepository) ClearVaultLegacy(ctx context.Context, userUUID string) error {
+	args := m.Called(ctx, userUUID)
 	return args.Error(0)
 }
 
-func (m *MockUserDataModelRepository) SetPrivateClientKey(ctx context.Context, data string, userUUID string) error {
-	args := m.Called(ctx, data, userUUID)
+func (m *MockUserDataModelRepository) SetPassword(ctx context.Context, hash string, userUUID string) error {
+	args := m.Called(ctx, hash, userUUID)
 	return args.Error(0)
 }
 
@@ -72,6 +91,11 @@ func (m *MockCardDataModelRepository) Update(ctx context.Context, data *models.C
 	return args.Error(0)
 }
 
+func (m *MockCardDataModelRepository) Delete(ctx context.Context, uuid string, version int64) error {
+	args := m.Called(ctx, uuid, version)
+	return args.Error(0)
+}
+
 // MockOwnerDataModelRepository is a mock implementation of OwnerDataModelRepository
 type MockOwnerDataModelRepository struct {
 	mock.Mock
@@ -98,11 +122,21 @@ func (m *MockOwnerDataModelRepository) Add(ctx context.Context, data *models.Own
 	return args.Get(0).(int64), args.Error(1)
 }
 
+func (m *MockOwnerDataModelRepository) Delete(ctx context.Context, userUUID string, dataUUID string, dataType string) (bool, error) {
+	args := m.Called(ctx, userUUID, dataUUID, dataType)
+	return args.Bool(0), args.Error(1)
+}
+
 func (m *MockOwnerDataModelRepository) AllOwnerData(ctx context.Context, userUUID string, offset int, limit int) ([]models.OwnerData, error) {
 	args := m.Called(ctx, userUUID, offset, limit)
 	return args.Get(0).([]models.OwnerData), args.Error(1)
 }
 
+func (m *MockOwnerDataModelRepository) ChangesSince(ctx context.Context, userUUID string, revision int64, limit int) ([]models.OwnerData, error) {
+	args := m.Called(ctx, userUUID, revision, limit)
+	return args.Get(0).([]models.OwnerData), args.Error(1)
+}
+
 // MockMetaDataModelRepository is a mock implementation of MetaDataModelRepository
 type MockMetaDataModelRepository struct {
 	mock.Mock
@@ -146,6 +180,11 @@ func (m *MockTextDataModelRepository) Update(ctx context.Context, data *models.T
 	return args.Error(0)
 }
 
+func (m *MockTextDataModelRepository) Delete(ctx context.Context, uuid string, version int64) error {
+	args := m.Called(ctx, uuid, version)
+	return args.Error(0)
+}
+
 // MockFileDataModelRepository is a mock implementation of FileDataModelRepository
 type MockFileDataModelRepository struct {
 	mock.Mock
@@ -169,6 +208,67 @@ func (m *MockFileDataModelRepository) Update(ctx context.Context, data *models.F
 	return args.Error(0)
 }
 
+func (m *MockFileDataModelRepository) Lock(ctx context.Context, uuid string) error {
+	args := m.Called(ctx, uuid)
+	return args.Error(0)
+}
+
+func (m *MockFileDataModelRepository) SetUploaded(ctx context.Context, uuid string, uploaded bool) error {
+	args := m.Called(ctx, uuid, uploaded)
+	return args.Error(0)
+}
+
+// MockFileChunkModelRepository is a mock implementation of FileChunkModelRepository
+type MockFileChunkModelRepository struct {
+	mock.Mock
+}
+
+func (m *MockFileChunkModelRepository) FindAllByFileUUID(ctx context.Context, fileUUID string) ([]models.FileChunk, error) {
+	args := m.Called(ctx, fileUUID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.FileChunk), args.Error(1)
+}
+
+func (m *MockFileChunkModelRepository) Save(ctx context.Context, chunk *models.FileChunk) error {
+	args := m.Called(ctx, chunk)
+	return args.Error(0)
+}
+
+func (m *MockFileChunkModelRepository) DeleteByFileUUID(ctx context.Context, fileUUID string) error {
+	args := m.Called(ctx, fileUUID)
+	return args.Error(0)
+}
+
+// MockCredentialDataModelRepository is a mock implementation of CredentialDataModelRepository
+type MockCredentialDataModelRepository struct {
+	mock.Mock
+}
+
+func (m *MockCredentialDataModelRepository) FindOneByUUID(ctx context.Context, uuid string) (*models.CredentialData, error) {
+	args := m.Called(ctx, uuid)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.CredentialData), args.Error(1)
+}
+
+func (m *MockCredentialDataModelRepository) Add(ctx context.Context, data *models.CredentialData) (int64, error) {
+	args := m.Called(ctx, data)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockCredentialDataModelRepository) Update(ctx context.Context, data *models.CredentialData) error {
+	args := m.Called(ctx, data)
+	return args.Error(0)
+}
+
+func (m *MockCredentialDataModelRepository) Delete(ctx context.Context, uuid string, version int64) error {
+	args := m.Called(ctx, uuid, version)
+	return args.Error(0)
+}
+
 // MockManager is a mock implementation of Repository
 type MockManager struct {
 	mock.Mock
@@ -178,6 +278,8 @@ type MockManager struct {
 	metaData *MockMetaDataModelRepository
 	textData *MockTextDataModelRepository
 	fileData *MockFileDataModelRepository
+
+	credentialData *MockCredentialDataModelRepository
 }
 
 func NewMockManager() *MockManager {
@@ -188,6 +290,7 @@ func NewMockManager() *MockManager {
 	instance.metaData = new(MockMetaDataModelRepository)
 	instance.textData = new(MockTextDataModelRepository)
 	instance.fileData = new(MockFileDataModelRepository)
+	instance.credentialData = new(MockCredentialDataModelRepository)
 
 	return instance
 
@@ -222,3 +325,395 @@ func (m *MockManager) FileData() repository.FileDataModelRepository {
 	args := m.Called()
 	return args.Get(0).(repository.FileDataModelRepository)
 }
+
+func (m *MockManager) FileChunk() repository.FileChunkModelRepository {
+	args := m.Called()
+	return args.Get(0).(repository.FileChunkModelRepository)
+}
+
+func (m *MockManager) CredentialData() repository.CredentialDataModelRepository {
+	args := m.Called()
+	return args.Get(0).(repository.CredentialDataModelRepository)
+}
+
+func (m *MockManager) Session() repository.SessionModelRepository {
+	args := m.Called()
+	return args.Get(0).(repository.SessionModelRepository)
+}
+
+// MockSessionModelRepository is a mock implementation of SessionModelRepository
+type MockSessionModelRepository struct {
+	mock.Mock
+}
+
+func (m *MockSessionModelRepository) FindOneByUUID(ctx context.Context, uuid string) (*models.Session, error) {
+	args := m.Called(ctx, uuid)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Session), args.Error(1)
+}
+
+func (m *MockSessionModelRepository) FindOneByRefreshTokenHash(ctx context.Context, hash string) (*models.Session, error) {
+	args := m.Called(ctx, hash)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Session), args.Error(1)
+}
+
+func (m *MockSessionModelRepository) FindAllActiveByUserUUID(ctx context.Context, userUUID string) ([]models.Session, error) {
+	args := m.Called(ctx, userUUID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.Session), args.Error(1)
+}
+
+func (m *MockSessionModelRepository) Add(ctx context.Context, data *models.Session) (int64, error) {
+	args := m.Called(ctx, data)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockSessionModelRepository) Rotate(ctx context.Context, uuid string, oldHash string, newHash string, expiresAt time.Time) (bool, error) {
+	args := m.Called(ctx, uuid, oldHash, newHash, expiresAt)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockSessionModelRepository) Revoke(ctx context.Context, uuid string) error {
+	args := m.Called(ctx, uuid)
+	return args.Error(0)
+}
+
+func (m *MockSessionModelRepository) RevokeAllByUserUUID(ctx context.Context, userUUID string) error {
+	args := m.Called(ctx, userUUID)
+	return args.Error(0)
+}
+
+func (m *MockManager) TOTP() repository.TOTPModelRepository {
+	args := m.Called()
+	return args.Get(0).(repository.TOTPModelRepository)
+}
+
+func (m *MockManager) ClientCertificate() repository.ClientCertificateModelRepository {
+	args := m.Called()
+	return args.Get(0).(repository.ClientCertificateModelRepository)
+}
+
+func (m *MockManager) KeyRotation() repository.KeyRotationModelRepository {
+	args := m.Called()
+	return args.Get(0).(repository.KeyRotationModelRepository)
+}
+
+func (m *MockManager) RecoveryKit() repository.RecoveryKitModelRepository {
+	args := m.Called()
+	return args.Get(0).(repository.RecoveryKitModelRepository)
+}
+
+func (m *MockManager) LoginFailure() repository.LoginFailureModelRepository {
+	args := m.Called()
+	return args.Get(0).(repository.LoginFailureModelRepository)
+}
+
+func (m *MockManager) LoginChallenge() repository.LoginChallengeModelRepository {
+	args := m.Called()
+	return args.Get(0).(repository.LoginChallengeModelRepository)
+}
+
+func (m *MockManager) RequestNonce() repository.RequestNonceModelRepository {
+	args := m.Called()
+	return args.Get(0).(repository.RequestNonceModelRepository)
+}
+
+func (m *MockManager) AuditEvent() repository.AuditEventModelRepository {
+	args := m.Called()
+	return args.Get(0).(repository.AuditEventModelRepository)
+}
+
+func (m *MockManager) Device() repository.DeviceModelRepository {
+	args := m.Called()
+	return args.Get(0).(repository.DeviceModelRepository)
+}
+
+func (m *MockManager) IdempotencyKey() repository.IdempotencyKeyModelRepository {
+	args := m.Called()
+	return args.Get(0).(repository.IdempotencyKeyModelRepository)
+}
+
+// MockTOTPModelRepository is a mock implementation of TOTPModelRepository
+type MockTOTPModelRepository struct {
+	mock.Mock
+}
+
+func (m *MockTOTPModelRepository) FindOneByUserUUID(ctx context.Context, userUUID string) (*models.UserTOTP, error) {
+	args := m.Called(ctx, userUUID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.UserTOTP), args.Error(1)
+}
+
+func (m *MockTOTPModelRepository) Save(ctx context.Context, userUUID string, secret string) error {
+	args := m.Called(ctx, userUUID, secret)
+	return args.Error(0)
+}
+
+func (m *MockTOTPModelRepository) Enable(ctx context.Context, userUUID string) error {
+	args := m.Called(ctx, userUUID)
+	return args.Error(0)
+}
+
+func (m *MockTOTPModelRepository) UseStep(ctx context.Context, userUUID string, step int64) (bool, error) {
+	args := m.Called(ctx, userUUID, step)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockTOTPModelRepository) Delete(ctx context.Context, userUUID string) error {
+	args := m.Called(ctx, userUUID)
+	return args.Error(0)
+}
+
+func (m *MockTOTPModelRepository) ReplaceBackupCodes(ctx context.Context, userUUID string, codeHashes []string) error {
+	args := m.Called(ctx, userUUID, codeHashes)
+	return args.Error(0)
+}
+
+func (m *MockTOTPModelRepository) UseBackupCode(ctx context.Context, userUUID string, codeHash string) (bool, error) {
+	args := m.Called(ctx, userUUID, codeHash)
+	return args.Bool(0), args.Error(1)
+}
+
+// MockClientCertificateModelRepository is a mock implementation of ClientCertificateModelRepository
+type MockClientCertificateModelRepository struct {
+	mock.Mock
+}
+
+func (m *MockClientCertificateModelRepository) FindOneByFingerprint(ctx context.Context, fingerprint string) (*models.ClientCertificate, error) {
+	args := m.Called(ctx, fingerprint)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ClientCertificate), args.Error(1)
+}
+
+func (m *MockClientCertificateModelRepository) Add(ctx context.Context, data *models.ClientCertificate) (int64, error) {
+	args := m.Called(ctx, data)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+// MockKeyRotationModelRepository is a mock implementation of KeyRotationModelRepository
+type MockKeyRotationModelRepository struct {
+	mock.Mock
+}
+
+func (m *MockKeyRotationModelRepository) FindLastByDeviceUUID(ctx context.Context, userUUID string, deviceUUID string) (*models.KeyRotation, error) {
+	args := m.Called(ctx, userUUID, deviceUUID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.KeyRotation), args.Error(1)
+}
+
+func (m *MockKeyRotationModelRepository) FindAllRunning(ctx context.Context) ([]models.KeyRotation, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.KeyRotation), args.Error(1)
+}
+
+func (m *MockKeyRotationModelRepository) Add(ctx context.Context, data *models.KeyRotation) (int64, error) {
+	args := m.Called(ctx, data)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockKeyRotationModelRepository) Fail(ctx context.Context, uuid string, reason string) error {
+	args := m.Called(ctx, uuid, reason)
+	return args.Error(0)
+}
+
+func (m *MockKeyRotationModelRepository) Finish(ctx context.Context, rotation *models.KeyRotation) error {
+	args := m.Called(ctx, rotation)
+	return args.Error(0)
+}
+
+// MockRecoveryKitModelRepository is a mock implementation of RecoveryKitModelRepository
+type MockRecoveryKitModelRepository struct {
+	mock.Mock
+}
+
+func (m *MockRecoveryKitModelRepository) FindOneByUserUUID(ctx context.Context, userUUID string) (*models.RecoveryKit, error) {
+	args := m.Called(ctx, userUUID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.RecoveryKit), args.Error(1)
+}
+
+func (m *MockRecoveryKitModelRepository) Save(ctx context.Context, kit *models.RecoveryKit) error {
+	args := m.Called(ctx, kit)
+	return args.Error(0)
+}
+
+// MockLoginFailureModelRepository is a mock implementation of LoginFailureModelRepository
+type MockLoginFailureModelRepository struct {
+	mock.Mock
+}
+
+func (m *MockLoginFailureModelRepository) FindOneByLogin(ctx context.Context, login string) (*models.LoginFailure, error) {
+	args := m.Called(ctx, login)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.LoginFailure), args.Error(1)
+}
+
+func (m *MockLoginFailureModelRepository) Fail(ctx context.Context, login string, failedAt time.Time, resetBefore time.Time) (int, error) {
+	args := m.Called(ctx, login, failedAt, resetBefore)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockLoginFailureModelRepository) Lock(ctx context.Context, login string, lockedUntil time.Time) error {
+	args := m.Called(ctx, login, lockedUntil)
+	return args.Error(0)
+}
+
+func (m *MockLoginFailureModelRepository) Reset(ctx context.Context, login string) error {
+	args := m.Called(ctx, login)
+	return args.Error(0)
+}
+
+// MockLoginChallengeModelRepository is a mock implementation of LoginChallengeModelRepository
+type MockLoginChallengeModelRepository struct {
+	mock.Mock
+}
+
+func (m *MockLoginChallengeModelRepository) Add(ctx context.Context, tokenHash string, userUUID string, expiresAt time.Time) error {
+	args := m.Called(ctx, tokenHash, userUUID, expiresAt)
+	return args.Error(0)
+}
+
+func (m *MockLoginChallengeModelRepository) Attempt(ctx context.Context, tokenHash string, maxAttempts int) (string, error) {
+	args := m.Called(ctx, tokenHash, maxAttempts)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockLoginChallengeModelRepository) Remove(ctx context.Context, tokenHash string) error {
+	args := m.Called(ctx, tokenHash)
+	return args.Error(0)
+}
+
+// MockRequestNonceModelRepository is a mock implementation of RequestNonceModelRepository
+type MockRequestNonceModelRepository struct {
+	mock.Mock
+}
+
+func (m *MockRequestNonceModelRepository) Use(ctx context.Context, userUUID string, nonce string, expiresAt time.Time, expiredBefore time.Time) (bool, error) {
+	args := m.Called(ctx, userUUID, nonce, expiresAt, expiredBefore)
+	return args.Bool(0), args.Error(1)
+}
+
+// MockAuditEventModelRepository is a mock implementation of AuditEventModelRepository
+type MockAuditEventModelRepository struct {
+	mock.Mock
+}
+
+func (m *MockAuditEventModelRepository) Add(ctx context.Context, event *models.AuditEvent, chainHash func(event *models.AuditEvent) string) (int64, error) {
+	args := m.Called(ctx, event, chainHash)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockAuditEventModelRepository) FindAll(ctx context.Context, filter repository.AuditEventFilter, offset int, limit int) ([]models.AuditEvent, error) {
+	args := m.Called(ctx, filter, offset, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.AuditEvent), args.Error(1)
+}
+
+func (m *MockAuditEventModelRepository) FindChain(ctx context.Context, userUUID string, afterID int64, limit int) ([]models.AuditEvent, error) {
+	args := m.Called(ctx, userUUID, afterID, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.AuditEvent), args.Error(1)
+}
+
+// MockDeviceModelRepository is a mock implementation of DeviceModelRepository
+type MockDeviceModelRepository struct {
+	mock.Mock
+}
+
+func (m *MockDeviceModelRepository) FindOneByUUID(ctx context.Context, uuid string) (*models.Device, error) {
+	args := m.Called(ctx, uuid)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Device), args.Error(1)
+}
+
+func (m *MockDeviceModelRepository) FindAllByUserUUID(ctx context.Context, userUUID string) ([]models.Device, error) {
+	args := m.Called(ctx, userUUID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.Device), args.Error(1)
+}
+
+func (m *MockDeviceModelRepository) Add(ctx context.Context, data *models.Device) (int64, error) {
+	args := m.Called(ctx, data)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockDeviceModelRepository) Touch(ctx context.Context, uuid string) error {
+	args := m.Called(ctx, uuid)
+	return args.Error(0)
+}
+
+func (m *MockDeviceModelRepository) SetKeys(ctx context.Context, uuid string, publicKey string, vaultKey string) error {
+	args := m.Called(ctx, uuid, publicKey, vaultKey)
+	return args.Error(0)
+}
+
+func (m *MockDeviceModelRepository) SetClientKey(ctx context.Context, uuid string, clientKey string) (bool, error) {
+	args := m.Called(ctx, uuid, clientKey)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockDeviceModelRepository) Rename(ctx context.Context, userUUID string, uuid string, name string) (bool, error) {
+	args := m.Called(ctx, userUUID, uuid, name)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockDeviceModelRepository) Revoke(ctx context.Context, userUUID string, uuid string) (bool, error) {
+	args := m.Called(ctx, userUUID, uuid)
+	return args.Bool(0), args.Error(1)
+}
+
+// MockIdempotencyKeyModelRepository is a mock implementation of IdempotencyKeyModelRepository
+type MockIdempotencyKeyModelRepository struct {
+	mock.Mock
+}
+
+func (m *MockIdempotencyKeyModelRepository) FindOne(ctx context.Context, userUUID string, key string) (*models.IdempotencyKey, error) {
+	args := m.Called(ctx, userUUID, key)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.IdempotencyKey), args.Error(1)
+}
+
+func (m *MockIdempotencyKeyModelRepository) Reserve(ctx context.Context, data *models.IdempotencyKey, expiredBefore time.Time, leaseExpiredBefore time.Time) (bool, error) {
+	args := m.Called(ctx, data, expiredBefore, leaseExpiredBefore)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockIdempotencyKeyModelRepository) Complete(ctx context.Context, userUUID string, key string, statusCode int, response string) error {
+	args := m.Called(ctx, userUUID, key, statusCode, response)
+	return args.Error(0)
+}
+
+func (m *MockIdempotencyKeyModelRepository) Release(ctx context.Context, userUUID string, key string) error {
+	args := m.Called(ctx, userUUID, key)
+	return args.Error(0)
+}
@@ -1,8 +1,12 @@
 package mock
 
 import (
+	"context"
 	"time"
 
+	"github.com/northmule/gophkeeper/internal/common/model_data"
+	"github.com/northmule/gophkeeper/internal/common/models"
+
 	"github.com/stretchr/testify/mock"
 )
 
@@ -12,12 +16,74 @@ type MockSessionManager struct {
 }
 
 // Add мок
-func (m *MockSessionManager) Add(token string, expire time.Time) {
-	m.Called(token, expire)
+func (m *MockSessionManager) Add(sessionUUID string, userUUID string, expire time.Time) {
+	m.Called(sessionUUID, userUUID, expire)
 }
 
 // IsValid мок
-func (m *MockSessionManager) IsValid(token string) bool {
-	args := m.Called(token)
+func (m *MockSessionManager) IsValid(sessionUUID string) bool {
+	args := m.Called(sessionUUID)
 	return args.Bool(0)
 }
+
+// Revoke мок
+func (m *MockSessionManager) Revoke(sessionUUID string) {
+	m.Called(sessionUUID)
+}
+
+// List мок
+func (m *MockSessionManager) List(userUUID string) []string {
+	args := m.Called(userUUID)
+	return args.Get(0).([]string)
+}
+
+// MockSessionOpener мок
+type MockSessionOpener struct {
+	mock.Mock
+}
+
+// Open мок
+func (m *MockSessionOpener) Open(ctx context.Context, userUUID string, device *models.Device) (*model_data.TokenResponse, error) {
+	args := m.Called(ctx, userUUID, device)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model_data.TokenResponse), args.Error(1)
+}
+
+// MockSecondFactor мок
+type MockSecondFactor struct {
+	mock.Mock
+}
+
+// Challenge мок
+func (m *MockSecondFactor) Challenge(ctx context.Context, userUUID string) (*model_data.LoginChallengeResponse, error) {
+	args := m.Called(ctx, userUUID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model_data.LoginChallengeResponse), args.Error(1)
+}
+
+// MockLoginLockout мок
+type MockLoginLockout struct {
+	mock.Mock
+}
+
+// Check мок
+func (m *MockLoginLockout) Check(ctx context.Context, login string) (time.Duration, error) {
+	args := m.Called(ctx, login)
+	return args.Get(0).(time.Duration), args.Error(1)
+}
+
+// Fail мок
+func (m *MockLoginLockout) Fail(ctx context.Context, login string) (time.Duration, error) {
+	args := m.Called(ctx, login)
+	return args.Get(0).(time.Duration), args.Error(1)
+}
+
+// Reset мок
+func (m *MockLoginLockout) Reset(ctx context.Context, login string) error {
+	args := m.Called(ctx, login)
+	return args.Error(0)
+}
@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"errors"
 
 	"github.com/northmule/gophkeeper/internal/common/data_type"
 	"github.com/northmule/gophkeeper/internal/common/models"
@@ -35,7 +36,7 @@ func NewOwnerRepository(store storage.DBQuery) (*OwnerRepository, error) {
 func (r *OwnerRepository) FindOneByUserUUIDAndDataUUIDAndDataType(ctx context.Context, userUuid string, dataUuid string, dataType string) (*models.Owner, error) {
 	ctx, cancel := context.WithTimeout(ctx, timeOut)
 	defer cancel()
-	rows, err := r.sqlFindOneByUserUUIDAndDataUUIDAndDataType.QueryContext(ctx, userUuid, dataUuid, dataType)
+	rows, err := storage.Stmt(ctx, r.sqlFindOneByUserUUIDAndDataUUIDAndDataType).QueryContext(ctx, userUuid, dataUuid, dataType)
 	if err != nil {
 		return nil, ErrorMsg(err)
 	}
@@ -58,7 +59,7 @@ func (r *OwnerRepository) FindOneByUserUUIDAndDataUUIDAndDataType(ctx context.Co
 func (r *OwnerRepository) FindOneByUserUUIDAndDataUUID(ctx context.Context, userUuid string, dataUuid string) (*models.Owner, error) {
 	ctx, cancel := context.WithTimeout(ctx, timeOut)
 	defer cancel()
-	rows, err := r.store.QueryContext(ctx, `select id, user_uuid, data_type, data_uuid from owner where user_uuid = $1 and data_uuid = $2 limit 1`, userUuid, dataUuid)
+	rows, err := storage.Query(ctx, r.store).QueryContext(ctx, `select id, user_uuid, data_type, data_uuid from owner where user_uuid = $1 and data_uuid = $2 limit 1`, userUuid, dataUuid)
 	if err != nil {
 		return nil, ErrorMsg(err)
 	}
@@ -81,7 +82,7 @@ func (r *OwnerRepository) FindOneByUserUUIDAndDataUUID(ctx context.Context, user
 func (r *OwnerRepository) Add(ctx context.Context, data *models.Owner) (int64, error) {
 	ctx, cancel := context.WithTimeout(ctx, timeOut)
 	defer cancel()
-	rows := r.store.QueryRowContext(ctx, `insert into owner (user_uuid, data_type, data_uuid) values ($1, $2, $3) returning id`, data.UserUUID, data.DataType, data.DataUUID)
+	rows := storage.Query(ctx, r.store).QueryRowContext(ctx, `insert into owner (user_uuid, data_type, data_uuid) values ($1, $2, $3) returning id`, data.UserUUID, data.DataType, data.DataUUID)
 	err := rows.Err()
 	if err != nil {
 		return 0, ErrorMsg(err)
@@ -96,6 +97,23 @@ func (r *OwnerRepository) Add(ctx context.Context, data *models.Owner) (int64, e
 	return id, nil
 }
 
+// Delete удаление владельца данных (запись в deleted_items добавляет триггер).
+// Вернёт false, если у пользователя нет таких данных
+func (r *OwnerRepository) Delete(ctx context.Context, userUUID string, dataUUID string, dataType string) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeOut)
+	defer cancel()
+	result, err := storage.Query(ctx, r.store).ExecContext(ctx, `delete from owner where user_uuid = $1 and data_uuid = $2 and data_type = $3`, userUUID, dataUUID, dataType)
+	if err != nil {
+		return false, ErrorMsg(err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, ErrorMsg(err)
+	}
+	return affected > 0, nil
+}
+
+// AllOwnerData данные пользователя постранично
 func (r *OwnerRepository) AllOwnerData(ctx context.Context, userUUID string, offset int, limit int) ([]models.OwnerData, error) {
 	ctx, cancel := context.WithTimeout(ctx, timeOut)
 	defer cancel()
@@ -103,17 +121,21 @@ func (r *OwnerRepository) AllOwnerData(ctx context.Context, userUUID string, off
 o.data_type as data_type,
 o.data_uuid as data_uuid,
 o.user_uuid as user_uuid,
-coalesce(cd."name", fd."name", td."name") as "name" 
+coalesce(cd."name", fd."name", td."name", crd."name") as "name",
+coalesce(cd.created_at, fd.created_at, td.created_at, crd.created_at) as created_at,
+coalesce(cd.updated_at, fd.updated_at, td.updated_at, crd.updated_at) as updated_at,
+coalesce(cd.revision, fd.revision, td.revision, crd.revision) as revision
 from owner o
 left join card_data cd on cd."uuid"  = o.data_uuid 
 left join file_data fd on fd."uuid"  = o.data_uuid 
 left join text_data td on td."uuid"  = o.data_uuid 
+left join credential_data crd on crd."uuid"  = o.data_uuid 
 where o.user_uuid  = $1
 order by o.id asc
 offset $2 limit $3
 `
 
-	rows, err := r.store.QueryContext(ctx, query, userUUID, offset, limit)
+	rows, err := storage.Query(ctx, r.store).QueryContext(ctx, query, userUUID, offset, limit)
 	if err != nil {
 		return nil, ErrorMsg(err)
 	}
@@ -125,7 +147,7 @@ offset $2 limit $3
 	var dataList []models.OwnerData
 	for rows.Next() {
 		data := models.OwnerData{}
-		err = rows.Scan(&data.DataType, &data.DataUUID, &data.UserUUID, &data.DataName)
+		err = rows.Scan(&data.DataType, &data.DataUUID, &data.UserUUID, &data.DataName, &data.CreatedAt, &data.UpdatedAt, &data.Revision)
 		if err != nil {
 			return nil, ErrorMsg(err)
 		}
@@ -135,3 +157,75 @@ offset $2 limit $3
 
 	return dataList, nil
 }
+
+// ChangesSince изменения данных пользователя с ревизией больше revision, по возрастанию ревизии.
+// Удалённые данные возвращаются из deleted_items с признаком Deleted.
+// Ревизии выдаются до фиксации транзакций, поэтому запрос ждёт транзакции, уже получившие ревизию
+// этого пользователя (блокировка из триггеров ревизий): иначе ревизия транзакции, зафиксированной позже, оказалась бы до курсора клиента
+func (r *OwnerRepository) ChangesSince(ctx context.Context, userUUID string, revision int64, limit int) ([]models.OwnerData, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeOut)
+	defer cancel()
+	tx, err := storage.BeginTx(ctx, r.store)
+	if err != nil {
+		return nil, ErrorMsg(err)
+	}
+	_, err = tx.ExecContext(ctx, `select pg_advisory_xact_lock(hashtext($1))`, "data_revision:"+userUUID)
+	if err != nil {
+		return nil, ErrorMsg(errors.Join(err, tx.Rollback()))
+	}
+	query := `select data_type, data_uuid, user_uuid, "name", created_at, updated_at, revision, deleted from (
+select o.data_type, o.data_uuid, o.user_uuid, cd."name", cd.created_at, cd.updated_at, cd.revision, false as deleted
+from owner o
+join card_data cd on cd."uuid" = o.data_uuid
+where o.user_uuid = $1 and cd.revision > $2
+union all
+select o.data_type, o.data_uuid, o.user_uuid, fd."name", fd.created_at, fd.updated_at, fd.revision, false
+from owner o
+join file_data fd on fd."uuid" = o.data_uuid
+where o.user_uuid = $1 and fd.revision > $2
+union all
+select o.data_type, o.data_uuid, o.user_uuid, td."name", td.created_at, td.updated_at, td.revision, false
+from owner o
+join text_data td on td."uuid" = o.data_uuid
+where o.user_uuid = $1 and td.revision > $2
+union all
+select o.data_type, o.data_uuid, o.user_uuid, crd."name", crd.created_at, crd.updated_at, crd.revision, false
+from owner o
+join credential_data crd on crd."uuid" = o.data_uuid
+where o.user_uuid = $1 and crd.revision > $2
+union all
+select di.data_type, di.data_uuid, di.user_uuid, '', di.deleted_at, di.deleted_at, di.revision, true
+from deleted_items di
+where di.user_uuid = $1 and di.revision > $2
+) changes
+order by revision asc
+limit $3
+`
+
+	rows, err := tx.QueryContext(ctx, query, userUUID, revision, limit)
+	if err != nil {
+		return nil, ErrorMsg(errors.Join(err, tx.Rollback()))
+	}
+	defer rows.Close()
+	err = rows.Err()
+	if err != nil {
+		return nil, ErrorMsg(errors.Join(err, tx.Rollback()))
+	}
+
+	var dataList []models.OwnerData
+	for rows.Next() {
+		data := models.OwnerData{}
+		err = rows.Scan(&data.DataType, &data.DataUUID, &data.UserUUID, &data.DataName, &data.CreatedAt, &data.UpdatedAt, &data.Revision, &data.Deleted)
+		if err != nil {
+			return nil, ErrorMsg(errors.Join(err, tx.Rollback()))
+		}
+		data.DataTypeName = data_type.TranslateDataType(data.DataType)
+		dataList = append(dataList, data)
+	}
+	_ = rows.Close()
+	if err = tx.Commit(); err != nil {
+		return nil, ErrorMsg(err)
+	}
+
+	return dataList, nil
+}
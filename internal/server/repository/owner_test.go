@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/northmule/gophkeeper/internal/common/data_type"
@@ -169,10 +170,32 @@ func (s *OwnerRepositoryTestSuite) TestAdd_DuplicateUUID() {
 	assert.Equal(s.T(), int64(0), id)
 }
 
+func (s *OwnerRepositoryTestSuite) TestDelete() {
+	s.mock.ExpectExec("delete from owner").
+		WithArgs("user-uuid", "data-uuid", "card_type").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	deleted, err := s.repository.Delete(context.Background(), "user-uuid", "data-uuid", "card_type")
+	require.NoError(s.T(), err)
+	assert.True(s.T(), deleted)
+
+	s.mock.ExpectExec("delete from owner").
+		WithArgs("user-uuid", "other-uuid", "card_type").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	deleted, err = s.repository.Delete(context.Background(), "user-uuid", "other-uuid", "card_type")
+	require.NoError(s.T(), err)
+	assert.False(s.T(), deleted)
+
+	s.mock.ExpectExec("delete from owner").
+		WillReturnError(errors.New("delete failed"))
+	_, err = s.repository.Delete(context.Background(), "user-uuid", "data-uuid", "card_type")
+	require.Error(s.T(), err)
+}
+
 func (s *OwnerRepositoryTestSuite) TestAllOwnerData_ValidData() {
 	userUUID := "user-uuid"
 	offset := 0
 	limit := 10
+	now := time.Now()
 	expectedData := []models.OwnerData{
 		{
 			DataType:     "data-type",
@@ -180,13 +203,16 @@ func (s *OwnerRepositoryTestSuite) TestAllOwnerData_ValidData() {
 			UserUUID:     userUUID,
 			DataName:     "data-name",
 			DataTypeName: data_type.TranslateDataType("data-type"),
+			CreatedAt:    now,
+			UpdatedAt:    now,
+			Revision:     5,
 		},
 	}
 
 	s.mock.ExpectQuery("select o.data_type as data_type").
 		WithArgs(userUUID, offset, limit).
-		WillReturnRows(sqlmock.NewRows([]string{"data_type", "data_uuid", "user_uuid", "name"}).
-			AddRow(expectedData[0].DataType, expectedData[0].DataUUID, expectedData[0].UserUUID, expectedData[0].DataName))
+		WillReturnRows(sqlmock.NewRows([]string{"data_type", "data_uuid", "user_uuid", "name", "created_at", "updated_at", "revision"}).
+			AddRow(expectedData[0].DataType, expectedData[0].DataUUID, expectedData[0].UserUUID, expectedData[0].DataName, now, now, 5))
 
 	data, err := s.repository.AllOwnerData(context.Background(), userUUID, offset, limit)
 	require.NoError(s.T(), err)
@@ -200,7 +226,7 @@ func (s *OwnerRepositoryTestSuite) TestAllOwnerData_NoData() {
 
 	s.mock.ExpectQuery("select o.data_type as data_type, o.data_uuid as data_uuid").
 		WithArgs(userUUID, offset, limit).
-		WillReturnRows(sqlmock.NewRows([]string{"data_type", "data_uuid", "user_uuid", "name"}))
+		WillReturnRows(sqlmock.NewRows([]string{"data_type", "data_uuid", "user_uuid", "name", "created_at", "updated_at", "revision"}))
 
 	data, err := s.repository.AllOwnerData(context.Background(), userUUID, offset, limit)
 	require.NoError(s.T(), err)
@@ -230,3 +256,65 @@ func (s *OwnerRepositoryTestSuite) TestAllOwnerData_InvalidUserUUID() {
 	require.Error(s.T(), err)
 	assert.Empty(s.T(), data)
 }
+
+func (s *OwnerRepositoryTestSuite) TestChangesSince_ValidData() {
+	userUUID := "user-uuid"
+	now := time.Now()
+	// изменения читаются после транзакций пользователя, получивших ревизию раньше
+	s.mock.ExpectBegin()
+	s.mock.ExpectExec("select pg_advisory_xact_lock").
+		WithArgs("data_revision:" + userUUID).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	// условие по ревизии в каждой части union, чтобы использовались индексы ревизий
+	s.mock.ExpectQuery("select data_type, data_uuid, user_uuid, \"name\", created_at, updated_at, revision, deleted from \\("+
+		"(.+join card_data cd .+ cd.revision > \\$2)"+
+		"(.+join file_data fd .+ fd.revision > \\$2)"+
+		"(.+join text_data td .+ td.revision > \\$2)"+
+		"(.+join credential_data crd .+ crd.revision > \\$2)"+
+		"(.+from deleted_items di .+ di.revision > \\$2)"+
+		"\\s+\\) changes").
+		WithArgs(userUUID, int64(3), 10).
+		WillReturnRows(sqlmock.NewRows([]string{"data_type", "data_uuid", "user_uuid", "name", "created_at", "updated_at", "revision", "deleted"}).
+			AddRow(data_type.TextType, "text-uuid", userUUID, "note", now, now, 4, false).
+			AddRow(data_type.CardType, "card-uuid", userUUID, "", now, now, 5, true))
+	s.mock.ExpectCommit()
+
+	data, err := s.repository.ChangesSince(context.Background(), userUUID, 3, 10)
+	require.NoError(s.T(), err)
+	require.Len(s.T(), data, 2)
+	assert.Equal(s.T(), "note", data[0].DataName)
+	assert.Equal(s.T(), int64(4), data[0].Revision)
+	assert.False(s.T(), data[0].Deleted)
+	assert.Equal(s.T(), "card-uuid", data[1].DataUUID)
+	assert.True(s.T(), data[1].Deleted)
+	assert.Equal(s.T(), data_type.TranslateDataType(data_type.CardType), data[1].DataTypeName)
+	assert.NoError(s.T(), s.mock.ExpectationsWereMet())
+}
+
+func (s *OwnerRepositoryTestSuite) TestChangesSince_Error() {
+	s.mock.ExpectBegin()
+	s.mock.ExpectExec("select pg_advisory_xact_lock").
+		WithArgs("data_revision:user-uuid").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	s.mock.ExpectQuery("select data_type, data_uuid, user_uuid").
+		WithArgs("user-uuid", int64(0), 10).
+		WillReturnError(errors.New("query failed"))
+	s.mock.ExpectRollback()
+
+	data, err := s.repository.ChangesSince(context.Background(), "user-uuid", 0, 10)
+	require.Error(s.T(), err)
+	assert.Empty(s.T(), data)
+	assert.NoError(s.T(), s.mock.ExpectationsWereMet())
+}
+
+func (s *OwnerRepositoryTestSuite) TestChangesSince_LockError() {
+	s.mock.ExpectBegin()
+	s.mock.ExpectExec("select pg_advisory_xact_lock").
+		WillReturnError(errors.New("lock timeout"))
+	s.mock.ExpectRollback()
+
+	data, err := s.repository.ChangesSince(context.Background(), "user-uuid", 0, 10)
+	require.Error(s.T(), err)
+	assert.Empty(s.T(), data)
+	assert.NoError(s.T(), s.mock.ExpectationsWereMet())
+}
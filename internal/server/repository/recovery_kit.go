@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/northmule/gophkeeper/internal/common/models"
+	"github.com/northmule/gophkeeper/internal/server/storage"
+)
+
+// RecoveryKitRepository репозитарий комплектов восстановления
+type RecoveryKitRepository struct {
+	store                storage.DBQuery
+	sqlFindOneByUserUUID *sql.Stmt
+}
+
+// NewRecoveryKitRepository конструктор
+func NewRecoveryKitRepository(store storage.DBQuery) (*RecoveryKitRepository, error) {
+	var err error
+	instance := new(RecoveryKitRepository)
+	instance.store = store
+	instance.sqlFindOneByUserUUID, err = store.Prepare(`select id, user_uuid, vault_key, client_key, created_at, updated_at from recovery_kits where user_uuid = $1 limit 1`)
+	if err != nil {
+		return nil, ErrorMsg(err)
+	}
+	return instance, nil
+}
+
+// FindOneByUserUUID комплект восстановления пользователя, nil если не создавался
+func (r *RecoveryKitRepository) FindOneByUserUUID(ctx context.Context, userUUID string) (*models.RecoveryKit, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeOut)
+	defer cancel()
+	rows, err := storage.Stmt(ctx, r.sqlFindOneByUserUUID).QueryContext(ctx, userUUID)
+	if err != nil {
+		return nil, ErrorMsg(err)
+	}
+	defer rows.Close()
+	err = rows.Err()
+	if err != nil {
+		return nil, ErrorMsg(err)
+	}
+	if !rows.Next() {
+		return nil, nil
+	}
+	kit := new(models.RecoveryKit)
+	err = rows.Scan(&kit.ID, &kit.UserUUID, &kit.VaultKey, &kit.ClientKey, &kit.CreatedAt, &kit.UpdatedAt)
+	if err != nil {
+		return nil, ErrorMsg(err)
+	}
+	return kit, nil
+}
+
+// Save сохранение комплекта восстановления. Пустой VaultKey оставляет прежнее значение
+// (после смены ключа клиента обновляется только ClientKey)
+func (r *RecoveryKitRepository) Save(ctx context.Context, kit *models.RecoveryKit) error {
+	ctx, cancel := context.WithTimeout(ctx, timeOut)
+	defer cancel()
+	_, err := storage.Query(ctx, r.store).ExecContext(
+		ctx,
+		`insert into recovery_kits (user_uuid, vault_key, client_key) values ($1, $2, $3)
+			on conflict (user_uuid) do update set vault_key = coalesce(nullif(excluded.vault_key, ''), recovery_kits.vault_key), client_key = excluded.client_key, updated_at = now()`,
+		kit.UserUUID, kit.VaultKey, kit.ClientKey,
+	)
+	if err != nil {
+		return ErrorMsg(err)
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/northmule/gophkeeper/internal/common/models"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"golang.org/x/net/context"
+)
+
+type RecoveryKitRepositoryTestSuite struct {
+	suite.Suite
+	DB         *sql.DB
+	mock       sqlmock.Sqlmock
+	repository *RecoveryKitRepository
+}
+
+func (s *RecoveryKitRepositoryTestSuite) SetupTest() {
+	var err error
+	s.DB, s.mock, err = sqlmock.New()
+	require.NoError(s.T(), err)
+	s.mock.ExpectPrepare("select id, user_uuid, vault_key")
+	s.repository, err = NewRecoveryKitRepository(s.DB)
+	require.NoError(s.T(), err)
+}
+
+func TestRecoveryKitRepositoryTestSuite(t *testing.T) {
+	suite.Run(t, new(RecoveryKitRepositoryTestSuite))
+}
+
+func (s *RecoveryKitRepositoryTestSuite) TestFindOneByUserUUID() {
+	s.mock.ExpectQuery("select").
+		WithArgs("user-uuid").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_uuid", "vault_key", "client_key", "created_at", "updated_at"}).
+			AddRow(1, "user-uuid", "sealedVaultKey", "sealedClientKey", time.Now(), time.Now()))
+
+	kit, err := s.repository.FindOneByUserUUID(context.Background(), "user-uuid")
+	require.NoError(s.T(), err)
+	require.NotNil(s.T(), kit)
+	s.Equal("sealedVaultKey", kit.VaultKey)
+	s.Equal("sealedClientKey", kit.ClientKey)
+}
+
+func (s *RecoveryKitRepositoryTestSuite) TestFindOneByUserUUID_NotFound() {
+	s.mock.ExpectQuery("select").
+		WithArgs("user-uuid").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_uuid", "vault_key", "client_key", "created_at", "updated_at"}))
+
+	kit, err := s.repository.FindOneByUserUUID(context.Background(), "user-uuid")
+	require.NoError(s.T(), err)
+	s.Nil(kit)
+}
+
+func (s *RecoveryKitRepositoryTestSuite) TestSave() {
+	s.mock.ExpectExec("insert into recovery_kits").
+		WithArgs("user-uuid", "", "sealedClientKey").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err := s.repository.Save(context.Background(), &models.RecoveryKit{UserUUID: "user-uuid", ClientKey: "sealedClientKey"})
+	require.NoError(s.T(), err)
+	s.NoError(s.mock.ExpectationsWereMet())
+}
+
+func (s *RecoveryKitRepositoryTestSuite) TestSave_Error() {
+	s.mock.ExpectExec("insert into recovery_kits").
+		WillReturnError(errors.New("db error"))
+
+	err := s.repository.Save(context.Background(), &models.RecoveryKit{UserUUID: "user-uuid"})
+	s.Error(err)
+}
@@ -0,0 +1,399 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/northmule/gophkeeper/internal/common/models"
+	"github.com/northmule/gophkeeper/internal/server/storage"
+)
+
+// ErrVersionConflict данные изменены после чтения: версия в базе не совпадает с версией сохраняемых данных
+var ErrVersionConflict = errors.New("data version conflict")
+
+// Repository менеджер репозитариев
+type Repository interface {
+	User() UserDataModelRepository
+	CardData() CardDataModelRepository
+	Owner() OwnerDataModelRepository
+	MetaData() MetaDataModelRepository
+	TextData() TextDataModelRepository
+	FileData() FileDataModelRepository
+	FileChunk() FileChunkModelRepository
+	CredentialData() CredentialDataModelRepository
+	Session() SessionModelRepository
+	TOTP() TOTPModelRepository
+	ClientCertificate() ClientCertificateModelRepository
+	KeyRotation() KeyRotationModelRepository
+	RecoveryKit() RecoveryKitModelRepository
+	LoginFailure() LoginFailureModelRepository
+	LoginChallenge() LoginChallengeModelRepository
+	RequestNonce() RequestNonceModelRepository
+	AuditEvent() AuditEventModelRepository
+	Device() DeviceModelRepository
+	IdempotencyKey() IdempotencyKeyModelRepository
+}
+
+// UserDataModelRepository операции над пользователями
+type UserDataModelRepository interface {
+	FindOneByLogin(ctx context.Context, login string) (*models.User, error)
+	FindOneByUUID(ctx context.Context, uuid string) (*models.User, error)
+	CreateNewUser(ctx context.Context, user models.User) (int64, error)
+	SetPrivateClientKey(ctx context.Context, data string, userUUID string) error
+	FindAllPrivateClientKeys(ctx context.Context) ([]models.User, error)
+	ReplacePrivateClientKey(ctx context.Context, userUUID string, oldValue string, newValue string) (bool, error)
+	SetMasterKey(ctx context.Context, salt string, check string, userUUID string) (bool, error)
+	ClearVaultLegacy(ctx context.Context, userUUID string) error
+	SetPassword(ctx context.Context, hash string, userUUID string) error
+}
+
+// CardDataModelRepository операции над данными карт
+type CardDataModelRepository interface {
+	FindOneByUUID(ctx context.Context, uuid string) (*models.CardData, error)
+	Add(ctx context.Context, data *models.CardData) (int64, error)
+	Update(ctx context.Context, data *models.CardData) error
+	Delete(ctx context.Context, uuid string, version int64) error
+}
+
+// OwnerDataModelRepository операции над владельцами данных
+type OwnerDataModelRepository interface {
+	FindOneByUserUUIDAndDataUUIDAndDataType(ctx context.Context, userUuid string, dataUuid string, dataType string) (*models.Owner, error)
+	FindOneByUserUUIDAndDataUUID(ctx context.Context, userUuid string, dataUuid string) (*models.Owner, error)
+	Add(ctx context.Context, data *models.Owner) (int64, error)
+	Delete(ctx context.Context, userUUID string, dataUUID string, dataType string) (bool, error)
+	AllOwnerData(ctx context.Context, userUUID string, offset int, limit int) ([]models.OwnerData, error)
+	ChangesSince(ctx context.Context, userUUID string, revision int64, limit int) ([]models.OwnerData, error)
+}
+
+// MetaDataModelRepository операции над мета данными
+type MetaDataModelRepository interface {
+	FindOneByUUID(ctx context.Context, uuid string) ([]models.MetaData, error)
+	Add(ctx context.Context, data *models.MetaData) (int64, error)
+	ReplaceMetaByDataUUID(ctx context.Context, dataUUID string, metaDataList []models.MetaData) error
+}
+
+// TextDataModelRepository операции над текстовыми данными
+type TextDataModelRepository interface {
+	FindOneByUUID(ctx context.Context, uuid string) (*models.TextData, error)
+	Add(ctx context.Context, data *models.TextData) (int64, error)
+	Update(ctx context.Context, data *models.TextData) error
+	Delete(ctx context.Context, uuid string, version int64) error
+}
+
+// FileDataModelRepository операции над данными файлов
+type FileDataModelRepository interface {
+	FindOneByUUID(ctx context.Context, uuid string) (*models.FileData, error)
+	Add(ctx context.Context, data *models.FileData) (int64, error)
+	Update(ctx context.Context, data *models.FileData) error
+	Lock(ctx context.Context, uuid string) error
+	SetUploaded(ctx context.Context, uuid string, uploaded bool) error
+}
+
+// FileChunkModelRepository операции над принятыми частями файлов
+type FileChunkModelRepository interface {
+	FindAllByFileUUID(ctx context.Context, fileUUID string) ([]models.FileChunk, error)
+	Save(ctx context.Context, chunk *models.FileChunk) error
+	DeleteByFileUUID(ctx context.Context, fileUUID string) error
+}
+
+// CredentialDataModelRepository операции над парами логин/пароль
+type CredentialDataModelRepository interface {
+	FindOneByUUID(ctx context.Context, uuid string) (*models.CredentialData, error)
+	Add(ctx context.Context, data *models.CredentialData) (int64, error)
+	Update(ctx context.Context, data *models.CredentialData) error
+	Delete(ctx context.Context, uuid string, version int64) error
+}
+
+// SessionModelRepository операции над сессиями пользователей
+type SessionModelRepository interface {
+	FindOneByUUID(ctx context.Context, uuid string) (*models.Session, error)
+	FindOneByRefreshTokenHash(ctx context.Context, hash string) (*models.Session, error)
+	FindAllActiveByUserUUID(ctx context.Context, userUUID string) ([]models.Session, error)
+	Add(ctx context.Context, data *models.Session) (int64, error)
+	Rotate(ctx context.Context, uuid string, oldHash string, newHash string, expiresAt time.Time) (bool, error)
+	Revoke(ctx context.Context, uuid string) error
+	RevokeAllByUserUUID(ctx context.Context, userUUID string) error
+}
+
+// TOTPModelRepository операции над вторым фактором (TOTP) и резервными кодами
+type TOTPModelRepository interface {
+	FindOneByUserUUID(ctx context.Context, userUUID string) (*models.UserTOTP, error)
+	Save(ctx context.Context, userUUID string, secret string) error
+	Enable(ctx context.Context, userUUID string) error
+	UseStep(ctx context.Context, userUUID string, step int64) (bool, error)
+	Delete(ctx context.Context, userUUID string) error
+	ReplaceBackupCodes(ctx context.Context, userUUID string, codeHashes []string) error
+	UseBackupCode(ctx context.Context, userUUID string, codeHash string) (bool, error)
+}
+
+// ClientCertificateModelRepository операции над сертификатами клиентов
+type ClientCertificateModelRepository interface {
+	FindOneByFingerprint(ctx context.Context, fingerprint string) (*models.ClientCertificate, error)
+	Add(ctx context.Context, data *models.ClientCertificate) (int64, error)
+}
+
+// KeyRotationModelRepository операции над сменами ключа шифрования клиентов
+type KeyRotationModelRepository interface {
+	FindLastByDeviceUUID(ctx context.Context, userUUID string, deviceUUID string) (*models.KeyRotation, error)
+	FindAllRunning(ctx context.Context) ([]models.KeyRotation, error)
+	Add(ctx context.Context, data *models.KeyRotation) (int64, error)
+	Fail(ctx context.Context, uuid string, reason string) error
+	Finish(ctx context.Context, rotation *models.KeyRotation) error
+}
+
+// RecoveryKitModelRepository операции над комплектами восстановления
+type RecoveryKitModelRepository interface {
+	FindOneByUserUUID(ctx context.Context, userUUID string) (*models.RecoveryKit, error)
+	Save(ctx context.Context, kit *models.RecoveryKit) error
+}
+
+// LoginFailureModelRepository операции над счётчиками неудачных попыток входа
+type LoginFailureModelRepository interface {
+	FindOneByLogin(ctx context.Context, login string) (*models.LoginFailure, error)
+	Fail(ctx context.Context, login string, failedAt time.Time, resetBefore time.Time) (int, error)
+	Lock(ctx context.Context, login string, lockedUntil time.Time) error
+	Reset(ctx context.Context, login string) error
+}
+
+// LoginChallengeModelRepository операции над входами, ожидающими кода второго фактора
+type LoginChallengeModelRepository interface {
+	Add(ctx context.Context, tokenHash string, userUUID string, expiresAt time.Time) error
+	Attempt(ctx context.Context, tokenHash string, maxAttempts int) (string, error)
+	Remove(ctx context.Context, tokenHash string) error
+}
+
+// RequestNonceModelRepository операции над nonce принятых зашифрованных запросов
+type RequestNonceModelRepository interface {
+	Use(ctx context.Context, userUUID string, nonce string, expiresAt time.Time, expiredBefore time.Time) (bool, error)
+}
+
+// AuditEventModelRepository операции над журналом действий пользователей
+type AuditEventModelRepository interface {
+	Add(ctx context.Context, event *models.AuditEvent, chainHash func(event *models.AuditEvent) string) (int64, error)
+	FindAll(ctx context.Context, filter AuditEventFilter, offset int, limit int) ([]models.AuditEvent, error)
+	FindChain(ctx context.Context, userUUID string, afterID int64, limit int) ([]models.AuditEvent, error)
+}
+
+// DeviceModelRepository операции над устройствами пользователей
+type DeviceModelRepository interface {
+	FindOneByUUID(ctx context.Context, uuid string) (*models.Device, error)
+	FindAllByUserUUID(ctx context.Context, userUUID string) ([]models.Device, error)
+	Add(ctx context.Context, data *models.Device) (int64, error)
+	Touch(ctx context.Context, uuid string) error
+	SetKeys(ctx context.Context, uuid string, publicKey string, vaultKey string) error
+	SetClientKey(ctx context.Context, uuid string, clientKey string) (bool, error)
+	Rename(ctx context.Context, userUUID string, uuid string, name string) (bool, error)
+	Revoke(ctx context.Context, userUUID string, uuid string) (bool, error)
+}
+
+// IdempotencyKeyModelRepository операции над ключами идемпотентности запросов сохранения
+type IdempotencyKeyModelRepository interface {
+	FindOne(ctx context.Context, userUUID string, key string) (*models.IdempotencyKey, error)
+	Reserve(ctx context.Context, data *models.IdempotencyKey, expiredBefore time.Time, leaseExpiredBefore time.Time) (bool, error)
+	Complete(ctx context.Context, userUUID string, key string, statusCode int, response string) error
+	Release(ctx context.Context, userUUID string, key string) error
+}
+
+// Manager менеджер репозитариев
+type Manager struct {
+	user              *UserRepository
+	cardData          *CardDataRepository
+	owner             *OwnerRepository
+	metaData          *MetaDataRepository
+	textData          *TextDataRepository
+	fileData          *FileDataRepository
+	fileChunk         *FileChunkRepository
+	credentialData    *CredentialDataRepository
+	session           *SessionRepository
+	totp              *TOTPRepository
+	clientCertificate *ClientCertificateRepository
+	keyRotation       *KeyRotationRepository
+	recoveryKit       *RecoveryKitRepository
+	loginFailure      *LoginFailureRepository
+	loginChallenge    *LoginChallengeRepository
+	requestNonce      *RequestNonceRepository
+	auditEvent        *AuditEventRepository
+	device            *DeviceRepository
+	idempotencyKey    *IdempotencyKeyRepository
+}
+
+// NewManager конструктор
+func NewManager(store storage.DBQuery) (*Manager, error) {
+	var err error
+	instance := new(Manager)
+
+	instance.user, err = NewUserRepository(store)
+	if err != nil {
+		return nil, err
+	}
+	instance.cardData, err = NewCardDataRepository(store)
+	if err != nil {
+		return nil, err
+	}
+	instance.owner, err = NewOwnerRepository(store)
+	if err != nil {
+		return nil, err
+	}
+	instance.metaData, err = NewMetaDataRepository(store)
+	if err != nil {
+		return nil, err
+	}
+	instance.textData, err = NewTextDataRepository(store)
+	if err != nil {
+		return nil, err
+	}
+	instance.fileData, err = NewFileDataRepository(store)
+	if err != nil {
+		return nil, err
+	}
+	instance.fileChunk, err = NewFileChunkRepository(store)
+	if err != nil {
+		return nil, err
+	}
+	instance.credentialData, err = NewCredentialDataRepository(store)
+	if err != nil {
+		return nil, err
+	}
+	instance.session, err = NewSessionRepository(store)
+	if err != nil {
+		return nil, err
+	}
+	instance.totp, err = NewTOTPRepository(store)
+	if err != nil {
+		return nil, err
+	}
+	instance.clientCertificate, err = NewClientCertificateRepository(store)
+	if err != nil {
+		return nil, err
+	}
+	instance.keyRotation, err = NewKeyRotationRepository(store)
+	if err != nil {
+		return nil, err
+	}
+	instance.recoveryKit, err = NewRecoveryKitRepository(store)
+	if err != nil {
+		return nil, err
+	}
+	instance.loginFailure, err = NewLoginFailureRepository(store)
+	if err != nil {
+		return nil, err
+	}
+	instance.loginChallenge, err = NewLoginChallengeRepository(store)
+	if err != nil {
+		return nil, err
+	}
+	instance.requestNonce, err = NewRequestNonceRepository(store)
+	if err != nil {
+		return nil, err
+	}
+	instance.auditEvent, err = NewAuditEventRepository(store)
+	if err != nil {
+		return nil, err
+	}
+	instance.device, err = NewDeviceRepository(store)
+	if err != nil {
+		return nil, err
+	}
+	instance.idempotencyKey, err = NewIdempotencyKeyRepository(store)
+	if err != nil {
+		return nil, err
+	}
+
+	return instance, nil
+}
+
+// User репозитарий пользователей
+func (m *Manager) User() UserDataModelRepository {
+	return m.user
+}
+
+// CardData репозитарий карт
+func (m *Manager) CardData() CardDataModelRepository {
+	return m.cardData
+}
+
+// Owner репозитарий владельцев данных
+func (m *Manager) Owner() OwnerDataModelRepository {
+	return m.owner
+}
+
+// MetaData репозитарий мета данных
+func (m *Manager) MetaData() MetaDataModelRepository {
+	return m.metaData
+}
+
+// TextData репозитарий текстовых данных
+func (m *Manager) TextData() TextDataModelRepository {
+	return m.textData
+}
+
+// FileData репозитарий файлов
+func (m *Manager) FileData() FileDataModelRepository {
+	return m.fileData
+}
+
+// FileChunk репозитарий частей файлов
+func (m *Manager) FileChunk() FileChunkModelRepository {
+	return m.fileChunk
+}
+
+// CredentialData репозитарий пар логин/пароль
+func (m *Manager) CredentialData() CredentialDataModelRepository {
+	return m.credentialData
+}
+
+// Session репозитарий сессий пользователей
+func (m *Manager) Session() SessionModelRepository {
+	return m.session
+}
+
+// TOTP репозитарий второго фактора
+func (m *Manager) TOTP() TOTPModelRepository {
+	return m.totp
+}
+
+// ClientCertificate репозитарий сертификатов клиентов
+func (m *Manager) ClientCertificate() ClientCertificateModelRepository {
+	return m.clientCertificate
+}
+
+// KeyRotation репозитарий смен ключа шифрования клиентов
+func (m *Manager) KeyRotation() KeyRotationModelRepository {
+	return m.keyRotation
+}
+
+// RecoveryKit репозитарий комплектов восстановления
+func (m *Manager) RecoveryKit() RecoveryKitModelRepository {
+	return m.recoveryKit
+}
+
+// LoginFailure репозитарий неудачных попыток входа
+func (m *Manager) LoginFailure() LoginFailureModelRepository {
+	return m.loginFailure
+}
+
+// LoginChallenge репозитарий входов, ожидающих второго фактора
+func (m *Manager) LoginChallenge() LoginChallengeModelRepository {
+	return m.loginChallenge
+}
+
+// RequestNonce репозитарий nonce зашифрованных запросов
+func (m *Manager) RequestNonce() RequestNonceModelRepository {
+	return m.requestNonce
+}
+
+// AuditEvent репозитарий журнала действий пользователей
+func (m *Manager) AuditEvent() AuditEventModelRepository {
+	return m.auditEvent
+}
+
+// Device репозитарий устройств пользователей
+func (m *Manager) Device() DeviceModelRepository {
+	return m.device
+}
+
+// IdempotencyKey репозитарий ключей идемпотентности
+func (m *Manager) IdempotencyKey() IdempotencyKeyModelRepository {
+	return m.idempotencyKey
+}
@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/northmule/gophkeeper/internal/server/storage"
+)
+
+// RequestNonceRepository репозитарий nonce принятых зашифрованных запросов.
+// Nonce хранится до конца окна допустимого времени запроса, повтор отклоняется на любом экземпляре сервера
+type RequestNonceRepository struct {
+	store storage.DBQuery
+}
+
+// NewRequestNonceRepository конструктор
+func NewRequestNonceRepository(store storage.DBQuery) (*RequestNonceRepository, error) {
+	instance := new(RequestNonceRepository)
+	instance.store = store
+	return instance, nil
+}
+
+// Use запоминает nonce запроса пользователя до expiresAt. Истёкшие к expiredBefore nonce пользователя удаляются.
+// Вернёт false, если nonce уже использован
+func (r *RequestNonceRepository) Use(ctx context.Context, userUUID string, nonce string, expiresAt time.Time, expiredBefore time.Time) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeOut)
+	defer cancel()
+	_, err := storage.Query(ctx, r.store).ExecContext(ctx, `delete from request_nonces where user_uuid = $1 and expires_at < $2`, userUUID, expiredBefore)
+	if err != nil {
+		return false, ErrorMsg(err)
+	}
+	var id int64
+	err = storage.Query(ctx, r.store).QueryRowContext(
+		ctx,
+		`insert into request_nonces (user_uuid, nonce, expires_at) values ($1, $2, $3)
+			on conflict (user_uuid, nonce) do nothing returning id`,
+		userUUID, nonce, expiresAt,
+	).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, ErrorMsg(err)
+	}
+	return true, nil
+}
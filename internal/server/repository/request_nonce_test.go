@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"golang.org/x/net/context"
+)
+
+type RequestNonceRepositoryTestSuite struct {
+	suite.Suite
+	DB         *sql.DB
+	mock       sqlmock.Sqlmock
+	repository *RequestNonceRepository
+}
+
+func (s *RequestNonceRepositoryTestSuite) SetupTest() {
+	var err error
+	s.DB, s.mock, err = sqlmock.New()
+	require.NoError(s.T(), err)
+	s.repository, err = NewRequestNonceRepository(s.DB)
+	require.NoError(s.T(), err)
+}
+
+func TestRequestNonceRepositoryTestSuite(t *testing.T) {
+	suite.Run(t, new(RequestNonceRepositoryTestSuite))
+}
+
+func (s *RequestNonceRepositoryTestSuite) TestUse() {
+	now := time.Now()
+	expiresAt := now.Add(5 * time.Minute)
+	s.mock.ExpectExec("delete from request_nonces where user_uuid").
+		WithArgs("user-uuid", now).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	s.mock.ExpectQuery("insert into request_nonces").
+		WithArgs("user-uuid", "nonce", expiresAt).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+
+	used, err := s.repository.Use(context.Background(), "user-uuid", "nonce", expiresAt, now)
+	require.NoError(s.T(), err)
+	s.True(used)
+	require.NoError(s.T(), s.mock.ExpectationsWereMet())
+}
+
+func (s *RequestNonceRepositoryTestSuite) TestUse_Replayed() {
+	s.mock.ExpectExec("delete from request_nonces").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	s.mock.ExpectQuery("insert into request_nonces").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	used, err := s.repository.Use(context.Background(), "user-uuid", "nonce", time.Now(), time.Now())
+	require.NoError(s.T(), err)
+	s.False(used)
+}
+
+func (s *RequestNonceRepositoryTestSuite) TestUse_Error() {
+	s.mock.ExpectExec("delete from request_nonces").
+		WillReturnError(errors.New("db error"))
+
+	_, err := s.repository.Use(context.Background(), "user-uuid", "nonce", time.Now(), time.Now())
+	s.Error(err)
+}
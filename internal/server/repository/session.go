@@ -0,0 +1,196 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/northmule/gophkeeper/internal/common/models"
+	"github.com/northmule/gophkeeper/internal/server/storage"
+)
+
+// SessionRepository репозитарий сессий пользователей
+type SessionRepository struct {
+	store storage.DBQuery
+
+	sqlFindOneByUUID             *sql.Stmt
+	sqlFindOneByRefreshTokenHash *sql.Stmt
+	sqlFindAllActiveByUserUUID   *sql.Stmt
+}
+
+const sessionColumns = `id, uuid, user_uuid, device_uuid, refresh_token_hash, previous_refresh_token_hash, created_at, expires_at, revoked_at`
+
+// NewSessionRepository конструктор
+func NewSessionRepository(store storage.DBQuery) (*SessionRepository, error) {
+	var err error
+	instance := new(SessionRepository)
+	instance.store = store
+	instance.sqlFindOneByUUID, err = store.Prepare(`select ` + sessionColumns + ` from sessions where uuid = $1 limit 1`)
+	if err != nil {
+		return nil, ErrorMsg(err)
+	}
+	instance.sqlFindOneByRefreshTokenHash, err = store.Prepare(`select ` + sessionColumns + ` from sessions where refresh_token_hash = $1 or previous_refresh_token_hash = $1 limit 1`)
+	if err != nil {
+		return nil, ErrorMsg(err)
+	}
+	instance.sqlFindAllActiveByUserUUID, err = store.Prepare(`select ` + sessionColumns + ` from sessions where user_uuid = $1 and revoked_at is null and expires_at > now() order by created_at`)
+	if err != nil {
+		return nil, ErrorMsg(err)
+	}
+	return instance, nil
+}
+
+// FindOneByUUID поиск сессии по UUID
+func (r *SessionRepository) FindOneByUUID(ctx context.Context, uuid string) (*models.Session, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeOut)
+	defer cancel()
+	rows, err := storage.Stmt(ctx, r.sqlFindOneByUUID).QueryContext(ctx, uuid)
+	if err != nil {
+		return nil, ErrorMsg(err)
+	}
+	defer rows.Close()
+	return r.scanOne(rows)
+}
+
+// FindOneByRefreshTokenHash поиск сессии по хэшу текущего или предыдущего refresh токена
+func (r *SessionRepository) FindOneByRefreshTokenHash(ctx context.Context, hash string) (*models.Session, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeOut)
+	defer cancel()
+	rows, err := storage.Stmt(ctx, r.sqlFindOneByRefreshTokenHash).QueryContext(ctx, hash)
+	if err != nil {
+		return nil, ErrorMsg(err)
+	}
+	defer rows.Close()
+	return r.scanOne(rows)
+}
+
+// FindAllActiveByUserUUID действующие сессии пользователя
+func (r *SessionRepository) FindAllActiveByUserUUID(ctx context.Context, userUUID string) ([]models.Session, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeOut)
+	defer cancel()
+	rows, err := storage.Stmt(ctx, r.sqlFindAllActiveByUserUUID).QueryContext(ctx, userUUID)
+	if err != nil {
+		return nil, ErrorMsg(err)
+	}
+	defer rows.Close()
+	err = rows.Err()
+	if err != nil {
+		return nil, ErrorMsg(err)
+	}
+	sessions := make([]models.Session, 0)
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			return nil, ErrorMsg(err)
+		}
+		sessions = append(sessions, *session)
+	}
+	return sessions, nil
+}
+
+// Add новая сессия
+func (r *SessionRepository) Add(ctx context.Context, data *models.Session) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeOut)
+	defer cancel()
+	rows := storage.Query(ctx, r.store).QueryRowContext(ctx, `insert into sessions (uuid, user_uuid, device_uuid, refresh_token_hash, expires_at) values ($1, $2, nullif($3, '')::uuid, $4, $5) returning id`, data.UUID, data.UserUUID, data.DeviceUUID, data.RefreshTokenHash, data.ExpiresAt)
+	err := rows.Err()
+	if err != nil {
+		return 0, ErrorMsg(err)
+	}
+
+	var id int64
+	err = rows.Scan(&id)
+	if err != nil {
+		return 0, ErrorMsg(err)
+	}
+	return id, nil
+}
+
+// Rotate замена refresh токена. Вернёт false, если токен уже был заменён или сессия отозвана
+func (r *SessionRepository) Rotate(ctx context.Context, uuid string, oldHash string, newHash string, expiresAt time.Time) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeOut)
+	defer cancel()
+	rows := storage.Query(ctx, r.store).QueryRowContext(
+		ctx,
+		`update sessions set previous_refresh_token_hash = refresh_token_hash, refresh_token_hash = $1, expires_at = $2 where uuid = $3 and refresh_token_hash = $4 and revoked_at is null returning id`,
+		newHash, expiresAt, uuid, oldHash,
+	)
+	var id int64
+	err := rows.Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, ErrorMsg(err)
+	}
+	return true, nil
+}
+
+// Revoke отзыв сессии
+func (r *SessionRepository) Revoke(ctx context.Context, uuid string) error {
+	ctx, cancel := context.WithTimeout(ctx, timeOut)
+	defer cancel()
+	rows := storage.Query(ctx, r.store).QueryRowContext(ctx, `update sessions set revoked_at = now() where uuid = $1 and revoked_at is null`, uuid)
+	err := rows.Err()
+	if err != nil {
+		return ErrorMsg(err)
+	}
+
+	return nil
+}
+
+// RevokeAllByUserUUID отзыв всех сессий пользователя
+func (r *SessionRepository) RevokeAllByUserUUID(ctx context.Context, userUUID string) error {
+	ctx, cancel := context.WithTimeout(ctx, timeOut)
+	defer cancel()
+	rows := storage.Query(ctx, r.store).QueryRowContext(ctx, `update sessions set revoked_at = now() where user_uuid = $1 and revoked_at is null`, userUUID)
+	err := rows.Err()
+	if err != nil {
+		return ErrorMsg(err)
+	}
+
+	return nil
+}
+
+func (r *SessionRepository) scanOne(rows *sql.Rows) (*models.Session, error) {
+	err := rows.Err()
+	if err != nil {
+		return nil, ErrorMsg(err)
+	}
+	if !rows.Next() {
+		return nil, nil
+	}
+	session, err := scanSession(rows)
+	if err != nil {
+		return nil, ErrorMsg(err)
+	}
+	return session, nil
+}
+
+func scanSession(rows *sql.Rows) (*models.Session, error) {
+	session := new(models.Session)
+	var (
+		deviceUUID sql.NullString
+		revokedAt  sql.NullTime
+	)
+	err := rows.Scan(
+		&session.ID,
+		&session.UUID,
+		&session.UserUUID,
+		&deviceUUID,
+		&session.RefreshTokenHash,
+		&session.PreviousRefreshTokenHash,
+		&session.CreatedAt,
+		&session.ExpiresAt,
+		&revokedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	session.DeviceUUID = deviceUUID.String
+	if revokedAt.Valid {
+		session.RevokedAt = &revokedAt.Time
+	}
+	return session, nil
+}
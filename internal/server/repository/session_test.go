@@ -0,0 +1,154 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/northmule/gophkeeper/internal/common/models"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"golang.org/x/net/context"
+)
+
+type SessionRepositoryTestSuite struct {
+	suite.Suite
+	DB         *sql.DB
+	mock       sqlmock.Sqlmock
+	repository *SessionRepository
+}
+
+var sessionRowColumns = []string{"id", "uuid", "user_uuid", "device_uuid", "refresh_token_hash", "previous_refresh_token_hash", "created_at", "expires_at", "revoked_at"}
+
+func (s *SessionRepositoryTestSuite) SetupTest() {
+	var err error
+	s.DB, s.mock, err = sqlmock.New()
+	require.NoError(s.T(), err)
+	s.mock.ExpectPrepare("select id, uuid, user_uuid")
+	s.mock.ExpectPrepare("select id, uuid, user_uuid")
+	s.mock.ExpectPrepare("select id, uuid, user_uuid")
+	s.repository, err = NewSessionRepository(s.DB)
+	require.NoError(s.T(), err)
+}
+
+func TestSessionRepositoryTestSuite(t *testing.T) {
+	suite.Run(t, new(SessionRepositoryTestSuite))
+}
+
+func (s *SessionRepositoryTestSuite) TestFindOneByUUID_Found() {
+	createdAt := time.Now().Add(-time.Hour)
+	expiresAt := time.Now().Add(time.Hour)
+	s.mock.ExpectQuery("select").
+		WithArgs("session-uuid").
+		WillReturnRows(sqlmock.NewRows(sessionRowColumns).
+			AddRow(1, "session-uuid", "user-uuid", "device-uuid", "hash", "", createdAt, expiresAt, nil))
+
+	session, err := s.repository.FindOneByUUID(context.Background(), "session-uuid")
+	require.NoError(s.T(), err)
+	require.NotNil(s.T(), session)
+	require.Equal(s.T(), "session-uuid", session.UUID)
+	require.Equal(s.T(), "user-uuid", session.UserUUID)
+	require.Equal(s.T(), "hash", session.RefreshTokenHash)
+	require.Nil(s.T(), session.RevokedAt)
+	require.True(s.T(), session.IsActive())
+}
+
+func (s *SessionRepositoryTestSuite) TestFindOneByUUID_NotFound() {
+	s.mock.ExpectQuery("select").
+		WithArgs("session-uuid").
+		WillReturnRows(sqlmock.NewRows(sessionRowColumns))
+
+	session, err := s.repository.FindOneByUUID(context.Background(), "session-uuid")
+	require.NoError(s.T(), err)
+	require.Nil(s.T(), session)
+}
+
+func (s *SessionRepositoryTestSuite) TestFindOneByRefreshTokenHash_Revoked() {
+	revokedAt := time.Now()
+	s.mock.ExpectQuery("select").
+		WithArgs("hash").
+		WillReturnRows(sqlmock.NewRows(sessionRowColumns).
+			AddRow(1, "session-uuid", "user-uuid", nil, "new-hash", "hash", time.Now(), time.Now().Add(time.Hour), revokedAt))
+
+	session, err := s.repository.FindOneByRefreshTokenHash(context.Background(), "hash")
+	require.NoError(s.T(), err)
+	require.NotNil(s.T(), session.RevokedAt)
+	require.Equal(s.T(), "hash", session.PreviousRefreshTokenHash)
+	require.False(s.T(), session.IsActive())
+}
+
+func (s *SessionRepositoryTestSuite) TestFindOneByRefreshTokenHash_Error() {
+	s.mock.ExpectQuery("select").
+		WithArgs("hash").
+		WillReturnError(errors.New("query failed"))
+
+	_, err := s.repository.FindOneByRefreshTokenHash(context.Background(), "hash")
+	require.Error(s.T(), err)
+}
+
+func (s *SessionRepositoryTestSuite) TestFindAllActiveByUserUUID() {
+	s.mock.ExpectQuery("select").
+		WithArgs("user-uuid").
+		WillReturnRows(sqlmock.NewRows(sessionRowColumns).
+			AddRow(1, "session-1", "user-uuid", "device-uuid", "hash-1", "", time.Now(), time.Now().Add(time.Hour), nil).
+			AddRow(2, "session-2", "user-uuid", nil, "hash-2", "", time.Now(), time.Now().Add(time.Hour), nil))
+
+	sessions, err := s.repository.FindAllActiveByUserUUID(context.Background(), "user-uuid")
+	require.NoError(s.T(), err)
+	require.Len(s.T(), sessions, 2)
+	require.Equal(s.T(), "session-2", sessions[1].UUID)
+}
+
+func (s *SessionRepositoryTestSuite) TestAdd() {
+	session := &models.Session{UserUUID: "user-uuid", RefreshTokenHash: "hash", ExpiresAt: time.Now().Add(time.Hour)}
+	session.UUID = "session-uuid"
+	s.mock.ExpectQuery("insert into sessions").
+		WithArgs(session.UUID, session.UserUUID, session.DeviceUUID, session.RefreshTokenHash, session.ExpiresAt).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+
+	id, err := s.repository.Add(context.Background(), session)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), int64(7), id)
+}
+
+func (s *SessionRepositoryTestSuite) TestRotate() {
+	expiresAt := time.Now().Add(time.Hour)
+	s.mock.ExpectQuery("update sessions set previous_refresh_token_hash").
+		WithArgs("new-hash", expiresAt, "session-uuid", "old-hash").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+
+	rotated, err := s.repository.Rotate(context.Background(), "session-uuid", "old-hash", "new-hash", expiresAt)
+	require.NoError(s.T(), err)
+	require.True(s.T(), rotated)
+}
+
+func (s *SessionRepositoryTestSuite) TestRotate_AlreadyRotated() {
+	expiresAt := time.Now().Add(time.Hour)
+	s.mock.ExpectQuery("update sessions set previous_refresh_token_hash").
+		WithArgs("new-hash", expiresAt, "session-uuid", "old-hash").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	rotated, err := s.repository.Rotate(context.Background(), "session-uuid", "old-hash", "new-hash", expiresAt)
+	require.NoError(s.T(), err)
+	require.False(s.T(), rotated)
+}
+
+func (s *SessionRepositoryTestSuite) TestRevoke() {
+	s.mock.ExpectQuery("update sessions set revoked_at").
+		WithArgs("session-uuid").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	err := s.repository.Revoke(context.Background(), "session-uuid")
+	require.NoError(s.T(), err)
+}
+
+func (s *SessionRepositoryTestSuite) TestRevokeAllByUserUUID_Error() {
+	s.mock.ExpectQuery("update sessions set revoked_at").
+		WithArgs("user-uuid").
+		WillReturnError(errors.New("update failed"))
+
+	err := s.repository.RevokeAllByUserUUID(context.Background(), "user-uuid")
+	require.Error(s.T(), err)
+}
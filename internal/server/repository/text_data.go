@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"errors"
 
 	"github.com/northmule/gophkeeper/internal/common/models"
 	"github.com/northmule/gophkeeper/internal/server/storage"
@@ -20,7 +21,7 @@ func NewTextDataRepository(store storage.DBQuery) (*TextDataRepository, error) {
 	var err error
 	instance := new(TextDataRepository)
 	instance.store = store
-	instance.sqlFindOneByUUID, err = store.Prepare(`select id, name, value, uuid from text_data where uuid = $1 limit 1`)
+	instance.sqlFindOneByUUID, err = store.Prepare(`select id, name, value, uuid, version from text_data where uuid = $1 limit 1`)
 	if err != nil {
 		return nil, ErrorMsg(err)
 	}
@@ -32,7 +33,7 @@ func (r *TextDataRepository) FindOneByUUID(ctx context.Context, uuid string) (*m
 
 	ctx, cancel := context.WithTimeout(ctx, timeOut)
 	defer cancel()
-	rows, err := r.sqlFindOneByUUID.QueryContext(ctx, uuid)
+	rows, err := storage.Stmt(ctx, r.sqlFindOneByUUID).QueryContext(ctx, uuid)
 	if err != nil {
 		return nil, ErrorMsg(err)
 	}
@@ -42,7 +43,7 @@ func (r *TextDataRepository) FindOneByUUID(ctx context.Context, uuid string) (*m
 	}
 	data := new(models.TextData)
 	if rows.Next() {
-		err = rows.Scan(&data.ID, &data.Name, &data.Value, &data.UUID)
+		err = rows.Scan(&data.ID, &data.Name, &data.Value, &data.UUID, &data.Version)
 		if err != nil {
 			return nil, ErrorMsg(err)
 		}
@@ -55,7 +56,7 @@ func (r *TextDataRepository) FindOneByUUID(ctx context.Context, uuid string) (*m
 func (r *TextDataRepository) Add(ctx context.Context, data *models.TextData) (int64, error) {
 	ctx, cancel := context.WithTimeout(ctx, timeOut)
 	defer cancel()
-	rows := r.store.QueryRowContext(ctx, `insert into text_data (name, "value", uuid) values ($1, $2, $3) returning id`, data.Name, data.Value, data.UUID)
+	rows := storage.Query(ctx, r.store).QueryRowContext(ctx, `insert into text_data (name, "value", uuid) values ($1, $2, $3) returning id`, data.Name, data.Value, data.UUID)
 	err := rows.Err()
 	if err != nil {
 		return 0, ErrorMsg(err)
@@ -69,11 +70,32 @@ func (r *TextDataRepository) Add(ctx context.Context, data *models.TextData) (in
 	return id, nil
 }
 
-// Update Обновление основных полей
+// Update Обновление основных полей, если версия данных не изменилась с data.Version.
+// Новая версия записывается в data.Version, иначе вернёт ErrVersionConflict
 func (r *TextDataRepository) Update(ctx context.Context, data *models.TextData) error {
 	ctx, cancel := context.WithTimeout(ctx, timeOut)
 	defer cancel()
-	rows := r.store.QueryRowContext(ctx, `update text_data set name = $1, value = $2 where uuid = $3`, data.Name, data.Value, data.UUID)
+	err := storage.Query(ctx, r.store).QueryRowContext(ctx, `update text_data set name = $1, value = $2, version = version + 1 where uuid = $3 and version = $4 returning version`, data.Name, data.Value, data.UUID, data.Version).Scan(&data.Version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrVersionConflict
+	}
+	if err != nil {
+		return ErrorMsg(err)
+	}
+	return nil
+}
 
-	return rows.Err()
+// Delete удаление данных, если версия не изменилась с version, иначе вернёт ErrVersionConflict
+func (r *TextDataRepository) Delete(ctx context.Context, uuid string, version int64) error {
+	ctx, cancel := context.WithTimeout(ctx, timeOut)
+	defer cancel()
+	var id int64
+	err := storage.Query(ctx, r.store).QueryRowContext(ctx, `delete from text_data where uuid = $1 and version = $2 returning id`, uuid, version).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrVersionConflict
+	}
+	if err != nil {
+		return ErrorMsg(err)
+	}
+	return nil
 }
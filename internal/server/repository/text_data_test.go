@@ -43,8 +43,8 @@ func (s *TextDataRepositoryTestSuite) TestFindOneByUUID_ValidData() {
 	expectedData.ID = 1
 	s.mock.ExpectQuery("select id, name").
 		WithArgs(uuid).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "value", "uuid"}).
-			AddRow(expectedData.ID, expectedData.Name, expectedData.Value, expectedData.UUID))
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "value", "uuid", "version"}).
+			AddRow(expectedData.ID, expectedData.Name, expectedData.Value, expectedData.UUID, expectedData.Version))
 
 	data, err := s.repository.FindOneByUUID(context.Background(), uuid)
 	require.NoError(s.T(), err)
@@ -56,7 +56,7 @@ func (s *TextDataRepositoryTestSuite) TestFindOneByUUID_NoData() {
 
 	s.mock.ExpectQuery("select id, name").
 		WithArgs(uuid).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "value", "uuid"}))
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "value", "uuid", "version"}))
 
 	data, err := s.repository.FindOneByUUID(context.Background(), uuid)
 	require.NoError(s.T(), err)
@@ -123,12 +123,30 @@ func (s *TextDataRepositoryTestSuite) TestUpdate_ValidData() {
 	}
 	data.ID = 1
 	data.UUID = "test-uuid"
+	data.Version = 1
 	s.mock.ExpectQuery("update text_data").
-		WithArgs(data.Name, data.Value, data.UUID).
-		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		WithArgs(data.Name, data.Value, data.UUID, int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(2))
 
 	err := s.repository.Update(context.Background(), data)
 	require.NoError(s.T(), err)
+	assert.Equal(s.T(), int64(2), data.Version)
+}
+
+func (s *TextDataRepositoryTestSuite) TestUpdate_VersionConflict() {
+	data := &models.TextData{
+		Name:    "updated-name",
+		Value:   "updated-value",
+		Version: 1,
+	}
+	data.UUID = "test-uuid"
+	s.mock.ExpectQuery("update text_data").
+		WithArgs(data.Name, data.Value, data.UUID, int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"version"}))
+
+	err := s.repository.Update(context.Background(), data)
+	assert.ErrorIs(s.T(), err, ErrVersionConflict)
+	assert.Equal(s.T(), int64(1), data.Version)
 }
 
 func (s *TextDataRepositoryTestSuite) TestUpdate_InvalidData() {
@@ -149,8 +167,8 @@ func (s *TextDataRepositoryTestSuite) TestUpdate_Error() {
 	}
 	data.ID = 1
 	data.UUID = "test-uuid"
-	s.mock.ExpectExec("update text_data").
-		WithArgs(data.Name, data.Value, data.UUID).
+	s.mock.ExpectQuery("update text_data").
+		WithArgs(data.Name, data.Value, data.UUID, int64(0)).
 		WillReturnError(errors.New("update failed"))
 
 	err := s.repository.Update(context.Background(), data)
@@ -189,3 +207,19 @@ func (s *TextDataRepositoryTestSuite) TestAdd_DuplicateData() {
 	require.Error(s.T(), err)
 	assert.Equal(s.T(), int64(0), id)
 }
+
+func (s *TextDataRepositoryTestSuite) TestDelete() {
+	s.mock.ExpectQuery("delete from text_data").
+		WithArgs("test-uuid", int64(2)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	err := s.repository.Delete(context.Background(), "test-uuid", 2)
+	require.NoError(s.T(), err)
+
+	// версия изменена или данных нет
+	s.mock.ExpectQuery("delete from text_data").
+		WithArgs("test-uuid", int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	err = s.repository.Delete(context.Background(), "test-uuid", 1)
+	assert.ErrorIs(s.T(), err, ErrVersionConflict)
+	require.NoError(s.T(), s.mock.ExpectationsWereMet())
+}
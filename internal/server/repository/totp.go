@@ -0,0 +1,156 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/northmule/gophkeeper/internal/common/models"
+	"github.com/northmule/gophkeeper/internal/server/storage"
+)
+
+// TOTPRepository репозитарий второго фактора (TOTP) и резервных кодов
+type TOTPRepository struct {
+	store                storage.DBQuery
+	sqlFindOneByUserUUID *sql.Stmt
+}
+
+// NewTOTPRepository конструктор
+func NewTOTPRepository(store storage.DBQuery) (*TOTPRepository, error) {
+	var err error
+	instance := new(TOTPRepository)
+	instance.store = store
+	instance.sqlFindOneByUserUUID, err = store.Prepare(`select id, user_uuid, secret, enabled, last_step, created_at from user_totp where user_uuid = $1 limit 1`)
+	if err != nil {
+		return nil, ErrorMsg(err)
+	}
+	return instance, nil
+}
+
+// FindOneByUserUUID настройки TOTP пользователя, nil если второй фактор не подключался
+func (r *TOTPRepository) FindOneByUserUUID(ctx context.Context, userUUID string) (*models.UserTOTP, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeOut)
+	defer cancel()
+	rows, err := storage.Stmt(ctx, r.sqlFindOneByUserUUID).QueryContext(ctx, userUUID)
+	if err != nil {
+		return nil, ErrorMsg(err)
+	}
+	defer rows.Close()
+	err = rows.Err()
+	if err != nil {
+		return nil, ErrorMsg(err)
+	}
+	if !rows.Next() {
+		return nil, nil
+	}
+	totp := new(models.UserTOTP)
+	err = rows.Scan(&totp.ID, &totp.UserUUID, &totp.Secret, &totp.Enabled, &totp.LastStep, &totp.CreatedAt)
+	if err != nil {
+		return nil, ErrorMsg(err)
+	}
+	return totp, nil
+}
+
+// Save новый неподтверждённый секрет. Ранее подключённый второй фактор выключается до подтверждения
+func (r *TOTPRepository) Save(ctx context.Context, userUUID string, secret string) error {
+	ctx, cancel := context.WithTimeout(ctx, timeOut)
+	defer cancel()
+	_, err := storage.Query(ctx, r.store).ExecContext(
+		ctx,
+		`insert into user_totp (user_uuid, secret) values ($1, $2) on conflict (user_uuid) do update set secret = excluded.secret, enabled = false, last_step = 0, created_at = now()`,
+		userUUID, secret,
+	)
+	if err != nil {
+		return ErrorMsg(err)
+	}
+	return nil
+}
+
+// Enable включение второго фактора после подтверждения кодом
+func (r *TOTPRepository) Enable(ctx context.Context, userUUID string) error {
+	ctx, cancel := context.WithTimeout(ctx, timeOut)
+	defer cancel()
+	_, err := storage.Query(ctx, r.store).ExecContext(ctx, `update user_totp set enabled = true where user_uuid = $1`, userUUID)
+	if err != nil {
+		return ErrorMsg(err)
+	}
+	return nil
+}
+
+// UseStep фиксирует шаг принятого кода. Вернёт false, если код этого или более позднего шага уже принимался
+func (r *TOTPRepository) UseStep(ctx context.Context, userUUID string, step int64) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeOut)
+	defer cancel()
+	rows := storage.Query(ctx, r.store).QueryRowContext(ctx, `update user_totp set last_step = $1 where user_uuid = $2 and last_step < $1 returning id`, step, userUUID)
+	var id int64
+	err := rows.Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, ErrorMsg(err)
+	}
+	return true, nil
+}
+
+// Delete отключение второго фактора вместе с резервными кодами
+func (r *TOTPRepository) Delete(ctx context.Context, userUUID string) error {
+	ctx, cancel := context.WithTimeout(ctx, timeOut)
+	defer cancel()
+	tx, err := storage.BeginTx(ctx, r.store)
+	if err != nil {
+		return ErrorMsg(err)
+	}
+	_, err = tx.ExecContext(ctx, `delete from totp_backup_codes where user_uuid = $1`, userUUID)
+	if err != nil {
+		return ErrorMsg(errors.Join(err, tx.Rollback()))
+	}
+	_, err = tx.ExecContext(ctx, `delete from user_totp where user_uuid = $1`, userUUID)
+	if err != nil {
+		return ErrorMsg(errors.Join(err, tx.Rollback()))
+	}
+	if err = tx.Commit(); err != nil {
+		return ErrorMsg(err)
+	}
+	return nil
+}
+
+// ReplaceBackupCodes заменяет резервные коды пользователя новыми (хранятся хэши)
+func (r *TOTPRepository) ReplaceBackupCodes(ctx context.Context, userUUID string, codeHashes []string) error {
+	ctx, cancel := context.WithTimeout(ctx, timeOut)
+	defer cancel()
+	tx, err := storage.BeginTx(ctx, r.store)
+	if err != nil {
+		return ErrorMsg(err)
+	}
+	_, err = tx.ExecContext(ctx, `delete from totp_backup_codes where user_uuid = $1`, userUUID)
+	if err != nil {
+		return ErrorMsg(errors.Join(err, tx.Rollback()))
+	}
+	for _, hash := range codeHashes {
+		_, err = tx.ExecContext(ctx, `insert into totp_backup_codes (user_uuid, code_hash) values ($1, $2)`, userUUID, hash)
+		if err != nil {
+			return ErrorMsg(errors.Join(err, tx.Rollback()))
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		return ErrorMsg(err)
+	}
+	return nil
+}
+
+// UseBackupCode погашение резервного кода. Вернёт false, если кода нет или он уже использован
+func (r *TOTPRepository) UseBackupCode(ctx context.Context, userUUID string, codeHash string) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeOut)
+	defer cancel()
+	rows := storage.Query(ctx, r.store).QueryRowContext(ctx, `update totp_backup_codes set used_at = now() where user_uuid = $1 and code_hash = $2 and used_at is null returning id`, userUUID, codeHash)
+	var id int64
+	err := rows.Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, ErrorMsg(err)
+	}
+	return true, nil
+}
@@ -0,0 +1,125 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"golang.org/x/net/context"
+)
+
+type TOTPRepositoryTestSuite struct {
+	suite.Suite
+	DB         *sql.DB
+	mock       sqlmock.Sqlmock
+	repository *TOTPRepository
+}
+
+func (s *TOTPRepositoryTestSuite) SetupTest() {
+	var err error
+	s.DB, s.mock, err = sqlmock.New()
+	require.NoError(s.T(), err)
+	s.mock.ExpectPrepare("select id, user_uuid, secret")
+	s.repository, err = NewTOTPRepository(s.DB)
+	require.NoError(s.T(), err)
+}
+
+func TestTOTPRepositoryTestSuite(t *testing.T) {
+	suite.Run(t, new(TOTPRepositoryTestSuite))
+}
+
+func (s *TOTPRepositoryTestSuite) TestFindOneByUserUUID() {
+	s.mock.ExpectQuery("select").
+		WithArgs("user-uuid").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_uuid", "secret", "enabled", "last_step", "created_at"}).
+			AddRow(1, "user-uuid", "SECRET", true, 100, time.Now()))
+
+	totp, err := s.repository.FindOneByUserUUID(context.Background(), "user-uuid")
+	require.NoError(s.T(), err)
+	require.NotNil(s.T(), totp)
+	require.Equal(s.T(), "SECRET", totp.Secret)
+	require.True(s.T(), totp.Enabled)
+	require.Equal(s.T(), int64(100), totp.LastStep)
+}
+
+func (s *TOTPRepositoryTestSuite) TestFindOneByUserUUID_NotFound() {
+	s.mock.ExpectQuery("select").
+		WithArgs("user-uuid").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_uuid", "secret", "enabled", "last_step", "created_at"}))
+
+	totp, err := s.repository.FindOneByUserUUID(context.Background(), "user-uuid")
+	require.NoError(s.T(), err)
+	require.Nil(s.T(), totp)
+}
+
+func (s *TOTPRepositoryTestSuite) TestSave() {
+	s.mock.ExpectExec("insert into user_totp").
+		WithArgs("user-uuid", "SECRET").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	err := s.repository.Save(context.Background(), "user-uuid", "SECRET")
+	require.NoError(s.T(), err)
+}
+
+func (s *TOTPRepositoryTestSuite) TestEnable_Error() {
+	s.mock.ExpectExec("update user_totp set enabled").
+		WithArgs("user-uuid").
+		WillReturnError(errors.New("update failed"))
+
+	err := s.repository.Enable(context.Background(), "user-uuid")
+	require.Error(s.T(), err)
+}
+
+func (s *TOTPRepositoryTestSuite) TestUseStep() {
+	s.mock.ExpectQuery("update user_totp set last_step").
+		WithArgs(int64(55), "user-uuid").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	used, err := s.repository.UseStep(context.Background(), "user-uuid", 55)
+	require.NoError(s.T(), err)
+	require.True(s.T(), used)
+
+	// код этого шага уже принимался
+	s.mock.ExpectQuery("update user_totp set last_step").
+		WithArgs(int64(55), "user-uuid").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	used, err = s.repository.UseStep(context.Background(), "user-uuid", 55)
+	require.NoError(s.T(), err)
+	require.False(s.T(), used)
+}
+
+func (s *TOTPRepositoryTestSuite) TestDelete() {
+	s.mock.ExpectBegin()
+	s.mock.ExpectExec("delete from totp_backup_codes").WithArgs("user-uuid").WillReturnResult(sqlmock.NewResult(0, 10))
+	s.mock.ExpectExec("delete from user_totp").WithArgs("user-uuid").WillReturnResult(sqlmock.NewResult(0, 1))
+	s.mock.ExpectCommit()
+
+	err := s.repository.Delete(context.Background(), "user-uuid")
+	require.NoError(s.T(), err)
+	require.NoError(s.T(), s.mock.ExpectationsWereMet())
+}
+
+func (s *TOTPRepositoryTestSuite) TestReplaceBackupCodes() {
+	s.mock.ExpectBegin()
+	s.mock.ExpectExec("delete from totp_backup_codes").WithArgs("user-uuid").WillReturnResult(sqlmock.NewResult(0, 0))
+	s.mock.ExpectExec("insert into totp_backup_codes").WithArgs("user-uuid", "hash-1").WillReturnResult(sqlmock.NewResult(1, 1))
+	s.mock.ExpectExec("insert into totp_backup_codes").WithArgs("user-uuid", "hash-2").WillReturnError(errors.New("insert failed"))
+	s.mock.ExpectRollback()
+
+	err := s.repository.ReplaceBackupCodes(context.Background(), "user-uuid", []string{"hash-1", "hash-2"})
+	require.Error(s.T(), err)
+	require.NoError(s.T(), s.mock.ExpectationsWereMet())
+}
+
+func (s *TOTPRepositoryTestSuite) TestUseBackupCode() {
+	s.mock.ExpectQuery("update totp_backup_codes set used_at").
+		WithArgs("user-uuid", "hash").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	used, err := s.repository.UseBackupCode(context.Background(), "user-uuid", "hash")
+	require.NoError(s.T(), err)
+	require.False(s.T(), used)
+}
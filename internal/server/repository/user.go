@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"time"
 
 	"github.com/northmule/gophkeeper/internal/common/models"
@@ -27,7 +28,7 @@ func NewUserRepository(store storage.DBQuery) (*UserRepository, error) {
 		store: store,
 	}
 	var err error
-	instance.sqlFindByLogin, err = store.Prepare(`select id, login, password, created_at, uuid, email, public_key, private_client_key from users where login = $1 limit 1`)
+	instance.sqlFindByLogin, err = store.Prepare(`select id, login, password, created_at, uuid, email, public_key, private_client_key, master_key_salt, master_key_check, vault_legacy from users where login = $1 limit 1`)
 	if err != nil {
 		return nil, ErrorMsg(err)
 	}
@@ -37,7 +38,7 @@ func NewUserRepository(store storage.DBQuery) (*UserRepository, error) {
 		return nil, ErrorMsg(err)
 	}
 
-	instance.sqlFindByUUID, err = store.Prepare(`select id, login, password, created_at, uuid, email, public_key, private_client_key from users where uuid = $1 limit 1`)
+	instance.sqlFindByUUID, err = store.Prepare(`select id, login, password, created_at, uuid, email, public_key, private_client_key, master_key_salt, master_key_check, vault_legacy from users where uuid = $1 limit 1`)
 	if err != nil {
 		return nil, ErrorMsg(err)
 	}
@@ -50,7 +51,7 @@ func (r *UserRepository) FindOneByLogin(ctx context.Context, login string) (*mod
 	user := models.User{}
 	ctx, cancel := context.WithTimeout(ctx, timeOut)
 	defer cancel()
-	rows, err := r.sqlFindByLogin.QueryContext(ctx, login)
+	rows, err := storage.Stmt(ctx, r.sqlFindByLogin).QueryContext(ctx, login)
 	if err != nil {
 		return nil, ErrorMsg(err)
 	}
@@ -60,7 +61,7 @@ func (r *UserRepository) FindOneByLogin(ctx context.Context, login string) (*mod
 	}
 
 	if rows.Next() {
-		err = rows.Scan(&user.ID, &user.Login, &user.Password, &user.CreatedAt, &user.UUID, &user.Email, &user.PublicKey, &user.PrivateClientKey)
+		err = rows.Scan(&user.ID, &user.Login, &user.Password, &user.CreatedAt, &user.UUID, &user.Email, &user.PublicKey, &user.PrivateClientKey, &user.MasterKeySalt, &user.MasterKeyCheck, &user.VaultLegacy)
 		if err != nil {
 			return nil, ErrorMsg(err)
 		}
@@ -74,7 +75,7 @@ func (r *UserRepository) FindOneByUUID(ctx context.Context, uuid string) (*model
 	user := models.User{}
 	ctx, cancel := context.WithTimeout(ctx, timeOut)
 	defer cancel()
-	rows, err := r.sqlFindByUUID.QueryContext(ctx, uuid)
+	rows, err := storage.Stmt(ctx, r.sqlFindByUUID).QueryContext(ctx, uuid)
 	if err != nil {
 		return nil, ErrorMsg(err)
 	}
@@ -84,7 +85,7 @@ func (r *UserRepository) FindOneByUUID(ctx context.Context, uuid string) (*model
 	}
 
 	if rows.Next() {
-		err = rows.Scan(&user.ID, &user.Login, &user.Password, &user.CreatedAt, &user.UUID, &user.Email, &user.PublicKey, &user.PrivateClientKey)
+		err = rows.Scan(&user.ID, &user.Login, &user.Password, &user.CreatedAt, &user.UUID, &user.Email, &user.PublicKey, &user.PrivateClientKey, &user.MasterKeySalt, &user.MasterKeyCheck, &user.VaultLegacy)
 		if err != nil {
 			return nil, ErrorMsg(err)
 		}
@@ -97,7 +98,7 @@ func (r *UserRepository) FindOneByUUID(ctx context.Context, uuid string) (*model
 func (r *UserRepository) CreateNewUser(ctx context.Context, user models.User) (int64, error) {
 	ctx, cancel := context.WithTimeout(ctx, timeOut)
 	defer cancel()
-	rows := r.sqlCreateUser.QueryRowContext(ctx, user.Login, user.Password, user.UUID, user.Email)
+	rows := storage.Stmt(ctx, r.sqlCreateUser).QueryRowContext(ctx, user.Login, user.Password, user.UUID, user.Email)
 	err := rows.Err()
 	if err != nil {
 		return 0, ErrorMsg(err)
@@ -111,31 +112,65 @@ func (r *UserRepository) CreateNewUser(ctx context.Context, user models.User) (i
 	return id, nil
 }
 
-// TxCreateNewUser создание пользователя в транзакции
-func (r *UserRepository) TxCreateNewUser(ctx context.Context, tx storage.TxDBQuery, user models.User) (int64, error) {
+// SetPrivateClientKey вставка значения публичного ключа
+func (r *UserRepository) SetPrivateClientKey(ctx context.Context, data string, userUUID string) error {
 	ctx, cancel := context.WithTimeout(ctx, timeOut)
 	defer cancel()
-	rows := tx.Tx().QueryRowContext(ctx, `insert into users (login, password, uuid, email) values ($1, $2, $3, $4) returning id`, user.Login, user.Password, user.UUID, user.Email)
+	rows := storage.Query(ctx, r.store).QueryRowContext(ctx, `update users set private_client_key = $1 where uuid = $2`, data, userUUID)
 	err := rows.Err()
 	if err != nil {
-		tx.AddError(ErrorMsg(err))
-		return 0, ErrorMsg(err)
+		return ErrorMsg(err)
+	}
+
+	return nil
+}
+
+// FindAllPrivateClientKeys пользователи с сохранённым ключом клиента (заполнены только UUID и PrivateClientKey)
+func (r *UserRepository) FindAllPrivateClientKeys(ctx context.Context) ([]models.User, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeOut)
+	defer cancel()
+	rows, err := storage.Query(ctx, r.store).QueryContext(ctx, `select uuid, private_client_key from users where private_client_key is not null and private_client_key not in ('', 'n') order by id`)
+	if err != nil {
+		return nil, ErrorMsg(err)
+	}
+	defer rows.Close()
+	err = rows.Err()
+	if err != nil {
+		return nil, ErrorMsg(err)
+	}
+	users := make([]models.User, 0)
+	for rows.Next() {
+		user := models.User{}
+		err = rows.Scan(&user.UUID, &user.PrivateClientKey)
+		if err != nil {
+			return nil, ErrorMsg(err)
+		}
+		users = append(users, user)
 	}
+	return users, nil
+}
 
+// ReplacePrivateClientKey замена ключа клиента, если он не изменился с момента чтения
+func (r *UserRepository) ReplacePrivateClientKey(ctx context.Context, userUUID string, oldValue string, newValue string) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeOut)
+	defer cancel()
+	rows := storage.Query(ctx, r.store).QueryRowContext(ctx, `update users set private_client_key = $1 where uuid = $2 and private_client_key = $3 returning id`, newValue, userUUID, oldValue)
 	var id int64
-	err = rows.Scan(&id)
+	err := rows.Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
 	if err != nil {
-		tx.AddError(err)
-		return 0, ErrorMsg(err)
+		return false, ErrorMsg(err)
 	}
-	return id, nil
+	return true, nil
 }
 
-// SetPublicKey вставка значения публичного ключа
-func (r *UserRepository) SetPublicKey(ctx context.Context, data string, userUUID string) error {
+// SetPassword замена хэша пароля
+func (r *UserRepository) SetPassword(ctx context.Context, hash string, userUUID string) error {
 	ctx, cancel := context.WithTimeout(ctx, timeOut)
 	defer cancel()
-	rows := r.store.QueryRowContext(ctx, `update users set public_key = $1 where uuid = $2`, data, userUUID)
+	rows := storage.Query(ctx, r.store).QueryRowContext(ctx, `update users set password = $1 where uuid = $2`, hash, userUUID)
 	err := rows.Err()
 	if err != nil {
 		return ErrorMsg(err)
@@ -144,15 +179,29 @@ func (r *UserRepository) SetPublicKey(ctx context.Context, data string, userUUID
 	return nil
 }
 
-// SetPrivateClientKey вставка значения публичного ключа
-func (r *UserRepository) SetPrivateClientKey(ctx context.Context, data string, userUUID string) error {
+// SetMasterKey сохранение параметров мастер-ключа клиента (соль и контрольное значение).
+// Параметры задаются один раз, вернёт false, если они уже сохранены
+func (r *UserRepository) SetMasterKey(ctx context.Context, salt string, check string, userUUID string) (bool, error) {
 	ctx, cancel := context.WithTimeout(ctx, timeOut)
 	defer cancel()
-	rows := r.store.QueryRowContext(ctx, `update users set private_client_key = $1 where uuid = $2`, data, userUUID)
-	err := rows.Err()
+	result, err := storage.Query(ctx, r.store).ExecContext(ctx, `update users set master_key_salt = $1, master_key_check = $2 where uuid = $3 and master_key_salt = ''`, salt, check, userUUID)
 	if err != nil {
-		return ErrorMsg(err)
+		return false, ErrorMsg(err)
 	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, ErrorMsg(err)
+	}
+	return affected > 0, nil
+}
 
+// ClearVaultLegacy снятие признака данных без шифрования мастер-ключом (после перешифрования на клиенте)
+func (r *UserRepository) ClearVaultLegacy(ctx context.Context, userUUID string) error {
+	ctx, cancel := context.WithTimeout(ctx, timeOut)
+	defer cancel()
+	_, err := storage.Query(ctx, r.store).ExecContext(ctx, `update users set vault_legacy = false where uuid = $1`, userUUID)
+	if err != nil {
+		return ErrorMsg(err)
+	}
 	return nil
 }
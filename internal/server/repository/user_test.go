@@ -57,8 +57,8 @@ func (s *UserRepositoryTestSuite) TestFindOneByLogin_ValidData() {
 
 	s.mock.ExpectQuery("select id, login, password").
 		WithArgs(login).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "login", "password", "created_at", "uuid", "email", "public_key", "private_client_key"}).
-			AddRow(expectedUser.ID, expectedUser.Login, expectedUser.Password, expectedUser.CreatedAt, expectedUser.UUID, expectedUser.Email, expectedUser.PublicKey, expectedUser.PrivateClientKey))
+		WillReturnRows(sqlmock.NewRows([]string{"id", "login", "password", "created_at", "uuid", "email", "public_key", "private_client_key", "master_key_salt", "master_key_check", "vault_legacy"}).
+			AddRow(expectedUser.ID, expectedUser.Login, expectedUser.Password, expectedUser.CreatedAt, expectedUser.UUID, expectedUser.Email, expectedUser.PublicKey, expectedUser.PrivateClientKey, expectedUser.MasterKeySalt, expectedUser.MasterKeyCheck, expectedUser.VaultLegacy))
 
 	user, err := s.repository.FindOneByLogin(context.Background(), login)
 	require.NoError(s.T(), err)
@@ -70,7 +70,7 @@ func (s *UserRepositoryTestSuite) TestFindOneByLogin_NoData() {
 
 	s.mock.ExpectQuery("select id, login").
 		WithArgs(login).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "login", "password", "created_at", "uuid", "email", "public_key", "private_client_key"}))
+		WillReturnRows(sqlmock.NewRows([]string{"id", "login", "password", "created_at", "uuid", "email", "public_key", "private_client_key", "master_key_salt", "master_key_check", "vault_legacy"}))
 
 	user, err := s.repository.FindOneByLogin(context.Background(), login)
 	require.NoError(s.T(), err)
@@ -102,8 +102,8 @@ func (s *UserRepositoryTestSuite) TestFindOneByUUID_ValidData() {
 	expectedUser.UUID = uuid
 	s.mock.ExpectQuery("select id, login, password").
 		WithArgs(uuid).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "login", "password", "created_at", "uuid", "email", "public_key", "private_client_key"}).
-			AddRow(expectedUser.ID, expectedUser.Login, expectedUser.Password, expectedUser.CreatedAt, expectedUser.UUID, expectedUser.Email, expectedUser.PublicKey, expectedUser.PrivateClientKey))
+		WillReturnRows(sqlmock.NewRows([]string{"id", "login", "password", "created_at", "uuid", "email", "public_key", "private_client_key", "master_key_salt", "master_key_check", "vault_legacy"}).
+			AddRow(expectedUser.ID, expectedUser.Login, expectedUser.Password, expectedUser.CreatedAt, expectedUser.UUID, expectedUser.Email, expectedUser.PublicKey, expectedUser.PrivateClientKey, expectedUser.MasterKeySalt, expectedUser.MasterKeyCheck, expectedUser.VaultLegacy))
 
 	user, err := s.repository.FindOneByUUID(context.Background(), uuid)
 	require.NoError(s.T(), err)
@@ -115,7 +115,7 @@ func (s *UserRepositoryTestSuite) TestFindOneByUUID_NoData() {
 
 	s.mock.ExpectQuery("select id, login, password").
 		WithArgs(uuid).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "login", "password", "created_at", "uuid", "email", "public_key", "private_client_key"}))
+		WillReturnRows(sqlmock.NewRows([]string{"id", "login", "password", "created_at", "uuid", "email", "public_key", "private_client_key", "master_key_salt", "master_key_check", "vault_legacy"}))
 
 	user, err := s.repository.FindOneByUUID(context.Background(), uuid)
 	require.NoError(s.T(), err)
@@ -177,67 +177,88 @@ func (s *UserRepositoryTestSuite) TestCreateNewUser_DuplicateUUID() {
 	assert.Equal(s.T(), int64(0), id)
 }
 
-func (s *UserRepositoryTestSuite) TestSetPublicKey_ValidData() {
-	data := "new-public-key"
+func (s *UserRepositoryTestSuite) TestSetPrivateClientKey_ValidData() {
+	data := "new-private-client-key"
 	userUUID := "test-uuid"
 
-	s.mock.ExpectQuery("update users").
-		WithArgs(data, userUUID).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	s.mock.ExpectQuery("update users set").
+		WithArgs(data, userUUID).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
 
-	err := s.repository.SetPublicKey(context.Background(), data, userUUID)
+	err := s.repository.SetPrivateClientKey(context.Background(), data, userUUID)
 	require.NoError(s.T(), err)
 }
 
-func (s *UserRepositoryTestSuite) TestSetPublicKey_InvalidData() {
+func (s *UserRepositoryTestSuite) TestSetPrivateClientKey_InvalidData() {
 	data := ""
 	userUUID := "test-uuid"
 
-	err := s.repository.SetPublicKey(context.Background(), data, userUUID)
+	err := s.repository.SetPrivateClientKey(context.Background(), data, userUUID)
 	require.Error(s.T(), err)
 }
 
-func (s *UserRepositoryTestSuite) TestSetPublicKey_Error() {
-	data := "new-public-key"
+func (s *UserRepositoryTestSuite) TestSetPrivateClientKey_Error() {
+	data := "new-private-client-key"
 	userUUID := "test-uuid"
 
 	s.mock.ExpectExec("update users set").
 		WithArgs(data, userUUID).
 		WillReturnError(errors.New("update failed"))
 
-	err := s.repository.SetPublicKey(context.Background(), data, userUUID)
+	err := s.repository.SetPrivateClientKey(context.Background(), data, userUUID)
 	require.Error(s.T(), err)
 }
 
-func (s *UserRepositoryTestSuite) TestSetPrivateClientKey_ValidData() {
-	data := "new-private-client-key"
-	userUUID := "test-uuid"
-
-	s.mock.ExpectQuery("update users set").
-		WithArgs(data, userUUID).
-		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+func (s *UserRepositoryTestSuite) TestFindAllPrivateClientKeys_ValidData() {
+	s.mock.ExpectQuery("select uuid, private_client_key from users").
+		WillReturnRows(sqlmock.NewRows([]string{"uuid", "private_client_key"}).
+			AddRow("uuid-1", "key-1").
+			AddRow("uuid-2", "kek1:local:key-2"))
 
-	err := s.repository.SetPrivateClientKey(context.Background(), data, userUUID)
+	users, err := s.repository.FindAllPrivateClientKeys(context.Background())
 	require.NoError(s.T(), err)
+	require.Len(s.T(), users, 2)
+	assert.Equal(s.T(), "uuid-1", users[0].UUID)
+	assert.Equal(s.T(), "kek1:local:key-2", users[1].PrivateClientKey)
 }
 
-func (s *UserRepositoryTestSuite) TestSetPrivateClientKey_InvalidData() {
-	data := ""
-	userUUID := "test-uuid"
+func (s *UserRepositoryTestSuite) TestFindAllPrivateClientKeys_Error() {
+	s.mock.ExpectQuery("select uuid, private_client_key from users").
+		WillReturnError(errors.New("select failed"))
 
-	err := s.repository.SetPrivateClientKey(context.Background(), data, userUUID)
+	users, err := s.repository.FindAllPrivateClientKeys(context.Background())
 	require.Error(s.T(), err)
+	assert.Nil(s.T(), users)
 }
 
-func (s *UserRepositoryTestSuite) TestSetPrivateClientKey_Error() {
-	data := "new-private-client-key"
-	userUUID := "test-uuid"
+func (s *UserRepositoryTestSuite) TestReplacePrivateClientKey_Replaced() {
+	s.mock.ExpectQuery("update users set private_client_key").
+		WithArgs("new", "test-uuid", "old").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
 
-	s.mock.ExpectExec("update users set").
-		WithArgs(data, userUUID).
+	ok, err := s.repository.ReplacePrivateClientKey(context.Background(), "test-uuid", "old", "new")
+	require.NoError(s.T(), err)
+	assert.True(s.T(), ok)
+}
+
+func (s *UserRepositoryTestSuite) TestReplacePrivateClientKey_Changed() {
+	s.mock.ExpectQuery("update users set private_client_key").
+		WithArgs("new", "test-uuid", "old").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	ok, err := s.repository.ReplacePrivateClientKey(context.Background(), "test-uuid", "old", "new")
+	require.NoError(s.T(), err)
+	assert.False(s.T(), ok)
+}
+
+func (s *UserRepositoryTestSuite) TestReplacePrivateClientKey_Error() {
+	s.mock.ExpectQuery("update users set private_client_key").
+		WithArgs("new", "test-uuid", "old").
 		WillReturnError(errors.New("update failed"))
 
-	err := s.repository.SetPrivateClientKey(context.Background(), data, userUUID)
+	ok, err := s.repository.ReplacePrivateClientKey(context.Background(), "test-uuid", "old", "new")
 	require.Error(s.T(), err)
+	assert.False(s.T(), ok)
 }
 
 func (s *UserRepositoryTestSuite) TestCreateNewUser_InvalidUUID() {
@@ -276,3 +297,90 @@ func (s *UserRepositoryTestSuite) TestFindOneByUUID_InvalidUUID() {
 	_, err := s.repository.FindOneByUUID(context.Background(), uuid)
 	require.Error(s.T(), err)
 }
+
+func (s *UserRepositoryTestSuite) TestSetMasterKey_ValidData() {
+	salt := "test-salt"
+	check := "test-check"
+	userUUID := "test-uuid"
+
+	s.mock.ExpectExec("update users set master_key_salt (.+) and master_key_salt = ''").
+		WithArgs(salt, check, userUUID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	saved, err := s.repository.SetMasterKey(context.Background(), salt, check, userUUID)
+	require.NoError(s.T(), err)
+	require.True(s.T(), saved)
+}
+
+func (s *UserRepositoryTestSuite) TestSetMasterKey_AlreadySet() {
+	salt := "test-salt"
+	check := "test-check"
+	userUUID := "test-uuid"
+
+	s.mock.ExpectExec("update users set master_key_salt").
+		WithArgs(salt, check, userUUID).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	saved, err := s.repository.SetMasterKey(context.Background(), salt, check, userUUID)
+	require.NoError(s.T(), err)
+	require.False(s.T(), saved)
+}
+
+func (s *UserRepositoryTestSuite) TestSetMasterKey_Error() {
+	salt := "test-salt"
+	check := "test-check"
+	userUUID := "test-uuid"
+
+	s.mock.ExpectExec("update users set master_key_salt").
+		WithArgs(salt, check, userUUID).
+		WillReturnError(errors.New("update failed"))
+
+	_, err := s.repository.SetMasterKey(context.Background(), salt, check, userUUID)
+	require.Error(s.T(), err)
+}
+
+func (s *UserRepositoryTestSuite) TestClearVaultLegacy_ValidData() {
+	userUUID := "test-uuid"
+
+	s.mock.ExpectExec("update users set vault_legacy = false").
+		WithArgs(userUUID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err := s.repository.ClearVaultLegacy(context.Background(), userUUID)
+	require.NoError(s.T(), err)
+}
+
+func (s *UserRepositoryTestSuite) TestClearVaultLegacy_Error() {
+	userUUID := "test-uuid"
+
+	s.mock.ExpectExec("update users set vault_legacy = false").
+		WithArgs(userUUID).
+		WillReturnError(errors.New("update failed"))
+
+	err := s.repository.ClearVaultLegacy(context.Background(), userUUID)
+	require.Error(s.T(), err)
+}
+
+func (s *UserRepositoryTestSuite) TestSetPassword_ValidData() {
+	hash := "$argon2id$v=19$m=65536,t=3,p=2$c2FsdA$aGFzaA"
+	userUUID := "test-uuid"
+
+	s.mock.ExpectQuery("update users set password").
+		WithArgs(hash, userUUID).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	err := s.repository.SetPassword(context.Background(), hash, userUUID)
+	require.NoError(s.T(), err)
+}
+
+func (s *UserRepositoryTestSuite) TestSetPassword_Error() {
+	hash := "hash"
+	userUUID := "test-uuid"
+
+	s.mock.ExpectQuery("update users set password").
+		WithArgs(hash, userUUID).
+		WillReturnError(errors.New("update failed"))
+
+	err := s.repository.SetPassword(context.Background(), hash, userUUID)
+	require.Error(s.T(), err)
+}
@@ -5,8 +5,12 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/go-chi/jwtauth/v5"
+	"github.com/golang-jwt/jwt/v4"
+	jwtx "github.com/lestrrat-go/jwx/v2/jwt"
 	"github.com/northmule/gophkeeper/internal/common/util"
 	"github.com/northmule/gophkeeper/internal/server/api/rctx"
 	"github.com/northmule/gophkeeper/internal/server/config"
@@ -14,28 +18,130 @@ import (
 
 // Access сервис проверки доступа
 type Access struct {
-	cfg *config.Config
+	cfg     *config.Config
+	keyRing *JWTKeyRing
+	// хэш-заглушка для проверки пароля несуществующего пользователя
+	dummyOnce sync.Once
+	dummyHash string
 }
 
 // NewAccess конструктор
-func NewAccess(cfg *config.Config) *Access {
-	return &Access{cfg: cfg}
+func NewAccess(cfg *config.Config) (*Access, error) {
+	keyRing, err := NewJWTKeyRing(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &Access{cfg: cfg, keyRing: keyRing}, nil
 }
 
 // PasswordHash хэшер пароля
 func (a *Access) PasswordHash(password string) (string, error) {
 	switch a.cfg.Value().PasswordAlgoHashing {
-	case "sha256":
+	case util.PasswordAlgoArgon2id:
+		return util.PasswordHashArgon2id(password)
+	case util.PasswordAlgoBcrypt:
+		return util.PasswordHashBcrypt(password)
+	case util.PasswordAlgoSha256:
 		return util.PasswordHashSha256(password), nil
-	case "sha512":
+	case util.PasswordAlgoSha512:
 		return util.PasswordHashSha512(password), nil
 	}
 	return "", fmt.Errorf("unknown hashing algorithm")
 }
 
-// FillJWTToken начальное значение токена
+// PasswordVerify проверка пароля по алгоритму сохранённого хэша
+func (a *Access) PasswordVerify(password string, hash string) (bool, error) {
+	return util.PasswordVerify(password, hash)
+}
+
+// PasswordVerifyDummy проверка пароля по хэшу-заглушке алгоритма из конфигурации, когда пользователь не найден.
+// Время ответа на вход не выдаёт наличие логина
+func (a *Access) PasswordVerifyDummy(password string) {
+	a.dummyOnce.Do(func() {
+		a.dummyHash, _ = a.PasswordHash("gophkeeper dummy password")
+	})
+	_, _ = util.PasswordVerify(password, a.dummyHash)
+}
+
+// PasswordNeedsRehash хэш нужно пересчитать алгоритмом из конфигурации
+func (a *Access) PasswordNeedsRehash(hash string) bool {
+	return util.PasswordNeedsRehash(hash, a.cfg.Value().PasswordAlgoHashing)
+}
+
+// FillJWTToken начальное значение токена (активный ключ подписи)
 func (a *Access) FillJWTToken() *jwtauth.JWTAuth {
-	return jwtauth.New("HS512", []byte("_secret_"), nil) // todo в конфиг
+	return a.keyRing.Active()
+}
+
+// IssueToken выпуск токена доступа в рамках сессии, подписанного активным ключом
+func (a *Access) IssueToken(userUUID string, sessionUUID string, deviceUUID string) (string, error) {
+	now := time.Now()
+	return a.keyRing.Sign(jwt.MapClaims{
+		"iat":                  now.Unix(),
+		"exp":                  now.Add(a.cfg.Value().JWTTTL).Unix(),
+		rctx.MapKeyUserUUID:    userUUID,
+		rctx.MapKeySessionUUID: sessionUUID,
+		rctx.MapKeyDeviceUUID:  deviceUUID,
+	})
+}
+
+// GetDeviceUUIDByJWTToken UUID устройства из токена
+func (a *Access) GetDeviceUUIDByJWTToken(ctx context.Context) (string, error) {
+	_, claims, err := jwtauth.FromContext(ctx)
+	if err != nil {
+		return "", err
+	}
+	value, ok := claims[rctx.MapKeyDeviceUUID].(string)
+	if !ok || value == "" {
+		return "", fmt.Errorf("no device found in claims")
+	}
+	return value, nil
+}
+
+// GetSessionUUIDByJWTToken UUID сессии из токена
+func (a *Access) GetSessionUUIDByJWTToken(ctx context.Context) (string, error) {
+	_, claims, err := jwtauth.FromContext(ctx)
+	if err != nil {
+		return "", err
+	}
+	value, ok := claims[rctx.MapKeySessionUUID].(string)
+	if !ok || value == "" {
+		return "", fmt.Errorf("no session found in claims")
+	}
+	return value, nil
+}
+
+// GetTokenExpirationByJWTToken время истечения токена
+func (a *Access) GetTokenExpirationByJWTToken(ctx context.Context) (time.Time, error) {
+	token, _, err := jwtauth.FromContext(ctx)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if token == nil || token.Expiration().IsZero() {
+		return time.Time{}, fmt.Errorf("no expiration found in token")
+	}
+	return token.Expiration(), nil
+}
+
+// JWTVerifier мидлвара проверки токена ключом, указанным в заголовке kid
+func (a *Access) JWTVerifier(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		token, err := a.verifyRequest(req)
+		ctx := jwtauth.NewContext(req.Context(), token, err)
+		next.ServeHTTP(res, req.WithContext(ctx))
+	})
+}
+
+func (a *Access) verifyRequest(req *http.Request) (jwtx.Token, error) {
+	tokenString := a.FindTokenByRequest(req)
+	if tokenString == "" {
+		return nil, jwtauth.ErrNoTokenFound
+	}
+	auth, err := a.keyRing.ByToken(tokenString)
+	if err != nil {
+		return nil, jwtauth.ErrUnauthorized
+	}
+	return jwtauth.VerifyToken(auth, tokenString)
 }
 
 // GetUserUUIDByJWTToken UUID пользвоателя из токена
@@ -2,6 +2,7 @@ package access
 
 import (
 	"net/http"
+	"strings"
 	"testing"
 	"time"
 
@@ -15,12 +16,21 @@ import (
 	"golang.org/x/net/context"
 )
 
-func TestAccess_PasswordHash(t *testing.T) {
+func newTestConfig() *config.Config {
 	cfg := config.NewConfig()
 	_ = cfg.Init()
+	cfg.Value().JWTAlg = JWTAlgHS512
+	cfg.Value().JWTKeys = []string{"k1:" + strings.Repeat("s", minJWTSecretLen)}
+	cfg.Value().JWTTTL = time.Hour
+	return cfg
+}
+
+func TestAccess_PasswordHash(t *testing.T) {
+	cfg := newTestConfig()
 	cfg.Value().PasswordAlgoHashing = "sha256"
 
-	access := NewAccess(cfg)
+	access, err := NewAccess(cfg)
+	require.NoError(t, err)
 	hash, err := access.PasswordHash("password")
 	assert.NoError(t, err)
 	assert.Equal(t, util.PasswordHashSha256("password"), hash)
@@ -31,6 +41,18 @@ func TestAccess_PasswordHash(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, util.PasswordHashSha512("password"), hash)
 
+	cfg.Value().PasswordAlgoHashing = "argon2id"
+
+	hash, err = access.PasswordHash("password")
+	assert.NoError(t, err)
+	assert.Equal(t, util.PasswordAlgoArgon2id, util.PasswordHashAlgo(hash))
+
+	cfg.Value().PasswordAlgoHashing = "bcrypt"
+
+	hash, err = access.PasswordHash("password")
+	assert.NoError(t, err)
+	assert.Equal(t, util.PasswordAlgoBcrypt, util.PasswordHashAlgo(hash))
+
 	cfg.Value().PasswordAlgoHashing = "unknown"
 
 	hash, err = access.PasswordHash("password")
@@ -39,20 +61,55 @@ func TestAccess_PasswordHash(t *testing.T) {
 
 }
 
+func TestAccess_PasswordVerify(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.Value().PasswordAlgoHashing = "argon2id"
+
+	access, err := NewAccess(cfg)
+	require.NoError(t, err)
+	legacyHash := util.PasswordHashSha512("password")
+
+	ok, err := access.PasswordVerify("password", legacyHash)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.True(t, access.PasswordNeedsRehash(legacyHash))
+
+	hash, err := access.PasswordHash("password")
+	require.NoError(t, err)
+	ok, err = access.PasswordVerify("password", hash)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.False(t, access.PasswordNeedsRehash(hash))
+
+	ok, err = access.PasswordVerify("wrong", hash)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestAccess_PasswordVerifyDummy(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.Value().PasswordAlgoHashing = "argon2id"
+
+	access, err := NewAccess(cfg)
+	require.NoError(t, err)
+	access.PasswordVerifyDummy("password")
+	assert.Equal(t, util.PasswordAlgoArgon2id, util.PasswordHashAlgo(access.dummyHash))
+}
+
 func TestAccess_FillJWTToken(t *testing.T) {
-	cfg := config.NewConfig()
-	_ = cfg.Init()
+	cfg := newTestConfig()
 
-	access := NewAccess(cfg)
+	access, err := NewAccess(cfg)
+	require.NoError(t, err)
 	token := access.FillJWTToken()
 	assert.NotNil(t, token)
 }
 
 func TestAccess_FindTokenByRequest(t *testing.T) {
-	cfg := config.NewConfig()
-	_ = cfg.Init()
+	cfg := newTestConfig()
 
-	access := NewAccess(cfg)
+	access, err := NewAccess(cfg)
+	require.NoError(t, err)
 	req := &http.Request{
 		Header: http.Header{"Authorization": {"Bearer test-token"}},
 	}
@@ -94,8 +151,7 @@ type jWTClaimsBad struct {
 }
 
 func TestGetUserUUIDByJWTToken(t *testing.T) {
-	cfg := config.NewConfig()
-	_ = cfg.Init()
+	cfg := newTestConfig()
 
 	t.Run("Valid JWT Token with UUID", func(t *testing.T) {
 		ctx := context.Background()
@@ -110,7 +166,8 @@ func TestGetUserUUIDByJWTToken(t *testing.T) {
 		jwtV, _ := jj.Decode(tokenValue)
 		ctx = jwtauth.NewContext(ctx, jwtV, nil)
 
-		a := NewAccess(cfg)
+		a, err := NewAccess(cfg)
+		require.NoError(t, err)
 		uuid, err := a.GetUserUUIDByJWTToken(ctx)
 		require.NoError(t, err)
 		assert.Equal(t, "123e4567-e89b-12d3-a456-426614174000", uuid)
@@ -127,10 +184,43 @@ func TestGetUserUUIDByJWTToken(t *testing.T) {
 		jwtV, _ := jj.Decode(tokenValue)
 		ctx = jwtauth.NewContext(ctx, jwtV, nil)
 
-		a := NewAccess(cfg)
+		a, err := NewAccess(cfg)
+		require.NoError(t, err)
 		uuid, err := a.GetUserUUIDByJWTToken(ctx)
 		require.Error(t, err)
 		assert.Empty(t, uuid)
 	})
 
 }
+
+func TestGetTokenExpirationByJWTToken(t *testing.T) {
+	cfg := newTestConfig()
+	a, err := NewAccess(cfg)
+	require.NoError(t, err)
+
+	t.Run("with exp", func(t *testing.T) {
+		expiresAt := time.Now().Add(time.Hour).Truncate(time.Second)
+		token := jwt.NewWithClaims(jwt.SigningMethodHS512, jWTClaims{
+			RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(expiresAt)},
+			UserUUID:         "123e4567-e89b-12d3-a456-426614174000",
+		})
+		tokenValue, err := token.SignedString([]byte("_secret_"))
+		require.NoError(t, err)
+		jwtV, err := jwtauth.New("HS512", []byte("_secret_"), nil).Decode(tokenValue)
+		require.NoError(t, err)
+
+		value, err := a.GetTokenExpirationByJWTToken(jwtauth.NewContext(context.Background(), jwtV, nil))
+		require.NoError(t, err)
+		assert.True(t, expiresAt.Equal(value))
+	})
+	t.Run("without exp", func(t *testing.T) {
+		token := jwt.NewWithClaims(jwt.SigningMethodHS512, jWTClaims{UserUUID: "123e4567-e89b-12d3-a456-426614174000"})
+		tokenValue, err := token.SignedString([]byte("_secret_"))
+		require.NoError(t, err)
+		jwtV, err := jwtauth.New("HS512", []byte("_secret_"), nil).Decode(tokenValue)
+		require.NoError(t, err)
+
+		_, err = a.GetTokenExpirationByJWTToken(jwtauth.NewContext(context.Background(), jwtV, nil))
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,191 @@
+package access
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/go-chi/jwtauth/v5"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/northmule/gophkeeper/internal/common/keys"
+	"github.com/northmule/gophkeeper/internal/common/util"
+	"github.com/northmule/gophkeeper/internal/server/config"
+)
+
+// Поддерживаемые алгоритмы подписи токенов
+const (
+	JWTAlgHS512 = "HS512"
+	JWTAlgRS256 = "RS256"
+	JWTAlgEdDSA = "EdDSA"
+)
+
+// defaultJWTKeyID kid ключа сервера, если JWT_KEYS не задан (RS256)
+const defaultJWTKeyID = "server"
+
+// minJWTSecretLen минимальная длина секрета HS512
+const minJWTSecretLen = 32
+
+// jwtKey ключ подписи токенов
+type jwtKey struct {
+	id string
+	// signKey nil, если ключ оставлен только для проверки выпущенных токенов
+	signKey any
+	auth    *jwtauth.JWTAuth
+}
+
+// JWTKeyRing набор ключей подписи токенов с идентификаторами kid
+type JWTKeyRing struct {
+	method jwt.SigningMethod
+	active *jwtKey
+	keys   map[string]*jwtKey
+}
+
+// NewJWTKeyRing конструктор
+func NewJWTKeyRing(cfg *config.Config) (*JWTKeyRing, error) {
+	instance := &JWTKeyRing{
+		keys: make(map[string]*jwtKey),
+	}
+	alg := cfg.Value().JWTAlg
+	switch alg {
+	case JWTAlgHS512:
+		instance.method = jwt.SigningMethodHS512
+	case JWTAlgRS256:
+		instance.method = jwt.SigningMethodRS256
+	case JWTAlgEdDSA:
+		instance.method = jwt.SigningMethodEdDSA
+	default:
+		return nil, fmt.Errorf("unknown jwt signing algorithm %q", alg)
+	}
+
+	entries := cfg.Value().JWTKeys
+	activeID := cfg.Value().JWTActiveKeyID
+	if len(entries) == 0 && alg == JWTAlgRS256 {
+		// ключ сервера, созданный keys.Keys
+		entries = []string{defaultJWTKeyID + ":" + path.Join(cfg.Value().PathKeys, keys.PrivateKeyFileName)}
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("jwt keys are not configured")
+	}
+
+	for _, entry := range entries {
+		id, value, found := strings.Cut(strings.TrimSpace(entry), ":")
+		if !found || id == "" || value == "" {
+			return nil, fmt.Errorf("invalid jwt key entry, expected kid:value")
+		}
+		if _, exists := instance.keys[id]; exists {
+			return nil, fmt.Errorf("duplicate jwt key id %q", id)
+		}
+		key, err := newJWTKey(alg, id, value)
+		if err != nil {
+			return nil, err
+		}
+		instance.keys[id] = key
+		if activeID == "" {
+			activeID = id
+		}
+	}
+
+	active, ok := instance.keys[activeID]
+	if !ok {
+		return nil, fmt.Errorf("active jwt key %q not found", activeID)
+	}
+	if active.signKey == nil {
+		return nil, fmt.Errorf("active jwt key %q has no private part", activeID)
+	}
+	instance.active = active
+
+	return instance, nil
+}
+
+// Sign подпись утверждений активным ключом, kid записывается в заголовок токена
+func (k *JWTKeyRing) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(k.method, claims)
+	token.Header["kid"] = k.active.id
+	return token.SignedString(k.active.signKey)
+}
+
+// Active проверяющий объект активного ключа
+func (k *JWTKeyRing) Active() *jwtauth.JWTAuth {
+	return k.active.auth
+}
+
+// ByToken проверяющий объект ключа, указанного в заголовке токена.
+// Токены без kid проверяются активным ключом
+func (k *JWTKeyRing) ByToken(tokenString string) (*jwtauth.JWTAuth, error) {
+	token, _, err := jwt.NewParser().ParseUnverified(tokenString, jwt.MapClaims{})
+	if err != nil {
+		return nil, err
+	}
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		return k.active.auth, nil
+	}
+	key, ok := k.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown jwt key id %q", kid)
+	}
+	return key.auth, nil
+}
+
+func newJWTKey(alg string, id string, value string) (*jwtKey, error) {
+	key := &jwtKey{id: id}
+	if alg == JWTAlgHS512 {
+		if len(value) < minJWTSecretLen {
+			return nil, fmt.Errorf("jwt secret %q is shorter than %d characters", id, minJWTSecretLen)
+		}
+		key.signKey = []byte(value)
+		key.auth = jwtauth.New(alg, []byte(value), nil)
+		return key, nil
+	}
+
+	private, public, err := loadJWTKeyFile(value)
+	if err != nil {
+		return nil, fmt.Errorf("jwt key %q: %w", id, err)
+	}
+	switch public.(type) {
+	case *rsa.PublicKey:
+		if alg != JWTAlgRS256 {
+			return nil, fmt.Errorf("jwt key %q is RSA, expected %s", id, alg)
+		}
+	case ed25519.PublicKey:
+		if alg != JWTAlgEdDSA {
+			return nil, fmt.Errorf("jwt key %q is Ed25519, expected %s", id, alg)
+		}
+	default:
+		return nil, fmt.Errorf("jwt key %q has unsupported type", id)
+	}
+	if private != nil {
+		key.signKey = private
+	}
+	key.auth = jwtauth.New(alg, private, public)
+	return key, nil
+}
+
+// loadJWTKeyFile читает PEM файл с приватным или публичным (PKIX) ключом
+func loadJWTKeyFile(path string) (crypto.Signer, crypto.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, nil, fmt.Errorf("no PEM data found in file")
+	}
+	if block.Type == "PUBLIC KEY" {
+		key, err := util.ParsePublicKey(data)
+		if err != nil {
+			return nil, nil, err
+		}
+		return nil, key, nil
+	}
+	signer, err := util.ParsePrivateKey(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	return signer, signer.Public(), nil
+}
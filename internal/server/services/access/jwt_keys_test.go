@@ -0,0 +1,188 @@
+package access
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/jwtauth/v5"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/northmule/gophkeeper/internal/common/keys"
+	"github.com/northmule/gophkeeper/internal/common/keys/signers"
+	"github.com/northmule/gophkeeper/internal/server/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type ed25519Generator struct{}
+
+func (g ed25519Generator) GenerateKey() (crypto.Signer, error) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	return key, err
+}
+
+func createTestKeys(t *testing.T, generator keys.KeyGenerator) *keys.Keys {
+	k := keys.NewKeys(keys.Options{
+		Generator:    generator,
+		SavePath:     t.TempDir(),
+		Organization: "test",
+		Country:      "RU",
+		SerialNumber: big.NewInt(1),
+	})
+	require.NoError(t, k.InitSelfSigned())
+	return k
+}
+
+func newKeyRingConfig(alg string, activeID string, entries ...string) *config.Config {
+	cfg := config.NewConfig()
+	cfg.Value().JWTAlg = alg
+	cfg.Value().JWTKeys = entries
+	cfg.Value().JWTActiveKeyID = activeID
+	cfg.Value().JWTTTL = time.Hour
+	return cfg
+}
+
+func testClaims() jwt.MapClaims {
+	return jwt.MapClaims{"exp": time.Now().Add(time.Hour).Unix(), "user_uuid": "uuid"}
+}
+
+func TestJWTKeyRing_HS512Rotation(t *testing.T) {
+	oldSecret := strings.Repeat("o", minJWTSecretLen)
+	newSecret := strings.Repeat("n", minJWTSecretLen)
+
+	oldRing, err := NewJWTKeyRing(newKeyRingConfig(JWTAlgHS512, "", "k1:"+oldSecret))
+	require.NoError(t, err)
+	oldToken, err := oldRing.Sign(testClaims())
+	require.NoError(t, err)
+
+	ring, err := NewJWTKeyRing(newKeyRingConfig(JWTAlgHS512, "k2", "k1:"+oldSecret, "k2:"+newSecret))
+	require.NoError(t, err)
+	newToken, err := ring.Sign(testClaims())
+	require.NoError(t, err)
+
+	parsed, _, err := jwt.NewParser().ParseUnverified(newToken, jwt.MapClaims{})
+	require.NoError(t, err)
+	assert.Equal(t, "k2", parsed.Header["kid"])
+
+	for _, token := range []string{oldToken, newToken} {
+		auth, err := ring.ByToken(token)
+		require.NoError(t, err)
+		_, err = jwtauth.VerifyToken(auth, token)
+		assert.NoError(t, err)
+	}
+
+	// ключ k1 выведен из набора
+	retiredRing, err := NewJWTKeyRing(newKeyRingConfig(JWTAlgHS512, "", "k2:"+newSecret))
+	require.NoError(t, err)
+	_, err = retiredRing.ByToken(oldToken)
+	assert.Error(t, err)
+}
+
+func TestJWTKeyRing_RS256ServerKeys(t *testing.T) {
+	serverKeys := createTestKeys(t, signers.NewRsaSigner())
+	cfg := newKeyRingConfig(JWTAlgRS256, "")
+	cfg.Value().PathKeys = path.Dir(serverKeys.PrivateKeyPath())
+
+	ring, err := NewJWTKeyRing(cfg)
+	require.NoError(t, err)
+	token, err := ring.Sign(testClaims())
+	require.NoError(t, err)
+
+	auth, err := ring.ByToken(token)
+	require.NoError(t, err)
+	_, err = jwtauth.VerifyToken(auth, token)
+	assert.NoError(t, err)
+
+	// HS512 токен не должен проходить проверку RSA ключом
+	hsToken, err := jwt.NewWithClaims(jwt.SigningMethodHS512, testClaims()).SignedString([]byte("secret"))
+	require.NoError(t, err)
+	_, err = jwtauth.VerifyToken(auth, hsToken)
+	assert.Error(t, err)
+}
+
+func TestJWTKeyRing_EdDSA(t *testing.T) {
+	active := createTestKeys(t, ed25519Generator{})
+	retired := createTestKeys(t, ed25519Generator{})
+
+	ring, err := NewJWTKeyRing(newKeyRingConfig(JWTAlgEdDSA, "new", "new:"+active.PrivateKeyPath(), "old:"+retired.PublicKeyPath()))
+	require.NoError(t, err)
+	token, err := ring.Sign(testClaims())
+	require.NoError(t, err)
+	auth, err := ring.ByToken(token)
+	require.NoError(t, err)
+	_, err = jwtauth.VerifyToken(auth, token)
+	assert.NoError(t, err)
+
+	// ключ, оставленный только для проверки, не может быть активным
+	_, err = NewJWTKeyRing(newKeyRingConfig(JWTAlgEdDSA, "old", "old:"+retired.PublicKeyPath()))
+	assert.Error(t, err)
+}
+
+func TestJWTKeyRing_InvalidConfig(t *testing.T) {
+	rsaKeys := createTestKeys(t, signers.NewRsaSigner())
+	secret := strings.Repeat("s", minJWTSecretLen)
+	tests := []struct {
+		name string
+		cfg  *config.Config
+	}{
+		{"unknown alg", newKeyRingConfig("HS256", "", "k1:"+secret)},
+		{"no keys", newKeyRingConfig(JWTAlgHS512, "")},
+		{"short secret", newKeyRingConfig(JWTAlgHS512, "", "k1:short")},
+		{"bad entry", newKeyRingConfig(JWTAlgHS512, "", secret)},
+		{"duplicate kid", newKeyRingConfig(JWTAlgHS512, "", "k1:"+secret, "k1:"+secret)},
+		{"unknown active", newKeyRingConfig(JWTAlgHS512, "k9", "k1:"+secret)},
+		{"missing file", newKeyRingConfig(JWTAlgRS256, "", "k1:/not/exists.pem")},
+		{"alg mismatch", newKeyRingConfig(JWTAlgEdDSA, "", "k1:"+rsaKeys.PrivateKeyPath())},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := NewJWTKeyRing(test.cfg)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestAccess_JWTVerifier(t *testing.T) {
+	a, err := NewAccess(newTestConfig())
+	require.NoError(t, err)
+
+	token, err := a.IssueToken("user-uuid", "session-uuid", "device-uuid")
+	require.NoError(t, err)
+
+	handler := a.JWTVerifier(jwtauth.Authenticator(a.FillJWTToken())(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		uuid, err := a.GetUserUUIDByJWTToken(req.Context())
+		require.NoError(t, err)
+		assert.Equal(t, "user-uuid", uuid)
+		sessionUUID, err := a.GetSessionUUIDByJWTToken(req.Context())
+		require.NoError(t, err)
+		assert.Equal(t, "session-uuid", sessionUUID)
+		deviceUUID, err := a.GetDeviceUUIDByJWTToken(req.Context())
+		require.NoError(t, err)
+		assert.Equal(t, "device-uuid", deviceUUID)
+		res.WriteHeader(http.StatusOK)
+	})))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	res := httptest.NewRecorder()
+	handler.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusOK, res.Code)
+
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer broken")
+	res = httptest.NewRecorder()
+	handler.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusUnauthorized, res.Code)
+
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	res = httptest.NewRecorder()
+	handler.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusUnauthorized, res.Code)
+}
@@ -0,0 +1,106 @@
+package audit
+
+import (
+	"context"
+	"time"
+
+	"github.com/northmule/gophkeeper/internal/common/model_data"
+	"github.com/northmule/gophkeeper/internal/common/models"
+	"github.com/northmule/gophkeeper/internal/server/services/kek"
+)
+
+// chainBatchSize записей цепочки за один запрос при проверке
+const chainBatchSize = 500
+
+// chainKeyPurpose назначение ключа HMAC цепочки, производного от KEK
+const chainKeyPurpose = "gophkeeper audit chain"
+
+// Store хранилище журнала
+type Store interface {
+	Add(ctx context.Context, event *models.AuditEvent, chainHash func(event *models.AuditEvent) string) (int64, error)
+	FindChain(ctx context.Context, userUUID string, afterID int64, limit int) ([]models.AuditEvent, error)
+}
+
+// Auditor журнал действий пользователей с цепочкой хэшей. Записи подписываются HMAC ключом,
+// производным от KEK: владелец БД без ключа сервера не пересчитает цепочку после изменения записи
+type Auditor struct {
+	store       Store
+	keyProvider kek.KeyProvider
+	now         func() time.Time
+}
+
+// NewAuditor конструктор
+func NewAuditor(store Store, keyProvider kek.KeyProvider) *Auditor {
+	return &Auditor{
+		store:       store,
+		keyProvider: keyProvider,
+		now:         time.Now,
+	}
+}
+
+// Record запись события в конец цепочки пользователя, подпись ключом от активного KEK
+func (a *Auditor) Record(ctx context.Context, event models.AuditEvent) error {
+	event.KeyID = a.keyProvider.ActiveKeyID()
+	key, err := a.keyProvider.DeriveKey(event.KeyID, chainKeyPurpose)
+	if err != nil {
+		return err
+	}
+	event.CreatedAt = a.now()
+	_, err = a.store.Add(ctx, &event, func(event *models.AuditEvent) string {
+		return event.ChainHash(key)
+	})
+	return err
+}
+
+// Verify проверка цепочки пользователя с первой записи: каждая запись ссылается на хэш предыдущей,
+// хэш записи сходится с её полями. Записи без подписи допустимы только до первой подписанной записи
+func (a *Auditor) Verify(ctx context.Context, userUUID string) (*model_data.AuditVerifyResponse, error) {
+	result := &model_data.AuditVerifyResponse{Valid: true}
+	keys := make(map[string][]byte)
+	var afterID int64
+	for {
+		events, err := a.store.FindChain(ctx, userUUID, afterID, chainBatchSize)
+		if err != nil {
+			return nil, err
+		}
+		for _, event := range events {
+			key, err := a.chainKey(keys, event.KeyID)
+			if err != nil {
+				return nil, err
+			}
+			// после подписанных записей запись без подписи - подмена хэша без ключа
+			unsigned := event.KeyID == ""
+			downgraded := unsigned && result.Checked > result.Unsigned
+			if downgraded || event.PrevHash != result.LastHash || event.ChainHash(key) != event.Hash {
+				result.Valid = false
+				result.BrokenID = event.ID
+				return result, nil
+			}
+			if unsigned {
+				result.Unsigned++
+			}
+			result.LastHash = event.Hash
+			result.Checked++
+			afterID = event.ID
+		}
+		if len(events) < chainBatchSize {
+			return result, nil
+		}
+	}
+}
+
+// chainKey ключ HMAC записей, подписанных от KEK id. Для записей без подписи - nil
+func (a *Auditor) chainKey(keys map[string][]byte, id string) ([]byte, error) {
+	if id == "" {
+		return nil, nil
+	}
+	if key, ok := keys[id]; ok {
+		return key, nil
+	}
+	key, err := a.keyProvider.DeriveKey(id, chainKeyPurpose)
+	if err != nil {
+		return nil, err
+	}
+	keys[id] = key
+	return key, nil
+}
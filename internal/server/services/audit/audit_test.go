@@ -0,0 +1,163 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/northmule/gophkeeper/internal/common/models"
+	"github.com/northmule/gophkeeper/internal/server/config"
+	"github.com/northmule/gophkeeper/internal/server/services/kek"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryStore цепочка в памяти, как её строит репозиторий
+type memoryStore struct {
+	events []models.AuditEvent
+	err    error
+}
+
+func (s *memoryStore) Add(_ context.Context, event *models.AuditEvent, chainHash func(event *models.AuditEvent) string) (int64, error) {
+	if s.err != nil {
+		return 0, s.err
+	}
+	event.ID = int64(len(s.events) + 1)
+	event.CreatedAt = event.CreatedAt.UTC().Truncate(time.Microsecond)
+	if len(s.events) > 0 {
+		event.PrevHash = s.events[len(s.events)-1].Hash
+	}
+	event.Hash = chainHash(event)
+	s.events = append(s.events, *event)
+	return event.ID, nil
+}
+
+func (s *memoryStore) FindChain(_ context.Context, _ string, afterID int64, limit int) ([]models.AuditEvent, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	result := make([]models.AuditEvent, 0)
+	for _, event := range s.events {
+		if event.ID > afterID && len(result) < limit {
+			result = append(result, event)
+		}
+	}
+	return result, nil
+}
+
+func newTestKeyProvider(t *testing.T) *kek.LocalFileProvider {
+	cfg := config.NewConfig()
+	cfg.Value().PathKeys = t.TempDir()
+	provider, err := kek.NewLocalFileProvider(cfg)
+	require.NoError(t, err)
+	return provider
+}
+
+func newFilledAuditor(t *testing.T, count int) (*Auditor, *memoryStore) {
+	store := new(memoryStore)
+	auditor := NewAuditor(store, newTestKeyProvider(t))
+	for i := 0; i < count; i++ {
+		err := auditor.Record(context.Background(), models.AuditEvent{UserUUID: "user-uuid", Action: models.AuditItemRead, DataUUID: "data-uuid"})
+		require.NoError(t, err)
+	}
+	return auditor, store
+}
+
+func TestAuditor_Verify(t *testing.T) {
+	auditor, store := newFilledAuditor(t, chainBatchSize+3)
+
+	result, err := auditor.Verify(context.Background(), "user-uuid")
+	require.NoError(t, err)
+	assert.True(t, result.Valid)
+	assert.Equal(t, int64(chainBatchSize+3), result.Checked)
+	assert.Equal(t, store.events[len(store.events)-1].Hash, result.LastHash)
+}
+
+func TestAuditor_Verify_Tampered(t *testing.T) {
+	t.Run("changed event", func(t *testing.T) {
+		auditor, store := newFilledAuditor(t, 5)
+		store.events[2].DataUUID = "other-uuid"
+
+		result, err := auditor.Verify(context.Background(), "user-uuid")
+		require.NoError(t, err)
+		assert.False(t, result.Valid)
+		assert.Equal(t, int64(3), result.BrokenID)
+		assert.Equal(t, int64(2), result.Checked)
+	})
+
+	t.Run("deleted event", func(t *testing.T) {
+		auditor, store := newFilledAuditor(t, 5)
+		store.events = append(store.events[:1], store.events[2:]...)
+
+		result, err := auditor.Verify(context.Background(), "user-uuid")
+		require.NoError(t, err)
+		assert.False(t, result.Valid)
+		assert.Equal(t, int64(3), result.BrokenID)
+	})
+
+	t.Run("rehashed event", func(t *testing.T) {
+		// без ключа сервера подпись изменённой записи не пересчитать
+		auditor, store := newFilledAuditor(t, 5)
+		store.events[2].Action = models.AuditLogin
+		store.events[2].Hash = store.events[2].ChainHash(nil)
+
+		result, err := auditor.Verify(context.Background(), "user-uuid")
+		require.NoError(t, err)
+		assert.False(t, result.Valid)
+		assert.Equal(t, int64(3), result.BrokenID)
+	})
+
+	t.Run("rehashed chain", func(t *testing.T) {
+		// пересчёт всей цепочки sha256 с конца: подписанные записи превращаются в записи без подписи
+		auditor, store := newFilledAuditor(t, 5)
+		store.events[2].Action = models.AuditLogin
+		for i := 2; i < len(store.events); i++ {
+			store.events[i].KeyID = ""
+			store.events[i].PrevHash = store.events[i-1].Hash
+			store.events[i].Hash = store.events[i].ChainHash(nil)
+		}
+
+		result, err := auditor.Verify(context.Background(), "user-uuid")
+		require.NoError(t, err)
+		assert.False(t, result.Valid)
+		assert.Equal(t, int64(3), result.BrokenID)
+	})
+}
+
+func TestAuditor_Verify_Unsigned(t *testing.T) {
+	// записи, сделанные до подписи, проверяются sha256 и считаются отдельно
+	store := new(memoryStore)
+	for i := 0; i < 2; i++ {
+		_, err := store.Add(context.Background(), &models.AuditEvent{UserUUID: "user-uuid", Action: models.AuditLogin}, func(event *models.AuditEvent) string {
+			return event.ChainHash(nil)
+		})
+		require.NoError(t, err)
+	}
+	auditor := NewAuditor(store, newTestKeyProvider(t))
+	require.NoError(t, auditor.Record(context.Background(), models.AuditEvent{UserUUID: "user-uuid", Action: models.AuditItemRead}))
+
+	result, err := auditor.Verify(context.Background(), "user-uuid")
+	require.NoError(t, err)
+	assert.True(t, result.Valid)
+	assert.Equal(t, int64(3), result.Checked)
+	assert.Equal(t, int64(2), result.Unsigned)
+	assert.NotEmpty(t, store.events[2].KeyID)
+}
+
+func TestAuditor_Verify_UnknownKey(t *testing.T) {
+	auditor, store := newFilledAuditor(t, 2)
+	store.events[1].KeyID = "removed"
+
+	_, err := auditor.Verify(context.Background(), "user-uuid")
+	assert.ErrorIs(t, err, kek.ErrUnknownKey)
+}
+
+func TestAuditor_StoreError(t *testing.T) {
+	store := &memoryStore{err: errors.New("db error")}
+	auditor := NewAuditor(store, newTestKeyProvider(t))
+
+	assert.Error(t, auditor.Record(context.Background(), models.AuditEvent{UserUUID: "user-uuid"}))
+	_, err := auditor.Verify(context.Background(), "user-uuid")
+	assert.Error(t, err)
+}
@@ -0,0 +1,220 @@
+package ca
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path"
+	"time"
+
+	"github.com/northmule/gophkeeper/internal/server/config"
+)
+
+// Удостоверяющий центр сервера выпускает сертификаты клиентов для входа по mTLS.
+// Ключ и сертификат CA хранятся в PATH_KEYS и создаются при первом запуске с MTLS_ENABLE
+
+const (
+	// CertificateFileName сертификат CA
+	CertificateFileName = "ca_cert.pem"
+	// PrivateKeyFileName приватный ключ CA
+	PrivateKeyFileName = "ca_key.pem"
+
+	// caLifetime время жизни сертификата CA
+	caLifetime = 10 * 365 * 24 * time.Hour
+	// defaultCertificateTTL время жизни сертификата клиента без настройки
+	defaultCertificateTTL = 365 * 24 * time.Hour
+)
+
+// ErrUnsupportedKey ключ клиента не подходит для сертификата
+var ErrUnsupportedKey = errors.New("unsupported client public key")
+
+// Authority удостоверяющий центр
+type Authority struct {
+	certificate *x509.Certificate
+	certPEM     []byte
+	key         crypto.Signer
+	ttl         time.Duration
+	now         func() time.Time
+}
+
+// IssuedCertificate выпущенный сертификат клиента
+type IssuedCertificate struct {
+	Certificate   *x509.Certificate
+	PEM           []byte
+	Fingerprint   string // sha256 сертификата в hex
+	PublicKeyHash string // sha256 публичного ключа в hex
+}
+
+// NewAuthority конструктор. Загружает CA из PATH_KEYS или создаёт новый
+func NewAuthority(cfg *config.Config) (*Authority, error) {
+	certPath := path.Join(cfg.Value().PathKeys, CertificateFileName)
+	keyPath := path.Join(cfg.Value().PathKeys, PrivateKeyFileName)
+
+	_, errCert := os.Stat(certPath)
+	_, errKey := os.Stat(keyPath)
+	if errors.Is(errCert, os.ErrNotExist) && errors.Is(errKey, os.ErrNotExist) {
+		if err := generate(certPath, keyPath); err != nil {
+			return nil, err
+		}
+	}
+
+	instance, err := load(certPath, keyPath)
+	if err != nil {
+		return nil, err
+	}
+	instance.ttl = cfg.Value().MTLSCertTTL
+	if instance.ttl <= 0 {
+		instance.ttl = defaultCertificateTTL
+	}
+	instance.now = time.Now
+	return instance, nil
+}
+
+// CertificatePEM сертификат CA в PEM
+func (a *Authority) CertificatePEM() []byte {
+	return a.certPEM
+}
+
+// Pool набор доверенных сертификатов для проверки клиентов
+func (a *Authority) Pool() *x509.CertPool {
+	pool := x509.NewCertPool()
+	pool.AddCert(a.certificate)
+	return pool
+}
+
+// Issue выпуск сертификата клиента на публичный ключ, uuid пользователя записывается в CommonName
+func (a *Authority) Issue(publicKey crypto.PublicKey, userUUID string) (*IssuedCertificate, error) {
+	switch publicKey.(type) {
+	case *rsa.PublicKey, *ecdsa.PublicKey, ed25519.PublicKey:
+	default:
+		return nil, ErrUnsupportedKey
+	}
+	publicKeyBytes, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		return nil, ErrUnsupportedKey
+	}
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, err
+	}
+	now := a.now()
+	template := x509.Certificate{
+		SerialNumber: serialNumber,
+		Subject: pkix.Name{
+			Organization: []string{"Go32_client"},
+			CommonName:   userUUID,
+		},
+		NotBefore:   now.Add(-time.Minute),
+		NotAfter:    now.Add(a.ttl),
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &template, a.certificate, publicKey, a.key)
+	if err != nil {
+		return nil, err
+	}
+	certificate, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+	fingerprint := sha256.Sum256(der)
+	keyHash := sha256.Sum256(publicKeyBytes)
+	return &IssuedCertificate{
+		Certificate:   certificate,
+		PEM:           pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		Fingerprint:   hex.EncodeToString(fingerprint[:]),
+		PublicKeyHash: hex.EncodeToString(keyHash[:]),
+	}, nil
+}
+
+// generate новый ключ и самоподписанный сертификат CA
+func generate(certPath string, keyPath string) error {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return err
+	}
+	template := x509.Certificate{
+		SerialNumber: serialNumber,
+		Subject: pkix.Name{
+			Organization: []string{"Go32_Server"},
+			CommonName:   "GophKeeper client CA",
+		},
+		NotBefore:             time.Now().Add(-time.Minute),
+		NotAfter:              time.Now().Add(caLifetime),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLenZero:        true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, key.Public(), key)
+	if err != nil {
+		return err
+	}
+	keyBytes, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(path.Dir(keyPath), 0700); err != nil {
+		return err
+	}
+	if err = os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyBytes}), 0600); err != nil {
+		return err
+	}
+	return os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644)
+}
+
+// load чтение ключа и сертификата CA
+func load(certPath string, keyPath string) (*Authority, error) {
+	certPEM, err := os.ReadFile(certPath)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(certPEM)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, fmt.Errorf("%s: no certificate", certPath)
+	}
+	certificate, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	if !certificate.IsCA {
+		return nil, fmt.Errorf("%s: not a CA certificate", certPath)
+	}
+
+	keyPEM, err := os.ReadFile(keyPath)
+	if err != nil {
+		return nil, err
+	}
+	block, _ = pem.Decode(keyPEM)
+	if block == nil {
+		return nil, fmt.Errorf("%s: no private key", keyPath)
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := parsed.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("%s: unsupported private key", keyPath)
+	}
+	if publicKey, ok := key.Public().(interface{ Equal(crypto.PublicKey) bool }); !ok || !publicKey.Equal(certificate.PublicKey) {
+		return nil, fmt.Errorf("%s does not match %s", keyPath, certPath)
+	}
+	return &Authority{certificate: certificate, certPEM: certPEM, key: key}, nil
+}
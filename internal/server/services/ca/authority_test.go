@@ -0,0 +1,132 @@
+package ca
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"math/big"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/northmule/gophkeeper/internal/common/keys"
+	"github.com/northmule/gophkeeper/internal/server/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestConfig(t *testing.T) *config.Config {
+	cfg := config.NewConfig()
+	cfg.Value().PathKeys = t.TempDir()
+	cfg.Value().MTLSCertTTL = time.Hour
+	return cfg
+}
+
+func TestAuthority_Issue(t *testing.T) {
+	authority, err := NewAuthority(newTestConfig(t))
+	require.NoError(t, err)
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	ed25519Key, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	for name, publicKey := range map[string]crypto.PublicKey{"rsa": rsaKey.Public(), "ecdsa": ecdsaKey.Public(), "ed25519": ed25519Key} {
+		t.Run(name, func(t *testing.T) {
+			issued, err := authority.Issue(publicKey, "user-uuid")
+			require.NoError(t, err)
+			assert.Equal(t, "user-uuid", issued.Certificate.Subject.CommonName)
+			assert.Equal(t, keys.Fingerprint(issued.Certificate.Raw), issued.Fingerprint)
+			fingerprint, err := keys.CertificateFingerprint(issued.PEM)
+			require.NoError(t, err)
+			assert.Equal(t, issued.Fingerprint, fingerprint)
+			assert.WithinDuration(t, time.Now().Add(time.Hour), issued.Certificate.NotAfter, time.Minute)
+
+			_, err = issued.Certificate.Verify(x509.VerifyOptions{
+				Roots:     authority.Pool(),
+				KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+			})
+			assert.NoError(t, err)
+
+			again, err := authority.Issue(publicKey, "user-uuid")
+			require.NoError(t, err)
+			assert.NotEqual(t, issued.Fingerprint, again.Fingerprint)
+			assert.Equal(t, issued.PublicKeyHash, again.PublicKeyHash)
+		})
+	}
+
+	t.Run("unsupported key", func(t *testing.T) {
+		_, err = authority.Issue("key", "user-uuid")
+		assert.ErrorIs(t, err, ErrUnsupportedKey)
+	})
+}
+
+func TestNewAuthority_Reload(t *testing.T) {
+	cfg := newTestConfig(t)
+	authority, err := NewAuthority(cfg)
+	require.NoError(t, err)
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	issued, err := authority.Issue(key.Public(), "user-uuid")
+	require.NoError(t, err)
+
+	// после перезапуска сервера ранее выданные сертификаты остаются действительными
+	reloaded, err := NewAuthority(cfg)
+	require.NoError(t, err)
+	assert.Equal(t, authority.CertificatePEM(), reloaded.CertificatePEM())
+	_, err = issued.Certificate.Verify(x509.VerifyOptions{
+		Roots:     reloaded.Pool(),
+		KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	assert.NoError(t, err)
+}
+
+func TestNewAuthority_Errors(t *testing.T) {
+	t.Run("key without certificate", func(t *testing.T) {
+		cfg := newTestConfig(t)
+		require.NoError(t, os.WriteFile(path.Join(cfg.Value().PathKeys, PrivateKeyFileName), []byte("key"), 0600))
+		_, err := NewAuthority(cfg)
+		assert.Error(t, err)
+	})
+	t.Run("key does not match certificate", func(t *testing.T) {
+		cfg := newTestConfig(t)
+		_, err := NewAuthority(cfg)
+		require.NoError(t, err)
+		other := newTestConfig(t)
+		_, err = NewAuthority(other)
+		require.NoError(t, err)
+		otherKey, err := os.ReadFile(path.Join(other.Value().PathKeys, PrivateKeyFileName))
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(path.Join(cfg.Value().PathKeys, PrivateKeyFileName), otherKey, 0600))
+		_, err = NewAuthority(cfg)
+		assert.Error(t, err)
+	})
+	t.Run("not a CA", func(t *testing.T) {
+		cfg := newTestConfig(t)
+		serverKeys := keys.NewKeys(keys.Options{
+			Generator:    ecdsaGenerator{},
+			SavePath:     cfg.Value().PathKeys,
+			Organization: "test",
+			Country:      "RU",
+			SerialNumber: new(big.Int).SetInt64(1),
+		})
+		require.NoError(t, serverKeys.InitSelfSigned())
+		require.NoError(t, os.Rename(serverKeys.CertPath(), path.Join(cfg.Value().PathKeys, CertificateFileName)))
+		require.NoError(t, os.Rename(serverKeys.PrivateKeyPath(), path.Join(cfg.Value().PathKeys, PrivateKeyFileName)))
+		_, err := NewAuthority(cfg)
+		assert.Error(t, err)
+	})
+}
+
+type ecdsaGenerator struct{}
+
+func (g ecdsaGenerator) GenerateKey() (crypto.Signer, error) {
+	return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+}
@@ -1,7 +1,11 @@
 package service
 
 import (
+	"crypto"
+	"crypto/ecdh"
 	"crypto/rsa"
+	"errors"
+	"os"
 	"path"
 
 	"github.com/northmule/gophkeeper/internal/common/keys"
@@ -9,31 +13,38 @@ import (
 	"github.com/northmule/gophkeeper/internal/server/config"
 )
 
+// ErrUnsupportedKeyExchange схема обмена ключами не поддерживается ключами сервера
+var ErrUnsupportedKeyExchange = errors.New("unsupported key exchange")
+
 // Crypt сервис шифрования дешифрования данных
 type Crypt struct {
-	// Клиентский публичный ключ, для шифрования исходящиз сообщений
-	clientPublicKey *rsa.PublicKey
-	// Приватный ключ сервера, для расшифровки сообщений от клиента
-	serverPrivateKey *rsa.PrivateKey
+	// Приватный ключ сервера (RSA, ECDSA или Ed25519)
+	serverPrivateKey crypto.Signer
+	// Ключ X25519 для согласования ключа шифрования с клиентом
+	exchangeKey *ecdh.PrivateKey
 
 	cfg *config.Config
 }
 
 // CryptService общий интерфейс
 type CryptService interface {
-	EncryptRSA(data []byte) ([]byte, error)
-	DecryptRSA(data []byte) ([]byte, error)
+	// KeyExchanges поддерживаемые схемы обмена ключами в порядке предпочтения
+	KeyExchanges() []string
+	// ExchangePublicKey публичный ключ обмена X25519
+	ExchangePublicKey() []byte
+	// DecryptKey расшифровка ключа клиента, присланного по схеме обмена
+	DecryptKey(scheme string, data []byte) ([]byte, error)
 }
 
-// NewCrypt конструктор
+// NewCrypt конструктор. Ключ обмена создаётся при первом запуске
 func NewCrypt(cfg *config.Config) (*Crypt, error) {
 	instance := new(Crypt)
 	var err error
-	instance.clientPublicKey, err = util.FillPublicRsaKeyFromFile(path.Join(cfg.Value().PathKeys, keys.PublicKeyFileName))
+	instance.serverPrivateKey, err = util.FillPrivateKeyFromFile(path.Join(cfg.Value().PathKeys, keys.PrivateKeyFileName))
 	if err != nil {
 		return nil, err
 	}
-	instance.serverPrivateKey, err = util.FillPrivateRsaKeyFromFile(path.Join(cfg.Value().PathKeys, keys.PrivateKeyFileName))
+	instance.exchangeKey, err = loadOrCreateExchangeKey(path.Join(cfg.Value().PathKeys, keys.ExchangeKeyFileName))
 	if err != nil {
 		return nil, err
 	}
@@ -41,12 +52,53 @@ func NewCrypt(cfg *config.Config) (*Crypt, error) {
 	return instance, nil
 }
 
-// EncryptRSA Шифрование исходящих данных клиентским публичным ключом
-func (crypt *Crypt) EncryptRSA(data []byte) ([]byte, error) {
-	return util.DataEncryptRSA(data, crypt.clientPublicKey)
+func loadOrCreateExchangeKey(keyPath string) (*ecdh.PrivateKey, error) {
+	key, err := util.FillExchangePrivateKeyFromFile(keyPath)
+	if err == nil {
+		return key, nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	key, err = util.GenerateExchangeKey()
+	if err != nil {
+		return nil, err
+	}
+	keyPEM, err := util.ExchangePrivateKeyToPEM(key)
+	if err != nil {
+		return nil, err
+	}
+	if err = os.WriteFile(keyPath, keyPEM, 0600); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// KeyExchanges X25519 поддерживается всегда, RSA-OAEP только с RSA ключом сервера
+func (crypt *Crypt) KeyExchanges() []string {
+	schemes := []string{util.KeyExchangeX25519}
+	if _, ok := crypt.serverPrivateKey.(*rsa.PrivateKey); ok {
+		schemes = append(schemes, util.KeyExchangeRSA)
+	}
+	return schemes
+}
+
+// ExchangePublicKey публичный ключ обмена X25519
+func (crypt *Crypt) ExchangePublicKey() []byte {
+	return crypt.exchangeKey.PublicKey().Bytes()
 }
 
-// DecryptRSA Расшифровка входящих сообещний приватным ключом сервера
-func (crypt *Crypt) DecryptRSA(data []byte) ([]byte, error) {
-	return util.DataDecryptRSA(data, crypt.serverPrivateKey)
+// DecryptKey Расшифровка ключа клиента. Без схемы ключ считается зашифрованным RSA-OAEP (старые клиенты)
+func (crypt *Crypt) DecryptKey(scheme string, data []byte) ([]byte, error) {
+	switch scheme {
+	case util.KeyExchangeX25519:
+		return util.DataDecryptX25519(data, crypt.exchangeKey)
+	case util.KeyExchangeRSA, "":
+		rsaKey, ok := crypt.serverPrivateKey.(*rsa.PrivateKey)
+		if !ok {
+			return nil, ErrUnsupportedKeyExchange
+		}
+		return util.DataDecryptRSA(data, rsaKey)
+	}
+	return nil, ErrUnsupportedKeyExchange
 }
@@ -1,6 +1,7 @@
 package service
 
 import (
+	"crypto/ecdh"
 	"crypto/rand"
 	"math/big"
 	"os"
@@ -10,79 +11,105 @@ import (
 
 	"github.com/northmule/gophkeeper/internal/common/keys"
 	"github.com/northmule/gophkeeper/internal/common/keys/signers"
+	"github.com/northmule/gophkeeper/internal/common/util"
 	"github.com/northmule/gophkeeper/internal/server/config"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
-func TestNewCrypt_Success(t *testing.T) {
+func newTestCrypt(t *testing.T, generator keys.KeyGenerator) *Crypt {
 	mockCfg := config.NewConfig()
 	mockCfg.Value().PathKeys = path.Join("testpath")
 
 	os.MkdirAll(mockCfg.Value().PathKeys, 0755)
-	os.WriteFile(filepath.Join(mockCfg.Value().PathKeys, keys.PrivateKeyFileNameForEncryption), []byte("encryption_key"), 0644)
+	t.Cleanup(func() {
+		os.RemoveAll("testpath")
+	})
 
 	serialNumber, _ := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
 	kk := keys.NewKeys(keys.Options{
-		Generator:    signers.NewRsaSigner(),
+		Generator:    generator,
 		SavePath:     mockCfg.Value().PathKeys,
 		Organization: "Go32_client",
 		Country:      "RU",
 		SerialNumber: serialNumber,
 	})
-	_ = kk.InitSelfSigned()
+	require.NoError(t, kk.InitSelfSigned())
 
-	defer os.RemoveAll("testpath")
 	crypt, err := NewCrypt(mockCfg)
-	assert.NoError(t, err)
-	assert.NotNil(t, crypt)
+	require.NoError(t, err)
+	return crypt
+}
+
+func TestNewCrypt_Success(t *testing.T) {
+	crypt := newTestCrypt(t, signers.NewRsaSigner())
 	assert.NotNil(t, crypt.serverPrivateKey)
-	assert.NotNil(t, crypt.clientPublicKey)
+	assert.NotNil(t, crypt.exchangeKey)
+	assert.FileExists(t, filepath.Join("testpath", keys.ExchangeKeyFileName))
+
+	// ключ обмена создаётся один раз
+	again, err := NewCrypt(crypt.cfg)
+	assert.NoError(t, err)
+	assert.True(t, crypt.exchangeKey.Equal(again.exchangeKey))
 }
 
-func TestCrypt_EncryptRSA(t *testing.T) {
+func TestNewCrypt_NoServerKey(t *testing.T) {
 	mockCfg := config.NewConfig()
-	mockCfg.Value().PathKeys = path.Join("testpath")
+	mockCfg.Value().PathKeys = t.TempDir()
 
-	os.MkdirAll(mockCfg.Value().PathKeys, 0755)
-	os.WriteFile(filepath.Join(mockCfg.Value().PathKeys, keys.PrivateKeyFileNameForEncryption), []byte("encryption_key"), 0644)
+	crypt, err := NewCrypt(mockCfg)
+	assert.Error(t, err)
+	assert.Nil(t, crypt)
+}
 
-	serialNumber, _ := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
-	kk := keys.NewKeys(keys.Options{
-		Generator:    signers.NewRsaSigner(),
-		SavePath:     mockCfg.Value().PathKeys,
-		Organization: "Go32_client",
-		Country:      "RU",
-		SerialNumber: serialNumber,
-	})
-	_ = kk.InitSelfSigned()
+func TestCrypt_KeyExchanges(t *testing.T) {
+	crypt := newTestCrypt(t, signers.NewRsaSigner())
+	assert.Equal(t, []string{util.KeyExchangeX25519, util.KeyExchangeRSA}, crypt.KeyExchanges())
 
-	defer os.RemoveAll("testpath")
-	crypt, _ := NewCrypt(mockCfg)
-	v, e := crypt.EncryptRSA([]byte("text"))
-	assert.NoError(t, e)
-	assert.NotEmpty(t, v)
+	os.RemoveAll("testpath")
+	crypt = newTestCrypt(t, signers.NewEd25519Signer())
+	assert.Equal(t, []string{util.KeyExchangeX25519}, crypt.KeyExchanges())
 }
-func TestCrypt_DecryptRSA(t *testing.T) {
-	mockCfg := config.NewConfig()
-	mockCfg.Value().PathKeys = path.Join("testpath")
 
-	os.MkdirAll(mockCfg.Value().PathKeys, 0755)
-	os.WriteFile(filepath.Join(mockCfg.Value().PathKeys, keys.PrivateKeyFileNameForEncryption), []byte("encryption_key"), 0644)
+func TestCrypt_DecryptKey(t *testing.T) {
+	crypt := newTestCrypt(t, signers.NewRsaSigner())
 
-	serialNumber, _ := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
-	kk := keys.NewKeys(keys.Options{
-		Generator:    signers.NewRsaSigner(),
-		SavePath:     mockCfg.Value().PathKeys,
-		Organization: "Go32_client",
-		Country:      "RU",
-		SerialNumber: serialNumber,
+	t.Run("x25519", func(t *testing.T) {
+		publicKey, err := ecdh.X25519().NewPublicKey(crypt.ExchangePublicKey())
+		require.NoError(t, err)
+		encrypted, err := util.DataEncryptX25519([]byte("text"), publicKey)
+		require.NoError(t, err)
+
+		v, err := crypt.DecryptKey(util.KeyExchangeX25519, encrypted)
+		assert.NoError(t, err)
+		assert.Equal(t, "text", string(v))
 	})
-	_ = kk.InitSelfSigned()
-
-	defer os.RemoveAll("testpath")
-	crypt, _ := NewCrypt(mockCfg)
-	vv, _ := crypt.EncryptRSA([]byte("text"))
-	v, e := crypt.DecryptRSA(vv)
-	assert.NoError(t, e)
-	assert.NotEmpty(t, v)
+
+	t.Run("rsa", func(t *testing.T) {
+		publicKey, err := util.FillPublicRsaKeyFromFile(filepath.Join("testpath", keys.PublicKeyFileName))
+		require.NoError(t, err)
+		encrypted, err := util.DataEncryptRSA([]byte("text"), publicKey)
+		require.NoError(t, err)
+
+		v, err := crypt.DecryptKey(util.KeyExchangeRSA, encrypted)
+		assert.NoError(t, err)
+		assert.Equal(t, "text", string(v))
+
+		// старые клиенты не передают схему
+		v, err = crypt.DecryptKey("", encrypted)
+		assert.NoError(t, err)
+		assert.Equal(t, "text", string(v))
+	})
+
+	t.Run("unknown scheme", func(t *testing.T) {
+		_, err := crypt.DecryptKey("dh-md5", []byte("text"))
+		assert.ErrorIs(t, err, ErrUnsupportedKeyExchange)
+	})
+}
+
+func TestCrypt_DecryptKey_RSAWithEcdsaKey(t *testing.T) {
+	crypt := newTestCrypt(t, signers.NewEcdsaSigner())
+
+	_, err := crypt.DecryptKey(util.KeyExchangeRSA, []byte("text"))
+	assert.ErrorIs(t, err, ErrUnsupportedKeyExchange)
 }
@@ -0,0 +1,147 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/northmule/gophkeeper/internal/common/models"
+	"github.com/northmule/gophkeeper/internal/server/logger"
+)
+
+// События изменения данных создаются триггерами БД и рассылаются уведомлением Postgres в канал Channel.
+// Каждый экземпляр сервера слушает канал (Listener) и передаёт события подписчикам своих соединений (Hub)
+
+// Channel канал уведомлений Postgres
+const Channel = "data_events"
+
+// subscriberBuffer событий в очереди подписчика. Переполненная подписка закрывается, клиент переподключается
+const subscriberBuffer = 32
+
+const (
+	listenRetryMin = time.Second
+	listenRetryMax = 30 * time.Second
+)
+
+// Hub подписки на события пользователей в экземпляре сервера
+type Hub struct {
+	subscribers map[string]map[chan models.Event]struct{}
+	mx          sync.Mutex
+}
+
+// NewHub конструктор
+func NewHub() *Hub {
+	return &Hub{
+		subscribers: make(map[string]map[chan models.Event]struct{}),
+	}
+}
+
+// Subscribe подписка на события пользователя. Канал закрывается отпиской, при переполнении очереди
+// и после обрыва соединения с БД: события могли быть потеряны, клиенту нужно синхронизироваться
+func (h *Hub) Subscribe(userUUID string) (<-chan models.Event, func()) {
+	ch := make(chan models.Event, subscriberBuffer)
+	h.mx.Lock()
+	defer h.mx.Unlock()
+	if h.subscribers[userUUID] == nil {
+		h.subscribers[userUUID] = make(map[chan models.Event]struct{})
+	}
+	h.subscribers[userUUID][ch] = struct{}{}
+	return ch, func() {
+		h.mx.Lock()
+		defer h.mx.Unlock()
+		h.remove(userUUID, ch)
+	}
+}
+
+// Publish передача события подписчикам пользователя
+func (h *Hub) Publish(event models.Event) {
+	h.mx.Lock()
+	defer h.mx.Unlock()
+	for ch := range h.subscribers[event.UserUUID] {
+		select {
+		case ch <- event:
+		default:
+			h.remove(event.UserUUID, ch)
+		}
+	}
+}
+
+// Reset закрытие всех подписок
+func (h *Hub) Reset() {
+	h.mx.Lock()
+	defer h.mx.Unlock()
+	for userUUID, subscribers := range h.subscribers {
+		for ch := range subscribers {
+			h.remove(userUUID, ch)
+		}
+	}
+}
+
+func (h *Hub) remove(userUUID string, ch chan models.Event) {
+	if _, ok := h.subscribers[userUUID][ch]; !ok {
+		return
+	}
+	close(ch)
+	delete(h.subscribers[userUUID], ch)
+	if len(h.subscribers[userUUID]) == 0 {
+		delete(h.subscribers, userUUID)
+	}
+}
+
+// Notifier подписка на уведомления канала БД
+type Notifier interface {
+	Listen(ctx context.Context, channel string, handle func(payload string)) error
+}
+
+// Listener получает события всех экземпляров сервера из канала БД и передаёт подписчикам
+type Listener struct {
+	notifier Notifier
+	hub      *Hub
+	log      *logger.Logger
+	retryMin time.Duration
+}
+
+// NewListener конструктор
+func NewListener(notifier Notifier, hub *Hub, log *logger.Logger) *Listener {
+	return &Listener{
+		notifier: notifier,
+		hub:      hub,
+		log:      log,
+		retryMin: listenRetryMin,
+	}
+}
+
+// Run слушает канал до отмены ctx, после обрыва соединения подключается заново
+func (l *Listener) Run(ctx context.Context) {
+	delay := l.retryMin
+	for {
+		started := time.Now()
+		err := l.notifier.Listen(ctx, Channel, l.handle)
+		if ctx.Err() != nil {
+			return
+		}
+		// события до переподключения не доставлены
+		l.hub.Reset()
+		if time.Since(started) > listenRetryMax {
+			delay = l.retryMin
+		}
+		l.log.Errorf("Listening to data events has been interrupted, retry in %s: %s", delay, err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, listenRetryMax)
+	}
+}
+
+func (l *Listener) handle(payload string) {
+	var event models.Event
+	err := json.Unmarshal([]byte(payload), &event)
+	if err != nil {
+		l.log.Error(err)
+		return
+	}
+	l.hub.Publish(event)
+}
@@ -0,0 +1,95 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/northmule/gophkeeper/internal/common/models"
+	"github.com/northmule/gophkeeper/internal/server/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHub(t *testing.T) {
+	hub := NewHub()
+	first, unsubscribeFirst := hub.Subscribe("user-1")
+	second, unsubscribeSecond := hub.Subscribe("user-1")
+	other, unsubscribeOther := hub.Subscribe("user-2")
+	defer unsubscribeOther()
+
+	hub.Publish(models.Event{Type: models.EventItemCreated, UserUUID: "user-1", DataUUID: "data-1"})
+	assert.Equal(t, "data-1", (<-first).DataUUID)
+	assert.Equal(t, "data-1", (<-second).DataUUID)
+	assert.Len(t, other, 0)
+
+	unsubscribeFirst()
+	unsubscribeFirst()
+	_, ok := <-first
+	assert.False(t, ok)
+
+	// очередь переполнена: подписка закрывается
+	for i := 0; i <= subscriberBuffer; i++ {
+		hub.Publish(models.Event{Type: models.EventItemUpdated, UserUUID: "user-1"})
+	}
+	for range second {
+	}
+	unsubscribeSecond()
+	assert.NotContains(t, hub.subscribers, "user-1")
+
+	hub.Reset()
+	_, ok = <-other
+	assert.False(t, ok)
+}
+
+type notifierMock struct {
+	calls     int
+	payloads  []string
+	reconnect chan struct{}
+}
+
+func (n *notifierMock) Listen(ctx context.Context, channel string, handle func(payload string)) error {
+	n.calls++
+	if n.calls == 1 {
+		for _, payload := range n.payloads {
+			handle(payload)
+		}
+		return errors.New("connection lost")
+	}
+	close(n.reconnect)
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestListener_Run(t *testing.T) {
+	log, err := logger.NewLogger("info")
+	require.NoError(t, err)
+	hub := NewHub()
+	events, _ := hub.Subscribe("user-1")
+	notifier := &notifierMock{reconnect: make(chan struct{}), payloads: []string{
+		`{"type":"item_updated","user_uuid":"user-1","data_type":"card","data_uuid":"data-1","revision":7}`,
+		`not json`,
+		`{"type":"key_rotated","user_uuid":"user-2"}`,
+	}}
+	listener := NewListener(notifier, hub, log)
+	listener.retryMin = time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		listener.Run(ctx)
+		close(done)
+	}()
+
+	event := <-events
+	assert.Equal(t, models.Event{Type: models.EventItemUpdated, UserUUID: "user-1", DataType: "card", DataUUID: "data-1", Revision: 7}, event)
+	// после обрыва соединения подписки закрываются
+	_, ok := <-events
+	assert.False(t, ok)
+
+	<-notifier.reconnect
+	cancel()
+	<-done
+	assert.Equal(t, 2, notifier.calls)
+}
@@ -0,0 +1,112 @@
+package kek
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Ключи клиентов (private_client_key) хранятся в БД зашифрованными ключом шифрования ключей (KEK).
+// Формат значения: kek1:<kid>:<base64(nonce|ciphertext)>, uuid пользователя передаётся как AAD,
+// поэтому значение нельзя перенести в запись другого пользователя.
+// Значения без префикса - ключи, сохранённые до включения шифрования, они читаются как есть
+// и переводятся в новый формат командой kek_rewrap.
+
+// wrappedPrefix признак и версия формата зашифрованного значения
+const wrappedPrefix = "kek1:"
+
+// KeySize размер KEK в байтах (AES-256)
+const KeySize = 32
+
+// ErrUnknownKey значение зашифровано ключом, которого нет в наборе
+var ErrUnknownKey = errors.New("unknown key encryption key")
+
+// ErrMalformed повреждённое зашифрованное значение
+var ErrMalformed = errors.New("malformed wrapped key")
+
+// KeyProvider ключ шифрования ключей
+type KeyProvider interface {
+	// ActiveKeyID kid ключа, которым шифруются новые значения
+	ActiveKeyID() string
+	// Wrap шифрование ключа клиента активным KEK
+	Wrap(plain []byte, aad []byte) (string, error)
+	// Unwrap расшифровка ключа клиента KEK из значения
+	Unwrap(wrapped string, aad []byte) ([]byte, error)
+	// DeriveKey ключ назначения purpose, производный от KEK с kid id. Сам KEK наружу не выдаётся
+	DeriveKey(id string, purpose string) ([]byte, error)
+}
+
+// IsWrapped значение зашифровано KEK
+func IsWrapped(value string) bool {
+	return strings.HasPrefix(value, wrappedPrefix)
+}
+
+// KeyID kid ключа, которым зашифровано значение
+func KeyID(value string) (string, bool) {
+	if !IsWrapped(value) {
+		return "", false
+	}
+	id, _, found := strings.Cut(strings.TrimPrefix(value, wrappedPrefix), ":")
+	return id, found && id != ""
+}
+
+// seal шифрование AES-GCM и упаковка в формат хранения
+func seal(id string, key []byte, plain []byte, aad []byte) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, plain, aad)
+	return wrappedPrefix + id + ":" + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// open распаковка и расшифровка значения
+func open(key []byte, wrapped string, aad []byte) ([]byte, error) {
+	_, payload, found := strings.Cut(strings.TrimPrefix(wrapped, wrappedPrefix), ":")
+	if !found {
+		return nil, ErrMalformed
+	}
+	sealed, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, ErrMalformed
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < gcm.NonceSize()+gcm.Overhead() {
+		return nil, ErrMalformed
+	}
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	plain, err := gcm.Open(nil, nonce, ciphertext, aad)
+	if err != nil {
+		return nil, fmt.Errorf("unwrap key: %w", err)
+	}
+	return plain, nil
+}
+
+// derive ключ назначения purpose: HMAC-SHA256 от KEK, разные назначения дают независимые ключи
+func derive(key []byte, purpose string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(purpose))
+	return mac.Sum(nil)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,136 @@
+package kek
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/northmule/gophkeeper/internal/server/config"
+)
+
+// DefaultKeyFileName файл KEK, если KEK_KEYS не задан
+const DefaultKeyFileName = "kek.key"
+
+// defaultKeyID kid ключа по умолчанию
+const defaultKeyID = "local"
+
+// LocalFileProvider KEK из локальных файлов. Файл содержит 32 случайных байта в base64
+type LocalFileProvider struct {
+	activeID string
+	keys     map[string][]byte
+}
+
+// NewLocalFileProvider конструктор. Ключи задаются в KEK_KEYS в формате kid:путь,
+// без настройки используется kek.key из PATH_KEYS (создаётся при первом запуске)
+func NewLocalFileProvider(cfg *config.Config) (*LocalFileProvider, error) {
+	instance := &LocalFileProvider{
+		keys: make(map[string][]byte),
+	}
+	entries := cfg.Value().KEKKeys
+	activeID := cfg.Value().KEKActiveKeyID
+	if len(entries) == 0 {
+		defaultPath := path.Join(cfg.Value().PathKeys, DefaultKeyFileName)
+		if _, err := os.Stat(defaultPath); errors.Is(err, os.ErrNotExist) {
+			if err = GenerateKeyFile(defaultPath); err != nil {
+				return nil, err
+			}
+		}
+		entries = []string{defaultKeyID + ":" + defaultPath}
+	}
+
+	for _, entry := range entries {
+		id, keyPath, found := strings.Cut(strings.TrimSpace(entry), ":")
+		if !found || id == "" || keyPath == "" {
+			return nil, fmt.Errorf("invalid kek entry, expected kid:path")
+		}
+		if _, exists := instance.keys[id]; exists {
+			return nil, fmt.Errorf("duplicate kek id %q", id)
+		}
+		key, err := readKeyFile(keyPath)
+		if err != nil {
+			return nil, fmt.Errorf("kek %q: %w", id, err)
+		}
+		instance.keys[id] = key
+		if activeID == "" {
+			activeID = id
+		}
+	}
+	if _, ok := instance.keys[activeID]; !ok {
+		return nil, fmt.Errorf("active kek %q not found", activeID)
+	}
+	instance.activeID = activeID
+
+	return instance, nil
+}
+
+// ActiveKeyID kid активного ключа
+func (p *LocalFileProvider) ActiveKeyID() string {
+	return p.activeID
+}
+
+// Wrap шифрование активным ключом
+func (p *LocalFileProvider) Wrap(plain []byte, aad []byte) (string, error) {
+	return seal(p.activeID, p.keys[p.activeID], plain, aad)
+}
+
+// Unwrap расшифровка ключом из значения. Незашифрованное значение возвращается как есть
+func (p *LocalFileProvider) Unwrap(wrapped string, aad []byte) ([]byte, error) {
+	if !IsWrapped(wrapped) {
+		return []byte(wrapped), nil
+	}
+	id, ok := KeyID(wrapped)
+	if !ok {
+		return nil, ErrMalformed
+	}
+	key, ok := p.keys[id]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownKey, id)
+	}
+	return open(key, wrapped, aad)
+}
+
+// DeriveKey ключ назначения purpose от KEK с kid id. Ключ доступен, пока KEK id есть в наборе
+func (p *LocalFileProvider) DeriveKey(id string, purpose string) ([]byte, error) {
+	key, ok := p.keys[id]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownKey, id)
+	}
+	return derive(key, purpose), nil
+}
+
+// GenerateKeyFile создаёт файл с новым случайным KEK. Существующий файл не перезаписывается
+func GenerateKeyFile(keyPath string) error {
+	key := make([]byte, KeySize)
+	if _, err := rand.Read(key); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(path.Dir(keyPath), 0700); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(keyPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = file.WriteString(base64.StdEncoding.EncodeToString(key) + "\n")
+	return err
+}
+
+func readKeyFile(keyPath string) ([]byte, error) {
+	data, err := os.ReadFile(keyPath)
+	if err != nil {
+		return nil, err
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil {
+		return nil, fmt.Errorf("key file is not base64: %w", err)
+	}
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("key must be %d bytes, got %d", KeySize, len(key))
+	}
+	return key, nil
+}
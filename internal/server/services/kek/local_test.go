@@ -0,0 +1,161 @@
+package kek
+
+import (
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/northmule/gophkeeper/internal/server/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestConfig(t *testing.T, activeID string, entries ...string) *config.Config {
+	cfg := config.NewConfig()
+	cfg.Value().PathKeys = t.TempDir()
+	cfg.Value().KEKKeys = entries
+	cfg.Value().KEKActiveKeyID = activeID
+	return cfg
+}
+
+func newTestKeyFile(t *testing.T, name string) string {
+	keyPath := path.Join(t.TempDir(), name)
+	require.NoError(t, GenerateKeyFile(keyPath))
+	return keyPath
+}
+
+func TestLocalFileProvider_WrapUnwrap(t *testing.T) {
+	provider, err := NewLocalFileProvider(newTestConfig(t, "", "k1:"+newTestKeyFile(t, "k1.key")))
+	require.NoError(t, err)
+	assert.Equal(t, "k1", provider.ActiveKeyID())
+
+	wrapped, err := provider.Wrap([]byte("client key"), []byte("uuid-1"))
+	require.NoError(t, err)
+	assert.True(t, IsWrapped(wrapped))
+	assert.NotContains(t, wrapped, "client key")
+	id, ok := KeyID(wrapped)
+	assert.True(t, ok)
+	assert.Equal(t, "k1", id)
+
+	plain, err := provider.Unwrap(wrapped, []byte("uuid-1"))
+	require.NoError(t, err)
+	assert.Equal(t, "client key", string(plain))
+
+	t.Run("another user", func(t *testing.T) {
+		_, err = provider.Unwrap(wrapped, []byte("uuid-2"))
+		assert.Error(t, err)
+	})
+	t.Run("legacy plain value", func(t *testing.T) {
+		plain, err = provider.Unwrap("legacy key", []byte("uuid-1"))
+		require.NoError(t, err)
+		assert.Equal(t, "legacy key", string(plain))
+	})
+	t.Run("unknown key", func(t *testing.T) {
+		_, err = provider.Unwrap(strings.Replace(wrapped, "kek1:k1:", "kek1:k9:", 1), []byte("uuid-1"))
+		assert.ErrorIs(t, err, ErrUnknownKey)
+	})
+	t.Run("malformed", func(t *testing.T) {
+		for _, value := range []string{"kek1:", "kek1:k1", "kek1:k1:!!!", "kek1:k1:AAAA"} {
+			_, err = provider.Unwrap(value, []byte("uuid-1"))
+			assert.ErrorIs(t, err, ErrMalformed, value)
+		}
+	})
+}
+
+func TestLocalFileProvider_Rotation(t *testing.T) {
+	oldPath := newTestKeyFile(t, "k1.key")
+	newPath := newTestKeyFile(t, "k2.key")
+
+	oldProvider, err := NewLocalFileProvider(newTestConfig(t, "", "k1:"+oldPath))
+	require.NoError(t, err)
+	wrapped, err := oldProvider.Wrap([]byte("client key"), []byte("uuid"))
+	require.NoError(t, err)
+
+	provider, err := NewLocalFileProvider(newTestConfig(t, "k2", "k1:"+oldPath, "k2:"+newPath))
+	require.NoError(t, err)
+	assert.Equal(t, "k2", provider.ActiveKeyID())
+	plain, err := provider.Unwrap(wrapped, []byte("uuid"))
+	require.NoError(t, err)
+	assert.Equal(t, "client key", string(plain))
+}
+
+func TestLocalFileProvider_DeriveKey(t *testing.T) {
+	provider, err := NewLocalFileProvider(newTestConfig(t, "k2", "k1:"+newTestKeyFile(t, "k1.key"), "k2:"+newTestKeyFile(t, "k2.key")))
+	require.NoError(t, err)
+
+	key, err := provider.DeriveKey("k1", "audit")
+	require.NoError(t, err)
+	assert.Len(t, key, 32)
+	again, err := provider.DeriveKey("k1", "audit")
+	require.NoError(t, err)
+	assert.Equal(t, key, again)
+	// разные назначения и разные KEK дают разные ключи
+	other, err := provider.DeriveKey("k1", "other")
+	require.NoError(t, err)
+	assert.NotEqual(t, key, other)
+	other, err = provider.DeriveKey("k2", "audit")
+	require.NoError(t, err)
+	assert.NotEqual(t, key, other)
+	assert.NotEqual(t, provider.keys["k1"], key)
+
+	_, err = provider.DeriveKey("unknown", "audit")
+	assert.ErrorIs(t, err, ErrUnknownKey)
+}
+
+func TestLocalFileProvider_DefaultKey(t *testing.T) {
+	cfg := newTestConfig(t, "")
+	provider, err := NewLocalFileProvider(cfg)
+	require.NoError(t, err)
+	assert.Equal(t, "local", provider.ActiveKeyID())
+	assert.FileExists(t, path.Join(cfg.Value().PathKeys, DefaultKeyFileName))
+
+	wrapped, err := provider.Wrap([]byte("client key"), []byte("uuid"))
+	require.NoError(t, err)
+
+	// при повторном запуске используется тот же файл
+	again, err := NewLocalFileProvider(cfg)
+	require.NoError(t, err)
+	plain, err := again.Unwrap(wrapped, []byte("uuid"))
+	require.NoError(t, err)
+	assert.Equal(t, "client key", string(plain))
+}
+
+func TestNewLocalFileProvider_Errors(t *testing.T) {
+	keyPath := newTestKeyFile(t, "k1.key")
+	shortKey := path.Join(t.TempDir(), "short.key")
+	require.NoError(t, os.WriteFile(shortKey, []byte("c2hvcnQ="), 0600))
+	notBase64 := path.Join(t.TempDir(), "text.key")
+	require.NoError(t, os.WriteFile(notBase64, []byte("not a key"), 0600))
+
+	tests := []struct {
+		name     string
+		activeID string
+		entries  []string
+	}{
+		{name: "no path", entries: []string{"k1"}},
+		{name: "empty id", entries: []string{":" + keyPath}},
+		{name: "duplicate id", entries: []string{"k1:" + keyPath, "k1:" + keyPath}},
+		{name: "missing file", entries: []string{"k1:" + path.Join(t.TempDir(), "none.key")}},
+		{name: "short key", entries: []string{"k1:" + shortKey}},
+		{name: "not base64", entries: []string{"k1:" + notBase64}},
+		{name: "unknown active", activeID: "k2", entries: []string{"k1:" + keyPath}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewLocalFileProvider(newTestConfig(t, tt.activeID, tt.entries...))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestGenerateKeyFile_NotOverwrite(t *testing.T) {
+	keyPath := newTestKeyFile(t, "k1.key")
+	before, err := os.ReadFile(keyPath)
+	require.NoError(t, err)
+
+	assert.Error(t, GenerateKeyFile(keyPath))
+	after, err := os.ReadFile(keyPath)
+	require.NoError(t, err)
+	assert.Equal(t, before, after)
+}
@@ -0,0 +1,100 @@
+package kek
+
+import (
+	"context"
+
+	"github.com/northmule/gophkeeper/internal/common/models"
+)
+
+// ClientKeyStore хранилище ключей клиентов
+type ClientKeyStore interface {
+	FindAllPrivateClientKeys(ctx context.Context) ([]models.User, error)
+	ReplacePrivateClientKey(ctx context.Context, userUUID string, oldValue string, newValue string) (bool, error)
+}
+
+// DeviceKeyStore хранилище ключей клиентов устройств
+type DeviceKeyStore interface {
+	FindAllClientKeys(ctx context.Context) ([]models.Device, error)
+	ReplaceClientKey(ctx context.Context, uuid string, oldValue string, newValue string) (bool, error)
+}
+
+// RewrapResult итог перешифрования
+type RewrapResult struct {
+	Total     int // всего ключей
+	Rewrapped int // перешифровано активным KEK
+	Skipped   int // уже зашифрованы активным KEK или изменены во время работы
+	Failed    int // не удалось расшифровать
+}
+
+// Rewrap перешифровывает ключи клиентов активным KEK: после смены KEK_ACTIVE_KID
+// и для значений, сохранённых до включения шифрования. Прежний ключ должен оставаться в KEK_KEYS
+// до завершения. Ошибка расшифровки отдельной записи не прерывает работу, запись учитывается в Failed
+func Rewrap(ctx context.Context, store ClientKeyStore, provider KeyProvider, onError func(userUUID string, err error)) (RewrapResult, error) {
+	users, err := store.FindAllPrivateClientKeys(ctx)
+	if err != nil {
+		return RewrapResult{}, err
+	}
+	keys := make([]ownedKey, 0, len(users))
+	for _, user := range users {
+		keys = append(keys, ownedKey{owner: user.UUID, value: user.PrivateClientKey})
+	}
+	return rewrap(ctx, keys, store.ReplacePrivateClientKey, provider, onError)
+}
+
+// RewrapDevices перешифровывает активным KEK ключи клиентов устройств, как Rewrap
+func RewrapDevices(ctx context.Context, store DeviceKeyStore, provider KeyProvider, onError func(deviceUUID string, err error)) (RewrapResult, error) {
+	devices, err := store.FindAllClientKeys(ctx)
+	if err != nil {
+		return RewrapResult{}, err
+	}
+	keys := make([]ownedKey, 0, len(devices))
+	for _, device := range devices {
+		keys = append(keys, ownedKey{owner: device.UUID, value: device.ClientKey})
+	}
+	return rewrap(ctx, keys, store.ReplaceClientKey, provider, onError)
+}
+
+// ownedKey ключ клиента и UUID его владельца (дополнительные данные при шифровании KEK)
+type ownedKey struct {
+	owner string
+	value string
+}
+
+type replaceFunc func(ctx context.Context, owner string, oldValue string, newValue string) (bool, error)
+
+func rewrap(ctx context.Context, keys []ownedKey, replace replaceFunc, provider KeyProvider, onError func(owner string, err error)) (RewrapResult, error) {
+	var result RewrapResult
+	for _, key := range keys {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+		result.Total++
+		if id, ok := KeyID(key.value); ok && id == provider.ActiveKeyID() {
+			result.Skipped++
+			continue
+		}
+		plain, err := provider.Unwrap(key.value, []byte(key.owner))
+		if err != nil {
+			result.Failed++
+			if onError != nil {
+				onError(key.owner, err)
+			}
+			continue
+		}
+		wrapped, err := provider.Wrap(plain, []byte(key.owner))
+		if err != nil {
+			return result, err
+		}
+		replaced, err := replace(ctx, key.owner, key.value, wrapped)
+		if err != nil {
+			return result, err
+		}
+		if !replaced {
+			// ключ сохранён заново во время работы, новое значение уже зашифровано активным KEK
+			result.Skipped++
+			continue
+		}
+		result.Rewrapped++
+	}
+	return result, nil
+}
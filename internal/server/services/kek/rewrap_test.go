@@ -0,0 +1,149 @@
+package kek
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/northmule/gophkeeper/internal/common/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeKeyStore struct {
+	values  map[string]string
+	order   []string
+	changed map[string]bool // записи, изменённые параллельно с перешифрованием
+	err     error
+}
+
+func (s *fakeKeyStore) FindAllPrivateClientKeys(_ context.Context) ([]models.User, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	users := make([]models.User, 0, len(s.order))
+	for _, userUUID := range s.order {
+		user := models.User{PrivateClientKey: s.values[userUUID]}
+		user.UUID = userUUID
+		users = append(users, user)
+	}
+	return users, nil
+}
+
+func (s *fakeKeyStore) ReplacePrivateClientKey(_ context.Context, userUUID string, oldValue string, newValue string) (bool, error) {
+	if s.changed[userUUID] || s.values[userUUID] != oldValue {
+		return false, nil
+	}
+	s.values[userUUID] = newValue
+	return true, nil
+}
+
+func (s *fakeKeyStore) FindAllClientKeys(ctx context.Context) ([]models.Device, error) {
+	users, err := s.FindAllPrivateClientKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+	devices := make([]models.Device, 0, len(users))
+	for _, user := range users {
+		device := models.Device{ClientKey: user.PrivateClientKey}
+		device.UUID = user.UUID
+		devices = append(devices, device)
+	}
+	return devices, nil
+}
+
+func (s *fakeKeyStore) ReplaceClientKey(ctx context.Context, uuid string, oldValue string, newValue string) (bool, error) {
+	return s.ReplacePrivateClientKey(ctx, uuid, oldValue, newValue)
+}
+
+func TestRewrap(t *testing.T) {
+	oldPath := newTestKeyFile(t, "k1.key")
+	newPath := newTestKeyFile(t, "k2.key")
+	oldProvider, err := NewLocalFileProvider(newTestConfig(t, "", "k1:"+oldPath))
+	require.NoError(t, err)
+	provider, err := NewLocalFileProvider(newTestConfig(t, "k2", "k1:"+oldPath, "k2:"+newPath))
+	require.NoError(t, err)
+
+	wrappedOld, err := oldProvider.Wrap([]byte("key-old"), []byte("uuid-old"))
+	require.NoError(t, err)
+	wrappedActive, err := provider.Wrap([]byte("key-active"), []byte("uuid-active"))
+	require.NoError(t, err)
+	wrappedChanged, err := oldProvider.Wrap([]byte("key-changed"), []byte("uuid-changed"))
+	require.NoError(t, err)
+
+	store := &fakeKeyStore{
+		values: map[string]string{
+			"uuid-legacy":  "key-legacy",
+			"uuid-old":     wrappedOld,
+			"uuid-active":  wrappedActive,
+			"uuid-broken":  "kek1:k9:AAAA",
+			"uuid-changed": wrappedChanged,
+		},
+		order:   []string{"uuid-legacy", "uuid-old", "uuid-active", "uuid-broken", "uuid-changed"},
+		changed: map[string]bool{"uuid-changed": true},
+	}
+	var failed []string
+	result, err := Rewrap(context.Background(), store, provider, func(userUUID string, err error) {
+		failed = append(failed, userUUID)
+	})
+	require.NoError(t, err)
+	assert.Equal(t, RewrapResult{Total: 5, Rewrapped: 2, Skipped: 2, Failed: 1}, result)
+	assert.Equal(t, []string{"uuid-broken"}, failed)
+	assert.Equal(t, wrappedActive, store.values["uuid-active"])
+
+	for userUUID, plain := range map[string]string{"uuid-legacy": "key-legacy", "uuid-old": "key-old"} {
+		id, ok := KeyID(store.values[userUUID])
+		require.True(t, ok)
+		assert.Equal(t, "k2", id)
+		value, err := provider.Unwrap(store.values[userUUID], []byte(userUUID))
+		require.NoError(t, err)
+		assert.Equal(t, plain, string(value))
+	}
+
+	t.Run("repeat", func(t *testing.T) {
+		result, err = Rewrap(context.Background(), store, provider, nil)
+		require.NoError(t, err)
+		assert.Equal(t, 0, result.Rewrapped)
+		assert.Equal(t, 1, result.Failed)
+	})
+}
+
+func TestRewrapDevices(t *testing.T) {
+	oldPath := newTestKeyFile(t, "k1.key")
+	newPath := newTestKeyFile(t, "k2.key")
+	oldProvider, err := NewLocalFileProvider(newTestConfig(t, "", "k1:"+oldPath))
+	require.NoError(t, err)
+	provider, err := NewLocalFileProvider(newTestConfig(t, "k2", "k1:"+oldPath, "k2:"+newPath))
+	require.NoError(t, err)
+
+	wrapped, err := oldProvider.Wrap([]byte("device key"), []byte("device-uuid"))
+	require.NoError(t, err)
+	store := &fakeKeyStore{values: map[string]string{"device-uuid": wrapped}, order: []string{"device-uuid"}}
+
+	result, err := RewrapDevices(context.Background(), store, provider, nil)
+	require.NoError(t, err)
+	assert.Equal(t, RewrapResult{Total: 1, Rewrapped: 1}, result)
+	id, _ := KeyID(store.values["device-uuid"])
+	assert.Equal(t, "k2", id)
+	value, err := provider.Unwrap(store.values["device-uuid"], []byte("device-uuid"))
+	require.NoError(t, err)
+	assert.Equal(t, "device key", string(value))
+}
+
+func TestRewrap_StoreError(t *testing.T) {
+	provider, err := NewLocalFileProvider(newTestConfig(t, ""))
+	require.NoError(t, err)
+	_, err = Rewrap(context.Background(), &fakeKeyStore{err: errors.New("db error")}, provider, nil)
+	assert.Error(t, err)
+}
+
+func TestRewrap_Canceled(t *testing.T) {
+	provider, err := NewLocalFileProvider(newTestConfig(t, ""))
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	store := &fakeKeyStore{values: map[string]string{"uuid": "key"}, order: []string{"uuid"}}
+	_, err = Rewrap(ctx, store, provider, nil)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, "key", store.values["uuid"])
+}
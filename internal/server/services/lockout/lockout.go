@@ -0,0 +1,105 @@
+package lockout
+
+import (
+	"context"
+	"time"
+
+	"github.com/northmule/gophkeeper/internal/common/models"
+	"github.com/northmule/gophkeeper/internal/server/config"
+)
+
+// Защита входа от подбора пароля. Неудачные попытки считаются по логину и хранятся в БД, поэтому
+// перезапуск сервера счётчики не сбрасывает. После каждой неудачи следующая попытка откладывается:
+// сначала на LOGIN_DELAY с удвоением, после LOGIN_LOCKOUT_THRESHOLD неудач подряд логин блокируется
+// на LOGIN_LOCKOUT_DURATION, каждая следующая неудача удваивает блокировку до LOGIN_LOCKOUT_MAX
+
+// Store хранилище неудачных попыток входа
+type Store interface {
+	FindOneByLogin(ctx context.Context, login string) (*models.LoginFailure, error)
+	Fail(ctx context.Context, login string, failedAt time.Time, resetBefore time.Time) (int, error)
+	Lock(ctx context.Context, login string, lockedUntil time.Time) error
+	Reset(ctx context.Context, login string) error
+}
+
+// Lockout задержки и блокировка входа после неудачных попыток
+type Lockout struct {
+	store     Store
+	delay     time.Duration
+	threshold int
+	duration  time.Duration
+	max       time.Duration
+	reset     time.Duration
+	now       func() time.Time
+}
+
+// NewLockout конструктор
+func NewLockout(store Store, cfg *config.Config) *Lockout {
+	return &Lockout{
+		store:     store,
+		delay:     cfg.Value().LoginDelay,
+		threshold: cfg.Value().LoginLockoutThreshold,
+		duration:  cfg.Value().LoginLockoutDuration,
+		max:       cfg.Value().LoginLockoutMax,
+		reset:     cfg.Value().LoginFailuresReset,
+		now:       time.Now,
+	}
+}
+
+// Check время до следующей разрешённой попытки входа, 0 - вход разрешён
+func (l *Lockout) Check(ctx context.Context, login string) (time.Duration, error) {
+	failure, err := l.store.FindOneByLogin(ctx, login)
+	if err != nil {
+		return 0, err
+	}
+	if failure == nil || failure.LockedUntil == nil {
+		return 0, nil
+	}
+	retryAfter := failure.LockedUntil.Sub(l.now())
+	if retryAfter < 0 {
+		return 0, nil
+	}
+	return retryAfter, nil
+}
+
+// Fail учёт неудачной попытки входа, вернёт время до следующей разрешённой попытки
+func (l *Lockout) Fail(ctx context.Context, login string) (time.Duration, error) {
+	now := l.now()
+	failures, err := l.store.Fail(ctx, login, now, now.Add(-l.reset))
+	if err != nil {
+		return 0, err
+	}
+	retryAfter := l.backoff(failures)
+	if retryAfter == 0 {
+		return 0, nil
+	}
+	err = l.store.Lock(ctx, login, now.Add(retryAfter))
+	if err != nil {
+		return 0, err
+	}
+	return retryAfter, nil
+}
+
+// Reset сброс счётчика после успешного входа
+func (l *Lockout) Reset(ctx context.Context, login string) error {
+	return l.store.Reset(ctx, login)
+}
+
+// backoff время до следующей попытки после failures неудач подряд
+func (l *Lockout) backoff(failures int) time.Duration {
+	if l.threshold > 0 && failures >= l.threshold {
+		return doubled(l.duration, failures-l.threshold, l.max)
+	}
+	return doubled(l.delay, failures-1, l.max)
+}
+
+// doubled base, удвоенная n раз, но не больше limit
+func doubled(base time.Duration, n int, limit time.Duration) time.Duration {
+	value := base
+	for i := 0; i < n && value < limit; i++ {
+		value *= 2
+	}
+	if limit > 0 && value > limit {
+		return limit
+	}
+	return value
+}
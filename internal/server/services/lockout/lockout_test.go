@@ -0,0 +1,140 @@
+package lockout
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/northmule/gophkeeper/internal/common/models"
+	"github.com/northmule/gophkeeper/internal/server/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeStore struct {
+	failures map[string]*models.LoginFailure
+	err      error
+}
+
+func newFakeStore() *fakeStore {
+	return &fakeStore{failures: make(map[string]*models.LoginFailure)}
+}
+
+func (s *fakeStore) FindOneByLogin(_ context.Context, login string) (*models.LoginFailure, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	return s.failures[login], nil
+}
+
+func (s *fakeStore) Fail(_ context.Context, login string, failedAt time.Time, resetBefore time.Time) (int, error) {
+	if s.err != nil {
+		return 0, s.err
+	}
+	failure, ok := s.failures[login]
+	if !ok || failure.FailedAt.Before(resetBefore) {
+		failure = &models.LoginFailure{Login: login}
+		s.failures[login] = failure
+	}
+	failure.Failures++
+	failure.FailedAt = failedAt
+	return failure.Failures, nil
+}
+
+func (s *fakeStore) Lock(_ context.Context, login string, lockedUntil time.Time) error {
+	s.failures[login].LockedUntil = &lockedUntil
+	return nil
+}
+
+func (s *fakeStore) Reset(_ context.Context, login string) error {
+	delete(s.failures, login)
+	return nil
+}
+
+func newTestLockout(store Store, now *time.Time) *Lockout {
+	cfg := config.NewConfig()
+	cfg.Value().LoginDelay = time.Second
+	cfg.Value().LoginLockoutThreshold = 3
+	cfg.Value().LoginLockoutDuration = time.Minute
+	cfg.Value().LoginLockoutMax = 3 * time.Minute
+	cfg.Value().LoginFailuresReset = time.Hour
+	l := NewLockout(store, cfg)
+	l.now = func() time.Time { return *now }
+	return l
+}
+
+func TestLockout_Progressive(t *testing.T) {
+	now := time.Now()
+	l := newTestLockout(newFakeStore(), &now)
+	ctx := context.Background()
+
+	retryAfter, err := l.Check(ctx, "login")
+	require.NoError(t, err)
+	assert.Zero(t, retryAfter)
+
+	// задержки до блокировки, затем блокировка с удвоением до максимума
+	want := []time.Duration{time.Second, 2 * time.Second, time.Minute, 2 * time.Minute, 3 * time.Minute, 3 * time.Minute}
+	for _, expected := range want {
+		retryAfter, err = l.Fail(ctx, "login")
+		require.NoError(t, err)
+		assert.Equal(t, expected, retryAfter)
+
+		retryAfter, err = l.Check(ctx, "login")
+		require.NoError(t, err)
+		assert.Equal(t, expected, retryAfter)
+
+		now = now.Add(expected)
+		retryAfter, err = l.Check(ctx, "login")
+		require.NoError(t, err)
+		assert.Zero(t, retryAfter)
+	}
+
+	require.NoError(t, l.Reset(ctx, "login"))
+	retryAfter, err = l.Fail(ctx, "login")
+	require.NoError(t, err)
+	assert.Equal(t, time.Second, retryAfter)
+}
+
+func TestLockout_ResetAfterQuietPeriod(t *testing.T) {
+	now := time.Now()
+	l := newTestLockout(newFakeStore(), &now)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		_, err := l.Fail(ctx, "login")
+		require.NoError(t, err)
+	}
+	now = now.Add(2 * time.Hour)
+	retryAfter, err := l.Fail(ctx, "login")
+	require.NoError(t, err)
+	assert.Equal(t, time.Second, retryAfter)
+}
+
+func TestLockout_Disabled(t *testing.T) {
+	now := time.Now()
+	store := newFakeStore()
+	l := newTestLockout(store, &now)
+	l.delay = 0
+	l.threshold = 0
+	ctx := context.Background()
+
+	for i := 0; i < 10; i++ {
+		retryAfter, err := l.Fail(ctx, "login")
+		require.NoError(t, err)
+		assert.Zero(t, retryAfter)
+	}
+	assert.Nil(t, store.failures["login"].LockedUntil)
+}
+
+func TestLockout_StoreError(t *testing.T) {
+	now := time.Now()
+	store := newFakeStore()
+	store.err = errors.New("db error")
+	l := newTestLockout(store, &now)
+
+	_, err := l.Check(context.Background(), "login")
+	assert.Error(t, err)
+	_, err = l.Fail(context.Background(), "login")
+	assert.Error(t, err)
+}
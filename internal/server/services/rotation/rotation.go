@@ -0,0 +1,120 @@
+package rotation
+
+import (
+	"context"
+	"sync"
+
+	"github.com/northmule/gophkeeper/internal/common/models"
+	"github.com/northmule/gophkeeper/internal/server/logger"
+	"github.com/northmule/gophkeeper/internal/server/services/kek"
+)
+
+// Смена ключа шифрования клиента устройства (devices.client_key, для сессий без устройства - users.private_client_key):
+// 1. Клиент присылает новый ключ, он сохраняется в key_rotations зашифрованным KEK, у устройства остаётся прежний ключ
+// 2. В фоне проверяется, что новый ключ расшифровывается KEK, и он заменяет прежний одной транзакцией.
+// Данные пользователя шифруются на клиенте мастер-ключом, ключ клиента защищает только запросы и ответы,
+// поэтому перешифровывать на сервере нечего. Смена, прерванная остановкой сервера, завершается после перезапуска
+
+// Store хранилище смен ключа
+type Store interface {
+	FindAllRunning(ctx context.Context) ([]models.KeyRotation, error)
+	Fail(ctx context.Context, uuid string, reason string) error
+	Finish(ctx context.Context, rotation *models.KeyRotation) error
+}
+
+// Rotator выполняет смены ключа в фоне
+type Rotator struct {
+	ctx         context.Context
+	store       Store
+	keyProvider kek.KeyProvider
+	log         *logger.Logger
+
+	running map[string]bool
+	mx      sync.Mutex
+	wg      sync.WaitGroup
+}
+
+// NewRotator конструктор. ctx - время жизни сервера, при его отмене работа останавливается и продолжится после перезапуска
+func NewRotator(ctx context.Context, store Store, keyProvider kek.KeyProvider, log *logger.Logger) *Rotator {
+	return &Rotator{
+		ctx:         ctx,
+		store:       store,
+		keyProvider: keyProvider,
+		log:         log,
+		running:     make(map[string]bool),
+	}
+}
+
+// Start запуск смены ключа в фоне
+func (r *Rotator) Start(rotation models.KeyRotation) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	if r.running[rotation.UUID] {
+		return
+	}
+	r.running[rotation.UUID] = true
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		defer func() {
+			r.mx.Lock()
+			delete(r.running, rotation.UUID)
+			r.mx.Unlock()
+		}()
+		r.run(&rotation)
+	}()
+}
+
+// Resume продолжение незавершённых смен ключа после перезапуска сервера
+func (r *Rotator) Resume() error {
+	rotations, err := r.store.FindAllRunning(r.ctx)
+	if err != nil {
+		return err
+	}
+	for _, rotation := range rotations {
+		r.log.Infof("Resuming the key rotation %s of user %s", rotation.UUID, rotation.UserUUID)
+		r.Start(rotation)
+	}
+	return nil
+}
+
+// Wait ожидание остановки фоновых смен ключа
+func (r *Rotator) Wait() {
+	r.wg.Wait()
+}
+
+func (r *Rotator) run(rotation *models.KeyRotation) {
+	err := r.check(rotation)
+	if err == nil {
+		err = r.ctx.Err()
+	}
+	if err == nil {
+		err = r.store.Finish(r.ctx, rotation)
+	}
+	if err == nil {
+		r.log.Infof("The key rotation %s of user %s has been finished", rotation.UUID, rotation.UserUUID)
+		return
+	}
+	if r.ctx.Err() != nil {
+		r.log.Infof("The key rotation %s has been interrupted, it will be resumed", rotation.UUID)
+		return
+	}
+	r.log.Errorf("The key rotation %s of user %s has failed: %s", rotation.UUID, rotation.UserUUID, err)
+	if err = r.store.Fail(r.ctx, rotation.UUID, err.Error()); err != nil {
+		r.log.Error(err)
+	}
+}
+
+// check новый ключ расшифровывается KEK для своего владельца (устройства или пользователя)
+func (r *Rotator) check(rotation *models.KeyRotation) error {
+	_, err := r.keyProvider.Unwrap(rotation.NewClientKey, []byte(KeyOwner(rotation)))
+	return err
+}
+
+// KeyOwner UUID владельца ключа смены - дополнительные данные при шифровании ключа KEK
+func KeyOwner(rotation *models.KeyRotation) string {
+	if rotation.DeviceUUID != "" {
+		return rotation.DeviceUUID
+	}
+	return rotation.UserUUID
+}
@@ -0,0 +1,138 @@
+package rotation
+
+import (
+	"context"
+	"path"
+	"sync"
+	"testing"
+
+	"github.com/northmule/gophkeeper/internal/common/models"
+	"github.com/northmule/gophkeeper/internal/server/config"
+	"github.com/northmule/gophkeeper/internal/server/logger"
+	"github.com/northmule/gophkeeper/internal/server/services/kek"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeStore struct {
+	mx       sync.Mutex
+	running  []models.KeyRotation
+	finished *models.KeyRotation
+	failed   string
+	userKey  string
+}
+
+func (s *fakeStore) FindAllRunning(_ context.Context) ([]models.KeyRotation, error) {
+	return s.running, nil
+}
+
+func (s *fakeStore) Fail(_ context.Context, _ string, reason string) error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	s.failed = reason
+	return nil
+}
+
+func (s *fakeStore) Finish(_ context.Context, rotation *models.KeyRotation) error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	s.finished = rotation
+	s.userKey = rotation.NewClientKey
+	return nil
+}
+
+func newTestKeyProvider(t *testing.T) kek.KeyProvider {
+	keyPath := path.Join(t.TempDir(), "k1.key")
+	require.NoError(t, kek.GenerateKeyFile(keyPath))
+	cfg := config.NewConfig()
+	cfg.Value().KEKKeys = []string{"k1:" + keyPath}
+	provider, err := kek.NewLocalFileProvider(cfg)
+	require.NoError(t, err)
+	return provider
+}
+
+func newTestRotation(t *testing.T, provider kek.KeyProvider) (*fakeStore, models.KeyRotation) {
+	oldKey, err := provider.Wrap([]byte("old key"), []byte("user-uuid"))
+	require.NoError(t, err)
+	newKey, err := provider.Wrap([]byte("new key"), []byte("user-uuid"))
+	require.NoError(t, err)
+	return &fakeStore{userKey: oldKey}, models.KeyRotation{UUID: "rotation-uuid", UserUUID: "user-uuid", NewClientKey: newKey, Status: models.KeyRotationRunning}
+}
+
+func TestRotator_Start(t *testing.T) {
+	log, _ := logger.NewLogger("info")
+	provider := newTestKeyProvider(t)
+	store, rotation := newTestRotation(t, provider)
+
+	rotator := NewRotator(context.Background(), store, provider, log)
+	rotator.Start(rotation)
+	rotator.Wait()
+
+	require.NotNil(t, store.finished)
+	assert.Equal(t, rotation.NewClientKey, store.userKey)
+	assert.Empty(t, store.failed)
+}
+
+func TestRotator_Resume(t *testing.T) {
+	log, _ := logger.NewLogger("info")
+	provider := newTestKeyProvider(t)
+	store, rotation := newTestRotation(t, provider)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// сервер остановлен до замены ключа
+	rotator := NewRotator(ctx, store, provider, log)
+	rotator.Start(rotation)
+	rotator.Wait()
+	require.Nil(t, store.finished)
+	assert.Empty(t, store.failed)
+
+	// после перезапуска смена завершается
+	store.running = []models.KeyRotation{rotation}
+	rotator = NewRotator(context.Background(), store, provider, log)
+	require.NoError(t, rotator.Resume())
+	rotator.Wait()
+
+	require.NotNil(t, store.finished)
+	assert.Equal(t, rotation.NewClientKey, store.userKey)
+}
+
+func TestRotator_Fail(t *testing.T) {
+	log, _ := logger.NewLogger("info")
+	provider := newTestKeyProvider(t)
+	store, rotation := newTestRotation(t, provider)
+	oldKey := store.userKey
+	// новый ключ зашифрован для другого пользователя
+	rotation.NewClientKey, _ = provider.Wrap([]byte("new key"), []byte("other-uuid"))
+
+	rotator := NewRotator(context.Background(), store, provider, log)
+	rotator.Start(rotation)
+	rotator.Wait()
+
+	// прежний ключ остаётся у пользователя
+	assert.Nil(t, store.finished)
+	assert.Equal(t, oldKey, store.userKey)
+	assert.NotEmpty(t, store.failed)
+}
+
+func TestRotator_Device(t *testing.T) {
+	log, _ := logger.NewLogger("info")
+	provider := newTestKeyProvider(t)
+	store, rotation := newTestRotation(t, provider)
+	rotation.DeviceUUID = "device-uuid"
+
+	// ключ устройства шифруется KEK с UUID устройства
+	rotator := NewRotator(context.Background(), store, provider, log)
+	rotator.Start(rotation)
+	rotator.Wait()
+	assert.Nil(t, store.finished)
+	assert.NotEmpty(t, store.failed)
+
+	store.failed = ""
+	rotation.NewClientKey, _ = provider.Wrap([]byte("new key"), []byte("device-uuid"))
+	rotator.Start(rotation)
+	rotator.Wait()
+	require.NotNil(t, store.finished)
+	assert.Equal(t, rotation.NewClientKey, store.userKey)
+	assert.Empty(t, store.failed)
+}
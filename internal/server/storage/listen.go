@@ -0,0 +1,39 @@
+package storage
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/stdlib"
+)
+
+// Listen подписка на уведомления канала Postgres (LISTEN) на отдельном соединении.
+// handle вызывается для каждого уведомления, возврат при отмене ctx или обрыве соединения
+func (p *Postgres) Listen(ctx context.Context, channel string, handle func(payload string)) error {
+	conn, err := p.RawDB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	return conn.Raw(func(driverConn any) error {
+		stdlibConn, ok := driverConn.(*stdlib.Conn)
+		if !ok {
+			return fmt.Errorf("unexpected driver connection %T", driverConn)
+		}
+		pgxConn := stdlibConn.Conn()
+		_, err := pgxConn.Exec(ctx, "listen "+pgx.Identifier{channel}.Sanitize())
+		for err == nil {
+			var notification *pgconn.Notification
+			notification, err = pgxConn.WaitForNotification(ctx)
+			if err == nil {
+				handle(notification.Payload)
+			}
+		}
+		// соединение с подпиской не возвращается в пул
+		return errors.Join(err, driver.ErrBadConn)
+	})
+}
@@ -0,0 +1,57 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	// ErrRequestExpired время запроса вне допустимого окна
+	ErrRequestExpired = errors.New("request timestamp is outside the allowed window")
+	// ErrRequestReplayed запрос с этим nonce уже принят
+	ErrRequestReplayed = errors.New("request nonce has already been used")
+)
+
+// NonceStore хранилище nonce принятых запросов пользователя
+type NonceStore interface {
+	Use(ctx context.Context, userUUID string, nonce string, expiresAt time.Time, expiredBefore time.Time) (bool, error)
+}
+
+// NonceGuard защита от повтора зашифрованных запросов. Nonce хранятся по пользователю до конца окна
+// допустимого расхождения времени: запрос старше окна отклоняется по времени, поэтому его nonce больше не нужен
+type NonceGuard struct {
+	window time.Duration
+	store  NonceStore
+	now    func() time.Time
+}
+
+// NonceGuardManager интерфейс
+type NonceGuardManager interface {
+	Check(ctx context.Context, userUUID string, nonce string, timestamp time.Time) error
+}
+
+// NewNonceGuard конструктор
+func NewNonceGuard(window time.Duration, store NonceStore) *NonceGuard {
+	return &NonceGuard{
+		window: window,
+		store:  store,
+		now:    time.Now,
+	}
+}
+
+// Check проверяет время запроса и запоминает nonce пользователя. Повторный nonce в пределах окна - ErrRequestReplayed
+func (g *NonceGuard) Check(ctx context.Context, userUUID string, nonce string, timestamp time.Time) error {
+	now := g.now()
+	if timestamp.Before(now.Add(-g.window)) || timestamp.After(now.Add(g.window)) {
+		return ErrRequestExpired
+	}
+	used, err := g.store.Use(ctx, userUUID, nonce, timestamp.Add(g.window), now)
+	if err != nil {
+		return err
+	}
+	if !used {
+		return ErrRequestReplayed
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testNonceStore struct {
+	values map[string]time.Time
+	err    error
+}
+
+func (s *testNonceStore) Use(_ context.Context, userUUID string, nonce string, expiresAt time.Time, expiredBefore time.Time) (bool, error) {
+	if s.err != nil {
+		return false, s.err
+	}
+	for key, value := range s.values {
+		if value.Before(expiredBefore) {
+			delete(s.values, key)
+		}
+	}
+	if _, ok := s.values[userUUID+":"+nonce]; ok {
+		return false, nil
+	}
+	s.values[userUUID+":"+nonce] = expiresAt
+	return true, nil
+}
+
+func newTestNonceGuard() (*NonceGuard, *testNonceStore, *time.Time) {
+	now := time.Date(2024, 12, 27, 12, 0, 0, 0, time.UTC)
+	store := &testNonceStore{values: make(map[string]time.Time)}
+	guard := NewNonceGuard(5*time.Minute, store)
+	guard.now = func() time.Time {
+		return now
+	}
+	return guard, store, &now
+}
+
+func TestNonceGuard_Check(t *testing.T) {
+	guard, _, now := newTestNonceGuard()
+	ctx := context.Background()
+	assert.NoError(t, guard.Check(ctx, "user1", "nonce1", *now))
+	assert.ErrorIs(t, guard.Check(ctx, "user1", "nonce1", *now), ErrRequestReplayed)
+	// nonce разных пользователей не пересекаются
+	assert.NoError(t, guard.Check(ctx, "user2", "nonce1", *now))
+
+	assert.ErrorIs(t, guard.Check(ctx, "user1", "old", now.Add(-6*time.Minute)), ErrRequestExpired)
+	assert.ErrorIs(t, guard.Check(ctx, "user1", "future", now.Add(6*time.Minute)), ErrRequestExpired)
+}
+
+func TestNonceGuard_Expires(t *testing.T) {
+	guard, store, now := newTestNonceGuard()
+	ctx := context.Background()
+	assert.NoError(t, guard.Check(ctx, "user1", "nonce1", *now))
+	assert.Equal(t, now.Add(5*time.Minute), store.values["user1:nonce1"])
+
+	// после окна запрос с тем же временем отклоняется по времени, nonce удаляется
+	*now = now.Add(10 * time.Minute)
+	assert.ErrorIs(t, guard.Check(ctx, "user1", "nonce1", now.Add(-10*time.Minute)), ErrRequestExpired)
+	assert.NoError(t, guard.Check(ctx, "user1", "nonce2", *now))
+	assert.NotContains(t, store.values, "user1:nonce1")
+}
+
+func TestNonceGuard_StoreError(t *testing.T) {
+	guard, store, now := newTestNonceGuard()
+	store.err = errors.New("db error")
+	assert.ErrorIs(t, guard.Check(context.Background(), "user1", "nonce1", *now), store.err)
+}
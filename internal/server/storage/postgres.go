@@ -57,10 +57,85 @@ func (p *Postgres) Ping(ctx context.Context) error {
 	return p.DB.PingContext(ctx)
 }
 
+// Querier запросы к БД или к транзакции
+type Querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+type transactionCtxKey struct{}
+
+// WithTransaction контекст с транзакцией запроса: репозитории выполняют в ней все запросы
+func WithTransaction(ctx context.Context, tx TxDBQuery) context.Context {
+	return context.WithValue(ctx, transactionCtxKey{}, tx)
+}
+
+// TransactionFromContext транзакция запроса
+func TransactionFromContext(ctx context.Context) (TxDBQuery, bool) {
+	tx, ok := ctx.Value(transactionCtxKey{}).(TxDBQuery)
+	if !ok || tx.Tx() == nil {
+		return nil, false
+	}
+	return tx, true
+}
+
+// Query запросы в транзакции запроса, без неё - к db
+func Query(ctx context.Context, db DBQuery) Querier {
+	if tx, ok := TransactionFromContext(ctx); ok {
+		return tx.Tx()
+	}
+	return db
+}
+
+// Stmt подготовленный запрос в транзакции запроса, без неё - сам stmt
+func Stmt(ctx context.Context, stmt *sql.Stmt) *sql.Stmt {
+	if tx, ok := TransactionFromContext(ctx); ok {
+		return tx.Tx().StmtContext(ctx, stmt)
+	}
+	return stmt
+}
+
+// Tx транзакция репозитория. Внутри транзакции запроса Commit и Rollback ничего не делают:
+// изменения фиксирует или откатывает транзакция запроса
+type Tx struct {
+	*sql.Tx
+	nested bool
+}
+
+// BeginTx транзакция запроса или новая транзакция
+func BeginTx(ctx context.Context, db DBQuery) (*Tx, error) {
+	if tx, ok := TransactionFromContext(ctx); ok {
+		return &Tx{Tx: tx.Tx(), nested: true}, nil
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	return &Tx{Tx: tx}, nil
+}
+
+// Commit сохранить изменения
+func (t *Tx) Commit() error {
+	if t.nested {
+		return nil
+	}
+	return t.Tx.Commit()
+}
+
+// Rollback откат
+func (t *Tx) Rollback() error {
+	if t.nested {
+		return nil
+	}
+	return t.Tx.Rollback()
+}
+
 // Transaction транзакции
 type Transaction struct {
-	t *sql.Tx
-	e []error
+	t           *sql.Tx
+	e           []error
+	afterCommit []func()
 }
 
 // NewTransaction Открывает новую транзакцию
@@ -99,9 +174,28 @@ func (t *Transaction) Rollback() error {
 	return t.t.Rollback()
 }
 
-// Commit сохранить изменения
+// Commit сохранить изменения, затем выполнить действия AfterCommit
 func (t *Transaction) Commit() error {
-	return t.t.Commit()
+	err := t.t.Commit()
+	if err != nil {
+		return err
+	}
+	for _, action := range t.afterCommit {
+		action()
+	}
+	return nil
+}
+
+// AfterCommit действие после фиксации транзакции запроса, например удаление файлов, на которые перестала ссылаться БД.
+// При откате транзакции действие не выполняется, без транзакции запроса выполняется сразу
+func AfterCommit(ctx context.Context, action func()) {
+	if tx, ok := TransactionFromContext(ctx); ok {
+		if transaction, ok := tx.(*Transaction); ok {
+			transaction.afterCommit = append(transaction.afterCommit, action)
+			return
+		}
+	}
+	action()
 }
 
 // Error получить ошибки
@@ -5,8 +5,10 @@ import (
 	"errors"
 	"testing"
 
+	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 	"golang.org/x/net/context"
 )
 
@@ -116,3 +118,65 @@ func TestTransaction_Tx(t *testing.T) {
 	actualTx := tx.Tx()
 	assert.Equal(t, mockTx, actualTx)
 }
+
+func TestQuery_Transaction(t *testing.T) {
+	db := new(MockDBQuery)
+	ctx := context.Background()
+	assert.Equal(t, db, Query(ctx, db))
+
+	_, ok := TransactionFromContext(WithTransaction(ctx, &Transaction{}))
+	assert.False(t, ok) // транзакция без *sql.Tx не используется
+
+	sqlTx := new(sql.Tx)
+	ctx = WithTransaction(ctx, &Transaction{t: sqlTx})
+	assert.Equal(t, sqlTx, Query(ctx, db))
+}
+
+func TestBeginTx_Nested(t *testing.T) {
+	db := new(MockDBQuery)
+	sqlTx := new(sql.Tx)
+	ctx := WithTransaction(context.Background(), &Transaction{t: sqlTx})
+
+	tx, err := BeginTx(ctx, db)
+	assert.NoError(t, err)
+	assert.Equal(t, sqlTx, tx.Tx)
+	assert.NoError(t, tx.Commit())
+	assert.NoError(t, tx.Rollback())
+	db.AssertNotCalled(t, "Begin")
+}
+
+func TestAfterCommit(t *testing.T) {
+	called := 0
+	AfterCommit(context.Background(), func() { called++ })
+	assert.Equal(t, 1, called) // без транзакции запроса выполняется сразу
+
+	db, sqlMock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectCommit()
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectRollback()
+
+	transaction, err := NewTransaction(db)
+	require.NoError(t, err)
+	AfterCommit(WithTransaction(context.Background(), transaction), func() { called++ })
+	assert.Equal(t, 1, called)
+	require.NoError(t, transaction.Commit())
+	assert.Equal(t, 2, called)
+
+	transaction, err = NewTransaction(db)
+	require.NoError(t, err)
+	AfterCommit(WithTransaction(context.Background(), transaction), func() { called++ })
+	require.NoError(t, transaction.Rollback())
+	assert.Equal(t, 2, called) // при откате не выполняется
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestBeginTx_Error(t *testing.T) {
+	db := new(MockDBQuery)
+	db.On("Begin").Return((*sql.Tx)(nil), errors.New("connection refused"))
+
+	_, err := BeginTx(context.Background(), db)
+	assert.Error(t, err)
+}
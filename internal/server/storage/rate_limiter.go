@@ -0,0 +1,66 @@
+package storage
+
+import (
+	"sync"
+	"time"
+)
+
+// RateLimiter ограничение числа запросов по ключу (IP, логин) за окно времени.
+// Счётчики в памяти: после перезапуска сервера окна начинаются заново
+type RateLimiter struct {
+	window  time.Duration
+	limit   int
+	values  map[string]*rateWindow
+	cleaned time.Time
+	now     func() time.Time
+	mx      sync.Mutex
+}
+
+// RateLimiterManager интерфейс
+type RateLimiterManager interface {
+	Allow(key string) (bool, time.Duration)
+}
+
+type rateWindow struct {
+	start time.Time
+	count int
+}
+
+// NewRateLimiter конструктор. limit 0 - без ограничения
+func NewRateLimiter(window time.Duration, limit int) *RateLimiter {
+	return &RateLimiter{
+		window: window,
+		limit:  limit,
+		values: make(map[string]*rateWindow),
+		now:    time.Now,
+	}
+}
+
+// Allow учитывает запрос с ключом. false - лимит окна исчерпан, запрос можно повторить через вернувшееся время
+func (l *RateLimiter) Allow(key string) (bool, time.Duration) {
+	if l.limit <= 0 {
+		return true, 0
+	}
+	l.mx.Lock()
+	defer l.mx.Unlock()
+	now := l.now()
+	// окна, которые уже закончились, больше не нужны
+	if now.Sub(l.cleaned) >= l.window {
+		for k, value := range l.values {
+			if now.Sub(value.start) >= l.window {
+				delete(l.values, k)
+			}
+		}
+		l.cleaned = now
+	}
+	value, ok := l.values[key]
+	if !ok || now.Sub(value.start) >= l.window {
+		value = &rateWindow{start: now}
+		l.values[key] = value
+	}
+	if value.count >= l.limit {
+		return false, value.start.Add(l.window).Sub(now)
+	}
+	value.count++
+	return true, 0
+}
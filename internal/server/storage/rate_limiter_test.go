@@ -0,0 +1,52 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimiter_Allow(t *testing.T) {
+	now := time.Now()
+	limiter := NewRateLimiter(time.Minute, 2)
+	limiter.now = func() time.Time { return now }
+
+	allowed, _ := limiter.Allow("127.0.0.1")
+	assert.True(t, allowed)
+	allowed, _ = limiter.Allow("127.0.0.1")
+	assert.True(t, allowed)
+
+	now = now.Add(20 * time.Second)
+	allowed, retryAfter := limiter.Allow("127.0.0.1")
+	assert.False(t, allowed)
+	assert.Equal(t, 40*time.Second, retryAfter)
+
+	// у другого ключа своё окно
+	allowed, _ = limiter.Allow("127.0.0.2")
+	assert.True(t, allowed)
+
+	now = now.Add(40 * time.Second)
+	allowed, _ = limiter.Allow("127.0.0.1")
+	assert.True(t, allowed)
+}
+
+func TestRateLimiter_Cleanup(t *testing.T) {
+	now := time.Now()
+	limiter := NewRateLimiter(time.Minute, 1)
+	limiter.now = func() time.Time { return now }
+
+	limiter.Allow("a")
+	limiter.Allow("b")
+	now = now.Add(2 * time.Minute)
+	limiter.Allow("c")
+	assert.Len(t, limiter.values, 1)
+}
+
+func TestRateLimiter_Unlimited(t *testing.T) {
+	limiter := NewRateLimiter(time.Minute, 0)
+	for i := 0; i < 100; i++ {
+		allowed, _ := limiter.Allow("key")
+		assert.True(t, allowed)
+	}
+}
@@ -5,40 +5,94 @@ import (
 	"time"
 )
 
-// Session данные по авторизованным пользователям
+// sessionSweepInterval как часто Add удаляет из кэша истёкшие сессии
+const sessionSweepInterval = time.Minute
+
+// Session действующие сессии пользователей (кэш перед таблицей sessions).
+// Истёкшие записи удаляются при проверке и периодически при добавлении, кэш не растёт с числом входов
 type Session struct {
-	values map[string]time.Time
-	mx     sync.RWMutex
+	values  map[string]sessionValue
+	cleaned time.Time
+	now     func() time.Time
+	mx      sync.RWMutex
+}
+
+type sessionValue struct {
+	userUUID string
+	expire   time.Time
 }
 
 // SessionManager интерфейс
 type SessionManager interface {
-	Add(token string, expire time.Time)
-	IsValid(token string) bool
+	Add(sessionUUID string, userUUID string, expire time.Time)
+	IsValid(sessionUUID string) bool
+	Revoke(sessionUUID string)
+	List(userUUID string) []string
 }
 
 // NewSession конструктор
 func NewSession() SessionManager {
 	return &Session{
-		values: make(map[string]time.Time),
+		values: make(map[string]sessionValue),
+		now:    time.Now,
 	}
 }
 
 // Add добавить
-func (s *Session) Add(token string, expire time.Time) {
+func (s *Session) Add(sessionUUID string, userUUID string, expire time.Time) {
 	s.mx.Lock()
 	defer s.mx.Unlock()
-	s.values[token] = expire
+	now := s.now()
+	if now.Sub(s.cleaned) >= sessionSweepInterval {
+		for key, value := range s.values {
+			if !value.expire.After(now) {
+				delete(s.values, key)
+			}
+		}
+		s.cleaned = now
+	}
+	s.values[sessionUUID] = sessionValue{userUUID: userUUID, expire: expire}
 }
 
-// IsValid проверк на валидность
-func (s *Session) IsValid(token string) bool {
+// IsValid проверк на валидность. Истёкшая запись удаляется
+func (s *Session) IsValid(sessionUUID string) bool {
 	s.mx.RLock()
-	defer s.mx.RUnlock()
-	expireTime, ok := s.values[token]
+	value, ok := s.values[sessionUUID]
+	s.mx.RUnlock()
 	if !ok {
 		return false
 	}
+	now := s.now()
+	if value.expire.After(now) {
+		return true
+	}
+
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	// запись могла быть обновлена после чтения
+	if current, ok := s.values[sessionUUID]; ok && !current.expire.After(now) {
+		delete(s.values, sessionUUID)
+	}
+	return false
+}
 
-	return expireTime.After(time.Now())
+// Revoke удалить сессию
+func (s *Session) Revoke(sessionUUID string) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	delete(s.values, sessionUUID)
+}
+
+// List действующие сессии пользователя
+func (s *Session) List(userUUID string) []string {
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+	now := s.now()
+	sessions := make([]string, 0)
+	for sessionUUID, value := range s.values {
+		if value.userUUID == userUUID && value.expire.After(now) {
+			sessions = append(sessions, sessionUUID)
+		}
+	}
+	return sessions
 }
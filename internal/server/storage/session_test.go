@@ -20,11 +20,12 @@ func TestAdd(t *testing.T) {
 
 	token := "test-token"
 	expire := time.Now().Add(1 * time.Hour)
-	session.Add(token, expire)
+	session.Add(token, "user", expire)
 
 	session.mx.RLock()
 	defer session.mx.RUnlock()
-	assert.Equal(t, expire, session.values[token])
+	assert.Equal(t, expire, session.values[token].expire)
+	assert.Equal(t, "user", session.values[token].userUUID)
 }
 
 func TestIsValid(t *testing.T) {
@@ -32,16 +33,39 @@ func TestIsValid(t *testing.T) {
 
 	token := "test-token"
 	expire := time.Now().Add(1 * time.Hour)
-	session.Add(token, expire)
+	session.Add(token, "user", expire)
 	assert.True(t, session.IsValid(token))
 
 	expiredToken := "expired-token"
 	expiredExpire := time.Now().Add(-1 * time.Hour)
-	session.Add(expiredToken, expiredExpire)
+	session.Add(expiredToken, "user", expiredExpire)
 	assert.False(t, session.IsValid(expiredToken))
 
 	nonExistentToken := "non-existent-token"
 	assert.False(t, session.IsValid(nonExistentToken))
+
+	// истёкшая запись удаляется при проверке
+	session.mx.RLock()
+	defer session.mx.RUnlock()
+	assert.NotContains(t, session.values, expiredToken)
+	assert.Contains(t, session.values, token)
+}
+
+func TestSession_Sweep(t *testing.T) {
+	now := time.Now()
+	session := NewSession().(*Session)
+	session.now = func() time.Time { return now }
+
+	session.Add("a", "user", now.Add(time.Second))
+	session.Add("b", "user", now.Add(time.Hour))
+	now = now.Add(2 * sessionSweepInterval)
+	// истёкшие записи, которые больше не проверяются, удаляются при добавлении
+	session.Add("c", "user", now.Add(time.Hour))
+
+	session.mx.RLock()
+	defer session.mx.RUnlock()
+	assert.Len(t, session.values, 2)
+	assert.NotContains(t, session.values, "a")
 }
 
 func TestConcurrency(t *testing.T) {
@@ -57,7 +81,7 @@ func TestConcurrency(t *testing.T) {
 			defer wg.Done()
 			token := fmt.Sprintf("token-%d", i)
 			expire := time.Now().Add(1 * time.Hour)
-			session.Add(token, expire)
+			session.Add(token, "user", expire)
 		}(i)
 	}
 	wg.Wait()
@@ -68,3 +92,28 @@ func TestConcurrency(t *testing.T) {
 	}
 
 }
+
+func TestRevoke(t *testing.T) {
+	session := NewSession()
+
+	session.Add("session-1", "user", time.Now().Add(time.Hour))
+	assert.True(t, session.IsValid("session-1"))
+
+	session.Revoke("session-1")
+	assert.False(t, session.IsValid("session-1"))
+
+	session.Revoke("non-existent")
+}
+
+func TestList(t *testing.T) {
+	session := NewSession()
+
+	session.Add("session-1", "user-1", time.Now().Add(time.Hour))
+	session.Add("session-2", "user-1", time.Now().Add(time.Hour))
+	session.Add("session-3", "user-2", time.Now().Add(time.Hour))
+	session.Add("session-4", "user-1", time.Now().Add(-time.Hour))
+
+	assert.ElementsMatch(t, []string{"session-1", "session-2"}, session.List("user-1"))
+	assert.Equal(t, []string{"session-3"}, session.List("user-2"))
+	assert.Empty(t, session.List("user-3"))
+}